@@ -4,70 +4,2103 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"paperbox/internal/apperrors"
+	"paperbox/internal/capture"
 	"paperbox/internal/config"
+	"paperbox/internal/config/core"
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/requests"
+	"paperbox/internal/config/storage"
+	"paperbox/internal/config/user"
+	"paperbox/internal/docker"
+	"paperbox/internal/docs"
+	"paperbox/internal/doctor"
+	"paperbox/internal/drafts"
+	"paperbox/internal/engine"
+	"paperbox/internal/faker"
+	"paperbox/internal/fuzz"
+	"paperbox/internal/har"
+	"paperbox/internal/health"
+	"paperbox/internal/importers"
+	"paperbox/internal/jobs"
+	"paperbox/internal/kube"
+	"paperbox/internal/leak"
+	"paperbox/internal/lint"
+	"paperbox/internal/metrics"
+	"paperbox/internal/openapi"
+	"paperbox/internal/plugins"
+	"paperbox/internal/protobuf"
+	"paperbox/internal/report"
+	"paperbox/internal/response"
+	"paperbox/internal/schema"
+	"paperbox/internal/search"
+	"paperbox/internal/session"
+	"paperbox/internal/transform"
+	"paperbox/internal/tunnel"
+	"paperbox/internal/version"
+	"paperbox/internal/workspace"
+	"paperbox/internal/wsdl"
 	"paperbox/models"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App is a thin wrapper for Wails bindings
 type App struct {
 	ctx       context.Context
 	configMgr *config.Manager
+	events    *core.EventBus
+	capture   *capture.Proxy
+	webhooks  *capture.Listener
+	tunnel    *tunnel.Tunnel
+	engine    *engine.Engine
+	finder    *search.Finder
+	metrics   *metrics.Recorder
+	plugins   *plugins.Manager
+	health    *health.Monitor
+	protobuf  *protobuf.Registry
+	sessions  *session.Store
+	drafts    *drafts.Store
+	kube      *kube.Forwarder
+	jobs      *jobs.Manager
+	started   time.Time // Response files older than this were left by an earlier run
+
+	workspaceMu sync.Mutex
+	workspaces  map[string]*engine.WorkspaceContext // Runtime state by profile
+
+	inflightMu sync.Mutex
+	inflight   map[string]*context.CancelFunc // Cancels the requests being sent, by request ID
+
+	sessionMu sync.Mutex
+	recording *session.Session // Session being recorded, nil when none is
 }
 
 // NewApp creates a new App instance
 func NewApp() *App {
+	faker.RegisterTemplateFuncs(engine.DefaultFuncs)
+	transform.RegisterTemplateFuncs(engine.DefaultFuncs)
+
+	events := core.NewEventBus(nil, nil)
+	eng := engine.New()
+	eng.SetStreamEmitter(events.Emit)
+	protos := protobuf.NewRegistry()
+	engine.DefaultMiddleware.Register("protobuf", engine.OrderSend, protobuf.Middleware(protos))
 	return &App{
-		configMgr: config.NewManager(),
+		configMgr:  config.NewManager(),
+		events:     events,
+		capture:    capture.NewProxy(events),
+		webhooks:   capture.NewListener(events),
+		tunnel:     tunnel.New(),
+		engine:     eng,
+		finder:     &search.Finder{},
+		metrics:    metrics.New(),
+		plugins:    plugins.NewManager(plugins.DefaultDir(), version.Version, engine.DefaultFuncs, engine.DefaultAuthProviders, engine.DefaultMiddleware),
+		health:     health.NewMonitor(eng, events.Emit),
+		protobuf:   protos,
+		sessions:   session.NewStore(session.DefaultDir()),
+		drafts:     drafts.NewStore(drafts.DefaultDir()),
+		kube:       kube.NewForwarder(events.Emit),
+		jobs:       jobs.NewManager(events.Emit),
+		workspaces: make(map[string]*engine.WorkspaceContext),
+		inflight:   make(map[string]*context.CancelFunc),
 	}
 }
 
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	a.started = time.Now()
 
 	// Set context for config manager (needed for events)
 	a.configMgr.SetContext(ctx, nil)
+	a.events.SetContext(ctx, nil)
 
 	// Load all configurations
 	if err := a.configMgr.LoadAll(); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to startup application: %v\n", err)
 		os.Exit(1)
 	}
+	a.applySettings()
+	a.offerDrafts()
+	// The folders storage lists the root folders at once and reads their contents in the background
+	go func() { _ = a.configMgr.Requests().LoadFolders() }()
+	// Pulling from the sync server can take a while, so it does not hold up the window
+	if server := a.configMgr.User().GetConfig().Sync.Server; server != "" {
+		a.jobs.Start(ctx, jobs.KindSync, "Sync with "+server, func(context.Context, *jobs.Handle) (any, error) {
+			if err := a.configMgr.StartSync(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to start team sync: %v\n", err)
+				return nil, err
+			}
+			return nil, nil
+		})
+	}
+
+	// Usage statistics are optional, so a broken metrics file only costs the history
+	if err := a.metrics.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load usage metrics: %v\n", err)
+	}
+	a.startPlugins()
+	a.health.Start(health.DefaultInterval, a.activeHealthCheck)
+}
+
+// offerDrafts announces the edits an earlier run left unsaved, e.g. because it crashed
+func (a *App) offerDrafts() {
+	// Checking drafts needs the whole tree, so without any the folders are not read for it
+	if stashed, err := a.drafts.List(); err == nil && len(stashed) == 0 {
+		return
+	}
+	recoverable, err := a.drafts.Recoverable(a.configMgr.Requests().GetRequestsConfig(), a.started)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read drafts: %v\n", err)
+		return
+	}
+	if len(recoverable) > 0 {
+		a.events.Emit(drafts.EventRecoverable, recoverable)
+	}
+}
+
+// startPlugins discovers plugins and starts the enabled ones; a failing plugin is reported in ListPlugins
+func (a *App) startPlugins() {
+	if err := a.plugins.Discover(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to discover plugins: %v\n", err)
+		return
+	}
+	for _, name := range a.configMgr.Plugins().Enabled() {
+		if err := a.plugins.Enable(name); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start plugin %s: %v\n", name, err)
+		}
+	}
+}
+
+// domReady restores the saved window geometry once the window is shown
+func (a *App) domReady(ctx context.Context) {
+	window := a.configMgr.User().GetConfig().Window
+	if window.Width > 0 && window.Height > 0 {
+		runtime.WindowSetSize(ctx, window.Width, window.Height)
+		runtime.WindowSetPosition(ctx, window.X, window.Y)
+	}
+	if window.Maximized {
+		runtime.WindowMaximise(ctx)
+	}
+}
+
+// beforeClose saves the window geometry and flushes pending saves; it never prevents closing
+func (a *App) beforeClose(ctx context.Context) bool {
+	window := a.configMgr.User().GetConfig().Window
+	window.Maximized = runtime.WindowIsMaximised(ctx)
+	// The size of a maximised window is not the one to restore
+	if !window.Maximized {
+		window.Width, window.Height = runtime.WindowGetSize(ctx)
+		window.X, window.Y = runtime.WindowGetPosition(ctx)
+	}
+
+	if err := a.configMgr.User().Patch(map[string]interface{}{"window": window}); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save window state: %v\n", err)
+	}
+
+	// Saves are debounced; write everything now so nothing is lost on exit
+	if err := a.configMgr.FlushAll(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save configs: %v\n", err)
+	}
+	return false
+}
+
+// applySettings applies user settings owned by the app rather than the config managers
+func (a *App) applySettings() {
+	cfg := a.configMgr.User().GetConfig()
+	a.engine.SetTimeout(cfg.RequestTimeout())
+	a.engine.SetBodyLimit(cfg.MaxResponseSize(), cfg.SaveLargeResponses)
+	retry := engine.RetryPolicy{}
+	if cfg.Retry.Enabled {
+		retry = engine.RetryPolicy{MaxRetries: cfg.Retry.MaxRetries, MaxDelay: cfg.Retry.MaxDelay()}
+	}
+	a.engine.SetRetryPolicy(retry)
+	a.engine.SetConditionalRequests(cfg.ConditionalRequests)
+	a.engine.SetTransportSettings(engine.TransportSettings{
+		MaxIdleConnsPerHost: cfg.Connections.MaxIdleConnsPerHost,
+		IdleConnTimeout:     cfg.Connections.IdleConnTimeout(),
+		DisableKeepAlives:   cfg.Connections.DisableKeepAlives,
+		ForceClose:          cfg.Connections.ForceClose,
+	})
+	// Workspaces open new connections with the settings
+	a.workspaceMu.Lock()
+	for _, ws := range a.workspaces {
+		ws.Close()
+	}
+	a.workspaceMu.Unlock()
+	a.loadProtoFiles(cfg.ProtoFiles)
+}
+
+// loadProtoFiles registers the .proto files of the settings in place of the previous ones; a file
+// that no longer compiles is reported and skipped
+func (a *App) loadProtoFiles(paths []string) {
+	for _, path := range a.protobuf.Files() {
+		a.protobuf.Unregister(path)
+	}
+	for _, path := range paths {
+		if _, err := a.protobuf.Register(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load %s: %v\n", path, err)
+		}
+	}
+}
+
+func (a *App) shutdown(ctx context.Context) {
+	a.jobs.CancelAll()
+	if err := a.capture.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to stop capture proxy: %v\n", err)
+	}
+	if err := a.tunnel.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to close tunnel: %v\n", err)
+	}
+	if err := a.webhooks.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to stop webhook listener: %v\n", err)
+	}
+	a.plugins.Close()
+	a.health.Stop()
+	a.engine.CloseSSHTunnels()
+	a.kube.StopAll()
+	if _, err := a.StopSession(); err != nil && apperrors.CodeOf(err) != apperrors.NotFound {
+		fmt.Fprintf(os.Stderr, "Failed to save session: %v\n", err)
+	}
+	a.workspaceMu.Lock()
+	for _, ws := range a.workspaces {
+		ws.Close()
+	}
+	a.workspaceMu.Unlock()
+	// Also covers quitting without closing the window, when beforeClose does not run
+	if err := a.configMgr.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save configs: %v\n", err)
+	}
+	if err := a.metrics.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save usage metrics: %v\n", err)
+	}
+	if err := response.RemoveTempFiles(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to remove response temp files: %v\n", err)
+	}
 }
 
 // GetRequests returns the requests for Wails bindings
 func (a *App) GetRequests() models.Requests {
-	reqConfig := a.configMgr.GetRequests()
+	reqConfig, revision := a.configMgr.Requests().Snapshot()
 	if reqConfig == nil {
 		return models.NewRequests()
 	}
 	return models.Requests{
 		Values:    reqConfig.Values,
 		RootOrder: reqConfig.RootOrder,
+		Revision:  revision,
+		Unloaded:  a.configMgr.Requests().UnloadedFolders(),
 	}
 }
 
+// LoadFolder reads the contents of a root folder the folders storage has not read yet, e.g. when it
+// is opened before the background load got to it. The tree is announced with requests:updated.
+func (a *App) LoadFolder(folderId string) error {
+	return a.configMgr.Requests().LoadFolder(folderId)
+}
+
 // SetRequestsPatch applies a partial update to the requests configuration
 func (a *App) SetRequestsPatch(patch models.RequestsPatch) error {
-	return a.configMgr.Requests().PatchValues(patch.Values)
+	return a.configMgr.Requests().AtRevision(patch.Revision).PatchValues(patch.Values)
 }
 
-// AddRequest adds a new request to a parent folder
-func (a *App) AddRequest(parentId string, name string, method string, path string) (string, error) {
-	return a.configMgr.Requests().AddRequest(parentId, name, method, path)
+// AddRequest adds a new request to a parent folder.
+// Mutating bindings take the last revision the caller has seen (0 skips the check) and fail on conflict.
+func (a *App) AddRequest(parentId string, name string, method string, path string, revision uint64) (string, error) {
+	return a.configMgr.Requests().AtRevision(revision).AddRequest(parentId, name, method, path)
 }
 
 // AddFolder adds a new folder to a parent folder
-func (a *App) AddFolder(parentId string, name string) (string, error) {
-	return a.configMgr.Requests().AddFolder(parentId, name)
+func (a *App) AddFolder(parentId string, name string, revision uint64) (string, error) {
+	return a.configMgr.Requests().AtRevision(revision).AddFolder(parentId, name)
 }
 
 // AddRootFolder adds a new root-level folder (without parent)
-func (a *App) AddRootFolder(name string) (string, error) {
-	return a.configMgr.Requests().AddRootFolder(name)
+func (a *App) AddRootFolder(name string, revision uint64) (string, error) {
+	return a.configMgr.Requests().AtRevision(revision).AddRootFolder(name)
 }
 
 // DeleteItem deletes an item from the requests configuration
-func (a *App) DeleteItem(itemId string) error {
-	return a.configMgr.Requests().DeleteItem(itemId)
+func (a *App) DeleteItem(itemId string, revision uint64) error {
+	return a.configMgr.Requests().AtRevision(revision).DeleteItem(itemId)
+}
+
+// StashDraft writes the editor state of a request to a scratch file ahead of the save, so the edit
+// can be recovered if the app stops before it is saved. The UI discards it once the request is saved.
+func (a *App) StashDraft(requestId string, draft requests.Item) error {
+	return a.drafts.Stash(requestId, draft)
+}
+
+// ListDrafts returns the unsaved edits left by an earlier run, most recent first. A stale draft is
+// older than the saved request.
+func (a *App) ListDrafts() ([]drafts.Draft, error) {
+	return a.drafts.Recoverable(a.configMgr.Requests().GetRequestsConfig(), a.started)
+}
+
+// RecoverDraft saves the draft of a request over the stored request and discards the draft
+func (a *App) RecoverDraft(requestId string, revision uint64) error {
+	draft, err := a.drafts.Get(requestId)
+	if err != nil {
+		return err
+	}
+	if err := a.configMgr.Requests().AtRevision(revision).ReplaceRequest(requestId, draft.Item); err != nil {
+		return err
+	}
+	return a.drafts.Discard(requestId)
+}
+
+// DiscardDraft drops the draft of a request, after it was saved or when it is not wanted
+func (a *App) DiscardDraft(requestId string) error {
+	return a.drafts.Discard(requestId)
+}
+
+// StartCaptureProxy starts the recording proxy on the given port and returns its address
+func (a *App) StartCaptureProxy(port int) (string, error) {
+	return a.capture.Start(port)
+}
+
+// StopCaptureProxy stops the recording proxy
+func (a *App) StopCaptureProxy() error {
+	return a.capture.Stop()
+}
+
+// GetCapturedRequests returns all exchanges recorded by the capture proxy
+func (a *App) GetCapturedRequests() []capture.Capture {
+	return a.capture.List()
+}
+
+// ClearCapturedRequests drops all recorded exchanges
+func (a *App) ClearCapturedRequests() {
+	a.capture.Clear()
+}
+
+// SaveCapturedRequest turns a recorded exchange into a request inside the given folder
+func (a *App) SaveCapturedRequest(captureId string, parentFolderId string) (string, error) {
+	c, ok := a.capture.Get(captureId)
+	if !ok {
+		return "", apperrors.NotFoundf("capture not found")
+	}
+	return a.configMgr.Requests().AddRequestItem(parentFolderId, capture.ToItem(c))
+}
+
+// StartWebhookListener starts recording every request sent to the given path on a local port and
+// returns the URL to configure as the webhook target (port 0 picks a free port)
+func (a *App) StartWebhookListener(port int, path string) (string, error) {
+	return a.webhooks.Start(port, path)
+}
+
+// StopWebhookListener stops the webhook listener; received requests are kept
+func (a *App) StopWebhookListener() error {
+	return a.webhooks.Stop()
+}
+
+// GetWebhookRequests returns the requests received by the webhook listener, oldest first
+func (a *App) GetWebhookRequests() []capture.Capture {
+	return a.webhooks.List()
+}
+
+// ClearWebhookRequests drops all received webhook requests
+func (a *App) ClearWebhookRequests() {
+	a.webhooks.Clear()
+}
+
+// SaveWebhookRequest turns a received webhook into a request inside the given folder, e.g. to replay it
+func (a *App) SaveWebhookRequest(requestId string, parentFolderId string) (string, error) {
+	c, ok := a.webhooks.Get(requestId)
+	if !ok {
+		return "", apperrors.NotFoundf("webhook request not found")
+	}
+	return a.configMgr.Requests().AddRequestItem(parentFolderId, capture.ToItem(c))
+}
+
+// StartTunnel exposes a local port (e.g. the webhook listener's) through the SSH server in the
+// tunnel settings and returns its public URL. A server without a pinned host key is trusted on
+// first use: its key is saved to the settings and checked on every later login.
+func (a *App) StartTunnel(localPort int) (string, error) {
+	settings := a.configMgr.User().GetConfig().Tunnel
+	url, err := a.tunnel.Start(tunnel.Options{
+		Server:     settings.Server,
+		User:       settings.User,
+		KeyFile:    settings.KeyFile,
+		RemotePort: settings.RemotePort,
+		PublicURL:  settings.PublicURL,
+		HostKey:    settings.HostKey,
+		TrustKey: func(fingerprint string) error {
+			pinned := settings
+			pinned.HostKey = fingerprint
+			return a.configMgr.User().Patch(map[string]interface{}{"tunnel": pinned})
+		},
+	}, localPort)
+	if err != nil {
+		return "", apperrors.Wrap(apperrors.IOError, err, "failed to open tunnel")
+	}
+	return url, nil
+}
+
+// StopTunnel closes the tunnel
+func (a *App) StopTunnel() error {
+	return a.tunnel.Stop()
+}
+
+// GetTunnelURL returns the public URL of the open tunnel, or an empty string
+func (a *App) GetTunnelURL() string {
+	return a.tunnel.URL()
+}
+
+// ImportHAR starts importing every unique request from a HAR file into the given folder; requests
+// already in the folder are merged as options says. It returns the job ID (see startImport).
+func (a *App) ImportHAR(path string, parentFolderId string, options requests.ImportOptions) string {
+	return a.startImport(path, func(ctx context.Context, job *jobs.Handle) (*requests.ImportSummary, error) {
+		data, err := importers.ReadFile(ctx, path, readProgress(job))
+		if err != nil {
+			return nil, err
+		}
+		file, err := har.Parse(data)
+		if err != nil {
+			return nil, err
+		}
+		return a.configMgr.Requests().ImportContext(ctx, parentFolderId, har.ToNodes(file), options, job.Counter(importers.PhaseMerge))
+	})
+}
+
+// startImport runs an import as a background job so large files do not block the binding call, and
+// returns the job ID. The job reports the read and merge phases (see importers.PhaseRead) and its
+// result is the requests.ImportSummary; a cancelled import applies nothing.
+func (a *App) startImport(source string, task func(ctx context.Context, job *jobs.Handle) (*requests.ImportSummary, error)) string {
+	return a.jobs.Start(a.ctx, jobs.KindImport, "Import "+filepath.Base(source), func(ctx context.Context, job *jobs.Handle) (any, error) {
+		summary, err := task(ctx, job)
+		if err != nil {
+			return nil, err
+		}
+		return summary, nil
+	})
+}
+
+// readProgress reports the bytes of a file read by importers.ReadFile
+func readProgress(job *jobs.Handle) func(done, total int64) {
+	return func(done, total int64) {
+		job.Report(importers.PhaseRead, done, total, "")
+	}
+}
+
+// ExportCapturesHAR writes all exchanges recorded by the capture proxy to a HAR file; likely secrets
+// are handled as policy says
+func (a *App) ExportCapturesHAR(path string, policy leak.Policy) error {
+	captures := a.capture.List()
+	exchanges := make([]har.Exchange, 0, len(captures))
+	for _, c := range captures {
+		exchanges = append(exchanges, c.Exchange())
+	}
+
+	return writeHAR(path, exchanges, policy)
+}
+
+// ExportHistoryHAR writes the requests sent from the app that got a response, as kept in the
+// history, to a HAR file; likely secrets are handled as policy says
+func (a *App) ExportHistoryHAR(path string, policy leak.Policy) error {
+	executions := a.workspace().History.List()
+	exchanges := make([]har.Exchange, 0, len(executions))
+	for _, exec := range executions {
+		if exec.Status == 0 {
+			continue
+		}
+		exchanges = append(exchanges, exec.Exchange())
+	}
+	return writeHAR(path, exchanges, policy)
+}
+
+// writeHAR writes exchanges to a HAR file once the leak guard passed them
+func writeHAR(path string, exchanges []har.Exchange, policy leak.Policy) error {
+	data, err := har.Marshal(har.Export(exchanges, version.Version))
+	if err != nil {
+		return err
+	}
+	guard := leak.NewGuard(policy)
+	data = guard.Check(filepath.Base(path), data)
+	if err := guard.Err(); err != nil {
+		return err
+	}
+	if err := storage.NewFileWriter().WriteAtomic(path, data, 0o644); err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to write HAR file")
+	}
+	return nil
+}
+
+// ImportThunderClient starts importing a Thunder Client collection export as a folder under the
+// given parent (an empty parentId creates a root folder), merged with a folder of the same name as
+// options says. It returns the job ID.
+func (a *App) ImportThunderClient(path string, parentId string, options requests.ImportOptions) string {
+	return a.startImport(path, func(ctx context.Context, job *jobs.Handle) (*requests.ImportSummary, error) {
+		data, err := importers.ReadFile(ctx, path, readProgress(job))
+		if err != nil {
+			return nil, err
+		}
+		node, err := importers.ParseThunderClient(data)
+		if err != nil {
+			return nil, err
+		}
+		return a.configMgr.Requests().ImportContext(ctx, parentId, []requests.Node{node}, options, job.Counter(importers.PhaseMerge))
+	})
+}
+
+// ImportHTTPFile starts importing a VS Code REST Client .http/.rest file as a folder under the
+// given parent (an empty parentId creates a root folder), merged with a folder of the same name as
+// options says. It returns the job ID.
+func (a *App) ImportHTTPFile(path string, parentId string, options requests.ImportOptions) string {
+	return a.startImport(path, func(ctx context.Context, job *jobs.Handle) (*requests.ImportSummary, error) {
+		data, err := importers.ReadFile(ctx, path, readProgress(job))
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		node, err := importers.ParseHTTPFile(data, name)
+		if err != nil {
+			return nil, err
+		}
+		return a.configMgr.Requests().ImportContext(ctx, parentId, []requests.Node{node}, options, job.Counter(importers.PhaseMerge))
+	})
+}
+
+// ImportOpenAPI starts importing an OpenAPI 3 or Swagger 2.0 document (file path or URL) as a
+// folder under the given parent, merged with a folder of the same name as options says. The folder
+// is linked to the document for drift checks. It returns the job ID.
+func (a *App) ImportOpenAPI(location string, parentId string, options requests.ImportOptions) string {
+	return a.startImport(location, func(ctx context.Context, job *jobs.Handle) (*requests.ImportSummary, error) {
+		job.Report(importers.PhaseRead, 0, 0, location)
+		doc, err := openapi.Load(ctx, location)
+		if err != nil {
+			return nil, err
+		}
+		if len(doc.Endpoints()) == 0 {
+			return nil, apperrors.Invalidf("the OpenAPI document defines no operations")
+		}
+		node := openapi.Import(doc, strings.TrimSuffix(filepath.Base(location), filepath.Ext(location)))
+		node.Item.Spec = location
+		return a.configMgr.Requests().ImportContext(ctx, parentId, []requests.Node{node}, options, job.Counter(importers.PhaseMerge))
+	})
+}
+
+// ListWSDLOperations lists the SOAP operations of a WSDL document (file path or URL)
+func (a *App) ListWSDLOperations(location string) ([]wsdl.Operation, error) {
+	defs, err := wsdl.Load(a.ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	return defs.Operations(), nil
+}
+
+// ImportWSDL starts importing the SOAP operations of a WSDL document (file path or URL) as requests
+// with a generated envelope under the given parent. operationIds selects operations by the IDs
+// ListWSDLOperations returns, all when empty. It returns the job ID.
+func (a *App) ImportWSDL(location string, parentId string, operationIds []string, options requests.ImportOptions) string {
+	return a.startImport(location, func(ctx context.Context, job *jobs.Handle) (*requests.ImportSummary, error) {
+		job.Report(importers.PhaseRead, 0, 0, location)
+		defs, err := wsdl.Load(ctx, location)
+		if err != nil {
+			return nil, err
+		}
+		node := wsdl.Import(defs, strings.TrimSuffix(filepath.Base(location), filepath.Ext(location)), operationIds)
+		if len(node.Children) == 0 {
+			return nil, apperrors.Invalidf("the WSDL document defines no SOAP operations")
+		}
+		return a.configMgr.Requests().ImportContext(ctx, parentId, []requests.Node{node}, options, job.Counter(importers.PhaseMerge))
+	})
+}
+
+// LinkSpec links a folder to an OpenAPI document (file path or URL) for drift checks; empty unlinks it
+func (a *App) LinkSpec(folderId string, location string) error {
+	return a.configMgr.Requests().SetSpec(folderId, location)
+}
+
+// CheckSpecDrift compares the requests under a folder with the operations of its linked OpenAPI spec
+func (a *App) CheckSpecDrift(folderId string) (*openapi.DriftReport, error) {
+	cfg := a.configMgr.GetRequests()
+	folder, exists := cfg.Values[folderId]
+	if !exists || folder.Type != requests.ItemTypeFolder {
+		return nil, apperrors.NotFoundf("folder not found")
+	}
+	if folder.Spec == "" {
+		return nil, apperrors.Invalidf("folder %q has no linked OpenAPI spec", folder.Name)
+	}
+
+	doc, err := openapi.Load(a.ctx, folder.Spec)
+	if err != nil {
+		return nil, err
+	}
+	report := openapi.CheckDrift(doc, cfg, folderId)
+	report.Spec = folder.Spec
+	return report, nil
+}
+
+// ExportOpenAPI synthesizes an OpenAPI 3.1 document (JSON) from the requests under a folder, using
+// stored bodies and the last response recorded for each request as examples; likely secrets are
+// handled as policy says
+func (a *App) ExportOpenAPI(folderId string, policy leak.Policy) (string, error) {
+	cfg := a.configMgr.GetRequests()
+	if folder, exists := cfg.Values[folderId]; !exists || folder.Type != requests.ItemTypeFolder {
+		return "", apperrors.NotFoundf("folder not found")
+	}
+
+	samples := make(map[string]openapi.ResponseSample)
+	for _, exec := range a.workspace().History.List() {
+		if exec.RequestID != "" && exec.Error == "" && exec.Binary == nil {
+			samples[exec.RequestID] = openapi.ResponseSample{Status: exec.Status, MimeType: exec.MimeType, Body: exec.Body}
+		}
+	}
+
+	data, err := openapi.Marshal(openapi.Export(cfg, folderId, samples))
+	if err != nil {
+		return "", err
+	}
+	guard := leak.NewGuard(policy)
+	data = guard.Check("openapi.json", data)
+	if err := guard.Err(); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ListPlugins returns the installed plugins and whether they are running
+func (a *App) ListPlugins() []plugins.Info {
+	return a.plugins.List()
+}
+
+// ReloadPlugins rescans the plugins directory and starts newly installed plugins that are enabled
+func (a *App) ReloadPlugins() []plugins.Info {
+	a.startPlugins()
+	return a.plugins.List()
+}
+
+// EnablePlugin starts a plugin and remembers it for the next launch
+func (a *App) EnablePlugin(name string) error {
+	if err := a.plugins.Enable(name); err != nil {
+		return err
+	}
+	return a.configMgr.Plugins().SetEnabled(name, true)
+}
+
+// DisablePlugin stops a plugin and keeps it stopped on the next launch (also for uninstalled plugins)
+func (a *App) DisablePlugin(name string) error {
+	if err := a.plugins.Disable(name); err != nil && apperrors.CodeOf(err) != apperrors.NotFound {
+		return err
+	}
+	return a.configMgr.Plugins().SetEnabled(name, false)
+}
+
+// GetPluginFormats returns the import and export formats provided by running plugins
+func (a *App) GetPluginFormats() map[string][]plugins.FormatRef {
+	return map[string][]plugins.FormatRef{
+		"importers": a.plugins.Importers(),
+		"exporters": a.plugins.Exporters(),
+	}
+}
+
+// ImportWithPlugin starts converting a file with a plugin's import format and adding the result
+// under the given parent (an empty parentId adds root folders), merged with existing items as
+// options says. It returns the job ID.
+func (a *App) ImportWithPlugin(plugin string, format string, path string, parentId string, options requests.ImportOptions) string {
+	return a.startImport(path, func(ctx context.Context, job *jobs.Handle) (*requests.ImportSummary, error) {
+		data, err := importers.ReadFile(ctx, path, readProgress(job))
+		if err != nil {
+			return nil, err
+		}
+		nodes, err := a.plugins.Import(plugin, format, filepath.Base(path), data)
+		if err != nil {
+			return nil, err
+		}
+		return a.configMgr.Requests().ImportContext(ctx, parentId, nodes, options, job.Counter(importers.PhaseMerge))
+	})
+}
+
+// ExportWithPlugin writes a folder (or every root folder when folderId is empty) in a plugin's export
+// format; likely secrets the plugin wrote are handled as policy says
+func (a *App) ExportWithPlugin(plugin string, format string, folderId string, path string, policy leak.Policy) error {
+	cfg := requests.MaskSecrets(a.configMgr.GetRequests())
+	nodes := requests.ToNodes(cfg)
+	if folderId != "" {
+		node, ok := requests.ToNode(cfg, folderId)
+		if !ok {
+			return apperrors.NotFoundf("item %q not found", folderId)
+		}
+		nodes = []requests.Node{node}
+	}
+
+	data, err := a.plugins.Export(plugin, format, nodes)
+	if err != nil {
+		return err
+	}
+	guard := leak.NewGuard(policy)
+	data = guard.Check(filepath.Base(path), data)
+	if err := guard.Err(); err != nil {
+		return err
+	}
+	if err := storage.NewFileWriter().WriteAtomic(path, data, 0o644); err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to write export")
+	}
+	return nil
+}
+
+// ExportWorkspace writes requests and portable user settings into a single bundle (secrets stripped,
+// other likely secrets handled as policy says)
+func (a *App) ExportWorkspace(path string, includeSettings bool, policy leak.Policy) error {
+	var settings *workspace.Settings
+	if includeSettings {
+		settings = workspace.SettingsFromConfig(a.configMgr.User().GetConfig())
+	}
+	envs := a.configMgr.Environments().GetEnvironmentsConfig()
+	return workspace.Export(path, version.Version, a.configMgr.GetRequests(), envs, settings, policy)
+}
+
+// ExportSettings writes the portable user settings (no window state) to a settings profile
+func (a *App) ExportSettings(path string) error {
+	return workspace.ExportSettings(path, version.Version, workspace.SettingsFromConfig(a.configMgr.User().GetConfig()))
+}
+
+// ImportSettings applies the settings of a profile written by ExportSettings
+func (a *App) ImportSettings(path string) error {
+	settings, err := workspace.ImportSettings(path)
+	if err != nil {
+		return err
+	}
+	if err := a.configMgr.PatchUser(settings.Patch()); err != nil {
+		return err
+	}
+	a.applySettings()
+	return nil
+}
+
+// GetProfiles returns the names of the user profiles, the default one first
+func (a *App) GetProfiles() ([]string, error) {
+	return user.ListProfiles()
+}
+
+// GetActiveProfile returns the name of the user profile in use
+func (a *App) GetActiveProfile() string {
+	return a.configMgr.User().Profile()
+}
+
+// SwitchProfile makes another user profile active, creating it with the default settings if
+// needed; the UI receives its settings with the config:updated event
+func (a *App) SwitchProfile(name string) error {
+	if err := a.configMgr.SwitchProfile(name); err != nil {
+		return err
+	}
+	a.applySettings()
+	return nil
+}
+
+// GetReadOnlyConfigs lists the config files written by a newer version of paperbox. They are open
+// read-only and announced at startup with the <name>:readonly events.
+func (a *App) GetReadOnlyConfigs() []core.ReadOnlyConfig {
+	return a.configMgr.ReadOnlyConfigs()
+}
+
+// RunDiagnostics checks the app data directory for problems: permissions, disk space, damaged or
+// invalid configs, dangling references, left-over responses, stale lock files and team sync.
+// Pending saves are written first so the files are checked as they are in memory.
+func (a *App) RunDiagnostics() *doctor.Report {
+	if err := a.configMgr.FlushAll(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save configs: %v\n", err)
+	}
+	return doctor.Run(a.diagnosticsOptions())
+}
+
+// FixDiagnostics applies the safe fixes of RunDiagnostics and returns what is left
+func (a *App) FixDiagnostics() (*doctor.Report, error) {
+	report := a.RunDiagnostics()
+	if _, err := doctor.Fix(report); err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to fix diagnostics")
+	}
+	return doctor.Run(a.diagnosticsOptions()), nil
+}
+
+// diagnosticsOptions points the doctor at the app's data directory and state
+func (a *App) diagnosticsOptions() doctor.Options {
+	return doctor.Options{
+		Dir:         doctor.DefaultDir(),
+		ResponseDir: response.TempDir(),
+		Since:       a.started,
+		Metrics:     a.metrics,
+	}
+}
+
+// GetConfigSchema returns the JSON Schema of a config file ("requests", "user" or "environments")
+func (a *App) GetConfigSchema(name string) (map[string]interface{}, error) {
+	return schema.ForConfig(name)
+}
+
+// ImportWorkspace adds the bundle's root folders and environments to the current workspace and,
+// if requested, applies the bundled user settings
+func (a *App) ImportWorkspace(path string, applySettings bool) ([]string, error) {
+	bundle, err := workspace.Import(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := a.configMgr.Requests().AddTree("", requests.ToNodes(bundle.Requests))
+	if err != nil {
+		return nil, err
+	}
+
+	if bundle.Environments != nil {
+		if _, err := a.configMgr.Environments().AddEnvironments(bundle.Environments.Ordered()); err != nil {
+			return ids, fmt.Errorf("requests imported but environments failed: %w", err)
+		}
+	}
+
+	if applySettings && bundle.Settings != nil {
+		if patch := bundle.Settings.Patch(); len(patch) > 0 {
+			if err := a.configMgr.PatchUser(patch); err != nil {
+				return ids, fmt.Errorf("requests imported but settings failed: %w", err)
+			}
+			a.applySettings()
+		}
+	}
+
+	return ids, nil
+}
+
+// ExportItemLink encodes a request or folder as a compact link that can be pasted into chat. The
+// variables it uses travel along with their values; secrets are left out and other likely secrets
+// are handled as policy says.
+func (a *App) ExportItemLink(itemId string, policy leak.Policy) (string, error) {
+	cfg := a.configMgr.GetRequests()
+	node, ok := requests.ToNode(cfg, itemId)
+	if !ok {
+		return "", apperrors.NotFoundf("item not found")
+	}
+	items := []requests.Item{}
+	var collect func(node requests.Node)
+	collect = func(node requests.Node) {
+		items = append(items, node.Item)
+		for _, child := range node.Children {
+			collect(child)
+		}
+	}
+	collect(node)
+
+	// Values as the item sees them: folder variables win over the active environment and globals
+	src := a.engineSources()
+	effective := make(map[string]engine.EffectiveVariable)
+	for _, v := range src.EffectiveVariables(itemId) {
+		effective[v.Key] = v
+	}
+	var vars []requests.Param
+	for _, name := range engine.ReferencedVariables(items...) {
+		if v, ok := effective[name]; ok {
+			vars = append(vars, requests.Param{Key: name, Value: v.Value, Secret: v.Secret})
+		}
+	}
+	return workspace.EncodeLink(cfg, itemId, vars, policy)
+}
+
+// SaveItemLink writes the link of a request or folder to a .paperbox file
+func (a *App) SaveItemLink(itemId string, path string, policy leak.Policy) error {
+	link, err := a.ExportItemLink(itemId, policy)
+	if err != nil {
+		return err
+	}
+	if err := storage.NewFileWriter().WriteAtomic(path, []byte(link+"\n"), 0o644); err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to write link file")
+	}
+	return nil
+}
+
+// ImportItemLink adds the request or folder of a link under a parent folder. Variables of the link
+// the parent does not define yet are added to it; an empty parentId creates a root folder named
+// after the item to hold a request and its variables.
+func (a *App) ImportItemLink(data string, parentId string) (string, error) {
+	link, err := workspace.DecodeLink(data)
+	if err != nil {
+		return "", err
+	}
+	node := link.Item
+
+	if parentId == "" {
+		if node.Item.Type == requests.ItemTypeRequest {
+			node = requests.Node{Item: requests.Item{Type: requests.ItemTypeFolder, Name: node.Item.Name}, Children: []requests.Node{node}}
+		}
+		node.Item.Variables = link.WithVariables(node.Item.Variables)
+		ids, err := a.configMgr.Requests().AddTree("", []requests.Node{node})
+		if err != nil {
+			return "", err
+		}
+		return ids[0], nil
+	}
+
+	parent, exists := a.configMgr.GetRequests().Values[parentId]
+	if !exists || parent.Type != requests.ItemTypeFolder {
+		return "", apperrors.NotFoundf("parent folder not found")
+	}
+	ids, err := a.configMgr.Requests().AddTree(parentId, []requests.Node{node})
+	if err != nil {
+		return "", err
+	}
+	if vars := link.WithVariables(parent.Variables); len(vars) > len(parent.Variables) {
+		if err := a.configMgr.Requests().SetVariables(parentId, vars); err != nil {
+			return ids[0], fmt.Errorf("item imported but its variables failed: %w", err)
+		}
+	}
+	return ids[0], nil
+}
+
+// ImportItemLinkFile imports the link stored in a .paperbox file, see ImportItemLink
+func (a *App) ImportItemLinkFile(path string, parentId string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", apperrors.Wrap(apperrors.IOError, err, "failed to read file")
+	}
+	return a.ImportItemLink(string(data), parentId)
+}
+
+// SetItemBaseURL overrides the base URL for a folder or request (empty removes the override)
+func (a *App) SetItemBaseURL(itemId string, baseURL string) error {
+	return a.configMgr.Requests().SetBaseURL(itemId, baseURL)
+}
+
+// SetQueryParams replaces a request's query parameters, e.g. after editing them in bulk
+func (a *App) SetQueryParams(requestId string, params []requests.Param) error {
+	return a.configMgr.Requests().SetQueryParams(requestId, params)
+}
+
+// ParseURLIntoRequest splits a pasted full URL into the request's base URL, path and query
+// parameters (see engine.SplitURL) and returns the parts applied
+func (a *App) ParseURLIntoRequest(requestId string, fullURL string) (*engine.URLParts, error) {
+	parts, err := engine.SplitURL(a.engineSources(), requestId, fullURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.configMgr.Requests().SetURL(requestId, parts.BaseURL, parts.Path, parts.QueryParams); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// GetEnvironments returns the environments configuration with secret values masked
+func (a *App) GetEnvironments() *environments.EnvironmentsConfig {
+	return environments.MaskSecrets(a.configMgr.Environments().GetEnvironmentsConfig())
+}
+
+// GetEnvironmentsEncryption returns whether the environments file is encrypted at rest and still
+// locked; a locked file was announced at startup with the environments:locked event
+func (a *App) GetEnvironmentsEncryption() environments.EncryptionStatus {
+	return a.configMgr.Environments().Encryption()
+}
+
+// UnlockEnvironments decrypts the environments file with the workspace passphrase; until then the
+// environments are empty and cannot be changed
+func (a *App) UnlockEnvironments(passphrase string) error {
+	return a.configMgr.Environments().Unlock(passphrase)
+}
+
+// EncryptEnvironments encrypts the environments file at rest with the workspace passphrase (Argon2id
+// and AES-256-GCM), replacing the plaintext file; on an encrypted file it changes the passphrase
+func (a *App) EncryptEnvironments(passphrase string) error {
+	return a.configMgr.Environments().EnableEncryption(passphrase)
+}
+
+// DecryptEnvironments stores the environments file as plaintext again, given the passphrase in use
+func (a *App) DecryptEnvironments(passphrase string) error {
+	return a.configMgr.Environments().DisableEncryption(passphrase)
+}
+
+// RevealVariable returns the value of a secret variable of an environment (empty envId for a global).
+// confirm must be set, so secrets are only shown on an explicit user action.
+func (a *App) RevealVariable(envId string, key string, confirm bool) (string, error) {
+	if !confirm {
+		return "", apperrors.Invalidf("revealing a secret must be confirmed")
+	}
+	cfg := a.configMgr.Environments().GetEnvironmentsConfig()
+	vars := cfg.Globals
+	if envId != "" {
+		env, exists := cfg.Values[envId]
+		if !exists {
+			return "", apperrors.NotFoundf("environment not found")
+		}
+		vars = env.Variables
+	}
+	for _, v := range vars {
+		if v.Key == key {
+			return v.Value, nil
+		}
+	}
+	return "", apperrors.NotFoundf("variable %q not found", key)
+}
+
+// AddEnvironment creates a new environment
+func (a *App) AddEnvironment(name string) (string, error) {
+	return a.configMgr.Environments().AddEnvironment(name)
+}
+
+// UpdateEnvironment replaces an environment's name, base URL and variables
+func (a *App) UpdateEnvironment(envId string, env environments.Environment) error {
+	return a.configMgr.Environments().UpdateEnvironment(envId, env)
+}
+
+// DeleteEnvironment deletes an environment
+func (a *App) DeleteEnvironment(envId string) error {
+	return a.configMgr.Environments().DeleteEnvironment(envId)
+}
+
+// ListDockerEndpoints lists the ports published by the running containers of the local Docker engine
+// (DOCKER_HOST, or the default socket)
+func (a *App) ListDockerEndpoints() ([]docker.Endpoint, error) {
+	client, err := docker.NewClient("")
+	if err != nil {
+		return nil, err
+	}
+	return client.Endpoints(a.ctx)
+}
+
+// SyncDockerHostOverrides replaces the host overrides an environment got from Docker with overrides
+// for the containers running now (service or container name with the container port, to the
+// published port) and returns them; overrides added by hand are kept
+func (a *App) SyncDockerHostOverrides(envId string) ([]environments.HostOverride, error) {
+	endpoints, err := a.ListDockerEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	overrides := docker.HostOverrides(endpoints)
+	if err := a.configMgr.Environments().SetGeneratedHostOverrides(envId, docker.Source, overrides); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// SetDockerBaseURL sets the base URL of an environment to where a container or compose service
+// publishes a container port, and returns it
+func (a *App) SetDockerBaseURL(envId string, container string, port int) (string, error) {
+	endpoints, err := a.ListDockerEndpoints()
+	if err != nil {
+		return "", err
+	}
+	endpoint, ok := docker.Find(endpoints, container, port)
+	if !ok {
+		return "", apperrors.NotFoundf("no running container %q publishes port %d", container, port)
+	}
+	if err := a.configMgr.Environments().SetBaseURL(envId, endpoint.URL); err != nil {
+		return "", err
+	}
+	return endpoint.URL, nil
+}
+
+// StartK8sPortForward forwards a local port to a port of a Kubernetes service and publishes the
+// local port as a variable of the active environment (see kube.VariableName). Empty kubeContext
+// and namespace use those of the kubeconfig.
+func (a *App) StartK8sPortForward(kubeContext string, namespace string, service string, port int) (*kube.Forward, error) {
+	envs := a.configMgr.Environments().GetEnvironmentsConfig()
+	if envs.Active == "" {
+		return nil, apperrors.Invalidf("no active environment to publish the local port in")
+	}
+	forward, err := a.kube.Start(a.ctx, kubeContext, namespace, service, port)
+	if err != nil {
+		return nil, err
+	}
+	variable := kube.VariableName(service)
+	if err := a.configMgr.Environments().SetVariables(envs.Active, map[string]string{variable: strconv.Itoa(forward.LocalPort)}); err != nil {
+		_ = a.kube.Stop(forward.ID)
+		return nil, err
+	}
+	a.kube.SetVariable(forward.ID, variable)
+	forward.Variable = variable
+	return forward, nil
+}
+
+// StopK8sPortForward ends a Kubernetes port-forward; the variable it published is kept
+func (a *App) StopK8sPortForward(id string) error {
+	return a.kube.Stop(id)
+}
+
+// ListK8sPortForwards returns the running Kubernetes port-forwards
+func (a *App) ListK8sPortForwards() []kube.Forward {
+	return a.kube.List()
+}
+
+// SetActiveEnvironment selects the environment used when resolving requests (empty for none)
+func (a *App) SetActiveEnvironment(envId string) error {
+	return a.configMgr.Environments().SetActive(envId)
+}
+
+// engineSources collects the current configs the engine resolves requests against
+func (a *App) engineSources() engine.Sources {
+	userCfg := a.configMgr.User().GetConfig()
+	return engine.Sources{
+		Requests:    a.configMgr.GetRequests(),
+		Environment: a.configMgr.Environments().GetActive(),
+		Globals:     a.configMgr.Environments().GetEnvironmentsConfig().Globals,
+		UserBaseURL: userCfg.BaseURL,
+		ProcessEnv:  userCfg.AllowProcessEnv,
+		Workspace:   a.workspace(),
+	}
+}
+
+// workspace returns the runtime state (cookies, connections, history) of the active profile, so
+// nothing a request picked up in one profile is sent or shown in another
+func (a *App) workspace() *engine.WorkspaceContext {
+	name := a.configMgr.User().Profile()
+	a.workspaceMu.Lock()
+	defer a.workspaceMu.Unlock()
+	ws, ok := a.workspaces[name]
+	if !ok {
+		ws = engine.NewWorkspaceContext(name)
+		a.workspaces[name] = ws
+	}
+	return ws
+}
+
+// ClearSession drops the cookies, open connections and cached responses of the active profile
+func (a *App) ClearSession() {
+	a.workspace().Reset()
+}
+
+// ClearResponseCache forgets the validators stored for conditional requests in the active profile
+func (a *App) ClearResponseCache() {
+	a.workspace().ClearCache()
+}
+
+// GetEffectiveVariables returns the variables a request resolves against with the scope each value
+// comes from and the definitions it overrides. envId selects the environment (empty for the active one).
+func (a *App) GetEffectiveVariables(requestId string, envId string) ([]engine.EffectiveVariable, error) {
+	src := a.engineSources()
+	if envId != "" {
+		env, exists := a.configMgr.Environments().GetEnvironmentsConfig().Values[envId]
+		if !exists {
+			return nil, apperrors.NotFoundf("environment not found")
+		}
+		src.Environment = &env
+	}
+	if _, exists := src.Requests.Values[requestId]; !exists {
+		return nil, apperrors.NotFoundf("request not found")
+	}
+	return engine.MaskVariables(src.EffectiveVariables(requestId)), nil
+}
+
+// CheckEnvironmentHealth requests the health endpoint of an environment on its base URL and every
+// folder base URL (empty envId for the active environment); the report is also emitted as env:health
+func (a *App) CheckEnvironmentHealth(envId string) (*health.Report, error) {
+	src, envId, err := a.environmentSources(envId)
+	if err != nil {
+		return nil, err
+	}
+	return a.health.Check(a.ctx, envId, src), nil
+}
+
+// CheckSSHTunnel logs in to the SSH jump host of an environment (empty envId for the active
+// environment) and returns its host key fingerprint, to pin in the tunnel settings. A failed login
+// still reports the fingerprint in the error's hostKey detail when the server sent one.
+func (a *App) CheckSSHTunnel(envId string) (string, error) {
+	src, _, err := a.environmentSources(envId)
+	if err != nil {
+		return "", err
+	}
+	t := src.SSHTunnel()
+	if t == nil {
+		return "", apperrors.Invalidf("environment has no SSH tunnel")
+	}
+	fingerprint, err := tunnel.Check(a.ctx, t.JumpHost())
+	if err != nil {
+		return "", apperrors.Wrap(apperrors.IOError, err, "SSH tunnel check failed").WithDetail("hostKey", fingerprint)
+	}
+	return fingerprint, nil
+}
+
+// GetEnvironmentHealth returns the latest health report of an environment, nil before the first check
+func (a *App) GetEnvironmentHealth(envId string) *health.Report {
+	report, _ := a.health.Latest(envId)
+	return report
+}
+
+// activeHealthCheck selects the active environment for the periodic health checks when it has a
+// health endpoint
+func (a *App) activeHealthCheck() (string, engine.Sources, bool) {
+	src, envId, err := a.environmentSources("")
+	if err != nil || src.Environment == nil || src.Environment.HealthPath == "" {
+		return "", src, false
+	}
+	return envId, src, true
+}
+
+// environmentSources returns the engine sources with an environment active (empty envId for the
+// active one) together with its ID
+func (a *App) environmentSources(envId string) (engine.Sources, string, error) {
+	src := a.engineSources()
+	envs := a.configMgr.Environments().GetEnvironmentsConfig()
+	if envId == "" {
+		envId = envs.Active
+	}
+	if envId != "" {
+		env, exists := envs.Values[envId]
+		if !exists {
+			return src, envId, apperrors.NotFoundf("environment not found")
+		}
+		src.Environment = &env
+	}
+	return src, envId, nil
+}
+
+// FindUndefinedVariables lists the {{variables}} requests use that are missing from some or all environments
+func (a *App) FindUndefinedVariables() []engine.UndefinedVariable {
+	return engine.FindUndefinedVariables(a.configMgr.GetRequests(), a.configMgr.Environments().GetEnvironmentsConfig())
+}
+
+// FindUnusedVariables lists the global, environment and folder variables nothing refers to
+func (a *App) FindUnusedVariables() []engine.UnusedVariable {
+	return engine.FindUnusedVariables(a.configMgr.GetRequests(), a.configMgr.Environments().GetEnvironmentsConfig())
+}
+
+// SetGlobalVariables replaces the variables available in every environment
+func (a *App) SetGlobalVariables(vars []environments.Variable) error {
+	return a.configMgr.Environments().SetGlobals(vars)
+}
+
+// SetFolderVariables replaces the folder-local variables of a folder
+func (a *App) SetFolderVariables(folderId string, vars []requests.Param) error {
+	return a.configMgr.Requests().SetVariables(folderId, vars)
+}
+
+// GetEffectiveBaseURL returns the base URL a request inherits (request > folder > environment > user config)
+func (a *App) GetEffectiveBaseURL(requestId string) string {
+	return engine.BaseURLFor(a.engineSources(), requestId)
+}
+
+// SetItemAuth sets the auth for a folder or request (nil inherits from the parent folder)
+func (a *App) SetItemAuth(itemId string, auth *requests.Auth) error {
+	return a.configMgr.Requests().SetAuth(itemId, auth)
+}
+
+// SetItemBudget sets the soft size/time limits of a folder or request (nil removes them)
+func (a *App) SetItemBudget(itemId string, budget *requests.Budget) error {
+	return a.configMgr.Requests().SetBudget(itemId, budget)
+}
+
+// ResolveRequest returns the request exactly as it would be sent (variables substituted,
+// base URL applied, auth injected) without sending it; secrets are masked
+func (a *App) ResolveRequest(requestId string) (*engine.ResolvedRequest, error) {
+	resolved, err := engine.ResolveItem(a.engineSources(), requestId)
+	if err != nil {
+		return nil, err
+	}
+	masked := resolved.Masked()
+	return &masked, nil
+}
+
+// RevealExecutionRequest returns a past execution's request as it was sent, secrets included.
+// confirm must be set, so secrets are only shown on an explicit user action.
+func (a *App) RevealExecutionRequest(executionId string, confirm bool) (*engine.ResolvedRequest, error) {
+	if !confirm {
+		return nil, apperrors.Invalidf("revealing secrets must be confirmed")
+	}
+	exec, ok := a.workspace().History.Get(executionId)
+	if !ok {
+		return nil, apperrors.NotFoundf("execution not found")
+	}
+	sent := exec.Unmasked()
+	return &sent, nil
+}
+
+// InspectTLS performs a TLS handshake with the server of a URL and returns its certificate chain,
+// also when the chain is not trusted. The active environment's host overrides apply.
+func (a *App) InspectTLS(url string) (*engine.TLSInspection, error) {
+	return engine.InspectTLS(a.ctx, url, a.engineSources().HostOverrides())
+}
+
+// GetTemplateFunctions returns the names of the functions usable inside {{ }} placeholders
+func (a *App) GetTemplateFunctions() []string {
+	names := engine.DefaultFuncs.Names()
+	sort.Strings(names)
+	return names
+}
+
+// GetTextTransforms returns the operations TransformText supports
+func (a *App) GetTextTransforms() []string {
+	return transform.Names()
+}
+
+// TransformText encodes, decodes or hashes text, e.g. TransformText("a b", "urlEncode", ""); key is
+// the secret of the HMAC operations
+func (a *App) TransformText(input string, operation string, key string) (string, error) {
+	return transform.Apply(operation, input, key)
+}
+
+// GenerateSampleBody builds a realistic JSON body from a JSON Schema
+func (a *App) GenerateSampleBody(jsonSchema string) (string, error) {
+	return faker.GenerateSampleBody(jsonSchema)
+}
+
+// FormatRequestBody pretty-prints a JSON or XML request body. The type comes from contentType or,
+// when empty, from the body itself; a body that is not well-formed fails with the line of the error.
+func (a *App) FormatRequestBody(body string, contentType string) (string, error) {
+	mediaType := response.BodyMediaType(contentType, body)
+	if err := response.Validate(mediaType, body); err != nil {
+		return "", apperrors.Wrap(apperrors.ValidationFailed, err, "body is not well-formed")
+	}
+	if formatted, ok := response.Format(mediaType, body); ok {
+		return formatted, nil
+	}
+	return body, nil
+}
+
+// SendRequest resolves and sends a request, running its assertions on the response.
+// Values captured by the request's capture rules are written into the active environment.
+func (a *App) SendRequest(requestId string) (*engine.Execution, error) {
+	return a.send(a.engineSources(), requestId)
+}
+
+// SendRequestExample sends a request with one of its named request examples applied
+func (a *App) SendRequestExample(requestId string, example string) (*engine.Execution, error) {
+	src := a.engineSources()
+	src.Example = example
+	return a.send(src, requestId)
+}
+
+// send runs a request and records its execution; CancelRequest stops it while it is in flight
+func (a *App) send(src engine.Sources, requestId string) (*engine.Execution, error) {
+	ctx, cancel := context.WithCancel(a.ctx)
+	defer cancel()
+	a.inflightMu.Lock()
+	a.inflight[requestId] = &cancel
+	a.inflightMu.Unlock()
+	defer func() {
+		a.inflightMu.Lock()
+		if a.inflight[requestId] == &cancel {
+			delete(a.inflight, requestId)
+		}
+		a.inflightMu.Unlock()
+	}()
+
+	exec, err := a.engine.Run(ctx, src, requestId)
+	if err != nil {
+		return nil, err
+	}
+	src.Workspace.History.Add(exec)
+	a.recordUsage(exec)
+	a.recordSession(src, exec)
+
+	// Only used for sorting, so a failure here must not hide the response
+	_ = a.configMgr.Requests().MarkUsed(map[string]time.Time{requestId: exec.StartedAt})
+
+	if len(exec.Captured) > 0 {
+		// Saving is reported as a test result so the response is still returned to the frontend
+		envs := a.configMgr.Environments().GetEnvironmentsConfig()
+		saved := engine.TestResult{Name: "Save captured values", Passed: true}
+		if envs.Active == "" {
+			saved.Passed = false
+			saved.Message = "no active environment"
+		} else if err := a.configMgr.Environments().SetVariables(envs.Active, exec.Captured); err != nil {
+			saved.Passed = false
+			saved.Message = err.Error()
+		}
+		exec.Tests = append(exec.Tests, saved)
+	}
+
+	return exec, nil
+}
+
+// ListJobs returns the background jobs (imports, runs, sweeps, team sync) that are running and the
+// latest finished ones, newest first. Their lifecycle is announced with job:started, job:progress
+// and job:finished.
+func (a *App) ListJobs() []jobs.Job {
+	return a.jobs.List()
+}
+
+// CancelJob asks a running job to stop; it finishes with the cancelled state
+func (a *App) CancelJob(id string) error {
+	return a.jobs.Cancel(id)
+}
+
+// CancelRequest stops the latest send of a request. A streamed response ends with the records
+// received so far; any other request fails as cancelled.
+func (a *App) CancelRequest(requestId string) error {
+	a.inflightMu.Lock()
+	defer a.inflightMu.Unlock()
+	cancel, exists := a.inflight[requestId]
+	if !exists {
+		return apperrors.NotFoundf("request is not being sent")
+	}
+	(*cancel)()
+	return nil
+}
+
+// SaveResponseSnapshot records the last response of a request as its snapshot; later executions
+// are compared against it and differences are reported as failed tests
+func (a *App) SaveResponseSnapshot(requestId string) (*requests.Snapshot, error) {
+	item, exists := a.configMgr.GetRequests().Values[requestId]
+	if !exists {
+		return nil, apperrors.NotFoundf("request not found")
+	}
+	var last *engine.Execution
+	for _, exec := range a.workspace().History.List() {
+		if exec.RequestID == requestId && exec.Error == "" {
+			last = exec
+		}
+	}
+	if last == nil {
+		return nil, apperrors.NotFoundf("request has no response to snapshot yet")
+	}
+
+	snapshot := engine.NewSnapshot(last, item.Snapshot)
+	if err := a.configMgr.Requests().SetSnapshot(requestId, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// SetSnapshotSettings sets the JSON paths ignored and the headers compared by a request's snapshot
+func (a *App) SetSnapshotSettings(requestId string, ignorePaths []string, compareHeaders []string) error {
+	item, exists := a.configMgr.GetRequests().Values[requestId]
+	if !exists || item.Snapshot == nil {
+		return apperrors.NotFoundf("request has no snapshot")
+	}
+	snapshot := *item.Snapshot
+	snapshot.IgnorePaths = ignorePaths
+	snapshot.CompareHeaders = compareHeaders
+	return a.configMgr.Requests().SetSnapshot(requestId, &snapshot)
+}
+
+// DeleteResponseSnapshot removes a request's snapshot
+func (a *App) DeleteResponseSnapshot(requestId string) error {
+	return a.configMgr.Requests().SetSnapshot(requestId, nil)
+}
+
+// RegisterProtoFile compiles a .proto file, adds it to the settings and returns its message names
+func (a *App) RegisterProtoFile(path string) ([]string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to resolve .proto path")
+	}
+	messages, err := a.protobuf.Register(path)
+	if err != nil {
+		return nil, err
+	}
+	files := a.configMgr.User().GetConfig().ProtoFiles
+	if !slices.Contains(files, path) {
+		if err := a.configMgr.PatchUser(map[string]interface{}{"protoFiles": append(slices.Clone(files), path)}); err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+// UnregisterProtoFile removes a .proto file from the settings
+func (a *App) UnregisterProtoFile(path string) error {
+	a.protobuf.Unregister(path)
+	files := slices.DeleteFunc(slices.Clone(a.configMgr.User().GetConfig().ProtoFiles), func(file string) bool { return file == path })
+	return a.configMgr.PatchUser(map[string]interface{}{"protoFiles": files})
+}
+
+// ListProtoMessages returns the full names of the messages of the registered .proto files
+func (a *App) ListProtoMessages() []string {
+	return a.protobuf.Messages()
+}
+
+// SetRequestProtobuf sets the protobuf messages a request's body is encoded as and its response
+// decoded from; nil sends the body as written
+func (a *App) SetRequestProtobuf(requestId string, body *requests.ProtobufBody) error {
+	if body != nil {
+		for _, message := range []string{body.RequestMessage, body.ResponseMessage} {
+			if _, ok := a.protobuf.Lookup(message); message != "" && !ok {
+				return apperrors.NotFoundf("protobuf message %s is not registered", message)
+			}
+		}
+	}
+	return a.configMgr.Requests().SetProtobuf(requestId, body)
+}
+
+// SetRequestExamples replaces the named request and response examples of a request
+func (a *App) SetRequestExamples(requestId string, examples *requests.Examples) error {
+	return a.configMgr.Requests().SetExamples(requestId, examples)
+}
+
+// SaveResponseExample saves the response of an execution as a named response example of its
+// request, replacing an example of the same name
+func (a *App) SaveResponseExample(executionId string, name string) (*requests.ResponseExample, error) {
+	exec, ok := a.workspace().History.Get(executionId)
+	if !ok {
+		return nil, apperrors.NotFoundf("execution not found")
+	}
+	if exec.Error != "" {
+		return nil, apperrors.Invalidf("execution has no response")
+	}
+	item, exists := a.configMgr.GetRequests().Values[exec.RequestID]
+	if !exists {
+		return nil, apperrors.NotFoundf("request not found")
+	}
+
+	example := requests.ResponseExample{Name: strings.TrimSpace(name), Status: exec.Status, Body: exec.Body}
+	if example.Name == "" {
+		return nil, apperrors.Invalidf("example name is required")
+	}
+	for key, values := range exec.Headers {
+		example.Headers = append(example.Headers, requests.Header{Key: key, Value: strings.Join(values, ", ")})
+	}
+	sort.Slice(example.Headers, func(i, j int) bool { return example.Headers[i].Key < example.Headers[j].Key })
+
+	examples := &requests.Examples{}
+	if item.Examples != nil {
+		examples.Requests = item.Examples.Requests
+		for _, existing := range item.Examples.Responses {
+			if existing.Name != example.Name {
+				examples.Responses = append(examples.Responses, existing)
+			}
+		}
+	}
+	examples.Responses = append(examples.Responses, example)
+	if err := a.configMgr.Requests().SetExamples(exec.RequestID, examples); err != nil {
+		return nil, err
+	}
+	return &example, nil
+}
+
+// AddRequestNote adds a free-text note to a request
+func (a *App) AddRequestNote(requestId string, text string) (*requests.Note, error) {
+	return a.configMgr.Requests().AddNote(requestId, text, nil)
+}
+
+// PinResponseNote adds a note to the request of an execution pinning part of its response.
+// An empty excerpt pins the start of the response body.
+func (a *App) PinResponseNote(executionId string, text string, excerpt string) (*requests.Note, error) {
+	exec, ok := a.workspace().History.Get(executionId)
+	if !ok {
+		return nil, apperrors.NotFoundf("execution not found")
+	}
+	if exec.Error != "" {
+		return nil, apperrors.Invalidf("execution has no response")
+	}
+	if excerpt == "" {
+		excerpt = exec.Body
+	}
+	return a.configMgr.Requests().AddNote(exec.RequestID, text, requests.NewExcerpt(exec.Status, excerpt))
+}
+
+// UpdateRequestNote replaces the text of a request note
+func (a *App) UpdateRequestNote(requestId string, noteId string, text string) error {
+	return a.configMgr.Requests().UpdateNote(requestId, noteId, text)
+}
+
+// DeleteRequestNote removes a note from a request
+func (a *App) DeleteRequestNote(requestId string, noteId string) error {
+	return a.configMgr.Requests().DeleteNote(requestId, noteId)
+}
+
+// recordUsage adds an execution to the local usage metrics
+func (a *App) recordUsage(exec *engine.Execution) {
+	a.metrics.Record(metrics.Sample{
+		RequestID:  exec.RequestID,
+		Method:     exec.Request.Method,
+		URL:        exec.Request.URL,
+		Status:     exec.Status,
+		DurationMs: exec.DurationMs,
+		Failed:     exec.Error != "" || exec.Status >= 400,
+		At:         exec.StartedAt,
+	})
+}
+
+// GetUsageStats returns local usage statistics for "day", "week", "month" or "all"
+func (a *App) GetUsageStats(period string) (*metrics.UsageStats, error) {
+	return a.metrics.Stats(metrics.Period(period), time.Now())
+}
+
+// GetLatencyHistory returns the response times of a request over "day", "week", "month" or "all",
+// bucketed for charting
+func (a *App) GetLatencyHistory(requestId string, window string) (*metrics.LatencyHistory, error) {
+	return a.metrics.LatencyHistory(requestId, metrics.Period(window), time.Now())
+}
+
+// ExtractFromResponse evaluates a JSONPath, XPath or header expression against a previous execution's response
+func (a *App) ExtractFromResponse(executionId string, expression string, kind string) (string, error) {
+	exec, ok := a.workspace().History.Get(executionId)
+	if !ok {
+		return "", apperrors.NotFoundf("execution not found")
+	}
+	return engine.Extract(exec, expression, requests.ExtractKind(kind))
+}
+
+// SetResponseSchema attaches a JSON Schema the request's response body must match (empty removes it)
+func (a *App) SetResponseSchema(requestId string, schema string) error {
+	return a.configMgr.Requests().SetResponseSchema(requestId, schema)
+}
+
+// SetCaptures replaces a request's capture rules from declarations such as "token = body.access_token"
+func (a *App) SetCaptures(requestId string, declarations []string) error {
+	rules := make([]requests.CaptureRule, 0, len(declarations))
+	for _, declaration := range declarations {
+		if strings.TrimSpace(declaration) == "" {
+			continue
+		}
+		rule, err := engine.ParseCaptureRule(declaration)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+	return a.configMgr.Requests().SetCaptures(requestId, rules)
+}
+
+// SetAssertions replaces a request's assertions, e.g. that the XPath //Status equals "OK"
+func (a *App) SetAssertions(requestId string, assertions []requests.Assertion) error {
+	return a.configMgr.Requests().SetAssertions(requestId, assertions)
+}
+
+// GenerateAssertions derives assertions locking in a recorded response of a request (status, media
+// type, JSON fields and their types, see engine.GenerateAssertions), adds those the request does not
+// have yet and returns the generated ones. executionId names an execution in the history or,
+// failing that, a response example of the request.
+func (a *App) GenerateAssertions(requestId string, executionId string) ([]requests.Assertion, error) {
+	item, exists := a.configMgr.GetRequests().Values[requestId]
+	if !exists || item.Type != requests.ItemTypeRequest {
+		return nil, apperrors.NotFoundf("request not found")
+	}
+	exec, ok := a.workspace().History.Get(executionId)
+	if ok && exec.RequestID != requestId {
+		return nil, apperrors.Invalidf("execution is not of this request")
+	}
+	if !ok {
+		example, found := item.Examples.ResponseExample(executionId)
+		if !found {
+			return nil, apperrors.NotFoundf("no execution or response example %q", executionId)
+		}
+		exec = engine.ExampleExecution(example)
+	}
+	if exec.Error != "" {
+		return nil, apperrors.Invalidf("execution has no response")
+	}
+
+	generated := engine.GenerateAssertions(exec)
+	if err := a.configMgr.Requests().SetAssertions(requestId, engine.MergeAssertions(item.Assertions, generated)); err != nil {
+		return nil, err
+	}
+	return generated, nil
+}
+
+// SetFormFields replaces the fields of a request's URL-encoded form body; the engine encodes the
+// enabled ones when sending, so a field is toggled without editing the body
+func (a *App) SetFormFields(requestId string, fields []requests.FormField) error {
+	return a.configMgr.Requests().SetFormFields(requestId, fields)
+}
+
+// RunCollection starts a job sending every request in a folder in order, chaining captured values
+// between them, and returns the job ID. The job's result is the engine.RunResult.
+func (a *App) RunCollection(folderId string) string {
+	return a.RunCollectionRepeated(folderId, 1)
+}
+
+// RunCollectionRepeated runs a folder like RunCollection but sends each request repeat times in a row,
+// reporting status flips and latency spread per request and flagging flaky endpoints. It returns the
+// job ID.
+func (a *App) RunCollectionRepeated(folderId string, repeat int) string {
+	src := a.engineSources()
+	title := "Run " + src.Requests.Values[folderId].Name
+	return a.jobs.Start(a.ctx, jobs.KindRun, title, func(ctx context.Context, job *jobs.Handle) (any, error) {
+		opts := engine.RunOptions{Repeat: repeat, Progress: job.Counter("requests")}
+		result, err := a.engine.RunCollectionWithOptions(ctx, src, folderId, opts)
+		if err != nil {
+			return nil, err
+		}
+		a.recordRun(src, result)
+		return result, nil
+	})
+}
+
+// CompareEnvironments runs a folder against two environments, one after the other, and compares
+// the status and body of each request (empty envId for the active environment). Both runs are
+// added to the history.
+func (a *App) CompareEnvironments(folderId string, envA string, envB string) (*engine.EnvironmentComparison, error) {
+	srcA, envA, err := a.environmentSources(envA)
+	if err != nil {
+		return nil, err
+	}
+	srcB, envB, err := a.environmentSources(envB)
+	if err != nil {
+		return nil, err
+	}
+	comparison, err := a.engine.CompareEnvironments(a.ctx, srcA, srcB, folderId)
+	if err != nil {
+		return nil, err
+	}
+	comparison.EnvironmentA, comparison.EnvironmentB = envA, envB
+	a.recordRun(srcA, comparison.RunA)
+	a.recordRun(srcB, comparison.RunB)
+	return comparison, nil
+}
+
+// RunSweep sends a request once per value, one after another, with the value bound to paramName
+// (a {{variable}}, and query parameters and path variables with that key), and tabulates the
+// status and latency per value. It returns the job ID; the job's result is the engine.SweepResult.
+func (a *App) RunSweep(requestId string, paramName string, values []string) string {
+	return a.RunSweepParallel(requestId, paramName, values, 1)
+}
+
+// RunSweepParallel runs a sweep like RunSweep with up to concurrency values in flight at once, as a
+// load test
+func (a *App) RunSweepParallel(requestId string, paramName string, values []string, concurrency int) string {
+	src := a.engineSources()
+	title := "Sweep " + src.Requests.Values[requestId].Name
+	return a.jobs.Start(a.ctx, jobs.KindSweep, title, func(ctx context.Context, job *jobs.Handle) (any, error) {
+		opts := engine.SweepOptions{Param: paramName, Values: values, Concurrency: concurrency, Progress: job.Counter("values")}
+		result, err := a.engine.RunSweep(ctx, src, requestId, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, exec := range result.Executions {
+			src.Workspace.History.Add(exec)
+			a.recordUsage(exec)
+		}
+		return result, nil
+	})
+}
+
+// GetFuzzTargets lists the query parameters and JSON body fields of a request FuzzRequest can mutate
+func (a *App) GetFuzzTargets(requestId string) ([]fuzz.Target, error) {
+	item, exists := a.configMgr.GetRequests().Values[requestId]
+	if !exists || item.Type != requests.ItemTypeRequest {
+		return nil, apperrors.NotFoundf("request not found")
+	}
+	return fuzz.Targets(item), nil
+}
+
+// FuzzRequest sends a request with its selected fields mutated by boundary values, long strings,
+// injections and type confusion payloads, and reports server errors, failures, slow responses and
+// reflected payloads
+func (a *App) FuzzRequest(requestId string, opts fuzz.Options) (*fuzz.Report, error) {
+	src := a.engineSources()
+	report, err := fuzz.Run(a.ctx, a.engine, src, requestId, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, exec := range report.Executions {
+		src.Workspace.History.Add(exec)
+	}
+	return report, nil
+}
+
+// recordRun adds a run and its executions to the workspace history, the usage metrics and the
+// session being recorded
+func (a *App) recordRun(src engine.Sources, result *engine.RunResult) {
+	src.Workspace.Runs.Add(result)
+	used := make(map[string]time.Time, len(result.Executions))
+	for _, exec := range result.Executions {
+		src.Workspace.History.Add(exec)
+		a.recordUsage(exec)
+		a.recordSession(src, exec)
+		used[exec.RequestID] = exec.StartedAt
+	}
+	_ = a.configMgr.Requests().MarkUsed(used)
+}
+
+// StartSession starts recording the executions of sent requests and collection runs into a named
+// session, until StopSession
+func (a *App) StartSession(name string) (*session.Session, error) {
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+	if a.recording != nil {
+		return nil, apperrors.New(apperrors.Conflict, "session %q is already being recorded", a.recording.Name)
+	}
+	sess, err := session.New(name, a.configMgr.Environments().GetEnvironmentsConfig().Active)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.sessions.Save(sess); err != nil {
+		return nil, err
+	}
+	a.recording = sess
+	return sess, nil
+}
+
+// StopSession stops recording and returns the recorded session
+func (a *App) StopSession() (*session.Session, error) {
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+	sess := a.recording
+	if sess == nil {
+		return nil, apperrors.NotFoundf("no session is being recorded")
+	}
+	stopped := time.Now()
+	sess.StoppedAt = &stopped
+	a.recording = nil
+	return sess, a.sessions.Save(sess)
+}
+
+// GetRecordingSession returns the session being recorded, or nil
+func (a *App) GetRecordingSession() *session.Session {
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+	return a.recording
+}
+
+// recordSession adds an execution to the session being recorded, saving it so a crash loses nothing
+func (a *App) recordSession(src engine.Sources, exec *engine.Execution) {
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+	if a.recording == nil {
+		return
+	}
+	a.recording.Record(exec, src.Requests.Values[exec.RequestID].Name, src.Example)
+	if err := a.sessions.Save(a.recording); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save session: %v\n", err)
+	}
+}
+
+// ListSessions returns the recorded sessions, most recent first
+func (a *App) ListSessions() ([]session.Session, error) {
+	return a.sessions.List()
+}
+
+// GetSession returns a recorded session with its steps
+func (a *App) GetSession(sessionId string) (*session.Session, error) {
+	return a.sessions.Get(sessionId)
+}
+
+// DeleteSession deletes a recorded session
+func (a *App) DeleteSession(sessionId string) error {
+	a.sessionMu.Lock()
+	recording := a.recording != nil && a.recording.ID == sessionId
+	a.sessionMu.Unlock()
+	if recording {
+		return apperrors.New(apperrors.Conflict, "the session is still being recorded")
+	}
+	return a.sessions.Delete(sessionId)
+}
+
+// ReplaySession sends the requests of a recorded session again in order, chaining captured values
+// like a collection run. envId selects the environment; empty replays against the one the session
+// was recorded in, or the active one when it no longer exists.
+func (a *App) ReplaySession(sessionId string, envId string) (*engine.RunResult, error) {
+	sess, err := a.sessions.Get(sessionId)
+	if err != nil {
+		return nil, err
+	}
+	if envId == "" {
+		if _, exists := a.configMgr.Environments().GetEnvironmentsConfig().Values[sess.Environment]; exists {
+			envId = sess.Environment
+		}
+	}
+	src, _, err := a.environmentSources(envId)
+	if err != nil {
+		return nil, err
+	}
+	result := a.engine.RunSteps(a.ctx, src, sess.RunSteps())
+	result.SessionID = sess.ID
+	a.recordRun(src, result)
+	return result, nil
+}
+
+// GetRunReportFormats returns the formats SaveRunReport can write ("junit", "json", "html", ...)
+func (a *App) GetRunReportFormats() []string {
+	return report.DefaultReporters.Formats()
+}
+
+// SaveRunReport writes the report of a recent collection run in the given format to a file; likely
+// secrets in it are handled as policy says
+func (a *App) SaveRunReport(runId string, format string, path string, policy leak.Policy) error {
+	result, ok := a.workspace().Runs.Get(runId)
+	if !ok {
+		return apperrors.NotFoundf("run not found")
+	}
+	data, err := report.DefaultReporters.Render(format, report.NewRun(result, a.configMgr.GetRequests()))
+	if err != nil {
+		return err
+	}
+	guard := leak.NewGuard(policy)
+	data = guard.Check(filepath.Base(path), data)
+	if err := guard.Err(); err != nil {
+		return err
+	}
+	if err := storage.NewFileWriter().WriteAtomic(path, data, 0o644); err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to write run report")
+	}
+	return nil
+}
+
+// SaveResponseAs writes the decoded response body of an execution to a file
+func (a *App) SaveResponseAs(executionId string, path string) error {
+	exec, ok := a.workspace().History.Get(executionId)
+	if !ok {
+		return apperrors.NotFoundf("execution not found")
+	}
+	return exec.SaveBody(path)
+}
+
+// LintCollection checks the items below a folder with the lint rules not disabled in the settings
+func (a *App) LintCollection(folderId string) ([]lint.Finding, error) {
+	c, err := a.lintCollection(folderId)
+	if err != nil {
+		return nil, err
+	}
+	return lint.DefaultRules.Lint(c, a.configMgr.User().GetConfig().DisabledLintRules), nil
+}
+
+// ApplyLintFixes applies the fixes of the given findings of a folder and returns the findings left
+func (a *App) ApplyLintFixes(folderId string, findingIds []string) ([]lint.Finding, error) {
+	// Fixes are recomputed from the current tree rather than trusted from the UI
+	c, err := a.lintCollection(folderId)
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]bool, len(findingIds))
+	for _, id := range findingIds {
+		selected[id] = true
+	}
+	var fixes []lint.Finding
+	for _, finding := range lint.DefaultRules.Lint(c, a.configMgr.User().GetConfig().DisabledLintRules) {
+		if selected[finding.ID] {
+			fixes = append(fixes, finding)
+		}
+	}
+	changes := lint.Apply(c, fixes)
+
+	// Variables first, so moved credentials exist before the requests reference them
+	envs := a.configMgr.Environments()
+	for envId, vars := range changes.Variables {
+		if envId == "" {
+			err = envs.SetGlobals(vars)
+		} else {
+			env := envs.GetEnvironmentsConfig().Values[envId]
+			env.Variables = vars
+			err = envs.UpdateEnvironment(envId, env)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(changes.Items) > 0 {
+		if err := a.configMgr.Requests().PatchValues(changes.Items); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range changes.Deleted {
+		if err := a.configMgr.Requests().DeleteItem(id); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, exists := a.configMgr.GetRequests().Values[folderId]; !exists {
+		return []lint.Finding{}, nil
+	}
+	return a.LintCollection(folderId)
+}
+
+// lintCollection collects what a lint run of a folder looks at
+func (a *App) lintCollection(folderId string) (*lint.Collection, error) {
+	cfg := a.configMgr.GetRequests()
+	if item, exists := cfg.Values[folderId]; !exists || item.Type != requests.ItemTypeFolder {
+		return nil, apperrors.NotFoundf("folder not found")
+	}
+	return &lint.Collection{
+		Requests:     cfg,
+		Environments: a.configMgr.Environments().GetEnvironmentsConfig(),
+		FolderID:     folderId,
+	}, nil
+}
+
+// GetItemHistory returns the recorded edits of a request or folder, oldest first
+func (a *App) GetItemHistory(itemId string) ([]requests.AuditEntry, error) {
+	return a.configMgr.Requests().History(itemId)
+}
+
+// RevertItemEdit undoes one recorded edit; it fails when a changed field was edited again since
+func (a *App) RevertItemEdit(entryId string) error {
+	return a.configMgr.Requests().RevertEdit(entryId)
+}
+
+// SetItemDescription sets the markdown description of a request or folder
+func (a *App) SetItemDescription(itemId string, description string) error {
+	return a.configMgr.Requests().SetDescription(itemId, description)
+}
+
+// GenerateCollectionDocs renders a folder and its requests as a "markdown" or "html" document
+func (a *App) GenerateCollectionDocs(folderId string, format string) (string, error) {
+	return docs.Generate(a.configMgr.GetRequests(), folderId, docs.Format(format))
+}
+
+// SetItemTags replaces the tags of a request or folder
+func (a *App) SetItemTags(itemId string, tags []string) error {
+	return a.configMgr.Requests().SetTags(itemId, tags)
+}
+
+// GetItemsByTag returns the IDs of items carrying the tag, in tree order
+func (a *App) GetItemsByTag(tag string) []string {
+	return requests.ItemsWithTag(a.configMgr.GetRequests(), tag)
+}
+
+// ValidateRequestsConfig checks the current request tree and returns every issue found
+func (a *App) ValidateRequestsConfig() []requests.Issue {
+	return requests.ValidateAll(a.configMgr.GetRequests())
+}
+
+// FindOrphanItems lists the items no root folder leads to, such as requests left at the root by an
+// external edit. They are not shown in the tree.
+func (a *App) FindOrphanItems() []requests.Orphan {
+	return a.configMgr.Requests().FindOrphanItems()
+}
+
+// AdoptOrphans moves the orphan items into a folder and returns the IDs of those moved
+func (a *App) AdoptOrphans(targetFolderId string) ([]string, error) {
+	return a.configMgr.Requests().AdoptOrphans(targetFolderId)
+}
+
+// PurgeOrphans deletes the orphan items and returns how many were deleted
+func (a *App) PurgeOrphans() (int, error) {
+	return a.configMgr.Requests().PurgeOrphans()
+}
+
+// SetMaxFolderDepth changes how deep folders may be nested; 0 removes the limit
+func (a *App) SetMaxFolderDepth(limit int) error {
+	return a.configMgr.SetMaxFolderDepth(limit)
+}
+
+// GetHTTPMethods returns the methods offered for requests: the standard ones, then the custom ones
+// of the settings
+func (a *App) GetHTTPMethods() []string {
+	return append(slices.Clone(requests.HTTPMethods), a.configMgr.User().GetConfig().CustomMethods...)
+}
+
+// SetCustomMethods changes the non-standard methods requests may use (e.g. PROPFIND or PURGE) and
+// whether any uppercase method is accepted
+func (a *App) SetCustomMethods(allowAny bool, methods []string) error {
+	return a.configMgr.SetCustomMethods(allowAny, methods)
+}
+
+// SortFolder reorders a folder's children (root folders when folderId is empty)
+// by "name", "method", "lastUsed" or "updated", in "asc" or "desc" direction
+func (a *App) SortFolder(folderId string, by string, direction string) error {
+	return a.configMgr.Requests().SortFolder(folderId, requests.SortKey(by), requests.SortDirection(direction))
+}
+
+// GetRecentRequests returns the most recently sent requests with their timestamps, newest first
+func (a *App) GetRecentRequests(limit int) []requests.RecentRequest {
+	return requests.RecentRequests(a.configMgr.GetRequests(), limit)
+}
+
+// QuickOpen fuzzy-matches requests by name and path for the command palette, best first,
+// with the matched character ranges for highlighting
+func (a *App) QuickOpen(query string) []search.Match {
+	return a.finder.Search(a.configMgr.Requests().Revision(), a.configMgr.GetRequests, query, search.DefaultLimit)
+}
+
+// ToggleFavorite flips the favorite flag of an item and returns the new value
+func (a *App) ToggleFavorite(itemId string) (bool, error) {
+	return a.configMgr.Requests().ToggleFavorite(itemId)
+}
+
+// ToggleLock locks or unlocks a folder and returns the new state; a null locked flips it.
+// Edits below a locked folder fail with a LOCKED error.
+func (a *App) ToggleLock(folderId string, locked *bool) (bool, error) {
+	return a.configMgr.Requests().ToggleLock(folderId, locked)
+}
+
+// GetFavoritesFolder returns the virtual "Favorites" folder listing favorite items
+func (a *App) GetFavoritesFolder() requests.Item {
+	return requests.FavoritesFolder(a.configMgr.GetRequests())
+}
+
+// BulkUpdateItems applies add/update/move/delete operations atomically and returns the IDs of added items
+func (a *App) BulkUpdateItems(ops []requests.Operation, revision uint64) ([]string, error) {
+	return a.configMgr.Requests().AtRevision(revision).BulkUpdate(ops)
 }
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"paperbox/internal/doctor"
+	"paperbox/internal/metrics"
+	"paperbox/internal/response"
+)
+
+// runDoctor implements "paperbox doctor [--json] [--fix]" and returns the exit code: 1 when an
+// error is left, 2 for bad arguments
+func runDoctor(args []string) int {
+	flags := flag.NewFlagSet("paperbox doctor", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	fix := flags.Bool("fix", false, "apply the safe fixes, then check again")
+	dir := flags.String("dir", doctor.DefaultDir(), "app data directory to check")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	// The app is not running, so every response file is left over
+	recorder := metrics.NewAt(filepath.Join(*dir, metrics.FileName))
+	if err := recorder.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load usage metrics: %v\n", err)
+		recorder = nil
+	}
+	opts := doctor.Options{Dir: *dir, ResponseDir: response.TempDir(), Since: time.Now(), Metrics: recorder}
+
+	report := doctor.Run(opts)
+	if *fix {
+		fixed, err := doctor.Fix(report)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to apply some fixes: %v\n", err)
+		}
+		if !*asJSON {
+			fmt.Printf("Fixed %d problem(s)\n\n", fixed)
+		}
+		report = doctor.Run(opts)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	} else {
+		report.WriteText(os.Stdout)
+	}
+	if report.Status == doctor.StatusError {
+		return 1
+	}
+	return 0
+}
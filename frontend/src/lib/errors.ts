@@ -0,0 +1,29 @@
+// Error codes returned by backend bindings (see internal/apperrors)
+export type AppErrorCode = 'NOT_FOUND' | 'VALIDATION_FAILED' | 'CONFLICT' | 'IO_ERROR' | 'INTERNAL'
+
+// Structured error rejected by backend bindings
+export interface AppError {
+  code: AppErrorCode
+  message: string
+  details?: Record<string, unknown>
+}
+
+export function isAppError(err: unknown): err is AppError {
+  return typeof err === 'object' && err !== null && 'code' in err && 'message' in err
+}
+
+// True when a mutation was rejected because it was based on a stale config revision
+export function isConflict(err: unknown): boolean {
+  return isAppError(err) && err.code === 'CONFLICT'
+}
+
+// Human-readable message for any rejected value
+export function errorMessage(err: unknown, fallback?: string): string {
+  if (isAppError(err) || err instanceof Error) {
+    return err.message
+  }
+  if (typeof err === 'string' && err !== '') {
+    return err
+  }
+  return fallback ?? String(err)
+}
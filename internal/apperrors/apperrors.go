@@ -0,0 +1,110 @@
+// Package apperrors defines coded errors returned across the Wails bindings. They are serialized to the
+// frontend as {code, message, details} objects so the UI can react to the kind of failure instead of
+// parsing messages.
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Code classifies an error for the frontend
+type Code string
+
+const (
+	NotFound         Code = "NOT_FOUND"
+	ValidationFailed Code = "VALIDATION_FAILED"
+	Conflict         Code = "CONFLICT"
+	IOError          Code = "IO_ERROR"
+	Internal         Code = "INTERNAL"
+	Locked           Code = "LOCKED"
+	SecretsDetected  Code = "SECRETS_DETECTED"
+	ReadOnly         Code = "READ_ONLY"
+)
+
+// Error is a coded error. Cause is kept for errors.Is/As but not serialized.
+type Error struct {
+	Code    Code                   `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	Cause   error                  `json:"-"`
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying cause
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// WithDetail returns the error with an extra detail attached
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+	e.Details[key] = value
+	return e
+}
+
+// New creates a coded error with a formatted message
+func New(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap creates a coded error around err; the message becomes "message: err".
+// Details of a coded err are carried over so they still reach the frontend.
+func Wrap(code Code, err error, message string) *Error {
+	wrapped := &Error{Code: code, Message: fmt.Sprintf("%s: %v", message, err), Cause: err}
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		for key, value := range appErr.Details {
+			wrapped.WithDetail(key, value)
+		}
+	}
+	return wrapped
+}
+
+// NotFoundf creates a NOT_FOUND error
+func NotFoundf(format string, args ...interface{}) *Error {
+	return New(NotFound, format, args...)
+}
+
+// Invalidf creates a VALIDATION_FAILED error
+func Invalidf(format string, args ...interface{}) *Error {
+	return New(ValidationFailed, format, args...)
+}
+
+// Ensure returns err unchanged when it already carries a code, otherwise wraps it with code.
+// The message is kept as-is.
+func Ensure(err error, code Code) error {
+	if err == nil {
+		return nil
+	}
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+	return &Error{Code: code, Message: err.Error(), Cause: err}
+}
+
+// CodeOf returns the code of the first coded error in err's chain, or Internal
+func CodeOf(err error) Code {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return Internal
+}
+
+// Format converts any error into its structured form (used as the Wails error formatter).
+// The message is always the full err.Error() so wrapping context is not lost.
+func Format(err error) any {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return &Error{Code: appErr.Code, Message: err.Error(), Details: appErr.Details}
+	}
+	return &Error{Code: Internal, Message: err.Error()}
+}
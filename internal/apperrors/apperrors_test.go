@@ -0,0 +1,42 @@
+package apperrors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	cause := errors.New("disk full")
+	wrapped := fmt.Errorf("failed to save: %w", Wrap(IOError, cause, "write failed").WithDetail("file", "a.json"))
+
+	data, err := json.Marshal(Format(wrapped))
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	want := `{"code":"IO_ERROR","message":"failed to save: write failed: disk full","details":{"file":"a.json"}}`
+	if string(data) != want {
+		t.Errorf("Format() = %s, want %s", data, want)
+	}
+	if !errors.Is(wrapped, cause) {
+		t.Errorf("coded error should unwrap to its cause")
+	}
+
+	data, _ = json.Marshal(Format(errors.New("boom")))
+	if string(data) != `{"code":"INTERNAL","message":"boom"}` {
+		t.Errorf("Format() of plain error = %s", data)
+	}
+}
+
+func TestEnsureKeepsExistingCode(t *testing.T) {
+	if got := CodeOf(Ensure(NotFoundf("item not found"), ValidationFailed)); got != NotFound {
+		t.Errorf("Ensure() code = %s, want NOT_FOUND", got)
+	}
+	if got := CodeOf(Ensure(errors.New("bad"), ValidationFailed)); got != ValidationFailed {
+		t.Errorf("Ensure() code = %s, want VALIDATION_FAILED", got)
+	}
+	if Ensure(nil, Internal) != nil {
+		t.Errorf("Ensure(nil) should be nil")
+	}
+}
@@ -0,0 +1,70 @@
+package capture
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"paperbox/internal/config/requests"
+	"paperbox/internal/har"
+)
+
+// skippedHeaders are transport-managed headers that make no sense to persist on a saved request
+var skippedHeaders = map[string]bool{
+	"Content-Length":  true,
+	"Accept-Encoding": true,
+	"Host":            true,
+}
+
+// ToItem converts a captured exchange into a request item ready to be added to the collection
+func ToItem(c Capture) requests.Item {
+	item := requests.Item{
+		Type:   requests.ItemTypeRequest,
+		Name:   itemName(c),
+		Method: strings.ToUpper(c.Method),
+		Path:   c.URL,
+		Body:   c.Body,
+	}
+
+	keys := make([]string, 0, len(c.Headers))
+	for key := range c.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if skippedHeaders[key] {
+			continue
+		}
+		for _, value := range c.Headers[key] {
+			item.Headers = append(item.Headers, requests.Header{Key: key, Value: value})
+		}
+	}
+
+	return item
+}
+
+// itemName builds a readable default name such as "GET /api/users"
+func itemName(c Capture) string {
+	name := c.URL
+	if parsed, err := url.Parse(c.URL); err == nil && parsed.Path != "" {
+		name = parsed.Path
+	}
+	return strings.ToUpper(c.Method) + " " + name
+}
+
+// Exchange converts a capture into a HAR exchange for export
+func (c Capture) Exchange() har.Exchange {
+	return har.Exchange{
+		Method:          c.Method,
+		URL:             c.URL,
+		Headers:         c.Headers,
+		Body:            c.Body,
+		Status:          c.Status,
+		ResponseHeaders: c.ResponseHeaders,
+		ResponseBody:    c.ResponseBody,
+		StartedAt:       c.StartedAt,
+		Duration:        time.Duration(c.DurationMs) * time.Millisecond,
+	}
+}
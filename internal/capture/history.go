@@ -0,0 +1,50 @@
+package capture
+
+import "sync"
+
+// history keeps the most recent captures in memory, dropping the oldest beyond MaxCaptures
+type history struct {
+	mu       sync.RWMutex
+	captures []Capture
+}
+
+// add stores a capture
+func (h *history) add(c Capture) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.captures = append(h.captures, c)
+	if len(h.captures) > MaxCaptures {
+		h.captures = h.captures[len(h.captures)-MaxCaptures:]
+	}
+}
+
+// List returns a copy of all recorded captures, oldest first
+func (h *history) List() []Capture {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	result := make([]Capture, len(h.captures))
+	copy(result, h.captures)
+	return result
+}
+
+// Get returns a recorded capture by ID
+func (h *history) Get(id string) (Capture, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, c := range h.captures {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	return Capture{}, false
+}
+
+// Clear drops all recorded captures
+func (h *history) Clear() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.captures = nil
+}
@@ -0,0 +1,318 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"paperbox/internal/config/core"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxCaptures is the number of captured exchanges kept in memory (oldest are dropped first)
+	MaxCaptures = 500
+	// MaxRecordedBodySize caps how much of each request/response body is recorded
+	MaxRecordedBodySize = 1 << 20
+	// shutdownTimeout bounds how long Stop waits for in-flight exchanges
+	shutdownTimeout = 3 * time.Second
+)
+
+// hopHeaders are connection-level headers that must not be forwarded by a proxy
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Capture is a single request/response exchange observed by the proxy
+type Capture struct {
+	ID              string              `json:"id"`
+	Method          string              `json:"method"`
+	URL             string              `json:"url"`
+	Headers         map[string][]string `json:"headers,omitempty"`
+	Query           map[string][]string `json:"query,omitempty"`
+	Body            string              `json:"body,omitempty"`
+	Status          int                 `json:"status"`
+	ResponseHeaders map[string][]string `json:"responseHeaders,omitempty"`
+	ResponseBody    string              `json:"responseBody,omitempty"`
+	Truncated       bool                `json:"truncated,omitempty"`
+	Error           string              `json:"error,omitempty"`
+	StartedAt       time.Time           `json:"startedAt"`
+	DurationMs      int64               `json:"durationMs"`
+}
+
+// Proxy is an HTTP forward proxy that records every plain-HTTP exchange passing through it.
+// HTTPS traffic (CONNECT) is tunnelled untouched and is not recorded.
+type Proxy struct {
+	history
+
+	mu        sync.RWMutex
+	server    *http.Server
+	addr      string
+	transport http.RoundTripper
+	events    *core.EventBus
+}
+
+// NewProxy creates a stopped capture proxy
+func NewProxy(events *core.EventBus) *Proxy {
+	return &Proxy{
+		transport: &http.Transport{
+			Proxy:                 nil, // Never chain into another proxy (including ourselves)
+			ForceAttemptHTTP2:     false,
+			MaxIdleConnsPerHost:   8,
+			IdleConnTimeout:       30 * time.Second,
+			ResponseHeaderTimeout: 60 * time.Second,
+		},
+		events: events,
+	}
+}
+
+// Start begins listening on the given port (0 picks a free port) and returns the bound address
+func (p *Proxy) Start(port int) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.server != nil {
+		return "", fmt.Errorf("capture proxy is already running on %s", p.addr)
+	}
+	if port < 0 || port > 65535 {
+		return "", fmt.Errorf("invalid port %d", port)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return "", fmt.Errorf("failed to listen: %w", err)
+	}
+
+	p.server = &http.Server{Handler: p}
+	p.addr = listener.Addr().String()
+
+	server := p.server
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			p.events.Error("capture:error", err.Error())
+		}
+	}()
+
+	return p.addr, nil
+}
+
+// Stop shuts the proxy down; recorded captures are kept
+func (p *Proxy) Stop() error {
+	p.mu.Lock()
+	server := p.server
+	p.server = nil
+	p.addr = ""
+	p.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to stop capture proxy: %w", err)
+	}
+	return nil
+}
+
+// Addr returns the listening address, or an empty string when stopped
+func (p *Proxy) Addr() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.addr
+}
+
+// record stores a capture and notifies the UI
+func (p *Proxy) record(c Capture) {
+	p.add(c)
+	p.events.Updated("capture:recorded", c)
+}
+
+// ServeHTTP implements http.Handler
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+
+	if !r.URL.IsAbs() {
+		http.Error(w, "paperbox capture proxy only accepts absolute-form proxy requests", http.StatusBadRequest)
+		return
+	}
+
+	capture := Capture{
+		ID:        uuid.New().String(),
+		Method:    r.Method,
+		URL:       r.URL.String(),
+		Headers:   cloneHeaders(r.Header),
+		Query:     queryValues(r.URL),
+		StartedAt: time.Now(),
+	}
+
+	// Buffer the request body so it can be both recorded and forwarded
+	var reqBody []byte
+	if r.Body != nil {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+		reqBody = data
+	}
+	capture.Body, capture.Truncated = truncateBody(reqBody)
+
+	outReq, err := http.NewRequestWithContext(r.Context(), r.Method, r.URL.String(), bytes.NewReader(reqBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	outReq.Header = cloneHeaders(r.Header)
+	removeHopHeaders(outReq.Header)
+	outReq.ContentLength = int64(len(reqBody))
+
+	resp, err := p.transport.RoundTrip(outReq)
+	if err != nil {
+		capture.Error = err.Error()
+		capture.DurationMs = time.Since(capture.StartedAt).Milliseconds()
+		p.record(capture)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	removeHopHeaders(resp.Header)
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	// Stream to the client while keeping a bounded copy for the capture
+	recorded := &limitedBuffer{limit: MaxRecordedBodySize}
+	if _, err := io.Copy(w, io.TeeReader(resp.Body, recorded)); err != nil {
+		capture.Error = err.Error()
+	}
+
+	capture.Status = resp.StatusCode
+	capture.ResponseHeaders = cloneHeaders(resp.Header)
+	capture.ResponseBody = recorded.buf.String()
+	capture.Truncated = capture.Truncated || recorded.truncated
+	capture.DurationMs = time.Since(capture.StartedAt).Milliseconds()
+	p.record(capture)
+}
+
+// tunnel blindly relays an HTTPS CONNECT session between client and upstream
+func (p *Proxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := net.DialTimeout("tcp", r.Host, 10*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	client, _, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		client.Close()
+		upstream.Close()
+		return
+	}
+
+	go relay(upstream, client)
+	go relay(client, upstream)
+}
+
+// relay copies from src to dst and closes both ends when done
+func relay(dst, src net.Conn) {
+	defer dst.Close()
+	defer src.Close()
+	_, _ = io.Copy(dst, src)
+}
+
+// cloneHeaders returns a deep copy of the header map
+func cloneHeaders(h http.Header) map[string][]string {
+	if len(h) == 0 {
+		return nil
+	}
+	return map[string][]string(h.Clone())
+}
+
+// queryValues returns the parsed query of u, or nil when it has none
+func queryValues(u *url.URL) map[string][]string {
+	if u.RawQuery == "" {
+		return nil
+	}
+	return map[string][]string(u.Query())
+}
+
+// removeHopHeaders strips connection-level headers, including those named in Connection
+func removeHopHeaders(h http.Header) {
+	for _, value := range h.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// truncateBody converts a body to string, cutting it at MaxRecordedBodySize
+func truncateBody(body []byte) (string, bool) {
+	if len(body) > MaxRecordedBodySize {
+		return string(body[:MaxRecordedBodySize]), true
+	}
+	return string(body), false
+}
+
+// limitedBuffer is an io.Writer that keeps at most limit bytes and silently drops the rest
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(data []byte) (int, error) {
+	remaining := b.limit - b.buf.Len()
+	if remaining <= 0 {
+		b.truncated = b.truncated || len(data) > 0
+		return len(data), nil
+	}
+	if len(data) > remaining {
+		b.buf.Write(data[:remaining])
+		b.truncated = true
+		return len(data), nil
+	}
+	b.buf.Write(data)
+	return len(data), nil
+}
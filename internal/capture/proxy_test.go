@@ -0,0 +1,72 @@
+package capture
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"paperbox/internal/config/core"
+)
+
+func TestProxyRecordsExchange(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Upstream", "yes")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("echo:" + string(body)))
+	}))
+	defer upstream.Close()
+
+	proxy := NewProxy(core.NewEventBus(nil, nil))
+	addr, err := proxy.Start(0)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer proxy.Stop()
+
+	proxyURL, _ := url.Parse("http://" + addr)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	resp, err := client.Post(upstream.URL+"/api/users?page=2", "application/json", strings.NewReader(`{"a":1}`))
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated || string(body) != `echo:{"a":1}` {
+		t.Fatalf("proxied response = %d %q", resp.StatusCode, body)
+	}
+
+	captures := proxy.List()
+	if len(captures) != 1 {
+		t.Fatalf("List() len = %d, want 1", len(captures))
+	}
+	c := captures[0]
+	if c.Method != "POST" || c.Status != http.StatusCreated || c.Body != `{"a":1}` || c.ResponseBody != `echo:{"a":1}` {
+		t.Errorf("capture = %+v", c)
+	}
+
+	item := ToItem(c)
+	if item.Name != "POST /api/users" || item.Path != upstream.URL+"/api/users?page=2" {
+		t.Errorf("ToItem() = %+v", item)
+	}
+	for _, h := range item.Headers {
+		if h.Key == "Content-Length" {
+			t.Errorf("ToItem() kept transport header %q", h.Key)
+		}
+	}
+}
+
+func TestProxyRejectsOriginFormRequests(t *testing.T) {
+	proxy := NewProxy(core.NewEventBus(nil, nil))
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/relative", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("ServeHTTP() status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,144 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"paperbox/internal/config/core"
+
+	"github.com/google/uuid"
+)
+
+// WebhookStatus is the status code the listener answers every recorded request with
+const WebhookStatus = http.StatusOK
+
+// Listener is a local HTTP server that records every request it receives, for developing
+// webhook consumers without an external inspection service
+type Listener struct {
+	history
+
+	mu     sync.RWMutex
+	server *http.Server
+	addr   string
+	path   string
+	events *core.EventBus
+}
+
+// NewListener creates a stopped webhook listener
+func NewListener(events *core.EventBus) *Listener {
+	return &Listener{events: events}
+}
+
+// Start listens on the given port (0 picks a free port) and returns the URL to send webhooks to.
+// Only requests under path are recorded; an empty path accepts every request.
+func (l *Listener) Start(port int, path string) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.server != nil {
+		return "", fmt.Errorf("webhook listener is already running on %s", l.addr)
+	}
+	if port < 0 || port > 65535 {
+		return "", fmt.Errorf("invalid port %d", port)
+	}
+	path = "/" + strings.Trim(path, "/")
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return "", fmt.Errorf("failed to listen: %w", err)
+	}
+
+	l.server = &http.Server{Handler: l}
+	l.addr = listener.Addr().String()
+	l.path = path
+
+	server := l.server
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			l.events.Error("webhook:error", err.Error())
+		}
+	}()
+
+	return l.urlLocked(), nil
+}
+
+// Stop shuts the listener down; recorded requests are kept
+func (l *Listener) Stop() error {
+	l.mu.Lock()
+	server := l.server
+	l.server = nil
+	l.addr = ""
+	l.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to stop webhook listener: %w", err)
+	}
+	return nil
+}
+
+// URL returns the URL webhooks should be sent to, or an empty string when stopped
+func (l *Listener) URL() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.urlLocked()
+}
+
+// urlLocked builds the listening URL (must hold the lock)
+func (l *Listener) urlLocked() string {
+	if l.addr == "" {
+		return ""
+	}
+	return "http://" + l.addr + l.path
+}
+
+// accepts reports whether a request path is under the listened path
+func (l *Listener) accepts(path string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.path == "/" || path == l.path || strings.HasPrefix(path, l.path+"/")
+}
+
+// ServeHTTP implements http.Handler
+func (l *Listener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !l.accepts(r.URL.Path) {
+		http.NotFound(w, r)
+		return
+	}
+
+	target := *r.URL
+	target.Scheme, target.Host = "http", r.Host
+	c := Capture{
+		ID:        uuid.New().String(),
+		Method:    r.Method,
+		URL:       target.String(),
+		Headers:   cloneHeaders(r.Header),
+		Query:     queryValues(r.URL),
+		StartedAt: time.Now(),
+		Status:    WebhookStatus,
+	}
+
+	body := &limitedBuffer{limit: MaxRecordedBodySize}
+	if _, err := io.Copy(body, r.Body); err != nil {
+		c.Error = err.Error()
+	}
+	c.Body, c.Truncated = body.buf.String(), body.truncated
+	c.DurationMs = time.Since(c.StartedAt).Milliseconds()
+
+	w.WriteHeader(WebhookStatus)
+	l.add(c)
+	l.events.Updated("webhook:received", c)
+}
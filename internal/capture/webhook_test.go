@@ -0,0 +1,74 @@
+package capture
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"paperbox/internal/config/core"
+)
+
+func TestListenerRecordsRequests(t *testing.T) {
+	listener := NewListener(core.NewEventBus(nil, nil))
+	webhookURL, err := listener.Start(0, "hooks/github/")
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer listener.Stop()
+
+	if !strings.HasSuffix(webhookURL, "/hooks/github") || listener.URL() != webhookURL {
+		t.Fatalf("Start() URL = %q", webhookURL)
+	}
+	if _, err := listener.Start(0, ""); err == nil {
+		t.Error("second Start() should fail while running")
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, webhookURL+"/push?delivery=42", strings.NewReader(`{"ref":"main"}`))
+	req.Header.Set("X-GitHub-Event", "push")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != WebhookStatus {
+		t.Errorf("status = %d, want %d", resp.StatusCode, WebhookStatus)
+	}
+
+	// Requests outside the path are answered but not recorded
+	resp, err = http.Get(strings.TrimSuffix(webhookURL, "/hooks/github") + "/other")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status outside path = %d, want 404", resp.StatusCode)
+	}
+
+	captures := listener.List()
+	if len(captures) != 1 {
+		t.Fatalf("List() len = %d, want 1", len(captures))
+	}
+	c := captures[0]
+	if c.Method != "POST" || c.Body != `{"ref":"main"}` || c.Query["delivery"][0] != "42" || c.Headers["X-Github-Event"][0] != "push" {
+		t.Errorf("capture = %+v", c)
+	}
+	if c.URL != webhookURL+"/push?delivery=42" {
+		t.Errorf("capture URL = %q", c.URL)
+	}
+	if _, ok := listener.Get(c.ID); !ok {
+		t.Error("Get() did not find the capture")
+	}
+
+	if err := listener.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if listener.URL() != "" || len(listener.List()) != 1 {
+		t.Error("Stop() should clear the URL and keep recorded requests")
+	}
+	listener.Clear()
+	if len(listener.List()) != 0 {
+		t.Error("Clear() kept recorded requests")
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"time"
 
+	"paperbox/internal/apperrors"
 	"paperbox/internal/config/storage"
 
 	"github.com/wailsapp/wails/v2/pkg/logger"
@@ -24,6 +26,19 @@ type BaseManager[T any] struct {
 	loader     func() (*T, error)
 	validator  func(*T) error
 	ensureFunc func(*T) // Function to ensure version and defaults
+	split      func(cfg *T, ids []string) (interface{}, map[string]interface{})
+	present    func(cfg *T) interface{}
+	revision   uint64 // Incremented on every successful mutation; starts at 1 once loaded
+
+	// Forward-version detection: a config newer than currentVersion is loaded read-only
+	currentVersion int
+	version        func(*T) int
+	readOnly       *ReadOnlyConfig
+
+	// Unsaved changes: dirtyAll when the whole config must be written, otherwise dirtyItems
+	dirty      bool
+	dirtyAll   bool
+	dirtyItems map[string]struct{}
 }
 
 // BaseManagerOptions contains options for creating a BaseManager.
@@ -34,27 +49,105 @@ type BaseManagerOptions[T any] struct {
 	Loader     func() (*T, error)
 	Validator  func(*T) error
 	EnsureFunc func(*T)
+	// Split returns the config without its items plus the listed items (nil for removed ones).
+	// With a storage.ItemStorage it lets saves after UpdateItemsAt write only the changed items.
+	Split func(cfg *T, ids []string) (document interface{}, items map[string]interface{})
+	// Present converts the config into the payload of ":updated" events (e.g. to mask secrets)
+	Present func(cfg *T) interface{}
+	// CurrentVersion and Version detect a config written by a newer version of paperbox. Such a
+	// config is loaded without validation and read-only: changes and saves fail with READ_ONLY,
+	// so the file, including what this version does not understand, is left as it is.
+	CurrentVersion int
+	Version        func(cfg *T) int
 }
 
 // NewBaseManager creates a new BaseManager with the provided options.
 func NewBaseManager[T any](opts BaseManagerOptions[T]) *BaseManager[T] {
 	return &BaseManager[T]{
 		debounce:   NewDebouncer(DefaultDebounceDuration),
-		events:     NewEventBus(context.TODO(), nil),
+		events:     NewEventBus(nil, nil),
 		storage:    opts.Storage,
 		configFile: opts.ConfigFile,
 		eventName:  opts.EventName,
 		loader:     opts.Loader,
 		validator:  opts.Validator,
 		ensureFunc: opts.EnsureFunc,
+		split:      opts.Split,
+		present:    opts.Present,
+
+		currentVersion: opts.CurrentVersion,
+		version:        opts.Version,
 	}
 }
 
+// presented returns the config as sent in events. Must be called with the lock held.
+func (b *BaseManager[T]) presented() interface{} {
+	if b.present != nil {
+		return b.present(b.config)
+	}
+	return b.config
+}
+
 // SetContext sets the Wails runtime context for emitting events.
 func (b *BaseManager[T]) SetContext(ctx context.Context, log logger.Logger) {
 	b.events.SetContext(ctx, log)
 }
 
+// SetDebounceDuration changes how long saves are delayed after a change.
+func (b *BaseManager[T]) SetDebounceDuration(duration time.Duration) {
+	b.debounce.SetDuration(duration)
+}
+
+// Flush writes a pending debounced save immediately. It must not be called with the lock held.
+func (b *BaseManager[T]) Flush() error {
+	if !b.debounce.Cancel() {
+		return nil
+	}
+	return b.Save()
+}
+
+// Close flushes pending changes; later changes are saved without delay.
+func (b *BaseManager[T]) Close() error {
+	err := b.Flush()
+	b.debounce.Close()
+	return err
+}
+
+// SetStorage replaces the storage backend and loader; the config must be reloaded afterwards.
+func (b *BaseManager[T]) SetStorage(s storage.Storage, loader func() (*T, error)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.storage = s
+	b.loader = loader
+}
+
+// SetConfigFile points the manager at another file (e.g. of another user profile) and its loader;
+// pending changes should be flushed first and the config must be reloaded afterwards.
+func (b *BaseManager[T]) SetConfigFile(filePath string, loader func() (*T, error)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.configFile = filePath
+	b.loader = loader
+}
+
+// ConfigFile returns the file the config is saved to.
+func (b *BaseManager[T]) ConfigFile() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.configFile
+}
+
+// NotifyUpdated emits the updated and revision events for the current config, e.g. after a reload
+// replaced it.
+func (b *BaseManager[T]) NotifyUpdated() {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.eventName != "" && b.config != nil {
+		b.events.Updated(b.eventName+":updated", b.presented())
+		b.events.Updated(b.eventName+":revision", b.revision)
+	}
+}
+
 // Load loads the configuration from storage.
 func (b *BaseManager[T]) Load() error {
 	b.mu.Lock()
@@ -64,31 +157,37 @@ func (b *BaseManager[T]) Load() error {
 		// Use custom loader if provided
 		cfg, err := b.loader()
 		if err != nil {
-			return err
+			return apperrors.Ensure(err, apperrors.IOError)
 		}
+		b.detectNewer(cfg)
 		b.config = cfg
+		b.revision++
+		b.clearDirty()
 		return nil
 	}
 
 	// Default loader: use storage
 	var cfg T
 	if err := b.storage.Load(b.configFile, &cfg); err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return apperrors.Wrap(apperrors.IOError, err, "failed to load config")
 	}
+	newer := b.detectNewer(&cfg)
 
 	// Ensure defaults/version
 	if b.ensureFunc != nil {
 		b.ensureFunc(&cfg)
 	}
 
-	// Validate if validator is provided
-	if b.validator != nil {
+	// Validate if validator is provided; a newer config may hold values this version rejects
+	if b.validator != nil && !newer {
 		if err := b.validator(&cfg); err != nil {
-			return fmt.Errorf("config validation failed: %w", err)
+			return apperrors.Wrap(apperrors.ValidationFailed, err, "config validation failed")
 		}
 	}
 
 	b.config = &cfg
+	b.revision++
+	b.clearDirty()
 	return nil
 }
 
@@ -106,6 +205,25 @@ func (b *BaseManager[T]) Get() *T {
 	return b.deepCopy(b.config)
 }
 
+// Revision returns the current revision of the configuration.
+func (b *BaseManager[T]) Revision() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.revision
+}
+
+// Snapshot returns a copy of the current configuration together with its revision.
+func (b *BaseManager[T]) Snapshot() (*T, uint64) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.config == nil {
+		var zero T
+		return &zero, b.revision
+	}
+	return b.deepCopy(b.config), b.revision
+}
+
 // deepCopy creates a deep copy of the config using JSON marshaling.
 func (b *BaseManager[T]) deepCopy(src *T) *T {
 	data, err := json.Marshal(src)
@@ -128,19 +246,20 @@ func (b *BaseManager[T]) deepCopy(src *T) *T {
 
 // Patch applies a partial update to the configuration.
 func (b *BaseManager[T]) Patch(patch map[string]interface{}) error {
-	ctx := b.events.Context()
-
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	if b.config == nil {
 		return fmt.Errorf("config is not loaded")
 	}
+	if b.readOnly != nil {
+		return b.readOnlyError()
+	}
 
 	// Merge patch into current config
 	var merged T
 	if err := storage.MergePatch(b.config, patch, &merged); err != nil {
-		return fmt.Errorf("failed to merge patch: %w", err)
+		return apperrors.Wrap(apperrors.ValidationFailed, err, "failed to merge patch")
 	}
 
 	// Ensure defaults/version
@@ -151,16 +270,19 @@ func (b *BaseManager[T]) Patch(patch map[string]interface{}) error {
 	// Validate if validator is provided
 	if b.validator != nil {
 		if err := b.validator(&merged); err != nil {
-			return fmt.Errorf("merged config validation failed: %w", err)
+			return apperrors.Wrap(apperrors.ValidationFailed, err, "merged config validation failed")
 		}
 	}
 
 	// Update in-memory config
 	b.config = &merged
+	b.revision++
+	b.markDirty(nil)
 
-	// Emit updated event
+	// Emit updated events
 	if b.eventName != "" {
-		b.events.Updated(b.eventName+":updated", b.config)
+		b.events.Updated(b.eventName+":updated", b.presented())
+		b.events.Updated(b.eventName+":revision", b.revision)
 	}
 
 	// Schedule save with debounce
@@ -168,13 +290,9 @@ func (b *BaseManager[T]) Patch(patch map[string]interface{}) error {
 		b.mu.Lock()
 		defer b.mu.Unlock()
 		if err := b.saveLocked(); err != nil {
-			if ctx != nil {
-				b.events.Error(b.eventName+":error", err.Error())
-			}
+			b.events.Error(b.eventName+":error", err.Error())
 		} else {
-			if ctx != nil {
-				b.events.Saved(b.eventName+":saved", b.configFile)
-			}
+			b.events.Saved(b.eventName+":saved", b.configFile)
 		}
 	})
 
@@ -194,47 +312,179 @@ func (b *BaseManager[T]) Save() error {
 }
 
 // saveLocked saves the configuration to storage (must be called with lock held).
+// Nothing is written when there are no unsaved changes.
 func (b *BaseManager[T]) saveLocked() error {
+	if !b.dirty {
+		return nil
+	}
+	if b.readOnly != nil {
+		return b.readOnlyError()
+	}
+
 	// Ensure defaults/version before saving
 	if b.ensureFunc != nil {
 		b.ensureFunc(b.config)
 	}
 
-	return b.storage.Save(b.configFile, b.config)
+	var err error
+	if itemStorage, ok := b.storage.(storage.ItemStorage); ok && b.split != nil && !b.dirtyAll {
+		ids := make([]string, 0, len(b.dirtyItems))
+		for id := range b.dirtyItems {
+			ids = append(ids, id)
+		}
+		document, items := b.split(b.config, ids)
+		err = itemStorage.SaveItems(b.configFile, document, items)
+	} else {
+		err = b.storage.Save(b.configFile, b.config)
+	}
+	if err != nil {
+		return apperrors.Ensure(err, apperrors.IOError)
+	}
+
+	b.clearDirty()
+	return nil
+}
+
+// Dirty reports whether the config has changes that are not saved yet.
+func (b *BaseManager[T]) Dirty() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.dirty
+}
+
+// markDirty records unsaved changes; nil ids means the whole config changed (must hold the lock).
+func (b *BaseManager[T]) markDirty(ids []string) {
+	b.dirty = true
+	if ids == nil {
+		b.dirtyAll = true
+		return
+	}
+	if b.dirtyItems == nil {
+		b.dirtyItems = make(map[string]struct{}, len(ids))
+	}
+	for _, id := range ids {
+		b.dirtyItems[id] = struct{}{}
+	}
+}
+
+// clearDirty forgets unsaved changes after a load or save (must hold the lock).
+func (b *BaseManager[T]) clearDirty() {
+	b.dirty = false
+	b.dirtyAll = false
+	b.dirtyItems = nil
 }
 
 // UpdateConfig updates the in-memory configuration and schedules a save.
 // This is useful for operations that modify the config directly.
 func (b *BaseManager[T]) UpdateConfig(updater func(*T) error) error {
-	ctx := b.events.Context()
+	return b.UpdateConfigAt(AnyRevision, updater)
+}
+
+// UpdateConfigAt is UpdateConfig guarded by optimistic concurrency: it fails with a CONFLICT error
+// when expected is not the current revision. AnyRevision skips the check.
+func (b *BaseManager[T]) UpdateConfigAt(expected uint64, updater func(*T) error) error {
+	return b.UpdateItemsAt(expected, func(cfg *T) ([]string, error) {
+		return nil, updater(cfg)
+	})
+}
 
+// UpdateItemsAt is UpdateConfigAt for updaters that report which items they added, changed or
+// removed, so the next save can write only those. Returning nil ids marks the whole config changed.
+func (b *BaseManager[T]) UpdateItemsAt(expected uint64, updater func(*T) ([]string, error)) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if b.config == nil {
-		return fmt.Errorf("config is not loaded")
+	if err := b.checkUpdateLocked(expected); err != nil {
+		return err
 	}
 
-	// Update config
-	if err := updater(b.config); err != nil {
-		return err
+	// Update a copy so a failed update or validation leaves the current config untouched.
+	// Uncoded updater errors are precondition failures and reported as validation errors.
+	updated := b.deepCopy(b.config)
+	ids, err := updater(updated)
+	if err != nil {
+		return apperrors.Ensure(err, apperrors.ValidationFailed)
 	}
 
 	// Ensure defaults/version
 	if b.ensureFunc != nil {
-		b.ensureFunc(b.config)
+		b.ensureFunc(updated)
 	}
 
 	// Validate if validator is provided
 	if b.validator != nil {
-		if err := b.validator(b.config); err != nil {
-			return fmt.Errorf("config validation failed: %w", err)
+		if err := b.validator(updated); err != nil {
+			return apperrors.Wrap(apperrors.ValidationFailed, err, "config validation failed")
 		}
 	}
 
-	// Emit updated event
+	b.config = updated
+	b.appliedLocked(ids)
+	return nil
+}
+
+// UpdateItemsInPlaceAt is UpdateItemsAt for updaters that change a few known items and check them
+// on their own. The updater gets the current config instead of a copy, and neither the defaults
+// nor the validator are applied, so the cost of the update does not grow with the config. The
+// updater must leave the config as it was when it fails.
+func (b *BaseManager[T]) UpdateItemsInPlaceAt(expected uint64, updater func(*T) ([]string, error)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.checkUpdateLocked(expected); err != nil {
+		return err
+	}
+	ids, err := updater(b.config)
+	if err != nil {
+		return apperrors.Ensure(err, apperrors.ValidationFailed)
+	}
+	b.appliedLocked(ids)
+	return nil
+}
+
+// Amend changes the current config without a new revision or a save, for content that was on disk
+// all along and is only read now, such as the folders a storage reads on demand. The updater
+// reports whether it changed the config; the updated event is emitted if so. Mutations based on
+// the current revision stay valid.
+func (b *BaseManager[T]) Amend(updater func(*T) (bool, error)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.config == nil {
+		return fmt.Errorf("config is not loaded")
+	}
+	changed, err := updater(b.config)
+	if changed && b.eventName != "" {
+		b.events.Updated(b.eventName+":updated", b.presented())
+	}
+	return err
+}
+
+// checkUpdateLocked fails when the config cannot be updated at the expected revision (must hold
+// the lock).
+func (b *BaseManager[T]) checkUpdateLocked(expected uint64) error {
+	if b.config == nil {
+		return fmt.Errorf("config is not loaded")
+	}
+	if b.readOnly != nil {
+		return b.readOnlyError()
+	}
+	if expected != AnyRevision && expected != b.revision {
+		return conflictError(b.eventName, expected, b.revision)
+	}
+	return nil
+}
+
+// appliedLocked moves to the next revision after an update, announces it and schedules the save
+// of the changed items (must hold the lock).
+func (b *BaseManager[T]) appliedLocked(ids []string) {
+	b.revision++
+	b.markDirty(ids)
+
+	// Emit updated events
 	if b.eventName != "" {
-		b.events.Updated(b.eventName+":updated", b.config)
+		b.events.Updated(b.eventName+":updated", b.presented())
+		b.events.Updated(b.eventName+":revision", b.revision)
 	}
 
 	// Schedule save with debounce
@@ -242,17 +492,11 @@ func (b *BaseManager[T]) UpdateConfig(updater func(*T) error) error {
 		b.mu.Lock()
 		defer b.mu.Unlock()
 		if err := b.saveLocked(); err != nil {
-			if ctx != nil {
-				b.events.Error(b.eventName+":error", err.Error())
-			}
+			b.events.Error(b.eventName+":error", err.Error())
 		} else {
-			if ctx != nil {
-				b.events.Saved(b.eventName+":saved", b.configFile)
-			}
+			b.events.Saved(b.eventName+":saved", b.configFile)
 		}
 	})
-
-	return nil
 }
 
 // GetConfig returns the current configuration (internal use, not a copy).
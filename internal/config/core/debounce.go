@@ -1,9 +1,8 @@
 package core
 
 import (
+	"sync"
 	"time"
-
-	"github.com/bep/debounce"
 )
 
 const (
@@ -11,17 +10,89 @@ const (
 	DefaultDebounceDuration = 700 * time.Millisecond
 )
 
-// Debouncer wraps the debounce helper so managers do not depend on globals.
+// Debouncer delays a callback until no new one was scheduled for the debounce window.
+// Only the most recently scheduled callback runs.
 type Debouncer struct {
-	debounced func(func())
+	mu       sync.Mutex
+	duration time.Duration
+	timer    *time.Timer
+	pending  func()
+	closed   bool
+
+	// running serializes callbacks so Flush waits for one that is already in progress
+	running sync.Mutex
 }
 
 // NewDebouncer returns a callable debouncer with the provided delay.
 func NewDebouncer(duration time.Duration) *Debouncer {
-	return &Debouncer{debounced: debounce.New(duration)}
+	return &Debouncer{duration: duration}
+}
+
+// SetDuration changes the delay used for callbacks scheduled from now on.
+func (d *Debouncer) SetDuration(duration time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.duration = duration
 }
 
-// Schedule triggers the callback after the debounce window.
+// Schedule triggers the callback after the debounce window. After Close the callback
+// runs right away on its own goroutine.
 func (d *Debouncer) Schedule(fn func()) {
-	d.debounced(fn)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		go func() {
+			d.running.Lock()
+			defer d.running.Unlock()
+			fn()
+		}()
+		return
+	}
+
+	d.pending = fn
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = time.AfterFunc(d.duration, d.Flush)
+}
+
+// Cancel drops the pending callback, reporting whether there was one. It waits for a
+// callback that is already running.
+func (d *Debouncer) Cancel() bool {
+	d.running.Lock()
+	defer d.running.Unlock()
+	return d.take() != nil
+}
+
+// Flush runs the pending callback now, on the calling goroutine. It waits for a callback
+// that is already running.
+func (d *Debouncer) Flush() {
+	d.running.Lock()
+	defer d.running.Unlock()
+	if fn := d.take(); fn != nil {
+		fn()
+	}
+}
+
+// Close flushes the pending callback; callbacks scheduled afterwards are not delayed.
+func (d *Debouncer) Close() {
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+	d.Flush()
+}
+
+// take removes and returns the pending callback
+func (d *Debouncer) take() func() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	fn := d.pending
+	d.pending = nil
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	return fn
 }
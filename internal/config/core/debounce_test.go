@@ -0,0 +1,51 @@
+package core
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDebouncerFlushRunsPendingOnce(t *testing.T) {
+	d := NewDebouncer(time.Hour)
+	var calls atomic.Int32
+
+	d.Schedule(func() { calls.Add(1) })
+	d.Schedule(func() { calls.Add(10) })
+	d.Flush()
+	d.Flush()
+
+	if got := calls.Load(); got != 10 {
+		t.Errorf("calls = %d, want only the latest callback to run once", got)
+	}
+}
+
+func TestDebouncerCancel(t *testing.T) {
+	d := NewDebouncer(time.Hour)
+	if d.Cancel() {
+		t.Error("Cancel() reported a pending callback on an idle debouncer")
+	}
+
+	ran := false
+	d.Schedule(func() { ran = true })
+	if !d.Cancel() {
+		t.Error("Cancel() should report the pending callback")
+	}
+	d.Flush()
+	if ran {
+		t.Error("cancelled callback ran")
+	}
+}
+
+func TestDebouncerCloseRunsLaterCallbacksImmediately(t *testing.T) {
+	d := NewDebouncer(time.Hour)
+	d.Close()
+
+	done := make(chan struct{})
+	d.Schedule(func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("callback scheduled after Close was delayed")
+	}
+}
@@ -3,68 +3,121 @@ package core
 import (
 	"context"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/logger"
 	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-// EventBus knows how to talk to the Wails runtime and emit config related events.
+// DefaultEventBuffer is how many events are kept while no runtime context is attached
+const DefaultEventBuffer = 64
+
+// SavedPayload is sent with "<name>:saved" events
+type SavedPayload struct {
+	UpdatedAt string `json:"updatedAt"`
+	Path      string `json:"path"`
+}
+
+// ErrorPayload is sent with "<name>:error" events
+type ErrorPayload struct {
+	Message string `json:"message"`
+}
+
+// pendingEvent is an event emitted before the runtime context was attached
+type pendingEvent struct {
+	name    string
+	payload interface{}
+}
+
+// EventBus is the single place events are emitted to the Wails runtime. Events emitted before
+// the context is attached are buffered (oldest dropped first) and replayed in order by SetContext.
 type EventBus struct {
-	ctx context.Context
-	log logger.Logger
+	mu         sync.Mutex
+	ctx        context.Context
+	log        logger.Logger
+	bufferSize int
+	pending    []pendingEvent
+	emit       func(ctx context.Context, event string, optionalData ...interface{})
 }
 
 // NewEventBus builds a bus with optional runtime context and logger.
 func NewEventBus(ctx context.Context, log logger.Logger) *EventBus {
-	return &EventBus{ctx: ctx, log: log}
+	return &EventBus{ctx: ctx, log: log, bufferSize: DefaultEventBuffer, emit: wailsruntime.EventsEmit}
+}
+
+// SetBufferSize changes how many early events are kept; 0 disables buffering.
+func (b *EventBus) SetBufferSize(size int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bufferSize = size
+	b.trimLocked()
 }
 
-// SetContext wires the bus to the Wails runtime.
+// SetContext wires the bus to the Wails runtime and replays buffered events.
 func (b *EventBus) SetContext(ctx context.Context, log logger.Logger) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	b.ctx = ctx
 	b.log = log
+	if ctx == nil {
+		return
+	}
+	for _, event := range b.pending {
+		b.emit(ctx, event.name, event.payload)
+	}
+	b.pending = nil
 }
 
 // Context returns the runtime context (used when code needs to log directly).
 func (b *EventBus) Context() context.Context {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	return b.ctx
 }
 
-// Updated notifies the UI that config data changed in memory.
-func (b *EventBus) Updated(event string, payload interface{}) {
+// Emit sends an event, buffering it when no runtime context is attached yet.
+func (b *EventBus) Emit(event string, payload interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	if b.ctx == nil {
+		if b.bufferSize > 0 {
+			b.pending = append(b.pending, pendingEvent{name: event, payload: payload})
+			b.trimLocked()
+		}
 		return
 	}
-	wailsruntime.EventsEmit(b.ctx, event, payload)
+	b.emit(b.ctx, event, payload)
+}
+
+// Updated notifies the UI that config data changed in memory.
+func (b *EventBus) Updated(event string, payload interface{}) {
+	b.Emit(event, payload)
 }
 
 // Saved notifies the UI that a config was flushed to disk (with metadata).
 func (b *EventBus) Saved(event string, filePath string) {
-	if b.ctx == nil {
-		return
-	}
-
-	fileInfo, err := os.Stat(filePath)
 	updatedAt := time.Now()
-	if err == nil {
+	if fileInfo, err := os.Stat(filePath); err == nil {
 		updatedAt = fileInfo.ModTime()
 	}
 
-	wailsruntime.EventsEmit(b.ctx, event, map[string]interface{}{
-		"updatedAt": updatedAt.Format(time.RFC3339),
-		"path":      filePath,
+	b.Emit(event, SavedPayload{
+		UpdatedAt: updatedAt.Format(time.RFC3339),
+		Path:      filePath,
 	})
 }
 
 // Error notifies listeners about a persistence failure.
 func (b *EventBus) Error(event string, message string) {
-	if b.ctx == nil {
-		return
-	}
-
-	wailsruntime.EventsEmit(b.ctx, event, map[string]interface{}{
-		"message": message,
-	})
+	b.Emit(event, ErrorPayload{Message: message})
 }
 
+// trimLocked drops the oldest buffered events beyond the buffer size (must hold the lock)
+func (b *EventBus) trimLocked() {
+	if excess := len(b.pending) - b.bufferSize; excess > 0 {
+		b.pending = append([]pendingEvent(nil), b.pending[excess:]...)
+	}
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+type recordedEvent struct {
+	name    string
+	payload interface{}
+}
+
+func newRecordingBus(events *[]recordedEvent) *EventBus {
+	bus := NewEventBus(nil, nil)
+	bus.emit = func(_ context.Context, name string, data ...interface{}) {
+		*events = append(*events, recordedEvent{name: name, payload: data[0]})
+	}
+	return bus
+}
+
+func TestEventBusBuffersUntilContext(t *testing.T) {
+	var emitted []recordedEvent
+	bus := newRecordingBus(&emitted)
+	bus.SetBufferSize(2)
+
+	bus.Updated("a:updated", 1)
+	bus.Updated("b:updated", 2)
+	bus.Error("c:error", "boom")
+	if len(emitted) != 0 {
+		t.Fatalf("events emitted before the context was set: %+v", emitted)
+	}
+
+	bus.SetContext(context.Background(), nil)
+	if len(emitted) != 2 || emitted[0].name != "b:updated" || emitted[1].name != "c:error" {
+		t.Fatalf("replayed %+v, want the two newest events in order", emitted)
+	}
+	if payload, ok := emitted[1].payload.(ErrorPayload); !ok || payload.Message != "boom" {
+		t.Errorf("error payload = %#v, want ErrorPayload", emitted[1].payload)
+	}
+
+	bus.Updated("d:updated", 3)
+	if len(emitted) != 3 || emitted[2].name != "d:updated" {
+		t.Errorf("events after the context is set should be emitted directly, got %+v", emitted)
+	}
+}
+
+func TestEventBusWithoutBufferDropsEarlyEvents(t *testing.T) {
+	var emitted []recordedEvent
+	bus := newRecordingBus(&emitted)
+	bus.SetBufferSize(0)
+
+	bus.Updated("a:updated", 1)
+	bus.SetContext(context.Background(), nil)
+	if len(emitted) != 0 {
+		t.Errorf("unbuffered bus replayed %+v", emitted)
+	}
+}
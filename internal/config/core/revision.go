@@ -0,0 +1,15 @@
+package core
+
+import "paperbox/internal/apperrors"
+
+// AnyRevision disables the revision check of UpdateConfigAt
+const AnyRevision uint64 = 0
+
+// conflictError reports a mutation based on a stale revision of a config
+func conflictError(config string, expected, current uint64) *apperrors.Error {
+	return apperrors.New(apperrors.Conflict,
+		"%s config was modified elsewhere (revision %d, expected %d); reload and retry", config, current, expected).
+		WithDetail("config", config).
+		WithDetail("expected", expected).
+		WithDetail("current", current)
+}
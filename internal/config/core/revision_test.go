@@ -0,0 +1,54 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"paperbox/internal/apperrors"
+)
+
+type testConfig struct {
+	Name string `json:"name"`
+}
+
+// discardStorage accepts saves without writing anywhere
+type discardStorage struct{}
+
+func (discardStorage) Load(string, interface{}) error { return nil }
+func (discardStorage) Save(string, interface{}) error { return nil }
+
+func TestUpdateConfigAtRejectsStaleRevision(t *testing.T) {
+	b := NewBaseManager(BaseManagerOptions[testConfig]{
+		Storage: discardStorage{},
+		Loader:  func() (*testConfig, error) { return &testConfig{Name: "a"}, nil },
+	})
+	if err := b.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	loaded := b.Revision()
+	if loaded == AnyRevision {
+		t.Fatalf("Revision() after Load = %d, want non-zero", loaded)
+	}
+
+	rename := func(name string) func(*testConfig) error {
+		return func(cfg *testConfig) error { cfg.Name = name; return nil }
+	}
+
+	if err := b.UpdateConfigAt(loaded, rename("b")); err != nil {
+		t.Fatalf("UpdateConfigAt(current) error = %v", err)
+	}
+
+	err := b.UpdateConfigAt(loaded, rename("c"))
+	var conflict *apperrors.Error
+	if !errors.As(err, &conflict) || conflict.Code != apperrors.Conflict || conflict.Details["current"] != loaded+1 {
+		t.Fatalf("UpdateConfigAt(stale) error = %v, want CONFLICT", err)
+	}
+	if cfg, revision := b.Snapshot(); cfg.Name != "b" || revision != loaded+1 {
+		t.Errorf("Snapshot() = %+v, %d; stale update must not apply", cfg, revision)
+	}
+
+	if err := b.UpdateConfigAt(AnyRevision, rename("d")); err != nil {
+		t.Errorf("UpdateConfigAt(AnyRevision) error = %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+type itemsConfig struct {
+	Values map[string]string `json:"values"`
+}
+
+// recordingStorage records which kind of save was made and which items it wrote
+type recordingStorage struct {
+	saves     int
+	itemSaves [][]string
+}
+
+func (s *recordingStorage) Load(string, interface{}) error { return nil }
+func (s *recordingStorage) Save(string, interface{}) error { s.saves++; return nil }
+func (s *recordingStorage) SaveItems(_ string, _ interface{}, items map[string]interface{}) error {
+	var ids []string
+	for id := range items {
+		ids = append(ids, id)
+	}
+	s.itemSaves = append(s.itemSaves, ids)
+	return nil
+}
+
+func TestSaveWritesOnlyDirtyState(t *testing.T) {
+	s := &recordingStorage{}
+	b := NewBaseManager(BaseManagerOptions[itemsConfig]{
+		Storage: s,
+		Loader: func() (*itemsConfig, error) {
+			return &itemsConfig{Values: map[string]string{"a": "1", "b": "2"}}, nil
+		},
+		Split: func(cfg *itemsConfig, ids []string) (interface{}, map[string]interface{}) {
+			items := make(map[string]interface{})
+			for _, id := range ids {
+				items[id] = cfg.Values[id]
+			}
+			return struct{}{}, items
+		},
+	})
+	if err := b.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer b.Close()
+
+	if err := b.Save(); err != nil || s.saves != 0 || len(s.itemSaves) != 0 || b.Dirty() {
+		t.Fatalf("Save() of an unchanged config wrote %d/%d times (err %v)", s.saves, len(s.itemSaves), err)
+	}
+
+	err := b.UpdateItemsAt(AnyRevision, func(cfg *itemsConfig) ([]string, error) {
+		cfg.Values["a"] = "changed"
+		return []string{"a"}, nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateItemsAt() error = %v", err)
+	}
+	if !b.Dirty() {
+		t.Error("Dirty() = false after an update")
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if s.saves != 0 || !reflect.DeepEqual(s.itemSaves, [][]string{{"a"}}) {
+		t.Errorf("item update saved %d full / items %v, want only item a", s.saves, s.itemSaves)
+	}
+
+	if err := b.UpdateConfig(func(cfg *itemsConfig) error { cfg.Values["b"] = "changed"; return nil }); err != nil {
+		t.Fatalf("UpdateConfig() error = %v", err)
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if s.saves != 1 {
+		t.Errorf("untracked update should save the whole config, got %d full saves", s.saves)
+	}
+}
@@ -0,0 +1,51 @@
+package core
+
+import "paperbox/internal/apperrors"
+
+// ReadOnlyConfig is the payload of "<name>:readonly": the config file was written by a newer
+// version of paperbox, so it is open read-only and never written back
+type ReadOnlyConfig struct {
+	Config    string `json:"config"`
+	File      string `json:"file"`
+	Version   int    `json:"version"`   // Version of the file
+	Supported int    `json:"supported"` // Newest version this build understands
+}
+
+// ReadOnly returns why the config is read-only, nil when it can be changed.
+func (b *BaseManager[T]) ReadOnly() *ReadOnlyConfig {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.readOnly == nil {
+		return nil
+	}
+	readOnly := *b.readOnly
+	return &readOnly
+}
+
+// detectNewer marks the config read-only when it was written by a newer version and emits
+// "<name>:readonly"; it reports whether it did (must hold the lock).
+func (b *BaseManager[T]) detectNewer(cfg *T) bool {
+	b.readOnly = nil
+	if b.version == nil || b.currentVersion == 0 {
+		return false
+	}
+	version := b.version(cfg)
+	if version <= b.currentVersion {
+		return false
+	}
+	b.readOnly = &ReadOnlyConfig{Config: b.eventName, File: b.configFile, Version: version, Supported: b.currentVersion}
+	if b.eventName != "" {
+		b.events.Emit(b.eventName+":readonly", *b.readOnly)
+	}
+	return true
+}
+
+// readOnlyError refuses a change to a config written by a newer version (must hold the lock)
+func (b *BaseManager[T]) readOnlyError() *apperrors.Error {
+	return apperrors.New(apperrors.ReadOnly,
+		"%s config was written by a newer version of paperbox (version %d, this one supports %d); it is read-only until paperbox is updated",
+		b.eventName, b.readOnly.Version, b.readOnly.Supported).
+		WithDetail("config", b.eventName).
+		WithDetail("version", b.readOnly.Version).
+		WithDetail("supported", b.readOnly.Supported)
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"paperbox/internal/apperrors"
+)
+
+type versionedConfig struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+}
+
+// rawStorage loads a fixed JSON document and counts saves
+type rawStorage struct {
+	data  string
+	saves int
+}
+
+func (s *rawStorage) Load(_ string, target interface{}) error {
+	return json.Unmarshal([]byte(s.data), target)
+}
+func (s *rawStorage) Save(string, interface{}) error { s.saves++; return nil }
+
+func TestNewerConfigIsReadOnly(t *testing.T) {
+	s := &rawStorage{data: `{"version": 3, "name": "from the future", "newField": true}`}
+	b := NewBaseManager(BaseManagerOptions[versionedConfig]{
+		Storage:        s,
+		EventName:      "test",
+		Validator:      func(*versionedConfig) error { return apperrors.Invalidf("unknown fields") },
+		CurrentVersion: 2,
+		Version:        func(cfg *versionedConfig) int { return cfg.Version },
+	})
+	if err := b.Load(); err != nil {
+		t.Fatalf("Load() error = %v, want the newer config loaded without validation", err)
+	}
+	defer b.Close()
+
+	readOnly := b.ReadOnly()
+	if readOnly == nil || readOnly.Version != 3 || readOnly.Supported != 2 || readOnly.Config != "test" {
+		t.Fatalf("ReadOnly() = %+v, want version 3 of 2", readOnly)
+	}
+	if b.Get().Name != "from the future" {
+		t.Errorf("Get().Name = %q, want the loaded value", b.Get().Name)
+	}
+
+	err := b.UpdateConfig(func(cfg *versionedConfig) error { cfg.Name = "changed"; return nil })
+	if apperrors.CodeOf(err) != apperrors.ReadOnly {
+		t.Errorf("UpdateConfig() error = %v, want READ_ONLY", err)
+	}
+	if err := b.Patch(map[string]interface{}{"name": "changed"}); apperrors.CodeOf(err) != apperrors.ReadOnly {
+		t.Errorf("Patch() error = %v, want READ_ONLY", err)
+	}
+	if err := b.Flush(); err != nil || s.saves != 0 {
+		t.Errorf("Flush() = %v with %d saves, want nothing written", err, s.saves)
+	}
+
+	// Once the file is at a supported version again, it can be changed
+	s.data = `{"version": 2, "name": "current"}`
+	b.validator = nil
+	if err := b.Load(); err != nil || b.ReadOnly() != nil {
+		t.Fatalf("Load() = %v, ReadOnly() = %+v; want a writable config", err, b.ReadOnly())
+	}
+	if err := b.UpdateConfig(func(cfg *versionedConfig) error { cfg.Name = "changed"; return nil }); err != nil {
+		t.Errorf("UpdateConfig() error = %v", err)
+	}
+}
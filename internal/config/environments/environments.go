@@ -0,0 +1,294 @@
+package environments
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// CurrentVersion is the current version of the environments config format
+	CurrentVersion = 1
+	// EnvironmentsFileName is the name of the environments config file
+	EnvironmentsFileName = "environments.json"
+)
+
+var (
+	appDataDir       = path.Join(xdg.DataHome, "paperbox")
+	environmentsFile = path.Join(appDataDir, EnvironmentsFileName)
+	validate         = validator.New()
+)
+
+// sha256Pattern matches a normalized certificate fingerprint
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// NormalizeFingerprint lower-cases a fingerprint and drops the colons and spaces it is often shown with
+func NormalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.NewReplacer(":", "", " ", "").Replace(fingerprint))
+}
+
+// Variable is a single environment variable referenced from requests as {{key}}.
+// Secret values are masked in the UI, in sent requests and in exports (see MaskSecrets).
+type Variable struct {
+	Key      string `json:"key" validate:"required"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled,omitempty"`
+	Secret   bool   `json:"secret,omitempty"`
+}
+
+// HostOverride sends connections for Host to Target instead of the address DNS returns, like an
+// /etc/hosts entry. Host may include a port to only match that port; a Target without a port keeps
+// the requested one. TLS still verifies the certificate against Host.
+type HostOverride struct {
+	Host     string `json:"host" validate:"required"`   // e.g. "api.example.com" or "api.example.com:443"
+	Target   string `json:"target" validate:"required"` // e.g. "127.0.0.1:8080"; may use {{variables}}
+	Disabled bool   `json:"disabled,omitempty"`
+	// Source names the integration that generated the override (e.g. "docker"); syncing it again
+	// replaces its overrides. Empty for overrides added by hand.
+	Source string `json:"source,omitempty"`
+}
+
+// TLSSettings adjust certificate checks for the servers of an environment
+type TLSSettings struct {
+	// InsecureSkipVerify accepts any certificate; executions sent this way are flagged as insecure
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+	MinVersion         string `json:"minVersion,omitempty" validate:"omitempty,oneof=1.0 1.1 1.2 1.3"`
+	// PinnedSHA256 lists hex SHA-256 fingerprints of certificates; one of them must be in the server's chain
+	PinnedSHA256 []string `json:"pinnedSHA256,omitempty" validate:"omitempty,dive,required"`
+}
+
+// NetworkSettings change how the servers of an environment are reached, to reproduce what a
+// service sees on dual-stack or split-DNS networks
+type NetworkSettings struct {
+	// IPVersion restricts connections to IPv4 ("4") or IPv6 ("6"); empty allows both
+	IPVersion string `json:"ipVersion,omitempty" validate:"omitempty,oneof=4 6"`
+	// DNSServer resolves host names instead of the system resolver (see ParseDNSServer)
+	DNSServer string `json:"dnsServer,omitempty"`
+}
+
+// ParseDNSServer splits a DNS server setting into its network ("udp", "tcp" or "https") and
+// address. "10.0.0.2", "10.0.0.2:5353" and "udp://10.0.0.2" query over UDP, "tcp://10.0.0.2" over
+// TCP (port 53 by default), and an https URL such as "https://cloudflare-dns.com/dns-query" over
+// DNS-over-HTTPS.
+func ParseDNSServer(server string) (network string, address string, err error) {
+	network, rest, found := strings.Cut(server, "://")
+	if !found {
+		network, rest = "udp", server
+	}
+	switch network {
+	case "https":
+		u, err := url.Parse(server)
+		if err != nil || u.Host == "" {
+			return "", "", fmt.Errorf("DNS server '%s' is not a valid URL", server)
+		}
+		return network, server, nil
+	case "udp", "tcp":
+		rest = strings.TrimSuffix(rest, "/")
+		host, port, err := net.SplitHostPort(rest)
+		if err != nil {
+			host, port = strings.Trim(rest, "[]"), "53"
+		}
+		if net.ParseIP(host) == nil {
+			return "", "", fmt.Errorf("DNS server '%s' must be an IP address", server)
+		}
+		return network, net.JoinHostPort(host, port), nil
+	}
+	return "", "", fmt.Errorf("DNS server '%s' must use udp://, tcp:// or https://", server)
+}
+
+// SSHTunnel reaches the servers of an environment through an SSH jump host, like "ssh -J", so
+// services only reachable inside a private network can be requested directly. Host names are
+// resolved by the jump host.
+type SSHTunnel struct {
+	Server string `json:"server" validate:"required"` // Jump host as "host" or "host:port" (port 22 by default); may use {{variables}}
+	User   string `json:"user" validate:"required"`
+	// KeyVariable names the secret variable (of the environment or the globals) holding the private
+	// key in PEM form; PassphraseVariable the one holding its passphrase, if it has one
+	KeyVariable        string `json:"keyVariable" validate:"required"`
+	PassphraseVariable string `json:"passphraseVariable,omitempty"`
+	// HostKey pins the jump host's key fingerprint ("SHA256:..."); requests are refused until it is set
+	HostKey string `json:"hostKey,omitempty"`
+	// Hosts are the hosts connections go through the tunnel for, e.g. "db.internal", "*.internal"
+	// or "10.0.1.5:8080"; empty sends every host through it
+	Hosts    []string `json:"hosts,omitempty" validate:"omitempty,dive,required"`
+	Disabled bool     `json:"disabled,omitempty"`
+}
+
+// Environment is a named set of variables and an optional base URL
+type Environment struct {
+	Name          string           `json:"name" validate:"required,min=1"`
+	BaseURL       string           `json:"baseURL,omitempty" validate:"omitempty,url"`
+	Variables     []Variable       `json:"variables,omitempty" validate:"omitempty,dive"`
+	HostOverrides []HostOverride   `json:"hostOverrides,omitempty" validate:"omitempty,dive"`
+	TLS           *TLSSettings     `json:"tls,omitempty"`
+	Network       *NetworkSettings `json:"network,omitempty"`
+	Tunnel        *SSHTunnel       `json:"tunnel,omitempty"`
+	// HealthPath is requested on the base URL and folder base URLs to tell whether the servers are
+	// reachable, e.g. "/health"; an absolute URL is requested on its own. May use {{variables}}.
+	HealthPath string `json:"healthPath,omitempty"`
+}
+
+// EnvironmentsConfig represents the environments configuration.
+// Globals are variables available whichever environment is active (environment variables win).
+type EnvironmentsConfig struct {
+	Version int                    `json:"version" validate:"required,min=1"`
+	Active  string                 `json:"active,omitempty"`
+	Values  map[string]Environment `json:"values" validate:"required,dive,keys,required,endkeys"`
+	Order   []string               `json:"order,omitempty" validate:"omitempty,dive,required"`
+	Globals []Variable             `json:"globals,omitempty" validate:"omitempty,dive"`
+}
+
+// NewEnvironmentsConfig creates a new empty environments config
+func NewEnvironmentsConfig() *EnvironmentsConfig {
+	return &EnvironmentsConfig{
+		Version: CurrentVersion,
+		Values:  make(map[string]Environment),
+		Order:   []string{},
+	}
+}
+
+// Ordered returns the environments in display order
+func (cfg *EnvironmentsConfig) Ordered() []Environment {
+	result := make([]Environment, 0, len(cfg.Values))
+	listed := make(map[string]bool, len(cfg.Order))
+	for _, id := range cfg.Order {
+		if env, exists := cfg.Values[id]; exists && !listed[id] {
+			result = append(result, env)
+			listed[id] = true
+		}
+	}
+	for id, env := range cfg.Values {
+		if !listed[id] {
+			result = append(result, env)
+		}
+	}
+	return result
+}
+
+// ensureDefaults fills in the version and nil collections of a freshly loaded config
+func ensureDefaults(cfg *EnvironmentsConfig) {
+	if cfg.Version == 0 {
+		cfg.Version = CurrentVersion
+	}
+	if cfg.Values == nil {
+		cfg.Values = make(map[string]Environment)
+	}
+	if cfg.Order == nil {
+		cfg.Order = []string{}
+	}
+}
+
+// Decode parses and validates an environments file the way the manager loads it. It does not
+// handle encrypted files.
+func Decode(data []byte) (*EnvironmentsConfig, error) {
+	var cfg EnvironmentsConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse environments file: %w", err)
+	}
+	ensureDefaults(&cfg)
+	if err := Validate(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate validates the environments configuration
+func Validate(cfg *EnvironmentsConfig) error {
+	if cfg == nil {
+		return fmt.Errorf("config is nil")
+	}
+
+	if err := validate.Struct(cfg); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	if cfg.Active != "" {
+		if _, exists := cfg.Values[cfg.Active]; !exists {
+			return fmt.Errorf("active environment '%s' does not exist", cfg.Active)
+		}
+	}
+
+	for _, id := range cfg.Order {
+		if _, exists := cfg.Values[id]; !exists {
+			return fmt.Errorf("environment '%s' in order does not exist", id)
+		}
+	}
+
+	globals := make(map[string]bool, len(cfg.Globals))
+	for _, v := range cfg.Globals {
+		if globals[v.Key] {
+			return fmt.Errorf("global variable '%s' is defined more than once", v.Key)
+		}
+		globals[v.Key] = true
+	}
+	// secrets are the keys of secret variables the SSH key of a tunnel may be read from
+	secrets := make(map[string]bool)
+	for _, v := range cfg.Globals {
+		secrets[v.Key] = v.Secret
+	}
+
+	for id, env := range cfg.Values {
+		seen := make(map[string]bool, len(env.Variables))
+		envSecrets := make(map[string]bool, len(env.Variables))
+		for _, v := range env.Variables {
+			if seen[v.Key] {
+				return fmt.Errorf("environment %s: variable '%s' is defined more than once", id, v.Key)
+			}
+			seen[v.Key] = true
+			envSecrets[v.Key] = v.Secret
+		}
+
+		hosts := make(map[string]bool, len(env.HostOverrides))
+		for _, o := range env.HostOverrides {
+			host := strings.ToLower(o.Host)
+			if strings.ContainsAny(host, "/ ") {
+				return fmt.Errorf("environment %s: host override '%s' must be a host name, optionally with a port", id, o.Host)
+			}
+			if hosts[host] {
+				return fmt.Errorf("environment %s: host '%s' is overridden more than once", id, o.Host)
+			}
+			hosts[host] = true
+		}
+
+		if env.TLS != nil {
+			for _, pin := range env.TLS.PinnedSHA256 {
+				if !sha256Pattern.MatchString(NormalizeFingerprint(pin)) {
+					return fmt.Errorf("environment %s: pinned fingerprint '%s' is not a hex SHA-256 hash", id, pin)
+				}
+			}
+		}
+
+		if env.Network != nil && env.Network.DNSServer != "" {
+			if _, _, err := ParseDNSServer(env.Network.DNSServer); err != nil {
+				return fmt.Errorf("environment %s: %w", id, err)
+			}
+		}
+
+		if t := env.Tunnel; t != nil {
+			// The key and passphrase must be masked in the UI and left out of exports
+			for _, name := range []string{t.KeyVariable, t.PassphraseVariable} {
+				secret, defined := envSecrets[name]
+				if !defined {
+					secret = secrets[name]
+				}
+				if name != "" && !secret {
+					return fmt.Errorf("environment %s: SSH tunnel variable '%s' must be a secret variable of the environment or a global", id, name)
+				}
+			}
+			for _, host := range t.Hosts {
+				if strings.ContainsAny(host, "/ ") {
+					return fmt.Errorf("environment %s: tunnelled host '%s' must be a host name, optionally with a port", id, host)
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,312 @@
+package environments
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/core"
+	"paperbox/internal/config/storage"
+
+	"github.com/google/uuid"
+	"github.com/wailsapp/wails/v2/pkg/logger"
+)
+
+// Manager manages the environments configuration
+type Manager struct {
+	*core.BaseManager[EnvironmentsConfig]
+	vault *storage.EncryptedStorage // Set when the storage can encrypt the file at rest
+}
+
+// EncryptionStatus tells whether the environments file is encrypted and still waits for its passphrase
+type EncryptionStatus struct {
+	Encrypted bool `json:"encrypted"`
+	Locked    bool `json:"locked"`
+}
+
+// NewManager creates a new environments config manager. With a storage.EncryptedStorage the file
+// can be encrypted at rest (see EnableEncryption).
+func NewManager(s storage.Storage) *Manager {
+	vault, _ := s.(*storage.EncryptedStorage)
+	return &Manager{
+		BaseManager: core.NewBaseManager(core.BaseManagerOptions[EnvironmentsConfig]{
+			Storage:        s,
+			ConfigFile:     environmentsFile,
+			EventName:      "environments",
+			Validator:      Validate,
+			EnsureFunc:     ensureDefaults,
+			Present:        func(cfg *EnvironmentsConfig) interface{} { return MaskSecrets(cfg) },
+			CurrentVersion: CurrentVersion,
+			Version:        func(cfg *EnvironmentsConfig) int { return cfg.Version },
+		}),
+		vault: vault,
+	}
+}
+
+// Load loads the environments. An encrypted file that is still locked is not an error: the
+// environments stay empty and read-only until Unlock, and "environments:locked" asks the UI for
+// the passphrase.
+func (m *Manager) Load() error {
+	err := m.BaseManager.Load()
+	if apperrors.CodeOf(err) == apperrors.Locked {
+		m.Events().Updated("environments:locked", m.Encryption())
+		return nil
+	}
+	return err
+}
+
+// Encryption returns whether the environments file is encrypted and locked
+func (m *Manager) Encryption() EncryptionStatus {
+	if m.vault == nil {
+		return EncryptionStatus{}
+	}
+	return EncryptionStatus{Encrypted: m.vault.Enabled(), Locked: m.vault.Locked()}
+}
+
+// Unlock decrypts the environments file with the workspace passphrase and loads it
+func (m *Manager) Unlock(passphrase string) error {
+	if m.vault == nil || !m.vault.Locked() {
+		return nil
+	}
+	if err := m.vault.Unlock(m.ConfigFile(), passphrase); err != nil {
+		return err
+	}
+	if err := m.BaseManager.Load(); err != nil {
+		return err
+	}
+	m.NotifyUpdated()
+	return nil
+}
+
+// EnableEncryption encrypts the environments file with a key derived from passphrase and writes it
+// right away, replacing a plaintext file; on an encrypted file it changes the passphrase
+func (m *Manager) EnableEncryption(passphrase string) error {
+	if m.vault == nil {
+		return apperrors.Invalidf("environments cannot be encrypted with this storage")
+	}
+	if err := m.vault.Enable(passphrase); err != nil {
+		return err
+	}
+	return m.rewrite()
+}
+
+// DisableEncryption writes the environments file as plaintext again; passphrase must be the one in use
+func (m *Manager) DisableEncryption(passphrase string) error {
+	if m.vault == nil {
+		return nil
+	}
+	if err := m.vault.Disable(passphrase); err != nil {
+		return err
+	}
+	return m.rewrite()
+}
+
+// rewrite saves the whole file now, e.g. after its encryption changed
+func (m *Manager) rewrite() error {
+	if err := m.BaseManager.UpdateConfig(func(*EnvironmentsConfig) error { return nil }); err != nil {
+		return err
+	}
+	return m.Flush()
+}
+
+// UpdateConfig changes the environments, failing with a LOCKED error while the file is locked
+func (m *Manager) UpdateConfig(updater func(*EnvironmentsConfig) error) error {
+	if m.vault != nil && m.vault.Locked() {
+		return apperrors.New(apperrors.Locked, "environments are encrypted; unlock them with the workspace passphrase")
+	}
+	return m.BaseManager.UpdateConfig(updater)
+}
+
+// SetContext sets the Wails runtime context for emitting events
+func (m *Manager) SetContext(ctx context.Context, log logger.Logger) {
+	m.BaseManager.SetContext(ctx, log)
+}
+
+// Get returns a copy of the current configuration (implements ManagerInterface)
+func (m *Manager) Get() interface{} {
+	return m.GetEnvironmentsConfig()
+}
+
+// GetEnvironmentsConfig returns the environments config (type-safe version); it is empty while locked
+func (m *Manager) GetEnvironmentsConfig() *EnvironmentsConfig {
+	cfg := m.BaseManager.Get()
+	ensureDefaults(cfg)
+	return cfg
+}
+
+// GetActive returns a copy of the active environment, or nil when none is active
+func (m *Manager) GetActive() *Environment {
+	cfg := m.GetEnvironmentsConfig()
+	env, exists := cfg.Values[cfg.Active]
+	if cfg.Active == "" || !exists {
+		return nil
+	}
+	return &env
+}
+
+// AddEnvironment creates a new empty environment and returns its ID
+func (m *Manager) AddEnvironment(name string) (string, error) {
+	newId := uuid.New().String()
+
+	err := m.UpdateConfig(func(cfg *EnvironmentsConfig) error {
+		cfg.Values[newId] = Environment{Name: name}
+		cfg.Order = append(cfg.Order, newId)
+		return nil
+	})
+
+	return newId, err
+}
+
+// UpdateEnvironment replaces an existing environment; secret variables still holding the mask keep their value
+func (m *Manager) UpdateEnvironment(id string, env Environment) error {
+	return m.UpdateConfig(func(cfg *EnvironmentsConfig) error {
+		stored, exists := cfg.Values[id]
+		if !exists {
+			return apperrors.NotFoundf("environment not found")
+		}
+		env.Variables = restoreSecrets(stored.Variables, env.Variables)
+		cfg.Values[id] = env
+		return nil
+	})
+}
+
+// DeleteEnvironment removes an environment, deactivating it if it was active
+func (m *Manager) DeleteEnvironment(id string) error {
+	return m.UpdateConfig(func(cfg *EnvironmentsConfig) error {
+		if _, exists := cfg.Values[id]; !exists {
+			return apperrors.NotFoundf("environment not found")
+		}
+		delete(cfg.Values, id)
+
+		newOrder := []string{}
+		for _, orderedId := range cfg.Order {
+			if orderedId != id {
+				newOrder = append(newOrder, orderedId)
+			}
+		}
+		cfg.Order = newOrder
+
+		if cfg.Active == id {
+			cfg.Active = ""
+		}
+		return nil
+	})
+}
+
+// SetActive selects the active environment (an empty id deactivates all environments)
+func (m *Manager) SetActive(id string) error {
+	return m.UpdateConfig(func(cfg *EnvironmentsConfig) error {
+		if id != "" {
+			if _, exists := cfg.Values[id]; !exists {
+				return apperrors.NotFoundf("environment not found")
+			}
+		}
+		cfg.Active = id
+		return nil
+	})
+}
+
+// AddEnvironments appends several environments at once (used by imports) and returns their new IDs
+func (m *Manager) AddEnvironments(envs []Environment) ([]string, error) {
+	ids := make([]string, 0, len(envs))
+
+	err := m.UpdateConfig(func(cfg *EnvironmentsConfig) error {
+		for _, env := range envs {
+			newId := uuid.New().String()
+			cfg.Values[newId] = env
+			cfg.Order = append(cfg.Order, newId)
+			ids = append(ids, newId)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// SetVariables sets variable values on an environment, updating existing keys and appending new ones
+func (m *Manager) SetVariables(id string, values map[string]string) error {
+	return m.UpdateConfig(func(cfg *EnvironmentsConfig) error {
+		env, exists := cfg.Values[id]
+		if !exists {
+			return apperrors.NotFoundf("environment not found")
+		}
+
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			updated := false
+			for i := range env.Variables {
+				if env.Variables[i].Key == key {
+					env.Variables[i].Value = values[key]
+					updated = true
+				}
+			}
+			if !updated {
+				env.Variables = append(env.Variables, Variable{Key: key, Value: values[key]})
+			}
+		}
+
+		cfg.Values[id] = env
+		return nil
+	})
+}
+
+// SetGeneratedHostOverrides replaces the host overrides an integration generated for an environment
+// (those with the same source) by overrides. Overrides added by hand win for the hosts they cover.
+func (m *Manager) SetGeneratedHostOverrides(id string, source string, overrides []HostOverride) error {
+	return m.UpdateConfig(func(cfg *EnvironmentsConfig) error {
+		env, exists := cfg.Values[id]
+		if !exists {
+			return apperrors.NotFoundf("environment not found")
+		}
+
+		kept := []HostOverride{}
+		taken := make(map[string]bool)
+		for _, o := range env.HostOverrides {
+			if o.Source != source {
+				kept = append(kept, o)
+				taken[strings.ToLower(o.Host)] = true
+			}
+		}
+		for _, o := range overrides {
+			if !taken[strings.ToLower(o.Host)] {
+				o.Source = source
+				kept = append(kept, o)
+				taken[strings.ToLower(o.Host)] = true
+			}
+		}
+
+		env.HostOverrides = kept
+		cfg.Values[id] = env
+		return nil
+	})
+}
+
+// SetBaseURL changes the base URL of an environment
+func (m *Manager) SetBaseURL(id string, baseURL string) error {
+	return m.UpdateConfig(func(cfg *EnvironmentsConfig) error {
+		env, exists := cfg.Values[id]
+		if !exists {
+			return apperrors.NotFoundf("environment not found")
+		}
+		env.BaseURL = baseURL
+		cfg.Values[id] = env
+		return nil
+	})
+}
+
+// SetGlobals replaces the global variables; secret variables still holding the mask keep their value
+func (m *Manager) SetGlobals(vars []Variable) error {
+	return m.UpdateConfig(func(cfg *EnvironmentsConfig) error {
+		cfg.Globals = restoreSecrets(cfg.Globals, vars)
+		return nil
+	})
+}
@@ -0,0 +1,50 @@
+package environments
+
+import "paperbox/internal/config/requests"
+
+// MaskSecrets returns a copy of cfg with the values of secret variables replaced by
+// requests.SecretMask, for handing the config to the UI
+func MaskSecrets(cfg *EnvironmentsConfig) *EnvironmentsConfig {
+	masked := *cfg
+	masked.Globals = maskVariables(cfg.Globals)
+	masked.Values = make(map[string]Environment, len(cfg.Values))
+	for id, env := range cfg.Values {
+		env.Variables = maskVariables(env.Variables)
+		masked.Values[id] = env
+	}
+	return &masked
+}
+
+// maskVariables returns a copy of vars with secret values masked
+func maskVariables(vars []Variable) []Variable {
+	if vars == nil {
+		return nil
+	}
+	masked := make([]Variable, len(vars))
+	copy(masked, vars)
+	for i := range masked {
+		if masked[i].Secret {
+			masked[i].Value = requests.SecretMask
+		}
+	}
+	return masked
+}
+
+// restoreSecrets puts the stored values back into secret variables that come back from the UI
+// still masked, so saving a masked config does not overwrite the secrets
+func restoreSecrets(stored []Variable, incoming []Variable) []Variable {
+	values := make(map[string]string, len(stored))
+	for _, v := range stored {
+		if v.Secret {
+			values[v.Key] = v.Value
+		}
+	}
+	restored := make([]Variable, len(incoming))
+	copy(restored, incoming)
+	for i, v := range restored {
+		if value, ok := values[v.Key]; ok && v.Secret && v.Value == requests.SecretMask {
+			restored[i].Value = value
+		}
+	}
+	return restored
+}
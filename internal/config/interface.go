@@ -3,6 +3,8 @@ package config
 import (
 	"context"
 
+	"paperbox/internal/config/core"
+
 	"github.com/wailsapp/wails/v2/pkg/logger"
 )
 
@@ -16,4 +18,8 @@ type ManagerInterface interface {
 	SetContext(ctx context.Context, log logger.Logger)
 	// Save saves the configuration to file (for manual saves)
 	Save() error
+	// Flush writes pending debounced changes immediately
+	Flush() error
+	// ReadOnly reports why the configuration cannot be changed, nil when it can
+	ReadOnly() *core.ReadOnlyConfig
 }
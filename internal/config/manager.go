@@ -2,11 +2,19 @@ package config
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/core"
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/plugins"
 	"paperbox/internal/config/requests"
 	"paperbox/internal/config/storage"
 	"paperbox/internal/config/user"
+	"paperbox/internal/sync"
 
 	"github.com/wailsapp/wails/v2/pkg/logger"
 )
@@ -14,37 +22,234 @@ import (
 // Manager manages all application configurations
 // It aggregates all config managers and provides a unified interface
 type Manager struct {
-	managers []ManagerInterface
-	requests *requests.Manager
-	user     *user.Manager
+	managers     []ManagerInterface
+	requests     *requests.Manager
+	user         *user.Manager
+	environments *environments.Manager
+	plugins      *plugins.Manager
+	database     *storage.SQLiteStorage // Open when the user selected the sqlite backend
+	backend      storage.Storage        // Where the request tree is stored, before syncing
+	syncQueue    *storage.SyncQueue     // Set while team sync is on
 }
 
 // NewManager creates a new config manager
 func NewManager() *Manager {
 	// Create shared storage coordinator for all configs
-	fileStorage := storage.NewFileStorage()
+	fileStorage := storage.NewCheckedFileStorage()
 	coordinator := storage.NewStorageCoordinator(fileStorage, nil, nil)
 
 	reqMgr := requests.NewManager(coordinator)
 	userMgr := user.NewManager(coordinator)
+	// Environments hold the secrets, so they alone can be encrypted at rest
+	envMgr := environments.NewManager(storage.NewEncryptedStorage(storage.NewIntegrityWriter(storage.NewFileWriter())))
+	pluginsMgr := plugins.NewManager(coordinator)
+	storage.SetRepairHandler(func(repair storage.Repair) {
+		userMgr.Events().Emit(storage.EventRepaired, repair)
+	})
 
 	return &Manager{
-		managers: []ManagerInterface{reqMgr, userMgr},
-		requests: reqMgr,
-		user:     userMgr,
+		managers:     []ManagerInterface{userMgr, reqMgr, envMgr, pluginsMgr},
+		requests:     reqMgr,
+		user:         userMgr,
+		environments: envMgr,
+		plugins:      pluginsMgr,
+		backend:      fileStorage,
 	}
 }
 
-// LoadAll loads all configurations
+// LoadAll loads all configurations. The user config is loaded first because its
+// settings (e.g. the folder depth limit, the storage backend) affect how the request tree is loaded.
 func (m *Manager) LoadAll() error {
 	for _, mgr := range m.managers {
 		if err := mgr.Load(); err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
+		if mgr == ManagerInterface(m.user) {
+			if err := m.applyUserSettings(); err != nil {
+				return fmt.Errorf("failed to apply user config: %w", err)
+			}
+			if err := m.selectStorage(); err != nil {
+				return fmt.Errorf("failed to open storage: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// selectStorage switches the requests config to the storage backend the user selected
+func (m *Manager) selectStorage() error {
+	switch m.user.GetConfig().StorageBackend {
+	case user.StorageSQLite:
+		if m.database != nil {
+			return nil
+		}
+		database, err := storage.NewSQLiteStorage(requests.DatabasePath())
+		if err != nil {
+			return apperrors.Wrap(apperrors.IOError, err, "failed to open request database")
+		}
+		m.database = database
+		m.backend = database
+		m.requests.UseStorage(database)
+	case user.StorageFolders:
+		m.backend = requests.NewCollectionStorage(requests.CollectionsDir())
+		m.requests.UseStorage(m.backend)
 	}
 	return nil
 }
 
+// StartSync shares the request tree through the user's team sync server, if one is set, and reloads
+// it with the teammates' changes. It must run after LoadAll, as the token is a global variable.
+// Conflicts are announced with the "requests:conflict" event. Saves that cannot reach the server
+// are queued and retried, announced with the sync:pending and sync:flushed events.
+func (m *Manager) StartSync() error {
+	settings := m.user.GetConfig().Sync
+	if settings.Server == "" {
+		return nil
+	}
+	client, err := sync.NewClient(settings.Server, settings.Workspace, m.syncToken(settings.TokenVariable))
+	if err != nil {
+		return err
+	}
+	store := sync.NewStorage(client, requests.SyncStatePath(), func(conflict sync.Conflict) {
+		m.requests.Events().Updated("requests:conflict", conflict)
+	})
+	queue, err := storage.NewSyncQueue(store, requests.SyncQueuePath(), m.requests.Events().Emit)
+	if err != nil {
+		return err
+	}
+	// Syncing merges whole trees, so the folders storage is read at once
+	local := m.backend
+	if collections, ok := local.(*requests.CollectionStorage); ok {
+		local = collections.Eager()
+	}
+	coordinator := storage.NewStorageCoordinator(local, store, store.Resolve)
+	coordinator.SetQueue(queue)
+	m.syncQueue = queue
+	m.requests.UseStorage(coordinator)
+	return m.requests.Load()
+}
+
+// syncToken reads the sync token from a secret global variable; no variable sends no token
+func (m *Manager) syncToken(variable string) sync.TokenSource {
+	return func() (string, error) {
+		if variable == "" {
+			return "", nil
+		}
+		for _, v := range m.environments.GetEnvironmentsConfig().Globals {
+			if v.Key == variable && !v.Disabled {
+				if !v.Secret {
+					return "", apperrors.Invalidf("global variable %q holding the sync token must be secret", variable)
+				}
+				return v.Value, nil
+			}
+		}
+		return "", apperrors.NotFoundf("global variable %q holding the sync token not found", variable)
+	}
+}
+
+// SetMaxFolderDepth changes the folder nesting limit (requests.UnlimitedFolderDepth for none).
+// Lowering the limit below the depth of the current tree is rejected.
+func (m *Manager) SetMaxFolderDepth(limit int) error {
+	if limit < 0 {
+		return apperrors.Invalidf("max folder depth must be positive or %d for unlimited", requests.UnlimitedFolderDepth)
+	}
+	if issues := requests.DepthIssues(m.requests.GetRequestsConfig(), limit); len(issues) > 0 {
+		return apperrors.Invalidf("the request tree is deeper than %d levels: %s", limit, issues[0].Message).
+			WithDetail("issues", issues)
+	}
+
+	return m.PatchUser(map[string]interface{}{"maxFolderDepth": limit})
+}
+
+// SetCustomMethods changes the non-standard methods requests may use: those listed (uppercased) and,
+// with allowAny, every uppercase method token. Disallowing a method a request still uses is rejected.
+func (m *Manager) SetCustomMethods(allowAny bool, methods []string) error {
+	allowed := make([]string, 0, len(methods))
+	for _, method := range methods {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if method != "" && !slices.Contains(allowed, method) {
+			allowed = append(allowed, method)
+		}
+	}
+	if err := requests.CheckCustomMethods(allowed); err != nil {
+		return apperrors.Invalidf("%v", err)
+	}
+	if issues := requests.MethodIssues(m.requests.GetRequestsConfig(), allowAny, allowed); len(issues) > 0 {
+		return apperrors.Invalidf("%d request(s) still use a method that would no longer be allowed: %s", len(issues), issues[0].String()).
+			WithDetail("issues", issues)
+	}
+
+	return m.PatchUser(map[string]interface{}{"allowCustomMethods": allowAny, "customMethods": allowed})
+}
+
+// PatchUser patches the user config and applies the settings that affect the other configs
+func (m *Manager) PatchUser(patch map[string]interface{}) error {
+	if err := m.user.Patch(patch); err != nil {
+		return err
+	}
+	return m.applyUserSettings()
+}
+
+// SwitchProfile makes another user profile active and applies its settings to the other configs.
+// The storage backend and sync settings of the profile take effect on the next start.
+func (m *Manager) SwitchProfile(name string) error {
+	if err := m.user.SwitchProfile(name); err != nil {
+		return err
+	}
+	return m.applyUserSettings()
+}
+
+// applyUserSettings pushes the user's folder depth limit and autosave interval to the other configs
+func (m *Manager) applyUserSettings() error {
+	cfg := m.user.GetConfig()
+	if err := requests.SetMaxFolderDepth(cfg.MaxFolderDepth); err != nil {
+		return err
+	}
+	if err := requests.SetCustomMethods(cfg.AllowCustomMethods, cfg.CustomMethods); err != nil {
+		return err
+	}
+	m.requests.SetDebounceDuration(cfg.AutosaveInterval())
+	m.user.SetDebounceDuration(cfg.AutosaveInterval())
+	m.environments.SetDebounceDuration(cfg.AutosaveInterval())
+	m.plugins.SetDebounceDuration(cfg.AutosaveInterval())
+	return nil
+}
+
+// ReadOnlyConfigs lists the configs written by a newer version of paperbox, which are open read-only
+func (m *Manager) ReadOnlyConfigs() []core.ReadOnlyConfig {
+	readOnly := []core.ReadOnlyConfig{}
+	for _, mgr := range m.managers {
+		if r := mgr.ReadOnly(); r != nil {
+			readOnly = append(readOnly, *r)
+		}
+	}
+	return readOnly
+}
+
+// FlushAll synchronously writes every config with pending changes, e.g. before the app exits
+func (m *Manager) FlushAll() error {
+	var errs []error
+	for _, mgr := range m.managers {
+		if err := mgr.Flush(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close flushes pending changes, stops retrying queued syncs and closes the request database, if open
+func (m *Manager) Close() error {
+	err := m.FlushAll()
+	if m.syncQueue != nil {
+		m.syncQueue.Close()
+	}
+	if m.database != nil {
+		err = errors.Join(err, m.database.Close())
+		m.database = nil
+	}
+	return err
+}
+
 // SetContext sets the Wails runtime context for all config managers
 func (m *Manager) SetContext(ctx context.Context, log logger.Logger) {
 	for _, mgr := range m.managers {
@@ -62,6 +267,16 @@ func (m *Manager) User() *user.Manager {
 	return m.user
 }
 
+// Environments returns the environments config manager
+func (m *Manager) Environments() *environments.Manager {
+	return m.environments
+}
+
+// Plugins returns the plugins config manager
+func (m *Manager) Plugins() *plugins.Manager {
+	return m.plugins
+}
+
 // GetRequests returns the requests configuration (for backward compatibility)
 func (m *Manager) GetRequests() *requests.RequestsConfig {
 	return m.requests.GetRequestsConfig()
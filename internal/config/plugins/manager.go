@@ -0,0 +1,73 @@
+package plugins
+
+import (
+	"context"
+	"sort"
+
+	"paperbox/internal/config/core"
+	"paperbox/internal/config/storage"
+
+	"github.com/wailsapp/wails/v2/pkg/logger"
+)
+
+// Manager manages the plugins configuration
+type Manager struct {
+	*core.BaseManager[PluginsConfig]
+}
+
+// NewManager creates a new plugins config manager
+func NewManager(storage storage.Storage) *Manager {
+	return &Manager{
+		BaseManager: core.NewBaseManager(core.BaseManagerOptions[PluginsConfig]{
+			Storage:        storage,
+			ConfigFile:     pluginsFile,
+			EventName:      "plugins",
+			Validator:      Validate,
+			EnsureFunc:     ensureDefaults,
+			CurrentVersion: CurrentVersion,
+			Version:        func(cfg *PluginsConfig) int { return cfg.Version },
+		}),
+	}
+}
+
+// SetContext sets the Wails runtime context for emitting events
+func (m *Manager) SetContext(ctx context.Context, log logger.Logger) {
+	m.BaseManager.SetContext(ctx, log)
+}
+
+// Get returns a copy of the current configuration (implements ManagerInterface)
+func (m *Manager) Get() interface{} {
+	return m.GetPluginsConfig()
+}
+
+// GetPluginsConfig returns the plugins config (type-safe version)
+func (m *Manager) GetPluginsConfig() *PluginsConfig {
+	return m.BaseManager.Get()
+}
+
+// IsEnabled reports whether the user enabled the named plugin
+func (m *Manager) IsEnabled(name string) bool {
+	return m.GetPluginsConfig().Values[name].Enabled
+}
+
+// Enabled returns the names of the enabled plugins, sorted
+func (m *Manager) Enabled() []string {
+	var names []string
+	for name, settings := range m.GetPluginsConfig().Values {
+		if settings.Enabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetEnabled enables or disables the named plugin
+func (m *Manager) SetEnabled(name string, enabled bool) error {
+	return m.UpdateConfig(func(cfg *PluginsConfig) error {
+		settings := cfg.Values[name]
+		settings.Enabled = enabled
+		cfg.Values[name] = settings
+		return nil
+	})
+}
@@ -0,0 +1,78 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/adrg/xdg"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// CurrentVersion is the current version of the plugins config format
+	CurrentVersion = 1
+	// PluginsFileName is the name of the plugins config file
+	PluginsFileName = "plugins.json"
+)
+
+var (
+	appDataDir  = path.Join(xdg.DataHome, "paperbox")
+	pluginsFile = path.Join(appDataDir, PluginsFileName)
+	validate    = validator.New()
+)
+
+// PluginSettings holds the user's choices for one installed plugin
+type PluginSettings struct {
+	Enabled bool `json:"enabled"`
+}
+
+// PluginsConfig records which discovered plugins are enabled, keyed by plugin name
+type PluginsConfig struct {
+	Version int                       `json:"version" validate:"required,min=1"`
+	Values  map[string]PluginSettings `json:"values" validate:"required,dive,keys,required,endkeys"`
+}
+
+// NewPluginsConfig creates a config with no plugins enabled
+func NewPluginsConfig() *PluginsConfig {
+	return &PluginsConfig{
+		Version: CurrentVersion,
+		Values:  make(map[string]PluginSettings),
+	}
+}
+
+// ensureDefaults fills in the version and nil collections of a freshly loaded config
+func ensureDefaults(cfg *PluginsConfig) {
+	if cfg.Version == 0 {
+		cfg.Version = CurrentVersion
+	}
+	if cfg.Values == nil {
+		cfg.Values = make(map[string]PluginSettings)
+	}
+}
+
+// Decode parses and validates a plugins file the way the manager loads it
+func Decode(data []byte) (*PluginsConfig, error) {
+	var cfg PluginsConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse plugins file: %w", err)
+	}
+	ensureDefaults(&cfg)
+	if err := Validate(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate validates the plugins configuration
+func Validate(cfg *PluginsConfig) error {
+	if cfg == nil {
+		return fmt.Errorf("config is nil")
+	}
+
+	if err := validate.Struct(cfg); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	return nil
+}
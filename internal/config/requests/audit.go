@@ -0,0 +1,278 @@
+package requests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"paperbox/internal/apperrors"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// AuditFileName is the name of the audit log in the app data directory
+	AuditFileName = "audit.jsonl"
+	// MaxAuditFileBytes is the size at which the audit log is rotated
+	MaxAuditFileBytes = 4 << 20
+	// AuditFiles is how many audit log files are kept, the current one included
+	AuditFiles = 3
+)
+
+// AuditAction is what happened to an item
+type AuditAction string
+
+const (
+	AuditCreated AuditAction = "created"
+	AuditUpdated AuditAction = "updated"
+	AuditDeleted AuditAction = "deleted"
+)
+
+// auditIgnoredFields are maintained by the manager and not edits of their own
+var auditIgnoredFields = map[string]bool{"createdAt": true, "updatedAt": true, "lastUsedAt": true}
+
+// AuditEntry records one change of an item: who changed it, when, and for updates which fields
+// changed from what to what (JSON values; absent when the field was unset)
+type AuditEntry struct {
+	ID      string        `json:"id"`
+	ItemID  string        `json:"itemId"`
+	Name    string        `json:"name"` // Item name at the time, so deleted items can still be told apart
+	Action  AuditAction   `json:"action"`
+	Actor   string        `json:"actor,omitempty"`
+	At      time.Time     `json:"at"`
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange is one changed field of an item, by its JSON name
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old,omitempty"`
+	New   json.RawMessage `json:"new,omitempty"`
+}
+
+// AuditLog appends audit entries to a JSON lines file, rotating it once it grows past
+// MaxAuditFileBytes (audit.jsonl.1 is the previous file and so on)
+type AuditLog struct {
+	mu    sync.Mutex
+	file  string
+	actor string
+}
+
+// NewAuditLog creates an audit log writing to file on behalf of the current OS user
+func NewAuditLog(file string) *AuditLog {
+	return &AuditLog{file: file, actor: currentActor()}
+}
+
+// DefaultAuditFile returns the path of the audit log in the app data directory
+func DefaultAuditFile() string {
+	return path.Join(appDataDir, AuditFileName)
+}
+
+// currentActor names the user making changes
+func currentActor() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// Append writes entries to the log, stamping them with the log's actor
+func (l *AuditLog) Append(entries []AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.rotate(); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		entry.Actor = l.actor
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit entry: %w", err)
+		}
+		buf.Write(append(line, '\n'))
+	}
+
+	if err := os.MkdirAll(path.Dir(l.file), 0755); err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to create audit log directory")
+	}
+	f, err := os.OpenFile(l.file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to open audit log")
+	}
+	defer f.Close()
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to write audit log")
+	}
+	return nil
+}
+
+// rotate shifts the log files once the current one is full, dropping the oldest
+func (l *AuditLog) rotate() error {
+	info, err := os.Stat(l.file)
+	if err != nil || info.Size() < MaxAuditFileBytes {
+		return nil
+	}
+	for i := AuditFiles - 1; i > 0; i-- {
+		older := fmt.Sprintf("%s.%d", l.file, i)
+		newer := l.file
+		if i > 1 {
+			newer = fmt.Sprintf("%s.%d", l.file, i-1)
+		}
+		if err := os.Rename(newer, older); err != nil && !os.IsNotExist(err) {
+			return apperrors.Wrap(apperrors.IOError, err, "failed to rotate audit log")
+		}
+	}
+	return nil
+}
+
+// History returns the entries of an item, oldest first. An empty itemID returns all entries.
+func (l *AuditLog) History(itemID string) ([]AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := []AuditEntry{}
+	for i := AuditFiles - 1; i >= 0; i-- {
+		file := l.file
+		if i > 0 {
+			file = fmt.Sprintf("%s.%d", l.file, i)
+		}
+		f, err := os.Open(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.IOError, err, "failed to read audit log")
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, MaxAuditFileBytes)
+		for scanner.Scan() {
+			var entry AuditEntry
+			// Skip lines cut short by a crash rather than losing the whole history
+			if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+				continue
+			}
+			if itemID == "" || entry.ItemID == itemID {
+				entries = append(entries, entry)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.IOError, err, "failed to read audit log")
+		}
+	}
+	return entries, nil
+}
+
+// Entry returns an entry by ID
+func (l *AuditLog) Entry(id string) (AuditEntry, error) {
+	entries, err := l.History("")
+	if err != nil {
+		return AuditEntry{}, err
+	}
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+	}
+	return AuditEntry{}, apperrors.NotFoundf("history entry not found")
+}
+
+// auditEntries compares items before an update (encodings from encodeItems) with the result
+func auditEntries(before map[string][]byte, values map[string]Item, now time.Time) []AuditEntry {
+	var entries []AuditEntry
+	add := func(id, name string, action AuditAction, changes []FieldChange) {
+		entries = append(entries, AuditEntry{ID: uuid.New().String(), ItemID: id, Name: name, Action: action, At: now.UTC(), Changes: changes})
+	}
+
+	for id, item := range values {
+		previous, existed := before[id]
+		if !existed {
+			add(id, item.Name, AuditCreated, nil)
+			continue
+		}
+		current, _ := json.Marshal(item)
+		if bytes.Equal(previous, current) {
+			continue
+		}
+		if changes := fieldChanges(previous, current); len(changes) > 0 {
+			add(id, item.Name, AuditUpdated, changes)
+		}
+	}
+	for id, previous := range before {
+		if _, exists := values[id]; !exists {
+			var old Item
+			_ = json.Unmarshal(previous, &old)
+			add(id, old.Name, AuditDeleted, nil)
+		}
+	}
+
+	sort.Slice(entries, func(a, b int) bool { return entries[a].ItemID < entries[b].ItemID })
+	return entries
+}
+
+// fieldChanges lists the fields that differ between two item encodings
+func fieldChanges(previous, current []byte) []FieldChange {
+	var old, updated map[string]json.RawMessage
+	if json.Unmarshal(previous, &old) != nil || json.Unmarshal(current, &updated) != nil {
+		return nil
+	}
+
+	fields := make(map[string]bool, len(old)+len(updated))
+	for field := range old {
+		fields[field] = true
+	}
+	for field := range updated {
+		fields[field] = true
+	}
+
+	var changes []FieldChange
+	for field := range fields {
+		if auditIgnoredFields[field] || bytes.Equal(old[field], updated[field]) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: field, Old: old[field], New: updated[field]})
+	}
+	sort.Slice(changes, func(a, b int) bool { return changes[a].Field < changes[b].Field })
+	return changes
+}
+
+// revertChanges sets the fields of an update entry back to their old values. Fields edited
+// again since then are a conflict, so a revert never silently drops a later edit.
+func revertChanges(item Item, entry AuditEntry) (Item, error) {
+	if entry.Action != AuditUpdated {
+		return item, apperrors.Invalidf("only edits can be reverted, not %s items", entry.Action)
+	}
+
+	current, _ := json.Marshal(item)
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(current, &fields); err != nil {
+		return item, fmt.Errorf("failed to decode item: %w", err)
+	}
+	for _, change := range entry.Changes {
+		if !bytes.Equal(fields[change.Field], change.New) {
+			return item, apperrors.New(apperrors.Conflict, "'%s' was changed again after this edit; revert the later edit first", change.Field)
+		}
+		if change.Old == nil {
+			delete(fields, change.Field)
+		} else {
+			fields[change.Field] = change.Old
+		}
+	}
+
+	reverted, _ := json.Marshal(fields)
+	var result Item
+	if err := json.Unmarshal(reverted, &result); err != nil {
+		return item, fmt.Errorf("failed to decode reverted item: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,193 @@
+package requests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sync"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/core"
+	"paperbox/internal/config/storage"
+)
+
+// DatabaseFileName is the name of the SQLite database used by the sqlite storage backend
+const DatabaseFileName = "paperbox.db"
+
+// SyncStateFileName is the name of the file keeping the team sync state
+const SyncStateFileName = "sync-state.json"
+
+// SyncQueueFileName is the name of the file keeping the saves waiting to be synced
+const SyncQueueFileName = "sync-queue.json"
+
+// DatabasePath returns the path of the SQLite database
+func DatabasePath() string {
+	return path.Join(appDataDir, DatabaseFileName)
+}
+
+// CollectionsDir returns the directory of the folders storage backend
+func CollectionsDir() string {
+	return path.Join(appDataDir, CollectionsDirName)
+}
+
+// SyncStatePath returns the file remembering what was last synced with the team sync server
+func SyncStatePath() string {
+	return path.Join(appDataDir, SyncStateFileName)
+}
+
+// SyncQueuePath returns the file keeping the saves waiting to be synced with the team sync server
+func SyncQueuePath() string {
+	return path.Join(appDataDir, SyncQueueFileName)
+}
+
+// UseStorage switches the requests config to the given backend (e.g. storage.SQLiteStorage or CollectionStorage).
+// It takes effect on the next Load; when the backend holds no config yet, the JSON file is imported into it.
+func (m *Manager) UseStorage(s storage.Storage) {
+	m.SetStorage(s, func() (*RequestsConfig, error) {
+		return loadFrom(s, getRequestsFilePath())
+	})
+}
+
+// SetStorage replaces the storage backend and loader; the config must be reloaded afterwards. With
+// a storage that reads root folders on demand, LoadFolder reads them.
+func (m *Manager) SetStorage(s storage.Storage, loader func() (*RequestsConfig, error)) {
+	m.BaseManager.SetStorage(s, loader)
+	m.folders.use(s, m.Events())
+}
+
+// folderLoader is implemented by storages whose Load leaves out the contents of root folders until
+// they are asked for (CollectionStorage)
+type folderLoader interface {
+	Unloaded() []string
+	LoadFolder(root string) (map[string]Item, error)
+}
+
+// folderSource holds the folder loader of the manager's storage, shared by its revision views
+type folderSource struct {
+	mu     sync.Mutex
+	loader folderLoader
+}
+
+// use takes the folder loader of s, if it has one, and reports its unreadable folder files with
+// EventCollectionSkipped
+func (f *folderSource) use(s storage.Storage, events *core.EventBus) {
+	if collections, ok := s.(*CollectionStorage); ok {
+		collections.SetSkipHandler(func(skipped SkippedCollection) {
+			events.Emit(EventCollectionSkipped, skipped)
+		})
+	}
+	loader, _ := s.(folderLoader)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.loader = loader
+}
+
+func (f *folderSource) get() folderLoader {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.loader
+}
+
+// UnloadedFolders returns the root folders whose contents the storage has not read yet. They are
+// in the config without children until LoadFolder reads them.
+func (m *Manager) UnloadedFolders() []string {
+	loader := m.folders.get()
+	if loader == nil {
+		return []string{}
+	}
+	return loader.Unloaded()
+}
+
+// LoadFolder reads the contents of a root folder the storage left out on load; it does nothing for
+// a folder that is loaded already. A folder whose file cannot be read is dropped from the tree and
+// one that is not valid stays unloaded; either way the failure is returned and emitted with
+// EventCollectionSkipped.
+func (m *Manager) LoadFolder(folderId string) error {
+	return m.loadFolders([]string{folderId})
+}
+
+// LoadFolders reads the contents of every root folder the storage left out on load
+func (m *Manager) LoadFolders() error {
+	return m.loadFolders(nil)
+}
+
+// loadFolders reads those of the given root folders that are not loaded yet, or all of them for
+// nil, into the config. Reading them changes no revision, as they were on disk all along.
+func (m *Manager) loadFolders(ids []string) error {
+	loader := m.folders.get()
+	if loader == nil {
+		return nil
+	}
+	unread := loader.Unloaded()
+	if ids != nil {
+		unread = slices.DeleteFunc(unread, func(id string) bool { return !slices.Contains(ids, id) })
+	}
+	if len(unread) == 0 {
+		return nil
+	}
+
+	var errs []error
+	err := m.Amend(func(cfg *RequestsConfig) (bool, error) {
+		changed := false
+		for _, id := range unread {
+			items, err := loader.LoadFolder(id)
+			if err != nil {
+				errs = append(errs, err)
+				if !slices.Contains(loader.Unloaded(), id) {
+					dropRoot(cfg, id)
+					changed = true
+				}
+				continue
+			}
+			for itemID, item := range items {
+				cfg.Values[itemID] = item
+			}
+			changed = changed || len(items) > 0
+		}
+		if changed {
+			// The folders read may hold locked ones
+			m.locks.cfg = nil
+		}
+		return changed, nil
+	})
+	if err != nil {
+		return err
+	}
+	return apperrors.Ensure(errors.Join(errs...), apperrors.IOError)
+}
+
+// dropRoot removes a root folder that could not be read from the config
+func dropRoot(cfg *RequestsConfig, id string) {
+	delete(cfg.Values, id)
+	cfg.RootOrder = slices.DeleteFunc(slices.Clone(cfg.RootOrder), func(root string) bool { return root == id })
+}
+
+// loadFrom reads the config from s, falling back to the JSON file when s is empty
+func loadFrom(s storage.Storage, key string) (*RequestsConfig, error) {
+	var data json.RawMessage
+	if err := s.Load(key, &data); err != nil {
+		return nil, fmt.Errorf("failed to load requests config: %w", err)
+	}
+
+	if data == nil {
+		config, err := Load()
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Save(key, config); err != nil {
+			return nil, fmt.Errorf("failed to import requests config: %w", err)
+		}
+		return config, nil
+	}
+
+	config, migrated, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if migrated {
+		_ = s.Save(key, config) // Ignore errors, continue with migrated config
+	}
+	return config, nil
+}
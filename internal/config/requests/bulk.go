@@ -0,0 +1,234 @@
+package requests
+
+import (
+	"fmt"
+
+	"paperbox/internal/apperrors"
+
+	"github.com/google/uuid"
+)
+
+// OperationType identifies a bulk operation
+type OperationType string
+
+const (
+	OperationAdd    OperationType = "add"
+	OperationUpdate OperationType = "update"
+	OperationMove   OperationType = "move"
+	OperationDelete OperationType = "delete"
+)
+
+// Operation is a single change in a bulk update.
+//   - add: inserts Item under ParentID (empty for a root folder). ID is optional; when set it is a
+//     placeholder that later operations in the same batch can use to refer to the new item.
+//   - update: replaces the fields of item ID with Item, keeping its children.
+//   - move: moves item ID under ParentID (empty for root level).
+//   - delete: removes item ID and all of its descendants.
+//
+// Index positions the item among its new siblings for add and move; nil appends.
+type Operation struct {
+	Op       OperationType `json:"op"`
+	ID       string        `json:"id,omitempty"`
+	ParentID string        `json:"parentId,omitempty"`
+	Index    *int          `json:"index,omitempty"`
+	Item     *Item         `json:"item,omitempty"`
+}
+
+// BulkUpdate applies the operations in order as one atomic change: the config is validated once,
+// a single updated event is emitted and nothing is applied if any operation fails. Returns the IDs
+// assigned to added items, in operation order.
+func (m *Manager) BulkUpdate(ops []Operation) ([]string, error) {
+	var added []string
+
+	err := m.update(func(cfg *RequestsConfig) error {
+		var err error
+		added, err = applyOperations(cfg, ops)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return added, nil
+}
+
+// applyOperations applies the operations to cfg in order and returns the IDs of added items
+func applyOperations(cfg *RequestsConfig, ops []Operation) ([]string, error) {
+	if cfg.Values == nil {
+		cfg.Values = make(map[string]Item)
+	}
+
+	added := make([]string, 0)
+	placeholders := make(map[string]string)
+	resolve := func(id string) string {
+		if real, ok := placeholders[id]; ok {
+			return real
+		}
+		return id
+	}
+
+	for i, op := range ops {
+		id, parentID := resolve(op.ID), resolve(op.ParentID)
+
+		var err error
+		switch op.Op {
+		case OperationAdd:
+			var newID string
+			newID, err = applyAdd(cfg, parentID, op.Index, op.Item)
+			if err == nil {
+				added = append(added, newID)
+				if op.ID != "" {
+					placeholders[op.ID] = newID
+				}
+			}
+		case OperationUpdate:
+			err = applyUpdate(cfg, id, op.Item)
+		case OperationMove:
+			err = applyMove(cfg, id, parentID, op.Index)
+		case OperationDelete:
+			if _, exists := cfg.Values[id]; !exists {
+				err = apperrors.NotFoundf("item not found")
+			} else {
+				removeItem(cfg, id)
+			}
+		default:
+			err = fmt.Errorf("unknown operation '%s'", op.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i, op.Op, err)
+		}
+	}
+
+	return added, nil
+}
+
+// applyAdd inserts a new item and returns its ID
+func applyAdd(cfg *RequestsConfig, parentID string, index *int, item *Item) (string, error) {
+	if item == nil {
+		return "", fmt.Errorf("item is required")
+	}
+	newItem := *item
+	newItem.Children = nil
+
+	newID := uuid.New().String()
+	cfg.Values[newID] = newItem
+
+	if err := attach(cfg, newID, parentID, index); err != nil {
+		return "", err
+	}
+	return newID, nil
+}
+
+// applyUpdate replaces an item's fields while keeping its type and children
+func applyUpdate(cfg *RequestsConfig, id string, item *Item) error {
+	existing, exists := cfg.Values[id]
+	if !exists {
+		return apperrors.NotFoundf("item not found")
+	}
+	if item == nil {
+		return fmt.Errorf("item is required")
+	}
+	if item.Type != existing.Type {
+		return fmt.Errorf("cannot change the type of an item")
+	}
+
+	updated := *item
+	updated.Children = existing.Children
+	cfg.Values[id] = updated
+	return nil
+}
+
+// applyMove detaches an item and attaches it under a new parent
+func applyMove(cfg *RequestsConfig, id string, parentID string, index *int) error {
+	if _, exists := cfg.Values[id]; !exists {
+		return apperrors.NotFoundf("item not found")
+	}
+	if parentID == id {
+		return fmt.Errorf("cannot move an item into itself")
+	}
+	for _, ancestor := range Ancestors(cfg, parentID) {
+		if ancestor == id {
+			return fmt.Errorf("cannot move a folder into its own descendant")
+		}
+	}
+
+	detach(cfg, id)
+	return attach(cfg, id, parentID, index)
+}
+
+// attach places id among the children of parentID (or the root order when parentID is empty)
+func attach(cfg *RequestsConfig, id string, parentID string, index *int) error {
+	if parentID == "" {
+		if cfg.Values[id].Type != ItemTypeFolder {
+			return fmt.Errorf("root level item must be a folder")
+		}
+		cfg.RootOrder = insertAt(cfg.RootOrder, id, index)
+		return nil
+	}
+
+	parent, exists := cfg.Values[parentID]
+	if !exists || parent.Type != ItemTypeFolder {
+		return apperrors.NotFoundf("parent folder not found")
+	}
+	parent.Children = insertAt(parent.Children, id, index)
+	cfg.Values[parentID] = parent
+	return nil
+}
+
+// detach removes id from every parent's children and from the root order
+func detach(cfg *RequestsConfig, id string) {
+	for parentID, parent := range cfg.Values {
+		if filtered, changed := without(parent.Children, id); changed {
+			parent.Children = filtered
+			cfg.Values[parentID] = parent
+		}
+	}
+	if filtered, changed := without(cfg.RootOrder, id); changed {
+		cfg.RootOrder = filtered
+	}
+}
+
+// removeItem detaches an item and deletes it together with all of its descendants
+func removeItem(cfg *RequestsConfig, id string) {
+	detach(cfg, id)
+
+	var remove func(id string)
+	remove = func(id string) {
+		item, exists := cfg.Values[id]
+		if !exists {
+			return
+		}
+		delete(cfg.Values, id)
+		for _, childID := range item.Children {
+			remove(childID)
+		}
+	}
+	remove(id)
+}
+
+// insertAt inserts id into ids at index (clamped); nil appends
+func insertAt(ids []string, id string, index *int) []string {
+	if index == nil || *index >= len(ids) {
+		return append(ids, id)
+	}
+	i := max(*index, 0)
+	ids = append(ids, "")
+	copy(ids[i+1:], ids[i:])
+	ids[i] = id
+	return ids
+}
+
+// without returns ids with every occurrence of id removed and whether anything was removed
+func without(ids []string, id string) ([]string, bool) {
+	filtered := make([]string, 0, len(ids))
+	for _, existing := range ids {
+		if existing != id {
+			filtered = append(filtered, existing)
+		}
+	}
+	return filtered, len(filtered) != len(ids)
+}
@@ -0,0 +1,470 @@
+package requests
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/storage"
+)
+
+const (
+	// CollectionsDirName is the directory of the folders storage backend
+	CollectionsDirName = "collections"
+
+	// EventCollectionSkipped is emitted with a SkippedCollection when a folder file cannot be read
+	EventCollectionSkipped = "requests:skipped"
+
+	collectionIndexFile   = "_index.json"
+	collectionUnfiledFile = "_unfiled.json"
+)
+
+// collectionIndex is the top-level document of the folders layout
+type collectionIndex struct {
+	Version   int      `json:"version"`
+	RootOrder []string `json:"rootOrder"`
+	// Roots holds every root item without its children, so the tree can be listed before the
+	// folder files are read
+	Roots map[string]Item `json:"roots,omitempty"`
+}
+
+// SkippedCollection is a folder file that could not be read. A file that could not be parsed was
+// moved to *.corrupt-<unix time> and its folder dropped; a folder that is not valid stays unloaded.
+type SkippedCollection struct {
+	FolderID string `json:"folderId,omitempty"` // Empty for the file of unfiled items
+	Error    string `json:"error"`
+}
+
+// collectionFile holds one root folder and every item below it
+type collectionFile struct {
+	Root   string          `json:"root,omitempty"`
+	Values map[string]Item `json:"values"`
+}
+
+// CollectionStorage stores the request tree as one JSON file per root folder under a directory,
+// plus an index with the version, the root order and the root folders themselves. Items not
+// reachable from a root folder go to an "unfiled" file. Load reads only the index and the unfiled
+// file; the contents of a root folder are read by LoadFolder when they are needed. A save rewrites
+// only the files whose content changed and never those of folders not read yet, and a file that
+// cannot be read is set aside instead of failing the whole tree.
+//
+// The directory is fixed at construction; the filePath passed to Load and Save is ignored.
+type CollectionStorage struct {
+	mu      sync.Mutex
+	dir     string
+	writer  storage.Writer
+	items   map[string]Item              // Items as last loaded or saved
+	roots   map[string]string            // Item ID to the root folder whose file holds it ("" for unfiled)
+	written map[string][sha256.Size]byte // File name to hash of its content on disk
+	pending map[string]Item              // Root folders whose file was not read yet, as listed in the index
+	onSkip  func(SkippedCollection)
+}
+
+// NewCollectionStorage creates a folders storage rooted at dir
+func NewCollectionStorage(dir string) *CollectionStorage {
+	return NewCollectionStorageWithWriter(dir, storage.NewIntegrityWriter(storage.NewFileWriter()))
+}
+
+// NewCollectionStorageWithWriter creates a folders storage with a custom writer (for testing)
+func NewCollectionStorageWithWriter(dir string, writer storage.Writer) *CollectionStorage {
+	return &CollectionStorage{
+		dir:     dir,
+		writer:  writer,
+		items:   make(map[string]Item),
+		roots:   make(map[string]string),
+		written: make(map[string][sha256.Size]byte),
+		pending: make(map[string]Item),
+	}
+}
+
+// SetSkipHandler sets the function told about every folder file that cannot be read
+func (c *CollectionStorage) SetSkipHandler(handler func(SkippedCollection)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onSkip = handler
+}
+
+// Load assembles the config from the index and the unfiled items. The root folders are listed
+// without children until LoadFolder reads them; Unloaded returns which. A config of an older
+// version is read whole, for the migrations. Without an index, target is left untouched.
+func (c *CollectionStorage) Load(_ string, target interface{}) error {
+	return c.load(target, true)
+}
+
+// Eager returns a view of the storage whose Load reads every folder file, for callers that need the
+// whole tree at once, such as the sync coordinator
+func (c *CollectionStorage) Eager() storage.ItemStorage {
+	return eagerCollections{storage: c}
+}
+
+// load reads the index and the folder files, leaving out those of the root folders the index
+// lists when lazy is set. Unreadable folder files are renamed to *.corrupt and their folders
+// dropped.
+func (c *CollectionStorage) load(target interface{}, lazy bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := storage.ReadVerified(filepath.Join(c.dir, collectionIndexFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read collection index: %w", err)
+	}
+	var index collectionIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return fmt.Errorf("failed to parse collection index: %w", err)
+	}
+	c.written[collectionIndexFile] = sha256.Sum256(data)
+	lazy = lazy && index.Version == CurrentVersion
+
+	items := make(map[string]Item)
+	roots := make(map[string]string)
+	pending := make(map[string]Item)
+	values := make(map[string]Item)
+	rootOrder := make([]string, 0, len(index.RootOrder))
+	for _, root := range append(index.RootOrder, "") {
+		if folder, listed := index.Roots[root]; lazy && listed && root != "" {
+			pending[root] = folder
+			values[root] = folder
+			rootOrder = append(rootOrder, root)
+			continue
+		}
+		file, err := c.readFile(root)
+		if err != nil {
+			if root != "" || !errors.Is(err, os.ErrNotExist) {
+				c.skip(root, err)
+			}
+			continue
+		}
+		for id, item := range file.Values {
+			items[id] = item
+			roots[id] = root
+			values[id] = item
+		}
+		if root != "" {
+			rootOrder = append(rootOrder, root)
+		}
+	}
+
+	c.items, c.roots, c.pending = items, roots, pending
+	config := RequestsConfig{Version: index.Version, RootOrder: rootOrder, Values: values}
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to assemble requests config: %w", err)
+	}
+	return json.Unmarshal(encoded, target)
+}
+
+// Unloaded returns the root folders whose contents the last Load left out and LoadFolder has not
+// read yet
+func (c *CollectionStorage) Unloaded() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	roots := make([]string, 0, len(c.pending))
+	for root := range c.pending {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// LoadFolder reads the file of a root folder the last Load left out and returns its items, the
+// folder included. It returns nil for a folder that was read already. A file that cannot be read
+// is set aside like on Load and its folder dropped; a folder that is not valid stays unloaded.
+func (c *CollectionStorage) LoadFolder(root string) (map[string]Item, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.pending[root]; !ok {
+		return nil, nil
+	}
+	file, err := c.readFile(root)
+	if err != nil {
+		delete(c.pending, root)
+		c.skip(root, err)
+		return nil, err
+	}
+	if err := Validate(&RequestsConfig{Version: CurrentVersion, RootOrder: []string{root}, Values: file.Values}); err != nil {
+		invalid := apperrors.Wrap(apperrors.ValidationFailed, err, fmt.Sprintf("folder %s is invalid", root))
+		c.skip(root, invalid)
+		return nil, invalid
+	}
+
+	delete(c.pending, root)
+	for id, item := range file.Values {
+		c.items[id] = item
+		c.roots[id] = root
+	}
+	// The caller gets its own copy, as Load decodes one into its target
+	data, err := json.Marshal(file.Values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy folder %s: %w", root, err)
+	}
+	var items map[string]Item
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("failed to copy folder %s: %w", root, err)
+	}
+	return items, nil
+}
+
+// skip reports a folder file that could not be read (must hold the lock)
+func (c *CollectionStorage) skip(root string, err error) {
+	if c.onSkip != nil {
+		c.onSkip(SkippedCollection{FolderID: root, Error: err.Error()})
+	}
+}
+
+// readFile reads the file of a root folder, setting a corrupt file aside (must hold the lock)
+func (c *CollectionStorage) readFile(root string) (*collectionFile, error) {
+	name, err := collectionFileName(root)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(c.dir, name)
+	data, err := storage.ReadVerified(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	var file collectionFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		backup := fmt.Sprintf("%s.corrupt-%d", path, time.Now().Unix())
+		if renameErr := os.Rename(path, backup); renameErr != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, errors.Join(err, renameErr))
+		}
+		return nil, fmt.Errorf("failed to parse %s (moved to %s): %w", name, filepath.Base(backup), err)
+	}
+	c.written[name] = sha256.Sum256(data)
+	return &file, nil
+}
+
+// Save writes the whole config, skipping files whose content did not change.
+func (c *CollectionStorage) Save(_ string, data interface{}) error {
+	cfg, err := asRequestsConfig(data)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[string]Item, len(cfg.Values))
+	for id, item := range cfg.Values {
+		c.items[id] = item
+	}
+	return c.write(cfg.Version, cfg.RootOrder, nil)
+}
+
+// SaveItems applies the given item changes and rewrites only the files they belong to.
+func (c *CollectionStorage) SaveItems(_ string, document interface{}, items map[string]interface{}) error {
+	cfg, err := asRequestsConfig(document)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changed := make(map[string]bool, len(items))
+	for id, value := range items {
+		changed[id] = true
+		if value == nil {
+			delete(c.items, id)
+			continue
+		}
+		item, ok := value.(Item)
+		if !ok {
+			return fmt.Errorf("unexpected item type %T", value)
+		}
+		c.items[id] = item
+	}
+	return c.write(cfg.Version, cfg.RootOrder, changed)
+}
+
+// write regroups the items by root folder and writes the index and the affected files. The files
+// of root folders not read yet are left as they are, unless the folder left the root order.
+// A nil changed set treats every file as affected; unchanged content is still not rewritten (must hold the lock).
+func (c *CollectionStorage) write(version int, rootOrder []string, changed map[string]bool) error {
+	affected := make(map[string]bool)
+	listed := make(map[string]bool, len(rootOrder))
+	for _, root := range rootOrder {
+		listed[root] = true
+	}
+	for root := range c.pending {
+		if !listed[root] {
+			affected[root] = true
+			delete(c.pending, root)
+		}
+	}
+
+	// What the config holds of a folder not read yet is its index entry, not its contents
+	roots := groupByRoot(c.items, rootOrder)
+	for id, root := range roots {
+		if _, unread := c.pending[root]; unread {
+			delete(roots, id)
+		}
+	}
+
+	// Files to rewrite: those of changed items and of items that moved between root folders
+	for id, root := range roots {
+		old, existed := c.roots[id]
+		if changed == nil || changed[id] || !existed || old != root {
+			affected[root] = true
+			if existed {
+				affected[old] = true
+			}
+		}
+	}
+	for id, old := range c.roots {
+		if _, ok := roots[id]; !ok {
+			affected[old] = true
+		}
+	}
+
+	// A nil file removes it: its root folder no longer holds any items
+	files := make(map[string]*collectionFile, len(affected))
+	for root := range affected {
+		files[root] = nil
+	}
+	for id, root := range roots {
+		if !affected[root] {
+			continue
+		}
+		file := files[root]
+		if file == nil {
+			file = &collectionFile{Root: root, Values: make(map[string]Item)}
+			files[root] = file
+		}
+		file.Values[id] = c.items[id]
+	}
+
+	for root, file := range files {
+		name, err := collectionFileName(root)
+		if err != nil {
+			return err
+		}
+		if file == nil {
+			if err := storage.RemoveFile(filepath.Join(c.dir, name)); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", name, err)
+			}
+			delete(c.written, name)
+			continue
+		}
+		if err := c.writeFile(name, file); err != nil {
+			return err
+		}
+	}
+
+	if rootOrder == nil {
+		rootOrder = []string{}
+	}
+	index := collectionIndex{Version: version, RootOrder: rootOrder, Roots: make(map[string]Item, len(rootOrder))}
+	for _, root := range rootOrder {
+		if folder, unread := c.pending[root]; unread {
+			index.Roots[root] = folder
+		} else if item, ok := c.items[root]; ok {
+			item.Children = nil
+			index.Roots[root] = item
+		}
+	}
+	if err := c.writeFile(collectionIndexFile, index); err != nil {
+		return err
+	}
+
+	c.roots = roots
+	return nil
+}
+
+// writeFile writes value as indented JSON unless the file already has that content (must hold the lock)
+func (c *CollectionStorage) writeFile(name string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+	hash := sha256.Sum256(data)
+	if old, ok := c.written[name]; ok && old == hash {
+		return nil
+	}
+	if err := c.writer.WriteAtomic(filepath.Join(c.dir, name), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	c.written[name] = hash
+	return nil
+}
+
+// groupByRoot maps every item to the root folder it is stored under ("" when unreachable)
+func groupByRoot(values map[string]Item, rootOrder []string) map[string]string {
+	roots := make(map[string]string, len(values))
+	var walk func(id, root string)
+	walk = func(id, root string) {
+		item, ok := values[id]
+		if !ok {
+			return
+		}
+		if _, seen := roots[id]; seen {
+			return
+		}
+		roots[id] = root
+		for _, child := range item.Children {
+			walk(child, root)
+		}
+	}
+	for _, root := range rootOrder {
+		walk(root, root)
+	}
+	for id := range values {
+		if _, ok := roots[id]; !ok {
+			roots[id] = ""
+		}
+	}
+	return roots
+}
+
+// collectionFileName returns the file of a root folder, rejecting IDs unsafe as file names
+func collectionFileName(root string) (string, error) {
+	if root == "" {
+		return collectionUnfiledFile, nil
+	}
+	if root != filepath.Base(root) || strings.HasPrefix(root, "_") || strings.HasPrefix(root, ".") {
+		return "", fmt.Errorf("folder id %q cannot be used as a file name", root)
+	}
+	return root + ".json", nil
+}
+
+// eagerCollections is a CollectionStorage whose Load reads every folder file
+type eagerCollections struct {
+	storage *CollectionStorage
+}
+
+func (e eagerCollections) Load(_ string, target interface{}) error {
+	return e.storage.load(target, false)
+}
+
+func (e eagerCollections) Save(filePath string, data interface{}) error {
+	return e.storage.Save(filePath, data)
+}
+
+func (e eagerCollections) SaveItems(filePath string, document interface{}, items map[string]interface{}) error {
+	return e.storage.SaveItems(filePath, document, items)
+}
+
+// asRequestsConfig accepts the config by value or pointer
+func asRequestsConfig(data interface{}) (*RequestsConfig, error) {
+	switch cfg := data.(type) {
+	case *RequestsConfig:
+		return cfg, nil
+	case RequestsConfig:
+		return &cfg, nil
+	default:
+		return nil, fmt.Errorf("unexpected config type %T", data)
+	}
+}
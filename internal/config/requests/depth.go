@@ -0,0 +1,170 @@
+package requests
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+)
+
+const (
+	// DefaultMaxFolderDepth is the default limit on folder nesting
+	// Structure: root (level 0) -> nested (level 1) -> nested (level 2) -> request
+	// This means maximum 3 folders in the chain
+	DefaultMaxFolderDepth = 3
+	// UnlimitedFolderDepth disables the nesting limit
+	UnlimitedFolderDepth = 0
+)
+
+// maxFolderDepth holds the active limit, shared by validation and imports
+var maxFolderDepth atomic.Int64
+
+func init() {
+	maxFolderDepth.Store(DefaultMaxFolderDepth)
+}
+
+// MaxFolderDepth returns the active folder nesting limit (UnlimitedFolderDepth if none)
+func MaxFolderDepth() int {
+	return int(maxFolderDepth.Load())
+}
+
+// SetMaxFolderDepth changes the folder nesting limit; UnlimitedFolderDepth removes it
+func SetMaxFolderDepth(limit int) error {
+	if limit < 0 {
+		return fmt.Errorf("max folder depth must be positive or %d for unlimited", UnlimitedFolderDepth)
+	}
+	maxFolderDepth.Store(int64(limit))
+	return nil
+}
+
+// DepthIssues reports folders that would break the given nesting limit
+func DepthIssues(config *RequestsConfig, limit int) []Issue {
+	ids := make([]string, 0, len(config.Values))
+	for id := range config.Values {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	if len(cycleIssues(ids, config.Values)) > 0 {
+		return nil
+	}
+	return nestingIssues(ids, config.Values, limit)
+}
+
+// nestingIssues validates that folder nesting doesn't exceed limit
+// Time complexity: O(n*m) where n is number of items, m is average number of children
+func nestingIssues(ids []string, allItems map[string]Item, limit int) []Issue {
+	if limit == UnlimitedFolderDepth {
+		return nil
+	}
+
+	var issues []Issue
+	referencedIDs := referenceIDs(allItems)
+
+	// Track visited items to avoid infinite loops (defensive check)
+	visited := make(map[string]bool)
+
+	// Validate depth starting from each root level folder
+	for _, id := range ids {
+		if !referencedIDs[id] && allItems[id].Type == ItemTypeFolder {
+			issues = append(issues, folderDepthIssues(id, allItems, 0, limit, visited)...)
+		}
+	}
+
+	return issues
+}
+
+// folderDepthIssues recursively validates folder depth
+func folderDepthIssues(itemID string, allItems map[string]Item, currentDepth, limit int, visited map[string]bool) []Issue {
+	// Check for cycles (defensive check)
+	if visited[itemID] {
+		return []Issue{{ItemID: itemID, Field: "children", Message: fmt.Sprintf("circular reference detected in folder depth validation for item '%s'", itemID), Severity: SeverityError}}
+	}
+	visited[itemID] = true
+	defer delete(visited, itemID)
+
+	item := allItems[itemID]
+	if item.Type != ItemTypeFolder {
+		return nil
+	}
+
+	// A limit of 3 means: root(0) -> nested(1) -> nested(2) -> request
+	if currentDepth >= limit {
+		return []Issue{{ItemID: itemID, Field: "children", Message: fmt.Sprintf("folder '%s' exceeds maximum nesting depth of %d levels", itemID, limit), Severity: SeverityError}}
+	}
+
+	var issues []Issue
+	for _, childID := range item.Children {
+		childItem, exists := allItems[childID]
+		if !exists {
+			continue // Already reported by referenceIssues
+		}
+
+		// If child is a folder, increment depth; if it's a request, depth stays the same
+		nextDepth := currentDepth
+		if childItem.Type == ItemTypeFolder {
+			nextDepth = currentDepth + 1
+			if nextDepth >= limit {
+				issues = append(issues, Issue{ItemID: itemID, Field: "children", Message: fmt.Sprintf("folder '%s' at depth %d cannot contain nested folders (maximum depth is %d)", itemID, currentDepth, limit), Severity: SeverityError})
+				continue
+			}
+		}
+
+		issues = append(issues, folderDepthIssues(childID, allItems, nextDepth, limit, visited)...)
+	}
+
+	return issues
+}
+
+// folderDepth returns the nesting level of a folder (0 for root folders)
+func folderDepth(cfg *RequestsConfig, folderID string) int {
+	parents := make(map[string]string, len(cfg.Values))
+	for id, item := range cfg.Values {
+		for _, childID := range item.Children {
+			parents[childID] = id
+		}
+	}
+
+	depth := 0
+	for id, ok := parents[folderID]; ok && depth <= len(cfg.Values); id, ok = parents[id] {
+		depth++
+	}
+	return depth
+}
+
+// flattenNodes hoists the requests of folders that would land at or beyond limit into their
+// deepest allowed ancestor, prefixing request names with the dropped folder names.
+// depth is the level the nodes will be inserted at; nodes are returned unchanged when unlimited.
+func flattenNodes(nodes []Node, depth, limit int) []Node {
+	if limit == UnlimitedFolderDepth {
+		return nodes
+	}
+
+	flattened := make([]Node, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Item.Type != ItemTypeFolder {
+			flattened = append(flattened, node)
+			continue
+		}
+		if depth >= limit {
+			flattened = append(flattened, hoistRequests(node, node.Item.Name)...)
+			continue
+		}
+		node.Children = flattenNodes(node.Children, depth+1, limit)
+		flattened = append(flattened, node)
+	}
+	return flattened
+}
+
+// hoistRequests returns every request below a folder, named "<folder> / <request>"
+func hoistRequests(folder Node, prefix string) []Node {
+	var hoisted []Node
+	for _, child := range folder.Children {
+		if child.Item.Type == ItemTypeFolder {
+			hoisted = append(hoisted, hoistRequests(child, prefix+" / "+child.Item.Name)...)
+			continue
+		}
+		child.Item.Name = prefix + " / " + child.Item.Name
+		hoisted = append(hoisted, child)
+	}
+	return hoisted
+}
@@ -0,0 +1,130 @@
+package requests
+
+import (
+	"fmt"
+	"strings"
+
+	"paperbox/internal/apperrors"
+)
+
+// Examples are the named variants saved under a request
+type Examples struct {
+	Requests  []RequestExample  `json:"requests,omitempty" validate:"omitempty,dive"`
+	Responses []ResponseExample `json:"responses,omitempty" validate:"omitempty,dive"`
+}
+
+// RequestExample is a named variant of a request. Its body replaces the request body when set;
+// its parameters and headers replace those of the request with the same key or are added.
+type RequestExample struct {
+	Name        string   `json:"name" validate:"required"`
+	QueryParams []Param  `json:"queryParams,omitempty" validate:"omitempty,dive"`
+	PathVars    []Param  `json:"pathVars,omitempty" validate:"omitempty,dive"`
+	Headers     []Header `json:"headers,omitempty" validate:"omitempty,dive"`
+	Body        string   `json:"body,omitempty"`
+}
+
+// ResponseExample is a named response of a request, e.g. for documentation and mocking.
+// Request optionally names the request example it answers.
+type ResponseExample struct {
+	Name    string   `json:"name" validate:"required"`
+	Request string   `json:"request,omitempty"`
+	Status  int      `json:"status" validate:"min=100,max=599"`
+	Headers []Header `json:"headers,omitempty" validate:"omitempty,dive"`
+	Body    string   `json:"body,omitempty"`
+}
+
+// RequestExample returns the request example with the given name
+func (e *Examples) RequestExample(name string) (RequestExample, bool) {
+	if e != nil {
+		for _, example := range e.Requests {
+			if example.Name == name {
+				return example, true
+			}
+		}
+	}
+	return RequestExample{}, false
+}
+
+// ResponseExample returns the response example with the given name
+func (e *Examples) ResponseExample(name string) (ResponseExample, bool) {
+	if e != nil {
+		for _, example := range e.Responses {
+			if example.Name == name {
+				return example, true
+			}
+		}
+	}
+	return ResponseExample{}, false
+}
+
+// WithExample returns a copy of a request with one of its request examples applied
+func WithExample(item Item, name string) (Item, error) {
+	example, ok := item.Examples.RequestExample(name)
+	if !ok {
+		return item, apperrors.NotFoundf("request has no example named '%s'", name)
+	}
+
+	if example.Body != "" {
+		item.Body, item.FormFields = example.Body, nil
+	}
+	item.QueryParams = mergeParams(item.QueryParams, example.QueryParams)
+	item.PathVars = mergeParams(item.PathVars, example.PathVars)
+
+	headers := append([]Header{}, item.Headers...)
+	for _, h := range example.Headers {
+		replaced := false
+		for i := range headers {
+			if strings.EqualFold(headers[i].Key, h.Key) {
+				headers[i], replaced = h, true
+			}
+		}
+		if !replaced {
+			headers = append(headers, h)
+		}
+	}
+	item.Headers = headers
+	return item, nil
+}
+
+// mergeParams replaces params with overrides of the same key and appends the others
+func mergeParams(params, overrides []Param) []Param {
+	merged := append([]Param{}, params...)
+	for _, p := range overrides {
+		replaced := false
+		for i := range merged {
+			if merged[i].Key == p.Key {
+				merged[i], replaced = p, true
+			}
+		}
+		if !replaced {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+// exampleIssues reports duplicate example names and response examples for unknown request examples
+func exampleIssues(examples *Examples) []string {
+	if examples == nil {
+		return nil
+	}
+	var issues []string
+	requestNames := make(map[string]bool, len(examples.Requests))
+	for _, example := range examples.Requests {
+		if requestNames[example.Name] {
+			issues = append(issues, fmt.Sprintf("request example '%s' is defined more than once", example.Name))
+		}
+		requestNames[example.Name] = true
+	}
+	responseNames := make(map[string]bool, len(examples.Responses))
+	for _, example := range examples.Responses {
+		if responseNames[example.Name] {
+			issues = append(issues, fmt.Sprintf("response example '%s' is defined more than once", example.Name))
+		}
+		responseNames[example.Name] = true
+		if example.Request != "" && !requestNames[example.Request] {
+			issues = append(issues, fmt.Sprintf("response example '%s' answers unknown request example '%s'", example.Name, example.Request))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,96 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// itemFields is Item without its JSON methods, so they can use the default encoding
+type itemFields Item
+
+// knownItemFields are the lowercased JSON names of Item's fields, as decoding ignores case
+var knownItemFields = jsonFieldNames(reflect.TypeOf(Item{}))
+
+// UnmarshalJSON decodes an item, keeping the fields this version does not know in Extra
+func (i *Item) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*itemFields)(i)); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	i.Extra = nil
+	for name, value := range fields {
+		if !knownItemFields[strings.ToLower(name)] {
+			if i.Extra == nil {
+				i.Extra = make(map[string]json.RawMessage)
+			}
+			i.Extra[name] = value
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes an item followed by its unknown fields, in name order
+func (i Item) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(itemFields(i))
+	if err != nil || len(i.Extra) == 0 {
+		return data, err
+	}
+
+	names := make([]string, 0, len(i.Extra))
+	for name, value := range i.Extra {
+		if len(value) > 0 && !knownItemFields[strings.ToLower(name)] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+	buf.Write(data[:len(data)-1])
+	separator := len(data) > 2
+	for _, name := range names {
+		if separator {
+			buf.WriteByte(',')
+		}
+		separator = true
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(i.Extra[name])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// keepExtra gives an item replacing existing the unknown fields of existing when it has none of
+// its own, e.g. when the UI, which does not know them, sends an edited item back
+func keepExtra(item, existing Item) Item {
+	if item.Extra == nil && existing.Extra != nil {
+		item.Extra = existing.Extra
+	}
+	return item
+}
+
+// jsonFieldNames returns the JSON names of a struct's encoded fields
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		names[strings.ToLower(name)] = true
+	}
+	return names
+}
@@ -0,0 +1,63 @@
+package requests
+
+import (
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// FormContentType is the media type of URL-encoded form bodies
+const FormContentType = "application/x-www-form-urlencoded"
+
+// EncodeForm encodes the enabled form fields in order, as a URL-encoded body
+func EncodeForm(fields []FormField) string {
+	var b strings.Builder
+	for _, f := range fields {
+		if f.Disabled {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(url.QueryEscape(f.Key))
+		b.WriteByte('=')
+		b.WriteString(url.QueryEscape(f.Value))
+	}
+	return b.String()
+}
+
+// ParseFormBody splits the body of a request sent as a URL-encoded form (by its enabled
+// Content-Type header) into form fields, in order. Bodies that are not a plain key=value list,
+// e.g. a single {{variable}} holding the whole form, are not split.
+func ParseFormBody(item Item) ([]FormField, bool) {
+	body := strings.TrimSpace(item.Body)
+	if item.Type != ItemTypeRequest || body == "" || !isFormRequest(item.Headers) {
+		return nil, false
+	}
+
+	var fields []FormField
+	for _, pair := range strings.Split(body, "&") {
+		if pair == "" {
+			continue
+		}
+		rawKey, rawValue, found := strings.Cut(pair, "=")
+		key, errKey := url.QueryUnescape(rawKey)
+		value, errValue := url.QueryUnescape(rawValue)
+		if !found || errKey != nil || errValue != nil || key == "" || strings.Contains(key, "{{") {
+			return nil, false
+		}
+		fields = append(fields, FormField{Key: key, Value: value})
+	}
+	return fields, len(fields) > 0
+}
+
+// isFormRequest reports whether the enabled Content-Type header declares a URL-encoded form
+func isFormRequest(headers []Header) bool {
+	for _, h := range headers {
+		if !h.Disabled && strings.EqualFold(h.Key, "Content-Type") {
+			mediaType, _, _ := mime.ParseMediaType(h.Value)
+			return mediaType == FormContentType
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"paperbox/internal/apperrors"
+)
+
+// ToggleLock locks or unlocks a folder and returns whether it is now locked. With a nil locked the
+// current state is flipped.
+func (m *Manager) ToggleLock(folderId string, locked *bool) (bool, error) {
+	var state bool
+	err := m.update(func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[folderId]
+		if !exists || item.Type != ItemTypeFolder {
+			return apperrors.NotFoundf("folder not found")
+		}
+		state = !item.Locked
+		if locked != nil {
+			state = *locked
+		}
+		item.Locked = state
+		cfg.Values[folderId] = item
+		return nil
+	})
+	return state, err
+}
+
+// lockedItems maps the locked folders and every item below them to the locked folder closest to
+// the root
+func lockedItems(cfg *RequestsConfig) map[string]string {
+	locked := make(map[string]string)
+	var mark func(id string, folderID string)
+	mark = func(id string, folderID string) {
+		if _, seen := locked[id]; seen {
+			return
+		}
+		locked[id] = folderID
+		for _, childID := range cfg.Values[id].Children {
+			mark(childID, folderID)
+		}
+	}
+	for id, item := range cfg.Values {
+		if item.Locked {
+			if outer := lockedAncestor(cfg, id); outer != "" {
+				mark(id, outer)
+			} else {
+				mark(id, id)
+			}
+		}
+	}
+	return locked
+}
+
+// lockIndex caches lockedItems for the config it was built from. updateItems changes neither the
+// tree nor the locks, so the cache stays valid until an update, load or patch replaces the config.
+// It is guarded by the config lock.
+type lockIndex struct {
+	cfg    *RequestsConfig
+	locked map[string]string
+}
+
+// of returns the locked items of cfg, mapping them again when cfg is not the cached config
+func (x *lockIndex) of(cfg *RequestsConfig) map[string]string {
+	if x.cfg != cfg {
+		x.cfg, x.locked = cfg, lockedItems(cfg)
+	}
+	return x.locked
+}
+
+// lockedAncestor returns the outermost locked folder containing itemID, if any
+func lockedAncestor(cfg *RequestsConfig, itemID string) string {
+	var outermost string
+	for _, id := range Ancestors(cfg, itemID) {
+		if cfg.Values[id].Locked {
+			outermost = id
+		}
+	}
+	return outermost
+}
+
+// checkLocks fails with a Locked error when an update changed, added or removed an item that is
+// locked before or after it. locked comes from lockedItems and before from encodeItems, both taken
+// before the update. Sending, favoriting and the lock itself are not changes.
+func checkLocks(cfg *RequestsConfig, before map[string][]byte, locked map[string]string) error {
+	after := lockedItems(cfg)
+	if len(locked) == 0 && len(after) == 0 {
+		return nil
+	}
+
+	for id, item := range cfg.Values {
+		folderID, isLocked := locked[id]
+		if !isLocked {
+			folderID, isLocked = after[id]
+		}
+		if !isLocked {
+			continue
+		}
+		previous, existed := before[id]
+		if existed {
+			current, _ := json.Marshal(item)
+			if bytes.Equal(previous, current) {
+				continue
+			}
+			var old Item
+			if json.Unmarshal(previous, &old) == nil && bytes.Equal(lockFingerprint(old), lockFingerprint(item)) {
+				continue
+			}
+		}
+		return lockedError(cfg, before, id, folderID)
+	}
+	for id, folderID := range locked {
+		if _, exists := cfg.Values[id]; !exists {
+			return lockedError(cfg, before, id, folderID)
+		}
+	}
+	return nil
+}
+
+// lockFingerprint encodes an item without what may change in a locked folder
+func lockFingerprint(item Item) []byte {
+	item.Locked, item.Favorite = false, false
+	return fingerprint(item)
+}
+
+// lockedError reports an attempt to modify an item of a locked folder, naming the folder as it is
+// or, when the update removed it, as it was
+func lockedError(cfg *RequestsConfig, before map[string][]byte, itemID string, folderID string) error {
+	folder, exists := cfg.Values[folderID]
+	if !exists {
+		_ = json.Unmarshal(before[folderID], &folder)
+	}
+	name := folder.Name
+	if name == "" {
+		name = folderID
+	}
+	return apperrors.New(apperrors.Locked, "'%s' is locked; unlock it to make changes", name).
+		WithDetail("itemId", itemID).
+		WithDetail("folderId", folderID)
+}
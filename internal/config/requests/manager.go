@@ -1,9 +1,16 @@
 package requests
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"paperbox/internal/apperrors"
 	"paperbox/internal/config/core"
 	"paperbox/internal/config/storage"
 
@@ -15,17 +22,27 @@ import (
 // Manager manages the requests configuration with in-memory state and debounced saves
 type Manager struct {
 	*core.BaseManager[RequestsConfig]
+	expected uint64     // Revision mutations must be based on (core.AnyRevision skips the check)
+	audit    *AuditLog  // Records item edits; nil disables it
+	locks    *lockIndex // Locked items of the current config, for updateItems
+	folders  *folderSource
 }
 
 // NewManager creates a new requests config manager
 func NewManager(storage storage.Storage) *Manager {
-	return &Manager{
+	m := &Manager{
+		audit:   NewAuditLog(DefaultAuditFile()),
+		locks:   &lockIndex{},
+		folders: &folderSource{},
 		BaseManager: core.NewBaseManager(core.BaseManagerOptions[RequestsConfig]{
-			Storage:    storage,
-			ConfigFile: getRequestsFilePath(),
-			EventName:  "requests",
-			Loader:     Load,
-			Validator:  Validate,
+			Storage:        storage,
+			ConfigFile:     getRequestsFilePath(),
+			EventName:      "requests",
+			Loader:         Load,
+			Validator:      Validate,
+			Split:          splitItems,
+			CurrentVersion: CurrentVersion,
+			Version:        func(cfg *RequestsConfig) int { return cfg.Version },
 			EnsureFunc: func(cfg *RequestsConfig) {
 				if cfg.Version == 0 {
 					cfg.Version = CurrentVersion
@@ -33,6 +50,8 @@ func NewManager(storage storage.Storage) *Manager {
 			},
 		}),
 	}
+	m.folders.use(storage, m.Events())
+	return m
 }
 
 // NewManagerWithWriter creates a new requests config manager with a custom writer (for testing)
@@ -41,12 +60,17 @@ func NewManagerWithWriter(writer storage.Writer) *Manager {
 	coordinator := storage.NewStorageCoordinator(fileStorage, nil, nil)
 
 	return &Manager{
+		locks:   &lockIndex{},
+		folders: &folderSource{},
 		BaseManager: core.NewBaseManager(core.BaseManagerOptions[RequestsConfig]{
-			Storage:    coordinator,
-			ConfigFile: getRequestsFilePath(),
-			EventName:  "requests",
-			Loader:     Load,
-			Validator:  Validate,
+			Storage:        coordinator,
+			ConfigFile:     getRequestsFilePath(),
+			EventName:      "requests",
+			Loader:         Load,
+			Validator:      Validate,
+			Split:          splitItems,
+			CurrentVersion: CurrentVersion,
+			Version:        func(cfg *RequestsConfig) int { return cfg.Version },
 			EnsureFunc: func(cfg *RequestsConfig) {
 				if cfg.Version == 0 {
 					cfg.Version = CurrentVersion
@@ -56,6 +80,181 @@ func NewManagerWithWriter(writer storage.Writer) *Manager {
 	}
 }
 
+// AtRevision returns a view of the manager whose mutations fail with a *core.ConflictError
+// unless the config is still at the given revision
+func (m *Manager) AtRevision(revision uint64) *Manager {
+	return &Manager{BaseManager: m.BaseManager, expected: revision, audit: m.audit, locks: m.locks, folders: m.folders}
+}
+
+// SetAuditLog replaces the log item edits are recorded in; nil disables recording
+func (m *Manager) SetAuditLog(log *AuditLog) {
+	m.audit = log
+}
+
+// update applies a mutation, checking the expected revision when one is set,
+// and stamps the timestamps of created and modified items. Only the items it touched
+// are marked for saving. Applied changes are recorded in the audit log.
+func (m *Manager) update(updater func(cfg *RequestsConfig) error) error {
+	// The whole tree is checked, so every folder must be read; one that was dropped is no obstacle
+	if err := m.loadFolders(nil); err != nil && len(m.UnloadedFolders()) > 0 {
+		return err
+	}
+	var entries []AuditEntry
+	err := m.UpdateItemsAt(m.expected, func(cfg *RequestsConfig) ([]string, error) {
+		before := encodeItems(cfg.Values)
+		locked := lockedItems(cfg)
+		if err := updater(cfg); err != nil {
+			return nil, err
+		}
+		if err := checkLocks(cfg, before, locked); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		if m.audit != nil {
+			entries = auditEntries(before, cfg.Values, now)
+		}
+		// A non-nil slice, even if empty, keeps the save to the touched items and the document
+		ids := append([]string{}, stampItems(cfg.Values, before, now)...)
+		return append(ids, removedItems(cfg.Values, before)...), nil
+	})
+	if err == nil {
+		m.record(entries)
+	}
+	return err
+}
+
+// errTreeChanged makes updateItems leave a mutation to update
+var errTreeChanged = errors.New("the update changes the tree")
+
+// updateItems is update for mutations that only edit the listed existing items, such as the editor
+// saving a request. It changes the current config instead of a copy and only encodes, checks,
+// stamps and validates those items, so its cost follows the change and not the size of the config.
+// The updater must not touch other items. When it adds or moves items, changes an item's type or
+// locks a folder, it is run again through update, which checks the whole tree.
+func (m *Manager) updateItems(ids []string, updater func(cfg *RequestsConfig) error) error {
+	// An unread root folder is in the config without its children, which the update would save
+	if err := m.loadFolders(ids); err != nil {
+		return err
+	}
+	var entries []AuditEntry
+	err := m.UpdateItemsInPlaceAt(m.expected, func(cfg *RequestsConfig) ([]string, error) {
+		originals := make(map[string]Item, len(ids))
+		for _, id := range ids {
+			item, exists := cfg.Values[id]
+			if !exists {
+				return nil, errTreeChanged
+			}
+			originals[id] = item
+		}
+		before := encodeItems(originals)
+		restore := func() {
+			for id, item := range originals {
+				cfg.Values[id] = item
+			}
+		}
+
+		// The updater edits copies, so a failed update leaves nothing behind
+		for id, data := range before {
+			var item Item
+			if err := json.Unmarshal(data, &item); err != nil {
+				restore()
+				return nil, errTreeChanged
+			}
+			cfg.Values[id] = item
+		}
+		if err := updater(cfg); err != nil {
+			restore()
+			return nil, err
+		}
+
+		touched := make(map[string]Item, len(ids))
+		for _, id := range ids {
+			item, exists := cfg.Values[id]
+			original := originals[id]
+			if !exists || item.Type != original.Type || item.Locked != original.Locked || !slices.Equal(item.Children, original.Children) {
+				restore()
+				return nil, errTreeChanged
+			}
+			touched[id] = item
+		}
+		locked := m.locks.of(cfg)
+		var issues []Issue
+		for _, id := range ids {
+			if folderID, isLocked := locked[id]; isLocked && !bytes.Equal(lockFingerprint(originals[id]), lockFingerprint(touched[id])) {
+				restore()
+				return nil, lockedError(cfg, before, id, folderID)
+			}
+			issues = append(issues, itemErrors(id, touched[id])...)
+		}
+		if len(issues) > 0 {
+			restore()
+			return nil, apperrors.Wrap(apperrors.ValidationFailed, &ValidationError{Issues: issues}, "config validation failed")
+		}
+
+		now := time.Now()
+		changed := append([]string{}, stampItems(touched, before, now)...)
+		for _, id := range changed {
+			cfg.Values[id] = touched[id]
+		}
+		if m.audit != nil {
+			entries = auditEntries(before, touched, now)
+		}
+		return changed, nil
+	})
+	if errors.Is(err, errTreeChanged) {
+		return m.update(updater)
+	}
+	if err == nil {
+		m.record(entries)
+	}
+	return err
+}
+
+// record appends applied changes to the audit log. The change is applied either way, so a failing
+// audit log is only reported.
+func (m *Manager) record(entries []AuditEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	if err := m.audit.Append(entries); err != nil {
+		if ctx := m.Events().Context(); ctx != nil {
+			runtime.LogError(ctx, fmt.Sprintf("Failed to record item history: %v", err))
+		}
+	}
+}
+
+// History returns the recorded edits of an item, oldest first
+func (m *Manager) History(itemId string) ([]AuditEntry, error) {
+	if m.audit == nil {
+		return []AuditEntry{}, nil
+	}
+	return m.audit.History(itemId)
+}
+
+// RevertEdit sets the fields changed by a recorded edit back to their previous values.
+// It fails with a conflict when one of those fields was edited again since.
+func (m *Manager) RevertEdit(entryId string) error {
+	if m.audit == nil {
+		return apperrors.NotFoundf("history entry not found")
+	}
+	entry, err := m.audit.Entry(entryId)
+	if err != nil {
+		return err
+	}
+	return m.update(func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[entry.ItemID]
+		if !exists {
+			return apperrors.NotFoundf("item no longer exists")
+		}
+		reverted, err := revertChanges(item, entry)
+		if err != nil {
+			return err
+		}
+		cfg.Values[entry.ItemID] = reverted
+		return nil
+	})
+}
+
 // getRequestsFilePath returns the path to the requests config file
 func getRequestsFilePath() string {
 	return requestsFile
@@ -71,8 +270,10 @@ func (m *Manager) Get() interface{} {
 	return m.GetRequestsConfig()
 }
 
-// GetRequestsConfig returns the requests config (type-safe version)
+// GetRequestsConfig returns the requests config (type-safe version). Root folders the storage has
+// not read yet are read first; Snapshot returns the config as it is.
 func (m *Manager) GetRequestsConfig() *RequestsConfig {
+	_ = m.loadFolders(nil) // Failures are emitted with EventCollectionSkipped
 	return m.BaseManager.Get()
 }
 
@@ -84,58 +285,69 @@ func (m *Manager) PatchValues(values map[string]Item) error {
 		runtime.LogInfo(ctx, fmt.Sprintf("PatchValues called with %d items", len(values)))
 	}
 
-	return m.UpdateConfig(func(cfg *RequestsConfig) error {
+	ids := make([]string, 0, len(values))
+	for id := range values {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return m.updateItems(ids, func(cfg *RequestsConfig) error {
 		if cfg.Values == nil {
 			cfg.Values = make(map[string]Item)
 		}
 
-		// Merge values into config
+		// Merge values into config, keeping the fields of newer versions the UI does not send back
 		for k, v := range values {
-			cfg.Values[k] = v
+			cfg.Values[k] = keepExtra(v, cfg.Values[k])
 		}
 
 		if ctx != nil {
 			runtime.LogInfo(ctx, fmt.Sprintf("Config updated in memory, values count: %d", len(cfg.Values)))
 		}
 
-		// Emit event with proper format
-		eventData := map[string]interface{}{
-			"version":   cfg.Version,
-			"values":    cfg.Values,
-			"rootOrder": cfg.RootOrder,
-		}
-		if ctx != nil {
-			runtime.LogInfo(ctx, fmt.Sprintf("About to emit requests:updated event with %d items", len(cfg.Values)))
-		}
-		m.Events().Updated("requests:updated", eventData)
-		if ctx != nil {
-			runtime.LogInfo(ctx, "Event requests:updated emitted")
-		}
+		return nil
+	})
+}
 
+// ReplaceRequest overwrites an existing request with an edited copy of it, e.g. a recovered draft.
+// The request keeps its place in the tree and the fields of newer versions.
+func (m *Manager) ReplaceRequest(requestId string, item Item) error {
+	return m.updateItems([]string{requestId}, func(cfg *RequestsConfig) error {
+		current, exists := cfg.Values[requestId]
+		if !exists || current.Type != ItemTypeRequest {
+			return apperrors.NotFoundf("request not found")
+		}
+		item.Type, item.Children = ItemTypeRequest, nil
+		cfg.Values[requestId] = keepExtra(item, current)
 		return nil
 	})
 }
 
 // AddRequest adds a new request to a parent folder
 func (m *Manager) AddRequest(parentId string, name string, method string, path string) (string, error) {
+	return m.AddRequestItem(parentId, Item{
+		Type:   ItemTypeRequest,
+		Name:   name,
+		Method: method,
+		Path:   path,
+	})
+}
+
+// AddRequestItem adds a fully populated request item (headers, body) to a parent folder
+func (m *Manager) AddRequestItem(parentId string, newItem Item) (string, error) {
 	var newId string
 
-	err := m.UpdateConfig(func(cfg *RequestsConfig) error {
+	err := m.update(func(cfg *RequestsConfig) error {
 		// Generate UUID
 		newId = uuid.New().String()
 
-		// Create new request item
-		newItem := Item{
-			Type:   ItemTypeRequest,
-			Name:   name,
-			Method: method,
-			Path:   path,
-		}
+		// Requests never carry children
+		newItem.Type = ItemTypeRequest
+		newItem.Children = nil
 
 		// Get parent folder
 		parent, exists := cfg.Values[parentId]
 		if !exists || parent.Type != ItemTypeFolder {
-			return fmt.Errorf("parent folder not found")
+			return apperrors.NotFoundf("parent folder not found")
 		}
 
 		// Add new item to config
@@ -152,14 +364,6 @@ func (m *Manager) AddRequest(parentId string, name string, method string, path s
 		parent.Children = append(parent.Children, newId)
 		cfg.Values[parentId] = parent
 
-		// Emit updated event
-		eventData := map[string]interface{}{
-			"version":   cfg.Version,
-			"values":    cfg.Values,
-			"rootOrder": cfg.RootOrder,
-		}
-		m.Events().Updated("requests:updated", eventData)
-
 		return nil
 	})
 
@@ -170,7 +374,7 @@ func (m *Manager) AddRequest(parentId string, name string, method string, path s
 func (m *Manager) AddFolder(parentId string, name string) (string, error) {
 	var newId string
 
-	err := m.UpdateConfig(func(cfg *RequestsConfig) error {
+	err := m.update(func(cfg *RequestsConfig) error {
 		// Generate UUID
 		newId = uuid.New().String()
 
@@ -184,7 +388,7 @@ func (m *Manager) AddFolder(parentId string, name string) (string, error) {
 		// Get parent folder
 		parent, exists := cfg.Values[parentId]
 		if !exists || parent.Type != ItemTypeFolder {
-			return fmt.Errorf("parent folder not found")
+			return apperrors.NotFoundf("parent folder not found")
 		}
 
 		// Add new item to config
@@ -201,14 +405,6 @@ func (m *Manager) AddFolder(parentId string, name string) (string, error) {
 		parent.Children = append(parent.Children, newId)
 		cfg.Values[parentId] = parent
 
-		// Emit updated event
-		eventData := map[string]interface{}{
-			"version":   cfg.Version,
-			"values":    cfg.Values,
-			"rootOrder": cfg.RootOrder,
-		}
-		m.Events().Updated("requests:updated", eventData)
-
 		return nil
 	})
 
@@ -219,7 +415,7 @@ func (m *Manager) AddFolder(parentId string, name string) (string, error) {
 func (m *Manager) AddRootFolder(name string) (string, error) {
 	var newId string
 
-	err := m.UpdateConfig(func(cfg *RequestsConfig) error {
+	err := m.update(func(cfg *RequestsConfig) error {
 		// Generate UUID
 		newId = uuid.New().String()
 
@@ -243,14 +439,6 @@ func (m *Manager) AddRootFolder(name string) (string, error) {
 		// Add new folder ID to the beginning of RootOrder
 		cfg.RootOrder = append([]string{newId}, cfg.RootOrder...)
 
-		// Emit updated event
-		eventData := map[string]interface{}{
-			"version":   cfg.Version,
-			"values":    cfg.Values,
-			"rootOrder": cfg.RootOrder,
-		}
-		m.Events().Updated("requests:updated", eventData)
-
 		return nil
 	})
 
@@ -259,61 +447,273 @@ func (m *Manager) AddRootFolder(name string) (string, error) {
 
 // DeleteItem deletes an item from the requests configuration
 func (m *Manager) DeleteItem(itemId string) error {
-	return m.UpdateConfig(func(cfg *RequestsConfig) error {
-		// Get item to delete
+	return m.update(func(cfg *RequestsConfig) error {
+		if _, exists := cfg.Values[itemId]; !exists {
+			return apperrors.NotFoundf("item not found")
+		}
+
+		// Remove the item from its parent and the root order, then delete it with all descendants
+		removeItem(cfg, itemId)
+
+		return nil
+	})
+}
+
+// SetBaseURL sets the base URL a folder (and its descendants) or a single request resolves against.
+// An empty baseURL removes the override.
+func (m *Manager) SetBaseURL(itemId string, baseURL string) error {
+	return m.updateItems([]string{itemId}, func(cfg *RequestsConfig) error {
 		item, exists := cfg.Values[itemId]
 		if !exists {
-			return fmt.Errorf("item not found")
-		}
-
-		// Remove from parent's children
-		for parentId, parent := range cfg.Values {
-			if parent.Type == ItemTypeFolder && parent.Children != nil {
-				// Filter out the deleted item from children
-				newChildren := []string{}
-				for _, childId := range parent.Children {
-					if childId != itemId {
-						newChildren = append(newChildren, childId)
-					}
-				}
-				// Only update if children changed
-				if len(newChildren) != len(parent.Children) {
-					parent.Children = newChildren
-					cfg.Values[parentId] = parent
-				}
-			}
+			return apperrors.NotFoundf("item not found")
 		}
+		item.BaseURL = baseURL
+		cfg.Values[itemId] = item
 
-		// If it's a folder, also delete all children recursively
-		if item.Type == ItemTypeFolder && item.Children != nil {
-			for _, childId := range item.Children {
-				// Recursively delete children (but don't call DeleteItem to avoid nested UpdateConfig)
-				delete(cfg.Values, childId)
-			}
+		return nil
+	})
+}
+
+// SetQueryParams replaces the query parameters of a request
+func (m *Manager) SetQueryParams(requestId string, params []Param) error {
+	return m.updateItems([]string{requestId}, func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[requestId]
+		if !exists || item.Type != ItemTypeRequest {
+			return apperrors.NotFoundf("request not found")
 		}
+		item.QueryParams = params
+		cfg.Values[requestId] = item
 
-		// Delete the item itself
-		delete(cfg.Values, itemId)
+		return nil
+	})
+}
 
-		// Remove from RootOrder if it's a root-level folder
-		if cfg.RootOrder != nil {
-			newRootOrder := []string{}
-			for _, id := range cfg.RootOrder {
-				if id != itemId {
-					newRootOrder = append(newRootOrder, id)
-				}
-			}
-			cfg.RootOrder = newRootOrder
+// SetURL replaces the base URL override, path and query parameters of a request at once
+func (m *Manager) SetURL(requestId string, baseURL string, path string, params []Param) error {
+	return m.updateItems([]string{requestId}, func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[requestId]
+		if !exists || item.Type != ItemTypeRequest {
+			return apperrors.NotFoundf("request not found")
 		}
+		item.BaseURL, item.Path, item.QueryParams = baseURL, path, params
+		cfg.Values[requestId] = item
 
-		// Emit updated event
-		eventData := map[string]interface{}{
-			"version":   cfg.Version,
-			"values":    cfg.Values,
-			"rootOrder": cfg.RootOrder,
+		return nil
+	})
+}
+
+// SetAuth sets the auth of a folder or request; nil makes the item inherit from its parent folder
+func (m *Manager) SetAuth(itemId string, auth *Auth) error {
+	return m.updateItems([]string{itemId}, func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[itemId]
+		if !exists {
+			return apperrors.NotFoundf("item not found")
+		}
+		if auth != nil && auth.Type == AuthTypeInherit {
+			auth = nil
+		}
+		item.Auth = auth
+		cfg.Values[itemId] = item
+
+		return nil
+	})
+}
+
+// SetBudget sets the size/time budget of a folder or request; nil removes it
+func (m *Manager) SetBudget(itemId string, budget *Budget) error {
+	return m.updateItems([]string{itemId}, func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[itemId]
+		if !exists {
+			return apperrors.NotFoundf("item not found")
+		}
+		if budget != nil && *budget == (Budget{}) {
+			budget = nil
+		}
+		item.Budget = budget
+		cfg.Values[itemId] = item
+
+		return nil
+	})
+}
+
+// SetProtobuf sets the protobuf messages of a request's body and response; nil sends the body as is
+func (m *Manager) SetProtobuf(requestId string, body *ProtobufBody) error {
+	return m.updateItems([]string{requestId}, func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[requestId]
+		if !exists || item.Type != ItemTypeRequest {
+			return apperrors.NotFoundf("request not found")
+		}
+		if body != nil && *body == (ProtobufBody{}) {
+			body = nil
+		}
+		item.Protobuf = body
+		cfg.Values[requestId] = item
+
+		return nil
+	})
+}
+
+// SetSnapshot sets the response snapshot of a request; nil removes it
+func (m *Manager) SetSnapshot(requestId string, snapshot *Snapshot) error {
+	return m.updateItems([]string{requestId}, func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[requestId]
+		if !exists || item.Type != ItemTypeRequest {
+			return apperrors.NotFoundf("request not found")
+		}
+		item.Snapshot = snapshot
+		cfg.Values[requestId] = item
+
+		return nil
+	})
+}
+
+// SetExamples replaces the named request and response examples of a request; nil removes them
+func (m *Manager) SetExamples(requestId string, examples *Examples) error {
+	return m.updateItems([]string{requestId}, func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[requestId]
+		if !exists || item.Type != ItemTypeRequest {
+			return apperrors.NotFoundf("request not found")
+		}
+		if examples != nil && len(examples.Requests) == 0 && len(examples.Responses) == 0 {
+			examples = nil
+		}
+		item.Examples = examples
+		cfg.Values[requestId] = item
+
+		return nil
+	})
+}
+
+// SetSpec links a folder to an OpenAPI document (file path or URL); empty unlinks it
+func (m *Manager) SetSpec(folderId string, location string) error {
+	return m.updateItems([]string{folderId}, func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[folderId]
+		if !exists || item.Type != ItemTypeFolder {
+			return apperrors.NotFoundf("folder not found")
+		}
+		item.Spec = strings.TrimSpace(location)
+		cfg.Values[folderId] = item
+
+		return nil
+	})
+}
+
+// SetVariables replaces the folder-local variables of a folder
+func (m *Manager) SetVariables(folderId string, vars []Param) error {
+	return m.updateItems([]string{folderId}, func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[folderId]
+		if !exists || item.Type != ItemTypeFolder {
+			return apperrors.NotFoundf("folder not found")
+		}
+		item.Variables = vars
+		cfg.Values[folderId] = item
+
+		return nil
+	})
+}
+
+// SetResponseSchema sets the JSON Schema a request's response is validated against (empty disables it)
+func (m *Manager) SetResponseSchema(requestId string, schema string) error {
+	return m.updateItems([]string{requestId}, func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[requestId]
+		if !exists || item.Type != ItemTypeRequest {
+			return apperrors.NotFoundf("request not found")
+		}
+		item.ResponseSchema = schema
+		cfg.Values[requestId] = item
+
+		return nil
+	})
+}
+
+// SetCaptures replaces the capture rules of a request
+func (m *Manager) SetCaptures(requestId string, captures []CaptureRule) error {
+	return m.updateItems([]string{requestId}, func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[requestId]
+		if !exists || item.Type != ItemTypeRequest {
+			return apperrors.NotFoundf("request not found")
+		}
+		item.Captures = captures
+		cfg.Values[requestId] = item
+
+		return nil
+	})
+}
+
+// SetAssertions replaces the assertions of a request
+func (m *Manager) SetAssertions(requestId string, assertions []Assertion) error {
+	return m.updateItems([]string{requestId}, func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[requestId]
+		if !exists || item.Type != ItemTypeRequest {
+			return apperrors.NotFoundf("request not found")
+		}
+		item.Assertions = assertions
+		cfg.Values[requestId] = item
+
+		return nil
+	})
+}
+
+// SetFormFields replaces the form fields of a request; setting any replaces its raw body
+func (m *Manager) SetFormFields(requestId string, fields []FormField) error {
+	return m.updateItems([]string{requestId}, func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[requestId]
+		if !exists || item.Type != ItemTypeRequest {
+			return apperrors.NotFoundf("request not found")
+		}
+		item.FormFields = fields
+		if len(fields) > 0 {
+			item.Body = ""
+		}
+		cfg.Values[requestId] = item
+
+		return nil
+	})
+}
+
+// SetDescription sets the markdown description of a request or folder
+func (m *Manager) SetDescription(itemId string, description string) error {
+	return m.updateItems([]string{itemId}, func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[itemId]
+		if !exists {
+			return apperrors.NotFoundf("item not found")
+		}
+		item.Description = description
+		cfg.Values[itemId] = item
+
+		return nil
+	})
+}
+
+// SetTags replaces the tags of a request or folder
+func (m *Manager) SetTags(itemId string, tags []string) error {
+	return m.updateItems([]string{itemId}, func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[itemId]
+		if !exists {
+			return apperrors.NotFoundf("item not found")
+		}
+		item.Tags = tags
+		cfg.Values[itemId] = item
+
+		return nil
+	})
+}
+
+// ToggleFavorite flips the favorite flag of an item and returns the new value
+func (m *Manager) ToggleFavorite(itemId string) (bool, error) {
+	var favorite bool
+
+	err := m.updateItems([]string{itemId}, func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[itemId]
+		if !exists {
+			return apperrors.NotFoundf("item not found")
 		}
-		m.Events().Updated("requests:updated", eventData)
+		item.Favorite = !item.Favorite
+		favorite = item.Favorite
+		cfg.Values[itemId] = item
 
 		return nil
 	})
+
+	return favorite, err
 }
@@ -0,0 +1,219 @@
+package requests
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"paperbox/internal/apperrors"
+)
+
+// MergeStrategy decides what an import does with a request that already exists in the target
+// folder, i.e. one with the same method and path
+type MergeStrategy string
+
+const (
+	// MergeKeepBoth adds the imported request next to the existing one with a numbered name
+	MergeKeepBoth MergeStrategy = "keepBoth"
+	// MergeSkip keeps the existing request and drops the imported one
+	MergeSkip MergeStrategy = "skip"
+	// MergeReplace overwrites the existing request with the imported one, keeping its ID
+	MergeReplace MergeStrategy = "replace"
+)
+
+// ImportOptions controls how imported nodes are merged into the tree. An empty strategy keeps both.
+type ImportOptions struct {
+	Strategy MergeStrategy `json:"strategy,omitempty"`
+}
+
+// ImportSummary lists the items an import created, updated (replaced) and skipped. IDs are the
+// top-level items the import landed in: new ones and existing folders it was merged into.
+type ImportSummary struct {
+	IDs     []string `json:"ids"`
+	Created []string `json:"created"`
+	Updated []string `json:"updated"`
+	Skipped []string `json:"skipped"`
+}
+
+// Import inserts nodes under a parent folder like AddTree, but merges them with what is already
+// there: an imported folder with the name of an existing one is merged into it, and requests with
+// the method and path of an existing request in the same folder are handled by the strategy.
+func (m *Manager) Import(parentId string, nodes []Node, opts ImportOptions) (*ImportSummary, error) {
+	return m.ImportContext(context.Background(), parentId, nodes, opts, nil)
+}
+
+// ProgressFunc is told, after each imported item, how many of the total are done
+type ProgressFunc func(done, total int, current string)
+
+// ImportContext is Import reporting its progress to progress (which may be nil). Cancelling ctx
+// stops the import with nothing applied.
+func (m *Manager) ImportContext(ctx context.Context, parentId string, nodes []Node, opts ImportOptions, progress ProgressFunc) (*ImportSummary, error) {
+	strategy := opts.Strategy
+	switch strategy {
+	case "":
+		strategy = MergeKeepBoth
+	case MergeKeepBoth, MergeSkip, MergeReplace:
+	default:
+		return nil, apperrors.Invalidf("unknown merge strategy '%s'", strategy)
+	}
+
+	var summary *ImportSummary
+	err := m.update(func(cfg *RequestsConfig) error {
+		summary = &ImportSummary{IDs: []string{}, Created: []string{}, Updated: []string{}, Skipped: []string{}}
+		if cfg.Values == nil {
+			cfg.Values = make(map[string]Item)
+		}
+
+		depth := 0
+		var siblings []string
+		if parentId == "" {
+			siblings = rootIDs(cfg)
+		} else {
+			parent, exists := cfg.Values[parentId]
+			if !exists || parent.Type != ItemTypeFolder {
+				return apperrors.NotFoundf("parent folder not found")
+			}
+			depth = folderDepth(cfg, parentId) + 1
+			siblings = parent.Children
+		}
+		nodes := flattenNodes(nodes, depth, MaxFolderDepth())
+
+		tracker := &importTracker{ctx: ctx, report: progress, total: countNodes(nodes)}
+		added, top, err := mergeNodes(cfg, siblings, nodes, strategy, summary, tracker)
+		if err != nil {
+			return err
+		}
+		summary.IDs = top
+		if parentId == "" {
+			for _, id := range added {
+				if cfg.Values[id].Type != ItemTypeFolder {
+					return fmt.Errorf("root level item '%s' must be a folder", cfg.Values[id].Name)
+				}
+			}
+			cfg.RootOrder = append(cfg.RootOrder, added...)
+		} else {
+			parent := cfg.Values[parentId]
+			parent.Children = append(parent.Children, added...)
+			cfg.Values[parentId] = parent
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// importTracker counts the items an import went through and stops it once ctx is cancelled
+type importTracker struct {
+	ctx    context.Context
+	report ProgressFunc
+	done   int
+	total  int
+}
+
+// step counts n items, the last of them named current
+func (t *importTracker) step(n int, current string) error {
+	t.done += n
+	if t.report != nil {
+		t.report(t.done, t.total, current)
+	}
+	return t.ctx.Err()
+}
+
+// countNodes returns the number of nodes including their descendants
+func countNodes(nodes []Node) int {
+	n := len(nodes)
+	for _, node := range nodes {
+		n += countNodes(node.Children)
+	}
+	return n
+}
+
+// mergeNodes merges nodes into a folder with the given children. It returns the IDs of the new
+// items to append to the folder and the IDs of every item the nodes ended up in.
+func mergeNodes(cfg *RequestsConfig, siblings []string, nodes []Node, strategy MergeStrategy, summary *ImportSummary, tracker *importTracker) ([]string, []string, error) {
+	// Only items that existed before the import count as duplicates
+	existing := append([]string{}, siblings...)
+	names := make(map[string]bool, len(siblings))
+	for _, id := range siblings {
+		names[cfg.Values[id].Name] = true
+	}
+
+	var added, landed []string
+	for _, node := range nodes {
+		match := findDuplicate(cfg, existing, node.Item)
+		switch {
+		case match != "" && node.Item.Type == ItemTypeFolder:
+			if err := tracker.step(1, node.Item.Name); err != nil {
+				return nil, nil, err
+			}
+			folder := cfg.Values[match]
+			children, _, err := mergeNodes(cfg, folder.Children, node.Children, strategy, summary, tracker)
+			if err != nil {
+				return nil, nil, err
+			}
+			folder.Children = append(folder.Children, children...)
+			cfg.Values[match] = folder
+			landed = append(landed, match)
+			continue
+		case match != "" && strategy == MergeSkip:
+			summary.Skipped = append(summary.Skipped, match)
+			if err := tracker.step(countNodes([]Node{node}), node.Item.Name); err != nil {
+				return nil, nil, err
+			}
+			continue
+		case match != "" && strategy == MergeReplace:
+			previous := cfg.Values[match]
+			item := node.Item
+			item.Children = nil
+			item.CreatedAt, item.LastUsedAt = previous.CreatedAt, previous.LastUsedAt
+			cfg.Values[match] = item
+			summary.Updated = append(summary.Updated, match)
+			landed = append(landed, match)
+			if err := tracker.step(1, node.Item.Name); err != nil {
+				return nil, nil, err
+			}
+			continue
+		case match != "":
+			node.Item.Name = numberedName(names, node.Item.Name)
+		}
+
+		id := insertNode(cfg, node, &summary.Created)
+		names[node.Item.Name] = true
+		added = append(added, id)
+		landed = append(landed, id)
+		if err := tracker.step(countNodes([]Node{node}), node.Item.Name); err != nil {
+			return nil, nil, err
+		}
+	}
+	return added, landed, nil
+}
+
+// findDuplicate returns the item among ids that an imported item duplicates: a folder of the same
+// name, or a request with the same method and path
+func findDuplicate(cfg *RequestsConfig, ids []string, item Item) string {
+	for _, id := range ids {
+		other, exists := cfg.Values[id]
+		if !exists || other.Type != item.Type {
+			continue
+		}
+		if item.Type == ItemTypeFolder && other.Name == item.Name {
+			return id
+		}
+		if item.Type == ItemTypeRequest && strings.EqualFold(other.Method, item.Method) && other.Path == item.Path {
+			return id
+		}
+	}
+	return ""
+}
+
+// numberedName returns "name (2)", "name (3)", ... whichever is not taken yet
+func numberedName(taken map[string]bool, name string) string {
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)", name, n)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package requests
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// methodTokenPattern matches custom methods: uppercase HTTP tokens such as PROPFIND or M-SEARCH
+var methodTokenPattern = regexp.MustCompile("^[A-Z0-9!#$%&'*+.^_`|~-]*[A-Z][A-Z0-9!#$%&'*+.^_`|~-]*$")
+
+// customMethods holds the methods accepted besides HTTPMethods, shared by validation and imports
+var customMethods struct {
+	sync.RWMutex
+	allowAny bool
+	allowed  []string
+}
+
+// SetCustomMethods changes the methods accepted besides HTTPMethods: those listed and, with allowAny,
+// every uppercase method token
+func SetCustomMethods(allowAny bool, methods []string) error {
+	if err := CheckCustomMethods(methods); err != nil {
+		return err
+	}
+
+	customMethods.Lock()
+	defer customMethods.Unlock()
+	customMethods.allowAny = allowAny
+	customMethods.allowed = slices.Clone(methods)
+	return nil
+}
+
+// CheckCustomMethods fails on the first method that is not an uppercase HTTP token
+func CheckCustomMethods(methods []string) error {
+	for _, method := range methods {
+		if !methodTokenPattern.MatchString(method) {
+			return fmt.Errorf("custom method %q must be an uppercase HTTP token", method)
+		}
+	}
+	return nil
+}
+
+// IsAllowedMethod reports whether a request may use a method: one of HTTPMethods or a listed custom
+// method (both matched case-insensitively), or any uppercase token when custom methods are allowed
+func IsAllowedMethod(method string) bool {
+	customMethods.RLock()
+	defer customMethods.RUnlock()
+	return methodAllowed(method, customMethods.allowAny, customMethods.allowed)
+}
+
+// MethodIssues reports requests whose method would be rejected with the given custom methods
+func MethodIssues(config *RequestsConfig, allowAny bool, methods []string) []Issue {
+	var issues []Issue
+	for id, item := range config.Values {
+		if item.Type == ItemTypeRequest && item.Method != "" && !methodAllowed(item.Method, allowAny, methods) {
+			issues = append(issues, Issue{
+				ItemID:   id,
+				Field:    "method",
+				Message:  fmt.Sprintf("request uses the custom method %s", item.Method),
+				Severity: SeverityError,
+			})
+		}
+	}
+	sort.Slice(issues, func(a, b int) bool { return issues[a].ItemID < issues[b].ItemID })
+	return issues
+}
+
+// methodAllowed implements IsAllowedMethod for a set of custom methods
+func methodAllowed(method string, allowAny bool, allowed []string) bool {
+	upper := strings.ToUpper(method)
+	if slices.Contains(HTTPMethods, upper) || slices.Contains(allowed, upper) {
+		return true
+	}
+	return allowAny && methodTokenPattern.MatchString(method)
+}
@@ -0,0 +1,128 @@
+package requests
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"paperbox/internal/apperrors"
+
+	"github.com/google/uuid"
+)
+
+// MaxExcerptBytes caps the response body kept in a note excerpt
+const MaxExcerptBytes = 16 << 10
+
+// Note is a timestamped free-text entry on a request, optionally pinning an excerpt of a response
+type Note struct {
+	ID        string    `json:"id" validate:"required"`
+	Text      string    `json:"text,omitempty" validate:"max=20000"`
+	Excerpt   *Excerpt  `json:"excerpt,omitempty" validate:"omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Excerpt is the part of a response a note pins, with the status it came with
+type Excerpt struct {
+	Status     int       `json:"status,omitempty" validate:"omitempty,min=100,max=599"`
+	Body       string    `json:"body"`
+	Truncated  bool      `json:"truncated,omitempty"`
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+// NewExcerpt keeps up to MaxExcerptBytes of a response body, cut at a line break where possible
+func NewExcerpt(status int, body string) *Excerpt {
+	excerpt := &Excerpt{Status: status, Body: body, RecordedAt: time.Now()}
+	if len(body) > MaxExcerptBytes {
+		cut := body[:MaxExcerptBytes]
+		if i := strings.LastIndexByte(cut, '\n'); i > MaxExcerptBytes/2 {
+			cut = cut[:i]
+		}
+		excerpt.Body = strings.ToValidUTF8(cut, "")
+		excerpt.Truncated = true
+	}
+	return excerpt
+}
+
+// AddNote appends a note to a request and returns it
+func (m *Manager) AddNote(requestId string, text string, excerpt *Excerpt) (*Note, error) {
+	now := time.Now().UTC()
+	note := &Note{ID: uuid.New().String(), Text: strings.TrimSpace(text), Excerpt: excerpt, CreatedAt: now, UpdatedAt: now}
+	if note.Text == "" && note.Excerpt == nil {
+		return nil, apperrors.Invalidf("note needs text or a response excerpt")
+	}
+
+	err := m.update(func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[requestId]
+		if !exists || item.Type != ItemTypeRequest {
+			return apperrors.NotFoundf("request not found")
+		}
+		item.Notes = append(append([]Note{}, item.Notes...), *note)
+		cfg.Values[requestId] = item
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// UpdateNote replaces the text of a note; the excerpt stays as recorded
+func (m *Manager) UpdateNote(requestId string, noteId string, text string) error {
+	return m.updateNotes(requestId, noteId, func(notes []Note, i int) ([]Note, error) {
+		text = strings.TrimSpace(text)
+		if text == "" && notes[i].Excerpt == nil {
+			return nil, apperrors.Invalidf("note needs text or a response excerpt")
+		}
+		notes[i].Text = text
+		notes[i].UpdatedAt = time.Now().UTC()
+		return notes, nil
+	})
+}
+
+// DeleteNote removes a note from a request
+func (m *Manager) DeleteNote(requestId string, noteId string) error {
+	return m.updateNotes(requestId, noteId, func(notes []Note, i int) ([]Note, error) {
+		return append(notes[:i], notes[i+1:]...), nil
+	})
+}
+
+// updateNotes edits a copy of a request's notes; edit gets the index of the note with noteId
+func (m *Manager) updateNotes(requestId string, noteId string, edit func(notes []Note, i int) ([]Note, error)) error {
+	return m.update(func(cfg *RequestsConfig) error {
+		item, exists := cfg.Values[requestId]
+		if !exists || item.Type != ItemTypeRequest {
+			return apperrors.NotFoundf("request not found")
+		}
+		for i, note := range item.Notes {
+			if note.ID != noteId {
+				continue
+			}
+			notes, err := edit(append([]Note{}, item.Notes...), i)
+			if err != nil {
+				return err
+			}
+			item.Notes = notes
+			cfg.Values[requestId] = item
+			return nil
+		}
+		return apperrors.NotFoundf("note not found")
+	})
+}
+
+// noteIssues reports duplicate note IDs and notes without content
+func noteIssues(notes []Note) []string {
+	var issues []string
+	seen := make(map[string]bool, len(notes))
+	for _, note := range notes {
+		if seen[note.ID] {
+			issues = append(issues, fmt.Sprintf("note '%s' is defined more than once", note.ID))
+		}
+		seen[note.ID] = true
+		if strings.TrimSpace(note.Text) == "" && note.Excerpt == nil {
+			issues = append(issues, fmt.Sprintf("note '%s' has neither text nor an excerpt", note.ID))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,159 @@
+package requests
+
+import (
+	"sort"
+
+	"paperbox/internal/apperrors"
+)
+
+// Orphan is an item that cannot be reached from any root folder, e.g. a request left at the root
+// by an external edit or an item whose only parents form a cycle. ParentID is the orphan it hangs
+// under, empty for the topmost orphans, which are the ones AdoptOrphans moves.
+type Orphan struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Type     ItemType `json:"type"`
+	ParentID string   `json:"parentId,omitempty"`
+}
+
+// FindOrphans returns the items of cfg that no root folder leads to, ordered by ID
+func FindOrphans(cfg *RequestsConfig) []Orphan {
+	reachable := make(map[string]bool, len(cfg.Values))
+	var reach func(id string)
+	reach = func(id string) {
+		if reachable[id] {
+			return
+		}
+		reachable[id] = true
+		for _, childID := range cfg.Values[id].Children {
+			if _, exists := cfg.Values[childID]; exists {
+				reach(childID)
+			}
+		}
+	}
+	for _, id := range rootIDs(cfg) {
+		reach(id)
+	}
+
+	parents := make(map[string]string)
+	var ids []string
+	for id, item := range cfg.Values {
+		if reachable[id] {
+			continue
+		}
+		ids = append(ids, id)
+		for _, childID := range item.Children {
+			if _, seen := parents[childID]; !seen && childID != id {
+				parents[childID] = id
+			}
+		}
+	}
+	sort.Strings(ids)
+
+	// Where the orphans form a cycle, none is on top; the one with the lowest ID is taken instead
+	top := make(map[string]bool)
+	covered := make(map[string]bool)
+	var cover func(id string)
+	cover = func(id string) {
+		if covered[id] {
+			return
+		}
+		covered[id] = true
+		for _, childID := range cfg.Values[id].Children {
+			if _, exists := cfg.Values[childID]; exists && !top[childID] {
+				cover(childID)
+			}
+		}
+	}
+	for _, id := range ids {
+		if _, hasParent := parents[id]; !hasParent {
+			top[id] = true
+			cover(id)
+		}
+	}
+	for _, id := range ids {
+		if !covered[id] {
+			top[id] = true
+			cover(id)
+		}
+	}
+
+	orphans := make([]Orphan, 0, len(ids))
+	for _, id := range ids {
+		item := cfg.Values[id]
+		orphan := Orphan{ID: id, Name: item.Name, Type: item.Type}
+		if !top[id] {
+			orphan.ParentID = parents[id]
+		}
+		orphans = append(orphans, orphan)
+	}
+	return orphans
+}
+
+// FindOrphanItems returns the items no root folder leads to. They are kept in the file but not
+// shown in the tree until they are adopted or purged.
+func (m *Manager) FindOrphanItems() []Orphan {
+	return FindOrphans(m.GetRequestsConfig())
+}
+
+// AdoptOrphans moves the topmost orphans, with the orphans below them, into a folder of the tree
+// and returns their IDs
+func (m *Manager) AdoptOrphans(targetFolderId string) ([]string, error) {
+	var adopted []string
+	err := m.update(func(cfg *RequestsConfig) error {
+		orphans := FindOrphans(cfg)
+		for _, orphan := range orphans {
+			if orphan.ID == targetFolderId {
+				return apperrors.Invalidf("the target folder is an orphan itself")
+			}
+		}
+		if target, exists := cfg.Values[targetFolderId]; !exists || target.Type != ItemTypeFolder {
+			return apperrors.NotFoundf("target folder not found")
+		}
+
+		adopted = nil
+		for _, orphan := range orphans {
+			if orphan.ParentID != "" {
+				continue
+			}
+			// A cycle is broken where it is adopted
+			detach(cfg, orphan.ID)
+			if err := attach(cfg, orphan.ID, targetFolderId, nil); err != nil {
+				return err
+			}
+			adopted = append(adopted, orphan.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return adopted, nil
+}
+
+// PurgeOrphans deletes every orphan and returns how many items were deleted
+func (m *Manager) PurgeOrphans() (int, error) {
+	var purged int
+	err := m.update(func(cfg *RequestsConfig) error {
+		orphans := FindOrphans(cfg)
+		for _, orphan := range orphans {
+			delete(cfg.Values, orphan.ID)
+		}
+		// Only orphans refer to orphans, but a request may have been listed among the roots
+		rootOrder := cfg.RootOrder[:0:0]
+		for _, id := range cfg.RootOrder {
+			if _, exists := cfg.Values[id]; exists {
+				rootOrder = append(rootOrder, id)
+			}
+		}
+		if len(rootOrder) != len(cfg.RootOrder) {
+			cfg.RootOrder = rootOrder
+		}
+		purged = len(orphans)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
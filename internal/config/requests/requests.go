@@ -5,6 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/storage"
 
 	"github.com/adrg/xdg"
 	"github.com/go-playground/validator/v10"
@@ -12,7 +16,7 @@ import (
 
 const (
 	// CurrentVersion is the current version of the requests config format
-	CurrentVersion = 2
+	CurrentVersion = 6
 	// RequestsFileName is the name of the requests config file
 	RequestsFileName = "requests.json"
 )
@@ -30,6 +34,9 @@ func init() {
 	if err := validate.RegisterValidation("http_method", validateHTTPMethod); err != nil {
 		panic(fmt.Sprintf("failed to register http_method validator: %v", err))
 	}
+	if err := validate.RegisterValidation("auth_type", validateAuthType); err != nil {
+		panic(fmt.Sprintf("failed to register auth_type validator: %v", err))
+	}
 }
 
 // ItemType represents the type of an item
@@ -40,13 +47,179 @@ const (
 	ItemTypeFolder  ItemType = "folder"
 )
 
-// Item represents a request or folder item
+// Header represents a single request header. Secret headers are masked wherever a sent request surfaces.
+type Header struct {
+	Key      string `json:"key" validate:"required"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled,omitempty"`
+	Secret   bool   `json:"secret,omitempty"`
+}
+
+// Param represents a query parameter, a path variable (e.g. "id" for "/users/:id") or a folder
+// variable. Secret folder variables are masked like secret environment variables.
+type Param struct {
+	Key      string `json:"key" validate:"required"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled,omitempty"`
+	Secret   bool   `json:"secret,omitempty"`
+}
+
+// FormField is a field of a URL-encoded form body; disabled fields are left out when sending
+type FormField struct {
+	Key         string `json:"key" validate:"required"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+}
+
+// AuthType identifies how a request authenticates
+type AuthType string
+
+const (
+	// AuthTypeInherit (empty) uses the auth of the nearest ancestor folder
+	AuthTypeInherit AuthType = ""
+	AuthTypeNone    AuthType = "none"
+	AuthTypeBasic   AuthType = "basic"
+	AuthTypeBearer  AuthType = "bearer"
+	AuthTypeAPIKey  AuthType = "apiKey"
+	// AuthTypeNTLM and AuthTypeNegotiate use Windows authentication with Username, Password and Domain
+	AuthTypeNTLM      AuthType = "ntlm"
+	AuthTypeNegotiate AuthType = "negotiate"
+
+	// PluginAuthPrefix starts the type of auth schemes provided by plugins ("plugin:<plugin>.<scheme>")
+	PluginAuthPrefix = "plugin:"
+)
+
+// Auth describes the credentials injected into a request (folders pass theirs down to children)
+type Auth struct {
+	Type     AuthType `json:"type,omitempty" validate:"omitempty,auth_type"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	Domain   string   `json:"domain,omitempty"` // Windows domain for NTLM/Negotiate ("DOMAIN\\user" also works)
+	Token    string   `json:"token,omitempty"`
+	Key      string   `json:"key,omitempty"`                                        // API key header/parameter name
+	Value    string   `json:"value,omitempty"`                                      // API key value
+	In       string   `json:"in,omitempty" validate:"omitempty,oneof=header query"` // Where the API key goes
+	// Params holds the fields of a plugin auth scheme
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// Budget sets soft limits on a request's size and duration; exceeding one only produces a warning.
+// Zero means no limit. Folders pass their budgets down, the nearest limit of each kind winning.
+type Budget struct {
+	MaxRequestBytes  int64 `json:"maxRequestBytes,omitempty" validate:"min=0"`
+	MaxResponseBytes int64 `json:"maxResponseBytes,omitempty" validate:"min=0"`
+	MaxDurationMs    int64 `json:"maxDurationMs,omitempty" validate:"min=0"`
+}
+
+// ProtobufBody declares a request body and response as protobuf messages of registered .proto files.
+// The body is written as JSON and encoded before sending; ResponseMessage decodes the response.
+type ProtobufBody struct {
+	RequestMessage  string `json:"requestMessage,omitempty"`
+	ResponseMessage string `json:"responseMessage,omitempty"`
+}
+
+// Snapshot is a recorded response later executions of a request are compared against.
+// Headers holds all recorded response headers; only those named in CompareHeaders are compared.
+// IgnorePaths are JSONPath expressions left out of the body comparison (timestamps, IDs).
+type Snapshot struct {
+	Status         int               `json:"status" validate:"min=100,max=599"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Body           string            `json:"body"`
+	IgnorePaths    []string          `json:"ignorePaths,omitempty" validate:"omitempty,dive,required"`
+	CompareHeaders []string          `json:"compareHeaders,omitempty" validate:"omitempty,dive,required"`
+	RecordedAt     time.Time         `json:"recordedAt"`
+}
+
+// ExtractKind identifies the expression language used to pull a value out of a response
+type ExtractKind string
+
+const (
+	ExtractKindJSONPath ExtractKind = "jsonpath"
+	ExtractKindXPath    ExtractKind = "xpath"
+	ExtractKindHeader   ExtractKind = "header"
+	ExtractKindStatus   ExtractKind = "status" // Response status code; the expression is ignored
+)
+
+// CaptureRule stores a value extracted from the response into an environment variable
+type CaptureRule struct {
+	Variable   string      `json:"variable" validate:"required"`
+	Expression string      `json:"expression" validate:"required"`
+	Kind       ExtractKind `json:"kind" validate:"required,oneof=jsonpath xpath header"`
+	Disabled   bool        `json:"disabled,omitempty"`
+}
+
+// AssertOperator compares the value an assertion extracts from a response with its expected value
+type AssertOperator string
+
+const (
+	AssertEquals      AssertOperator = "equals"
+	AssertNotEquals   AssertOperator = "notEquals"
+	AssertContains    AssertOperator = "contains"
+	AssertMatches     AssertOperator = "matches" // Value is a regular expression
+	AssertExists      AssertOperator = "exists"  // Value is ignored
+	AssertNotExists   AssertOperator = "notExists"
+	AssertLessThan    AssertOperator = "lessThan" // Numeric comparison
+	AssertGreaterThan AssertOperator = "greaterThan"
+)
+
+// Assertion checks a value extracted from the response, e.g. the XPath //status equals "OK"
+type Assertion struct {
+	Kind       ExtractKind    `json:"kind" validate:"required,oneof=jsonpath xpath header status"`
+	Expression string         `json:"expression" validate:"required"`
+	Operator   AssertOperator `json:"operator" validate:"required,oneof=equals notEquals contains matches exists notExists lessThan greaterThan"`
+	Value      string         `json:"value,omitempty"`
+	Disabled   bool           `json:"disabled,omitempty"`
+}
+
+// Item represents a request or folder item.
+// Description is markdown documentation for the item.
+// ResponseSchema is a JSON Schema the response body is validated against after execution;
+// Captures are evaluated after execution and written into the active environment.
+// Assertions are checked against the response after execution and reported as test results.
+// Budget sets soft size/time limits for the item and, on folders, everything below it.
+// Snapshot is the recorded response executions of a request are checked against (see Snapshot).
+// Examples are named request variants selectable at send time and named responses (see Examples).
+// Notes are timestamped debugging notes on a request, optionally pinning a response excerpt.
+// FormFields make up a URL-encoded form body, in place of Body, encoded when the request is sent.
+// Protobuf sends the JSON body as a protobuf message and decodes the response (see ProtobufBody).
+// Spec links a folder to the OpenAPI document (file path or URL) its requests are checked against.
+// Variables are folder-local {{variables}} for everything below the folder; they win over environment ones.
+// Locked folders and everything below them can only be sent, favorited or unlocked (see ToggleLock).
+// CreatedAt/UpdatedAt are maintained by the Manager; LastUsedAt is when the request was last sent.
+// Extra keeps the fields this version does not know (see fields.go), so they survive a save.
 type Item struct {
-	Type     ItemType `json:"type" validate:"required,oneof=request folder"`
-	Name     string   `json:"name" validate:"required,min=1"`
-	Method   string   `json:"method,omitempty" validate:"omitempty,http_method"`
-	Path     string   `json:"path,omitempty" validate:"omitempty,min=1"`
-	Children []string `json:"children,omitempty" validate:"omitempty,dive,required"`
+	Type           ItemType      `json:"type" validate:"required,oneof=request folder"`
+	Name           string        `json:"name" validate:"required,min=1"`
+	Description    string        `json:"description,omitempty" validate:"omitempty,max=20000"`
+	Tags           []string      `json:"tags,omitempty"`
+	Favorite       bool          `json:"favorite,omitempty"`
+	Locked         bool          `json:"locked,omitempty"`
+	CreatedAt      time.Time     `json:"createdAt,omitzero"`
+	UpdatedAt      time.Time     `json:"updatedAt,omitzero"`
+	LastUsedAt     *time.Time    `json:"lastUsedAt,omitempty"`
+	Method         string        `json:"method,omitempty" validate:"omitempty,http_method"`
+	Path           string        `json:"path,omitempty" validate:"omitempty,min=1"`
+	BaseURL        string        `json:"baseURL,omitempty" validate:"omitempty,url"`
+	QueryParams    []Param       `json:"queryParams,omitempty" validate:"omitempty,dive"`
+	PathVars       []Param       `json:"pathVars,omitempty" validate:"omitempty,dive"`
+	Headers        []Header      `json:"headers,omitempty" validate:"omitempty,dive"`
+	Body           string        `json:"body,omitempty"`
+	FormFields     []FormField   `json:"formFields,omitempty" validate:"omitempty,dive"`
+	Auth           *Auth         `json:"auth,omitempty" validate:"omitempty"`
+	Protobuf       *ProtobufBody `json:"protobuf,omitempty" validate:"omitempty"`
+	ResponseSchema string        `json:"responseSchema,omitempty"`
+	Captures       []CaptureRule `json:"captures,omitempty" validate:"omitempty,dive"`
+	Assertions     []Assertion   `json:"assertions,omitempty" validate:"omitempty,dive"`
+	Budget         *Budget       `json:"budget,omitempty" validate:"omitempty"`
+	Snapshot       *Snapshot     `json:"snapshot,omitempty" validate:"omitempty"`
+	Examples       *Examples     `json:"examples,omitempty" validate:"omitempty"`
+	Notes          []Note        `json:"notes,omitempty" validate:"omitempty,dive"`
+	Spec           string        `json:"spec,omitempty"`
+	Variables      []Param       `json:"variables,omitempty" validate:"omitempty,dive"`
+	Children       []string      `json:"children,omitempty" validate:"omitempty,dive,required"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // RequestsConfig represents the requests configuration
@@ -83,29 +256,78 @@ func Load() (*RequestsConfig, error) {
 		return config, nil
 	}
 
-	// Read requests file
-	data, err := os.ReadFile(requestsFile)
+	// Read requests file, restoring the latest backup if it is damaged
+	data, err := storage.ReadVerified(requestsFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read requests file: %w", err)
 	}
 
+	config, migrated, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save migrated config
+	if migrated {
+		_ = Save(config) // Ignore errors, continue with migrated config
+	}
+
+	return config, nil
+}
+
+// Decode parses a requests config (e.g. from an imported file), runs it through the
+// migration chain and validates it without touching the config on disk. A config written by a
+// newer version of paperbox is refused.
+func Decode(data []byte) (*RequestsConfig, error) {
+	config, _, err := decode(data)
+	if err == nil && config.Version > CurrentVersion {
+		return nil, apperrors.Invalidf("requests config version %d was written by a newer version of paperbox (this one supports %d)", config.Version, CurrentVersion)
+	}
+	return config, err
+}
+
+// Check parses and migrates a requests config like Decode, but instead of failing at the first
+// invalid item it returns every issue (see ValidateAll). A config written by a newer version is
+// returned as it is, without issues.
+func Check(data []byte) (*RequestsConfig, []Issue, error) {
+	var config RequestsConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse requests file: %w", err)
+	}
+	if config.Version > CurrentVersion {
+		return &config, nil, nil
+	}
+	if err := migrateConfig(&config); err != nil {
+		return nil, nil, fmt.Errorf("failed to migrate requests config: %w", err)
+	}
+	return &config, ValidateAll(&config), nil
+}
+
+// decode parses, migrates and validates a requests config, reporting whether a migration ran
+func decode(data []byte) (*RequestsConfig, bool, error) {
 	// Parse config
 	var config RequestsConfig
 	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse requests file: %w", err)
+		return nil, false, fmt.Errorf("failed to parse requests file: %w", err)
+	}
+
+	// A config written by a newer version is neither migrated nor validated; the manager keeps it read-only
+	if config.Version > CurrentVersion {
+		return &config, false, nil
 	}
 
 	// Migrate config if needed
+	fromVersion := config.Version
 	if err := migrateConfig(&config); err != nil {
-		return nil, fmt.Errorf("failed to migrate requests config: %w", err)
+		return nil, false, fmt.Errorf("failed to migrate requests config: %w", err)
 	}
 
 	// Validate config
 	if err := Validate(&config); err != nil {
-		return nil, fmt.Errorf("requests config validation failed: %w", err)
+		return nil, false, fmt.Errorf("requests config validation failed: %w", err)
 	}
 
-	return &config, nil
+	return &config, config.Version != fromVersion, nil
 }
 
 // Save saves the requests configuration to file
@@ -129,8 +351,8 @@ func Save(config *RequestsConfig) error {
 		return fmt.Errorf("failed to marshal requests config: %w", err)
 	}
 
-	// Write config file
-	if err := os.WriteFile(requestsFile, data, 0644); err != nil {
+	// Write config file with its checksum
+	if err := storage.NewIntegrityWriter(storage.NewFileWriter()).WriteAtomic(requestsFile, data, 0644); err != nil {
 		return fmt.Errorf("failed to write requests file: %w", err)
 	}
 
@@ -153,8 +375,6 @@ func migrateConfig(config *RequestsConfig) error {
 			}
 		}
 		config.Version = CurrentVersion
-		// Save migrated config
-		_ = Save(config) // Ignore errors, continue with default config
 	}
 
 	return nil
@@ -192,6 +412,35 @@ func migrateFromVersion(config *RequestsConfig, fromVersion int) error {
 			}
 		}
 		return nil
+	case 2:
+		// Migration from version 2 to 3
+		// Adds optional markdown descriptions, no changes needed
+		return nil
+	case 3:
+		// Migration from version 3 to 4
+		// Adds item timestamps; existing items are stamped with the migration time
+		stampItems(config.Values, nil, time.Now())
+		return nil
+	case 4:
+		// Migration from version 4 to 5
+		// Adds named request and response examples; empty example sets are dropped
+		for id, item := range config.Values {
+			if item.Examples != nil && len(item.Examples.Requests) == 0 && len(item.Examples.Responses) == 0 {
+				item.Examples = nil
+				config.Values[id] = item
+			}
+		}
+		return nil
+	case 5:
+		// Migration from version 5 to 6
+		// Adds structured form bodies; URL-encoded bodies are split into form fields
+		for id, item := range config.Values {
+			if fields, ok := ParseFormBody(item); ok {
+				item.Body, item.FormFields = "", fields
+				config.Values[id] = item
+			}
+		}
+		return nil
 	default:
 		return fmt.Errorf("unknown migration from version %d", fromVersion)
 	}
@@ -1,11 +1,21 @@
 package requests
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
 	"testing"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/storage"
 )
 
 func TestValidateConfig(t *testing.T) {
@@ -36,7 +46,7 @@ func TestValidateConfig(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name: "request at root level should fail",
+			name: "request at root level is only an orphan",
 			config: &RequestsConfig{
 				Version: 1,
 				Values: map[string]Item{
@@ -48,8 +58,7 @@ func TestValidateConfig(t *testing.T) {
 					},
 				},
 			},
-			wantErr: true,
-			errMsg:  "root level item 'req1' must be a folder",
+			wantErr: false,
 		},
 		{
 			name: "multiple folders at root level should be valid",
@@ -138,7 +147,7 @@ func TestValidateConfig(t *testing.T) {
 			errMsg:  "request must have a method",
 		},
 		{
-			name: "request without path should fail",
+			name: "request without path should pass with a warning",
 			config: &RequestsConfig{
 				Version: 1,
 				Values: map[string]Item{
@@ -154,8 +163,76 @@ func TestValidateConfig(t *testing.T) {
 					},
 				},
 			},
+			wantErr: false,
+		},
+		{
+			name: "duplicate path variable should fail",
+			config: &RequestsConfig{
+				Version: 1,
+				Values: map[string]Item{
+					"req1": {
+						Type:   ItemTypeRequest,
+						Name:   "Get User",
+						Method: "GET",
+						Path:   "/api/users/:id",
+						PathVars: []Param{
+							{Key: "id", Value: "1"},
+							{Key: "id", Value: "2"},
+						},
+					},
+					"folder1": {
+						Type:     ItemTypeFolder,
+						Name:     "API",
+						Children: []string{"req1"},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "path variable 'id' is defined more than once",
+		},
+		{
+			name: "description over the length limit should fail",
+			config: &RequestsConfig{
+				Version: 1,
+				Values: map[string]Item{
+					"folder1": {
+						Type:        ItemTypeFolder,
+						Name:        "API",
+						Description: strings.Repeat("a", 20001),
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid tag should fail",
+			config: &RequestsConfig{
+				Version: 1,
+				Values: map[string]Item{
+					"folder1": {
+						Type: ItemTypeFolder,
+						Name: "API",
+						Tags: []string{"auth", "Needs Review"},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "tag 'Needs Review' must be 1-32 lowercase letters, digits, '-' or '_'",
+		},
+		{
+			name: "folder with query parameters should fail",
+			config: &RequestsConfig{
+				Version: 1,
+				Values: map[string]Item{
+					"folder1": {
+						Type:        ItemTypeFolder,
+						Name:        "API",
+						QueryParams: []Param{{Key: "page", Value: "1"}},
+					},
+				},
+			},
 			wantErr: true,
-			errMsg:  "request must have a path",
+			errMsg:  "folder cannot have query parameters",
 		},
 		{
 			name: "invalid HTTP method should fail",
@@ -341,7 +418,7 @@ func TestMigrateConfig(t *testing.T) {
 		expectedVersion int
 	}{
 		{
-			name: "version 0 should migrate to 1",
+			name: "version 0 should migrate to current",
 			config: &RequestsConfig{
 				Version: 0,
 				Values: map[string]Item{
@@ -353,10 +430,10 @@ func TestMigrateConfig(t *testing.T) {
 					},
 				},
 			},
-			expectedVersion: 1,
+			expectedVersion: CurrentVersion,
 		},
 		{
-			name: "version 1 should stay at 1",
+			name: "version 1 should migrate to current",
 			config: &RequestsConfig{
 				Version: 1,
 				Values: map[string]Item{
@@ -368,7 +445,23 @@ func TestMigrateConfig(t *testing.T) {
 					},
 				},
 			},
-			expectedVersion: 1,
+			expectedVersion: CurrentVersion,
+		},
+		{
+			name: "version 4 should migrate to current",
+			config: &RequestsConfig{
+				Version: 4,
+				Values: map[string]Item{
+					"req1": {
+						Type:     ItemTypeRequest,
+						Name:     "Test",
+						Method:   "GET",
+						Path:     "/test",
+						Examples: &Examples{},
+					},
+				},
+			},
+			expectedVersion: CurrentVersion,
 		},
 	}
 
@@ -517,6 +610,88 @@ func TestConfigVersioning(t *testing.T) {
 	}
 }
 
+func TestNewerConfigVersion(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalAppDataDir := appDataDir
+	appDataDir = tmpDir
+	requestsFile = filepath.Join(tmpDir, RequestsFileName)
+	defer func() {
+		appDataDir = originalAppDataDir
+		requestsFile = filepath.Join(appDataDir, RequestsFileName)
+	}()
+
+	newerJSON := fmt.Sprintf(`{
+		"version": %d,
+		"rootOrder": ["folder1"],
+		"values": {
+			"folder1": {"type": "workspace", "name": "Unknown item type", "children": []}
+		}
+	}`, CurrentVersion+1)
+	if err := os.WriteFile(requestsFile, []byte(newerJSON), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v, want the newer config loaded as is", err)
+	}
+	if config.Version != CurrentVersion+1 {
+		t.Errorf("Load() version = %v, want %v", config.Version, CurrentVersion+1)
+	}
+	if data, _ := os.ReadFile(requestsFile); string(data) != newerJSON {
+		t.Errorf("Load() rewrote the newer config")
+	}
+
+	if _, err := Decode([]byte(newerJSON)); apperrors.CodeOf(err) != apperrors.ValidationFailed {
+		t.Errorf("Decode() error = %v, want the newer config refused", err)
+	}
+}
+
+func TestUnknownItemFieldsRoundTrip(t *testing.T) {
+	input := `{
+		"version": 6,
+		"rootOrder": ["folder1"],
+		"values": {
+			"folder1": {"type": "folder", "name": "Folder", "children": ["req1"], "color": "#ff0000"},
+			"req1": {"type": "request", "name": "Req", "method": "GET", "path": "/", "retryPolicy": {"max": 3}, "x-tool": [1, 2]}
+		}
+	}`
+	config, err := Decode([]byte(input))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got := string(config.Values["req1"].Extra["retryPolicy"]); got != `{"max": 3}` {
+		t.Errorf("Extra[retryPolicy] = %q, want the raw value", got)
+	}
+	if _, known := config.Values["req1"].Extra["method"]; known {
+		t.Error("Extra holds a known field")
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var saved struct {
+		Values map[string]map[string]json.RawMessage `json:"values"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("saved config is not valid JSON: %v\n%s", err, data)
+	}
+	for id, field := range map[string]string{"folder1": "color", "req1": "x-tool"} {
+		if _, ok := saved.Values[id][field]; !ok {
+			t.Errorf("saved %s lost %q: %s", id, field, data)
+		}
+	}
+
+	// An item sent back without the unknown fields keeps them
+	edited := config.Values["req1"]
+	edited.Extra = nil
+	edited.Path = "/edited"
+	if got := keepExtra(edited, config.Values["req1"]); got.Path != "/edited" || got.Extra["retryPolicy"] == nil {
+		t.Errorf("keepExtra() = %+v, want the edit with the unknown fields", got)
+	}
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
 		(len(s) > len(substr) && (s[:len(substr)] == substr ||
@@ -565,3 +740,1352 @@ func TestJSONUnmarshal(t *testing.T) {
 		t.Errorf("json.Unmarshal() values count = %v, want 2", len(config.Values))
 	}
 }
+
+func TestValidateAllReportsEveryIssue(t *testing.T) {
+	cfg := &RequestsConfig{
+		Version: CurrentVersion,
+		Values: map[string]Item{
+			"folder1": {Type: ItemTypeFolder, Name: "Folder", Method: "GET", Children: []string{"req1", "missing"}},
+			"req1":    {Type: ItemTypeRequest, Name: "", Method: "GET", Tags: []string{"Bad Tag"}},
+			"req2":    {Type: ItemTypeRequest, Name: "Orphan", Method: "GET", Path: "/orphan"},
+		},
+	}
+
+	issues := ValidateAll(cfg)
+
+	want := []struct{ itemID, contains string }{
+		{"folder1", "folder cannot have a method"},
+		{"folder1", "child reference 'missing' does not exist"},
+		{"req1", "Name is required"},
+		{"req1", "tag 'Bad Tag'"},
+	}
+	for _, w := range want {
+		found := false
+		for _, issue := range issues {
+			if issue.ItemID == w.itemID && issue.Severity == SeverityError && strings.Contains(issue.Message, w.contains) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected error issue on %s containing %q, got %+v", w.itemID, w.contains, issues)
+		}
+	}
+
+	var warned bool
+	for _, issue := range issues {
+		if issue.ItemID == "req1" && issue.Field == "path" && issue.Severity == SeverityWarning {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Errorf("expected a path warning on req1, got %+v", issues)
+	}
+
+	var orphaned bool
+	for _, issue := range issues {
+		if issue.ItemID == "req2" && issue.Field == "parent" && issue.Severity == SeverityWarning {
+			orphaned = true
+		}
+	}
+	if !orphaned {
+		t.Errorf("expected an orphan warning on req2, got %+v", issues)
+	}
+
+	err := Validate(cfg)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Validate() error = %v, want *ValidationError", err)
+	}
+	if len(verr.Issues) < len(want) {
+		t.Errorf("Validate() reported %d issues, want at least %d", len(verr.Issues), len(want))
+	}
+}
+
+func TestValidateAllDetectsCycles(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]Item
+		want   string
+	}{
+		{
+			name: "two item cycle",
+			values: map[string]Item{
+				"a": {Type: ItemTypeFolder, Name: "A", Children: []string{"b"}},
+				"b": {Type: ItemTypeFolder, Name: "B", Children: []string{"a"}},
+			},
+			want: "circular reference detected: a -> b -> a",
+		},
+		{
+			name: "cycle below a root folder",
+			values: map[string]Item{
+				"root": {Type: ItemTypeFolder, Name: "Root", Children: []string{"x"}},
+				"x":    {Type: ItemTypeFolder, Name: "X", Children: []string{"y"}},
+				"y":    {Type: ItemTypeFolder, Name: "Y", Children: []string{"x"}},
+			},
+			want: "circular reference detected: x -> y -> x",
+		},
+		{
+			name: "shared child",
+			values: map[string]Item{
+				"f1":  {Type: ItemTypeFolder, Name: "F1", Children: []string{"req"}},
+				"f2":  {Type: ItemTypeFolder, Name: "F2", Children: []string{"req"}},
+				"req": {Type: ItemTypeRequest, Name: "Req", Method: "GET", Path: "/"},
+			},
+			want: "item 'req' is referenced by multiple parents: f1, f2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := ValidateAll(&RequestsConfig{Version: CurrentVersion, Values: tt.values})
+
+			var messages []string
+			for _, issue := range issues {
+				messages = append(messages, issue.Message)
+				if strings.Contains(issue.Message, "depth") {
+					t.Errorf("unexpected depth issue for a cyclic tree: %s", issue.Message)
+				}
+			}
+			if !strings.Contains(strings.Join(messages, "\n"), tt.want) {
+				t.Errorf("expected issue %q, got %v", tt.want, messages)
+			}
+		})
+	}
+}
+
+func TestMaskSecretsInExamplesAndSnapshots(t *testing.T) {
+	cfg := &RequestsConfig{
+		Version: CurrentVersion,
+		Values: map[string]Item{
+			"req1": {
+				Type: ItemTypeRequest, Name: "Login", Method: "POST", Path: "/login",
+				Headers: []Header{{Key: "Authorization", Value: "Bearer live"}},
+				Examples: &Examples{
+					Requests: []RequestExample{{Name: "admin", Headers: []Header{
+						{Key: "X-Api-Key", Value: "example-key"},
+						{Key: "X-Tenant", Value: "acme", Secret: true},
+						{Key: "Accept", Value: "application/json"},
+					}}},
+					Responses: []ResponseExample{{Name: "ok", Status: 200, Headers: []Header{{Key: "Set-Cookie", Value: "session=abc"}}}},
+				},
+				Snapshot: &Snapshot{Status: 200, Headers: map[string]string{"Set-Cookie": "session=abc", "Content-Type": "application/json"}},
+			},
+		},
+	}
+
+	item := MaskSecrets(cfg).Values["req1"]
+	headers := item.Examples.Requests[0].Headers
+	if item.Headers[0].Value != SecretMask || headers[0].Value != SecretMask || headers[1].Value != SecretMask || headers[2].Value != "application/json" {
+		t.Errorf("request example headers = %+v, want the secret ones masked", headers)
+	}
+	if got := item.Examples.Responses[0].Headers[0].Value; got != SecretMask {
+		t.Errorf("response example Set-Cookie = %q, want it masked", got)
+	}
+	if item.Snapshot.Headers["Set-Cookie"] != SecretMask || item.Snapshot.Headers["Content-Type"] != "application/json" {
+		t.Errorf("snapshot headers = %v, want Set-Cookie masked", item.Snapshot.Headers)
+	}
+
+	original := cfg.Values["req1"]
+	if original.Examples.Requests[0].Headers[0].Value != "example-key" || original.Snapshot.Headers["Set-Cookie"] != "session=abc" {
+		t.Error("MaskSecrets() changed the original config")
+	}
+}
+
+func TestTagsAndFavorites(t *testing.T) {
+	cfg := &RequestsConfig{
+		Version:   CurrentVersion,
+		RootOrder: []string{"b", "a"},
+		Values: map[string]Item{
+			"a":    {Type: ItemTypeFolder, Name: "A", Children: []string{"req1"}, Tags: []string{"auth"}},
+			"b":    {Type: ItemTypeFolder, Name: "B", Children: []string{"req2"}},
+			"req1": {Type: ItemTypeRequest, Name: "One", Method: "GET", Tags: []string{"auth"}, Favorite: true},
+			"req2": {Type: ItemTypeRequest, Name: "Two", Method: "GET", Tags: []string{"auth", "slow"}, Favorite: true},
+		},
+	}
+
+	if got := ItemsWithTag(cfg, "auth"); strings.Join(got, ",") != "req2,a,req1" {
+		t.Errorf("ItemsWithTag() = %v, want tree order [req2 a req1]", got)
+	}
+	if got := ItemsWithTag(cfg, "missing"); len(got) != 0 {
+		t.Errorf("ItemsWithTag() = %v, want none", got)
+	}
+
+	favorites := FavoritesFolder(cfg)
+	if favorites.Type != ItemTypeFolder || strings.Join(favorites.Children, ",") != "req2,req1" {
+		t.Errorf("FavoritesFolder() = %+v", favorites)
+	}
+}
+
+func TestApplyOperations(t *testing.T) {
+	newConfig := func() *RequestsConfig {
+		return &RequestsConfig{
+			Version:   CurrentVersion,
+			RootOrder: []string{"api"},
+			Values: map[string]Item{
+				"api":    {Type: ItemTypeFolder, Name: "API", Children: []string{"users", "health"}},
+				"users":  {Type: ItemTypeFolder, Name: "Users", Children: []string{"list"}},
+				"list":   {Type: ItemTypeRequest, Name: "List", Method: "GET", Path: "/users"},
+				"health": {Type: ItemTypeRequest, Name: "Health", Method: "GET", Path: "/health"},
+			},
+		}
+	}
+	first := 0
+
+	t.Run("add, update and move in one batch", func(t *testing.T) {
+		cfg := newConfig()
+		added, err := applyOperations(cfg, []Operation{
+			{Op: OperationAdd, ID: "tmp-admin", Item: &Item{Type: ItemTypeFolder, Name: "Admin"}},
+			{Op: OperationAdd, ParentID: "tmp-admin", Item: &Item{Type: ItemTypeRequest, Name: "Stats", Method: "GET", Path: "/stats"}},
+			{Op: OperationUpdate, ID: "health", Item: &Item{Type: ItemTypeRequest, Name: "Ping", Method: "HEAD", Path: "/ping"}},
+			{Op: OperationMove, ID: "health", ParentID: "users", Index: &first},
+		})
+		if err != nil {
+			t.Fatalf("applyOperations() error = %v", err)
+		}
+		if len(added) != 2 {
+			t.Fatalf("applyOperations() added = %v, want 2 IDs", added)
+		}
+		if admin := cfg.Values[added[0]]; len(admin.Children) != 1 || admin.Children[0] != added[1] {
+			t.Errorf("placeholder parent not resolved, admin = %+v", admin)
+		}
+		if strings.Join(cfg.RootOrder, ",") != "api,"+added[0] {
+			t.Errorf("RootOrder = %v", cfg.RootOrder)
+		}
+		if got := strings.Join(cfg.Values["users"].Children, ","); got != "health,list" {
+			t.Errorf("users children = %s, want health,list", got)
+		}
+		if got := strings.Join(cfg.Values["api"].Children, ","); got != "users" {
+			t.Errorf("api children = %s, want users", got)
+		}
+		if cfg.Values["health"].Name != "Ping" {
+			t.Errorf("update not applied: %+v", cfg.Values["health"])
+		}
+		if err := Validate(cfg); err != nil {
+			t.Errorf("Validate() after operations error = %v", err)
+		}
+	})
+
+	t.Run("delete removes descendants", func(t *testing.T) {
+		cfg := newConfig()
+		if _, err := applyOperations(cfg, []Operation{{Op: OperationDelete, ID: "users"}}); err != nil {
+			t.Fatalf("applyOperations() error = %v", err)
+		}
+		if _, exists := cfg.Values["list"]; exists || len(cfg.Values) != 2 {
+			t.Errorf("Values = %v, want users and list removed", cfg.Values)
+		}
+	})
+
+	t.Run("invalid operations fail", func(t *testing.T) {
+		for name, ops := range map[string][]Operation{
+			"move into descendant": {{Op: OperationMove, ID: "api", ParentID: "users"}},
+			"request at root":      {{Op: OperationMove, ID: "list"}},
+			"change type":          {{Op: OperationUpdate, ID: "list", Item: &Item{Type: ItemTypeFolder, Name: "X"}}},
+			"missing item":         {{Op: OperationDelete, ID: "nope"}},
+			"unknown op":           {{Op: "rename", ID: "list"}},
+		} {
+			if _, err := applyOperations(newConfig(), ops); err == nil {
+				t.Errorf("%s: applyOperations() error = nil, want error", name)
+			}
+		}
+	})
+}
+
+func TestConfigurableFolderDepth(t *testing.T) {
+	deep := &RequestsConfig{
+		Version: CurrentVersion,
+		Values: map[string]Item{
+			"f0":  {Type: ItemTypeFolder, Name: "F0", Children: []string{"f1"}},
+			"f1":  {Type: ItemTypeFolder, Name: "F1", Children: []string{"f2"}},
+			"f2":  {Type: ItemTypeFolder, Name: "F2", Children: []string{"f3"}},
+			"f3":  {Type: ItemTypeFolder, Name: "F3", Children: []string{"req"}},
+			"req": {Type: ItemTypeRequest, Name: "Req", Method: "GET", Path: "/"},
+		},
+	}
+
+	defer func() { _ = SetMaxFolderDepth(DefaultMaxFolderDepth) }()
+
+	if err := Validate(deep); err == nil {
+		t.Fatal("expected default limit to reject 4 nested folders")
+	}
+
+	if err := SetMaxFolderDepth(UnlimitedFolderDepth); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(deep); err != nil {
+		t.Errorf("unlimited depth should accept 4 nested folders, got %v", err)
+	}
+
+	if issues := DepthIssues(deep, 5); len(issues) != 0 {
+		t.Errorf("limit 5 should accept 4 nested folders, got %+v", issues)
+	}
+
+	if err := SetMaxFolderDepth(-1); err == nil {
+		t.Error("expected negative limit to be rejected")
+	}
+}
+
+func TestCustomMethods(t *testing.T) {
+	cfg := &RequestsConfig{
+		Version: CurrentVersion,
+		Values: map[string]Item{
+			"api":   {Type: ItemTypeFolder, Name: "API", Children: []string{"purge", "dav"}},
+			"purge": {Type: ItemTypeRequest, Name: "Purge", Method: "purge", Path: "/cache"},
+			"dav":   {Type: ItemTypeRequest, Name: "List", Method: "PROPFIND", Path: "/files"},
+		},
+	}
+
+	defer func() { _ = SetCustomMethods(false, nil) }()
+
+	if err := Validate(cfg); err == nil {
+		t.Fatal("expected non-standard methods to be rejected by default")
+	}
+
+	if err := SetCustomMethods(false, []string{"PURGE"}); err != nil {
+		t.Fatal(err)
+	}
+	if issues := MethodIssues(cfg, false, []string{"PURGE"}); len(issues) != 1 || issues[0].ItemID != "dav" {
+		t.Errorf("MethodIssues() = %+v, want only the PROPFIND request", issues)
+	}
+	if err := Validate(cfg); err == nil {
+		t.Error("expected PROPFIND to be rejected when only PURGE is listed")
+	}
+
+	if err := SetCustomMethods(true, []string{"PURGE"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("allowing custom methods should accept PROPFIND, got %v", err)
+	}
+	if IsAllowedMethod("propfind") || IsAllowedMethod("BAD METHOD") {
+		t.Error("only uppercase method tokens should be accepted as arbitrary custom methods")
+	}
+
+	if err := SetCustomMethods(false, []string{"purge"}); err == nil {
+		t.Error("expected a lowercase custom method to be rejected")
+	}
+}
+
+func TestMigrateFormBodies(t *testing.T) {
+	form := []Header{{Key: "Content-Type", Value: "application/x-www-form-urlencoded"}}
+	cfg := &RequestsConfig{
+		Version: 5,
+		Values: map[string]Item{
+			"api":     {Type: ItemTypeFolder, Name: "API", Children: []string{"login", "json", "wrapped"}},
+			"login":   {Type: ItemTypeRequest, Name: "Login", Method: "POST", Path: "/login", Headers: form, Body: "user=ada+l&token={{token}}&empty="},
+			"json":    {Type: ItemTypeRequest, Name: "JSON", Method: "POST", Path: "/json", Body: "a=b"},
+			"wrapped": {Type: ItemTypeRequest, Name: "Wrapped", Method: "POST", Path: "/raw", Headers: form, Body: "{{formBody}}"},
+		},
+	}
+	if err := migrateConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	login := cfg.Values["login"]
+	want := []FormField{{Key: "user", Value: "ada l"}, {Key: "token", Value: "{{token}}"}, {Key: "empty"}}
+	if login.Body != "" || !reflect.DeepEqual(login.FormFields, want) {
+		t.Errorf("login = body %q, fields %+v; want fields %+v", login.Body, login.FormFields, want)
+	}
+	if cfg.Values["json"].Body != "a=b" || cfg.Values["wrapped"].Body != "{{formBody}}" {
+		t.Errorf("bodies not sent as a plain form should stay raw, got %+v", cfg.Values)
+	}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate() after migration error = %v", err)
+	}
+
+	login.Body = "raw"
+	cfg.Values["login"] = login
+	if err := Validate(cfg); err == nil {
+		t.Error("expected a request with both form fields and a body to be rejected")
+	}
+}
+
+func TestFlattenNodes(t *testing.T) {
+	req := func(name string) Node {
+		return Node{Item: Item{Type: ItemTypeRequest, Name: name, Method: "GET"}}
+	}
+	folder := func(name string, children ...Node) Node {
+		return Node{Item: Item{Type: ItemTypeFolder, Name: name}, Children: children}
+	}
+
+	nodes := []Node{folder("A", req("a"), folder("B", req("b"), folder("C", req("c"))))}
+
+	if got := flattenNodes(nodes, 0, UnlimitedFolderDepth); len(got[0].Children[1].Children[1].Children) != 1 {
+		t.Fatalf("unlimited depth should keep the tree unchanged, got %+v", got)
+	}
+
+	got := flattenNodes(nodes, 0, 2)
+	b := got[0].Children[1]
+	if b.Item.Name != "B" || len(b.Children) != 2 {
+		t.Fatalf("expected B to keep two children, got %+v", b)
+	}
+	if hoisted := b.Children[1]; hoisted.Item.Type != ItemTypeRequest || hoisted.Item.Name != "C / c" {
+		t.Errorf("expected C's request hoisted into B as 'C / c', got %+v", hoisted)
+	}
+}
+
+func TestSortedIDs(t *testing.T) {
+	used := func(minutes int) *time.Time {
+		at := time.Date(2024, 1, 1, 0, minutes, 0, 0, time.UTC)
+		return &at
+	}
+	cfg := &RequestsConfig{
+		Version: CurrentVersion,
+		Values: map[string]Item{
+			"folder": {Type: ItemTypeFolder, Name: "zeta", Children: []string{"inner"}},
+			"inner":  {Type: ItemTypeRequest, Name: "Inner", Method: "GET", LastUsedAt: used(30)},
+			"post":   {Type: ItemTypeRequest, Name: "beta", Method: "POST", LastUsedAt: used(10)},
+			"get":    {Type: ItemTypeRequest, Name: "Alpha", Method: "GET"},
+			"delete": {Type: ItemTypeRequest, Name: "gamma", Method: "DELETE", LastUsedAt: used(20)},
+		},
+	}
+	ids := []string{"folder", "post", "get", "delete"}
+
+	tests := []struct {
+		by        SortKey
+		direction SortDirection
+		want      []string
+	}{
+		{SortByName, SortAscending, []string{"get", "post", "delete", "folder"}},
+		{SortByName, SortDescending, []string{"folder", "delete", "post", "get"}},
+		{SortByMethod, SortAscending, []string{"folder", "delete", "get", "post"}},
+		{SortByLastUsed, SortDescending, []string{"folder", "delete", "post", "get"}},
+	}
+
+	for _, tt := range tests {
+		got := sortedIDs(cfg, ids, tt.by, tt.direction)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("sort by %s %s = %v, want %v", tt.by, tt.direction, got, tt.want)
+		}
+	}
+}
+
+func TestStampItems(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := created.Add(time.Hour)
+
+	values := map[string]Item{
+		"same":    {Type: ItemTypeRequest, Name: "Same", Method: "GET", CreatedAt: created, UpdatedAt: created},
+		"renamed": {Type: ItemTypeRequest, Name: "Old", Method: "GET", CreatedAt: created, UpdatedAt: created},
+		"used":    {Type: ItemTypeRequest, Name: "Used", Method: "GET", CreatedAt: created, UpdatedAt: created},
+	}
+	before := encodeItems(values)
+
+	renamed := values["renamed"]
+	renamed.Name = "New"
+	values["renamed"] = renamed
+	used := values["used"]
+	used.LastUsedAt = &now
+	values["used"] = used
+	values["added"] = Item{Type: ItemTypeRequest, Name: "Added", Method: "GET"}
+
+	changed := stampItems(values, before, now)
+	sort.Strings(changed)
+	if want := []string{"added", "renamed", "used"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("stampItems() changed = %v, want %v", changed, want)
+	}
+
+	if got := values["same"]; !got.UpdatedAt.Equal(created) {
+		t.Errorf("unchanged item UpdatedAt = %v, want %v", got.UpdatedAt, created)
+	}
+	if got := values["renamed"]; !got.UpdatedAt.Equal(now) || !got.CreatedAt.Equal(created) {
+		t.Errorf("renamed item timestamps = %v/%v, want created %v updated %v", got.CreatedAt, got.UpdatedAt, created, now)
+	}
+	if got := values["used"]; !got.UpdatedAt.Equal(created) {
+		t.Errorf("sending a request should not count as an edit, UpdatedAt = %v", got.UpdatedAt)
+	}
+	if got := values["added"]; !got.CreatedAt.Equal(now) || !got.UpdatedAt.Equal(now) {
+		t.Errorf("added item timestamps = %v/%v, want %v", got.CreatedAt, got.UpdatedAt, now)
+	}
+
+	migrated := &RequestsConfig{Version: 3, Values: map[string]Item{
+		"folder": {Type: ItemTypeFolder, Name: "Folder"},
+	}}
+	if err := migrateConfig(migrated); err != nil {
+		t.Fatal(err)
+	}
+	if item := migrated.Values["folder"]; item.CreatedAt.IsZero() || item.UpdatedAt.IsZero() {
+		t.Errorf("migration should stamp existing items, got %+v", item)
+	}
+}
+
+func TestRecentRequests(t *testing.T) {
+	at := func(minutes int) *time.Time {
+		used := time.Date(2024, 1, 1, 0, minutes, 0, 0, time.UTC)
+		return &used
+	}
+	cfg := &RequestsConfig{
+		Version: CurrentVersion,
+		Values: map[string]Item{
+			"folder": {Type: ItemTypeFolder, Name: "Folder", Children: []string{"a", "b", "c", "never"}},
+			"a":      {Type: ItemTypeRequest, Name: "A", Method: "GET", LastUsedAt: at(1)},
+			"b":      {Type: ItemTypeRequest, Name: "B", Method: "GET", LastUsedAt: at(3)},
+			"c":      {Type: ItemTypeRequest, Name: "C", Method: "GET", LastUsedAt: at(2)},
+			"never":  {Type: ItemTypeRequest, Name: "Never", Method: "GET"},
+		},
+	}
+
+	got := RecentRequests(cfg, 2)
+	if len(got) != 2 || got[0].ID != "b" || got[1].ID != "c" {
+		t.Errorf("RecentRequests(2) = %+v, want b, c", got)
+	}
+	if all := RecentRequests(cfg, 0); len(all) != 3 {
+		t.Errorf("RecentRequests(0) returned %d requests, want the 3 that were sent", len(all))
+	}
+}
+
+func TestLoadFromImportsJSONFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalAppDataDir := appDataDir
+	appDataDir = tmpDir
+	requestsFile = filepath.Join(tmpDir, RequestsFileName)
+	defer func() {
+		appDataDir = originalAppDataDir
+		requestsFile = filepath.Join(appDataDir, RequestsFileName)
+	}()
+
+	existing := &RequestsConfig{
+		Version:   CurrentVersion,
+		RootOrder: []string{"folder1"},
+		Values: map[string]Item{
+			"folder1": {Type: ItemTypeFolder, Name: "Folder", Children: []string{"req1"}},
+			"req1":    {Type: ItemTypeRequest, Name: "Existing", Method: "GET", Path: "/existing"},
+		},
+	}
+	if err := Save(existing); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	db, err := storage.NewSQLiteStorage(DatabasePath())
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error = %v", err)
+	}
+	defer db.Close()
+
+	// First load imports requests.json into the empty database
+	config, err := loadFrom(db, requestsFile)
+	if err != nil {
+		t.Fatalf("loadFrom() error = %v", err)
+	}
+	if config.Values["req1"].Name != "Existing" {
+		t.Fatalf("loadFrom() did not import the JSON file, got %+v", config.Values)
+	}
+
+	// Later loads read the database, not the file
+	if err := os.Remove(requestsFile); err != nil {
+		t.Fatal(err)
+	}
+	config, err = loadFrom(db, requestsFile)
+	if err != nil {
+		t.Fatalf("loadFrom() error = %v", err)
+	}
+	if config.Values["req1"].Name != "Existing" || len(config.RootOrder) != 1 {
+		t.Errorf("loadFrom() = %+v, want the imported config", config)
+	}
+}
+
+// largeConfig returns a valid config with n requests spread over folders of 100
+func largeConfig(n int) *RequestsConfig {
+	cfg := &RequestsConfig{Version: CurrentVersion, Values: make(map[string]Item, n+n/100+1)}
+	var folder Item
+	var folderID string
+	for i := 0; i < n; i++ {
+		if i%100 == 0 {
+			folderID = fmt.Sprintf("folder%d", i/100)
+			folder = Item{Type: ItemTypeFolder, Name: folderID}
+			cfg.RootOrder = append(cfg.RootOrder, folderID)
+		}
+		id := fmt.Sprintf("req%d", i)
+		cfg.Values[id] = Item{
+			Type:    ItemTypeRequest,
+			Name:    id,
+			Method:  "POST",
+			Path:    "/api/v1/items/" + id,
+			Headers: []Header{{Key: "Content-Type", Value: "application/json"}},
+			Body:    `{"name": "` + id + `", "description": "a request body of moderate size"}`,
+		}
+		folder.Children = append(folder.Children, id)
+		cfg.Values[folderID] = folder
+	}
+	stampItems(cfg.Values, nil, time.Now())
+	return cfg
+}
+
+// useTempDataDir points the requests file at a temporary directory for the test
+func useTempDataDir(tb testing.TB) {
+	originalAppDataDir := appDataDir
+	appDataDir = tb.TempDir()
+	requestsFile = filepath.Join(appDataDir, RequestsFileName)
+	tb.Cleanup(func() {
+		appDataDir = originalAppDataDir
+		requestsFile = filepath.Join(appDataDir, RequestsFileName)
+	})
+}
+
+func TestManagerSavesOnlyChangedItems(t *testing.T) {
+	useTempDataDir(t)
+	if err := Save(largeConfig(200)); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := storage.NewSQLiteStorage(DatabasePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m := NewManager(db)
+	m.UseStorage(db)
+	if err := m.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer m.Close()
+
+	// Plant a marker in another row; an item-wise save must leave it alone
+	if err := db.SaveItems(requestsFile, m.GetRequestsConfig(), map[string]interface{}{
+		"req1": Item{Type: ItemTypeRequest, Name: "marker", Method: "GET"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	item := m.GetRequestsConfig().Values["req0"]
+	item.Name = "renamed"
+	if err := m.PatchValues(map[string]Item{"req0": item}); err != nil {
+		t.Fatalf("PatchValues() error = %v", err)
+	}
+	if err := m.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	saved, err := loadFrom(db, requestsFile)
+	if err != nil {
+		t.Fatalf("loadFrom() error = %v", err)
+	}
+	if saved.Values["req0"].Name != "renamed" {
+		t.Errorf("changed item was not saved, got %q", saved.Values["req0"].Name)
+	}
+	if saved.Values["req1"].Name != "marker" {
+		t.Errorf("unchanged item was rewritten, got %q", saved.Values["req1"].Name)
+	}
+}
+
+// BenchmarkSaveOneChange10k measures renaming one request of a 10k-item config and saving it
+func BenchmarkSaveOneChange10k(b *testing.B) {
+	backends := map[string]func(b *testing.B) storage.Storage{
+		"json": func(*testing.B) storage.Storage {
+			return storage.NewStorageCoordinator(storage.NewFileStorage(), nil, nil)
+		},
+		"sqlite": func(b *testing.B) storage.Storage {
+			db, err := storage.NewSQLiteStorage(DatabasePath())
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Cleanup(func() { db.Close() })
+			return db
+		},
+		"folders": func(*testing.B) storage.Storage {
+			return NewCollectionStorage(CollectionsDir())
+		},
+	}
+
+	for name, open := range backends {
+		b.Run(name, func(b *testing.B) {
+			useTempDataDir(b)
+			if err := Save(largeConfig(10000)); err != nil {
+				b.Fatal(err)
+			}
+			s := open(b)
+			m := NewManager(s)
+			m.SetStorage(s, func() (*RequestsConfig, error) { return loadFrom(s, requestsFile) })
+			if err := m.Load(); err != nil {
+				b.Fatal(err)
+			}
+			defer m.Close()
+			item := m.GetRequestsConfig().Values["req42"]
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				item.Name = fmt.Sprintf("renamed %d", i)
+				if err := m.PatchValues(map[string]Item{"req42": item}); err != nil {
+					b.Fatal(err)
+				}
+				if err := m.Flush(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestPatchValuesChangesOnlyThePatchedItems(t *testing.T) {
+	useTempDataDir(t)
+	m := NewManagerWithWriter(storage.NewFileWriter())
+	m.SetAuditLog(nil)
+	m.SetStorage(storage.NewFileStorage(), func() (*RequestsConfig, error) { return largeConfig(300), nil })
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	item := m.GetRequestsConfig().Values["req7"]
+	stamped := item.UpdatedAt
+	item.Headers = append(item.Headers, Header{Key: "X-Trace", Value: "1"})
+	if err := m.PatchValues(map[string]Item{"req7": item}); err != nil {
+		t.Fatalf("PatchValues() error = %v", err)
+	}
+	saved := m.GetRequestsConfig().Values["req7"]
+	if len(saved.Headers) != 2 || !saved.UpdatedAt.After(stamped) {
+		t.Errorf("patched item = %+v, want the new header and a new UpdatedAt", saved)
+	}
+
+	// An invalid item is refused as a whole and leaves the config as it was
+	revision := m.Revision()
+	item.Headers = nil
+	item.Method = "NOT A METHOD"
+	if err := m.PatchValues(map[string]Item{"req7": item}); apperrors.CodeOf(err) != apperrors.ValidationFailed {
+		t.Fatalf("PatchValues() with an invalid method error = %v, want VALIDATION_FAILED", err)
+	}
+	if current := m.GetRequestsConfig().Values["req7"]; m.Revision() != revision || len(current.Headers) != 2 || current.Method != "POST" {
+		t.Errorf("failed patch left %+v at revision %d, want the item and revision %d unchanged", current, m.Revision(), revision)
+	}
+
+	// Moving an item is checked against the whole tree
+	folder := m.GetRequestsConfig().Values["folder0"]
+	folder.Children = append(folder.Children, "missing")
+	if err := m.PatchValues(map[string]Item{"folder0": folder}); err == nil {
+		t.Error("PatchValues() adding a missing child succeeded")
+	}
+	folder = m.GetRequestsConfig().Values["folder0"]
+	slices.Reverse(folder.Children)
+	if err := m.PatchValues(map[string]Item{"folder0": folder}); err != nil {
+		t.Fatalf("PatchValues() reordering children error = %v", err)
+	}
+	if children := m.GetRequestsConfig().Values["folder0"].Children; children[0] != "req99" {
+		t.Errorf("children = %v, want them reversed", children[:3])
+	}
+}
+
+// recordingWriter writes files and records their names
+type recordingWriter struct {
+	storage.FileWriter
+	names []string
+}
+
+func (w *recordingWriter) WriteAtomic(filename string, data []byte, perm os.FileMode) error {
+	w.names = append(w.names, filepath.Base(filename))
+	return w.FileWriter.WriteAtomic(filename, data, perm)
+}
+
+func TestCollectionStorage(t *testing.T) {
+	dir := t.TempDir()
+	writer := &recordingWriter{}
+	cs := NewCollectionStorageWithWriter(dir, writer)
+
+	cfg := largeConfig(300)
+	if err := cs.Save("", cfg); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	sort.Strings(writer.names)
+	if want := []string{"_index.json", "folder0.json", "folder1.json", "folder2.json"}; !reflect.DeepEqual(writer.names, want) {
+		t.Fatalf("Save() wrote %v, want %v", writer.names, want)
+	}
+
+	// Renaming one request rewrites only the file of its root folder
+	writer.names = nil
+	item := cfg.Values["req150"]
+	item.Name = "renamed"
+	cfg.Values["req150"] = item
+	document, items := splitItems(cfg, []string{"req150"})
+	if err := cs.SaveItems("", document, items); err != nil {
+		t.Fatalf("SaveItems() error = %v", err)
+	}
+	if want := []string{"folder1.json"}; !reflect.DeepEqual(writer.names, want) {
+		t.Errorf("SaveItems() wrote %v, want %v", writer.names, want)
+	}
+
+	// A corrupt folder file is set aside and the rest of the tree still loads
+	if err := os.WriteFile(filepath.Join(dir, "folder2.json"), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var skipped []SkippedCollection
+	eager := NewCollectionStorage(dir)
+	eager.SetSkipHandler(func(s SkippedCollection) { skipped = append(skipped, s) })
+	var loaded RequestsConfig
+	if err := eager.Eager().Load("", &loaded); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if want := []string{"folder0", "folder1"}; !reflect.DeepEqual(loaded.RootOrder, want) {
+		t.Errorf("Load() root order = %v, want %v", loaded.RootOrder, want)
+	}
+	if loaded.Values["req150"].Name != "renamed" || len(loaded.Values) != 202 {
+		t.Errorf("Load() returned %d items, req150 = %q", len(loaded.Values), loaded.Values["req150"].Name)
+	}
+	if err := Validate(&loaded); err != nil {
+		t.Errorf("recovered config should be valid, got %v", err)
+	}
+	if backups, _ := filepath.Glob(filepath.Join(dir, "folder2.json.corrupt-*")); len(backups) != 1 {
+		t.Errorf("corrupt file should be kept as a backup, found %v", backups)
+	}
+	if len(skipped) != 1 || skipped[0].FolderID != "folder2" {
+		t.Errorf("skipped = %+v, want folder2 reported", skipped)
+	}
+}
+
+func TestCollectionStorageLoadsFoldersLazily(t *testing.T) {
+	dir := t.TempDir()
+	if err := NewCollectionStorage(dir).Save("", largeConfig(300)); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "folder2.json"), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	writer := &recordingWriter{}
+	cs := NewCollectionStorageWithWriter(dir, writer)
+	var skipped []SkippedCollection
+	cs.SetSkipHandler(func(s SkippedCollection) { skipped = append(skipped, s) })
+
+	// Only the index is read: the root folders are listed without their children
+	var cfg RequestsConfig
+	if err := cs.Load("", &cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if want := []string{"folder0", "folder1", "folder2"}; !reflect.DeepEqual(cfg.RootOrder, want) || !reflect.DeepEqual(cs.Unloaded(), want) {
+		t.Fatalf("Load() root order = %v, unloaded = %v, want %v for both", cfg.RootOrder, cs.Unloaded(), want)
+	}
+	if folder := cfg.Values["folder1"]; len(cfg.Values) != 3 || folder.Name != "folder1" || folder.Children != nil {
+		t.Errorf("Load() returned %d items, folder1 = %+v, want the root folders without children", len(cfg.Values), folder)
+	}
+	if err := Validate(&cfg); err != nil {
+		t.Errorf("the config listing unread folders should be valid, got %v", err)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("Load() read a folder file: skipped = %+v", skipped)
+	}
+
+	items, err := cs.LoadFolder("folder1")
+	if err != nil {
+		t.Fatalf("LoadFolder() error = %v", err)
+	}
+	if len(items) != 101 || len(items["folder1"].Children) != 100 {
+		t.Errorf("LoadFolder() returned %d items, want folder1 and its 100 requests", len(items))
+	}
+	if again, err := cs.LoadFolder("folder1"); again != nil || err != nil {
+		t.Errorf("LoadFolder() of a loaded folder = %d items, %v, want nothing", len(again), err)
+	}
+	for id, item := range items {
+		cfg.Values[id] = item
+	}
+
+	// A save leaves the files of unread folders alone, even though the config only lists them
+	item := cfg.Values["req150"]
+	item.Name = "renamed"
+	cfg.Values["req150"] = item
+	if err := cs.Save("", &cfg); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	sort.Strings(writer.names)
+	if want := []string{"folder1.json"}; !reflect.DeepEqual(writer.names, want) {
+		t.Errorf("Save() wrote %v, want %v", writer.names, want)
+	}
+
+	// The corrupt file is only found once the folder is read
+	if _, err := cs.LoadFolder("folder2"); err == nil {
+		t.Error("LoadFolder() of a corrupt file succeeded")
+	}
+	if len(skipped) != 1 || skipped[0].FolderID != "folder2" {
+		t.Errorf("skipped = %+v, want folder2 reported", skipped)
+	}
+	if want := []string{"folder0"}; !reflect.DeepEqual(cs.Unloaded(), want) {
+		t.Errorf("Unloaded() = %v, want %v", cs.Unloaded(), want)
+	}
+
+	var full RequestsConfig
+	if err := NewCollectionStorage(dir).Eager().Load("", &full); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(full.Values) != 202 || full.Values["req150"].Name != "renamed" || len(full.Values["folder0"].Children) != 100 {
+		t.Errorf("Load() returned %d items, want folder0 intact and the rename of req150", len(full.Values))
+	}
+}
+
+func TestManagerLoadsFoldersOnDemand(t *testing.T) {
+	useTempDataDir(t)
+	if err := NewCollectionStorage(CollectionsDir()).Save("", largeConfig(300)); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManagerWithWriter(storage.NewFileWriter())
+	m.SetAuditLog(nil)
+	m.UseStorage(NewCollectionStorage(CollectionsDir()))
+	if err := m.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer m.Close()
+
+	cfg, revision := m.Snapshot()
+	if len(cfg.Values) != 3 || len(m.UnloadedFolders()) != 3 {
+		t.Fatalf("Snapshot() has %d items, %d folders unloaded, want only the 3 root folders", len(cfg.Values), len(m.UnloadedFolders()))
+	}
+
+	if err := m.LoadFolder("folder0"); err != nil {
+		t.Fatalf("LoadFolder() error = %v", err)
+	}
+	cfg, _ = m.Snapshot()
+	if len(cfg.Values) != 103 || m.Revision() != revision {
+		t.Errorf("after LoadFolder() %d items at revision %d, want 103 at revision %d", len(cfg.Values), m.Revision(), revision)
+	}
+
+	// Editing a request or a root folder reads no more than that folder
+	item := cfg.Values["req5"]
+	item.Name = "renamed"
+	if err := m.AtRevision(revision).PatchValues(map[string]Item{"req5": item}); err != nil {
+		t.Fatalf("PatchValues() error = %v", err)
+	}
+	if err := m.SetDescription("folder1", "The first folder"); err != nil {
+		t.Fatalf("SetDescription() error = %v", err)
+	}
+	if unloaded := m.UnloadedFolders(); !reflect.DeepEqual(unloaded, []string{"folder2"}) {
+		t.Errorf("UnloadedFolders() = %v, want [folder2]", unloaded)
+	}
+	if children := m.GetConfig().Values["folder1"].Children; len(children) != 100 {
+		t.Errorf("edited folder has %d children, want its contents read first", len(children))
+	}
+	if err := m.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	// Whoever needs the whole tree gets it
+	if full := m.GetRequestsConfig(); len(full.Values) != 303 || len(m.UnloadedFolders()) != 0 {
+		t.Errorf("GetRequestsConfig() has %d items, %v unloaded, want all 303", len(full.Values), m.UnloadedFolders())
+	}
+	var saved RequestsConfig
+	if err := NewCollectionStorage(CollectionsDir()).Eager().Load("", &saved); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Values) != 303 || saved.Values["req5"].Name != "renamed" || saved.Values["folder1"].Description != "The first folder" {
+		t.Errorf("saved %d items, req5 = %q, folder1 = %q", len(saved.Values), saved.Values["req5"].Name, saved.Values["folder1"].Description)
+	}
+}
+
+func TestCollectionStorageMovesFolders(t *testing.T) {
+	dir := t.TempDir()
+	cs := NewCollectionStorage(dir)
+
+	cfg := largeConfig(200)
+	if err := cs.Save("", cfg); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Nest folder1 inside folder0: its file goes away and its items join folder0.json
+	folder0 := cfg.Values["folder0"]
+	folder0.Children = append(folder0.Children, "folder1")
+	cfg.Values["folder0"] = folder0
+	cfg.RootOrder = []string{"folder0"}
+	document, items := splitItems(cfg, []string{"folder0"})
+	if err := cs.SaveItems("", document, items); err != nil {
+		t.Fatalf("SaveItems() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "folder1.json")); !os.IsNotExist(err) {
+		t.Errorf("file of a folder that is no longer at the root should be removed, stat error = %v", err)
+	}
+	var loaded RequestsConfig
+	if err := NewCollectionStorage(dir).Eager().Load("", &loaded); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(loaded.Values) != len(cfg.Values) {
+		t.Errorf("Load() returned %d items, want %d", len(loaded.Values), len(cfg.Values))
+	}
+}
+
+func TestManagerRecordsAndRevertsEdits(t *testing.T) {
+	useTempDataDir(t)
+	if err := Save(largeConfig(2)); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(storage.NewStorageCoordinator(storage.NewFileStorage(), nil, nil))
+	if err := m.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer m.Close()
+
+	item := m.GetRequestsConfig().Values["req0"]
+	original := item
+	item.Path = "/v2/users"
+	item.Headers = nil
+	if err := m.PatchValues(map[string]Item{"req0": item}); err != nil {
+		t.Fatalf("PatchValues() error = %v", err)
+	}
+	if err := m.MarkUsed(map[string]time.Time{"req0": time.Now()}); err != nil {
+		t.Fatalf("MarkUsed() error = %v", err)
+	}
+
+	history, err := m.History("req0")
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(history) != 1 || history[0].Action != AuditUpdated || history[0].Actor == "" {
+		t.Fatalf("History() = %+v, want one edit (sending is not an edit)", history)
+	}
+	changes := history[0].Changes
+	if len(changes) != 2 || changes[0].Field != "headers" || changes[0].New != nil || changes[1].Field != "path" || string(changes[1].New) != `"/v2/users"` {
+		t.Errorf("History() changes = %+v", changes)
+	}
+
+	// A later edit of the same field blocks reverting the earlier one
+	item.Path = "/v3/users"
+	if err := m.PatchValues(map[string]Item{"req0": item}); err != nil {
+		t.Fatalf("PatchValues() error = %v", err)
+	}
+	if err := m.RevertEdit(history[0].ID); err == nil {
+		t.Fatal("RevertEdit() reverted over a later edit")
+	}
+
+	history, _ = m.History("req0")
+	if err := m.RevertEdit(history[1].ID); err != nil {
+		t.Fatalf("RevertEdit() error = %v", err)
+	}
+	if err := m.RevertEdit(history[0].ID); err != nil {
+		t.Fatalf("RevertEdit() error = %v", err)
+	}
+	reverted := m.GetRequestsConfig().Values["req0"]
+	if reverted.Path != original.Path || len(reverted.Headers) != 1 || reverted.Headers[0] != original.Headers[0] {
+		t.Errorf("after reverting item = %+v, want %+v", reverted, original)
+	}
+	if history, _ = m.History("req0"); len(history) != 4 {
+		t.Errorf("History() has %d entries, want reverts recorded as edits", len(history))
+	}
+}
+
+func TestRequestExamples(t *testing.T) {
+	item := Item{
+		Type:        ItemTypeRequest,
+		Name:        "Create user",
+		Method:      "POST",
+		Path:        "/users",
+		Body:        `{"name":"default"}`,
+		QueryParams: []Param{{Key: "dryRun", Value: "false"}},
+		Headers:     []Header{{Key: "Content-Type", Value: "application/json"}},
+		Examples: &Examples{
+			Requests: []RequestExample{{
+				Name:        "admin",
+				Body:        `{"name":"root","admin":true}`,
+				QueryParams: []Param{{Key: "dryRun", Value: "true"}, {Key: "notify", Value: "1"}},
+				Headers:     []Header{{Key: "content-type", Value: "application/vnd.api+json"}},
+			}},
+			Responses: []ResponseExample{{Name: "created", Request: "admin", Status: 201}},
+		},
+	}
+
+	got, err := WithExample(item, "admin")
+	if err != nil {
+		t.Fatalf("WithExample() error = %v", err)
+	}
+	if got.Body != `{"name":"root","admin":true}` {
+		t.Errorf("WithExample() body = %s", got.Body)
+	}
+	if len(got.QueryParams) != 2 || got.QueryParams[0].Value != "true" || got.QueryParams[1].Key != "notify" {
+		t.Errorf("WithExample() query params = %+v", got.QueryParams)
+	}
+	if len(got.Headers) != 1 || got.Headers[0].Value != "application/vnd.api+json" {
+		t.Errorf("WithExample() headers = %+v", got.Headers)
+	}
+	if item.Headers[0].Value != "application/json" || item.QueryParams[0].Value != "false" {
+		t.Error("WithExample() modified the original request")
+	}
+	if _, err := WithExample(item, "missing"); err == nil {
+		t.Error("WithExample() accepted an unknown example")
+	}
+
+	cfg := &RequestsConfig{Version: CurrentVersion, Values: map[string]Item{
+		"root": {Type: ItemTypeFolder, Name: "Users", Children: []string{"req"}},
+		"req":  item,
+	}}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	item.Examples.Responses = append(item.Examples.Responses, ResponseExample{Name: "created", Request: "guest", Status: 200})
+	cfg.Values["req"] = item
+	err = Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "more than once") || !strings.Contains(err.Error(), "unknown request example 'guest'") {
+		t.Errorf("Validate() error = %v, want duplicate and unknown example issues", err)
+	}
+}
+
+func TestManagerImportMergesDuplicates(t *testing.T) {
+	useTempDataDir(t)
+	if err := Save(largeConfig(2)); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(storage.NewStorageCoordinator(storage.NewFileStorage(), nil, nil))
+	if err := m.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer m.Close()
+
+	// folder0 holds req0 and req1 (POST /api/v1/items/reqN)
+	collection := func() []Node {
+		return []Node{{
+			Item: Item{Type: ItemTypeFolder, Name: "folder0"},
+			Children: []Node{
+				{Item: Item{Type: ItemTypeRequest, Name: "req0", Method: "post", Path: "/api/v1/items/req0", Body: "imported"}},
+				{Item: Item{Type: ItemTypeRequest, Name: "new", Method: "GET", Path: "/api/v1/items"}},
+			},
+		}}
+	}
+
+	summary, err := m.Import("", collection(), ImportOptions{Strategy: MergeSkip})
+	if err != nil {
+		t.Fatalf("Import(skip) error = %v", err)
+	}
+	if !reflect.DeepEqual(summary.IDs, []string{"folder0"}) || len(summary.Created) != 1 || !reflect.DeepEqual(summary.Skipped, []string{"req0"}) {
+		t.Errorf("Import(skip) summary = %+v", summary)
+	}
+	cfg := m.GetRequestsConfig()
+	if len(cfg.RootOrder) != 1 || len(cfg.Values["folder0"].Children) != 3 || cfg.Values["req0"].Body == "imported" {
+		t.Errorf("Import(skip) tree: roots %v, folder0 children %v", cfg.RootOrder, cfg.Values["folder0"].Children)
+	}
+
+	created := cfg.Values["req0"].CreatedAt
+	summary, err = m.Import("", collection(), ImportOptions{Strategy: MergeReplace})
+	if err != nil {
+		t.Fatalf("Import(replace) error = %v", err)
+	}
+	cfg = m.GetRequestsConfig()
+	if len(summary.Updated) != 2 || summary.Updated[0] != "req0" || len(summary.Created) != 0 || cfg.Values["req0"].Body != "imported" || !cfg.Values["req0"].CreatedAt.Equal(created) {
+		t.Errorf("Import(replace) summary = %+v, req0 = %+v", summary, cfg.Values["req0"])
+	}
+
+	summary, err = m.Import("folder0", collection()[0].Children, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import(keep both) error = %v", err)
+	}
+	cfg = m.GetRequestsConfig()
+	if len(summary.Created) != 2 || cfg.Values[summary.Created[0]].Name != "req0 (2)" || cfg.Values[summary.Created[1]].Name != "new (2)" {
+		t.Errorf("Import(keep both) summary = %+v", summary)
+	}
+
+	if _, err := m.Import("", collection(), ImportOptions{Strategy: "merge"}); err == nil {
+		t.Error("Import() accepted an unknown strategy")
+	}
+}
+
+func TestManagerImportContextReportsProgressAndCancels(t *testing.T) {
+	useTempDataDir(t)
+	if err := Save(largeConfig(2)); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(storage.NewStorageCoordinator(storage.NewFileStorage(), nil, nil))
+	if err := m.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer m.Close()
+
+	nodes := []Node{{
+		Item: Item{Type: ItemTypeFolder, Name: "Imported"},
+		Children: []Node{
+			{Item: Item{Type: ItemTypeRequest, Name: "one", Method: "GET", Path: "/one"}},
+			{Item: Item{Type: ItemTypeRequest, Name: "two", Method: "GET", Path: "/two"}},
+		},
+	}}
+
+	var reports []string
+	_, err := m.ImportContext(context.Background(), "", nodes, ImportOptions{}, func(done, total int, current string) {
+		reports = append(reports, fmt.Sprintf("%d/%d %s", done, total, current))
+	})
+	if err != nil {
+		t.Fatalf("ImportContext() error = %v", err)
+	}
+	if !reflect.DeepEqual(reports, []string{"3/3 Imported"}) {
+		t.Errorf("progress = %v, want the new folder counted with its requests", reports)
+	}
+
+	// Merged into the existing folder, every item is reported on its own
+	ctx, cancel := context.WithCancel(context.Background())
+	before := len(m.GetRequestsConfig().Values)
+	reports = nil
+	_, err = m.ImportContext(ctx, "", nodes, ImportOptions{}, func(done, total int, current string) {
+		reports = append(reports, current)
+		if done == 2 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ImportContext() error = %v, want context.Canceled", err)
+	}
+	if !reflect.DeepEqual(reports, []string{"Imported", "one (2)"}) {
+		t.Errorf("progress = %v, want it to stop at the cancellation", reports)
+	}
+	if after := len(m.GetRequestsConfig().Values); after != before {
+		t.Errorf("a cancelled import changed the tree from %d to %d items", before, after)
+	}
+}
+
+func TestManagerNotes(t *testing.T) {
+	useTempDataDir(t)
+	if err := Save(largeConfig(2)); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(storage.NewStorageCoordinator(storage.NewFileStorage(), nil, nil))
+	if err := m.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer m.Close()
+
+	note, err := m.AddNote("req0", "  returns 500 when the name is empty ", nil)
+	if err != nil {
+		t.Fatalf("AddNote() error = %v", err)
+	}
+	body := strings.Repeat("line of the response body\n", MaxExcerptBytes/20)
+	pinned, err := m.AddNote("req0", "", NewExcerpt(500, body))
+	if err != nil {
+		t.Fatalf("AddNote() with excerpt error = %v", err)
+	}
+	if !pinned.Excerpt.Truncated || len(pinned.Excerpt.Body) > MaxExcerptBytes || !strings.HasSuffix(pinned.Excerpt.Body, "body") {
+		t.Errorf("NewExcerpt() kept %d bytes, truncated = %v", len(pinned.Excerpt.Body), pinned.Excerpt.Truncated)
+	}
+	if _, err := m.AddNote("req0", " ", nil); err == nil {
+		t.Error("AddNote() accepted an empty note")
+	}
+	if _, err := m.AddNote("folder0", "text", nil); err == nil {
+		t.Error("AddNote() accepted a folder")
+	}
+
+	if err := m.UpdateNote("req0", note.ID, "fixed in v2"); err != nil {
+		t.Fatalf("UpdateNote() error = %v", err)
+	}
+	if err := m.DeleteNote("req0", pinned.ID); err != nil {
+		t.Fatalf("DeleteNote() error = %v", err)
+	}
+	notes := m.GetRequestsConfig().Values["req0"].Notes
+	if len(notes) != 1 || notes[0].Text != "fixed in v2" || notes[0].CreatedAt.IsZero() {
+		t.Errorf("notes = %+v", notes)
+	}
+	if err := m.DeleteNote("req0", pinned.ID); err == nil {
+		t.Error("DeleteNote() accepted an unknown note")
+	}
+}
+
+func TestLockedFolders(t *testing.T) {
+	useTempDataDir(t)
+	if err := Save(largeConfig(2)); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(storage.NewStorageCoordinator(storage.NewFileStorage(), nil, nil))
+	if err := m.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer m.Close()
+
+	if locked, err := m.ToggleLock("folder0", nil); err != nil || !locked {
+		t.Fatalf("ToggleLock() = %v, %v", locked, err)
+	}
+	edited := m.GetRequestsConfig().Values["req0"]
+	edited.Path = "/changed"
+	for name, err := range map[string]error{
+		"PatchValues": m.PatchValues(map[string]Item{"req0": edited}),
+		"AddRequest":  func() error { _, err := m.AddRequest("folder0", "New", "GET", "/new"); return err }(),
+		"DeleteItem":  m.DeleteItem("folder0"),
+	} {
+		if apperrors.CodeOf(err) != apperrors.Locked {
+			t.Errorf("%s() in a locked folder error = %v, want LOCKED", name, err)
+		}
+	}
+	if _, err := m.ToggleFavorite("req0"); err != nil {
+		t.Errorf("ToggleFavorite() in a locked folder error = %v", err)
+	}
+	if err := m.MarkUsed(map[string]time.Time{"req0": time.Now()}); err != nil {
+		t.Errorf("MarkUsed() in a locked folder error = %v", err)
+	}
+
+	unlock := false
+	if locked, err := m.ToggleLock("folder0", &unlock); err != nil || locked {
+		t.Fatalf("ToggleLock(false) = %v, %v", locked, err)
+	}
+	if err := m.PatchValues(map[string]Item{"req0": edited}); err != nil {
+		t.Errorf("PatchValues() after unlocking error = %v", err)
+	}
+	if _, err := m.ToggleLock("req0", nil); apperrors.CodeOf(err) != apperrors.NotFound {
+		t.Errorf("ToggleLock() on a request error = %v, want NOT_FOUND", err)
+	}
+}
+
+func TestFindOrphans(t *testing.T) {
+	cfg := &RequestsConfig{
+		Version:   CurrentVersion,
+		RootOrder: []string{"folder1"},
+		Values: map[string]Item{
+			"folder1": {Type: ItemTypeFolder, Name: "API", Children: []string{"req1"}},
+			"req1":    {Type: ItemTypeRequest, Name: "Listed", Method: "GET"},
+			"stray":   {Type: ItemTypeRequest, Name: "Stray", Method: "GET"},
+			"loopA":   {Type: ItemTypeFolder, Name: "A", Children: []string{"loopB"}},
+			"loopB":   {Type: ItemTypeFolder, Name: "B", Children: []string{"loopA", "req2"}},
+			"req2":    {Type: ItemTypeRequest, Name: "In a loop", Method: "GET"},
+		},
+	}
+
+	want := []Orphan{
+		{ID: "loopA", Name: "A", Type: ItemTypeFolder},
+		{ID: "loopB", Name: "B", Type: ItemTypeFolder, ParentID: "loopA"},
+		{ID: "req2", Name: "In a loop", Type: ItemTypeRequest, ParentID: "loopB"},
+		{ID: "stray", Name: "Stray", Type: ItemTypeRequest},
+	}
+	if got := FindOrphans(cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("FindOrphans() = %+v, want %+v", got, want)
+	}
+	for _, node := range ToNodes(cfg) {
+		if node.Item.Name != "API" {
+			t.Errorf("ToNodes() shows orphan %q at the root", node.Item.Name)
+		}
+	}
+}
+
+func TestAdoptAndPurgeOrphans(t *testing.T) {
+	useTempDataDir(t)
+	cfg := largeConfig(2)
+	cfg.Values["stray"] = Item{Type: ItemTypeRequest, Name: "Stray", Method: "GET", Path: "/stray"}
+	cfg.RootOrder = append(cfg.RootOrder, "stray")
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(storage.NewStorageCoordinator(storage.NewFileStorage(), nil, nil))
+	if err := m.Load(); err != nil {
+		t.Fatalf("Load() error = %v, want orphans loaded", err)
+	}
+	defer m.Close()
+
+	if orphans := m.FindOrphanItems(); len(orphans) != 1 || orphans[0].ID != "stray" {
+		t.Fatalf("FindOrphanItems() = %+v, want the stray request", orphans)
+	}
+	if _, err := m.AdoptOrphans("req0"); apperrors.CodeOf(err) != apperrors.NotFound {
+		t.Errorf("AdoptOrphans() into a request error = %v, want NOT_FOUND", err)
+	}
+	adopted, err := m.AdoptOrphans("folder0")
+	if err != nil || !reflect.DeepEqual(adopted, []string{"stray"}) {
+		t.Fatalf("AdoptOrphans() = %v, %v; want [stray]", adopted, err)
+	}
+	got := m.GetRequestsConfig()
+	if children := got.Values["folder0"].Children; children[len(children)-1] != "stray" {
+		t.Errorf("folder0 children = %v, want stray appended", children)
+	}
+	if !reflect.DeepEqual(got.RootOrder, []string{"folder0"}) {
+		t.Errorf("RootOrder = %v, want the request taken out", got.RootOrder)
+	}
+	if orphans := m.FindOrphanItems(); len(orphans) != 0 {
+		t.Errorf("FindOrphanItems() after adopting = %+v, want none", orphans)
+	}
+
+	err = m.UpdateConfig(func(cfg *RequestsConfig) error {
+		cfg.Values["stray2"] = Item{Type: ItemTypeRequest, Name: "Stray 2", Method: "GET", Path: "/stray"}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged, err := m.PurgeOrphans(); err != nil || purged != 1 {
+		t.Fatalf("PurgeOrphans() = %d, %v; want 1", purged, err)
+	}
+	if _, exists := m.GetRequestsConfig().Values["stray2"]; exists {
+		t.Error("PurgeOrphans() kept the orphan")
+	}
+}
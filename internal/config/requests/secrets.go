@@ -0,0 +1,103 @@
+package requests
+
+import "strings"
+
+// SecretMask replaces secret values wherever they are shown, logged or exported
+const SecretMask = "****"
+
+// sensitiveHeaders are header names that usually carry credentials
+var sensitiveHeaders = []string{
+	"authorization",
+	"proxy-authorization",
+	"cookie",
+	"set-cookie",
+	"x-api-key",
+	"api-key",
+	"x-auth-token",
+}
+
+// IsSensitiveHeader reports whether a header usually carries credentials
+func IsSensitiveHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range sensitiveHeaders {
+		if name == sensitive {
+			return true
+		}
+	}
+	return strings.Contains(name, "token") || strings.Contains(name, "secret")
+}
+
+// IsSecretHeader reports whether a header is marked secret or usually carries credentials
+func IsSecretHeader(h Header) bool {
+	return h.Secret || IsSensitiveHeader(h.Key)
+}
+
+// MaskSecrets returns a copy of cfg with the values of secret headers and secret folder variables
+// replaced by SecretMask, for exports that leave the app
+func MaskSecrets(cfg *RequestsConfig) *RequestsConfig {
+	masked := *cfg
+	masked.Values = make(map[string]Item, len(cfg.Values))
+	for id, item := range cfg.Values {
+		item = MaskHeaders(item, SecretMask)
+		if len(item.Variables) > 0 {
+			variables := make([]Param, len(item.Variables))
+			for i, v := range item.Variables {
+				if v.Secret {
+					v.Value = SecretMask
+				}
+				variables[i] = v
+			}
+			item.Variables = variables
+		}
+		masked.Values[id] = item
+	}
+	return &masked
+}
+
+// MaskHeaders returns a copy of item with the values of secret headers replaced by mask: those of
+// the request, of its request and response examples, and of its response snapshot (e.g. Set-Cookie)
+func MaskHeaders(item Item, mask string) Item {
+	item.Headers = maskHeaderList(item.Headers, mask)
+	if item.Examples != nil {
+		examples := Examples{
+			Requests:  make([]RequestExample, len(item.Examples.Requests)),
+			Responses: make([]ResponseExample, len(item.Examples.Responses)),
+		}
+		for i, example := range item.Examples.Requests {
+			example.Headers = maskHeaderList(example.Headers, mask)
+			examples.Requests[i] = example
+		}
+		for i, example := range item.Examples.Responses {
+			example.Headers = maskHeaderList(example.Headers, mask)
+			examples.Responses[i] = example
+		}
+		item.Examples = &examples
+	}
+	if item.Snapshot != nil && len(item.Snapshot.Headers) > 0 {
+		snapshot := *item.Snapshot
+		snapshot.Headers = make(map[string]string, len(item.Snapshot.Headers))
+		for key, value := range item.Snapshot.Headers {
+			if IsSensitiveHeader(key) {
+				value = mask
+			}
+			snapshot.Headers[key] = value
+		}
+		item.Snapshot = &snapshot
+	}
+	return item
+}
+
+// maskHeaderList returns a copy of headers with the values of secret ones replaced by mask
+func maskHeaderList(headers []Header, mask string) []Header {
+	if len(headers) == 0 {
+		return headers
+	}
+	masked := make([]Header, len(headers))
+	for i, h := range headers {
+		if IsSecretHeader(h) {
+			h.Value = mask
+		}
+		masked[i] = h
+	}
+	return masked
+}
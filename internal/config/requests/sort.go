@@ -0,0 +1,181 @@
+package requests
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"paperbox/internal/apperrors"
+)
+
+// SortKey selects what SortFolder orders children by
+type SortKey string
+
+const (
+	SortByName     SortKey = "name"
+	SortByMethod   SortKey = "method"
+	SortByLastUsed SortKey = "lastUsed"
+	SortByUpdated  SortKey = "updated"
+)
+
+// SortDirection is the order SortFolder applies
+type SortDirection string
+
+const (
+	SortAscending  SortDirection = "asc"
+	SortDescending SortDirection = "desc"
+)
+
+// SortFolder reorders a folder's children (or the root folders when folderId is empty).
+// Ties keep their current relative order.
+func (m *Manager) SortFolder(folderId string, by SortKey, direction SortDirection) error {
+	if by != SortByName && by != SortByMethod && by != SortByLastUsed && by != SortByUpdated {
+		return apperrors.Invalidf("unknown sort key %q", by)
+	}
+	if direction != SortAscending && direction != SortDescending {
+		return apperrors.Invalidf("unknown sort direction %q", direction)
+	}
+
+	return m.update(func(cfg *RequestsConfig) error {
+		if folderId == "" {
+			cfg.RootOrder = sortedIDs(cfg, rootIDs(cfg), by, direction)
+		} else {
+			folder, exists := cfg.Values[folderId]
+			if !exists || folder.Type != ItemTypeFolder {
+				return apperrors.NotFoundf("folder not found")
+			}
+			folder.Children = sortedIDs(cfg, folder.Children, by, direction)
+			cfg.Values[folderId] = folder
+		}
+
+		return nil
+	})
+}
+
+// MarkUsed records when requests were last sent; IDs that no longer exist are skipped
+func (m *Manager) MarkUsed(used map[string]time.Time) error {
+	return m.update(func(cfg *RequestsConfig) error {
+		for id, at := range used {
+			item, exists := cfg.Values[id]
+			if !exists || item.Type != ItemTypeRequest {
+				continue
+			}
+			at := at.UTC()
+			if item.LastUsedAt != nil && !at.After(*item.LastUsedAt) {
+				continue
+			}
+			item.LastUsedAt = &at
+			cfg.Values[id] = item
+		}
+
+		return nil
+	})
+}
+
+// DefaultRecentLimit is how many recent requests are returned when no limit is given
+const DefaultRecentLimit = 20
+
+// RecentRequest is a request together with when it was last sent
+type RecentRequest struct {
+	ID         string    `json:"id"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+}
+
+// RecentRequests returns up to limit requests, most recently sent first
+func RecentRequests(cfg *RequestsConfig, limit int) []RecentRequest {
+	if limit <= 0 {
+		limit = DefaultRecentLimit
+	}
+
+	recent := []RecentRequest{}
+	for id, item := range cfg.Values {
+		if item.Type == ItemTypeRequest && item.LastUsedAt != nil {
+			recent = append(recent, RecentRequest{ID: id, LastUsedAt: *item.LastUsedAt})
+		}
+	}
+	sort.Slice(recent, func(i, j int) bool {
+		if !recent[i].LastUsedAt.Equal(recent[j].LastUsedAt) {
+			return recent[i].LastUsedAt.After(recent[j].LastUsedAt)
+		}
+		return recent[i].ID < recent[j].ID
+	})
+
+	if len(recent) > limit {
+		recent = recent[:limit]
+	}
+	return recent
+}
+
+// sortedIDs returns a sorted copy of ids
+func sortedIDs(cfg *RequestsConfig, ids []string, by SortKey, direction SortDirection) []string {
+	sorted := append([]string(nil), ids...)
+	less := sortLess(cfg, by)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if direction == SortDescending {
+			return less(sorted[j], sorted[i])
+		}
+		return less(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+// sortLess returns the ascending comparison for a sort key
+func sortLess(cfg *RequestsConfig, by SortKey) func(a, b string) bool {
+	name := func(id string) string {
+		return strings.ToLower(cfg.Values[id].Name)
+	}
+
+	switch by {
+	case SortByMethod:
+		// Folders have no method and sort before requests
+		return func(a, b string) bool {
+			ma, mb := strings.ToUpper(cfg.Values[a].Method), strings.ToUpper(cfg.Values[b].Method)
+			if ma != mb {
+				return ma < mb
+			}
+			return name(a) < name(b)
+		}
+	case SortByLastUsed, SortByUpdated:
+		stamp := func(item Item) time.Time { return item.UpdatedAt }
+		if by == SortByLastUsed {
+			stamp = func(item Item) time.Time {
+				if item.LastUsedAt == nil {
+					return time.Time{}
+				}
+				return *item.LastUsedAt
+			}
+		}
+		memo := make(map[string]time.Time)
+		return func(a, b string) bool {
+			ta, tb := latest(cfg, a, stamp, memo), latest(cfg, b, stamp, memo)
+			if !ta.Equal(tb) {
+				return ta.Before(tb)
+			}
+			return name(a) < name(b)
+		}
+	default:
+		return func(a, b string) bool {
+			return name(a) < name(b)
+		}
+	}
+}
+
+// latest returns an item's timestamp; for folders, the most recent one of the folder and
+// everything inside it. Results are memoized in memo.
+func latest(cfg *RequestsConfig, id string, stamp func(Item) time.Time, memo map[string]time.Time) time.Time {
+	if t, ok := memo[id]; ok {
+		return t
+	}
+	memo[id] = time.Time{} // guards against reference cycles
+
+	item := cfg.Values[id]
+	newest := stamp(item)
+	for _, childID := range item.Children {
+		if t := latest(cfg, childID, stamp, memo); t.After(newest) {
+			newest = t
+		}
+	}
+
+	memo[id] = newest
+	return newest
+}
@@ -0,0 +1,94 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// encodeItems returns each item's JSON encoding, used to detect which items an update touched
+func encodeItems(values map[string]Item) map[string][]byte {
+	encoded := make(map[string][]byte, len(values))
+	for id, item := range values {
+		encoded[id], _ = json.Marshal(item)
+	}
+	return encoded
+}
+
+// fingerprint encodes an item with its timestamps cleared, so that only user-visible
+// changes (not being sent) count as modifications
+func fingerprint(item Item) []byte {
+	item.CreatedAt = time.Time{}
+	item.UpdatedAt = time.Time{}
+	item.LastUsedAt = nil
+	data, _ := json.Marshal(item)
+	return data
+}
+
+// stampItems sets CreatedAt on items missing from before (or lacking one) and UpdatedAt on
+// every item whose content differs from before, where before holds encodings from encodeItems.
+// A nil before stamps only unset timestamps. It returns the IDs of items that were added or
+// changed in any way (including timestamps only), i.e. the ones that need saving.
+func stampItems(values map[string]Item, before map[string][]byte, now time.Time) []string {
+	now = now.UTC()
+	var changedIDs []string
+	for id, item := range values {
+		changed := false
+		previous, existed := before[id]
+		if before != nil && !existed {
+			item.CreatedAt, item.UpdatedAt = now, now
+			changed = true
+		} else if existed {
+			current, _ := json.Marshal(item)
+			if !bytes.Equal(previous, current) {
+				changed = true
+				var old Item
+				if json.Unmarshal(previous, &old) != nil || !bytes.Equal(fingerprint(old), fingerprint(item)) {
+					item.UpdatedAt = now
+				}
+			}
+		}
+
+		if item.CreatedAt.IsZero() {
+			item.CreatedAt = now
+			changed = true
+		}
+		if item.UpdatedAt.IsZero() {
+			item.UpdatedAt = item.CreatedAt
+			changed = true
+		}
+		if changed {
+			values[id] = item
+			changedIDs = append(changedIDs, id)
+		}
+	}
+	return changedIDs
+}
+
+// removedItems returns the IDs present in before but no longer in values
+func removedItems(values map[string]Item, before map[string][]byte) []string {
+	var removed []string
+	for id := range before {
+		if _, ok := values[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	return removed
+}
+
+// splitItems separates a config into its document (without items) and the listed items,
+// nil for those that were removed; used for item-wise saves
+func splitItems(cfg *RequestsConfig, ids []string) (interface{}, map[string]interface{}) {
+	document := *cfg
+	document.Values = nil
+
+	items := make(map[string]interface{}, len(ids))
+	for _, id := range ids {
+		if item, ok := cfg.Values[id]; ok {
+			items[id] = item
+		} else {
+			items[id] = nil
+		}
+	}
+	return &document, items
+}
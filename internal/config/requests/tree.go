@@ -0,0 +1,252 @@
+package requests
+
+import (
+	"fmt"
+	"sort"
+
+	"paperbox/internal/apperrors"
+
+	"github.com/google/uuid"
+)
+
+// Node is an item together with its nested children, used to insert whole subtrees at once (imports)
+type Node struct {
+	Item     Item   `json:"item"`
+	Children []Node `json:"children,omitempty"`
+}
+
+// AddTree inserts the given nodes (and their descendants) under a parent folder in a single update.
+// An empty parentId inserts the nodes as root folders. Folders that would exceed MaxFolderDepth are
+// flattened into their deepest allowed ancestor. Returns the IDs of the top-level inserted nodes.
+func (m *Manager) AddTree(parentId string, nodes []Node) ([]string, error) {
+	var ids []string
+
+	err := m.update(func(cfg *RequestsConfig) error {
+		if cfg.Values == nil {
+			cfg.Values = make(map[string]Item)
+		}
+
+		// Imported trees may be deeper than the configured limit
+		depth := 0
+		if parentId != "" {
+			depth = folderDepth(cfg, parentId) + 1
+		}
+		nodes := flattenNodes(nodes, depth, MaxFolderDepth())
+
+		ids = make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			ids = append(ids, insertNode(cfg, node, nil))
+		}
+
+		if parentId == "" {
+			for _, id := range ids {
+				if cfg.Values[id].Type != ItemTypeFolder {
+					return fmt.Errorf("root level item '%s' must be a folder", cfg.Values[id].Name)
+				}
+			}
+			cfg.RootOrder = append(cfg.RootOrder, ids...)
+		} else {
+			parent, exists := cfg.Values[parentId]
+			if !exists || parent.Type != ItemTypeFolder {
+				return apperrors.NotFoundf("parent folder not found")
+			}
+			parent.Children = append(parent.Children, ids...)
+			cfg.Values[parentId] = parent
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// insertNode stores the node and its descendants under fresh IDs and returns the node's ID.
+// The new IDs are appended to created unless it is nil.
+func insertNode(cfg *RequestsConfig, node Node, created *[]string) string {
+	id := uuid.New().String()
+	if created != nil {
+		*created = append(*created, id)
+	}
+	item := node.Item
+
+	if item.Type == ItemTypeFolder {
+		item.Children = make([]string, 0, len(node.Children))
+		for _, child := range node.Children {
+			item.Children = append(item.Children, insertNode(cfg, child, created))
+		}
+	} else {
+		item.Children = nil
+	}
+
+	cfg.Values[id] = item
+	return id
+}
+
+// ToNodes converts a flat config into a tree of root folder nodes, ordered by RootOrder
+// (root folders missing from RootOrder are appended afterwards)
+func ToNodes(cfg *RequestsConfig) []Node {
+	ids := rootIDs(cfg)
+
+	visited := make(map[string]bool)
+	nodes := make([]Node, 0, len(ids))
+	for _, id := range ids {
+		nodes = append(nodes, buildNode(cfg, id, visited))
+	}
+	return nodes
+}
+
+// ToNode converts one item and its descendants into a node
+func ToNode(cfg *RequestsConfig, id string) (Node, bool) {
+	if _, ok := cfg.Values[id]; !ok {
+		return Node{}, false
+	}
+	return buildNode(cfg, id, make(map[string]bool)), true
+}
+
+// rootIDs returns the root folder IDs ordered by RootOrder, followed by unlisted roots sorted by
+// ID. Other items at the root are orphans (see FindOrphans) and left out.
+func rootIDs(cfg *RequestsConfig) []string {
+	referenced := make(map[string]bool)
+	for _, item := range cfg.Values {
+		for _, childID := range item.Children {
+			referenced[childID] = true
+		}
+	}
+
+	var ids []string
+	listed := make(map[string]bool)
+	for _, id := range cfg.RootOrder {
+		if item, exists := cfg.Values[id]; exists && item.Type == ItemTypeFolder && !listed[id] {
+			ids = append(ids, id)
+			listed[id] = true
+		}
+	}
+	var unlisted []string
+	for id, item := range cfg.Values {
+		if !referenced[id] && !listed[id] && item.Type == ItemTypeFolder {
+			unlisted = append(unlisted, id)
+		}
+	}
+	sort.Strings(unlisted)
+	return append(ids, unlisted...)
+}
+
+// buildNode converts an item and its descendants into a node; visited guards against cycles
+func buildNode(cfg *RequestsConfig, id string, visited map[string]bool) Node {
+	visited[id] = true
+	item := cfg.Values[id]
+	node := Node{Item: item}
+	node.Item.Children = nil
+
+	for _, childID := range item.Children {
+		if _, exists := cfg.Values[childID]; !exists || visited[childID] {
+			continue
+		}
+		node.Children = append(node.Children, buildNode(cfg, childID, visited))
+	}
+	return node
+}
+
+// Ancestors returns the folder IDs containing itemID, nearest parent first
+func Ancestors(cfg *RequestsConfig, itemID string) []string {
+	parents := make(map[string]string)
+	for id, item := range cfg.Values {
+		for _, childID := range item.Children {
+			parents[childID] = id
+		}
+	}
+
+	var chain []string
+	seen := map[string]bool{itemID: true}
+	for current := itemID; ; {
+		parent, ok := parents[current]
+		if !ok || seen[parent] {
+			return chain
+		}
+		chain = append(chain, parent)
+		seen[parent] = true
+		current = parent
+	}
+}
+
+// RequestsUnder returns the IDs of all requests below a folder, depth-first in child order
+func RequestsUnder(cfg *RequestsConfig, folderID string) []string {
+	var ids []string
+	visited := make(map[string]bool)
+
+	var walk func(id string)
+	walk = func(id string) {
+		if visited[id] {
+			return
+		}
+		visited[id] = true
+
+		item, exists := cfg.Values[id]
+		if !exists {
+			return
+		}
+		if item.Type == ItemTypeRequest {
+			ids = append(ids, id)
+			return
+		}
+		for _, childID := range item.Children {
+			walk(childID)
+		}
+	}
+	walk(folderID)
+
+	return ids
+}
+
+// FavoritesFolderName is the name of the virtual folder listing favorite items
+const FavoritesFolderName = "Favorites"
+
+// ItemsWithTag returns the IDs of items carrying tag, in tree order
+func ItemsWithTag(cfg *RequestsConfig, tag string) []string {
+	return collect(cfg, func(item Item) bool {
+		for _, t := range item.Tags {
+			if t == tag {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// FavoritesFolder returns a virtual (never stored) folder whose children are the favorite items in tree order
+func FavoritesFolder(cfg *RequestsConfig) Item {
+	return Item{
+		Type:     ItemTypeFolder,
+		Name:     FavoritesFolderName,
+		Children: collect(cfg, func(item Item) bool { return item.Favorite }),
+	}
+}
+
+// collect returns the IDs of items matching keep, walking the tree depth-first in display order
+func collect(cfg *RequestsConfig, keep func(Item) bool) []string {
+	ids := []string{}
+	visited := make(map[string]bool)
+
+	var walk func(id string)
+	walk = func(id string) {
+		item, exists := cfg.Values[id]
+		if !exists || visited[id] {
+			return
+		}
+		visited[id] = true
+		if keep(item) {
+			ids = append(ids, id)
+		}
+		for _, childID := range item.Children {
+			walk(childID)
+		}
+	}
+
+	for _, node := range rootIDs(cfg) {
+		walk(node)
+	}
+	return ids
+}
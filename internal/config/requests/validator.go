@@ -1,247 +1,509 @@
 package requests
 
 import (
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
 
-// Validate validates the requests configuration
+// pathVarNamePattern matches valid path variable names
+var pathVarNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// tagPattern matches valid tags: lowercase letters, digits, "-" and "_", up to 32 characters
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_\-]{0,31}$`)
+
+// Severity describes how serious a validation issue is
+type Severity string
+
+const (
+	// SeverityError marks issues that make the config invalid
+	SeverityError Severity = "error"
+	// SeverityWarning marks suspicious but loadable configs
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a single validation problem, scoped to an item when ItemID is set
+type Issue struct {
+	ItemID   string   `json:"itemId,omitempty"`
+	Field    string   `json:"field,omitempty"`
+	Message  string   `json:"message"`
+	Severity Severity `json:"severity"`
+}
+
+// String renders the issue the way Validate reports it
+func (i Issue) String() string {
+	if i.ItemID == "" {
+		return i.Message
+	}
+	return fmt.Sprintf("item %s: %s", i.ItemID, i.Message)
+}
+
+// ValidationError is returned by Validate and carries every error-level issue
+type ValidationError struct {
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		messages = append(messages, issue.String())
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Validate validates the requests configuration and fails if any error-level issue is found
 func Validate(config *RequestsConfig) error {
 	if config == nil {
 		return fmt.Errorf("config is nil")
 	}
 
+	var errs []Issue
+	for _, issue := range ValidateAll(config) {
+		if issue.Severity == SeverityError {
+			errs = append(errs, issue)
+		}
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Issues: errs}
+	}
+	return nil
+}
+
+// ValidateAll checks the whole configuration and returns every issue found, ordered by item ID
+func ValidateAll(config *RequestsConfig) []Issue {
+	if config == nil {
+		return []Issue{{Message: "config is nil", Severity: SeverityError}}
+	}
+
+	var issues []Issue
+
 	// Validate basic structure using validator
 	if err := validate.Struct(config); err != nil {
-		return formatValidationError(err)
+		issues = append(issues, structIssues(err)...)
 	}
 
-	// Validate type-specific rules first (before checking references)
-	for id, item := range config.Values {
-		if err := validateItemTypeSpecificRules(item); err != nil {
-			return fmt.Errorf("item %s: %w", id, err)
-		}
+	ids := make([]string, 0, len(config.Values))
+	for id := range config.Values {
+		ids = append(ids, id)
 	}
+	sort.Strings(ids)
 
-	// Validate business logic that can't be expressed in tags
-	// This combines reference validation and root level validation in a single pass
-	if err := validateReferencesAndRootLevel(config.Values); err != nil {
-		return err
+	// Validate type-specific rules for every item
+	for _, id := range ids {
+		issues = append(issues, itemIssues(id, config.Values[id])...)
 	}
 
+	// Validate business logic that can't be expressed in tags
+	issues = append(issues, referenceIssues(ids, config.Values)...)
+
+	// Cycles make depth meaningless, so only measure depth on an acyclic tree
+	cycles := cycleIssues(ids, config.Values)
+	issues = append(issues, cycles...)
+	issues = append(issues, sharedChildIssues(ids, config.Values)...)
+
 	// Validate maximum nesting depth (3 folders: root -> nested -> nested -> request)
-	if err := validateMaxNestingDepth(config.Values); err != nil {
-		return err
+	if len(cycles) == 0 {
+		issues = append(issues, nestingIssues(ids, config.Values, MaxFolderDepth())...)
 	}
 
-	return nil
+	sort.SliceStable(issues, func(a, b int) bool {
+		return issues[a].ItemID < issues[b].ItemID
+	})
+	return issues
 }
 
-// validateHTTPMethod validates that the method is a valid HTTP method
+// itemErrors returns the error-level issues of a single item, without those of the tree it is in
+// (references, cycles and depth)
+func itemErrors(id string, item Item) []Issue {
+	var issues []Issue
+	if err := validate.Struct(item); err != nil {
+		for _, issue := range structIssues(err) {
+			issue.ItemID = id
+			issues = append(issues, issue)
+		}
+	}
+	for _, issue := range itemIssues(id, item) {
+		if issue.Severity == SeverityError {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// HTTPMethods are the methods a request may use (matched case-insensitively); SetCustomMethods
+// allows more
+var HTTPMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "CONNECT", "TRACE"}
+
+// validateHTTPMethod validates that the method is a standard or an allowed custom HTTP method
 func validateHTTPMethod(fl validator.FieldLevel) bool {
 	method := fl.Field().String()
 	if method == "" {
 		return true // Empty is allowed (omitempty handles this)
 	}
 
-	validMethods := map[string]bool{
-		"GET":     true,
-		"POST":    true,
-		"PUT":     true,
-		"PATCH":   true,
-		"DELETE":  true,
-		"HEAD":    true,
-		"OPTIONS": true,
-		"CONNECT": true,
-		"TRACE":   true,
-	}
+	return IsAllowedMethod(method)
+}
+
+// pluginAuthPattern matches auth types provided by plugins
+var pluginAuthPattern = regexp.MustCompile(`^` + PluginAuthPrefix + `[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+$`)
 
-	return validMethods[strings.ToUpper(method)]
+// validateAuthType accepts the built-in auth types and plugin schemes
+func validateAuthType(fl validator.FieldLevel) bool {
+	switch authType := AuthType(fl.Field().String()); authType {
+	case AuthTypeInherit, AuthTypeNone, AuthTypeBasic, AuthTypeBearer, AuthTypeAPIKey, AuthTypeNTLM, AuthTypeNegotiate:
+		return true
+	default:
+		return pluginAuthPattern.MatchString(string(authType))
+	}
 }
 
-// validateItemTypeSpecificRules validates rules that depend on item type
-func validateItemTypeSpecificRules(item Item) error {
+// itemIssues validates rules that depend on item type
+func itemIssues(id string, item Item) []Issue {
+	var issues []Issue
+	add := func(field, message string) {
+		issues = append(issues, Issue{ItemID: id, Field: field, Message: message, Severity: SeverityError})
+	}
+
+	if msg := authIssue(item.Auth); msg != "" {
+		add("auth", msg)
+	}
+	for _, msg := range tagIssues(item.Tags) {
+		add("tags", msg)
+	}
+
 	switch item.Type {
 	case ItemTypeRequest:
 		// Request must have method
 		if item.Method == "" {
-			return fmt.Errorf("request must have a method")
+			add("method", "request must have a method")
+		}
+
+		// Request without a path can still be sent against the base URL
+		if item.Path == "" {
+			issues = append(issues, Issue{ItemID: id, Field: "path", Message: "request has no path", Severity: SeverityWarning})
 		}
 
 		// Request must not have children
 		if len(item.Children) > 0 {
-			return fmt.Errorf("request cannot have children")
+			add("children", "request cannot have children")
+		}
+
+		// Form fields are the body, so a request has one or the other
+		if len(item.FormFields) > 0 && (item.Body != "" || item.Protobuf != nil) {
+			add("formFields", "request cannot have both form fields and a body")
+		}
+
+		// Response schema must be a JSON document
+		if item.ResponseSchema != "" && !json.Valid([]byte(item.ResponseSchema)) {
+			add("responseSchema", "response schema must be valid JSON")
+		}
+
+		// Path variables must be valid, unique identifiers
+		for _, msg := range pathVarIssues(item.PathVars) {
+			add("pathVars", msg)
+		}
+
+		// Example names must be unique and responses must answer existing request examples
+		for _, msg := range exampleIssues(item.Examples) {
+			add("examples", msg)
+		}
+
+		// Note IDs must be unique and every note must say something
+		for _, msg := range noteIssues(item.Notes) {
+			add("notes", msg)
+		}
+
+		// Only folders are linked to an OpenAPI spec or define variables
+		if item.Spec != "" {
+			add("spec", "request cannot link an OpenAPI spec")
+		}
+		if len(item.Variables) > 0 {
+			add("variables", "request cannot define variables")
+		}
+		if item.Locked {
+			add("locked", "only folders can be locked")
 		}
 
 	case ItemTypeFolder:
 		// Folder must not have method
 		if item.Method != "" {
-			return fmt.Errorf("folder cannot have a method")
+			add("method", "folder cannot have a method")
 		}
 
 		// Folder must not have path
 		if item.Path != "" {
-			return fmt.Errorf("folder cannot have a path")
+			add("path", "folder cannot have a path")
 		}
-	}
 
-	return nil
-}
+		// Folder must not have headers or body
+		if len(item.Headers) > 0 || item.Body != "" || len(item.FormFields) > 0 {
+			add("headers", "folder cannot have headers or a body")
+		}
 
-// validateReferencesAndRootLevel validates references and root level items efficiently
-// Time complexity: O(n*m) where n is number of items, m is average number of children
-// Space complexity: O(n) for the referencedIDs map
-func validateReferencesAndRootLevel(allItems map[string]Item) error {
-	// Collect all referenced child IDs in a single pass
-	referencedIDs := make(map[string]bool)
-
-	// First pass: collect all referenced IDs and check for circular references
-	for id, item := range allItems {
-		if item.Children != nil {
-			for _, childID := range item.Children {
-				// Check for circular reference (item cannot reference itself)
-				if childID == id {
-					return fmt.Errorf("circular reference detected: item '%s' references itself", id)
-				}
-				referencedIDs[childID] = true
+		// Folder must not have query parameters or path variables
+		if len(item.QueryParams) > 0 || len(item.PathVars) > 0 {
+			add("queryParams", "folder cannot have query parameters or path variables")
+		}
+
+		// Folder must not have a response schema, captures, assertions or a snapshot
+		if item.ResponseSchema != "" || len(item.Captures) > 0 || len(item.Assertions) > 0 || item.Snapshot != nil {
+			add("responseSchema", "folder cannot have a response schema, captures, assertions or a snapshot")
+		}
+
+		// Folder must not have examples or notes
+		if item.Examples != nil {
+			add("examples", "folder cannot have examples")
+		}
+		if len(item.Notes) > 0 {
+			add("notes", "folder cannot have notes")
+		}
+
+		// Folder variables must be unique
+		seen := make(map[string]bool, len(item.Variables))
+		for _, v := range item.Variables {
+			if seen[v.Key] {
+				add("variables", fmt.Sprintf("variable '%s' is defined more than once", v.Key))
 			}
+			seen[v.Key] = true
 		}
 	}
 
-	// Second pass: check root level items (must be folders)
-	for id, item := range allItems {
-		if !referencedIDs[id] {
-			// This is a root level item - must be a folder
-			if item.Type != ItemTypeFolder {
-				return fmt.Errorf("root level item '%s' must be a folder, but got type '%s'", id, item.Type)
-			}
+	return issues
+}
+
+// authIssue reports a missing field required by the auth type
+func authIssue(auth *Auth) string {
+	if auth == nil {
+		return ""
+	}
+
+	switch auth.Type {
+	case AuthTypeBasic:
+		if auth.Username == "" {
+			return "basic auth requires a username"
+		}
+	case AuthTypeNTLM, AuthTypeNegotiate:
+		if auth.Username == "" {
+			return string(auth.Type) + " auth requires a username"
+		}
+	case AuthTypeBearer:
+		if auth.Token == "" {
+			return "bearer auth requires a token"
+		}
+	case AuthTypeAPIKey:
+		if auth.Key == "" {
+			return "API key auth requires a key name"
 		}
 	}
 
-	// Third pass: verify all referenced IDs exist
-	// This catches cases where a child ID is referenced but doesn't exist in allItems
-	for childID := range referencedIDs {
-		if _, exists := allItems[childID]; !exists {
-			return fmt.Errorf("child reference '%s' does not exist", childID)
+	return ""
+}
+
+// tagIssues reports malformed and duplicate tags
+func tagIssues(tags []string) []string {
+	var messages []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if !tagPattern.MatchString(tag) {
+			messages = append(messages, fmt.Sprintf("tag '%s' must be 1-32 lowercase letters, digits, '-' or '_'", tag))
+		} else if seen[tag] {
+			messages = append(messages, fmt.Sprintf("tag '%s' is used more than once", tag))
 		}
+		seen[tag] = true
 	}
+	return messages
+}
 
-	return nil
+// pathVarIssues reports path variable names that are not identifiers or are repeated
+func pathVarIssues(vars []Param) []string {
+	var messages []string
+	seen := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		if !pathVarNamePattern.MatchString(v.Key) {
+			messages = append(messages, fmt.Sprintf("path variable '%s' must contain only letters, digits and underscores", v.Key))
+		} else if seen[v.Key] {
+			messages = append(messages, fmt.Sprintf("path variable '%s' is defined more than once", v.Key))
+		}
+		seen[v.Key] = true
+	}
+	return messages
 }
 
-const (
-	// MaxFolderDepth is the maximum allowed depth of folder nesting
-	// Structure: root (level 0) -> nested (level 1) -> nested (level 2) -> request
-	// This means maximum 3 folders in the chain
-	MaxFolderDepth = 3
-)
+// referenceIDs collects every ID referenced as a child
+func referenceIDs(allItems map[string]Item) map[string]bool {
+	referenced := make(map[string]bool)
+	for _, item := range allItems {
+		for _, childID := range item.Children {
+			referenced[childID] = true
+		}
+	}
+	return referenced
+}
 
-// validateMaxNestingDepth validates that folder nesting doesn't exceed MaxFolderDepth
+// referenceIssues validates child references and root level items
 // Time complexity: O(n*m) where n is number of items, m is average number of children
-// Space complexity: O(n) for recursion stack and visited map
-func validateMaxNestingDepth(allItems map[string]Item) error {
-	// Find all root level items (not referenced as children)
-	referencedIDs := make(map[string]bool)
-	for _, item := range allItems {
-		if item.Children != nil {
-			for _, childID := range item.Children {
-				referencedIDs[childID] = true
+func referenceIssues(ids []string, allItems map[string]Item) []Issue {
+	var issues []Issue
+	referencedIDs := referenceIDs(allItems)
+
+	for _, id := range ids {
+		item := allItems[id]
+		for _, childID := range item.Children {
+			// Referenced child must exist
+			if _, exists := allItems[childID]; !exists {
+				issues = append(issues, Issue{ItemID: id, Field: "children", Message: fmt.Sprintf("child reference '%s' does not exist", childID), Severity: SeverityError})
 			}
 		}
+
+		// Only folders can be at the root; anything else is an orphan for AdoptOrphans or PurgeOrphans
+		if !referencedIDs[id] && item.Type != ItemTypeFolder {
+			issues = append(issues, Issue{ItemID: id, Field: "parent", Message: fmt.Sprintf("%s '%s' is not in any folder", item.Type, id), Severity: SeverityWarning})
+		}
 	}
 
-	// Track visited items to avoid infinite loops (defensive check)
-	visited := make(map[string]bool)
+	return issues
+}
+
+// DFS colors used by cycleIssues
+const (
+	white = iota // not visited yet
+	grey         // on the current DFS path
+	black        // fully explored
+)
+
+// cycleIssues finds reference cycles of any length and reports each with its full path
+// Time complexity: O(n+e) where n is number of items, e is number of child references
+func cycleIssues(ids []string, allItems map[string]Item) []Issue {
+	var issues []Issue
+	color := make(map[string]int, len(allItems))
+	var path []string
+
+	var visit func(id string)
+	visit = func(id string) {
+		color[id] = grey
+		path = append(path, id)
+
+		for _, childID := range allItems[id].Children {
+			if _, exists := allItems[childID]; !exists {
+				continue
+			}
 
-	// Validate depth starting from each root level folder
-	for id, item := range allItems {
-		if !referencedIDs[id] && item.Type == ItemTypeFolder {
-			if err := validateFolderDepth(id, allItems, 0, visited); err != nil {
-				return err
+			switch color[childID] {
+			case white:
+				visit(childID)
+			case grey:
+				start := len(path) - 1
+				for path[start] != childID {
+					start--
+				}
+				cycle := append(append([]string{}, path[start:]...), childID)
+				issues = append(issues, Issue{
+					ItemID:   childID,
+					Field:    "children",
+					Message:  fmt.Sprintf("circular reference detected: %s", strings.Join(cycle, " -> ")),
+					Severity: SeverityError,
+				})
 			}
 		}
+
+		path = path[:len(path)-1]
+		color[id] = black
+	}
+
+	for _, id := range ids {
+		if color[id] == white {
+			visit(id)
+		}
 	}
 
-	return nil
+	return issues
 }
 
-// validateFolderDepth recursively validates folder depth
-func validateFolderDepth(itemID string, allItems map[string]Item, currentDepth int, visited map[string]bool) error {
-	// Check for cycles (defensive check)
-	if visited[itemID] {
-		return fmt.Errorf("circular reference detected in folder depth validation for item '%s'", itemID)
+// sharedChildIssues reports items listed as a child of more than one parent
+func sharedChildIssues(ids []string, allItems map[string]Item) []Issue {
+	parents := make(map[string][]string)
+	for _, id := range ids {
+		for _, childID := range allItems[id].Children {
+			if childID == id {
+				continue
+			}
+			parents[childID] = append(parents[childID], id)
+		}
 	}
-	visited[itemID] = true
-	defer delete(visited, itemID)
 
-	item, exists := allItems[itemID]
-	if !exists {
-		return fmt.Errorf("item '%s' not found during depth validation", itemID)
+	var issues []Issue
+	for _, id := range ids {
+		if len(parents[id]) > 1 {
+			issues = append(issues, Issue{
+				ItemID:   id,
+				Field:    "children",
+				Message:  fmt.Sprintf("item '%s' is referenced by multiple parents: %s", id, strings.Join(parents[id], ", ")),
+				Severity: SeverityError,
+			})
+		}
 	}
 
-	// Only folders contribute to depth
-	if item.Type == ItemTypeFolder {
-		// Check if current depth exceeds maximum (depth 0, 1, 2 are allowed, 3+ is not)
-		// MaxFolderDepth = 3 means: root(0) -> nested(1) -> nested(2) -> request
-		if currentDepth >= MaxFolderDepth {
-			return fmt.Errorf("folder '%s' exceeds maximum nesting depth of %d levels", itemID, MaxFolderDepth)
-		}
+	return issues
+}
 
-		// Recursively check children
-		if item.Children != nil {
-			for _, childID := range item.Children {
-				childItem, exists := allItems[childID]
-				if !exists {
-					continue // Already validated in validateReferencesAndRootLevel
-				}
+// itemNamespace extracts the item ID from a validator namespace like "RequestsConfig.Values[id].Name"
+var itemNamespace = regexp.MustCompile(`\.Values\[([^\]]+)\]`)
 
-				// If child is a folder, increment depth; if it's a request, depth stays the same
-				nextDepth := currentDepth
-				if childItem.Type == ItemTypeFolder {
-					nextDepth = currentDepth + 1
-					// Check before recursing: if nextDepth would exceed max, this folder can't have folder children
-					if nextDepth >= MaxFolderDepth {
-						return fmt.Errorf("folder '%s' at depth %d cannot contain nested folders (maximum depth is %d)", itemID, currentDepth, MaxFolderDepth)
-					}
-				}
+// structIssues converts validator errors into issues
+func structIssues(err error) []Issue {
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return []Issue{{Message: err.Error(), Severity: SeverityError}}
+	}
 
-				if err := validateFolderDepth(childID, allItems, nextDepth, visited); err != nil {
-					return err
-				}
-			}
+	issues := make([]Issue, 0, len(validationErrors))
+	for _, validationError := range validationErrors {
+		issue := Issue{
+			Field:    lowerFirst(validationError.Field()),
+			Message:  fieldErrorMessage(validationError),
+			Severity: SeverityError,
 		}
+		if match := itemNamespace.FindStringSubmatch(validationError.Namespace()); match != nil {
+			issue.ItemID = match[1]
+		}
+		issues = append(issues, issue)
 	}
+	return issues
+}
 
-	return nil
+// fieldErrorMessage formats a single validator error into a readable string
+func fieldErrorMessage(validationError validator.FieldError) string {
+	field := validationError.Field()
+	param := validationError.Param()
+
+	switch tag := validationError.Tag(); tag {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "min":
+		return fmt.Sprintf("%s must be at least %s characters", field, param)
+	case "max":
+		return fmt.Sprintf("%s must be at most %s characters", field, param)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, param)
+	case "http_method":
+		return fmt.Sprintf("%s must be a valid HTTP method", field)
+	case "url":
+		return fmt.Sprintf("%s must be a valid URL", field)
+	case "auth_type":
+		return fmt.Sprintf("%s must be none, basic, bearer, apiKey or a plugin scheme", field)
+	default:
+		return fmt.Sprintf("%s failed validation for tag '%s'", field, tag)
+	}
 }
 
-// formatValidationError formats validator errors into a readable string
-func formatValidationError(err error) error {
-	if validationErrors, ok := err.(validator.ValidationErrors); ok {
-		var messages []string
-		for _, validationError := range validationErrors {
-			field := validationError.Field()
-			tag := validationError.Tag()
-			param := validationError.Param()
-
-			var message string
-			switch tag {
-			case "required":
-				message = fmt.Sprintf("%s is required", field)
-			case "min":
-				message = fmt.Sprintf("%s must be at least %s characters", field, param)
-			case "oneof":
-				message = fmt.Sprintf("%s must be one of: %s", field, param)
-			case "http_method":
-				message = fmt.Sprintf("%s must be a valid HTTP method", field)
-			default:
-				message = fmt.Sprintf("%s failed validation for tag '%s'", field, tag)
-			}
-			messages = append(messages, message)
-		}
-		return fmt.Errorf("validation failed: %s", strings.Join(messages, "; "))
+// lowerFirst converts a Go field name to its JSON spelling
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
 	}
-	return err
+	return strings.ToLower(s[:1]) + s[1:]
 }
@@ -28,6 +28,7 @@ type StorageCoordinator struct {
 	file            Storage
 	cloud           Storage
 	conflictHandler ConflictHandler
+	queue           *SyncQueue
 }
 
 // NewStorageCoordinator creates a new StorageCoordinator.
@@ -40,6 +41,12 @@ func NewStorageCoordinator(file Storage, cloud Storage, conflictHandler Conflict
 	}
 }
 
+// SetQueue makes failed cloud saves go to queue, to be retried in the background, instead of
+// failing the save.
+func (c *StorageCoordinator) SetQueue(queue *SyncQueue) {
+	c.queue = queue
+}
+
 // Load loads configuration from file (authoritative) and optionally merges with cloud data.
 func (c *StorageCoordinator) Load(filePath string, target interface{}) error {
 	// First, load from file (authoritative source)
@@ -111,10 +118,15 @@ func (c *StorageCoordinator) Save(filePath string, data interface{}) error {
 	// If cloud storage is available, sync to cloud
 	if c.cloud != nil {
 		if err := c.cloud.Save(filePath, data); err != nil {
-			// Cloud save failed, but file save succeeded - log but don't fail
-			// In the future, this could be handled by retry logic or error reporting
+			// Cloud save failed, but file save succeeded - queue it for a retry if possible
+			if c.queue != nil {
+				return c.queue.Add(filePath, data)
+			}
 			return fmt.Errorf("failed to sync to cloud (file saved successfully): %w", err)
 		}
+		if c.queue != nil {
+			return c.queue.Done(filePath)
+		}
 	}
 
 	return nil
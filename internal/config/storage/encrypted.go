@@ -0,0 +1,294 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"paperbox/internal/apperrors"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// EncryptionFormat marks an encrypted config file
+	EncryptionFormat = "aes-256-gcm"
+	// kdfArgon2id is the only key derivation supported
+	kdfArgon2id = "argon2id"
+)
+
+// DefaultKDFParams are the Argon2id costs new passphrases are derived with (the second
+// recommendation of RFC 9106: 3 passes over 64 MiB)
+var DefaultKDFParams = KDFParams{Algorithm: kdfArgon2id, Time: 3, MemoryKiB: 64 * 1024, Threads: 4}
+
+// ErrWrongPassphrase is returned when a passphrase does not decrypt a file
+var ErrWrongPassphrase = apperrors.Invalidf("wrong passphrase")
+
+// KDFParams derive the encryption key of a file from a passphrase; they are stored in the file
+type KDFParams struct {
+	Algorithm string `json:"algorithm"`
+	Salt      []byte `json:"salt"`
+	Time      uint32 `json:"time"`
+	MemoryKiB uint32 `json:"memoryKiB"`
+	Threads   uint8  `json:"threads"`
+}
+
+// key derives the 256-bit key for passphrase
+func (p KDFParams) key(passphrase string) ([]byte, error) {
+	if p.Algorithm != kdfArgon2id || len(p.Salt) == 0 || p.Time == 0 || p.MemoryKiB == 0 || p.Threads == 0 {
+		return nil, fmt.Errorf("unsupported key derivation %q", p.Algorithm)
+	}
+	return argon2.IDKey([]byte(passphrase), p.Salt, p.Time, p.MemoryKiB, p.Threads, 32), nil
+}
+
+// envelope is the on-disk layout of an encrypted config: the JSON config sealed with AES-GCM
+type envelope struct {
+	Format string    `json:"encrypted"`
+	KDF    KDFParams `json:"kdf"`
+	Nonce  []byte    `json:"nonce"`
+	Data   []byte    `json:"data"`
+}
+
+// EncryptedStorage stores configs as AES-256-GCM encrypted files, with the key derived from a
+// passphrase by Argon2id. Plaintext files are still read and stay plaintext until encryption is
+// enabled, so existing configs migrate on their next save. An encrypted file can neither be loaded
+// nor overwritten until it is unlocked with its passphrase.
+type EncryptedStorage struct {
+	mu      sync.RWMutex
+	writer  Writer
+	enabled bool      // Saves are encrypted
+	params  KDFParams // Salt and costs the key was derived with
+	key     []byte    // nil while locked
+}
+
+// NewEncryptedStorage creates a storage writing through writer; encryption is off until a loaded
+// file turns out to be encrypted or Enable is called
+func NewEncryptedStorage(writer Writer) *EncryptedStorage {
+	return &EncryptedStorage{writer: writer}
+}
+
+// Enabled reports whether saves are encrypted
+func (s *EncryptedStorage) Enabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.enabled
+}
+
+// Locked reports whether an encrypted file was found but no passphrase unlocked it yet
+func (s *EncryptedStorage) Locked() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.enabled && s.key == nil
+}
+
+// Unlock derives the key of an encrypted file from passphrase, failing with ErrWrongPassphrase
+// when it does not decrypt the file. A plaintext or missing file needs no unlocking.
+func (s *EncryptedStorage) Unlock(filePath string, passphrase string) error {
+	env, err := readEnvelope(filePath)
+	if err != nil || env == nil {
+		return err
+	}
+	key, err := env.KDF.key(passphrase)
+	if err != nil {
+		return err
+	}
+	if _, err := env.open(key); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enabled, s.params, s.key = true, env.KDF, key
+	return nil
+}
+
+// Enable encrypts later saves with a key derived from passphrase and a new salt, also when the
+// saves were encrypted with another passphrase before. It fails while locked.
+func (s *EncryptedStorage) Enable(passphrase string) error {
+	if passphrase == "" {
+		return apperrors.Invalidf("passphrase is required")
+	}
+	if s.Locked() {
+		return lockedError()
+	}
+	params := DefaultKDFParams
+	params.Salt = make([]byte, 16)
+	if _, err := rand.Read(params.Salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key, err := params.key(passphrase)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enabled, s.params, s.key = true, params, key
+	return nil
+}
+
+// Disable makes later saves plaintext again, once passphrase is checked against the key in use
+func (s *EncryptedStorage) Disable(passphrase string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.enabled {
+		return nil
+	}
+	if s.key == nil {
+		return lockedError()
+	}
+	key, err := s.params.key(passphrase)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(key, s.key) != 1 {
+		return ErrWrongPassphrase
+	}
+	s.enabled, s.params, s.key = false, KDFParams{}, nil
+	return nil
+}
+
+// Load reads a plaintext or encrypted config; an encrypted one fails with a LOCKED error until
+// unlocked. A missing or empty file leaves target untouched.
+func (s *EncryptedStorage) Load(filePath string, target interface{}) error {
+	data, err := ReadVerified(filePath)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(data) == 0) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	env, err := parseEnvelope(data)
+	if err != nil {
+		return err
+	}
+	if env != nil {
+		s.mu.Lock()
+		s.enabled = true
+		key := s.key
+		if key == nil {
+			s.params = env.KDF
+		}
+		s.mu.Unlock()
+		if key == nil {
+			return lockedError()
+		}
+		if data, err = env.open(key); err != nil {
+			return err
+		}
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return nil
+}
+
+// Save writes a config, encrypted when encryption is enabled; it fails while locked so an
+// encrypted file is never replaced by a config that could not be read
+func (s *EncryptedStorage) Save(filePath string, data interface{}) error {
+	plaintext, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	s.mu.RLock()
+	enabled, params, key := s.enabled, s.params, s.key
+	s.mu.RUnlock()
+	if !enabled {
+		return s.writer.WriteAtomic(filePath, plaintext, 0o644)
+	}
+	if key == nil {
+		return lockedError()
+	}
+
+	env, err := seal(key, params, plaintext)
+	if err != nil {
+		return err
+	}
+	sealed, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal encrypted config: %w", err)
+	}
+	return s.writer.WriteAtomic(filePath, sealed, 0o600)
+}
+
+// seal encrypts plaintext with key under a fresh nonce
+func seal(key []byte, params KDFParams, plaintext []byte) (*envelope, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return &envelope{Format: EncryptionFormat, KDF: params, Nonce: nonce, Data: aead.Seal(nil, nonce, plaintext, nil)}, nil
+}
+
+// open decrypts the envelope, failing with ErrWrongPassphrase when key does not authenticate it
+func (e *envelope) open(key []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted config has an invalid nonce")
+	}
+	plaintext, err := aead.Open(nil, e.Nonce, e.Data, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+// newAEAD returns AES-GCM with a 256-bit key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// readEnvelope reads a file and returns its envelope, nil when the file is missing or plaintext
+func readEnvelope(filePath string) (*envelope, error) {
+	data, err := ReadVerified(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return parseEnvelope(data)
+}
+
+// parseEnvelope returns the envelope of an encrypted config, nil for a plaintext one
+func parseEnvelope(data []byte) (*envelope, error) {
+	var probe struct {
+		Format json.RawMessage `json:"encrypted"`
+	}
+	if len(data) == 0 || json.Unmarshal(data, &probe) != nil || probe.Format == nil {
+		return nil, nil
+	}
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted config: %w", err)
+	}
+	if env.Format != EncryptionFormat {
+		return nil, fmt.Errorf("unsupported config encryption %q", env.Format)
+	}
+	return &env, nil
+}
+
+// lockedError reports an encrypted config used before it was unlocked
+func lockedError() error {
+	return apperrors.New(apperrors.Locked, "the config is encrypted; unlock it with the workspace passphrase")
+}
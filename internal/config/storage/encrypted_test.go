@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"paperbox/internal/apperrors"
+)
+
+func TestEncryptedStorageMigratesAndLocks(t *testing.T) {
+	// Cheap derivation so the test stays fast; the stored params are what decryption uses
+	defer func(params KDFParams) { DefaultKDFParams = params }(DefaultKDFParams)
+	DefaultKDFParams.Time, DefaultKDFParams.MemoryKiB, DefaultKDFParams.Threads = 1, 64, 1
+
+	path := filepath.Join(t.TempDir(), "environments.json")
+	if err := os.WriteFile(path, []byte(`{"version": 1, "values": {"a": {"name": "token-123"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewEncryptedStorage(NewFileWriter())
+	var plain testConfig
+	if err := s.Load(path, &plain); err != nil || plain.Values["a"].Name != "token-123" {
+		t.Fatalf("Load() of a plaintext file = %+v, %v", plain, err)
+	}
+	if s.Enabled() {
+		t.Error("a plaintext file should not turn encryption on")
+	}
+
+	if err := s.Enable("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(path, plain); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "token-123") || !strings.Contains(string(data), EncryptionFormat) {
+		t.Fatalf("saved file is not encrypted: %s", data)
+	}
+
+	// A new start finds the file locked and refuses to overwrite it
+	restarted := NewEncryptedStorage(NewFileWriter())
+	var locked testConfig
+	if err := restarted.Load(path, &locked); apperrors.CodeOf(err) != apperrors.Locked {
+		t.Fatalf("Load() of a locked file error = %v, want LOCKED", err)
+	}
+	if !restarted.Locked() {
+		t.Error("Locked() = false after loading an encrypted file")
+	}
+	if err := restarted.Save(path, testConfig{Version: 1}); apperrors.CodeOf(err) != apperrors.Locked {
+		t.Errorf("Save() while locked error = %v, want LOCKED", err)
+	}
+	if err := restarted.Unlock(path, "wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Unlock() with a wrong passphrase error = %v", err)
+	}
+	if err := restarted.Unlock(path, "correct horse"); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	var unlocked testConfig
+	if err := restarted.Load(path, &unlocked); err != nil || unlocked.Values["a"].Name != "token-123" {
+		t.Fatalf("Load() after unlocking = %+v, %v", unlocked, err)
+	}
+
+	// Turning encryption off takes the passphrase and writes plaintext again
+	if err := restarted.Disable("wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Disable() with a wrong passphrase error = %v", err)
+	}
+	if err := restarted.Disable("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if err := restarted.Save(path, unlocked); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "token-123") {
+		t.Errorf("file after disabling encryption = %s, want plaintext", data)
+	}
+}
+
+func TestEncryptedStorageDetectsTampering(t *testing.T) {
+	defer func(params KDFParams) { DefaultKDFParams = params }(DefaultKDFParams)
+	DefaultKDFParams.Time, DefaultKDFParams.MemoryKiB, DefaultKDFParams.Threads = 1, 64, 1
+
+	path := filepath.Join(t.TempDir(), "environments.json")
+	s := NewEncryptedStorage(NewFileWriter())
+	if err := s.Enable("secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(path, testConfig{Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	env, err := readEnvelope(path)
+	if err != nil || env == nil {
+		t.Fatalf("readEnvelope() = %v, %v", env, err)
+	}
+	env.Data[0] ^= 1
+	key, _ := env.KDF.key("secret")
+	if _, err := env.open(key); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("open() of a tampered file error = %v, want it rejected", err)
+	}
+}
+
+func TestEncryptedStorageKeepsNoPlaintextBackups(t *testing.T) {
+	defer func(params KDFParams) { DefaultKDFParams = params }(DefaultKDFParams)
+	DefaultKDFParams.Time, DefaultKDFParams.MemoryKiB, DefaultKDFParams.Threads = 1, 64, 1
+
+	path := filepath.Join(t.TempDir(), "environments.json")
+	s := NewEncryptedStorage(NewIntegrityWriter(NewFileWriter()))
+	cfg := testConfig{Version: 1, Values: map[string]testItem{"a": {Name: "token-123"}}}
+	for i := 0; i < 2; i++ {
+		if err := s.Save(path, cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := os.Stat(backupPath(path, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("backup mode = %v, want it readable by the user only", perm)
+	}
+
+	// Turning encryption on drops the plaintext backups
+	if err := s.Enable("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Save(path, cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 1; i <= Backups; i++ {
+		if data, err := os.ReadFile(backupPath(path, i)); err == nil && strings.Contains(string(data), "token-123") {
+			t.Errorf("backup %d holds the plaintext: %s", i, data)
+		}
+	}
+
+	// A damaged encrypted file is never replaced by a plaintext backup
+	plain := []byte(`{"version": 1, "values": {"a": {"name": "token-123"}}}`)
+	if err := os.WriteFile(backupPath(path, 1), plain, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Remove(backupPath(path, 1) + ChecksumSuffix)
+	_ = os.Remove(backupPath(path, 2))
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	SetRepairHandler(func(Repair) {})
+	defer SetRepairHandler(nil)
+	if data, err := ReadVerified(path); err != nil || strings.Contains(string(data), "token-123") {
+		t.Fatalf("ReadVerified() = %q, %v; want no plaintext restored", data, err)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "token-123") {
+		t.Errorf("file after repair = %s, want no plaintext", data)
+	}
+}
@@ -19,6 +19,14 @@ func NewFileStorage() *FileStorage {
 	}
 }
 
+// NewCheckedFileStorage creates a FileStorage that keeps checksums and backups of the files it
+// writes (see IntegrityWriter), for the app's own configs.
+func NewCheckedFileStorage() *FileStorage {
+	return &FileStorage{
+		writer: NewIntegrityWriter(NewFileWriter()),
+	}
+}
+
 // NewFileStorageWithWriter creates a new FileStorage with a custom writer (for testing).
 func NewFileStorageWithWriter(writer Writer) *FileStorage {
 	return &FileStorage{
@@ -39,8 +47,8 @@ func (f *FileStorage) Load(filePath string, target interface{}) error {
 		return nil
 	}
 
-	// Read file
-	data, err := os.ReadFile(filePath)
+	// Read file, restoring the latest backup if it is damaged
+	data, err := ReadVerified(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to read config file: %w", err)
 	}
@@ -0,0 +1,352 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// ChecksumSuffix is appended to the name of a config file for the file holding its checksum
+	ChecksumSuffix = ".sha256"
+	// BackupSuffix is appended, followed by the generation, to the previous versions of a config file
+	BackupSuffix = ".bak."
+	// EventRepaired is emitted with a Repair when a damaged config file was found on load
+	EventRepaired = "config:repaired"
+)
+
+// Backups is how many previous versions of a config file IntegrityWriter keeps
+var Backups = 3
+
+// Checksum records what a config file held when it was last written
+type Checksum struct {
+	SHA256    string    `json:"sha256"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"modTime"`
+	Encrypted bool      `json:"encrypted,omitempty"` // The file was written by an EncryptedStorage with encryption on
+}
+
+// Repair describes a damaged config file found on load and what was done about it
+type Repair struct {
+	File         string    `json:"file"`
+	Problem      string    `json:"problem"`
+	RestoredFrom string    `json:"restoredFrom,omitempty"` // Empty when no intact backup was found
+	CorruptCopy  string    `json:"corruptCopy,omitempty"`  // Where the damaged file was moved
+	Time         time.Time `json:"time"`
+}
+
+var (
+	repairMu      sync.RWMutex
+	repairHandler func(Repair)
+)
+
+// SetRepairHandler sets the function told about every damaged config file ReadVerified finds
+func SetRepairHandler(handler func(Repair)) {
+	repairMu.Lock()
+	defer repairMu.Unlock()
+	repairHandler = handler
+}
+
+// notifyRepair passes a repair to the handler, if one is set
+func notifyRepair(r Repair) {
+	repairMu.RLock()
+	handler := repairHandler
+	repairMu.RUnlock()
+	if handler != nil {
+		handler(r)
+	}
+}
+
+// IntegrityWriter writes config files with a checksum next to them and keeps their previous
+// versions as backups, so ReadVerified can detect a damaged file and restore the latest intact one.
+type IntegrityWriter struct {
+	writer Writer
+}
+
+// NewIntegrityWriter wraps writer, which must write to disk
+func NewIntegrityWriter(writer Writer) *IntegrityWriter {
+	return &IntegrityWriter{writer: writer}
+}
+
+// WriteAtomic backs up the current file when it is intact, then writes data and its checksum.
+// When data is sealed otherwise than the current file (encryption turned on or off, or a new
+// passphrase), the backups are removed instead, so no copy of the old state is left behind.
+// A failed backup does not fail the write.
+func (w *IntegrityWriter) WriteAtomic(filename string, data []byte, perm os.FileMode) error {
+	if current, err := os.ReadFile(filename); err == nil && sealedBy(current) != sealedBy(data) {
+		removeBackups(filename)
+	} else {
+		_ = rotateBackups(filename)
+	}
+	if err := w.writer.WriteAtomic(filename, data, perm); err != nil {
+		return err
+	}
+	return writeChecksum(w.writer, filename, data, perm)
+}
+
+// ReadVerified reads a config file, checking it against its checksum. A file that is damaged
+// (truncated, not valid JSON, or changed without its modification time changing) is replaced by
+// its latest intact backup and moved to *.corrupt-<unix time>; either way a Repair is passed to
+// the repair handler. Files that were never written by an IntegrityWriter are returned as they are.
+func ReadVerified(filePath string) ([]byte, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	problem := damage(filePath, data)
+	if problem == "" {
+		return data, nil
+	}
+	return restore(filePath, data, problem), nil
+}
+
+// Verify describes what is wrong with a config file without repairing it, "" when it is intact
+// or missing
+func Verify(filePath string) string {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return ""
+	}
+	return damage(filePath, data)
+}
+
+// RemoveFile removes a config file along with its checksum and backups
+func RemoveFile(filePath string) error {
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	_ = os.Remove(filePath + ChecksumSuffix)
+	removeBackups(filePath)
+	return nil
+}
+
+// removeBackups removes the backups of a file and their checksums
+func removeBackups(filePath string) {
+	for i := 1; i <= Backups; i++ {
+		_ = os.Remove(backupPath(filePath, i))
+		_ = os.Remove(backupPath(filePath, i) + ChecksumSuffix)
+	}
+}
+
+// damage describes what is wrong with the data of a config file, "" when it is fine. A file
+// changed by something else than the app (its size or modification time differ from the
+// checksum) is fine as long as it is still valid JSON, so hand edits are kept.
+func damage(filePath string, data []byte) string {
+	sum, err := readChecksum(filePath)
+	if err != nil || sum == nil {
+		if hasBackup(filePath) && !json.Valid(data) {
+			return invalidProblem(data, -1)
+		}
+		return ""
+	}
+	if checksumOf(data) == sum.SHA256 {
+		return ""
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil || info.Size() != sum.Size || !info.ModTime().Equal(sum.ModTime) {
+		if json.Valid(data) {
+			return ""
+		}
+		return invalidProblem(data, sum.Size)
+	}
+	return "the file does not match its checksum"
+}
+
+// invalidProblem describes data that is not valid JSON; size is what was written, -1 if unknown
+func invalidProblem(data []byte, size int64) string {
+	switch {
+	case len(data) == 0:
+		return "the file is empty"
+	case size > int64(len(data)):
+		return fmt.Sprintf("the file is truncated (%d of %d bytes)", len(data), size)
+	default:
+		return "the file is not valid JSON"
+	}
+}
+
+// restore replaces a damaged file by its latest intact backup and reports the repair. Only
+// backups encrypted the same way as the file are used, so a plaintext backup never replaces an
+// encrypted file, and none is used when that cannot be told. Without such a backup the damaged
+// data is returned unchanged and left in place.
+func restore(filePath string, data []byte, problem string) []byte {
+	repair := Repair{File: filePath, Problem: problem, Time: time.Now()}
+	defer func() { notifyRepair(repair) }()
+
+	encrypted, known := encryptedFile(filePath, data)
+	if !known {
+		return data
+	}
+
+	perm := os.FileMode(0o644)
+	if info, err := os.Stat(filePath); err == nil {
+		perm = info.Mode().Perm()
+	}
+	for i := 1; i <= Backups; i++ {
+		backup := backupPath(filePath, i)
+		saved, err := os.ReadFile(backup)
+		if err != nil || !intactBackup(backup, saved) || (sealedBy(saved) != "") != encrypted {
+			continue
+		}
+
+		corrupt := fmt.Sprintf("%s.corrupt-%d", filePath, repair.Time.Unix())
+		if err := os.Rename(filePath, corrupt); err == nil {
+			repair.CorruptCopy = filepath.Base(corrupt)
+		}
+		writer := NewFileWriter()
+		if err := writer.WriteAtomic(filePath, saved, perm); err != nil {
+			repair.Problem += fmt.Sprintf("; restoring %s failed: %v", filepath.Base(backup), err)
+			return saved
+		}
+		_ = writeChecksum(writer, filePath, saved, perm)
+		repair.RestoredFrom = filepath.Base(backup)
+		return saved
+	}
+	return data
+}
+
+// rotateBackups shifts the backups of a file by one generation and makes the file the first one,
+// unless it is missing or damaged
+func rotateBackups(filename string) error {
+	if Backups <= 0 || !intactFile(filename) {
+		return nil
+	}
+	for i := Backups; i > 1; i-- {
+		from, to := backupPath(filename, i-1), backupPath(filename, i)
+		if err := os.Rename(from, to); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err := os.Rename(from+ChecksumSuffix, to+ChecksumSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		_ = os.Chmod(to, 0o600)
+	}
+
+	// Backups are copies only the user can read, whatever the mode of the file
+	first := backupPath(filename, 1)
+	_ = os.Remove(first)
+	_ = os.Remove(first + ChecksumSuffix)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(first, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(filename+ChecksumSuffix, first+ChecksumSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// intactFile reports whether a file is unchanged since its checksum was written, comparing only
+// size and modification time; files without checksum are checked to be valid JSON
+func intactFile(filename string) bool {
+	info, err := os.Stat(filename)
+	if err != nil || info.Size() == 0 {
+		return false
+	}
+	sum, err := readChecksum(filename)
+	if err == nil && sum != nil && info.Size() == sum.Size && info.ModTime().Equal(sum.ModTime) {
+		return true
+	}
+	data, err := os.ReadFile(filename)
+	return err == nil && json.Valid(data)
+}
+
+// intactBackup reports whether a backup matches its checksum, or is valid JSON without one.
+// Only the content is compared, as a copied backup has another modification time.
+func intactBackup(backup string, data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	sum, err := readChecksum(backup)
+	if err == nil && sum != nil {
+		return checksumOf(data) == sum.SHA256
+	}
+	return json.Valid(data)
+}
+
+// hasBackup reports whether a file has at least one backup
+func hasBackup(filename string) bool {
+	_, err := os.Stat(backupPath(filename, 1))
+	return err == nil
+}
+
+// writeChecksum records the checksum, size and modification time of a file just written
+func writeChecksum(writer Writer, filename string, data []byte, perm os.FileMode) error {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fmt.Errorf("failed to stat config file: %w", err)
+	}
+	sum, err := json.Marshal(Checksum{SHA256: checksumOf(data), Size: int64(len(data)), ModTime: info.ModTime(), Encrypted: sealedBy(data) != ""})
+	if err != nil {
+		return fmt.Errorf("failed to marshal checksum: %w", err)
+	}
+	return writer.WriteAtomic(filename+ChecksumSuffix, sum, perm)
+}
+
+// readChecksum reads the checksum of a file, nil when it has none
+func readChecksum(filename string) (*Checksum, error) {
+	data, err := os.ReadFile(filename + ChecksumSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sum Checksum
+	if err := json.Unmarshal(data, &sum); err != nil {
+		return nil, err
+	}
+	return &sum, nil
+}
+
+// sealedBy identifies how a config is encrypted: the format and salt of an encrypted one, ""
+// for a plaintext one
+func sealedBy(data []byte) string {
+	env, err := parseEnvelope(data)
+	if err != nil {
+		return EncryptionFormat
+	}
+	if env == nil {
+		return ""
+	}
+	return EncryptionFormat + ":" + hex.EncodeToString(env.KDF.Salt)
+}
+
+// encryptedFile tells whether a possibly damaged config file is encrypted, from its checksum
+// or else from its first key; known is false when too little of it is left to tell
+func encryptedFile(filePath string, data []byte) (encrypted, known bool) {
+	if sum, err := readChecksum(filePath); err == nil && sum != nil {
+		return sum.Encrypted, true
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return false, false
+	}
+	key, err := dec.Token()
+	name, ok := key.(string)
+	if err != nil || !ok {
+		return false, false
+	}
+	return name == "encrypted", true
+}
+
+// checksumOf returns the hex SHA-256 of data
+func checksumOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// backupPath returns the name of a file's backup of the given generation (1 is the latest)
+func backupPath(filename string, generation int) string {
+	return fmt.Sprintf("%s%s%d", filename, BackupSuffix, generation)
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIntegrityWriterKeepsChecksumAndBackups(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	writer := NewIntegrityWriter(NewFileWriter())
+	for _, content := range []string{`{"version":1}`, `{"version":2}`, `{"version":3}`} {
+		if err := writer.WriteAtomic(file, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteAtomic() error = %v", err)
+		}
+	}
+
+	sum, err := readChecksum(file)
+	if err != nil || sum == nil || sum.SHA256 != checksumOf([]byte(`{"version":3}`)) {
+		t.Fatalf("readChecksum() = %+v, %v; want the checksum of the last write", sum, err)
+	}
+	for generation, want := range map[int]string{1: `{"version":2}`, 2: `{"version":1}`} {
+		data, err := os.ReadFile(backupPath(file, generation))
+		if err != nil || string(data) != want {
+			t.Errorf("backup %d = %q, %v; want %q", generation, data, err, want)
+		}
+	}
+}
+
+func TestReadVerifiedRestoresDamagedFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		damage  func(file string)
+		problem string
+	}{
+		{"truncated", func(file string) { _ = os.WriteFile(file, []byte(`{"vers`), 0o644) }, "truncated"},
+		{"empty", func(file string) { _ = os.WriteFile(file, nil, 0o644) }, "empty"},
+		{"bit flip", func(file string) {
+			info, _ := os.Stat(file)
+			_ = os.WriteFile(file, []byte(`{"version":9}`), 0o644)
+			_ = os.Chtimes(file, info.ModTime(), info.ModTime())
+		}, "checksum"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "config.json")
+			writer := NewIntegrityWriter(NewFileWriter())
+			_ = writer.WriteAtomic(file, []byte(`{"version":1}`), 0o644)
+			_ = writer.WriteAtomic(file, []byte(`{"version":2}`), 0o644)
+			tt.damage(file)
+
+			var repairs []Repair
+			SetRepairHandler(func(r Repair) { repairs = append(repairs, r) })
+			defer SetRepairHandler(nil)
+
+			data, err := ReadVerified(file)
+			if err != nil || string(data) != `{"version":1}` {
+				t.Fatalf("ReadVerified() = %q, %v; want the backup", data, err)
+			}
+			if len(repairs) != 1 || !strings.Contains(repairs[0].Problem, tt.problem) || repairs[0].RestoredFrom != "config.json.bak.1" {
+				t.Fatalf("repairs = %+v, want one restored from the first backup", repairs)
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(file), repairs[0].CorruptCopy)); err != nil {
+				t.Errorf("corrupt copy not kept: %v", err)
+			}
+			if data, _ := ReadVerified(file); string(data) != `{"version":1}` || len(repairs) != 1 {
+				t.Errorf("second ReadVerified() = %q with %d repairs, want the restored file as is", data, len(repairs))
+			}
+		})
+	}
+}
+
+func TestReadVerifiedKeepsHandEdits(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	writer := NewIntegrityWriter(NewFileWriter())
+	_ = writer.WriteAtomic(file, []byte(`{"version":1}`), 0o644)
+	_ = writer.WriteAtomic(file, []byte(`{"version":2}`), 0o644)
+	if err := os.WriteFile(file, []byte(`{"version":2,"theme":"dark"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var repairs []Repair
+	SetRepairHandler(func(r Repair) { repairs = append(repairs, r) })
+	defer SetRepairHandler(nil)
+
+	data, err := ReadVerified(file)
+	if err != nil || string(data) != `{"version":2,"theme":"dark"}` || len(repairs) != 0 {
+		t.Fatalf("ReadVerified() = %q, %v with repairs %+v; want the edited file", data, err, repairs)
+	}
+}
+
+func TestReadVerifiedWithoutBackupReportsDamage(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	if err := NewIntegrityWriter(NewFileWriter()).WriteAtomic(file, []byte(`{"version":1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.WriteFile(file, []byte(`{"ver`), 0o644)
+
+	var repairs []Repair
+	SetRepairHandler(func(r Repair) { repairs = append(repairs, r) })
+	defer SetRepairHandler(nil)
+
+	data, err := ReadVerified(file)
+	if err != nil || string(data) != `{"ver` {
+		t.Fatalf("ReadVerified() = %q, %v; want the damaged data", data, err)
+	}
+	if len(repairs) != 1 || repairs[0].RestoredFrom != "" {
+		t.Fatalf("repairs = %+v, want one without a restored backup", repairs)
+	}
+}
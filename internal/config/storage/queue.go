@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// EventSyncPending is emitted with a QueueStatus when a cloud save is queued
+	EventSyncPending = "sync:pending"
+	// EventSyncFlushed is emitted with a QueueStatus when queued cloud saves went through
+	EventSyncFlushed = "sync:flushed"
+
+	// MinSyncBackoff is the delay before the first retry of a failed cloud save
+	MinSyncBackoff = 2 * time.Second
+	// MaxSyncBackoff caps the delay between retries
+	MaxSyncBackoff = 5 * time.Minute
+)
+
+// QueueStatus is the payload of the sync queue events
+type QueueStatus struct {
+	Pending int `json:"pending"`           // Files still waiting to be synced
+	Flushed int `json:"flushed,omitempty"` // Files synced by the retry that emitted the event
+}
+
+// SyncQueue keeps the cloud saves that failed, for example while offline, and retries them in the
+// background with exponential backoff. The queue is kept on disk so it survives a restart; only the
+// latest data of each file is kept, as it supersedes the older saves.
+type SyncQueue struct {
+	cloud Storage
+	path  string
+	files *FileStorage
+	emit  func(event string, payload interface{})
+
+	mu      sync.Mutex
+	pending map[string]json.RawMessage // Data by config file path
+	backoff time.Duration
+	timer   *time.Timer
+	closed  bool
+}
+
+// NewSyncQueue creates a queue for cloud kept in path and schedules a retry of the saves still
+// queued there. emit, if not nil, receives the sync:pending and sync:flushed events.
+func NewSyncQueue(cloud Storage, path string, emit func(event string, payload interface{})) (*SyncQueue, error) {
+	q := &SyncQueue{cloud: cloud, path: path, files: NewFileStorage(), emit: emit, backoff: MinSyncBackoff}
+	if err := q.files.Load(path, &q.pending); err != nil {
+		return nil, fmt.Errorf("failed to load sync queue: %w", err)
+	}
+	if q.pending == nil {
+		q.pending = make(map[string]json.RawMessage)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) > 0 {
+		q.scheduleLocked(0)
+	}
+	return q, nil
+}
+
+// Add queues the data of a failed cloud save, replacing what was queued for the file
+func (q *SyncQueue) Add(filePath string, data interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to queue cloud save: %w", err)
+	}
+
+	q.mu.Lock()
+	q.pending[filePath] = encoded
+	err = q.persistLocked()
+	q.scheduleLocked(q.backoff)
+	status := QueueStatus{Pending: len(q.pending)}
+	q.mu.Unlock()
+
+	q.notify(EventSyncPending, status)
+	return err
+}
+
+// Done drops what is queued for a file after a later cloud save of it went through
+func (q *SyncQueue) Done(filePath string) error {
+	q.mu.Lock()
+	if _, queued := q.pending[filePath]; !queued {
+		q.mu.Unlock()
+		return nil
+	}
+	delete(q.pending, filePath)
+	err := q.persistLocked()
+	status := QueueStatus{Pending: len(q.pending), Flushed: 1}
+	q.mu.Unlock()
+
+	q.notify(EventSyncFlushed, status)
+	return err
+}
+
+// Pending returns how many files wait to be synced
+func (q *SyncQueue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// Close stops retrying; queued saves stay on disk for the next start
+func (q *SyncQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+}
+
+// retry saves the queued files to the cloud, doubling the delay before the next attempt while
+// saves keep failing
+func (q *SyncQueue) retry() {
+	q.mu.Lock()
+	q.timer = nil
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	paths := make([]string, 0, len(q.pending))
+	entries := make(map[string]json.RawMessage, len(q.pending))
+	for path, data := range q.pending {
+		paths = append(paths, path)
+		entries[path] = data
+	}
+	q.mu.Unlock()
+	sort.Strings(paths)
+
+	var synced []string
+	for _, path := range paths {
+		if err := q.cloud.Save(path, entries[path]); err == nil {
+			synced = append(synced, path)
+		}
+	}
+
+	q.mu.Lock()
+	for _, path := range synced {
+		// Data queued while this attempt ran is newer and stays queued
+		if bytes.Equal(q.pending[path], entries[path]) {
+			delete(q.pending, path)
+		}
+	}
+	err := q.persistLocked()
+	if len(synced) < len(paths) || err != nil {
+		q.backoff = min(q.backoff*2, MaxSyncBackoff)
+	} else {
+		q.backoff = MinSyncBackoff
+	}
+	if len(q.pending) > 0 {
+		q.scheduleLocked(q.backoff)
+	}
+	status := QueueStatus{Pending: len(q.pending), Flushed: len(synced)}
+	q.mu.Unlock()
+
+	if len(synced) > 0 {
+		q.notify(EventSyncFlushed, status)
+	}
+}
+
+// scheduleLocked starts a retry after delay unless one is scheduled (must hold the lock)
+func (q *SyncQueue) scheduleLocked(delay time.Duration) {
+	if q.timer == nil && !q.closed {
+		q.timer = time.AfterFunc(delay, q.retry)
+	}
+}
+
+// persistLocked writes the queue to disk (must hold the lock)
+func (q *SyncQueue) persistLocked() error {
+	if err := q.files.Save(q.path, q.pending); err != nil {
+		return fmt.Errorf("failed to save sync queue: %w", err)
+	}
+	return nil
+}
+
+// notify emits a queue event
+func (q *SyncQueue) notify(event string, status QueueStatus) {
+	if q.emit != nil {
+		q.emit(event, status)
+	}
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// flakyStorage is a cloud storage that fails while offline
+type flakyStorage struct {
+	mu      sync.Mutex
+	offline bool
+	saved   map[string]interface{}
+}
+
+func (f *flakyStorage) Load(filePath string, target interface{}) error { return nil }
+
+func (f *flakyStorage) Save(filePath string, data interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.offline {
+		return errors.New("offline")
+	}
+	f.saved[filePath] = data
+	return nil
+}
+
+func TestSyncQueueRetriesFailedSaves(t *testing.T) {
+	dir := t.TempDir()
+	cloud := &flakyStorage{offline: true, saved: make(map[string]interface{})}
+	var events []string
+	queue, err := NewSyncQueue(cloud, filepath.Join(dir, "queue.json"), func(event string, payload interface{}) {
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Fatalf("NewSyncQueue() error = %v", err)
+	}
+	defer queue.Close()
+	coordinator := NewStorageCoordinator(NewFileStorage(), cloud, nil)
+	coordinator.SetQueue(queue)
+
+	config := filepath.Join(dir, "requests.json")
+	if err := coordinator.Save(config, testConfig{Version: 1}); err != nil {
+		t.Fatalf("Save() while offline error = %v, want the save queued", err)
+	}
+	if err := coordinator.Save(config, testConfig{Version: 2}); err != nil {
+		t.Fatalf("Save() while offline error = %v", err)
+	}
+	if queue.Pending() != 1 || len(events) != 2 || events[0] != EventSyncPending {
+		t.Fatalf("Pending() = %d, events = %v; want one file pending", queue.Pending(), events)
+	}
+
+	// The queue survives a restart
+	reopened, err := NewSyncQueue(cloud, filepath.Join(dir, "queue.json"), nil)
+	if err != nil {
+		t.Fatalf("NewSyncQueue() error = %v", err)
+	}
+	reopened.Close()
+	if reopened.Pending() != 1 {
+		t.Errorf("reopened Pending() = %d, want 1", reopened.Pending())
+	}
+
+	queue.retry()
+	if queue.Pending() != 1 || queue.backoff != 2*MinSyncBackoff {
+		t.Errorf("after a failed retry Pending() = %d, backoff = %v", queue.Pending(), queue.backoff)
+	}
+
+	cloud.offline = false
+	queue.retry()
+	if queue.Pending() != 0 || queue.backoff != MinSyncBackoff || events[len(events)-1] != EventSyncFlushed {
+		t.Errorf("after a retry online Pending() = %d, backoff = %v, events = %v", queue.Pending(), queue.backoff, events)
+	}
+	if saved, ok := cloud.saved[config].(json.RawMessage); !ok || string(saved) != `{"version":2,"order":null,"values":null}` {
+		t.Errorf("cloud saved %v, want the latest config", cloud.saved)
+	}
+}
@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	_ "modernc.org/sqlite"
+)
+
+// itemsKey is the top-level config field whose entries are stored as separate rows
+const itemsKey = "values"
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS documents (
+	path TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS items (
+	path TEXT NOT NULL,
+	id   TEXT NOT NULL,
+	data TEXT NOT NULL,
+	PRIMARY KEY (path, id)
+);`
+
+// SQLiteStorage implements Storage on a SQLite database. The entries of a config's "values"
+// map are stored one row per item and everything else as a document row, so a save only
+// writes the items that changed instead of rewriting one large JSON file.
+type SQLiteStorage struct {
+	mu sync.Mutex
+	db *sql.DB
+	// stored holds, per config path, a hash of every item row as last read or written
+	stored map[string]map[string][sha256.Size]byte
+}
+
+// NewSQLiteStorage opens (creating if needed) the database at dbPath.
+func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
+	if err := EnsureParentDir(dbPath); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// A single connection serializes writers and keeps pragmas in effect
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create database schema: %w", err)
+	}
+
+	return &SQLiteStorage{db: db, stored: make(map[string]map[string][sha256.Size]byte)}, nil
+}
+
+// Close closes the database.
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
+
+// Load reads the config stored under filePath. Like FileStorage, a config that was never
+// saved leaves target untouched and returns nil.
+func (s *SQLiteStorage) Load(filePath string, target interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var document string
+	err := s.db.QueryRow(`SELECT data FROM documents WHERE path = ?`, filePath).Scan(&document)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(document), &fields); err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	items, hashes, err := s.readItems(filePath)
+	if err != nil {
+		return err
+	}
+	if len(items) > 0 {
+		values, err := json.Marshal(items)
+		if err != nil {
+			return fmt.Errorf("failed to assemble config: %w", err)
+		}
+		fields[itemsKey] = values
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("failed to assemble config: %w", err)
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	s.stored[filePath] = hashes
+	return nil
+}
+
+// Save writes the config under filePath in one transaction, touching only changed item rows.
+func (s *SQLiteStorage) Save(filePath string, data interface{}) error {
+	fields, err := encodeFields(data)
+	if err != nil {
+		return err
+	}
+
+	var items map[string]json.RawMessage
+	if raw, ok := fields[itemsKey]; ok {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return fmt.Errorf("config %s must be an object: %w", itemsKey, err)
+		}
+		delete(fields, itemsKey)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, err := s.hashes(filePath)
+	if err != nil {
+		return err
+	}
+
+	changes := make(map[string]json.RawMessage)
+	for id, raw := range items {
+		if old, ok := previous[id]; !ok || old != sha256.Sum256(raw) {
+			changes[id] = raw
+		}
+	}
+	for id := range previous {
+		if _, ok := items[id]; !ok {
+			changes[id] = nil
+		}
+	}
+
+	return s.write(filePath, fields, changes)
+}
+
+// SaveItems writes document and only the given items, without encoding the rest of the config.
+func (s *SQLiteStorage) SaveItems(filePath string, document interface{}, items map[string]interface{}) error {
+	fields, err := encodeFields(document)
+	if err != nil {
+		return err
+	}
+	delete(fields, itemsKey)
+
+	changes := make(map[string]json.RawMessage, len(items))
+	for id, item := range items {
+		if item == nil {
+			changes[id] = nil
+			continue
+		}
+		raw, err := json.Marshal(item)
+		if err != nil {
+			return fmt.Errorf("failed to marshal item %s: %w", id, err)
+		}
+		changes[id] = raw
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.hashes(filePath); err != nil {
+		return err
+	}
+	return s.write(filePath, fields, changes)
+}
+
+// encodeFields encodes a config as its top-level JSON fields
+func encodeFields(data interface{}) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, fmt.Errorf("config must be a JSON object: %w", err)
+	}
+	return fields, nil
+}
+
+// hashes returns the known item hashes of a config, reading them on first use (must hold the lock)
+func (s *SQLiteStorage) hashes(filePath string) (map[string][sha256.Size]byte, error) {
+	if hashes, ok := s.stored[filePath]; ok {
+		return hashes, nil
+	}
+	_, hashes, err := s.readItems(filePath)
+	if err != nil {
+		return nil, err
+	}
+	s.stored[filePath] = hashes
+	return hashes, nil
+}
+
+// write stores the document and applies item changes (nil deletes) in one transaction (must hold the lock)
+func (s *SQLiteStorage) write(filePath string, fields map[string]json.RawMessage, changes map[string]json.RawMessage) error {
+	document, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO documents (path, data) VALUES (?, ?)
+		ON CONFLICT(path) DO UPDATE SET data = excluded.data`, filePath, string(document)); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+
+	for id, raw := range changes {
+		if raw == nil {
+			if _, err := tx.Exec(`DELETE FROM items WHERE path = ? AND id = ?`, filePath, id); err != nil {
+				return fmt.Errorf("failed to delete item %s: %w", id, err)
+			}
+			continue
+		}
+		if _, err := tx.Exec(`INSERT INTO items (path, id, data) VALUES (?, ?, ?)
+			ON CONFLICT(path, id) DO UPDATE SET data = excluded.data`, filePath, id, string(raw)); err != nil {
+			return fmt.Errorf("failed to write item %s: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit config: %w", err)
+	}
+
+	hashes := s.stored[filePath]
+	for id, raw := range changes {
+		if raw == nil {
+			delete(hashes, id)
+		} else {
+			hashes[id] = sha256.Sum256(raw)
+		}
+	}
+	return nil
+}
+
+// readItems returns the item rows of a config and their hashes (must hold the lock)
+func (s *SQLiteStorage) readItems(filePath string) (map[string]json.RawMessage, map[string][sha256.Size]byte, error) {
+	rows, err := s.db.Query(`SELECT id, data FROM items WHERE path = ?`, filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make(map[string]json.RawMessage)
+	hashes := make(map[string][sha256.Size]byte)
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, nil, fmt.Errorf("failed to read items: %w", err)
+		}
+		items[id] = json.RawMessage(data)
+		hashes[id] = sha256.Sum256([]byte(data))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read items: %w", err)
+	}
+	return items, hashes, nil
+}
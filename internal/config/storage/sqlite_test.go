@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type testItem struct {
+	Name string `json:"name"`
+}
+
+type testConfig struct {
+	Version int                 `json:"version"`
+	Order   []string            `json:"order"`
+	Values  map[string]testItem `json:"values"`
+}
+
+func openTestDB(t *testing.T) *SQLiteStorage {
+	t.Helper()
+	s, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSQLiteStorageRoundTrip(t *testing.T) {
+	s := openTestDB(t)
+
+	var empty testConfig
+	if err := s.Load("requests.json", &empty); err != nil {
+		t.Fatalf("Load() of a missing config error = %v", err)
+	}
+	if empty.Version != 0 || empty.Values != nil {
+		t.Errorf("Load() of a missing config should leave target untouched, got %+v", empty)
+	}
+
+	want := testConfig{
+		Version: 4,
+		Order:   []string{"a", "b"},
+		Values:  map[string]testItem{"a": {Name: "A"}, "b": {Name: "B"}},
+	}
+	if err := s.Save("requests.json", want); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	delete(want.Values, "b")
+	want.Values["a"] = testItem{Name: "renamed"}
+	want.Order = []string{"a"}
+	if err := s.Save("requests.json", want); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	var got testConfig
+	if err := s.Load("requests.json", &got); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSQLiteStorageWritesOnlyChangedItems(t *testing.T) {
+	s := openTestDB(t)
+
+	cfg := testConfig{Version: 1, Values: map[string]testItem{"a": {Name: "A"}, "b": {Name: "B"}}}
+	if err := s.Save("requests.json", cfg); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Tamper with row b behind the storage's back; an unchanged item must not be rewritten
+	if _, err := s.db.Exec(`UPDATE items SET data = '{"name":"untouched"}' WHERE id = 'b'`); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Values["a"] = testItem{Name: "changed"}
+	if err := s.Save("requests.json", cfg); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	var got testConfig
+	if err := s.Load("requests.json", &got); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got.Values["a"].Name != "changed" {
+		t.Errorf("changed item was not written, got %q", got.Values["a"].Name)
+	}
+	if got.Values["b"].Name != "untouched" {
+		t.Errorf("unchanged item was rewritten, got %q", got.Values["b"].Name)
+	}
+}
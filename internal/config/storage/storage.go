@@ -10,3 +10,12 @@ type Storage interface {
 	Save(filePath string, data interface{}) error
 }
 
+// ItemStorage is implemented by storages that keep the items of a config separately
+// and can rewrite only the ones that changed.
+type ItemStorage interface {
+	Storage
+
+	// SaveItems writes document (the config without its items) and the given items.
+	// A nil item is deleted; items not listed are left as they are.
+	SaveItems(filePath string, document interface{}, items map[string]interface{}) error
+}
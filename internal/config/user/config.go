@@ -2,11 +2,15 @@ package user
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path"
+	"sync"
+	"time"
 
 	"paperbox/internal/config/core"
+	"paperbox/internal/config/requests"
 	"paperbox/internal/config/storage"
 
 	"github.com/adrg/xdg"
@@ -15,7 +19,7 @@ import (
 
 const (
 	// CurrentVersion is the current version of the user config format
-	CurrentVersion = 1
+	CurrentVersion = 8
 	// ConfigFileName is the name of the user config file
 	ConfigFileName = "config.json"
 )
@@ -27,12 +31,116 @@ var (
 
 // Config represents the user configuration
 type Config struct {
-	Version  int    `json:"version"`
-	Theme    string `json:"theme"`    // "light" | "dark" | "auto"
-	FontSize int    `json:"fontSize"` // Font size in pixels
-	BaseURL  string `json:"baseURL"`  // Base URL for API requests
+	Version  int    `json:"version" validate:"required,min=1"`
+	Theme    string `json:"theme" validate:"oneof=light dark auto"`
+	FontSize int    `json:"fontSize" validate:"min=8,max=48"` // Font size in pixels
+	BaseURL  string `json:"baseURL" validate:"omitempty,url"` // Base URL for API requests
+	// MaxFolderDepth limits folder nesting in the request tree; 0 means unlimited
+	MaxFolderDepth int `json:"maxFolderDepth" validate:"min=0"`
+
+	Window             WindowState `json:"window"`
+	Locale             string      `json:"locale" validate:"bcp47_language_tag"`            // UI language, e.g. "en" or "pt-BR"
+	AutosaveIntervalMs int         `json:"autosaveIntervalMs" validate:"min=100,max=60000"` // Delay before changes are written to disk
+	RequestTimeoutMs   int         `json:"requestTimeoutMs" validate:"min=0,max=600000"`    // Default request timeout; 0 disables it
+	// MaxResponseSizeMB is how much of a response body is kept in memory; 0 keeps all of it
+	MaxResponseSizeMB int `json:"maxResponseSizeMB" validate:"min=0,max=4096"`
+	// SaveLargeResponses writes bodies over MaxResponseSizeMB to a temp file instead of dropping the rest
+	SaveLargeResponses bool `json:"saveLargeResponses"`
+	// Retry honours Retry-After and backs off when a server is rate limiting or unavailable
+	Retry RetrySettings `json:"retry"`
+	// ConditionalRequests stores ETag and Last-Modified validators and sends them with later GET
+	// and HEAD requests to the same URL, to check a server's caching (see engine.CacheInfo)
+	ConditionalRequests bool `json:"conditionalRequests"`
+	// Connections tune connection reuse; they apply to new connections
+	Connections ConnectionSettings `json:"connections"`
+	// StorageBackend selects how the request tree is stored; takes effect on the next start
+	StorageBackend string `json:"storageBackend" validate:"oneof=json sqlite folders"`
+	// Tunnel is the SSH server used to expose the local webhook listener on a public URL
+	Tunnel TunnelSettings `json:"tunnel"`
+	// AllowProcessEnv lets {{env:NAME}} placeholders read the environment of the paperbox process
+	AllowProcessEnv bool `json:"allowProcessEnv"`
+	// Sync is the team sync server the request tree is shared through; takes effect on the next start
+	Sync SyncSettings `json:"sync"`
+	// DisabledLintRules are the collection lint rules that are not run (see internal/lint)
+	DisabledLintRules []string `json:"disabledLintRules,omitempty" validate:"omitempty,dive,required"`
+	// AllowCustomMethods lets requests use any uppercase method token besides the standard methods
+	AllowCustomMethods bool `json:"allowCustomMethods"`
+	// CustomMethods are the non-standard methods requests may use, e.g. PROPFIND or PURGE
+	CustomMethods []string `json:"customMethods,omitempty" validate:"omitempty,dive,required"`
+	// ProtoFiles are the .proto files whose messages requests can send and receive (see internal/protobuf)
+	ProtoFiles []string `json:"protoFiles,omitempty" validate:"omitempty,dive,required"`
+}
+
+// TunnelSettings configures the SSH reverse tunnel (see internal/tunnel)
+type TunnelSettings struct {
+	Server     string `json:"server" validate:"required,hostname_port"`
+	User       string `json:"user" validate:"required"`
+	KeyFile    string `json:"keyFile"` // Private key; empty logs in without one
+	RemotePort int    `json:"remotePort" validate:"min=0,max=65535"`
+	// PublicURL is needed for own servers; tunnel services announce theirs
+	PublicURL string `json:"publicURL" validate:"omitempty,url"`
+	HostKey   string `json:"hostKey"` // Pinned host key fingerprint ("SHA256:..."); empty pins the key seen on first use
+}
+
+// RetrySettings configures retrying requests answered with 429 or 503 (see engine.RetryPolicy)
+type RetrySettings struct {
+	Enabled    bool `json:"enabled"`
+	MaxRetries int  `json:"maxRetries" validate:"min=0,max=10"`
+	MaxDelayMs int  `json:"maxDelayMs" validate:"min=0,max=600000"` // Cap on a single wait; 0 for none
+}
+
+// ConnectionSettings tune how connections are pooled and kept alive (see engine.TransportSettings)
+type ConnectionSettings struct {
+	MaxIdleConnsPerHost int  `json:"maxIdleConnsPerHost" validate:"min=0,max=1000"`  // 0 keeps the Go default (2)
+	IdleConnTimeoutMs   int  `json:"idleConnTimeoutMs" validate:"min=0,max=3600000"` // 0 keeps the Go default (90 s)
+	DisableKeepAlives   bool `json:"disableKeepAlives"`
+	ForceClose          bool `json:"forceClose"` // Sends every request with "Connection: close"
 }
 
+// SyncSettings configures team sync (see internal/sync); an empty Server disables it
+type SyncSettings struct {
+	Server    string `json:"server" validate:"omitempty,url"`
+	Workspace string `json:"workspace" validate:"required_with=Server"`
+	// TokenVariable names the secret global variable holding the workspace token; empty sends none
+	TokenVariable string `json:"tokenVariable"`
+}
+
+// WindowState is the main window geometry saved on exit; a zero Width means nothing was saved yet
+type WindowState struct {
+	Width     int  `json:"width" validate:"omitempty,min=400"`
+	Height    int  `json:"height" validate:"omitempty,min=300"`
+	X         int  `json:"x"`
+	Y         int  `json:"y"`
+	Maximized bool `json:"maximized"`
+}
+
+const (
+	// DefaultLocale is the UI language used until the user picks one
+	DefaultLocale = "en"
+	// DefaultAutosaveInterval matches core.DefaultDebounceDuration
+	DefaultAutosaveInterval = 700 * time.Millisecond
+	// DefaultRequestTimeout matches engine.DefaultTimeout
+	DefaultRequestTimeout = 30 * time.Second
+	// DefaultMaxResponseSizeMB matches engine.DefaultMaxBodySize
+	DefaultMaxResponseSizeMB = 64
+	// DefaultMaxRetries is how often a rate-limited request is retried once retrying is enabled
+	DefaultMaxRetries = 3
+	// DefaultMaxRetryDelay caps a single wait before retrying
+	DefaultMaxRetryDelay = 30 * time.Second
+	// DefaultMaxIdleConnsPerHost keeps enough connections for collection runs against one host
+	DefaultMaxIdleConnsPerHost = 16
+
+	// StorageJSON keeps the request tree in requests.json
+	StorageJSON = "json"
+	// StorageSQLite keeps the request tree in a SQLite database, one row per item
+	StorageSQLite = "sqlite"
+	// StorageFolders keeps each root folder of the request tree in its own JSON file
+	StorageFolders = "folders"
+
+	// DefaultTunnelServer is a public tunnel service that needs no account
+	DefaultTunnelServer = "localhost.run:22"
+)
+
 // DefaultConfig returns a new config with default values
 func DefaultConfig() *Config {
 	return &Config{
@@ -40,50 +148,157 @@ func DefaultConfig() *Config {
 		Theme:    "light",
 		FontSize: 14,
 		BaseURL:  "",
+
+		MaxFolderDepth:     requests.DefaultMaxFolderDepth,
+		Locale:             DefaultLocale,
+		AutosaveIntervalMs: int(DefaultAutosaveInterval / time.Millisecond),
+		RequestTimeoutMs:   int(DefaultRequestTimeout / time.Millisecond),
+		MaxResponseSizeMB:  DefaultMaxResponseSizeMB,
+		SaveLargeResponses: true,
+		Retry:              RetrySettings{MaxRetries: DefaultMaxRetries, MaxDelayMs: int(DefaultMaxRetryDelay / time.Millisecond)},
+		Connections:        ConnectionSettings{MaxIdleConnsPerHost: DefaultMaxIdleConnsPerHost},
+		StorageBackend:     StorageJSON,
+		Tunnel:             TunnelSettings{Server: DefaultTunnelServer, User: "nokey", RemotePort: 80},
+	}
+}
+
+// AutosaveInterval returns the autosave delay as a duration
+func (c *Config) AutosaveInterval() time.Duration {
+	return time.Duration(c.AutosaveIntervalMs) * time.Millisecond
+}
+
+// RequestTimeout returns the default request timeout as a duration (0 for none)
+func (c *Config) RequestTimeout() time.Duration {
+	return time.Duration(c.RequestTimeoutMs) * time.Millisecond
+}
+
+// MaxDelay returns the cap on a single wait as a duration (0 for none)
+func (s RetrySettings) MaxDelay() time.Duration {
+	return time.Duration(s.MaxDelayMs) * time.Millisecond
+}
+
+// IdleConnTimeout returns how long idle connections are kept as a duration (0 for the default)
+func (s ConnectionSettings) IdleConnTimeout() time.Duration {
+	return time.Duration(s.IdleConnTimeoutMs) * time.Millisecond
+}
+
+// MaxResponseSize returns the in-memory response body limit in bytes (0 for none)
+func (c *Config) MaxResponseSize() int64 {
+	return int64(c.MaxResponseSizeMB) << 20
+}
+
+// migrate fills in settings added after the config was written
+func migrate(cfg *Config) {
+	defaults := DefaultConfig()
+
+	// Version 2: configs written before the depth setting existed used the fixed default
+	if cfg.Version < 2 {
+		cfg.MaxFolderDepth = defaults.MaxFolderDepth
+	}
+
+	// Version 3: locale, autosave interval and request timeout (window state starts empty)
+	if cfg.Version < 3 {
+		cfg.Locale = defaults.Locale
+		cfg.AutosaveIntervalMs = defaults.AutosaveIntervalMs
+		cfg.RequestTimeoutMs = defaults.RequestTimeoutMs
+	}
+
+	// Version 4: storage backend
+	if cfg.Version < 4 {
+		cfg.StorageBackend = defaults.StorageBackend
+	}
+
+	// Version 5: tunnel settings
+	if cfg.Version < 5 {
+		cfg.Tunnel = defaults.Tunnel
+	}
+
+	// Version 6: response size limit
+	if cfg.Version < 6 {
+		cfg.MaxResponseSizeMB = defaults.MaxResponseSizeMB
+		cfg.SaveLargeResponses = defaults.SaveLargeResponses
 	}
+
+	// Version 7: retry settings (retrying stays off)
+	if cfg.Version < 7 {
+		cfg.Retry = defaults.Retry
+	}
+
+	// Version 8: connection settings
+	if cfg.Version < 8 {
+		cfg.Connections = defaults.Connections
+	}
+
+	cfg.Version = CurrentVersion
 }
 
-// Manager manages the user configuration
+// Manager manages the user configuration of the active profile
 type Manager struct {
 	*core.BaseManager[Config]
+
+	profileMu sync.Mutex
+	profile   string
 }
 
 // loadUserConfig loads user config from file, creating default if file doesn't exist
-func loadUserConfig() (*Config, error) {
+func loadUserConfig(file string) (*Config, error) {
 	// Ensure directory exists
-	if err := storage.EnsureParentDir(configFile); err != nil {
+	if err := storage.EnsureParentDir(file); err != nil {
 		return nil, fmt.Errorf("failed to ensure parent directory: %w", err)
 	}
 
 	// If config file doesn't exist, return default config
-	if _, err := os.Stat(configFile); os.IsNotExist(err) {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
 		return DefaultConfig(), nil
 	}
 
 	// Load from file using FileStorage
 	fileStorage := storage.NewFileStorage()
 	var cfg Config
-	if err := fileStorage.Load(configFile, &cfg); err != nil {
+	if err := fileStorage.Load(file, &cfg); err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Ensure version is set
-	if cfg.Version == 0 {
-		cfg.Version = CurrentVersion
+	// A config written by a newer version is loaded as it is and stays read-only
+	if cfg.Version <= CurrentVersion {
+		migrate(&cfg)
+	}
+
+	// A hand-edited file must not prevent startup, so invalid values fall back to defaults
+	for _, issue := range repair(&cfg) {
+		fmt.Fprintf(os.Stderr, "Invalid user config, using default: %s\n", issue.Message)
 	}
 
 	return &cfg, nil
 }
 
-// NewManager creates a new config manager
+// Decode parses a user config file the way it is loaded, returning the invalid fields that
+// loading resets to their defaults
+func Decode(data []byte) (*Config, []FieldIssue, error) {
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse user config: %w", err)
+	}
+	if cfg.Version <= CurrentVersion {
+		migrate(&cfg)
+	}
+	return &cfg, repair(&cfg), nil
+}
+
+// NewManager creates a new config manager for the profile used last
 func NewManager(storage storage.Storage) *Manager {
+	profile := lastProfile()
+	file := ProfileConfigPath(profile)
 	return &Manager{
+		profile: profile,
 		BaseManager: core.NewBaseManager(core.BaseManagerOptions[Config]{
-			Storage:    storage,
-			ConfigFile: configFile,
-			EventName:  "config",
-			Loader:     loadUserConfig,
-			Validator:  nil, // No validation for user config
+			Storage:        storage,
+			ConfigFile:     file,
+			EventName:      "config",
+			Loader:         func() (*Config, error) { return loadUserConfig(file) },
+			Validator:      Validate,
+			CurrentVersion: CurrentVersion,
+			Version:        func(cfg *Config) int { return cfg.Version },
 			EnsureFunc: func(cfg *Config) {
 				if cfg.Version == 0 {
 					cfg.Version = CurrentVersion
@@ -99,12 +314,15 @@ func NewManagerWithWriter(writer storage.Writer) *Manager {
 	coordinator := storage.NewStorageCoordinator(fileStorage, nil, nil)
 
 	return &Manager{
+		profile: DefaultProfile,
 		BaseManager: core.NewBaseManager(core.BaseManagerOptions[Config]{
-			Storage:    coordinator,
-			ConfigFile: configFile,
-			EventName:  "config",
-			Loader:     loadUserConfig,
-			Validator:  nil, // No validation for user config
+			Storage:        coordinator,
+			ConfigFile:     configFile,
+			EventName:      "config",
+			Loader:         func() (*Config, error) { return loadUserConfig(configFile) },
+			Validator:      Validate,
+			CurrentVersion: CurrentVersion,
+			Version:        func(cfg *Config) int { return cfg.Version },
 			EnsureFunc: func(cfg *Config) {
 				if cfg.Version == 0 {
 					cfg.Version = CurrentVersion
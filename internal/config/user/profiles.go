@@ -0,0 +1,106 @@
+package user
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"sort"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/storage"
+)
+
+const (
+	// DefaultProfile is the profile kept in the original config.json
+	DefaultProfile = "default"
+	// ProfilesDirName is the directory holding the other profiles, one subdirectory each
+	ProfilesDirName = "profiles"
+	// ActiveProfileFileName remembers the profile used last, inside the profiles directory
+	ActiveProfileFileName = "active.json"
+)
+
+// profileNamePattern restricts profile names to ones that are safe as directory names
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _.-]{0,63}$`)
+
+// activeProfile is the content of the active profile file
+type activeProfile struct {
+	Name string `json:"name"`
+}
+
+// ProfileConfigPath returns the config file of a profile
+func ProfileConfigPath(name string) string {
+	if name == DefaultProfile {
+		return configFile
+	}
+	return path.Join(appDataDir, ProfilesDirName, name, ConfigFileName)
+}
+
+// ListProfiles returns the default profile followed by the other profiles, sorted
+func ListProfiles() ([]string, error) {
+	entries, err := os.ReadDir(path.Join(appDataDir, ProfilesDirName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to list profiles")
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != DefaultProfile && profileNamePattern.MatchString(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfile}, names...), nil
+}
+
+// Profile returns the name of the active profile
+func (m *Manager) Profile() string {
+	m.profileMu.Lock()
+	defer m.profileMu.Unlock()
+	return m.profile
+}
+
+// SwitchProfile saves pending changes, loads the config of another profile and announces it with
+// the config:updated event. A profile that does not exist yet starts with the default settings and
+// is created by its first save.
+func (m *Manager) SwitchProfile(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return apperrors.Invalidf("profile name must start with a letter or digit and use only letters, digits, spaces, '.', '_' and '-'")
+	}
+	m.profileMu.Lock()
+	defer m.profileMu.Unlock()
+	if name == m.profile {
+		return nil
+	}
+
+	if err := m.Flush(); err != nil {
+		return err
+	}
+	previous := m.profile
+	m.useProfile(name)
+	if err := m.Load(); err != nil {
+		m.useProfile(previous)
+		return apperrors.Wrap(apperrors.IOError, err, "failed to load profile "+name)
+	}
+	m.profile = name
+
+	active := path.Join(appDataDir, ProfilesDirName, ActiveProfileFileName)
+	if err := storage.NewFileStorage().Save(active, activeProfile{Name: name}); err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to remember the active profile")
+	}
+	m.NotifyUpdated()
+	return nil
+}
+
+// useProfile points the manager at the config file of a profile
+func (m *Manager) useProfile(name string) {
+	file := ProfileConfigPath(name)
+	m.SetConfigFile(file, func() (*Config, error) { return loadUserConfig(file) })
+}
+
+// lastProfile returns the profile used last, or the default one when it is unknown
+func lastProfile() string {
+	var active activeProfile
+	if err := storage.NewFileStorage().Load(path.Join(appDataDir, ProfilesDirName, ActiveProfileFileName), &active); err != nil || !profileNamePattern.MatchString(active.Name) {
+		return DefaultProfile
+	}
+	return active.Name
+}
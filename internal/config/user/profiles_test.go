@@ -0,0 +1,65 @@
+package user
+
+import (
+	"path"
+	"reflect"
+	"testing"
+
+	"paperbox/internal/config/storage"
+)
+
+func TestSwitchProfile(t *testing.T) {
+	originalAppDataDir, originalConfigFile := appDataDir, configFile
+	appDataDir = t.TempDir()
+	configFile = path.Join(appDataDir, ConfigFileName)
+	t.Cleanup(func() {
+		appDataDir, configFile = originalAppDataDir, originalConfigFile
+	})
+
+	m := NewManager(storage.NewStorageCoordinator(storage.NewFileStorage(), nil, nil))
+	if err := m.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer m.Close()
+	if m.Profile() != DefaultProfile {
+		t.Fatalf("Profile() = %q, want the default profile", m.Profile())
+	}
+	if err := m.Patch(map[string]interface{}{"baseURL": "https://prod.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.SwitchProfile("staging"); err != nil {
+		t.Fatalf("SwitchProfile() error = %v", err)
+	}
+	if got := m.GetConfig().BaseURL; got != "" {
+		t.Errorf("new profile BaseURL = %q, want the default", got)
+	}
+	if err := m.Patch(map[string]interface{}{"baseURL": "https://staging.example.com", "theme": "dark"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new manager starts on the profile used last
+	reopened := NewManager(storage.NewStorageCoordinator(storage.NewFileStorage(), nil, nil))
+	if err := reopened.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if reopened.Profile() != "staging" || reopened.GetConfig().Theme != "dark" {
+		t.Errorf("reopened on %q with %+v", reopened.Profile(), reopened.GetConfig())
+	}
+
+	if err := m.SwitchProfile(DefaultProfile); err != nil {
+		t.Fatalf("SwitchProfile() back error = %v", err)
+	}
+	if got := m.GetConfig().BaseURL; got != "https://prod.example.com" {
+		t.Errorf("default profile BaseURL = %q, want the one saved before switching", got)
+	}
+	if profiles, err := ListProfiles(); err != nil || !reflect.DeepEqual(profiles, []string{DefaultProfile, "staging"}) {
+		t.Errorf("ListProfiles() = %v, %v", profiles, err)
+	}
+	if err := m.SwitchProfile("../escape"); err == nil {
+		t.Error("SwitchProfile() accepted a name with a path separator")
+	}
+}
@@ -0,0 +1,113 @@
+package user
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"paperbox/internal/apperrors"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var validate *validator.Validate
+
+func init() {
+	validate = validator.New()
+
+	// Report fields by their JSON names, as the frontend knows them
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+}
+
+// FieldIssue describes an invalid user config field
+type FieldIssue struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+
+	structField string
+}
+
+// Issues returns every invalid field of the user config
+func Issues(cfg *Config) []FieldIssue {
+	err := validate.Struct(cfg)
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		if err != nil {
+			return []FieldIssue{{Message: err.Error()}}
+		}
+		return nil
+	}
+
+	issues := make([]FieldIssue, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		// Namespaces look like "Config.window.width"; drop the root type name
+		_, field, _ := strings.Cut(fe.Namespace(), ".")
+		_, structField, _ := strings.Cut(fe.StructNamespace(), ".")
+		issues = append(issues, FieldIssue{Field: field, Message: fieldErrorMessage(fe), structField: structField})
+	}
+	return issues
+}
+
+// Validate validates the user configuration. The invalid fields are listed in the
+// "fields" detail of the returned error.
+func Validate(cfg *Config) error {
+	issues := Issues(cfg)
+	if len(issues) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		messages = append(messages, issue.Message)
+	}
+	return apperrors.Invalidf("invalid settings: %s", strings.Join(messages, "; ")).WithDetail("fields", issues)
+}
+
+// repair resets every invalid field to its default and returns what was reset
+func repair(cfg *Config) []FieldIssue {
+	issues := Issues(cfg)
+	current := reflect.ValueOf(cfg).Elem()
+	defaults := reflect.ValueOf(DefaultConfig()).Elem()
+	for _, issue := range issues {
+		if issue.structField == "" {
+			continue
+		}
+		target, source := current, defaults
+		for _, name := range strings.Split(issue.structField, ".") {
+			target, source = target.FieldByName(name), source.FieldByName(name)
+		}
+		target.Set(source)
+	}
+	return issues
+}
+
+// fieldErrorMessage formats a single validator error into a readable string
+func fieldErrorMessage(fe validator.FieldError) string {
+	_, field, _ := strings.Cut(fe.Namespace(), ".")
+	param := fe.Param()
+
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "min", "gte":
+		return fmt.Sprintf("%s must be at least %s", field, param)
+	case "max", "lte":
+		return fmt.Sprintf("%s must be at most %s", field, param)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, param)
+	case "url":
+		return fmt.Sprintf("%s must be a valid URL", field)
+	case "hostname_port":
+		return fmt.Sprintf("%s must be a host and port such as 'example.com:22'", field)
+	case "bcp47_language_tag":
+		return fmt.Sprintf("%s must be a language tag such as 'en' or 'pt-BR'", field)
+	default:
+		return fmt.Sprintf("%s failed validation for tag '%s'", field, fe.Tag())
+	}
+}
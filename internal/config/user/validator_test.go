@@ -0,0 +1,67 @@
+package user
+
+import (
+	"errors"
+	"testing"
+
+	"paperbox/internal/apperrors"
+)
+
+func TestValidate(t *testing.T) {
+	if err := Validate(DefaultConfig()); err != nil {
+		t.Fatalf("default config should be valid, got %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Theme = "neon"
+	cfg.FontSize = -2
+	cfg.BaseURL = "not a url"
+
+	err := Validate(cfg)
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) || appErr.Code != apperrors.ValidationFailed {
+		t.Fatalf("Validate() error = %v, want VALIDATION_FAILED", err)
+	}
+	issues, _ := appErr.Details["fields"].([]FieldIssue)
+	fields := map[string]bool{}
+	for _, issue := range issues {
+		fields[issue.Field] = true
+	}
+	for _, field := range []string{"theme", "fontSize", "baseURL"} {
+		if !fields[field] {
+			t.Errorf("expected an issue for %s, got %+v", field, issues)
+		}
+	}
+}
+
+func TestRepairResetsInvalidFields(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Theme = "dark"
+	cfg.FontSize = 200
+
+	if fixed := repair(cfg); len(fixed) != 1 {
+		t.Fatalf("expected one repaired field, got %+v", fixed)
+	}
+	if cfg.FontSize != DefaultConfig().FontSize || cfg.Theme != "dark" {
+		t.Errorf("repair should only reset invalid fields, got %+v", cfg)
+	}
+}
+
+func TestMigrateFillsNewSettings(t *testing.T) {
+	cfg := &Config{Version: 1, Theme: "dark", FontSize: 16}
+	migrate(cfg)
+
+	defaults := DefaultConfig()
+	if cfg.Version != CurrentVersion || cfg.Theme != "dark" || cfg.FontSize != 16 {
+		t.Errorf("migrate() changed existing settings: %+v", cfg)
+	}
+	if cfg.MaxFolderDepth != defaults.MaxFolderDepth || cfg.Locale != defaults.Locale ||
+		cfg.AutosaveIntervalMs != defaults.AutosaveIntervalMs || cfg.RequestTimeoutMs != defaults.RequestTimeoutMs ||
+		cfg.StorageBackend != defaults.StorageBackend || cfg.Tunnel != defaults.Tunnel ||
+		cfg.MaxResponseSizeMB != defaults.MaxResponseSizeMB || !cfg.SaveLargeResponses {
+		t.Errorf("migrate() did not fill new settings: %+v", cfg)
+	}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("migrated config should be valid, got %v", err)
+	}
+}
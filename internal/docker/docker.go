@@ -0,0 +1,213 @@
+// Package docker lists the running containers of the local Docker engine and the ports they
+// publish, so environments can reach them through host overrides or a base URL that follow what is
+// actually running. It talks to the Engine API directly, over the Unix socket or a tcp:// DOCKER_HOST.
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/environments"
+)
+
+const (
+	// DefaultHost is the Docker engine address used when DOCKER_HOST is not set
+	DefaultHost = "unix:///var/run/docker.sock"
+	// Source marks the host overrides generated from containers (see environments.HostOverride)
+	Source = "docker"
+	// RequestTimeout bounds a call to the Docker engine
+	RequestTimeout = 10 * time.Second
+
+	// Compose labels naming the service and project of a container
+	serviceLabel = "com.docker.compose.service"
+	projectLabel = "com.docker.compose.project"
+	// maxResponseSize bounds how much of a Docker engine response is read
+	maxResponseSize = 16 << 20
+)
+
+// Endpoint is a container port published on the host
+type Endpoint struct {
+	ContainerID string `json:"containerId"` // Short ID
+	Container   string `json:"container"`
+	Service     string `json:"service,omitempty"` // Compose service
+	Project     string `json:"project,omitempty"` // Compose project
+	Image       string `json:"image"`
+	Port        int    `json:"port"` // Port inside the container
+	PublicPort  int    `json:"publicPort"`
+	Host        string `json:"host"` // Address the published port is reached at
+	URL         string `json:"url"`
+}
+
+// Client talks to a Docker engine
+type Client struct {
+	http *http.Client
+	base string
+	host string // Address published ports are reached at
+}
+
+// NewClient creates a client for a Docker engine address ("unix:///path" or "tcp://host:port"); an
+// empty address uses DOCKER_HOST, then DefaultHost
+func NewClient(address string) (*Client, error) {
+	if address == "" {
+		address = os.Getenv("DOCKER_HOST")
+	}
+	if address == "" {
+		address = DefaultHost
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, apperrors.Invalidf("invalid Docker host %q", address)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		dialer := &net.Dialer{Timeout: RequestTimeout}
+		transport := &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", u.Path)
+		}}
+		return &Client{http: &http.Client{Transport: transport, Timeout: RequestTimeout}, base: "http://docker", host: "127.0.0.1"}, nil
+	case "tcp":
+		if u.Host == "" {
+			return nil, apperrors.Invalidf("invalid Docker host %q", address)
+		}
+		// Ports published by a remote engine are reached on its host
+		return &Client{http: &http.Client{Timeout: RequestTimeout}, base: "http://" + u.Host, host: u.Hostname()}, nil
+	}
+	return nil, apperrors.Invalidf("unsupported Docker host %q: use unix:// or tcp://", address)
+}
+
+// container is the part of a /containers/json entry the endpoints are made from
+type container struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	Labels map[string]string `json:"Labels"`
+	Ports  []struct {
+		IP          string `json:"IP"`
+		PrivatePort int    `json:"PrivatePort"`
+		PublicPort  int    `json:"PublicPort"`
+		Type        string `json:"Type"`
+	} `json:"Ports"`
+}
+
+// Endpoints lists the TCP ports running containers publish, by project, service, container and port.
+// A port published on both IPv4 and IPv6 is listed once.
+func (c *Client) Endpoints(ctx context.Context) ([]Endpoint, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+"/containers/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to reach the Docker engine")
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to read the Docker engine response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, apperrors.New(apperrors.IOError, "Docker engine answered %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	var containers []container
+	if err := json.Unmarshal(data, &containers); err != nil {
+		return nil, fmt.Errorf("failed to parse the Docker engine response: %w", err)
+	}
+
+	endpoints := []Endpoint{}
+	seen := make(map[string]bool)
+	for _, ctr := range containers {
+		name := ctr.ID
+		if len(ctr.Names) > 0 {
+			name = strings.TrimPrefix(ctr.Names[0], "/")
+		}
+		for _, p := range ctr.Ports {
+			key := ctr.ID + "/" + strconv.Itoa(p.PrivatePort) + "/" + strconv.Itoa(p.PublicPort)
+			if p.PublicPort == 0 || p.Type != "tcp" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			host := c.host
+			if ip := net.ParseIP(p.IP); ip != nil && !ip.IsUnspecified() {
+				host = p.IP
+			}
+			endpoints = append(endpoints, Endpoint{
+				ContainerID: ctr.ID[:min(12, len(ctr.ID))],
+				Container:   name,
+				Service:     ctr.Labels[serviceLabel],
+				Project:     ctr.Labels[projectLabel],
+				Image:       ctr.Image,
+				Port:        p.PrivatePort,
+				PublicPort:  p.PublicPort,
+				Host:        host,
+				URL:         endpointURL(host, p.PrivatePort, p.PublicPort),
+			})
+		}
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		a, b := endpoints[i], endpoints[j]
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.Container != b.Container {
+			return a.Container < b.Container
+		}
+		return a.Port < b.Port
+	})
+	return endpoints, nil
+}
+
+// endpointURL is the URL of a published port; containers listening on 443 or 8443 are assumed to
+// serve HTTPS
+func endpointURL(host string, port int, publicPort int) string {
+	scheme := "http"
+	if port == 443 || port == 8443 {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(publicPort))
+}
+
+// HostOverrides maps the names containers are known by on their network (compose service and
+// container name) with the container port to the published port, so requests written against
+// http://api:8080 reach the container from the host. The first endpoint wins for a name taken by
+// several containers, e.g. scaled services.
+func HostOverrides(endpoints []Endpoint) []environments.HostOverride {
+	overrides := []environments.HostOverride{}
+	seen := make(map[string]bool)
+	for _, e := range endpoints {
+		target := net.JoinHostPort(e.Host, strconv.Itoa(e.PublicPort))
+		for _, name := range []string{e.Service, e.Container} {
+			host := strings.ToLower(net.JoinHostPort(name, strconv.Itoa(e.Port)))
+			if name == "" || seen[host] {
+				continue
+			}
+			seen[host] = true
+			overrides = append(overrides, environments.HostOverride{Host: host, Target: target, Source: Source})
+		}
+	}
+	return overrides
+}
+
+// Find returns the endpoint of a container or compose service for a container port
+func Find(endpoints []Endpoint, name string, port int) (Endpoint, bool) {
+	for _, e := range endpoints {
+		if (e.Container == name || e.Service == name || e.ContainerID == name) && e.Port == port {
+			return e, true
+		}
+	}
+	return Endpoint{}, false
+}
@@ -0,0 +1,105 @@
+package docker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"paperbox/internal/apperrors"
+)
+
+// containersJSON is a /containers/json answer: a compose service published on IPv4 and IPv6, a
+// container bound to one address and a port that is not published
+const containersJSON = `[
+	{"Id": "b1946ac92492d2347c6235b4d2611184", "Names": ["/shop-api-1"], "Image": "shop/api",
+	 "Labels": {"com.docker.compose.service": "api", "com.docker.compose.project": "shop"},
+	 "Ports": [{"IP": "0.0.0.0", "PrivatePort": 8080, "PublicPort": 32768, "Type": "tcp"},
+	           {"IP": "::", "PrivatePort": 8080, "PublicPort": 32768, "Type": "tcp"},
+	           {"PrivatePort": 9090, "Type": "tcp"}]},
+	{"Id": "591785b794601e212b260e25925636fd", "Names": ["/proxy"], "Image": "nginx",
+	 "Ports": [{"IP": "127.0.0.2", "PrivatePort": 443, "PublicPort": 8443, "Type": "tcp"},
+	           {"IP": "0.0.0.0", "PrivatePort": 53, "PublicPort": 5353, "Type": "udp"}]}
+]`
+
+func TestEndpoints(t *testing.T) {
+	dir, err := os.MkdirTemp("", "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(containersJSON))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	client, err := NewClient("unix://" + socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints, err := client.Endpoints(context.Background())
+	if err != nil {
+		t.Fatalf("Endpoints() error = %v", err)
+	}
+	if len(endpoints) != 2 {
+		t.Fatalf("Endpoints() = %+v, want the two published TCP ports", endpoints)
+	}
+	proxy, api := endpoints[0], endpoints[1]
+	if proxy.Container != "proxy" || proxy.URL != "https://127.0.0.2:8443" {
+		t.Errorf("Endpoints()[0] = %+v", proxy)
+	}
+	if api.Service != "api" || api.Project != "shop" || api.ContainerID != "b1946ac92492" || api.URL != "http://127.0.0.1:32768" {
+		t.Errorf("Endpoints()[1] = %+v", api)
+	}
+
+	overrides := HostOverrides(endpoints)
+	if len(overrides) != 3 || overrides[0].Host != "proxy:443" || overrides[1].Host != "api:8080" || overrides[2].Host != "shop-api-1:8080" {
+		t.Fatalf("HostOverrides() = %+v", overrides)
+	}
+	if overrides[1].Target != "127.0.0.1:32768" || overrides[1].Source != Source {
+		t.Errorf("HostOverrides()[1] = %+v", overrides[1])
+	}
+	if e, ok := Find(endpoints, "api", 8080); !ok || e.Container != "shop-api-1" {
+		t.Errorf("Find(api, 8080) = %+v, %v", e, ok)
+	}
+	if _, ok := Find(endpoints, "api", 9090); ok {
+		t.Error("Find() returned a port that is not published")
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "permission denied", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("tcp://" + server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Endpoints(context.Background()); apperrors.CodeOf(err) != apperrors.IOError {
+		t.Errorf("Endpoints() error = %v, want the engine's answer", err)
+	}
+	if client, _ := NewClient("tcp://10.0.0.5:2375"); client.host != "10.0.0.5" {
+		t.Errorf("remote engine host = %q, want published ports reached on it", client.host)
+	}
+	for _, bad := range []string{"npipe:////./pipe/docker_engine", "tcp://", "ssh://user@host"} {
+		if _, err := NewClient(bad); err == nil {
+			t.Errorf("NewClient(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package docs
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/requests"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// Format is the output format of generated documentation
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+
+	// maxHeadingLevel is the deepest markdown heading used; deeper folders reuse it
+	maxHeadingLevel = 6
+	// redactedValue replaces the values of credential headers
+	redactedValue = "<redacted>"
+)
+
+// Generate renders the documentation of a folder and everything below it
+func Generate(cfg *requests.RequestsConfig, folderID string, format Format) (string, error) {
+	folder, exists := cfg.Values[folderID]
+	if !exists || folder.Type != requests.ItemTypeFolder {
+		return "", apperrors.NotFoundf("folder not found")
+	}
+
+	markdown := Markdown(cfg, folderID)
+
+	switch format {
+	case FormatMarkdown:
+		return markdown, nil
+	case FormatHTML:
+		return renderHTML(folder.Name, markdown)
+	default:
+		return "", fmt.Errorf("unsupported documentation format '%s'", format)
+	}
+}
+
+// Markdown renders the documentation of an item and its descendants as markdown
+func Markdown(cfg *requests.RequestsConfig, itemID string) string {
+	var b strings.Builder
+	writeItem(&b, cfg, itemID, 1, make(map[string]bool))
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// writeItem writes an item at the given heading level; visited guards against cycles
+func writeItem(b *strings.Builder, cfg *requests.RequestsConfig, id string, level int, visited map[string]bool) {
+	item, exists := cfg.Values[id]
+	if !exists || visited[id] {
+		return
+	}
+	visited[id] = true
+
+	heading := strings.Repeat("#", min(level, maxHeadingLevel))
+
+	if item.Type == requests.ItemTypeFolder {
+		fmt.Fprintf(b, "%s %s\n\n", heading, item.Name)
+		writeDescription(b, item.Description)
+		for _, childID := range item.Children {
+			writeItem(b, cfg, childID, level+1, visited)
+		}
+		return
+	}
+
+	fmt.Fprintf(b, "%s %s\n\n", heading, item.Name)
+	fmt.Fprintf(b, "`%s %s`\n\n", item.Method, item.Path)
+	writeDescription(b, item.Description)
+
+	if item.Auth != nil && item.Auth.Type != requests.AuthTypeInherit {
+		fmt.Fprintf(b, "**Auth:** %s\n\n", item.Auth.Type)
+	}
+
+	writeParams(b, "Path variables", item.PathVars)
+	writeParams(b, "Query parameters", item.QueryParams)
+
+	var headers []requests.Param
+	for _, h := range item.Headers {
+		if h.Disabled {
+			continue
+		}
+		value := h.Value
+		if requests.IsSecretHeader(h) {
+			value = redactedValue
+		}
+		headers = append(headers, requests.Param{Key: h.Key, Value: value})
+	}
+	writeParams(b, "Headers", headers)
+
+	var fields []requests.Param
+	for _, f := range item.FormFields {
+		fields = append(fields, requests.Param{Key: f.Key, Value: f.Value, Disabled: f.Disabled})
+	}
+	writeParams(b, "Form fields", fields)
+
+	if item.Body != "" {
+		f := fence(item.Body)
+		fmt.Fprintf(b, "**Body**\n\n%s\n%s\n%s\n\n", f, item.Body, f)
+	}
+}
+
+// writeDescription writes a markdown description followed by a blank line
+func writeDescription(b *strings.Builder, description string) {
+	if description = strings.TrimSpace(description); description != "" {
+		b.WriteString(description)
+		b.WriteString("\n\n")
+	}
+}
+
+// writeParams writes enabled parameters as a two-column table
+func writeParams(b *strings.Builder, title string, params []requests.Param) {
+	var rows []requests.Param
+	for _, p := range params {
+		if !p.Disabled {
+			rows = append(rows, p)
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "**%s**\n\n| Name | Value |\n| --- | --- |\n", title)
+	for _, p := range rows {
+		fmt.Fprintf(b, "| `%s` | %s |\n", escapeCell(p.Key), escapeCell(p.Value))
+	}
+	b.WriteString("\n")
+}
+
+// fence returns a code fence longer than any backtick run inside content
+func fence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// escapeCell keeps a value on one table row
+func escapeCell(value string) string {
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.ReplaceAll(value, "\n", " ")
+}
+
+// htmlPage wraps rendered documentation in a standalone page
+var htmlPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 960px; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; color: #1f2328; }
+code, pre { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; background: #f6f8fa; border-radius: 4px; }
+code { padding: 0.1em 0.3em; }
+pre { padding: 1rem; overflow-x: auto; }
+table { border-collapse: collapse; margin-bottom: 1rem; }
+th, td { border: 1px solid #d0d7de; padding: 0.3rem 0.6rem; text-align: left; }
+</style>
+</head>
+<body>
+{{.Content}}
+</body>
+</html>
+`))
+
+// renderHTML converts markdown to a standalone HTML page. Raw HTML in descriptions is omitted.
+func renderHTML(title string, markdown string) (string, error) {
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+	var content bytes.Buffer
+	if err := md.Convert([]byte(markdown), &content); err != nil {
+		return "", fmt.Errorf("failed to render documentation: %w", err)
+	}
+
+	var page bytes.Buffer
+	err := htmlPage.Execute(&page, struct {
+		Title   string
+		Content template.HTML
+	}{
+		Title:   title,
+		Content: template.HTML(content.String()),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render documentation: %w", err)
+	}
+	return page.String(), nil
+}
@@ -0,0 +1,82 @@
+package docs
+
+import (
+	"strings"
+	"testing"
+
+	"paperbox/internal/config/requests"
+)
+
+func testConfig() *requests.RequestsConfig {
+	return &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"api": {
+				Type:        requests.ItemTypeFolder,
+				Name:        "API",
+				Description: "Public API.",
+				Children:    []string{"users"},
+			},
+			"users": {
+				Type:     requests.ItemTypeFolder,
+				Name:     "Users",
+				Children: []string{"get"},
+			},
+			"get": {
+				Type:        requests.ItemTypeRequest,
+				Name:        "Get user",
+				Description: "Returns a *single* user. <script>alert(1)</script>",
+				Method:      "GET",
+				Path:        "/users/:id",
+				PathVars:    []requests.Param{{Key: "id", Value: "42"}},
+				Headers: []requests.Header{
+					{Key: "Authorization", Value: "Bearer secret"},
+					{Key: "Accept", Value: "application/json"},
+					{Key: "X-Debug", Value: "1", Disabled: true},
+				},
+				Body: "```",
+			},
+		},
+	}
+}
+
+func TestGenerateMarkdown(t *testing.T) {
+	got, err := Generate(testConfig(), "api", FormatMarkdown)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"# API\n\nPublic API.\n",
+		"## Users\n",
+		"### Get user\n\n`GET /users/:id`\n",
+		"| `id` | 42 |",
+		"| `Authorization` | <redacted> |",
+		"| `Accept` | application/json |",
+		"````\n```\n````",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Generate() missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "secret") || strings.Contains(got, "X-Debug") {
+		t.Errorf("Generate() leaked a secret or disabled header:\n%s", got)
+	}
+}
+
+func TestGenerateHTML(t *testing.T) {
+	got, err := Generate(testConfig(), "api", FormatHTML)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(got, "<title>API</title>") || !strings.Contains(got, "<em>single</em>") {
+		t.Errorf("Generate() HTML missing title or rendered markdown:\n%s", got)
+	}
+	if strings.Contains(got, "<script>") {
+		t.Errorf("Generate() HTML contains raw script from description")
+	}
+
+	if _, err := Generate(testConfig(), "get", FormatHTML); err == nil {
+		t.Errorf("Generate() on a request should fail")
+	}
+}
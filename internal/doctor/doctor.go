@@ -0,0 +1,610 @@
+// Package doctor checks the app data directory for problems that keep paperbox from starting or
+// saving: file permissions, disk space, damaged or invalid configs, dangling child references,
+// left-over response files and latency series, stale temp files and team sync. Each problem comes
+// with a suggested fix; the safe ones can be applied with Fix.
+package doctor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/plugins"
+	"paperbox/internal/config/requests"
+	"paperbox/internal/config/storage"
+	"paperbox/internal/config/user"
+	"paperbox/internal/metrics"
+
+	"github.com/adrg/xdg"
+)
+
+const (
+	// MinFreeSpace is the free disk space below which saves are likely to fail
+	MinFreeSpace = 10 << 20
+	// LowFreeSpace is the free disk space below which a warning is reported
+	LowFreeSpace = 100 << 20
+	// StaleAfter is how old a temp or lock file must be before it counts as left over
+	StaleAfter = time.Minute
+)
+
+// Status is the outcome of a check, or the severity of a problem
+type Status string
+
+const (
+	StatusOK      Status = "ok"
+	StatusWarning Status = "warning"
+	StatusError   Status = "error"
+)
+
+// rank orders statuses from best to worst
+func (s Status) rank() int {
+	switch s {
+	case StatusError:
+		return 2
+	case StatusWarning:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Report is the result of Run: the worst status and every check in order
+type Report struct {
+	Status    Status    `json:"status"`
+	Dir       string    `json:"dir"`
+	CheckedAt time.Time `json:"checkedAt"`
+	Checks    []Check   `json:"checks"`
+}
+
+// Check is one area of the diagnostics; its status is that of its worst problem
+type Check struct {
+	Name     string    `json:"name"`
+	Status   Status    `json:"status"`
+	Summary  string    `json:"summary"`
+	Problems []Problem `json:"problems,omitempty"`
+}
+
+// Problem is something wrong with a file or item, with what to do about it. Fixable problems are
+// applied by Fix.
+type Problem struct {
+	Severity Status `json:"severity"`
+	Message  string `json:"message"`
+	Path     string `json:"path,omitempty"`
+	ItemID   string `json:"itemId,omitempty"`
+	Fix      string `json:"fix,omitempty"`
+	Fixable  bool   `json:"fixable,omitempty"`
+
+	apply func() error
+}
+
+// Options select what Run checks
+type Options struct {
+	Dir         string            // App data directory
+	ResponseDir string            // Where large response bodies are written (response.TempDir)
+	Since       time.Time         // Response files older than this were left by an earlier run
+	Metrics     *metrics.Recorder // Latency series checked against the request tree; nil skips them
+}
+
+// DefaultDir returns the app data directory
+func DefaultDir() string {
+	return path.Join(xdg.DataHome, "paperbox")
+}
+
+// Run checks the app data directory. It only reads, except for a temp file written to check that
+// the directory is writable.
+func Run(opts Options) *Report {
+	report := &Report{Status: StatusOK, Dir: opts.Dir, CheckedAt: time.Now()}
+	settings := activeSettings(opts.Dir)
+	tree := readTree(opts.Dir, settings)
+
+	report.Checks = []Check{
+		checkPermissions(opts.Dir),
+		checkDiskSpace(opts.Dir),
+		checkConfigs(opts.Dir, tree),
+		checkReferences(tree),
+		checkResponseCache(opts, tree),
+		checkLockFiles(opts.Dir, report.CheckedAt),
+		checkSync(opts.Dir, settings),
+	}
+	for _, check := range report.Checks {
+		if check.Status.rank() > report.Status.rank() {
+			report.Status = check.Status
+		}
+	}
+	return report
+}
+
+// Fix applies the fixable problems of a report and returns how many were fixed. Run again to see
+// what is left.
+func Fix(report *Report) (int, error) {
+	fixed := 0
+	var errs []error
+	for _, check := range report.Checks {
+		for _, problem := range check.Problems {
+			if problem.apply == nil {
+				continue
+			}
+			if err := problem.apply(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", problem.Message, err))
+				continue
+			}
+			fixed++
+		}
+	}
+	return fixed, errors.Join(errs...)
+}
+
+// WriteText writes the report for a terminal
+func (r *Report) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "paperbox doctor: %s (%s)\n\n", r.Dir, r.Status)
+	for _, check := range r.Checks {
+		fmt.Fprintf(w, "[%s] %s: %s\n", check.Status, check.Name, check.Summary)
+		for _, problem := range check.Problems {
+			fmt.Fprintf(w, "    - %s", problem.Message)
+			if problem.Path != "" {
+				fmt.Fprintf(w, " (%s)", problem.Path)
+			}
+			fmt.Fprintln(w)
+			if problem.Fix != "" {
+				fixable := ""
+				if problem.Fixable {
+					fixable = " [--fix]"
+				}
+				fmt.Fprintf(w, "      fix: %s%s\n", problem.Fix, fixable)
+			}
+		}
+	}
+}
+
+// newCheck builds a check from its problems; summary is used when there are none
+func newCheck(name, summary string, problems []Problem) Check {
+	check := Check{Name: name, Status: StatusOK, Summary: summary, Problems: problems}
+	errorCount, warningCount := 0, 0
+	for _, p := range problems {
+		if p.Severity == StatusError {
+			errorCount++
+		} else {
+			warningCount++
+		}
+		if p.Severity.rank() > check.Status.rank() {
+			check.Status = p.Severity
+		}
+	}
+	if len(problems) > 0 {
+		check.Summary = fmt.Sprintf("%d error(s), %d warning(s)", errorCount, warningCount)
+	}
+	return check
+}
+
+// fixable marks a problem as applied by Fix
+func fixable(p Problem, apply func() error) Problem {
+	p.Fixable = true
+	p.apply = apply
+	return p
+}
+
+// settings is what the checks need from the user config of the active profile
+type settings struct {
+	file           string
+	storageBackend string
+	sync           user.SyncSettings
+}
+
+// activeSettings reads the user config of the profile used last; defaults when it is unreadable
+func activeSettings(dir string) settings {
+	s := settings{file: filepath.Join(dir, user.ConfigFileName), storageBackend: user.StorageJSON}
+	var active struct {
+		Name string `json:"name"`
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, user.ProfilesDirName, user.ActiveProfileFileName)); err == nil &&
+		json.Unmarshal(data, &active) == nil && active.Name != "" && active.Name != user.DefaultProfile &&
+		filepath.Base(active.Name) == active.Name {
+		s.file = filepath.Join(dir, user.ProfilesDirName, active.Name, user.ConfigFileName)
+	}
+	if data, err := os.ReadFile(s.file); err == nil {
+		if cfg, _, err := user.Decode(data); err == nil {
+			s.storageBackend, s.sync = cfg.StorageBackend, cfg.Sync
+		}
+	}
+	return s
+}
+
+// tree is the request tree as stored, with every validation issue
+type tree struct {
+	file   string // File or database holding it
+	config *requests.RequestsConfig
+	issues []requests.Issue
+	err    error // Why it could not be read
+}
+
+// readTree reads the request tree from the selected storage backend without validating it
+func readTree(dir string, s settings) tree {
+	t := tree{file: filepath.Join(dir, requests.RequestsFileName)}
+	var data json.RawMessage
+	switch s.storageBackend {
+	case user.StorageSQLite:
+		t.file = filepath.Join(dir, requests.DatabaseFileName)
+		if _, err := os.Stat(t.file); err != nil {
+			return t
+		}
+		db, err := storage.NewSQLiteStorage(t.file)
+		if err != nil {
+			t.err = err
+			return t
+		}
+		defer db.Close()
+		// Rows are keyed by the path of the JSON file they replaced
+		t.err = db.Load(path.Join(dir, requests.RequestsFileName), &data)
+	case user.StorageFolders:
+		t.file = filepath.Join(dir, requests.CollectionsDirName)
+		t.err = requests.NewCollectionStorage(t.file).Eager().Load("", &data)
+	default:
+		raw, err := os.ReadFile(t.file)
+		if errors.Is(err, os.ErrNotExist) {
+			return t
+		}
+		data, t.err = raw, err
+	}
+	if t.err != nil || len(data) == 0 {
+		return t
+	}
+	t.config, t.issues, t.err = requests.Check(data)
+	return t
+}
+
+// isReferenceIssue reports whether an issue is about how items refer to each other
+func isReferenceIssue(issue requests.Issue) bool {
+	return issue.Field == "children" || issue.Field == "parent"
+}
+
+// checkPermissions checks that the directory is writable and the configs can be read and saved
+func checkPermissions(dir string) Check {
+	var problems []Problem
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return newCheck("permissions", "nothing saved yet", nil)
+	}
+	probe, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		problems = append(problems, Problem{
+			Severity: StatusError, Message: "the app data directory is not writable", Path: dir,
+			Fix: "Give your user write access to the directory (e.g. chmod u+rwx)",
+		})
+	} else {
+		probe.Close()
+		os.Remove(probe.Name())
+	}
+
+	for _, file := range configFiles(dir) {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		if f, err := os.Open(file); err != nil {
+			problems = append(problems, Problem{
+				Severity: StatusError, Message: "the file cannot be read", Path: file,
+				Fix: "Give your user read access to the file (e.g. chmod u+rw)",
+			})
+			continue
+		} else {
+			f.Close()
+		}
+		if info.Mode().Perm()&0o200 == 0 {
+			problems = append(problems, Problem{
+				Severity: StatusError, Message: "the file is read-only, so changes cannot be saved", Path: file,
+				Fix: "Give your user write access to the file (e.g. chmod u+w)",
+			})
+		}
+		if runtime.GOOS != "windows" && filepath.Base(file) == environments.EnvironmentsFileName && info.Mode().Perm()&0o077 != 0 {
+			file := file
+			problems = append(problems, fixable(Problem{
+				Severity: StatusWarning, Message: "the environments file holds secrets but can be read by other users", Path: file,
+				Fix: "Restrict the file to your user (chmod 600)",
+			}, func() error { return os.Chmod(file, 0o600) }))
+		}
+	}
+	return newCheck("permissions", "the configs can be read and saved", problems)
+}
+
+// checkDiskSpace checks the free space on the drive holding the directory
+func checkDiskSpace(dir string) Check {
+	existing := dir
+	for {
+		if _, err := os.Stat(existing); err == nil || filepath.Dir(existing) == existing {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	free, err := freeSpace(existing)
+	if err != nil {
+		return newCheck("diskSpace", "free space unknown", []Problem{{
+			Severity: StatusWarning, Message: fmt.Sprintf("free space could not be read: %v", err), Path: existing,
+		}})
+	}
+	summary := fmt.Sprintf("%s free", formatBytes(free))
+	switch {
+	case free < MinFreeSpace:
+		return newCheck("diskSpace", summary, []Problem{{
+			Severity: StatusError, Message: fmt.Sprintf("only %s free; saves will fail", formatBytes(free)), Path: existing,
+			Fix: "Free up space on the drive",
+		}})
+	case free < LowFreeSpace:
+		return newCheck("diskSpace", summary, []Problem{{
+			Severity: StatusWarning, Message: fmt.Sprintf("only %s free", formatBytes(free)), Path: existing,
+			Fix: "Free up space on the drive before the configs can no longer be saved",
+		}})
+	}
+	return newCheck("diskSpace", summary, nil)
+}
+
+// configFiles lists the config files of the directory: requests, environments, plugins and the
+// user config of every profile
+func configFiles(dir string) []string {
+	files := []string{
+		filepath.Join(dir, requests.RequestsFileName),
+		filepath.Join(dir, environments.EnvironmentsFileName),
+		filepath.Join(dir, plugins.PluginsFileName),
+		filepath.Join(dir, user.ConfigFileName),
+	}
+	profiles, _ := filepath.Glob(filepath.Join(dir, user.ProfilesDirName, "*", user.ConfigFileName))
+	sort.Strings(profiles)
+	return append(files, profiles...)
+}
+
+// checkConfigs checks that every config file is intact, readable by this version and valid
+func checkConfigs(dir string, t tree) Check {
+	var problems []Problem
+	for _, file := range configFiles(dir) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		if problem := storage.Verify(file); problem != "" {
+			problems = append(problems, Problem{
+				Severity: StatusError, Message: "the file is damaged: " + problem, Path: file,
+				Fix: "It is replaced by its latest intact backup on the next start; without one, restore it from your own backups",
+			})
+			continue
+		}
+		problems = append(problems, fileProblems(file, data)...)
+	}
+
+	if t.err != nil {
+		problems = append(problems, Problem{
+			Severity: StatusError, Message: fmt.Sprintf("the request tree cannot be read: %v", t.err), Path: t.file,
+			Fix: "Restore the file from its backup (" + requests.RequestsFileName + storage.BackupSuffix + "1) or your own backups",
+		})
+	}
+	for _, issue := range t.issues {
+		if isReferenceIssue(issue) {
+			continue
+		}
+		severity := StatusError
+		if issue.Severity == requests.SeverityWarning {
+			severity = StatusWarning
+		}
+		problems = append(problems, Problem{
+			Severity: severity, Message: issue.String(), Path: t.file, ItemID: issue.ItemID,
+			Fix: "Edit the item to fix the field, or delete it",
+		})
+	}
+
+	corrupt, _ := filepath.Glob(filepath.Join(dir, "*.corrupt-*"))
+	more, _ := filepath.Glob(filepath.Join(dir, requests.CollectionsDirName, "*.corrupt-*"))
+	for _, file := range append(corrupt, more...) {
+		problems = append(problems, Problem{
+			Severity: StatusWarning, Message: "a damaged file was set aside", Path: file,
+			Fix: "Check it for data worth recovering, then delete it",
+		})
+	}
+	return newCheck("configs", "every config is intact and valid", problems)
+}
+
+// fileProblems decodes a config file like its manager and reports why it would not load
+func fileProblems(file string, data []byte) []Problem {
+	var probe struct {
+		Version   int             `json:"version"`
+		Encrypted json.RawMessage `json:"encrypted"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return []Problem{{
+			Severity: StatusError, Message: fmt.Sprintf("the file is not valid JSON: %v", err), Path: file,
+			Fix: "Fix the syntax or restore the file from its backup (" + filepath.Base(file) + storage.BackupSuffix + "1)",
+		}}
+	}
+	if probe.Encrypted != nil {
+		return nil
+	}
+
+	var supported int
+	var err error
+	var issues []user.FieldIssue
+	switch filepath.Base(file) {
+	case requests.RequestsFileName:
+		supported = requests.CurrentVersion // Items are checked with the request tree
+	case environments.EnvironmentsFileName:
+		supported = environments.CurrentVersion
+		if probe.Version <= supported {
+			_, err = environments.Decode(data)
+		}
+	case plugins.PluginsFileName:
+		supported = plugins.CurrentVersion
+		if probe.Version <= supported {
+			_, err = plugins.Decode(data)
+		}
+	default:
+		supported = user.CurrentVersion
+		_, issues, err = user.Decode(data)
+	}
+
+	if probe.Version > supported {
+		return []Problem{{
+			Severity: StatusWarning, Path: file,
+			Message: fmt.Sprintf("the file was written by a newer version of paperbox (version %d, this one supports %d) and is read-only", probe.Version, supported),
+			Fix:     "Update paperbox",
+		}}
+	}
+	if err != nil {
+		return []Problem{{
+			Severity: StatusError, Message: err.Error(), Path: file,
+			Fix: "Fix the value in the file or restore it from its backup (" + filepath.Base(file) + storage.BackupSuffix + "1)",
+		}}
+	}
+	var problems []Problem
+	for _, issue := range issues {
+		problems = append(problems, Problem{
+			Severity: StatusWarning, Message: issue.Message, Path: file,
+			Fix: "The setting is reset to its default when paperbox starts; set it again in the settings",
+		})
+	}
+	return problems
+}
+
+// checkReferences checks that folders only refer to items that exist, once each
+func checkReferences(t tree) Check {
+	var problems []Problem
+	for _, issue := range t.issues {
+		if !isReferenceIssue(issue) {
+			continue
+		}
+		problem := Problem{
+			Severity: StatusError, Message: issue.String(), Path: t.file, ItemID: issue.ItemID,
+			Fix: "Remove the reference from the folder's children, or restore the missing item from a backup",
+		}
+		if issue.Field == "parent" {
+			problem.Severity = StatusWarning
+			problem.Fix = "Move it into a folder (AdoptOrphans) or delete it (PurgeOrphans)"
+		}
+		problems = append(problems, problem)
+	}
+	return newCheck("references", "every item is in the tree", problems)
+}
+
+// checkResponseCache looks for response files and latency series nothing refers to anymore
+func checkResponseCache(opts Options, t tree) Check {
+	var problems []Problem
+	if opts.ResponseDir != "" {
+		entries, _ := os.ReadDir(opts.ResponseDir)
+		var stale []string
+		var size int64
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil || entry.IsDir() || !info.ModTime().Before(opts.Since) {
+				continue
+			}
+			stale = append(stale, filepath.Join(opts.ResponseDir, entry.Name()))
+			size += info.Size()
+		}
+		if len(stale) > 0 {
+			problems = append(problems, fixable(Problem{
+				Severity: StatusWarning, Path: opts.ResponseDir,
+				Message: fmt.Sprintf("%d response file(s) (%s) were left by an earlier run", len(stale), formatBytes(uint64(size))),
+				Fix:     "Delete them",
+			}, func() error {
+				var errs []error
+				for _, file := range stale {
+					if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+						errs = append(errs, err)
+					}
+				}
+				return errors.Join(errs...)
+			}))
+		}
+	}
+
+	if opts.Metrics != nil && t.config != nil && t.err == nil {
+		var orphaned []string
+		for _, id := range opts.Metrics.LatencyRequestIDs() {
+			if _, exists := t.config.Values[id]; !exists {
+				orphaned = append(orphaned, id)
+			}
+		}
+		if len(orphaned) > 0 {
+			recorder := opts.Metrics
+			problems = append(problems, fixable(Problem{
+				Severity: StatusWarning, Path: filepath.Join(opts.Dir, metrics.FileName),
+				Message: fmt.Sprintf("the latency history of %d deleted request(s) is still kept", len(orphaned)),
+				Fix:     "Delete it",
+			}, func() error {
+				recorder.ForgetLatency(orphaned...)
+				return recorder.Flush()
+			}))
+		}
+	}
+	return newCheck("responseCache", "no left-over responses", problems)
+}
+
+// checkLockFiles looks for lock files and temp files of interrupted saves
+func checkLockFiles(dir string, now time.Time) Check {
+	var problems []Problem
+	dirs := []string{dir, filepath.Join(dir, requests.CollectionsDirName)}
+	profiles, _ := filepath.Glob(filepath.Join(dir, user.ProfilesDirName, "*"))
+	for _, d := range append(dirs, profiles...) {
+		entries, _ := os.ReadDir(d)
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || !(strings.Contains(name, ".tmp.") || strings.HasSuffix(name, ".lock")) {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil || now.Sub(info.ModTime()) < StaleAfter {
+				continue
+			}
+			file := filepath.Join(d, name)
+			message := "a save was interrupted and left its temp file"
+			if strings.HasSuffix(name, ".lock") {
+				message = "a lock file was left by a process that is no longer running"
+			}
+			problems = append(problems, fixable(Problem{
+				Severity: StatusWarning, Message: message, Path: file,
+				Fix: "Delete it",
+			}, func() error { return os.Remove(file) }))
+		}
+	}
+	return newCheck("lockFiles", "no stale lock or temp files", problems)
+}
+
+// checkSync reports saves waiting to be pushed to the team sync server
+func checkSync(dir string, s settings) Check {
+	if s.sync.Server == "" {
+		return newCheck("sync", "team sync is off", nil)
+	}
+	var problems []Problem
+	if _, err := os.Stat(filepath.Join(dir, requests.SyncStateFileName)); errors.Is(err, os.ErrNotExist) {
+		problems = append(problems, Problem{
+			Severity: StatusWarning, Message: fmt.Sprintf("the request tree was never synced with %s", s.sync.Server),
+			Fix: "Check the server address, the workspace and the token variable in the settings",
+		})
+	}
+	var queued map[string]json.RawMessage
+	if data, err := os.ReadFile(filepath.Join(dir, requests.SyncQueueFileName)); err == nil && json.Unmarshal(data, &queued) == nil && len(queued) > 0 {
+		problems = append(problems, Problem{
+			Severity: StatusWarning, Message: fmt.Sprintf("%d file(s) wait to be pushed to %s", len(queued), s.sync.Server),
+			Path: filepath.Join(dir, requests.SyncQueueFileName),
+			Fix:  "Connect to the sync server; queued saves are retried in the background while paperbox runs",
+		})
+	}
+	return newCheck("sync", fmt.Sprintf("in sync with %s", s.sync.Server), problems)
+}
+
+// formatBytes renders a size with a binary unit
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
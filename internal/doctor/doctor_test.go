@@ -0,0 +1,151 @@
+package doctor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"paperbox/internal/config/requests"
+	"paperbox/internal/config/user"
+	"paperbox/internal/metrics"
+)
+
+func findCheck(t *testing.T, report *Report, name string) Check {
+	t.Helper()
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	t.Fatalf("report has no %s check", name)
+	return Check{}
+}
+
+func writeFile(t *testing.T, path, data string, perm os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), perm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunEmptyDir(t *testing.T) {
+	report := Run(Options{Dir: filepath.Join(t.TempDir(), "paperbox")})
+	for _, check := range report.Checks {
+		if check.Name != "diskSpace" && check.Status != StatusOK {
+			t.Errorf("%s = %s %v, want ok before anything was saved", check.Name, check.Status, check.Problems)
+		}
+	}
+}
+
+func TestRunFindsAndFixesProblems(t *testing.T) {
+	dir := t.TempDir()
+	responses := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+
+	writeFile(t, filepath.Join(dir, requests.RequestsFileName), fmt.Sprintf(`{
+		"version": %d,
+		"rootOrder": ["folder1"],
+		"values": {
+			"folder1": {"type": "folder", "name": "API", "children": ["req1", "gone"]},
+			"req1": {"type": "request", "name": "Get", "method": "GET", "url": "https://example.com"}
+		}
+	}`, requests.CurrentVersion), 0o644)
+	writeFile(t, filepath.Join(dir, "environments.json"), `{"version": 1, "active": "", "environments": {}}`, 0o644)
+	writeFile(t, filepath.Join(dir, "plugins.json"), `{"version": 99}`, 0o644)
+	writeFile(t, filepath.Join(dir, user.ConfigFileName), `{"version": 1, "theme": `, 0o644)
+
+	stale := filepath.Join(dir, "requests.json.tmp.123")
+	writeFile(t, stale, "{", 0o644)
+	os.Chtimes(stale, old, old)
+	fresh := filepath.Join(dir, "user.json.tmp.456")
+	writeFile(t, fresh, "{", 0o644)
+
+	response := filepath.Join(responses, "response-1.bin")
+	writeFile(t, response, "body", 0o644)
+	os.Chtimes(response, old, old)
+
+	recorder := metrics.NewAt(filepath.Join(dir, metrics.FileName))
+	recorder.Record(metrics.Sample{RequestID: "req1", Status: 200, DurationMs: 5, At: time.Now()})
+	recorder.Record(metrics.Sample{RequestID: "deleted", Status: 200, DurationMs: 5, At: time.Now()})
+
+	opts := Options{Dir: dir, ResponseDir: responses, Since: time.Now(), Metrics: recorder}
+	report := Run(opts)
+	if report.Status != StatusError {
+		t.Errorf("Status = %s, want error", report.Status)
+	}
+
+	references := findCheck(t, report, "references")
+	if references.Status != StatusError || len(references.Problems) != 1 || references.Problems[0].ItemID != "folder1" {
+		t.Errorf("references = %+v, want the dangling child of folder1", references)
+	}
+	configs := findCheck(t, report, "configs")
+	var invalid, newer bool
+	for _, p := range configs.Problems {
+		switch filepath.Base(p.Path) {
+		case user.ConfigFileName:
+			invalid = p.Severity == StatusError
+		case "plugins.json":
+			newer = p.Severity == StatusWarning
+		}
+	}
+	if !invalid || !newer {
+		t.Errorf("configs = %+v, want the broken user config and the newer plugins file", configs.Problems)
+	}
+	if lockFiles := findCheck(t, report, "lockFiles"); len(lockFiles.Problems) != 1 || lockFiles.Problems[0].Path != stale {
+		t.Errorf("lockFiles = %+v, want only the stale temp file", lockFiles.Problems)
+	}
+	if cache := findCheck(t, report, "responseCache"); len(cache.Problems) != 2 {
+		t.Errorf("responseCache = %+v, want the old response and the deleted request's latency", cache.Problems)
+	}
+	if runtime.GOOS != "windows" {
+		if permissions := findCheck(t, report, "permissions"); permissions.Status != StatusWarning {
+			t.Errorf("permissions = %+v, want the readable environments file", permissions)
+		}
+	}
+
+	fixed, err := Fix(report)
+	if err != nil {
+		t.Fatalf("Fix() error = %v", err)
+	}
+	if fixed == 0 {
+		t.Fatal("Fix() fixed nothing")
+	}
+
+	report = Run(opts)
+	for _, name := range []string{"permissions", "lockFiles", "responseCache"} {
+		if check := findCheck(t, report, name); check.Status != StatusOK {
+			t.Errorf("%s after Fix = %+v, want ok", name, check.Problems)
+		}
+	}
+	if findCheck(t, report, "references").Status != StatusError {
+		t.Error("references after Fix should still report the dangling child, which needs a decision")
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("the temp file of a save in progress was removed: %v", err)
+	}
+	if ids := recorder.LatencyRequestIDs(); len(ids) != 1 || ids[0] != "req1" {
+		t.Errorf("LatencyRequestIDs() = %v, want [req1]", ids)
+	}
+}
+
+func TestRunReportsPendingSync(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, user.ProfilesDirName, user.ActiveProfileFileName), `{"name": "work"}`, 0o644)
+	writeFile(t, filepath.Join(dir, user.ProfilesDirName, "work", user.ConfigFileName),
+		fmt.Sprintf(`{"version": %d, "sync": {"server": "https://sync.example.com", "workspace": "team"}}`, user.CurrentVersion), 0o644)
+	writeFile(t, filepath.Join(dir, requests.SyncQueueFileName), `{"requests.json": {}}`, 0o644)
+
+	check := findCheck(t, Run(Options{Dir: dir}), "sync")
+	if check.Status != StatusWarning || len(check.Problems) != 2 {
+		t.Errorf("sync = %+v, want the queued push and the missing sync state", check)
+	}
+}
@@ -0,0 +1,192 @@
+// Package drafts keeps the unsaved editor state of requests in a scratch directory, one file per
+// request written ahead of the config save, so an edit still in the UI or in the save debounce
+// window survives a crash. Drafts left by an earlier run are offered for recovery.
+package drafts
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/requests"
+	"paperbox/internal/config/storage"
+
+	"github.com/adrg/xdg"
+)
+
+const (
+	// DirName is the directory drafts are stored in, in the app data directory
+	DirName = "drafts"
+
+	// EventRecoverable is emitted at startup with the drafts an earlier run left behind
+	EventRecoverable = "drafts:recoverable"
+)
+
+// Draft is the editor state of a request that may not be saved yet
+type Draft struct {
+	RequestID string        `json:"requestId"`
+	Item      requests.Item `json:"item"`
+	StashedAt time.Time     `json:"stashedAt"`
+	// Stale is set by Recoverable when the request was saved after the draft was stashed, so
+	// recovering it would undo newer changes
+	Stale bool `json:"stale,omitempty"`
+}
+
+// Store keeps drafts as JSON files in a directory
+type Store struct {
+	mu     sync.Mutex
+	dir    string
+	writer storage.Writer
+}
+
+// NewStore creates a store of drafts in dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir, writer: storage.NewFileWriter()}
+}
+
+// DefaultDir returns the drafts directory in the app data directory
+func DefaultDir() string {
+	return path.Join(xdg.DataHome, "paperbox", DirName)
+}
+
+// file returns the path of a request's draft, refusing IDs that are not a plain file name
+func (st *Store) file(requestID string) (string, error) {
+	if requestID == "" || requestID == "." || requestID == ".." || strings.ContainsAny(requestID, `/\`) {
+		return "", apperrors.Invalidf("invalid request ID")
+	}
+	return filepath.Join(st.dir, requestID+".json"), nil
+}
+
+// Stash writes the editor state of a request, replacing its previous draft. Drafts may hold
+// secrets, so only the user can read them.
+func (st *Store) Stash(requestID string, item requests.Item) error {
+	file, err := st.file(requestID)
+	if err != nil {
+		return err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	draft := Draft{RequestID: requestID, Item: item, StashedAt: time.Now()}
+	if err := storage.SaveJSON(st.writer, draft, file, 0o600, nil); err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to stash draft")
+	}
+	return nil
+}
+
+// Get reads the draft of a request
+func (st *Store) Get(requestID string) (*Draft, error) {
+	file, err := st.file(requestID)
+	if err != nil {
+		return nil, apperrors.NotFoundf("draft not found")
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return read(file)
+}
+
+// read decodes a draft file
+func read(file string) (*Draft, error) {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, apperrors.NotFoundf("draft not found")
+	}
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to read draft")
+	}
+	var draft Draft
+	if err := json.Unmarshal(data, &draft); err != nil || draft.RequestID == "" {
+		return nil, apperrors.Invalidf("draft %s is damaged", filepath.Base(file))
+	}
+	return &draft, nil
+}
+
+// List returns the stored drafts, most recently stashed first. Damaged files, e.g. cut short by
+// the crash, are skipped.
+func (st *Store) List() ([]Draft, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.listLocked()
+}
+
+func (st *Store) listLocked() ([]Draft, error) {
+	entries, err := os.ReadDir(st.dir)
+	if os.IsNotExist(err) {
+		return []Draft{}, nil
+	}
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to list drafts")
+	}
+
+	drafts := []Draft{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		draft, err := read(filepath.Join(st.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		drafts = append(drafts, *draft)
+	}
+	sort.Slice(drafts, func(i, j int) bool { return drafts[i].StashedAt.After(drafts[j].StashedAt) })
+	return drafts, nil
+}
+
+// Discard removes the draft of a request; there being none is not an error
+func (st *Store) Discard(requestID string) error {
+	file, err := st.file(requestID)
+	if err != nil {
+		return err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to discard draft")
+	}
+	return nil
+}
+
+// Recoverable returns the drafts stashed before the given time (by an earlier run) that still hold
+// unsaved work against cfg. Drafts of requests that no longer exist and drafts the saved request
+// already matches are discarded.
+func (st *Store) Recoverable(cfg *requests.RequestsConfig, before time.Time) ([]Draft, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	drafts, err := st.listLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	recoverable := []Draft{}
+	for _, draft := range drafts {
+		if !draft.StashedAt.Before(before) {
+			continue
+		}
+		current, exists := cfg.Values[draft.RequestID]
+		if !exists || current.Type != requests.ItemTypeRequest || sameContent(current, draft.Item) {
+			if file, err := st.file(draft.RequestID); err == nil {
+				_ = os.Remove(file)
+			}
+			continue
+		}
+		draft.Stale = current.UpdatedAt.After(draft.StashedAt)
+		recoverable = append(recoverable, draft)
+	}
+	return recoverable, nil
+}
+
+// sameContent reports whether two items only differ in their timestamps, which saving updates, and
+// in the fields of newer versions, which the UI does not send back
+func sameContent(a, b requests.Item) bool {
+	a.CreatedAt, a.UpdatedAt, a.LastUsedAt, a.Extra = time.Time{}, time.Time{}, nil, nil
+	b.CreatedAt, b.UpdatedAt, b.LastUsedAt, b.Extra = time.Time{}, time.Time{}, nil, nil
+	aData, errA := json.Marshal(a)
+	bData, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aData) == string(bData)
+}
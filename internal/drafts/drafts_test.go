@@ -0,0 +1,106 @@
+package drafts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/requests"
+)
+
+func TestStore(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if list, err := store.List(); err != nil || len(list) != 0 {
+		t.Fatalf("List() of a new store = %v, %v", list, err)
+	}
+
+	item := requests.Item{Type: requests.ItemTypeRequest, Name: "Get", Method: "GET", Path: "/users"}
+	if err := store.Stash("req1", item); err != nil {
+		t.Fatalf("Stash() error = %v", err)
+	}
+	item.Path = "/users/{id}"
+	if err := store.Stash("req1", item); err != nil {
+		t.Fatalf("Stash() error = %v", err)
+	}
+	if err := store.Stash("../requests", item); apperrors.CodeOf(err) != apperrors.ValidationFailed {
+		t.Errorf("Stash() of a path error = %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(store.dir, "req1.json")); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("draft file = %v, %v; want it readable by the user only", info, err)
+	}
+
+	got, err := store.Get("req1")
+	if err != nil || got.Item.Path != "/users/{id}" || got.StashedAt.IsZero() {
+		t.Fatalf("Get() = %+v, %v; want the latest stash", got, err)
+	}
+
+	// A draft cut short by a crash is skipped
+	if err := os.WriteFile(filepath.Join(store.dir, "req2.json"), []byte(`{"requestId": "req2", "item": {`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if list, err := store.List(); err != nil || len(list) != 1 || list[0].RequestID != "req1" {
+		t.Errorf("List() = %+v, %v; want only the intact draft", list, err)
+	}
+
+	if err := store.Discard("req1"); err != nil {
+		t.Fatalf("Discard() error = %v", err)
+	}
+	if err := store.Discard("req1"); err != nil {
+		t.Errorf("Discard() of a missing draft error = %v", err)
+	}
+	if _, err := store.Get("req1"); apperrors.CodeOf(err) != apperrors.NotFound {
+		t.Errorf("Get() of a discarded draft error = %v", err)
+	}
+}
+
+func TestRecoverable(t *testing.T) {
+	store := NewStore(t.TempDir())
+	saved := time.Now().Add(-time.Hour)
+	cfg := requests.NewRequestsConfig()
+	for _, id := range []string{"edited", "saved", "stale", "live"} {
+		cfg.Values[id] = requests.Item{Type: requests.ItemTypeRequest, Name: id, Method: "GET", Path: "/", UpdatedAt: saved}
+	}
+
+	edited := cfg.Values["edited"]
+	edited.Path = "/edited"
+	store.Stash("edited", edited)
+	store.Stash("saved", cfg.Values["saved"])
+	store.Stash("deleted", requests.Item{Type: requests.ItemTypeRequest, Name: "deleted", Method: "GET", Path: "/"})
+	stale := cfg.Values["stale"]
+	stale.Method = "POST"
+	store.Stash("stale", stale)
+
+	// The stale request was saved again after its draft, and the app then restarted
+	newer := cfg.Values["stale"]
+	newer.UpdatedAt = time.Now().Add(time.Second)
+	cfg.Values["stale"] = newer
+	started := time.Now()
+	live := cfg.Values["live"]
+	live.Path = "/typing"
+	time.Sleep(time.Millisecond)
+	store.Stash("live", live)
+
+	// Saving updates the timestamps, which does not make the draft unsaved work
+	current := cfg.Values["saved"]
+	current.UpdatedAt = time.Now()
+	cfg.Values["saved"] = current
+
+	recoverable, err := store.Recoverable(cfg, started)
+	if err != nil {
+		t.Fatalf("Recoverable() error = %v", err)
+	}
+	found := make(map[string]Draft)
+	for _, draft := range recoverable {
+		found[draft.RequestID] = draft
+	}
+	if len(found) != 2 || found["edited"].Item.Path != "/edited" || found["edited"].Stale || !found["stale"].Stale {
+		t.Errorf("Recoverable() = %+v, want the edited draft and the stale one", recoverable)
+	}
+
+	list, _ := store.List()
+	if len(list) != 3 {
+		t.Errorf("List() after Recoverable() = %+v, want the drafts of the saved and deleted requests discarded", list)
+	}
+}
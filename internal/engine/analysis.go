@@ -0,0 +1,327 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/requests"
+)
+
+// UndefinedVariable is a variable used by requests that does not resolve in some or all environments
+type UndefinedVariable struct {
+	Name         string   `json:"name"`
+	Items        []string `json:"items"`        // Requests and folders using it
+	Environments []string `json:"environments"` // Environments lacking it; all of them when it is defined nowhere
+}
+
+// UnusedVariable is a defined variable no request, folder or environment refers to
+type UnusedVariable struct {
+	Name        string `json:"name"`
+	Environment string `json:"environment,omitempty"` // Environment ID; empty for globals and folder variables
+	FolderID    string `json:"folderId,omitempty"`    // Set for folder variables
+}
+
+// reference is a variable name read by a placeholder. Optional references are template function
+// names and arguments, which read a variable only when one has their name.
+type reference struct {
+	name     string
+	optional bool
+}
+
+// usage is what one item refers to, with the folders whose variables it sees (nearest last)
+type usage struct {
+	itemID     string
+	folders    []string
+	references []reference
+}
+
+// FindUndefinedVariables reports the {{variables}} requests and folders use that resolve neither
+// from the globals, their folders, nor every environment. Variables set by captures count as
+// defined; template functions and process environment placeholders are not variables.
+func FindUndefinedVariables(cfg *requests.RequestsConfig, envs *environments.EnvironmentsConfig) []UndefinedVariable {
+	defined := make(map[string]bool)
+	if envs != nil {
+		for _, v := range envs.Globals {
+			if !v.Disabled {
+				defined[v.Key] = true
+			}
+		}
+	}
+	for _, item := range cfg.Values {
+		for _, capture := range item.Captures {
+			defined[capture.Variable] = true
+		}
+	}
+
+	envIDs, envDefines := environmentVariables(envs)
+	undefined := make(map[string]*UndefinedVariable)
+	for _, use := range usages(cfg) {
+		for _, ref := range use.references {
+			if ref.optional || defined[ref.name] || definedInFolders(cfg, use.folders, ref.name) {
+				continue
+			}
+			missing := []string{}
+			for _, id := range envIDs {
+				if !envDefines[id][ref.name] {
+					missing = append(missing, id)
+				}
+			}
+			if len(envIDs) > 0 && len(missing) == 0 {
+				continue
+			}
+
+			u, exists := undefined[ref.name]
+			if !exists {
+				u = &UndefinedVariable{Name: ref.name, Environments: missing}
+				undefined[ref.name] = u
+			}
+			if len(u.Items) == 0 || u.Items[len(u.Items)-1] != use.itemID {
+				u.Items = append(u.Items, use.itemID)
+			}
+		}
+	}
+
+	result := make([]UndefinedVariable, 0, len(undefined))
+	for _, u := range undefined {
+		result = append(result, *u)
+	}
+	sort.Slice(result, func(a, b int) bool { return result[a].Name < result[b].Name })
+	return result
+}
+
+// FindUnusedVariables reports global, environment and folder variables that nothing refers to.
+// Globals and environment variables may be used anywhere, folder variables only below their folder.
+// Variables set by captures count as used.
+func FindUnusedVariables(cfg *requests.RequestsConfig, envs *environments.EnvironmentsConfig) []UnusedVariable {
+	used := make(map[string]bool)
+	usedIn := make(map[string]map[string]bool) // Names by folder whose variables the user sees
+	for _, use := range usages(cfg) {
+		for _, ref := range use.references {
+			used[ref.name] = true
+			for _, folder := range use.folders {
+				if usedIn[folder] == nil {
+					usedIn[folder] = make(map[string]bool)
+				}
+				usedIn[folder][ref.name] = true
+			}
+		}
+	}
+	for _, item := range cfg.Values {
+		for _, capture := range item.Captures {
+			used[capture.Variable] = true
+		}
+	}
+	if envs != nil {
+		for _, env := range envs.Values {
+			for _, ref := range environmentReferences(env) {
+				used[ref.name] = true
+			}
+		}
+	}
+
+	result := []UnusedVariable{}
+	if envs != nil {
+		for _, v := range envs.Globals {
+			if !used[v.Key] {
+				result = append(result, UnusedVariable{Name: v.Key})
+			}
+		}
+		envIDs, _ := environmentVariables(envs)
+		for _, id := range envIDs {
+			for _, v := range envs.Values[id].Variables {
+				if !used[v.Key] {
+					result = append(result, UnusedVariable{Name: v.Key, Environment: id})
+				}
+			}
+		}
+	}
+	folderIDs := make([]string, 0)
+	for id, item := range cfg.Values {
+		if len(item.Variables) > 0 {
+			folderIDs = append(folderIDs, id)
+		}
+	}
+	sort.Strings(folderIDs)
+	for _, id := range folderIDs {
+		for _, v := range cfg.Values[id].Variables {
+			if !usedIn[id][v.Key] {
+				result = append(result, UnusedVariable{Name: v.Key, FolderID: id})
+			}
+		}
+	}
+	return result
+}
+
+// ReferencedVariables returns the sorted names of the variables the items may read, function
+// arguments included
+func ReferencedVariables(items ...requests.Item) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, item := range items {
+		for _, ref := range itemReferences(item) {
+			if !seen[ref.name] {
+				seen[ref.name] = true
+				names = append(names, ref.name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// environmentVariables returns the sorted environment IDs and the enabled variable names of each
+func environmentVariables(envs *environments.EnvironmentsConfig) ([]string, map[string]map[string]bool) {
+	if envs == nil {
+		return nil, nil
+	}
+	ids := make([]string, 0, len(envs.Values))
+	names := make(map[string]map[string]bool, len(envs.Values))
+	for id, env := range envs.Values {
+		ids = append(ids, id)
+		names[id] = make(map[string]bool, len(env.Variables))
+		for _, v := range env.Variables {
+			if !v.Disabled {
+				names[id][v.Key] = true
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids, names
+}
+
+// definedInFolders reports whether one of the folders defines an enabled variable
+func definedInFolders(cfg *requests.RequestsConfig, folders []string, name string) bool {
+	for _, id := range folders {
+		for _, v := range cfg.Values[id].Variables {
+			if v.Key == name && !v.Disabled {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// usages walks the tree and collects the references of every item that has any
+func usages(cfg *requests.RequestsConfig) []usage {
+	referenced := make(map[string]bool)
+	for _, item := range cfg.Values {
+		for _, childID := range item.Children {
+			referenced[childID] = true
+		}
+	}
+	var roots []string
+	for id := range cfg.Values {
+		if !referenced[id] {
+			roots = append(roots, id)
+		}
+	}
+	sort.Strings(roots)
+
+	var uses []usage
+	visited := make(map[string]bool)
+	var walk func(id string, folders []string)
+	walk = func(id string, folders []string) {
+		item, exists := cfg.Values[id]
+		if !exists || visited[id] {
+			return
+		}
+		visited[id] = true
+		if item.Type == requests.ItemTypeFolder {
+			folders = append(folders[:len(folders):len(folders)], id)
+		}
+		if refs := itemReferences(item); len(refs) > 0 {
+			uses = append(uses, usage{itemID: id, folders: folders, references: refs})
+		}
+		for _, childID := range item.Children {
+			walk(childID, folders)
+		}
+	}
+	for _, id := range roots {
+		walk(id, nil)
+	}
+	return uses
+}
+
+// itemReferences returns the references in the fields of an item that are substituted when sending
+func itemReferences(item requests.Item) []reference {
+	texts := []string{item.Path, item.Body, item.BaseURL}
+	for _, p := range item.QueryParams {
+		texts = append(texts, p.Key, p.Value)
+	}
+	for _, p := range item.PathVars {
+		texts = append(texts, p.Value)
+	}
+	for _, h := range item.Headers {
+		texts = append(texts, h.Key, h.Value)
+	}
+	for _, f := range item.FormFields {
+		texts = append(texts, f.Key, f.Value)
+	}
+	if auth := item.Auth; auth != nil {
+		texts = append(texts, auth.Username, auth.Password, auth.Domain, auth.Token, auth.Key, auth.Value)
+		for _, value := range auth.Params {
+			texts = append(texts, value)
+		}
+	}
+	if item.Examples != nil {
+		for _, example := range item.Examples.Requests {
+			texts = append(texts, example.Body)
+			for _, p := range append(example.QueryParams, example.PathVars...) {
+				texts = append(texts, p.Key, p.Value)
+			}
+			for _, h := range example.Headers {
+				texts = append(texts, h.Key, h.Value)
+			}
+		}
+	}
+	return references(texts)
+}
+
+// environmentReferences returns the references in the templated fields of an environment
+func environmentReferences(env environments.Environment) []reference {
+	texts := []string{env.BaseURL}
+	for _, o := range env.HostOverrides {
+		texts = append(texts, o.Target)
+	}
+	return references(texts)
+}
+
+// references returns the variable references of the placeholders in texts
+func references(texts []string) []reference {
+	var refs []reference
+	for _, text := range texts {
+		for _, expr := range Placeholders(text) {
+			refs = append(refs, placeholderReferences(expr)...)
+		}
+	}
+	return refs
+}
+
+// placeholderReferences returns the variables a placeholder expression may read, mirroring
+// Substituter.evaluate: a lone name is a variable unless a template function has that name, and the
+// unquoted arguments of a function call read a variable when one has their name
+func placeholderReferences(expr string) []reference {
+	if strings.HasPrefix(expr, ProcessEnvPrefix) {
+		return nil
+	}
+	tokens, err := tokenize(expr)
+	if err != nil || len(tokens) == 0 {
+		return []reference{{name: expr}}
+	}
+	if len(tokens) == 1 {
+		_, isFunc := DefaultFuncs.Lookup(expr)
+		return []reference{{name: expr, optional: isFunc}}
+	}
+	if _, isFunc := DefaultFuncs.Lookup(tokens[0].text); !isFunc || tokens[0].quoted {
+		return []reference{{name: expr}}
+	}
+
+	var refs []reference
+	for _, t := range tokens[1:] {
+		if !t.quoted {
+			refs = append(refs, reference{name: t.text, optional: true})
+		}
+	}
+	return refs
+}
@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"paperbox/internal/config/requests"
+)
+
+// CheckAssertion extracts a value from the response and compares it as the assertion says
+func CheckAssertion(a requests.Assertion, exec *Execution) TestResult {
+	result := TestResult{Name: assertionName(a)}
+	value, err := Extract(exec, a.Expression, a.Kind)
+	switch {
+	case a.Operator == requests.AssertNotExists:
+		result.Passed = err != nil
+		if !result.Passed {
+			result.Message = fmt.Sprintf("found %q", value)
+		}
+		return result
+	case err != nil:
+		result.Message = err.Error()
+		return result
+	}
+
+	switch a.Operator {
+	case requests.AssertExists:
+		result.Passed = true
+	case requests.AssertEquals:
+		result.Passed = value == a.Value
+	case requests.AssertNotEquals:
+		result.Passed = value != a.Value
+	case requests.AssertContains:
+		result.Passed = strings.Contains(value, a.Value)
+	case requests.AssertMatches:
+		pattern, err := regexp.Compile(a.Value)
+		if err != nil {
+			result.Message = fmt.Sprintf("invalid pattern: %v", err)
+			return result
+		}
+		result.Passed = pattern.MatchString(value)
+	case requests.AssertLessThan, requests.AssertGreaterThan:
+		actual, errActual := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		expected, errExpected := strconv.ParseFloat(strings.TrimSpace(a.Value), 64)
+		if errActual != nil || errExpected != nil {
+			result.Message = fmt.Sprintf("cannot compare %q with %q as numbers", value, a.Value)
+			return result
+		}
+		result.Passed = actual < expected
+		if a.Operator == requests.AssertGreaterThan {
+			result.Passed = actual > expected
+		}
+	default:
+		result.Message = fmt.Sprintf("unsupported operator '%s'", a.Operator)
+		return result
+	}
+	if !result.Passed {
+		result.Message = fmt.Sprintf("got %q", value)
+	}
+	return result
+}
+
+// runAssertions checks the enabled assertions of a request
+func runAssertions(assertions []requests.Assertion, exec *Execution) []TestResult {
+	var results []TestResult
+	for _, a := range assertions {
+		if !a.Disabled {
+			results = append(results, CheckAssertion(a, exec))
+		}
+	}
+	return results
+}
+
+// assertionName describes an assertion, e.g. `//status equals "OK"`
+func assertionName(a requests.Assertion) string {
+	name := a.Expression
+	switch a.Kind {
+	case requests.ExtractKindHeader:
+		name = "header " + name
+	case requests.ExtractKindStatus:
+		name = "status"
+	}
+	switch a.Operator {
+	case requests.AssertExists, requests.AssertNotExists:
+		return fmt.Sprintf("%s %s", name, a.Operator)
+	}
+	return fmt.Sprintf("%s %s %q", name, a.Operator, a.Value)
+}
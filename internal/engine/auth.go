@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"paperbox/internal/config/requests"
+)
+
+// AuthProvider injects the credentials of an auth scheme that is not built in (e.g. from a plugin)
+type AuthProvider func(req *ResolvedRequest, auth *requests.Auth) error
+
+// AuthRegistry is a concurrency-safe set of auth providers keyed by auth type
+type AuthRegistry struct {
+	mu        sync.RWMutex
+	providers map[requests.AuthType]AuthProvider
+}
+
+// NewAuthRegistry creates an empty registry
+func NewAuthRegistry() *AuthRegistry {
+	return &AuthRegistry{providers: make(map[requests.AuthType]AuthProvider)}
+}
+
+// Register adds or replaces the provider of an auth type
+func (r *AuthRegistry) Register(authType requests.AuthType, provider AuthProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[authType] = provider
+}
+
+// Unregister removes the provider of an auth type
+func (r *AuthRegistry) Unregister(authType requests.AuthType) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.providers, authType)
+}
+
+// Lookup returns the provider registered for an auth type
+func (r *AuthRegistry) Lookup(authType requests.AuthType) (AuthProvider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	provider, ok := r.providers[authType]
+	return provider, ok
+}
+
+// DefaultAuthProviders holds the providers of non built-in auth types; plugins register theirs here
+var DefaultAuthProviders = NewAuthRegistry()
+
+// EffectiveAuth returns the auth a request uses: its own, or that of the nearest ancestor folder
+// defining one. Returns nil when nothing applies or the resolved type is "none".
+func EffectiveAuth(cfg *requests.RequestsConfig, requestID string) *requests.Auth {
+	chain := append([]string{requestID}, requests.Ancestors(cfg, requestID)...)
+	for _, id := range chain {
+		item := cfg.Values[id]
+		if item.Auth == nil || item.Auth.Type == requests.AuthTypeInherit {
+			continue
+		}
+		if item.Auth.Type == requests.AuthTypeNone {
+			return nil
+		}
+		auth := *item.Auth
+		return &auth
+	}
+	return nil
+}
+
+// substituteAuth applies variable substitution to every credential field
+func substituteAuth(auth *requests.Auth, sub *Substituter) {
+	auth.Username = sub.Apply(auth.Username)
+	auth.Password = sub.Apply(auth.Password)
+	auth.Domain = sub.Apply(auth.Domain)
+	auth.Token = sub.Apply(auth.Token)
+	auth.Key = sub.Apply(auth.Key)
+	auth.Value = sub.Apply(auth.Value)
+	if len(auth.Params) > 0 {
+		params := make(map[string]string, len(auth.Params))
+		for key, value := range auth.Params {
+			params[key] = sub.Apply(value)
+		}
+		auth.Params = params
+	}
+}
+
+// applyAuth injects the credentials into the resolved request. Explicit headers win over injected ones.
+// Other auth types are handed to their provider in DefaultAuthProviders.
+func applyAuth(req *ResolvedRequest, auth *requests.Auth) error {
+	if auth == nil {
+		return nil
+	}
+
+	switch auth.Type {
+	case requests.AuthTypeBasic:
+		credentials := base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password))
+		setDefaultHeader(req, "Authorization", "Basic "+credentials)
+	case requests.AuthTypeBearer:
+		setDefaultHeader(req, "Authorization", "Bearer "+auth.Token)
+	case requests.AuthTypeAPIKey:
+		if auth.In == "query" {
+			separator := "?"
+			if strings.Contains(req.URL, "?") {
+				separator = "&"
+			}
+			req.URL += separator + url.QueryEscape(auth.Key) + "=" + url.QueryEscape(auth.Value)
+			return nil
+		}
+		setDefaultHeader(req, auth.Key, auth.Value)
+	case requests.AuthTypeNTLM, requests.AuthTypeNegotiate:
+		// The handshake needs server challenges, so it happens when sending
+		if !hasHeader(req, "Authorization") {
+			handshake := *auth
+			req.handshake = &handshake
+		}
+	default:
+		provider, ok := DefaultAuthProviders.Lookup(auth.Type)
+		if !ok {
+			return fmt.Errorf("auth type %q is not available; is its plugin enabled?", auth.Type)
+		}
+		if err := provider(req, auth); err != nil {
+			return fmt.Errorf("auth %s failed: %w", auth.Type, err)
+		}
+	}
+	return nil
+}
+
+// setDefaultHeader adds a header unless the request already sets it
+func setDefaultHeader(req *ResolvedRequest, key string, value string) {
+	if !hasHeader(req, key) {
+		req.Headers = append(req.Headers, requests.Header{Key: key, Value: value})
+	}
+}
+
+// hasHeader reports whether the request sets a header
+func hasHeader(req *ResolvedRequest, key string) bool {
+	for _, h := range req.Headers {
+		if strings.EqualFold(h.Key, key) {
+			return true
+		}
+	}
+	return false
+}
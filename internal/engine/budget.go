@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"fmt"
+
+	"paperbox/internal/config/requests"
+)
+
+// Budget warning kinds
+const (
+	BudgetRequestSize  = "requestSize"
+	BudgetResponseSize = "responseSize"
+	BudgetDuration     = "duration"
+)
+
+// BudgetWarning reports an execution exceeding one limit of its budget
+type BudgetWarning struct {
+	Kind    string `json:"kind"`
+	Limit   int64  `json:"limit"`  // bytes or milliseconds
+	Actual  int64  `json:"actual"` // bytes or milliseconds
+	Message string `json:"message"`
+}
+
+// EffectiveBudget returns the budget a request is held to: each limit comes from the request or the
+// nearest ancestor folder setting it. Returns nil when no limit applies.
+func EffectiveBudget(cfg *requests.RequestsConfig, requestID string) *requests.Budget {
+	var budget requests.Budget
+	chain := append([]string{requestID}, requests.Ancestors(cfg, requestID)...)
+	for _, id := range chain {
+		own := cfg.Values[id].Budget
+		if own == nil {
+			continue
+		}
+		if budget.MaxRequestBytes == 0 {
+			budget.MaxRequestBytes = own.MaxRequestBytes
+		}
+		if budget.MaxResponseBytes == 0 {
+			budget.MaxResponseBytes = own.MaxResponseBytes
+		}
+		if budget.MaxDurationMs == 0 {
+			budget.MaxDurationMs = own.MaxDurationMs
+		}
+	}
+	if budget == (requests.Budget{}) {
+		return nil
+	}
+	return &budget
+}
+
+// checkBudget compares an execution against a budget. Response limits are only checked when a
+// response was received.
+func checkBudget(budget *requests.Budget, exec *Execution) []BudgetWarning {
+	if budget == nil {
+		return nil
+	}
+
+	var warnings []BudgetWarning
+	if size := int64(len(exec.Unmasked().Body)); budget.MaxRequestBytes > 0 && size > budget.MaxRequestBytes {
+		warnings = append(warnings, BudgetWarning{
+			Kind: BudgetRequestSize, Limit: budget.MaxRequestBytes, Actual: size,
+			Message: fmt.Sprintf("request body is %s, over the %s budget", formatBytes(size), formatBytes(budget.MaxRequestBytes)),
+		})
+	}
+	if exec.Error != "" {
+		return warnings
+	}
+	if budget.MaxResponseBytes > 0 && exec.Size > budget.MaxResponseBytes {
+		warnings = append(warnings, BudgetWarning{
+			Kind: BudgetResponseSize, Limit: budget.MaxResponseBytes, Actual: exec.Size,
+			Message: fmt.Sprintf("response is %s, over the %s budget", formatBytes(exec.Size), formatBytes(budget.MaxResponseBytes)),
+		})
+	}
+	if budget.MaxDurationMs > 0 && exec.DurationMs > budget.MaxDurationMs {
+		warnings = append(warnings, BudgetWarning{
+			Kind: BudgetDuration, Limit: budget.MaxDurationMs, Actual: exec.DurationMs,
+			Message: fmt.Sprintf("took %d ms, over the %d ms budget", exec.DurationMs, budget.MaxDurationMs),
+		})
+	}
+	return warnings
+}
+
+// formatBytes renders a size with a decimal unit, e.g. "1.5 MB"
+func formatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, suffix := float64(n)/unit, "kB"
+	for _, next := range []string{"MB", "GB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.3g %s", value, suffix)
+}
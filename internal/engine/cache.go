@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"paperbox/internal/config/requests"
+)
+
+// CacheInfo describes how an execution used the stored validators of earlier responses (see
+// Engine.SetConditionalRequests)
+type CacheInfo struct {
+	// IfNoneMatch and IfModifiedSince are the validators added to the request, empty when none was stored
+	IfNoneMatch     string `json:"ifNoneMatch,omitempty"`
+	IfModifiedSince string `json:"ifModifiedSince,omitempty"`
+	// NotModified is set when the server answered 304 Not Modified
+	NotModified bool `json:"notModified,omitempty"`
+	// Hit is set when the body of a 304 was taken from the stored response
+	Hit bool `json:"hit,omitempty"`
+	// StoredAt is when the stored response was received, for hits
+	StoredAt *time.Time `json:"storedAt,omitempty"`
+	// Stored is set when this response's validators were stored for the next request
+	Stored bool `json:"stored,omitempty"`
+}
+
+// cachedResponse is a response stored for its validators, with its body as received
+type cachedResponse struct {
+	etag         string
+	lastModified string
+	header       http.Header
+	body         []byte
+	storedAt     time.Time
+}
+
+// validatorCache stores the last response with validators per method and URL
+type validatorCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+}
+
+func newValidatorCache() *validatorCache {
+	return &validatorCache{entries: make(map[string]cachedResponse)}
+}
+
+// SetConditionalRequests turns the cache-aware mode on or off. When on, the ETag and Last-Modified
+// of successful GET and HEAD responses are stored (per workspace, like cookies), and later
+// requests to the same URL send them as If-None-Match and If-Modified-Since. A 304 keeps its status
+// and headers and gets the stored body; Execution.Cache reports what happened.
+func (e *Engine) SetConditionalRequests(enabled bool) {
+	e.conditional.Store(enabled)
+}
+
+// validatorsFor returns the validator cache requests sent with ws use, nil when the mode is off
+func (e *Engine) validatorsFor(ws *WorkspaceContext) *validatorCache {
+	if !e.conditional.Load() {
+		return nil
+	}
+	if ws != nil {
+		return ws.cache
+	}
+	return e.cache
+}
+
+// cacheKey identifies the stored response of a request, nil for requests that are never cached
+func cacheKey(req *ResolvedRequest) (string, bool) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return "", false
+	}
+	return req.Method + " " + req.URL, true
+}
+
+// conditional returns the request with the validators of its stored response added. Requests that
+// set a conditional header of their own are sent as they are.
+func (c *validatorCache) conditional(req *ResolvedRequest) (*ResolvedRequest, *CacheInfo) {
+	key, ok := cacheKey(req)
+	if !ok {
+		return req, nil
+	}
+	for _, h := range req.Headers {
+		if strings.EqualFold(h.Key, "If-None-Match") || strings.EqualFold(h.Key, "If-Modified-Since") {
+			return req, &CacheInfo{}
+		}
+	}
+	c.mu.Lock()
+	entry, exists := c.entries[key]
+	c.mu.Unlock()
+	if !exists {
+		return req, &CacheInfo{}
+	}
+
+	info := &CacheInfo{IfNoneMatch: entry.etag, IfModifiedSince: entry.lastModified}
+	conditional := *req
+	conditional.Headers = append([]requests.Header{}, req.Headers...)
+	if entry.etag != "" {
+		conditional.Headers = append(conditional.Headers, requests.Header{Key: "If-None-Match", Value: entry.etag})
+	}
+	if entry.lastModified != "" {
+		conditional.Headers = append(conditional.Headers, requests.Header{Key: "If-Modified-Since", Value: entry.lastModified})
+	}
+	return &conditional, info
+}
+
+// lookup returns the stored response of a request
+func (c *validatorCache) lookup(req *ResolvedRequest) (cachedResponse, bool) {
+	key, ok := cacheKey(req)
+	if !ok {
+		return cachedResponse{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, exists := c.entries[key]
+	return entry, exists
+}
+
+// store keeps a successful response with validators for the next request, unless it is marked
+// no-store; it reports whether it was stored
+func (c *validatorCache) store(req *ResolvedRequest, resp *http.Response, body []byte) bool {
+	key, ok := cacheKey(req)
+	if !ok || resp.StatusCode != http.StatusOK {
+		return false
+	}
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" || hasDirective(strings.ToLower(strings.Join(resp.Header.Values("Cache-Control"), ",")), "no-store") {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cachedResponse{
+		etag:         etag,
+		lastModified: lastModified,
+		header:       resp.Header.Clone(),
+		body:         append([]byte(nil), body...),
+		storedAt:     time.Now(),
+	}
+	return true
+}
+
+// clear drops all stored responses
+func (c *validatorCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cachedResponse)
+}
+
+// revalidated returns the headers and body a 304 is processed with: the stored response's, with
+// the headers of the 304 replacing theirs
+func (r cachedResponse) revalidated(notModified http.Header) (http.Header, []byte) {
+	header := r.header.Clone()
+	for key, values := range notModified {
+		header[key] = values
+	}
+	return header, r.body
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	var gotIfNoneMatch string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotIfNoneMatch = r.Header.Get("If-None-Match")
+		w.Header().Set("ETag", `"v1"`)
+		if r.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		if gotIfNoneMatch == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	e := NewWithClient(server.Client())
+	req := &ResolvedRequest{Method: "GET", URL: server.URL + "/items"}
+	if exec := e.Send(context.Background(), req); exec.Cache != nil {
+		t.Fatalf("Cache = %+v with the mode off", exec.Cache)
+	}
+
+	e.SetConditionalRequests(true)
+	first := e.Send(context.Background(), req)
+	if first.Cache == nil || !first.Cache.Stored || first.Cache.IfNoneMatch != "" {
+		t.Fatalf("first Cache = %+v, want the validators stored", first.Cache)
+	}
+
+	second := e.Send(context.Background(), req)
+	if gotIfNoneMatch != `"v1"` {
+		t.Errorf("If-None-Match = %q, want the stored ETag", gotIfNoneMatch)
+	}
+	if second.Status != http.StatusNotModified || second.Cache == nil || !second.Cache.NotModified || !second.Cache.Hit {
+		t.Fatalf("second = %d %+v, want a cache hit", second.Status, second.Cache)
+	}
+	if second.Body != `{"id":1}` || second.MimeType != "application/json" {
+		t.Errorf("second Body = %q (%s), want the stored body", second.Body, second.MimeType)
+	}
+	if !hasHeader(&second.Request, "If-None-Match") {
+		t.Errorf("sent request %+v lacks the validator", second.Request.Headers)
+	}
+
+	// Responses marked no-store are not kept, and other methods are not cached
+	private := &ResolvedRequest{Method: "GET", URL: server.URL + "/private"}
+	e.Send(context.Background(), private)
+	if exec := e.Send(context.Background(), private); gotIfNoneMatch != "" || exec.Cache.Stored {
+		t.Errorf("no-store response was stored: %+v", exec.Cache)
+	}
+	if exec := e.Send(context.Background(), &ResolvedRequest{Method: "POST", URL: server.URL + "/items"}); exec.Cache != nil {
+		t.Errorf("POST Cache = %+v", exec.Cache)
+	}
+}
+
+func TestWorkspaceClearCache(t *testing.T) {
+	var conditional int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Modified-Since") != "" {
+			conditional++
+		}
+		w.Header().Set("Last-Modified", "Mon, 01 Jan 2024 00:00:00 GMT")
+	}))
+	defer server.Close()
+
+	e := NewWithClient(server.Client())
+	e.SetConditionalRequests(true)
+	ws := NewWorkspaceContext("test")
+	req := &ResolvedRequest{Method: "GET", URL: server.URL}
+	e.send(context.Background(), req, ws, "")
+	e.Send(context.Background(), req)
+	if conditional != 0 {
+		t.Errorf("conditional requests = %d, want workspaces to keep their own validators", conditional)
+	}
+	e.send(context.Background(), req, ws, "")
+	ws.ClearCache()
+	e.send(context.Background(), req, ws, "")
+	if conditional != 1 {
+		t.Errorf("conditional requests = %d, want 1 before clearing the cache", conditional)
+	}
+}
@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"paperbox/internal/config/requests"
+)
+
+// captureVariablePattern matches variable names usable in {{placeholders}}
+var captureVariablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.\-]*$`)
+
+// ParseCaptureRule parses a declarative capture such as "token = body.access_token" into a rule.
+// The source may be "body" or "body.<path>" (JSON), "header.<name>", a JSONPath starting with "$"
+// an XPath starting with "/", or any XPath expression after "xpath:", e.g. "xpath:count(//item)".
+func ParseCaptureRule(declaration string) (requests.CaptureRule, error) {
+	variable, source, ok := strings.Cut(declaration, "=")
+	if !ok {
+		return requests.CaptureRule{}, fmt.Errorf("capture must have the form 'variable = source'")
+	}
+	variable = strings.TrimSpace(variable)
+	source = strings.TrimSpace(source)
+
+	if !captureVariablePattern.MatchString(variable) {
+		return requests.CaptureRule{}, fmt.Errorf("invalid capture variable name '%s'", variable)
+	}
+
+	rule := requests.CaptureRule{Variable: variable}
+	switch {
+	case source == "body":
+		rule.Kind, rule.Expression = requests.ExtractKindJSONPath, "$"
+	case strings.HasPrefix(source, "body.") || strings.HasPrefix(source, "body["):
+		rule.Kind, rule.Expression = requests.ExtractKindJSONPath, "$"+strings.TrimPrefix(source, "body")
+	case strings.HasPrefix(source, "header.") || strings.HasPrefix(source, "headers."):
+		_, name, _ := strings.Cut(source, ".")
+		rule.Kind, rule.Expression = requests.ExtractKindHeader, name
+	case strings.HasPrefix(source, "$"):
+		rule.Kind, rule.Expression = requests.ExtractKindJSONPath, source
+	case strings.HasPrefix(source, "/"):
+		rule.Kind, rule.Expression = requests.ExtractKindXPath, source
+	case strings.HasPrefix(source, "xpath:"):
+		rule.Kind, rule.Expression = requests.ExtractKindXPath, strings.TrimSpace(strings.TrimPrefix(source, "xpath:"))
+	default:
+		return requests.CaptureRule{}, fmt.Errorf("unsupported capture source '%s'", source)
+	}
+
+	if rule.Expression == "" {
+		return requests.CaptureRule{}, fmt.Errorf("capture source '%s' is missing a name", source)
+	}
+	return rule, nil
+}
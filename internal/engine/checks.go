@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"paperbox/internal/config/requests"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// maxSchemaViolations caps how many individual violations are reported per response
+const maxSchemaViolations = 20
+
+// runChecks evaluates every assertion and capture rule configured on the request item against the execution
+func runChecks(item requests.Item, exec *Execution) []TestResult {
+	var results []TestResult
+
+	if strings.TrimSpace(item.ResponseSchema) != "" {
+		results = append(results, CheckResponseSchema(item.ResponseSchema, exec.Body)...)
+	}
+	if len(item.Captures) > 0 {
+		results = append(results, applyCaptures(item.Captures, exec)...)
+	}
+	if len(item.Assertions) > 0 {
+		results = append(results, runAssertions(item.Assertions, exec)...)
+	}
+	if item.Snapshot != nil {
+		results = append(results, CompareSnapshot(item.Snapshot, exec)...)
+	}
+
+	return results
+}
+
+// CheckResponseSchema validates a response body against a JSON Schema. A passing body yields a single
+// passed result; otherwise each violation becomes a failed result named after its instance location.
+func CheckResponseSchema(schemaJSON string, body string) []TestResult {
+	const name = "Response matches JSON schema"
+
+	schemaDoc, err := jsonschema.UnmarshalJSON(strings.NewReader(schemaJSON))
+	if err != nil {
+		return []TestResult{{Name: name, Message: fmt.Sprintf("invalid schema: %v", err)}}
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("response-schema.json", schemaDoc); err != nil {
+		return []TestResult{{Name: name, Message: fmt.Sprintf("invalid schema: %v", err)}}
+	}
+	schema, err := compiler.Compile("response-schema.json")
+	if err != nil {
+		return []TestResult{{Name: name, Message: fmt.Sprintf("invalid schema: %v", err)}}
+	}
+
+	instance, err := jsonschema.UnmarshalJSON(strings.NewReader(body))
+	if err != nil {
+		return []TestResult{{Name: name, Message: "response body is not valid JSON"}}
+	}
+
+	err = schema.Validate(instance)
+	if err == nil {
+		return []TestResult{{Name: name, Passed: true}}
+	}
+
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return []TestResult{{Name: name, Message: err.Error()}}
+	}
+
+	var results []TestResult
+	for _, unit := range validationErr.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		location := unit.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		results = append(results, TestResult{
+			Name:    fmt.Sprintf("%s at %s", name, location),
+			Message: unit.Error.String(),
+		})
+		if len(results) == maxSchemaViolations {
+			break
+		}
+	}
+	if len(results) == 0 {
+		results = append(results, TestResult{Name: name, Message: validationErr.Error()})
+	}
+	return results
+}
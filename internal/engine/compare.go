@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"context"
+
+	"paperbox/internal/config/requests"
+
+	"github.com/ohler55/ojg/jp"
+	"github.com/ohler55/ojg/oj"
+)
+
+// maxComparisonDiffs caps how many body differences are reported per request of a comparison
+const maxComparisonDiffs = 20
+
+// EnvironmentComparison is a folder run against two environments, compared request by request
+type EnvironmentComparison struct {
+	FolderID     string              `json:"folderId"`
+	EnvironmentA string              `json:"environmentA"`
+	EnvironmentB string              `json:"environmentB"`
+	RunA         *RunResult          `json:"runA"`
+	RunB         *RunResult          `json:"runB"`
+	Requests     []RequestComparison `json:"requests"`
+	Matching     int                 `json:"matching"`
+	Different    int                 `json:"different"`
+}
+
+// RequestComparison puts the responses of a request in two environments side by side
+type RequestComparison struct {
+	RequestID   string           `json:"requestId"`
+	Name        string           `json:"name"`
+	ExecutionA  string           `json:"executionA,omitempty"` // Empty when the run stopped before the request
+	ExecutionB  string           `json:"executionB,omitempty"`
+	StatusA     int              `json:"statusA"`
+	StatusB     int              `json:"statusB"`
+	ErrorA      string           `json:"errorA,omitempty"`
+	ErrorB      string           `json:"errorB,omitempty"`
+	DurationMsA int64            `json:"durationMsA"`
+	DurationMsB int64            `json:"durationMsB"`
+	Match       bool             `json:"match"`
+	BodyMatch   bool             `json:"bodyMatch"`
+	Differences []BodyDifference `json:"differences,omitempty"`
+}
+
+// BodyDifference is one place where the response bodies of a comparison differ; Location is a
+// JSONPath for JSON bodies and empty for text bodies
+type BodyDifference struct {
+	Location string `json:"location,omitempty"`
+	Message  string `json:"message"`
+}
+
+// CompareEnvironments runs a folder against srcA and then srcB (see RunCollection) and compares
+// the responses of each request: status, error and body. JSON bodies are compared structurally
+// without the paths the request's snapshot ignores; other bodies must match exactly. Neither run
+// changes the environments.
+func (e *Engine) CompareEnvironments(ctx context.Context, srcA, srcB Sources, folderID string) (*EnvironmentComparison, error) {
+	runA, err := e.RunCollection(ctx, srcA, folderID)
+	if err != nil {
+		return nil, err
+	}
+	runB, err := e.RunCollection(ctx, srcB, folderID)
+	if err != nil {
+		return nil, err
+	}
+	return CompareRuns(srcA.Requests, runA, runB), nil
+}
+
+// CompareRuns compares two runs of the same folder request by request, in tree order
+func CompareRuns(cfg *requests.RequestsConfig, runA, runB *RunResult) *EnvironmentComparison {
+	comparison := &EnvironmentComparison{FolderID: runA.FolderID, RunA: runA, RunB: runB, Requests: []RequestComparison{}}
+	execsA, execsB := firstExecutions(runA), firstExecutions(runB)
+	for _, requestID := range requests.RequestsUnder(cfg, runA.FolderID) {
+		item := cfg.Values[requestID]
+		c := compareExecutions(item, execsA[requestID], execsB[requestID])
+		c.RequestID = requestID
+		c.Name = item.Name
+		if c.Match {
+			comparison.Matching++
+		} else {
+			comparison.Different++
+		}
+		comparison.Requests = append(comparison.Requests, c)
+	}
+	return comparison
+}
+
+// firstExecutions indexes the executions of a run by request, keeping the first of repeated ones
+func firstExecutions(run *RunResult) map[string]*Execution {
+	execs := make(map[string]*Execution, len(run.Executions))
+	for _, exec := range run.Executions {
+		if _, exists := execs[exec.RequestID]; !exists {
+			execs[exec.RequestID] = exec
+		}
+	}
+	return execs
+}
+
+// compareExecutions compares the responses of a request in two environments (nil when not sent)
+func compareExecutions(item requests.Item, a, b *Execution) RequestComparison {
+	var c RequestComparison
+	if a != nil {
+		c.ExecutionA, c.StatusA, c.ErrorA, c.DurationMsA = a.ID, a.Status, a.Error, a.DurationMs
+	}
+	if b != nil {
+		c.ExecutionB, c.StatusB, c.ErrorB, c.DurationMsB = b.ID, b.Status, b.Error, b.DurationMs
+	}
+	if a == nil || b == nil {
+		c.Differences = []BodyDifference{{Message: "the request was not sent in both environments"}}
+		return c
+	}
+	if a.Error != "" || b.Error != "" {
+		c.Match = a.Error == b.Error
+		return c
+	}
+
+	var ignorePaths []string
+	if item.Snapshot != nil {
+		ignorePaths = item.Snapshot.IgnorePaths
+	}
+	c.Differences = compareBodies(a.Body, b.Body, ignorePaths)
+	c.BodyMatch = len(c.Differences) == 0
+	c.Match = c.BodyMatch && a.Status == b.Status
+	return c
+}
+
+// compareBodies lists the differences between two response bodies, body b against body a
+func compareBodies(a, b string, ignorePaths []string) []BodyDifference {
+	const reference = "environment A"
+
+	want, wantErr := oj.ParseString(a)
+	got, gotErr := oj.ParseString(b)
+	if wantErr != nil || gotErr != nil {
+		if a == b {
+			return nil
+		}
+		return []BodyDifference{{Message: firstDifference(a, b, reference)}}
+	}
+
+	// Invalid paths are already reported by the snapshot tests
+	for _, expression := range ignorePaths {
+		if path, err := jp.ParseString(expression); err == nil {
+			want, _ = path.Remove(want)
+			got, _ = path.Remove(got)
+		}
+	}
+	var diffs []BodyDifference
+	diffJSON("$", want, got, reference, func(location, message string) bool {
+		diffs = append(diffs, BodyDifference{Location: location, Message: message})
+		return len(diffs) < maxComparisonDiffs
+	})
+	return diffs
+}
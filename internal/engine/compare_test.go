@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/requests"
+)
+
+func TestCompareEnvironments(t *testing.T) {
+	handler := func(version string, missing int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/users":
+				w.Write([]byte(`{"users": [{"id": 1, "name": "Ada"}], "servedBy": "` + version + `"}`))
+			case "/version":
+				w.Write([]byte(version))
+			case "/orders":
+				w.WriteHeader(missing)
+			}
+		}
+	}
+	prod := httptest.NewServer(handler("prod", http.StatusOK))
+	defer prod.Close()
+	staging := httptest.NewServer(handler("staging", http.StatusNotFound))
+	defer staging.Close()
+
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"root": {Type: requests.ItemTypeFolder, Name: "Root", Children: []string{"users", "version", "orders"}},
+			"users": {
+				Type: requests.ItemTypeRequest, Name: "Users", Method: "GET", Path: "/users",
+				Snapshot: &requests.Snapshot{IgnorePaths: []string{"$.servedBy"}},
+			},
+			"version": {Type: requests.ItemTypeRequest, Name: "Version", Method: "GET", Path: "/version"},
+			"orders":  {Type: requests.ItemTypeRequest, Name: "Orders", Method: "GET", Path: "/orders"},
+		},
+	}
+	srcA := Sources{Requests: cfg, Environment: &environments.Environment{Name: "Prod", BaseURL: prod.URL}}
+	srcB := Sources{Requests: cfg, Environment: &environments.Environment{Name: "Staging", BaseURL: staging.URL}}
+
+	comparison, err := New().CompareEnvironments(context.Background(), srcA, srcB, "root")
+	if err != nil {
+		t.Fatalf("CompareEnvironments() error = %v", err)
+	}
+	if len(comparison.Requests) != 3 || comparison.Matching != 1 || comparison.Different != 2 {
+		t.Fatalf("CompareEnvironments() = %d requests, %d matching, %d different; want 3, 1, 2",
+			len(comparison.Requests), comparison.Matching, comparison.Different)
+	}
+
+	users := comparison.Requests[0]
+	if users.RequestID != "users" || !users.Match || len(users.Differences) != 0 {
+		t.Errorf("users comparison = %+v, want a match with servedBy ignored", users)
+	}
+	version := comparison.Requests[1]
+	if version.Match || version.BodyMatch || len(version.Differences) != 1 || !strings.Contains(version.Differences[0].Message, `"staging", environment A has "prod"`) {
+		t.Errorf("version comparison = %+v, want a text body difference", version)
+	}
+	orders := comparison.Requests[2]
+	if orders.Match || !orders.BodyMatch || orders.StatusA != http.StatusOK || orders.StatusB != http.StatusNotFound {
+		t.Errorf("orders comparison = %+v, want a status difference only", orders)
+	}
+	if comparison.RunA.Executions[0].Request.URL == comparison.RunB.Executions[0].Request.URL {
+		t.Error("both runs were sent to the same environment")
+	}
+}
+
+func TestCompareBodiesJSON(t *testing.T) {
+	diffs := compareBodies(`{"a": 1, "b": [1, 2]}`, `{"a": 2, "b": [1], "c": true}`, nil)
+	want := map[string]string{
+		"$.a": "got 2, environment A has 1",
+		"$.b": "1 items, environment A has 2",
+		"$.c": "not in the environment A, got true",
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("compareBodies() = %+v, want %d differences", diffs, len(want))
+	}
+	for _, diff := range diffs {
+		if want[diff.Location] != diff.Message {
+			t.Errorf("difference at %s = %q, want %q", diff.Location, diff.Message, want[diff.Location])
+		}
+	}
+}
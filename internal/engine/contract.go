@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"paperbox/internal/config/requests"
+
+	"github.com/ohler55/ojg/oj"
+)
+
+const (
+	// MaxGeneratedAssertions caps how many assertions GenerateAssertions derives from one response
+	MaxGeneratedAssertions = 50
+	// maxContractDepth is how deep GenerateAssertions descends into a JSON body
+	maxContractDepth = 4
+)
+
+// keyFields are the JSON field names whose value is locked in rather than only their type
+var keyFields = map[string]bool{"id": true, "status": true, "state": true, "type": true, "kind": true, "code": true}
+
+// identifier matches the field names usable in a dotted JSONPath
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Type patterns for the text Extract returns for a JSON value; strings carry no marker, so they
+// are only asserted to exist
+const (
+	numberPattern  = `^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`
+	booleanPattern = `^(true|false)$`
+	objectPattern  = `^\{`
+	arrayPattern   = `^\[`
+)
+
+// GenerateAssertions derives a regression suite from a response: its status, its media type and,
+// for JSON bodies, the presence and type of every field down to a few levels, with key fields
+// (id, status, type, code...) pinned to their value. Arrays are checked through their first item.
+func GenerateAssertions(exec *Execution) []requests.Assertion {
+	assertions := []requests.Assertion{{
+		Kind: requests.ExtractKindStatus, Expression: "status", Operator: requests.AssertEquals, Value: strconv.Itoa(exec.Status),
+	}}
+	if contentType := http.Header(exec.Headers).Get("Content-Type"); contentType != "" {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+			assertions = append(assertions, requests.Assertion{
+				Kind: requests.ExtractKindHeader, Expression: "Content-Type", Operator: requests.AssertContains, Value: mediaType,
+			})
+		}
+	}
+
+	body, err := oj.ParseString(exec.Body)
+	if err != nil {
+		return assertions
+	}
+	return contractAssertions(assertions, "$", "", body, 0)
+}
+
+// ExampleExecution turns a saved response example into an execution, e.g. to generate assertions
+func ExampleExecution(example requests.ResponseExample) *Execution {
+	exec := &Execution{Status: example.Status, Headers: make(map[string][]string, len(example.Headers)), Body: example.Body}
+	for _, h := range example.Headers {
+		http.Header(exec.Headers).Add(h.Key, h.Value)
+	}
+	return exec
+}
+
+// contractAssertions appends the assertions of a JSON value at path, then those of its children
+func contractAssertions(assertions []requests.Assertion, path, field string, value any, depth int) []requests.Assertion {
+	if len(assertions) >= MaxGeneratedAssertions {
+		return assertions
+	}
+	jsonPath := func(operator requests.AssertOperator, expected string) requests.Assertion {
+		return requests.Assertion{Kind: requests.ExtractKindJSONPath, Expression: path, Operator: operator, Value: expected}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if path != "$" {
+			assertions = append(assertions, jsonPath(requests.AssertMatches, objectPattern))
+		}
+		if depth >= maxContractDepth {
+			return assertions
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			assertions = contractAssertions(assertions, childPath(path, key), key, v[key], depth+1)
+		}
+		return assertions
+
+	case []any:
+		if path != "$" {
+			assertions = append(assertions, jsonPath(requests.AssertMatches, arrayPattern))
+		}
+		if len(v) == 0 || depth >= maxContractDepth {
+			return assertions
+		}
+		return contractAssertions(assertions, path+"[0]", "", v[0], depth+1)
+
+	case nil:
+		// Extract renders null as the JSON text
+		return append(assertions, jsonPath(requests.AssertEquals, "null"))
+	}
+
+	text, err := formatValue(value)
+	if err == nil && keyFields[strings.ToLower(field)] {
+		return append(assertions, jsonPath(requests.AssertEquals, text))
+	}
+	switch value.(type) {
+	case bool:
+		return append(assertions, jsonPath(requests.AssertMatches, booleanPattern))
+	case string:
+		return append(assertions, jsonPath(requests.AssertExists, ""))
+	default:
+		return append(assertions, jsonPath(requests.AssertMatches, numberPattern))
+	}
+}
+
+// childPath returns the JSONPath of a field of the object at path
+func childPath(path, key string) string {
+	if identifier.MatchString(key) {
+		return path + "." + key
+	}
+	return path + "['" + strings.ReplaceAll(key, "'", `\'`) + "']"
+}
+
+// MergeAssertions adds the generated assertions a request does not have yet (same kind, expression
+// and operator) after its own
+func MergeAssertions(existing, generated []requests.Assertion) []requests.Assertion {
+	type key struct {
+		kind       requests.ExtractKind
+		expression string
+		operator   requests.AssertOperator
+	}
+	seen := make(map[key]bool, len(existing))
+	merged := append([]requests.Assertion{}, existing...)
+	for _, a := range existing {
+		seen[key{a.Kind, a.Expression, a.Operator}] = true
+	}
+	for _, a := range generated {
+		if k := (key{a.Kind, a.Expression, a.Operator}); !seen[k] {
+			seen[k] = true
+			merged = append(merged, a)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"testing"
+
+	"paperbox/internal/config/requests"
+)
+
+func TestGenerateAssertions(t *testing.T) {
+	exec := &Execution{
+		Status:  200,
+		Headers: map[string][]string{"Content-Type": {"application/json; charset=utf-8"}},
+		Body:    `{"id": 7, "status": "active", "name": "Ada", "score": 4.5, "admin": false, "manager": null, "tags": ["a"], "address": {"zip code": "12345"}}`,
+	}
+
+	assertions := GenerateAssertions(exec)
+	want := []requests.Assertion{
+		{Kind: requests.ExtractKindStatus, Expression: "status", Operator: requests.AssertEquals, Value: "200"},
+		{Kind: requests.ExtractKindHeader, Expression: "Content-Type", Operator: requests.AssertContains, Value: "application/json"},
+		{Kind: requests.ExtractKindJSONPath, Expression: "$.address", Operator: requests.AssertMatches, Value: objectPattern},
+		{Kind: requests.ExtractKindJSONPath, Expression: "$.address['zip code']", Operator: requests.AssertExists},
+		{Kind: requests.ExtractKindJSONPath, Expression: "$.admin", Operator: requests.AssertMatches, Value: booleanPattern},
+		{Kind: requests.ExtractKindJSONPath, Expression: "$.id", Operator: requests.AssertEquals, Value: "7"},
+		{Kind: requests.ExtractKindJSONPath, Expression: "$.manager", Operator: requests.AssertEquals, Value: "null"},
+		{Kind: requests.ExtractKindJSONPath, Expression: "$.name", Operator: requests.AssertExists},
+		{Kind: requests.ExtractKindJSONPath, Expression: "$.score", Operator: requests.AssertMatches, Value: numberPattern},
+		{Kind: requests.ExtractKindJSONPath, Expression: "$.status", Operator: requests.AssertEquals, Value: "active"},
+		{Kind: requests.ExtractKindJSONPath, Expression: "$.tags", Operator: requests.AssertMatches, Value: arrayPattern},
+		{Kind: requests.ExtractKindJSONPath, Expression: "$.tags[0]", Operator: requests.AssertExists},
+	}
+	if len(assertions) != len(want) {
+		t.Fatalf("GenerateAssertions() = %d assertions, want %d: %+v", len(assertions), len(want), assertions)
+	}
+	for i := range want {
+		if assertions[i] != want[i] {
+			t.Errorf("assertion %d = %+v, want %+v", i, assertions[i], want[i])
+		}
+	}
+
+	// The generated suite passes against the response it came from
+	for _, a := range assertions {
+		if result := CheckAssertion(a, exec); !result.Passed {
+			t.Errorf("%s failed on its own response: %s", result.Name, result.Message)
+		}
+	}
+	changed := *exec
+	changed.Status = 500
+	changed.Body = `{"id": "7", "status": "disabled"}`
+	var failed int
+	for _, a := range assertions {
+		if !CheckAssertion(a, &changed).Passed {
+			failed++
+		}
+	}
+	if failed < 3 {
+		t.Errorf("only %d generated assertions failed on a changed response", failed)
+	}
+}
+
+func TestMergeAssertions(t *testing.T) {
+	existing := []requests.Assertion{{Kind: requests.ExtractKindStatus, Expression: "status", Operator: requests.AssertEquals, Value: "201"}}
+	generated := []requests.Assertion{
+		{Kind: requests.ExtractKindStatus, Expression: "status", Operator: requests.AssertEquals, Value: "200"},
+		{Kind: requests.ExtractKindJSONPath, Expression: "$.id", Operator: requests.AssertExists},
+	}
+	merged := MergeAssertions(existing, generated)
+	if len(merged) != 2 || merged[0].Value != "201" || merged[1].Expression != "$.id" {
+		t.Errorf("MergeAssertions() = %+v, want the existing status assertion kept and $.id added", merged)
+	}
+}
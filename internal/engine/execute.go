@@ -0,0 +1,440 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/requests"
+	"paperbox/internal/har"
+	"paperbox/internal/response"
+	"paperbox/internal/tunnel"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultTimeout bounds a single request when the caller's context has no deadline
+	DefaultTimeout = 30 * time.Second
+	// DefaultMaxBodySize is how much of a response body is read into memory by default
+	DefaultMaxBodySize = 64 << 20
+)
+
+// TestResult is the outcome of a single assertion run against a response
+type TestResult struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// Execution is the result of sending a request. Body holds the decoded (decompressed, UTF-8) response
+// text and FormattedBody its pretty-printed form for JSON, XML and HTML; binary bodies are described
+// by Binary instead. Size is the size on the wire.
+type Execution struct {
+	ID              string              `json:"id"`
+	RequestID       string              `json:"requestId,omitempty"`
+	Request         ResolvedRequest     `json:"request"`
+	Status          int                 `json:"status"`
+	StatusText      string              `json:"statusText,omitempty"`
+	Headers         map[string][]string `json:"headers,omitempty"`
+	Body            string              `json:"body"`
+	FormattedBody   string              `json:"formattedBody,omitempty"`
+	MimeType        string              `json:"mimeType,omitempty"`
+	Charset         string              `json:"charset,omitempty"`
+	ContentEncoding string              `json:"contentEncoding,omitempty"`
+	Size            int64               `json:"size"`
+	StartedAt       time.Time           `json:"startedAt"`
+	DurationMs      int64               `json:"durationMs"`
+	Error           string              `json:"error,omitempty"`
+	Tests           []TestResult        `json:"tests,omitempty"`
+	Captured        map[string]string   `json:"captured,omitempty"`
+	Timings         *Timings            `json:"timings,omitempty"`
+	Binary          *response.Binary    `json:"binary,omitempty"`
+	// InsecureTLS is set when the request was sent without certificate verification
+	InsecureTLS bool `json:"insecureTLS,omitempty"`
+	// BudgetWarnings lists the limits of the request's budget that this execution exceeded
+	BudgetWarnings []BudgetWarning `json:"budgetWarnings,omitempty"`
+	// Hints are security, caching and deprecation findings about the response headers (see AnalyzeHeaders)
+	Hints []HeaderHint `json:"hints,omitempty"`
+	// Cache reports the validators sent and whether a 304 was served from the stored response
+	// (see Engine.SetConditionalRequests); nil when the mode is off or the method is not cached
+	Cache *CacheInfo `json:"cache,omitempty"`
+	// Attempts lists the earlier attempts that were answered with 429 or 503 and retried (see RetryPolicy)
+	Attempts []RetryAttempt `json:"attempts,omitempty"`
+	// Flaky is set on the attempts of a request whose repeated runs disagreed (see Stability)
+	Flaky bool `json:"flaky,omitempty"`
+	// Truncated is set when the body exceeded the engine's size limit; Body then only holds its start
+	Truncated bool `json:"truncated,omitempty"`
+	// BodyFile holds the whole body of a truncated response as received, when saving it is enabled
+	BodyFile string `json:"bodyFile,omitempty"`
+
+	// raw is the decoded response body as bytes, kept for saving to disk
+	raw []byte
+	// sent is the request as it went over the wire; Request is its masked copy
+	sent *ResolvedRequest
+}
+
+// Unmasked returns the request as it was sent, secrets included
+func (e *Execution) Unmasked() ResolvedRequest {
+	if e.sent != nil {
+		return *e.sent
+	}
+	return e.Request
+}
+
+// Exchange converts the execution into a HAR exchange. The request is the masked one, so secrets
+// do not end up in the file; disabled headers were not sent and are left out.
+func (e *Execution) Exchange() har.Exchange {
+	headers := make(map[string][]string, len(e.Request.Headers))
+	for _, h := range e.Request.Headers {
+		if !h.Disabled {
+			headers[h.Key] = append(headers[h.Key], h.Value)
+		}
+	}
+	return har.Exchange{
+		Method:          e.Request.Method,
+		URL:             e.Request.URL,
+		Headers:         headers,
+		Body:            e.Request.Body,
+		Status:          e.Status,
+		ResponseHeaders: e.Headers,
+		ResponseBody:    e.Body,
+		StartedAt:       e.StartedAt,
+		Duration:        time.Duration(e.DurationMs) * time.Millisecond,
+	}
+}
+
+// Engine sends resolved requests over HTTP
+type Engine struct {
+	client      *http.Client
+	timeout     atomic.Int64
+	maxBodySize atomic.Int64
+	spillBodies atomic.Bool
+	conditional atomic.Bool
+	cache       *validatorCache // Validators of requests sent without a workspace
+	// streamBuffer is how many bytes of a streamed body are kept (see readStream)
+	streamBuffer atomic.Int64
+
+	mu              sync.Mutex
+	overrideClients map[string]*http.Client // Clients for requests with host overrides, by override set
+	jumps           *tunnel.Jumps           // SSH connections to the jump hosts of environments
+	middleware      *MiddlewareRegistry
+	emitStream      func(event string, payload interface{})
+	retry           RetryPolicy
+	transport       TransportSettings
+}
+
+// New creates an engine with a default HTTP client
+func New() *Engine {
+	return NewWithClient(&http.Client{})
+}
+
+// NewWithClient creates an engine using a custom HTTP client (for testing)
+func NewWithClient(client *http.Client) *Engine {
+	e := &Engine{client: client, middleware: DefaultMiddleware, cache: newValidatorCache(), jumps: tunnel.NewJumps()}
+	e.SetTimeout(DefaultTimeout)
+	e.SetBodyLimit(DefaultMaxBodySize, true)
+	e.SetStreamBufferSize(DefaultStreamBufferSize)
+	return e
+}
+
+// SetTimeout changes the per-request timeout; 0 disables it
+func (e *Engine) SetTimeout(timeout time.Duration) {
+	e.timeout.Store(int64(timeout))
+}
+
+// SetBodyLimit changes how many bytes of a response body are kept in memory; 0 disables the limit.
+// Bodies over the limit are truncated and, with spill, streamed to a temp file in full.
+func (e *Engine) SetBodyLimit(maxSize int64, spill bool) {
+	e.maxBodySize.Store(maxSize)
+	e.spillBodies.Store(spill)
+}
+
+// Run passes the request with the given ID through the middleware chain, which resolves it, injects
+// its auth and runs its assertions around sending it.
+// Transport failures are reported in Execution.Error; the returned error is for resolution problems.
+func (e *Engine) Run(ctx context.Context, src Sources, requestID string) (*Execution, error) {
+	handler := e.Middleware().Then(func(ctx context.Context, call *Call) (*Execution, error) {
+		if call.Request == nil {
+			return nil, fmt.Errorf("request %s was not resolved", call.RequestID)
+		}
+		return e.send(ctx, call.Request, call.Sources.Workspace, call.RequestID), nil
+	})
+	return handler(ctx, &Call{Sources: src, RequestID: requestID})
+}
+
+// Middleware returns the middleware requests run through
+func (e *Engine) Middleware() *MiddlewareRegistry {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.middleware
+}
+
+// SetMiddleware replaces the middleware requests run through, DefaultMiddleware by default
+func (e *Engine) SetMiddleware(middleware *MiddlewareRegistry) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.middleware = middleware
+}
+
+// Send performs a resolved request with the engine's shared connections and no cookies
+func (e *Engine) Send(ctx context.Context, req *ResolvedRequest) *Execution {
+	return e.send(ctx, req, nil, "")
+}
+
+// send performs a resolved request with the cookies and connections of ws, if not nil. Streamed
+// responses are emitted record by record while they are read (see SetStreamEmitter).
+func (e *Engine) send(ctx context.Context, req *ResolvedRequest, ws *WorkspaceContext, requestID string) *Execution {
+	cache := e.validatorsFor(ws)
+	var cacheInfo *CacheInfo
+	if cache != nil {
+		req, cacheInfo = cache.conditional(req)
+	}
+	sent := *req
+	exec := &Execution{
+		ID:          uuid.New().String(),
+		RequestID:   requestID,
+		Request:     req.Masked(),
+		StartedAt:   time.Now(),
+		InsecureTLS: req.TLS != nil && req.TLS.InsecureSkipVerify,
+		Cache:       cacheInfo,
+		sent:        &sent,
+	}
+	// Transport errors quote the URL, which may carry secrets
+	defer func() { exec.Error = req.maskString(exec.Error) }()
+
+	if timeout := time.Duration(e.timeout.Load()); timeout > 0 {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+	}
+
+	client, err := e.clientFor(req, ws)
+	if err != nil {
+		exec.Error = err.Error()
+		return exec
+	}
+
+	// Rate-limited attempts are recorded and retried as the retry policy says
+	policy := e.retryPolicy()
+	recorder := newTimingRecorder()
+	resp, release, err := e.do(ctx, client, req, recorder)
+	for retry := 1; err == nil; retry++ {
+		delay, ok := policy.retryDelay(resp, retry, time.Now())
+		if !ok {
+			break
+		}
+		// A retry that could not start before the timeout would only turn the response into an error
+		if deadline, set := ctx.Deadline(); set && time.Until(deadline) <= delay {
+			break
+		}
+		exec.Attempts = append(exec.Attempts, RetryAttempt{
+			Status:     resp.StatusCode,
+			RetryAfter: resp.Header.Get("Retry-After"),
+			StartedAt:  recorder.start,
+			DurationMs: time.Since(recorder.start).Milliseconds(),
+			DelayMs:    delay.Milliseconds(),
+		})
+		resp.Body.Close()
+		release()
+		if err = wait(ctx, delay); err != nil {
+			err = fmt.Errorf("stopped waiting to retry: %w", err)
+			break
+		}
+		recorder = newTimingRecorder()
+		resp, release, err = e.do(ctx, client, req, recorder)
+	}
+	if err != nil {
+		exec.Error = err.Error()
+		exec.DurationMs = time.Since(exec.StartedAt).Milliseconds()
+		return exec
+	}
+	defer release()
+	defer resp.Body.Close()
+
+	var body []byte
+	var size int64
+	if emit := e.streamEmitter(); emit != nil && isStream(resp) {
+		body, size, err = e.readStream(ctx, exec, resp, emit)
+	} else {
+		body, size, err = e.readBody(exec, resp)
+	}
+	end := time.Now()
+	exec.DurationMs = end.Sub(exec.StartedAt).Milliseconds()
+	timings := recorder.finish(end)
+	exec.Timings = &timings
+	if err != nil {
+		exec.Error = fmt.Sprintf("failed to read response body: %v", err)
+	}
+
+	exec.Status = resp.StatusCode
+	exec.StatusText = http.StatusText(resp.StatusCode)
+	exec.Headers = map[string][]string(resp.Header)
+	exec.Hints = AnalyzeHeaders(req, resp.StatusCode, resp.Header)
+	exec.Size = size
+
+	// A 304 to the validators sent is shown with the body of the response they came from
+	header := resp.Header
+	if exec.Cache != nil && err == nil && !exec.Truncated {
+		if resp.StatusCode == http.StatusNotModified {
+			exec.Cache.NotModified = true
+			if entry, ok := cache.lookup(req); ok && (exec.Cache.IfNoneMatch != "" || exec.Cache.IfModifiedSince != "") {
+				header, body = entry.revalidated(resp.Header)
+				exec.Cache.Hit, exec.Cache.StoredAt = true, &entry.storedAt
+			}
+		} else {
+			exec.Cache.Stored = cache.store(req, resp, body)
+		}
+	}
+
+	// The start of a compressed body usually cannot be decoded, which only affects the preview
+	processed, err := response.Process(header, body)
+	if err != nil && exec.Error == "" && !exec.Truncated {
+		exec.Error = err.Error()
+	}
+	exec.raw = processed.Bytes
+	exec.FormattedBody = processed.Formatted
+	exec.MimeType = processed.MimeType
+	exec.Charset = processed.Charset
+	exec.ContentEncoding = processed.ContentEncoding
+
+	// Binary bodies are not pushed through a JSON string; the frontend gets a preview or a file instead
+	if response.IsText(processed.MimeType) || len(processed.Bytes) == 0 {
+		exec.Body = processed.Raw
+	} else if exec.Binary, err = response.DescribeBinary(processed); err != nil && exec.Error == "" {
+		exec.Error = err.Error()
+	}
+
+	return exec
+}
+
+// do sends a request once, with the NTLM/Negotiate handshake when its auth needs one. release
+// frees the connection the handshake was bound to, once the response has been read.
+func (e *Engine) do(ctx context.Context, client *http.Client, req *ResolvedRequest, recorder *timingRecorder) (*http.Response, func(), error) {
+	httpReq, err := buildHTTPRequest(httptrace.WithClientTrace(ctx, recorder.trace()), req)
+	if err != nil {
+		return nil, nil, err
+	}
+	httpReq.Close = e.forceClose()
+	auth := req.handshake
+	if auth == nil {
+		resp, err := client.Do(httpReq)
+		return resp, func() {}, err
+	}
+	scheme := "NTLM"
+	if auth.Type == requests.AuthTypeNegotiate {
+		scheme = "Negotiate"
+	}
+	resp, release, err := sendWithHandshake(client, httpReq, scheme, newNTLMCredentials(auth.Username, auth.Password, auth.Domain))
+	if release == nil {
+		release = func() {}
+	}
+	if err != nil {
+		release()
+	}
+	return resp, release, err
+}
+
+// readBody reads the response body up to the engine's size limit and returns it with the size of
+// the whole body. A larger body is truncated and, when enabled, streamed into exec.BodyFile; its
+// size is then the bytes streamed, otherwise the Content-Length or what was read.
+func (e *Engine) readBody(exec *Execution, resp *http.Response) ([]byte, int64, error) {
+	limit := e.maxBodySize.Load()
+	if limit <= 0 {
+		body, err := io.ReadAll(resp.Body)
+		return body, int64(len(body)), err
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil || int64(len(body)) <= limit {
+		return body, int64(len(body)), err
+	}
+	exec.Truncated = true
+	size := max(resp.ContentLength, int64(len(body)))
+	if e.spillBodies.Load() {
+		path, written, err := response.WriteTempBody(io.MultiReader(bytes.NewReader(body), resp.Body), resp.Header.Get("Content-Type"))
+		if err != nil {
+			return body[:limit], written, err
+		}
+		exec.BodyFile, size = path, written
+	}
+	return body[:limit], size, nil
+}
+
+// buildHTTPRequest converts a resolved request into a net/http request
+func buildHTTPRequest(ctx context.Context, req *ResolvedRequest) (*http.Request, error) {
+	var body io.Reader
+	if req.Body != "" {
+		body = strings.NewReader(req.Body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	for _, h := range req.Headers {
+		if strings.EqualFold(h.Key, "Host") {
+			httpReq.Host = h.Value
+			continue
+		}
+		httpReq.Header.Add(h.Key, h.Value)
+	}
+
+	return httpReq, nil
+}
+
+// RawBody returns the decoded response body as bytes
+func (exec *Execution) RawBody() []byte {
+	return exec.raw
+}
+
+// SaveBody writes the decoded response body to path. The body of a truncated response is copied
+// from BodyFile as it was received.
+func (exec *Execution) SaveBody(path string) error {
+	if exec.Truncated {
+		return exec.copyBodyFile(path)
+	}
+	if exec.raw == nil {
+		return apperrors.NotFoundf("execution has no response body")
+	}
+	if err := os.WriteFile(path, exec.raw, 0o644); err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to save response")
+	}
+	return nil
+}
+
+// copyBodyFile writes the whole body of a truncated response to path
+func (exec *Execution) copyBodyFile(path string) error {
+	if exec.BodyFile == "" {
+		return apperrors.Invalidf("the response was truncated and its full body was not kept")
+	}
+	src, err := os.Open(exec.BodyFile)
+	if err != nil {
+		return apperrors.Wrap(apperrors.NotFound, err, "the full response body is no longer available")
+	}
+	defer src.Close()
+	dst, err := os.Create(path)
+	if err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to save response")
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return apperrors.Wrap(apperrors.IOError, err, "failed to save response")
+	}
+	if err := dst.Close(); err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to save response")
+	}
+	return nil
+}
@@ -0,0 +1,439 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/requests"
+)
+
+const userSchema = `{
+	"type": "object",
+	"required": ["id", "email"],
+	"properties": {
+		"id": {"type": "integer"},
+		"email": {"type": "string"}
+	}
+}`
+
+func TestRunSendsRequestAndChecksSchema(t *testing.T) {
+	var gotMethod, gotBody, gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotHeader = r.Header.Get("X-Trace")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/users/bad" {
+			w.Write([]byte(`{"id": "seven"}`))
+			return
+		}
+		w.Write([]byte(`{"id": 7, "email": "a@example.com"}`))
+	}))
+	defer server.Close()
+
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"good": {
+				Type:           requests.ItemTypeRequest,
+				Name:           "Good",
+				Method:         "POST",
+				Path:           "/users/good",
+				Headers:        []requests.Header{{Key: "X-Trace", Value: "abc"}},
+				Body:           `{"name":"a"}`,
+				ResponseSchema: userSchema,
+			},
+			"bad": {
+				Type:           requests.ItemTypeRequest,
+				Name:           "Bad",
+				Method:         "GET",
+				Path:           "/users/bad",
+				ResponseSchema: userSchema,
+			},
+		},
+	}
+	src := Sources{Requests: cfg, UserBaseURL: server.URL}
+	eng := NewWithClient(server.Client())
+
+	exec, err := eng.Run(context.Background(), src, "good")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if gotMethod != "POST" || gotBody != `{"name":"a"}` || gotHeader != "abc" {
+		t.Errorf("server received method=%q body=%q header=%q", gotMethod, gotBody, gotHeader)
+	}
+	if exec.Status != http.StatusOK || exec.RequestID != "good" || exec.Error != "" {
+		t.Errorf("Run() status=%d requestId=%q error=%q", exec.Status, exec.RequestID, exec.Error)
+	}
+	if len(exec.Tests) != 1 || !exec.Tests[0].Passed {
+		t.Errorf("Run() tests = %+v, want single passing schema check", exec.Tests)
+	}
+	if exec.Timings == nil || exec.Timings.Total <= 0 || exec.Timings.ReusedConn || exec.Timings.RemoteAddr == "" {
+		t.Errorf("Run() timings = %+v, want a fresh connection with a total duration", exec.Timings)
+	}
+
+	exec, err = eng.Run(context.Background(), src, "bad")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	var failures []string
+	for _, result := range exec.Tests {
+		if !result.Passed {
+			failures = append(failures, result.Name)
+		}
+	}
+	if len(failures) != 2 {
+		t.Fatalf("Run() failures = %v, want violations for /id and root", failures)
+	}
+	if !strings.Contains(strings.Join(failures, ","), "/id") {
+		t.Errorf("Run() failures = %v, want one located at /id", failures)
+	}
+}
+
+func TestRunMasksSecrets(t *testing.T) {
+	var gotAuth, gotKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotKey = r.URL.Query().Get("key")
+	}))
+	defer server.Close()
+
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"get": {
+				Type:    requests.ItemTypeRequest,
+				Name:    "Get",
+				Method:  "GET",
+				Path:    "/items?key={{apiKey}}",
+				Headers: []requests.Header{{Key: "X-Api-Key", Value: "{{apiKey}}"}, {Key: "X-Session", Value: "abc", Secret: true}},
+				Auth:    &requests.Auth{Type: requests.AuthTypeBearer, Token: "{{token}}"},
+			},
+		},
+	}
+	src := Sources{
+		Requests:    cfg,
+		UserBaseURL: server.URL,
+		Environment: &environments.Environment{
+			Name: "dev",
+			Variables: []environments.Variable{
+				{Key: "apiKey", Value: "k-12345", Secret: true},
+				{Key: "token", Value: "t-67890"},
+			},
+		},
+	}
+
+	exec, err := NewWithClient(server.Client()).Run(context.Background(), src, "get")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if gotAuth != "Bearer t-67890" || gotKey != "k-12345" {
+		t.Errorf("server received authorization=%q key=%q, want the secrets", gotAuth, gotKey)
+	}
+	if strings.Contains(exec.Request.URL, "k-12345") || !strings.HasSuffix(exec.Request.URL, "key=****") {
+		t.Errorf("Run() request URL = %q, want the secret masked", exec.Request.URL)
+	}
+	for _, h := range exec.Request.Headers {
+		if h.Value != requests.SecretMask {
+			t.Errorf("Run() request header %s = %q, want it masked", h.Key, h.Value)
+		}
+	}
+	if sent := exec.Unmasked(); !strings.Contains(sent.URL, "key=k-12345") {
+		t.Errorf("Unmasked() URL = %q, want the value sent", sent.URL)
+	}
+}
+
+func TestRunMiddleware(t *testing.T) {
+	var attempts int
+	var gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			// Drop the connection so the first attempt fails in transport
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		gotHeader = r.Header.Get("X-Hook")
+	}))
+	defer server.Close()
+
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"get": {Type: requests.ItemTypeRequest, Name: "Get", Method: "GET", Path: "/items"},
+		},
+	}
+	chain := NewMiddlewareRegistry()
+	chain.Register("resolve", OrderResolve, resolveStage)
+	chain.Register("auth", OrderAuth, authStage)
+	chain.Register("checks", OrderChecks, checksStage)
+	var log bytes.Buffer
+	chain.Register("log", OrderObserve, Logging(&log))
+	chain.Register("retry", OrderSend, Retry(3, time.Millisecond))
+	chain.Register("hook", OrderSend+1, func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (*Execution, error) {
+			call.Request.Headers = append(call.Request.Headers, requests.Header{Key: "X-Hook", Value: call.RequestID})
+			return next(ctx, call)
+		}
+	})
+	if got := strings.Join(chain.Names(), ","); got != "log,resolve,auth,checks,retry,hook" {
+		t.Fatalf("Names() = %s", got)
+	}
+
+	e := NewWithClient(server.Client())
+	e.SetMiddleware(chain)
+	exec, err := e.Run(context.Background(), Sources{Requests: cfg, UserBaseURL: server.URL}, "get")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if attempts != 2 || exec.Error != "" || exec.Status != http.StatusOK {
+		t.Errorf("Run() took %d attempts, status %d, error %q; want a retry to succeed", attempts, exec.Status, exec.Error)
+	}
+	if gotHeader != "get" {
+		t.Errorf("server received X-Hook = %q, want the header added by the middleware", gotHeader)
+	}
+	if !strings.HasPrefix(log.String(), "GET "+server.URL+"/items 200") {
+		t.Errorf("log = %q", log.String())
+	}
+
+	chain.Unregister("resolve")
+	if _, err := e.Run(context.Background(), Sources{Requests: cfg, UserBaseURL: server.URL}, "get"); err == nil {
+		t.Error("Run() without the resolve stage succeeded")
+	}
+}
+
+func TestCheckResponseSchemaNonJSONBody(t *testing.T) {
+	results := CheckResponseSchema(userSchema, "<html></html>")
+	if len(results) != 1 || results[0].Passed {
+		t.Errorf("CheckResponseSchema() = %+v, want single failure", results)
+	}
+}
+
+func TestSendDescribesBinaryResponses(t *testing.T) {
+	var img bytes.Buffer
+	png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 3, 2)))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(img.Bytes())
+	}))
+	defer server.Close()
+
+	exec := NewWithClient(server.Client()).Send(context.Background(), &ResolvedRequest{Method: "GET", URL: server.URL})
+	if exec.Error != "" {
+		t.Fatalf("Send() error = %s", exec.Error)
+	}
+	if exec.Body != "" || exec.Binary == nil {
+		t.Fatalf("Send() body = %q binary = %+v, want binary description only", exec.Body, exec.Binary)
+	}
+	if exec.Binary.Width != 3 || exec.Binary.Height != 2 || exec.Binary.Size != img.Len() {
+		t.Errorf("Send() binary = %+v", exec.Binary)
+	}
+	if preview, _ := base64.StdEncoding.DecodeString(exec.Binary.Preview); !bytes.Equal(preview, img.Bytes()) {
+		t.Errorf("Send() preview does not match the response body")
+	}
+
+	path := filepath.Join(t.TempDir(), "out.png")
+	if err := exec.SaveBody(path); err != nil {
+		t.Fatalf("SaveBody() error = %v", err)
+	}
+	if saved, _ := os.ReadFile(path); !bytes.Equal(saved, img.Bytes()) {
+		t.Errorf("SaveBody() wrote %d bytes, want %d", len(saved), img.Len())
+	}
+}
+
+func TestSendTruncatesLargeBodies(t *testing.T) {
+	body := strings.Repeat("0123456789", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	e := NewWithClient(server.Client())
+	e.SetBodyLimit(100, true)
+	exec := e.Send(context.Background(), &ResolvedRequest{Method: "GET", URL: server.URL})
+	if exec.Error != "" {
+		t.Fatalf("Send() error = %s", exec.Error)
+	}
+	if !exec.Truncated || exec.Body != body[:100] || exec.Size != int64(len(body)) {
+		t.Fatalf("Send() truncated = %v, body length %d, size %d", exec.Truncated, len(exec.Body), exec.Size)
+	}
+	saved := filepath.Join(t.TempDir(), "body.txt")
+	if err := exec.SaveBody(saved); err != nil {
+		t.Fatalf("SaveBody() error = %v", err)
+	}
+	if data, _ := os.ReadFile(saved); string(data) != body {
+		t.Errorf("SaveBody() wrote %d bytes, want the whole body", len(data))
+	}
+	os.Remove(exec.BodyFile)
+
+	e.SetBodyLimit(100, false)
+	exec = e.Send(context.Background(), &ResolvedRequest{Method: "GET", URL: server.URL})
+	if !exec.Truncated || exec.BodyFile != "" || exec.SaveBody(saved) == nil {
+		t.Errorf("Send() without saving kept %q", exec.BodyFile)
+	}
+}
+
+func TestWorkspacesIsolateCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s-1", Path: "/"})
+			return
+		}
+		if _, err := r.Cookie("session"); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"login": {Type: requests.ItemTypeRequest, Name: "Login", Method: "POST", Path: "/login"},
+			"me":    {Type: requests.ItemTypeRequest, Name: "Me", Method: "GET", Path: "/me"},
+		},
+	}
+	e := NewWithClient(server.Client())
+	work, personal := NewWorkspaceContext("work"), NewWorkspaceContext("personal")
+	status := func(ws *WorkspaceContext, requestID string) int {
+		t.Helper()
+		exec, err := e.Run(context.Background(), Sources{Requests: cfg, UserBaseURL: server.URL, Workspace: ws}, requestID)
+		if err != nil || exec.Error != "" {
+			t.Fatalf("Run(%s) error = %v %s", requestID, err, exec.Error)
+		}
+		return exec.Status
+	}
+
+	status(work, "login")
+	if got := status(work, "me"); got != http.StatusOK {
+		t.Errorf("status in the workspace that logged in = %d, want the cookie sent", got)
+	}
+	if got := status(personal, "me"); got != http.StatusUnauthorized {
+		t.Errorf("status in another workspace = %d, want no cookie", got)
+	}
+	if got := status(nil, "me"); got != http.StatusUnauthorized {
+		t.Errorf("status without a workspace = %d, want no cookie", got)
+	}
+
+	work.Reset()
+	if got := status(work, "me"); got != http.StatusUnauthorized {
+		t.Errorf("status after Reset() = %d, want the cookie dropped", got)
+	}
+}
+
+func TestSendAppliesTransportSettings(t *testing.T) {
+	var closing []bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		closing = append(closing, r.Close)
+	}))
+	defer server.Close()
+
+	e := NewWithClient(&http.Client{})
+	e.SetTransportSettings(TransportSettings{MaxIdleConnsPerHost: 8, IdleConnTimeout: time.Minute})
+	transport, ok := e.client.Transport.(*http.Transport)
+	if !ok || transport.MaxIdleConnsPerHost != 8 || transport.IdleConnTimeout != time.Minute {
+		t.Fatalf("shared transport = %+v, want the settings applied", e.client.Transport)
+	}
+
+	ws := NewWorkspaceContext("work")
+	client, err := e.clientFor(&ResolvedRequest{}, ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wsTransport, ok := client.Transport.(*http.Transport); !ok || wsTransport == transport || wsTransport.MaxIdleConnsPerHost != 8 {
+		t.Errorf("workspace transport = %+v, want a tuned transport of its own", client.Transport)
+	}
+
+	e.SetTransportSettings(TransportSettings{ForceClose: true})
+	e.Send(context.Background(), &ResolvedRequest{Method: "GET", URL: server.URL})
+	if len(closing) != 1 || !closing[0] {
+		t.Errorf("requests closing their connection = %v, want Connection: close", closing)
+	}
+}
+
+func TestSendHonoursTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	e := NewWithClient(server.Client())
+	e.SetTimeout(50 * time.Millisecond)
+
+	exec := e.Send(context.Background(), &ResolvedRequest{Method: "GET", URL: server.URL})
+	if !strings.Contains(exec.Error, "deadline exceeded") {
+		t.Errorf("Send() error = %q, want a timeout", exec.Error)
+	}
+}
+
+func TestSendHonoursHostOverrides(t *testing.T) {
+	var gotHost string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+		w.Write([]byte("local"))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	eng := New()
+	req := &ResolvedRequest{
+		Method:        "GET",
+		URL:           "http://api.example.invalid:" + port + "/health",
+		HostOverrides: map[string]string{"api.example.invalid": "127.0.0.1"},
+	}
+	exec := eng.Send(context.Background(), req)
+	if exec.Error != "" || exec.Body != "local" {
+		t.Fatalf("Send() error = %q body = %q", exec.Error, exec.Body)
+	}
+	if gotHost != "api.example.invalid:"+port {
+		t.Errorf("server saw Host %q, want the original host", gotHost)
+	}
+
+	// Without the override the name does not resolve
+	req.HostOverrides = nil
+	if exec := eng.Send(context.Background(), req); exec.Error == "" {
+		t.Error("Send() without override should fail to resolve the host")
+	}
+}
+
+func TestOverrideAddr(t *testing.T) {
+	overrides := map[string]string{
+		"api.example.com":     "127.0.0.1:8080",
+		"web.example.com:443": "10.0.0.2",
+		"web.example.com":     "10.0.0.1",
+	}
+	tests := []struct{ addr, want string }{
+		{"api.example.com:443", "127.0.0.1:8080"},
+		{"API.example.com:80", "127.0.0.1:8080"},
+		{"web.example.com:443", "10.0.0.2:443"},
+		{"web.example.com:80", "10.0.0.1:80"},
+		{"other.example.com:443", "other.example.com:443"},
+	}
+	for _, tt := range tests {
+		if got := overrideAddr(overrides, tt.addr); got != tt.want {
+			t.Errorf("overrideAddr(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
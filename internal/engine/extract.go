@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"paperbox/internal/config/requests"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"github.com/ohler55/ojg/jp"
+	"github.com/ohler55/ojg/oj"
+)
+
+// Extract evaluates an expression against an execution's response.
+// Scalars are returned as plain text; multiple matches, objects and arrays are returned as JSON.
+func Extract(exec *Execution, expression string, kind requests.ExtractKind) (string, error) {
+	if kind == requests.ExtractKindStatus {
+		return strconv.Itoa(exec.Status), nil
+	}
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		return "", fmt.Errorf("expression is empty")
+	}
+
+	switch kind {
+	case requests.ExtractKindJSONPath:
+		return extractJSONPath(exec.Body, expression)
+	case requests.ExtractKindXPath:
+		return extractXPath(exec.Body, expression)
+	case requests.ExtractKindHeader:
+		values := http.Header(exec.Headers).Values(expression)
+		if len(values) == 0 {
+			return "", fmt.Errorf("response has no '%s' header", expression)
+		}
+		return strings.Join(values, ", "), nil
+	default:
+		return "", fmt.Errorf("unsupported extraction kind '%s'", kind)
+	}
+}
+
+// extractJSONPath evaluates a JSONPath expression against a JSON body
+func extractJSONPath(body string, expression string) (string, error) {
+	path, err := jp.ParseString(expression)
+	if err != nil {
+		return "", fmt.Errorf("invalid JSONPath: %w", err)
+	}
+	data, err := oj.ParseString(body)
+	if err != nil {
+		return "", fmt.Errorf("response body is not valid JSON: %w", err)
+	}
+
+	matches := path.Get(data)
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("JSONPath matched nothing")
+	case 1:
+		return formatValue(matches[0])
+	default:
+		return formatValue(matches)
+	}
+}
+
+// extractXPath evaluates an XPath expression against an XML body. Node sets yield the text of
+// their nodes; expressions such as count(//item) or //total > 100 yield their number or boolean.
+// Prefixed names match the prefixes used in the document, local-name() matches any namespace.
+func extractXPath(body string, expression string) (string, error) {
+	doc, err := xmlquery.Parse(strings.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("response body is not valid XML: %w", err)
+	}
+
+	expr, err := xpath.Compile(expression)
+	if err != nil {
+		return "", fmt.Errorf("invalid XPath: %w", err)
+	}
+
+	var texts []string
+	switch result := expr.Evaluate(xmlquery.CreateXPathNavigator(doc)).(type) {
+	case *xpath.NodeIterator:
+		for result.MoveNext() {
+			texts = append(texts, result.Current().Value())
+		}
+	case string:
+		return result, nil
+	case float64:
+		return strconv.FormatFloat(result, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(result), nil
+	}
+
+	switch len(texts) {
+	case 0:
+		return "", fmt.Errorf("XPath matched nothing")
+	case 1:
+		return texts[0], nil
+	default:
+		return formatValue(texts)
+	}
+}
+
+// formatValue renders strings as-is and everything else as compact JSON
+func formatValue(value interface{}) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode extracted value: %w", err)
+	}
+	return string(data), nil
+}
+
+// applyCaptures evaluates enabled capture rules against the execution, storing the values in
+// exec.Captured and reporting failures as failed test results
+func applyCaptures(rules []requests.CaptureRule, exec *Execution) []TestResult {
+	var results []TestResult
+
+	for _, rule := range rules {
+		if rule.Disabled {
+			continue
+		}
+		value, err := Extract(exec, rule.Expression, rule.Kind)
+		if err != nil {
+			results = append(results, TestResult{
+				Name:    fmt.Sprintf("Capture %s", rule.Variable),
+				Message: err.Error(),
+			})
+			continue
+		}
+		if exec.Captured == nil {
+			exec.Captured = make(map[string]string)
+		}
+		exec.Captured[rule.Variable] = value
+	}
+
+	return results
+}
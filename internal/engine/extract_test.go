@@ -0,0 +1,104 @@
+package engine
+
+import (
+	"testing"
+
+	"paperbox/internal/config/requests"
+)
+
+func TestExtract(t *testing.T) {
+	jsonExec := &Execution{
+		Body:    `{"token": "abc", "user": {"id": 7}, "items": [{"n": 1}, {"n": 2}]}`,
+		Headers: map[string][]string{"X-Request-Id": {"r-1"}},
+	}
+	xmlExec := &Execution{
+		Body: `<users><user id="1"><name>Ann</name></user><user id="2"><name>Bob</name></user></users>`,
+	}
+
+	tests := []struct {
+		name       string
+		exec       *Execution
+		expression string
+		kind       requests.ExtractKind
+		want       string
+		wantErr    bool
+	}{
+		{"JSONPath string", jsonExec, "$.token", requests.ExtractKindJSONPath, "abc", false},
+		{"JSONPath number", jsonExec, "$.user.id", requests.ExtractKindJSONPath, "7", false},
+		{"JSONPath multiple matches", jsonExec, "$.items[*].n", requests.ExtractKindJSONPath, "[1,2]", false},
+		{"JSONPath no match", jsonExec, "$.missing", requests.ExtractKindJSONPath, "", true},
+		{"XPath element text", xmlExec, "//user[@id='2']/name", requests.ExtractKindXPath, "Bob", false},
+		{"XPath attribute", xmlExec, "//user[1]/@id", requests.ExtractKindXPath, "1", false},
+		{"XPath count", xmlExec, "count(//user)", requests.ExtractKindXPath, "2", false},
+		{"XPath comparison", xmlExec, "//user[1]/@id < //user[2]/@id", requests.ExtractKindXPath, "true", false},
+		{"XPath multiple matches", xmlExec, "//name", requests.ExtractKindXPath, `["Ann","Bob"]`, false},
+		{"XPath local-name", xmlExec, "//*[local-name()='user'][2]/name", requests.ExtractKindXPath, "Bob", false},
+		{"XPath syntax error", xmlExec, "//user[", requests.ExtractKindXPath, "", true},
+		{"XPath on JSON body", jsonExec, "//user", requests.ExtractKindXPath, "", true},
+		{"header is case-insensitive", jsonExec, "x-request-id", requests.ExtractKindHeader, "r-1", false},
+		{"status ignores the expression", &Execution{Status: 201}, "", requests.ExtractKindStatus, "201", false},
+		{"unknown kind", jsonExec, "$.token", "regex", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract(tt.exec, tt.expression, tt.kind)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Extract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAssertion(t *testing.T) {
+	exec := &Execution{
+		Body: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>` +
+			`<GetOrderResponse><Status>SHIPPED</Status><Total>42.50</Total></GetOrderResponse></soap:Body></soap:Envelope>`,
+		Headers: map[string][]string{"Content-Type": {"text/xml; charset=utf-8"}},
+	}
+
+	tests := []struct {
+		assertion requests.Assertion
+		passed    bool
+	}{
+		{requests.Assertion{Kind: requests.ExtractKindXPath, Expression: "//soap:Body//Status", Operator: requests.AssertEquals, Value: "SHIPPED"}, true},
+		{requests.Assertion{Kind: requests.ExtractKindXPath, Expression: "//Status", Operator: requests.AssertNotEquals, Value: "SHIPPED"}, false},
+		{requests.Assertion{Kind: requests.ExtractKindXPath, Expression: "//Total", Operator: requests.AssertGreaterThan, Value: "40"}, true},
+		{requests.Assertion{Kind: requests.ExtractKindXPath, Expression: "//Total", Operator: requests.AssertLessThan, Value: "40"}, false},
+		{requests.Assertion{Kind: requests.ExtractKindXPath, Expression: "//Status", Operator: requests.AssertLessThan, Value: "40"}, false},
+		{requests.Assertion{Kind: requests.ExtractKindXPath, Expression: "//Status", Operator: requests.AssertMatches, Value: "^SHIP"}, true},
+		{requests.Assertion{Kind: requests.ExtractKindXPath, Expression: "//soap:Fault", Operator: requests.AssertNotExists}, true},
+		{requests.Assertion{Kind: requests.ExtractKindXPath, Expression: "//soap:Fault", Operator: requests.AssertExists}, false},
+		{requests.Assertion{Kind: requests.ExtractKindHeader, Expression: "Content-Type", Operator: requests.AssertContains, Value: "xml"}, true},
+	}
+
+	for _, tt := range tests {
+		result := CheckAssertion(tt.assertion, exec)
+		if result.Passed != tt.passed {
+			t.Errorf("CheckAssertion(%s) passed = %v (%s), want %v", result.Name, result.Passed, result.Message, tt.passed)
+		}
+		if !result.Passed && result.Message == "" {
+			t.Errorf("CheckAssertion(%s) failed without a message", result.Name)
+		}
+	}
+}
+
+func TestApplyCaptures(t *testing.T) {
+	exec := &Execution{Body: `{"access_token": "t-1"}`}
+	rules := []requests.CaptureRule{
+		{Variable: "token", Expression: "$.access_token", Kind: requests.ExtractKindJSONPath},
+		{Variable: "skipped", Expression: "$.access_token", Kind: requests.ExtractKindJSONPath, Disabled: true},
+		{Variable: "refresh", Expression: "$.refresh_token", Kind: requests.ExtractKindJSONPath},
+	}
+
+	results := applyCaptures(rules, exec)
+	if exec.Captured["token"] != "t-1" || len(exec.Captured) != 1 {
+		t.Errorf("applyCaptures() captured = %v", exec.Captured)
+	}
+	if len(results) != 1 || results[0].Passed || results[0].Name != "Capture refresh" {
+		t.Errorf("applyCaptures() results = %+v", results)
+	}
+}
@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxRunRepeat bounds how often a collection run may repeat each request
+	MaxRunRepeat = 100
+	// flakyLatencyRatio is how many times the median duration the slowest attempt may take
+	flakyLatencyRatio = 3
+	// flakyLatencyMinSpreadMs keeps fast endpoints with a few milliseconds of jitter from counting as flaky
+	flakyLatencyMinSpreadMs = 100
+)
+
+// Stability summarizes the repeated attempts of one request in a collection run. A request is
+// flaky when its status changes between attempts, some attempts pass and others fail, or the
+// slowest attempt takes far longer than the median.
+type Stability struct {
+	RequestID string         `json:"requestId"`
+	Attempts  int            `json:"attempts"`
+	Failures  int            `json:"failures"`
+	Statuses  map[string]int `json:"statuses"` // Attempts by status code, "error" for transport failures
+	MinMs     int64          `json:"minMs"`
+	MedianMs  int64          `json:"medianMs"`
+	MaxMs     int64          `json:"maxMs"`
+	Flaky     bool           `json:"flaky"`
+	Reasons   []string       `json:"reasons,omitempty"`
+}
+
+// stabilityOf compares the attempts of a request
+func stabilityOf(requestID string, attempts []*Execution) Stability {
+	s := Stability{RequestID: requestID, Attempts: len(attempts), Statuses: make(map[string]int)}
+
+	var durations []int64
+	for _, exec := range attempts {
+		if !executionPassed(exec) {
+			s.Failures++
+		}
+		if exec.Error != "" && exec.Status == 0 {
+			s.Statuses["error"]++
+			continue
+		}
+		s.Statuses[strconv.Itoa(exec.Status)]++
+		durations = append(durations, exec.DurationMs)
+	}
+
+	if len(s.Statuses) > 1 {
+		s.Reasons = append(s.Reasons, "status changed between attempts: "+describeStatuses(s.Statuses))
+	}
+	if s.Failures > 0 && s.Failures < s.Attempts {
+		s.Reasons = append(s.Reasons, fmt.Sprintf("%d of %d attempts failed", s.Failures, s.Attempts))
+	}
+
+	if len(durations) > 0 {
+		sort.Slice(durations, func(a, b int) bool { return durations[a] < durations[b] })
+		s.MinMs = durations[0]
+		s.MedianMs = durations[len(durations)/2]
+		s.MaxMs = durations[len(durations)-1]
+		if s.MaxMs-s.MinMs >= flakyLatencyMinSpreadMs && s.MaxMs > flakyLatencyRatio*s.MedianMs {
+			s.Reasons = append(s.Reasons, fmt.Sprintf("latency spread %d-%d ms around a median of %d ms", s.MinMs, s.MaxMs, s.MedianMs))
+		}
+	}
+
+	s.Flaky = len(s.Reasons) > 0
+	return s
+}
+
+// describeStatuses lists attempt counts by status, e.g. "200 ×4, 503 ×1"
+func describeStatuses(statuses map[string]int) string {
+	keys := make([]string, 0, len(statuses))
+	for key := range statuses {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s ×%d", key, statuses[key])
+	}
+	return strings.Join(parts, ", ")
+}
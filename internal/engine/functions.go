@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TemplateFunc computes the value of a {{name arg1 arg2}} placeholder at send time
+type TemplateFunc func(args []string) (string, error)
+
+// FuncRegistry is a concurrency-safe set of template functions
+type FuncRegistry struct {
+	mu    sync.RWMutex
+	funcs map[string]TemplateFunc
+}
+
+// NewFuncRegistry creates an empty registry
+func NewFuncRegistry() *FuncRegistry {
+	return &FuncRegistry{funcs: make(map[string]TemplateFunc)}
+}
+
+// Register adds or replaces a template function
+func (r *FuncRegistry) Register(name string, fn TemplateFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs[name] = fn
+}
+
+// Unregister removes a template function
+func (r *FuncRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.funcs, name)
+}
+
+// Lookup returns the function registered under name
+func (r *FuncRegistry) Lookup(name string) (TemplateFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn, ok := r.funcs[name]
+	return fn, ok
+}
+
+// Names returns the registered function names
+func (r *FuncRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.funcs))
+	for name := range r.funcs {
+		names = append(names, name)
+	}
+	return names
+}
+
+// DefaultFuncs holds the built-in template functions; other packages may register more
+var DefaultFuncs = NewFuncRegistry()
+
+func init() {
+	DefaultFuncs.Register("uuid", func(args []string) (string, error) {
+		return uuid.New().String(), nil
+	})
+	DefaultFuncs.Register("timestamp", func(args []string) (string, error) {
+		return strconv.FormatInt(time.Now().Unix(), 10), nil
+	})
+	DefaultFuncs.Register("isoDate", func(args []string) (string, error) {
+		return time.Now().UTC().Format(time.RFC3339), nil
+	})
+	DefaultFuncs.Register("randomInt", randomInt)
+	DefaultFuncs.Register("base64", func(args []string) (string, error) {
+		if len(args) != 1 {
+			return "", fmt.Errorf("base64 expects 1 argument, got %d", len(args))
+		}
+		return base64.StdEncoding.EncodeToString([]byte(args[0])), nil
+	})
+	DefaultFuncs.Register("hmacSHA256", func(args []string) (string, error) {
+		if len(args) != 2 {
+			return "", fmt.Errorf("hmacSHA256 expects 2 arguments (key, payload), got %d", len(args))
+		}
+		mac := hmac.New(sha256.New, []byte(args[0]))
+		mac.Write([]byte(args[1]))
+		return hex.EncodeToString(mac.Sum(nil)), nil
+	})
+}
+
+// randomInt returns a random integer in [min, max] (defaults: 0..1000)
+func randomInt(args []string) (string, error) {
+	min, max := int64(0), int64(1000)
+	if len(args) > 0 {
+		if len(args) != 2 {
+			return "", fmt.Errorf("randomInt expects 0 or 2 arguments (min, max), got %d", len(args))
+		}
+		var err error
+		if min, err = strconv.ParseInt(args[0], 10, 64); err != nil {
+			return "", fmt.Errorf("randomInt: invalid min %q", args[0])
+		}
+		if max, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+			return "", fmt.Errorf("randomInt: invalid max %q", args[1])
+		}
+	}
+	if max < min {
+		return "", fmt.Errorf("randomInt: max %d is less than min %d", max, min)
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(max-min+1))
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(n.Int64()+min, 10), nil
+}
+
+// token is a single argument inside a template expression
+type token struct {
+	text   string
+	quoted bool
+}
+
+// tokenize splits an expression on whitespace, keeping "double quoted" strings (with \" escapes) together
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	var current strings.Builder
+	inQuotes, hasToken := false, false
+
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case inQuotes && c == '\\' && i+1 < len(expr):
+			i++
+			current.WriteByte(expr[i])
+		case c == '"':
+			if inQuotes {
+				tokens = append(tokens, token{text: current.String(), quoted: true})
+				current.Reset()
+				hasToken = false
+			}
+			inQuotes = !inQuotes
+		case !inQuotes && (c == ' ' || c == '\t'):
+			if hasToken {
+				tokens = append(tokens, token{text: current.String()})
+				current.Reset()
+				hasToken = false
+			}
+		default:
+			current.WriteByte(c)
+			hasToken = true
+		}
+	}
+
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated string in %q", expr)
+	}
+	if hasToken {
+		tokens = append(tokens, token{text: current.String()})
+	}
+	return tokens, nil
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Header hint categories
+const (
+	HintSecurity   = "security"
+	HintCaching    = "caching"
+	HintDeprecated = "deprecated"
+)
+
+// Header hint severities
+const (
+	HintInfo    = "info"
+	HintWarning = "warning"
+)
+
+// minHSTSMaxAge is the HSTS lifetime below which a policy is reported as short (180 days)
+const minHSTSMaxAge = 180 * 24 * 60 * 60
+
+// HeaderHint is a finding about the headers of a response, e.g. a missing security header or
+// caching headers that contradict each other
+type HeaderHint struct {
+	Category string `json:"category"` // HintSecurity, HintCaching or HintDeprecated
+	Severity string `json:"severity"` // HintInfo or HintWarning
+	Header   string `json:"header"`   // Header the hint is about
+	Message  string `json:"message"`
+}
+
+// deprecatedHeaders are response headers browsers ignore or no longer recommend, with what to use
+var deprecatedHeaders = []struct {
+	name, advice string
+}{
+	{"X-XSS-Protection", "browsers removed the XSS auditor; set Content-Security-Policy and drop the header or send 0"},
+	{"Public-Key-Pins", "HPKP is no longer supported by browsers"},
+	{"Public-Key-Pins-Report-Only", "HPKP is no longer supported by browsers"},
+	{"Expect-CT", "Certificate Transparency is enforced by default; the header is obsolete"},
+	{"Feature-Policy", "replaced by Permissions-Policy"},
+	{"P3P", "P3P policies are ignored by browsers"},
+}
+
+// AnalyzeHeaders reports security, caching and deprecation findings about the headers of a
+// response to req. Only responses that were received are analyzed.
+func AnalyzeHeaders(req *ResolvedRequest, status int, header http.Header) []HeaderHint {
+	if status == 0 {
+		return nil
+	}
+	var hints []HeaderHint
+	add := func(category, severity, name, message string) {
+		hints = append(hints, HeaderHint{Category: category, Severity: severity, Header: name, Message: message})
+	}
+
+	// Security
+	secure := false
+	if u, err := url.Parse(req.URL); err == nil {
+		secure = strings.EqualFold(u.Scheme, "https")
+	}
+	if hsts := header.Get("Strict-Transport-Security"); secure && hsts == "" {
+		add(HintSecurity, HintWarning, "Strict-Transport-Security", "HTTPS response without HSTS; browsers may still connect over plain HTTP")
+	} else if secure {
+		if maxAge, ok := directiveValue(hsts, "max-age"); ok {
+			if seconds, err := strconv.Atoi(maxAge); err == nil && seconds < minHSTSMaxAge {
+				add(HintSecurity, HintInfo, "Strict-Transport-Security", "HSTS max-age is under 180 days")
+			}
+		}
+	} else if hsts != "" {
+		add(HintSecurity, HintInfo, "Strict-Transport-Security", "HSTS sent over plain HTTP is ignored by browsers")
+	}
+
+	origin := header.Get("Access-Control-Allow-Origin")
+	credentials := strings.EqualFold(header.Get("Access-Control-Allow-Credentials"), "true")
+	switch {
+	case origin == "*" && credentials:
+		add(HintSecurity, HintWarning, "Access-Control-Allow-Origin", "a wildcard origin with credentials allowed is rejected by browsers")
+	case origin == "*":
+		add(HintSecurity, HintInfo, "Access-Control-Allow-Origin", "any origin may read this response")
+	case origin == "null":
+		add(HintSecurity, HintWarning, "Access-Control-Allow-Origin", `allowing the "null" origin lets sandboxed pages and local files read this response`)
+	case origin != "" && credentials && origin == requestHeader(req, "Origin"):
+		add(HintSecurity, HintWarning, "Access-Control-Allow-Origin", "the request's Origin is reflected with credentials allowed; check that origins are validated")
+	}
+
+	if header.Get("Content-Type") != "" && !strings.EqualFold(header.Get("X-Content-Type-Options"), "nosniff") {
+		add(HintSecurity, HintInfo, "X-Content-Type-Options", "without nosniff browsers may guess another content type")
+	}
+	for _, name := range []string{"Server", "X-Powered-By", "X-AspNet-Version"} {
+		if value := header.Get(name); strings.ContainsAny(value, "0123456789") {
+			add(HintSecurity, HintInfo, name, "discloses software versions: "+value)
+		}
+	}
+
+	// Caching
+	cacheControl := strings.ToLower(strings.Join(header.Values("Cache-Control"), ","))
+	noStore := hasDirective(cacheControl, "no-store")
+	validators := header.Get("ETag") != "" || header.Get("Last-Modified") != ""
+	switch {
+	case noStore && validators:
+		add(HintCaching, HintInfo, "ETag", "validators are unused with no-store, as nothing is cached to revalidate")
+	case hasDirective(cacheControl, "no-cache") && !validators:
+		add(HintCaching, HintInfo, "Cache-Control", "no-cache without ETag or Last-Modified makes every revalidation a full download")
+	case cacheControl == "" && header.Get("Expires") == "" && !validators && status == http.StatusOK && req.Method == http.MethodGet:
+		add(HintCaching, HintInfo, "Cache-Control", "no caching headers; caches decide on their own how long to keep the response")
+	}
+	if header.Get("Expires") != "" && (hasDirective(cacheControl, "max-age") || hasDirective(cacheControl, "s-maxage")) {
+		add(HintCaching, HintInfo, "Expires", "ignored in favour of Cache-Control max-age")
+	}
+	if header.Get("Pragma") != "" {
+		if cacheControl == "" {
+			add(HintDeprecated, HintWarning, "Pragma", "Pragma is an HTTP/1.0 request header; responses should use Cache-Control")
+		} else {
+			add(HintDeprecated, HintInfo, "Pragma", "Pragma is an HTTP/1.0 request header and is ignored next to Cache-Control")
+		}
+	}
+
+	for _, deprecated := range deprecatedHeaders {
+		if header.Get(deprecated.name) != "" {
+			add(HintDeprecated, HintInfo, deprecated.name, deprecated.advice)
+		}
+	}
+	if header.Get("X-Frame-Options") != "" && strings.Contains(strings.ToLower(header.Get("Content-Security-Policy")), "frame-ancestors") {
+		add(HintDeprecated, HintInfo, "X-Frame-Options", "superseded by the frame-ancestors directive of Content-Security-Policy")
+	}
+	return hints
+}
+
+// directiveValue returns the value of a "name=value" directive of a comma or semicolon separated header
+func directiveValue(header, name string) (string, bool) {
+	for _, directive := range strings.FieldsFunc(header, func(r rune) bool { return r == ',' || r == ';' }) {
+		key, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(key, name) {
+			return strings.Trim(value, `"`), true
+		}
+	}
+	return "", false
+}
+
+// hasDirective reports whether a header has a directive, with or without a value
+func hasDirective(header, name string) bool {
+	_, ok := directiveValue(header, name)
+	return ok
+}
+
+// requestHeader returns the value of a header of a request
+func requestHeader(req *ResolvedRequest, name string) string {
+	for _, h := range req.Headers {
+		if strings.EqualFold(h.Key, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
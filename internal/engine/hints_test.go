@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"paperbox/internal/config/requests"
+)
+
+// hintHeaders lists the headers hints are about, in order
+func hintHeaders(hints []HeaderHint) []string {
+	var names []string
+	for _, h := range hints {
+		names = append(names, h.Header)
+	}
+	return names
+}
+
+func TestAnalyzeHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		req    []requests.Header
+		header http.Header
+		want   []string
+	}{
+		{
+			name:   "well configured HTTPS response",
+			url:    "https://api.example.com",
+			header: http.Header{"Strict-Transport-Security": {"max-age=31536000"}, "Cache-Control": {"no-cache"}, "Etag": {`"v1"`}},
+		},
+		{
+			name:   "HTTPS without HSTS",
+			url:    "https://api.example.com",
+			header: http.Header{"Cache-Control": {"max-age=60"}},
+			want:   []string{"Strict-Transport-Security"},
+		},
+		{
+			name:   "short HSTS and HSTS over HTTP",
+			url:    "http://api.example.com",
+			header: http.Header{"Strict-Transport-Security": {"max-age=60"}, "Cache-Control": {"max-age=60"}},
+			want:   []string{"Strict-Transport-Security"},
+		},
+		{
+			name:   "wildcard origin with credentials",
+			url:    "http://api.example.com",
+			header: http.Header{"Access-Control-Allow-Origin": {"*"}, "Access-Control-Allow-Credentials": {"true"}, "Cache-Control": {"no-store"}},
+			want:   []string{"Access-Control-Allow-Origin"},
+		},
+		{
+			name:   "reflected origin with credentials",
+			url:    "http://api.example.com",
+			req:    []requests.Header{{Key: "Origin", Value: "https://evil.example"}},
+			header: http.Header{"Access-Control-Allow-Origin": {"https://evil.example"}, "Access-Control-Allow-Credentials": {"true"}, "Cache-Control": {"no-store"}},
+			want:   []string{"Access-Control-Allow-Origin"},
+		},
+		{
+			name:   "no-store with validators, Expires next to max-age",
+			url:    "http://api.example.com",
+			header: http.Header{"Cache-Control": {"no-store, max-age=0"}, "Etag": {`"v1"`}, "Expires": {"0"}},
+			want:   []string{"ETag", "Expires"},
+		},
+		{
+			name:   "no caching headers",
+			url:    "http://api.example.com",
+			header: http.Header{},
+			want:   []string{"Cache-Control"},
+		},
+		{
+			name:   "deprecated headers and versions",
+			url:    "http://api.example.com",
+			header: http.Header{"Pragma": {"no-cache"}, "X-Xss-Protection": {"1; mode=block"}, "Server": {"nginx/1.25.3"}, "Last-Modified": {"Mon, 01 Jan 2024 00:00:00 GMT"}},
+			want:   []string{"Server", "Pragma", "X-XSS-Protection"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &ResolvedRequest{Method: "GET", URL: tt.url, Headers: tt.req}
+			got := hintHeaders(AnalyzeHeaders(req, http.StatusOK, tt.header))
+			if len(got) != len(tt.want) {
+				t.Fatalf("hints about %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("hints about %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	if hints := AnalyzeHeaders(&ResolvedRequest{URL: "https://api.example.com"}, 0, nil); hints != nil {
+		t.Errorf("hints without a response = %+v", hints)
+	}
+}
+
+func TestSendReturnsHeaderHints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "null")
+		w.Header().Set("Cache-Control", "no-store")
+	}))
+	defer server.Close()
+
+	exec := New().Send(context.Background(), &ResolvedRequest{Method: "GET", URL: server.URL})
+	if len(exec.Hints) != 1 || exec.Hints[0].Category != HintSecurity || exec.Hints[0].Severity != HintWarning {
+		t.Errorf("Hints = %+v, want the null origin", exec.Hints)
+	}
+}
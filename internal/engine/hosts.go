@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"net"
+	"strings"
+
+	"paperbox/internal/config/environments"
+)
+
+// hostOverrides returns the enabled host overrides of an environment keyed by lower-case host,
+// with variables substituted in the targets
+func hostOverrides(env *environments.Environment, sub *Substituter) map[string]string {
+	if env == nil {
+		return nil
+	}
+	var overrides map[string]string
+	for _, o := range env.HostOverrides {
+		if o.Disabled {
+			continue
+		}
+		if overrides == nil {
+			overrides = make(map[string]string)
+		}
+		overrides[strings.ToLower(o.Host)] = sub.Apply(o.Target)
+	}
+	return overrides
+}
+
+// overrideAddr maps a dial address through the overrides: an entry for "host:port" wins over one
+// for "host", and a target without a port keeps the requested port
+func overrideAddr(overrides map[string]string, addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	host = strings.ToLower(host)
+
+	target, ok := overrides[net.JoinHostPort(host, port)]
+	if !ok {
+		if target, ok = overrides[host]; !ok {
+			return addr
+		}
+	}
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		return target
+	}
+	return net.JoinHostPort(strings.Trim(target, "[]"), port)
+}
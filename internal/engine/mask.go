@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+
+	"paperbox/internal/config/requests"
+)
+
+// minSecretLength is the shortest secret value masked inside URLs, header values and bodies;
+// shorter values would mask unrelated text, so they are only hidden in secret headers
+const minSecretLength = 4
+
+// secretValues collects the values a resolved request must not reveal: those of secret variables,
+// the auth credentials and values read from the process environment. Longest first, so overlapping
+// secrets are masked whole.
+func secretValues(vars []EffectiveVariable, auth *requests.Auth, envValues []string) []string {
+	var secrets []string
+	add := func(value string) {
+		if len(value) >= minSecretLength {
+			secrets = append(secrets, value)
+		}
+	}
+	for _, v := range vars {
+		if v.Secret {
+			add(v.Value)
+		}
+	}
+	if auth != nil {
+		add(auth.Password)
+		add(auth.Token)
+		add(auth.Value)
+		for _, value := range auth.Params {
+			add(value)
+		}
+	}
+	for _, value := range envValues {
+		add(value)
+	}
+	sort.Slice(secrets, func(a, b int) bool { return len(secrets[a]) > len(secrets[b]) })
+	return secrets
+}
+
+// Masked returns a copy of the request for display, history and exports: secret headers (see
+// requests.IsSecretHeader) and the values of secret variables and credentials are masked
+func (r *ResolvedRequest) Masked() ResolvedRequest {
+	masked := *r
+	masked.URL = r.maskString(r.URL)
+	masked.Body = r.maskString(r.Body)
+	masked.Headers = make([]requests.Header, len(r.Headers))
+	for i, h := range r.Headers {
+		if requests.IsSecretHeader(h) {
+			h.Value = requests.SecretMask
+		} else {
+			h.Value = r.maskString(h.Value)
+		}
+		masked.Headers[i] = h
+	}
+	return masked
+}
+
+// maskString replaces the request's secret values in s
+func (r *ResolvedRequest) maskString(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, requests.SecretMask)
+	}
+	return s
+}
@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Orders of the built-in middleware. Middleware with a lower order wraps the ones after it, so it
+// sees the call first and the execution last; equal orders run by name.
+const (
+	// OrderObserve is for middleware watching whole executions, such as logging and metrics
+	OrderObserve = 0
+	// OrderResolve resolves variables into the request
+	OrderResolve = 100
+	// OrderAuth injects the credentials of the request's auth
+	OrderAuth = 200
+	// OrderChecks runs assertions and budgets on the execution
+	OrderChecks = 300
+	// OrderSend is for middleware that needs the final request, such as retries and plugin hooks
+	OrderSend = 400
+)
+
+// Call is a request execution passing through the middleware chain. Request is nil until the
+// resolve stage ran; later middleware may modify it before it is sent.
+type Call struct {
+	Sources   Sources
+	RequestID string
+	Request   *ResolvedRequest
+
+	// Set by the resolve stage for the auth stage
+	sub  *Substituter
+	vars []EffectiveVariable
+}
+
+// Handler executes a call
+type Handler func(ctx context.Context, call *Call) (*Execution, error)
+
+// Middleware wraps a handler to add behavior around the execution of every request
+type Middleware func(next Handler) Handler
+
+// middlewareEntry is a registered middleware and its position in the chain
+type middlewareEntry struct {
+	name       string
+	order      int
+	middleware Middleware
+}
+
+// MiddlewareRegistry is a concurrency-safe, ordered set of middleware keyed by name
+type MiddlewareRegistry struct {
+	mu      sync.RWMutex
+	entries map[string]middlewareEntry
+}
+
+// NewMiddlewareRegistry creates an empty registry
+func NewMiddlewareRegistry() *MiddlewareRegistry {
+	return &MiddlewareRegistry{entries: make(map[string]middlewareEntry)}
+}
+
+// Register adds or replaces a middleware at the given order
+func (r *MiddlewareRegistry) Register(name string, order int, middleware Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[name] = middlewareEntry{name: name, order: order, middleware: middleware}
+}
+
+// Unregister removes a middleware
+func (r *MiddlewareRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, name)
+}
+
+// Names returns the registered middleware in chain order
+func (r *MiddlewareRegistry) Names() []string {
+	entries := r.sorted()
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.name
+	}
+	return names
+}
+
+// Then wraps final, which sends the request, in the registered middleware
+func (r *MiddlewareRegistry) Then(final Handler) Handler {
+	entries := r.sorted()
+	handler := final
+	for i := len(entries) - 1; i >= 0; i-- {
+		handler = entries[i].middleware(handler)
+	}
+	return handler
+}
+
+// sorted returns the entries in chain order
+func (r *MiddlewareRegistry) sorted() []middlewareEntry {
+	r.mu.RLock()
+	entries := make([]middlewareEntry, 0, len(r.entries))
+	for _, entry := range r.entries {
+		entries = append(entries, entry)
+	}
+	r.mu.RUnlock()
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].order != entries[j].order {
+			return entries[i].order < entries[j].order
+		}
+		return entries[i].name < entries[j].name
+	})
+	return entries
+}
+
+// DefaultMiddleware holds the middleware every request runs through, starting with the built-in
+// stages; other packages and plugins may register more
+var DefaultMiddleware = NewMiddlewareRegistry()
+
+func init() {
+	DefaultMiddleware.Register("resolve", OrderResolve, resolveStage)
+	DefaultMiddleware.Register("auth", OrderAuth, authStage)
+	DefaultMiddleware.Register("checks", OrderChecks, checksStage)
+}
+
+// resolveStage resolves the request with the call's variables
+func resolveStage(next Handler) Handler {
+	return func(ctx context.Context, call *Call) (*Execution, error) {
+		if err := resolveVariables(call); err != nil {
+			return nil, err
+		}
+		return next(ctx, call)
+	}
+}
+
+// authStage injects the credentials of the request's auth
+func authStage(next Handler) Handler {
+	return func(ctx context.Context, call *Call) (*Execution, error) {
+		if call.Request == nil {
+			return nil, fmt.Errorf("auth requires a resolved request")
+		}
+		if err := injectAuth(call); err != nil {
+			return nil, err
+		}
+		return next(ctx, call)
+	}
+}
+
+// checksStage runs the request's assertions and checks its budget
+func checksStage(next Handler) Handler {
+	return func(ctx context.Context, call *Call) (*Execution, error) {
+		exec, err := next(ctx, call)
+		if err != nil || exec == nil {
+			return exec, err
+		}
+		if exec.Error == "" {
+			exec.Tests = runChecks(call.Sources.Requests.Values[call.RequestID], exec)
+		}
+		exec.BudgetWarnings = checkBudget(EffectiveBudget(call.Sources.Requests, call.RequestID), exec)
+		return exec, nil
+	}
+}
+
+// Retry sends a request up to attempts times while it fails in transport, waiting delay between
+// attempts. Register it at OrderSend so assertions run once on the final attempt.
+func Retry(attempts int, delay time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (*Execution, error) {
+			var exec *Execution
+			var err error
+			for attempt := 1; ; attempt++ {
+				exec, err = next(ctx, call)
+				if err != nil || exec == nil || exec.Error == "" || attempt >= attempts {
+					return exec, err
+				}
+				select {
+				case <-ctx.Done():
+					return exec, nil
+				case <-time.After(delay):
+				}
+			}
+		}
+	}
+}
+
+// Logging writes one line per execution to w, e.g. "GET https://api.example.com/users 200 (12ms)".
+// The request is logged with its secrets masked.
+func Logging(w io.Writer) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (*Execution, error) {
+			exec, err := next(ctx, call)
+			switch {
+			case err != nil:
+				fmt.Fprintf(w, "%s: %v\n", call.RequestID, err)
+			case exec.Error != "":
+				fmt.Fprintf(w, "%s %s failed: %s\n", exec.Request.Method, exec.Request.URL, exec.Error)
+			default:
+				fmt.Fprintf(w, "%s %s %d (%dms)\n", exec.Request.Method, exec.Request.URL, exec.Status, exec.DurationMs)
+			}
+			return exec, err
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"paperbox/internal/config/environments"
+	"paperbox/internal/tunnel"
+)
+
+// dohContentType is the media type of DNS messages sent over HTTPS (RFC 8484)
+const dohContentType = "application/dns-message"
+
+// networkSettings returns the network settings of an environment, or nil when it has none
+func networkSettings(env *environments.Environment) *environments.NetworkSettings {
+	if env == nil || env.Network == nil || *env.Network == (environments.NetworkSettings{}) {
+		return nil
+	}
+	settings := *env.Network
+	return &settings
+}
+
+// dialFunc returns how a transport opens connections: through the host overrides, restricted to
+// an IP version and resolving names with a custom DNS server, or from an SSH jump host for the
+// hosts it routes. It returns nil when none apply.
+func dialFunc(overrides map[string]string, network *environments.NetworkSettings, ssh *SSHTunnel, jumps *tunnel.Jumps) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	if len(overrides) == 0 && network == nil && ssh == nil {
+		return nil, nil
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	suffix := ""
+	if network != nil {
+		suffix = network.IPVersion
+		if network.DNSServer != "" {
+			resolver, err := dnsResolver(network.DNSServer)
+			if err != nil {
+				return nil, err
+			}
+			dialer.Resolver = resolver
+		}
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// The jump host resolves names, so the IP version and DNS server do not apply
+		if ssh != nil && ssh.routes(addr) {
+			return jumps.Dial(ctx, ssh.JumpHost(), overrideAddr(overrides, addr))
+		}
+		if network == "tcp" || network == "udp" {
+			network += suffix
+		}
+		return dialer.DialContext(ctx, network, overrideAddr(overrides, addr))
+	}, nil
+}
+
+// dnsResolver creates a resolver querying a DNS server (see environments.ParseDNSServer) instead
+// of the system's
+func dnsResolver(server string) (*net.Resolver, error) {
+	network, address, err := environments.ParseDNSServer(server)
+	if err != nil {
+		return nil, err
+	}
+	resolver := &net.Resolver{PreferGo: true}
+	switch network {
+	case "https":
+		client := &http.Client{Timeout: 10 * time.Second}
+		resolver.Dial = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return &dohConn{ctx: ctx, client: client, url: address}, nil
+		}
+	default:
+		// A UDP server is asked over TCP when the resolver retries a truncated answer
+		dialer := &net.Dialer{Timeout: 10 * time.Second}
+		resolver.Dial = func(ctx context.Context, asked, _ string) (net.Conn, error) {
+			if network == "udp" {
+				return dialer.DialContext(ctx, asked, address)
+			}
+			return dialer.DialContext(ctx, network, address)
+		}
+	}
+	return resolver, nil
+}
+
+// dohConn carries the DNS queries of the Go resolver over HTTPS. It is not a packet connection,
+// so the resolver frames messages as over TCP: a 2-byte length, then the message. Every query
+// written is POSTed to the server and its answer is read back with the same framing.
+type dohConn struct {
+	ctx    context.Context
+	client *http.Client
+	url    string
+
+	mu       sync.Mutex
+	deadline time.Time
+	query    bytes.Buffer
+	answer   bytes.Buffer
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.query.Write(b)
+	for c.query.Len() >= 2 {
+		size := int(binary.BigEndian.Uint16(c.query.Bytes()))
+		if c.query.Len() < 2+size {
+			break
+		}
+		message := make([]byte, size)
+		copy(message, c.query.Next(2 + size)[2:])
+		answer, err := c.exchange(message)
+		if err != nil {
+			return 0, err
+		}
+		c.answer.Write(binary.BigEndian.AppendUint16(nil, uint16(len(answer))))
+		c.answer.Write(answer)
+	}
+	return len(b), nil
+}
+
+// exchange POSTs one DNS message and returns the answer
+func (c *dohConn) exchange(message []byte) ([]byte, error) {
+	ctx := c.ctx
+	if !c.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, c.deadline)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(message))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", dohContentType)
+	req.Header.Set("Accept", dohContentType)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS-over-HTTPS server answered %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<16-1))
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.answer.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.answer.Read(b)
+}
+
+func (c *dohConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return nil
+}
+
+func (c *dohConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { return c.SetDeadline(t) }
+func (c *dohConn) Close() error                       { return nil }
+func (c *dohConn) LocalAddr() net.Addr                { return dohAddr("") }
+func (c *dohConn) RemoteAddr() net.Addr               { return dohAddr(c.url) }
+
+// dohAddr is the address of either end of a dohConn
+type dohAddr string
+
+func (a dohAddr) Network() string { return "https" }
+func (a dohAddr) String() string  { return string(a) }
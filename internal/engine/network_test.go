@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"paperbox/internal/config/environments"
+)
+
+// answerLocal answers every A question of a DNS query with 127.0.0.1, other questions with nothing
+func answerLocal(t *testing.T, query []byte) []byte {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(query); err != nil {
+		t.Errorf("invalid DNS query: %v", err)
+		return nil
+	}
+	msg.Header.Response, msg.Header.Authoritative = true, true
+	for _, q := range msg.Questions {
+		if q.Type == dnsmessage.TypeA {
+			msg.Answers = append(msg.Answers, dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 60},
+				Body:   &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}},
+			})
+		}
+	}
+	answer, err := msg.Pack()
+	if err != nil {
+		t.Errorf("failed to pack DNS answer: %v", err)
+	}
+	return answer
+}
+
+func TestSendResolvesWithDNSServer(t *testing.T) {
+	dns, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dns.Close()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := dns.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			dns.WriteTo(answerLocal(t, buf[:n]), addr)
+		}
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("resolved"))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	req := &ResolvedRequest{
+		Method:  "GET",
+		URL:     "http://api.example.invalid:" + port,
+		Network: &environments.NetworkSettings{DNSServer: "udp://" + dns.LocalAddr().String()},
+	}
+	exec := New().Send(context.Background(), req)
+	if exec.Error != "" || exec.Body != "resolved" {
+		t.Errorf("Send() error = %q body = %q", exec.Error, exec.Body)
+	}
+}
+
+func TestDoHConnResolves(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != dohContentType {
+			t.Errorf("DoH request = %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		query, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", dohContentType)
+		w.Write(answerLocal(t, query))
+	}))
+	defer server.Close()
+
+	resolver := &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return &dohConn{ctx: ctx, client: server.Client(), url: server.URL}, nil
+	}}
+	addrs, err := resolver.LookupHost(context.Background(), "api.example.invalid")
+	if err != nil || len(addrs) != 1 || addrs[0] != "127.0.0.1" {
+		t.Errorf("LookupHost() = %v, %v; want 127.0.0.1", addrs, err)
+	}
+}
+
+func TestSendForcesIPVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	eng := New()
+	req := &ResolvedRequest{Method: "GET", URL: server.URL, Network: &environments.NetworkSettings{IPVersion: "4"}}
+	if exec := eng.Send(context.Background(), req); exec.Error != "" {
+		t.Errorf("Send() over IPv4 error = %q", exec.Error)
+	}
+	req.Network = &environments.NetworkSettings{IPVersion: "6"}
+	if exec := eng.Send(context.Background(), req); exec.Error == "" {
+		t.Error("Send() over IPv6 to an IPv4 address should fail")
+	}
+}
+
+func TestParseDNSServer(t *testing.T) {
+	tests := []struct {
+		server, network, address string
+	}{
+		{"10.0.0.2", "udp", "10.0.0.2:53"},
+		{"10.0.0.2:5353", "udp", "10.0.0.2:5353"},
+		{"tcp://[2001:db8::1]", "tcp", "[2001:db8::1]:53"},
+		{"::1", "udp", "[::1]:53"},
+		{"https://dns.example.com/dns-query", "https", "https://dns.example.com/dns-query"},
+	}
+	for _, tt := range tests {
+		network, address, err := environments.ParseDNSServer(tt.server)
+		if err != nil || network != tt.network || address != tt.address {
+			t.Errorf("ParseDNSServer(%q) = %q, %q, %v; want %q, %q", tt.server, network, address, err, tt.network, tt.address)
+		}
+	}
+	for _, server := range []string{"dns.example.com", "tls://1.1.1.1", "https://"} {
+		if _, _, err := environments.ParseDNSServer(server); err == nil {
+			t.Errorf("ParseDNSServer(%q) should fail", server)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package engine
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// NTLM (MS-NLMP) with NTLMv2 responses. Only authentication is implemented: the handshake
+// proves the credentials to the server, no message signing or sealing is negotiated.
+
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+const (
+	ntlmNegotiateUnicode         = 0x00000001
+	ntlmRequestTarget            = 0x00000004
+	ntlmNegotiateNTLM            = 0x00000200
+	ntlmNegotiateAlwaysSign      = 0x00008000
+	ntlmNegotiateExtendedSession = 0x00080000
+	ntlmNegotiateTargetInfo      = 0x00800000
+	ntlmNegotiate128             = 0x20000000
+	ntlmNegotiate56              = 0x80000000
+
+	ntlmNegotiateFlags = ntlmNegotiateUnicode | ntlmRequestTarget | ntlmNegotiateNTLM | ntlmNegotiateAlwaysSign |
+		ntlmNegotiateExtendedSession | ntlmNegotiateTargetInfo | ntlmNegotiate128 | ntlmNegotiate56
+
+	// avTimestamp is the AV pair carrying the server time in the challenge's target info
+	avTimestamp = 7
+)
+
+// ntlmCredentials are the user, password and domain used in the handshake
+type ntlmCredentials struct {
+	User     string
+	Password string
+	Domain   string
+}
+
+// newNTLMCredentials accepts the domain separately or as "DOMAIN\user"
+func newNTLMCredentials(username, password, domain string) ntlmCredentials {
+	if domain == "" {
+		if d, user, ok := strings.Cut(username, `\`); ok {
+			domain, username = d, user
+		}
+	}
+	return ntlmCredentials{User: username, Password: password, Domain: domain}
+}
+
+// ntlmChallenge is the parsed CHALLENGE_MESSAGE sent by the server
+type ntlmChallenge struct {
+	Flags           uint32
+	ServerChallenge [8]byte
+	TargetInfo      []byte
+}
+
+// ntlmNegotiateMessage builds the NEGOTIATE_MESSAGE that starts the handshake
+func ntlmNegotiateMessage() []byte {
+	msg := make([]byte, 32)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 1)
+	binary.LittleEndian.PutUint32(msg[12:], ntlmNegotiateFlags)
+	// Empty domain and workstation fields point at the end of the message
+	binary.LittleEndian.PutUint32(msg[20:], 32)
+	binary.LittleEndian.PutUint32(msg[28:], 32)
+	return msg
+}
+
+// parseNTLMChallenge decodes a CHALLENGE_MESSAGE
+func parseNTLMChallenge(msg []byte) (*ntlmChallenge, error) {
+	if len(msg) < 32 || !bytes.Equal(msg[:8], ntlmSignature) || binary.LittleEndian.Uint32(msg[8:]) != 2 {
+		return nil, errors.New("invalid NTLM challenge")
+	}
+	challenge := &ntlmChallenge{Flags: binary.LittleEndian.Uint32(msg[20:])}
+	copy(challenge.ServerChallenge[:], msg[24:32])
+
+	if len(msg) >= 48 {
+		length := int(binary.LittleEndian.Uint16(msg[40:]))
+		offset := int(binary.LittleEndian.Uint32(msg[44:]))
+		if offset+length > len(msg) {
+			return nil, errors.New("invalid NTLM challenge: target info out of range")
+		}
+		challenge.TargetInfo = msg[offset : offset+length]
+	}
+	return challenge, nil
+}
+
+// ntlmAuthenticateMessage builds the AUTHENTICATE_MESSAGE answering a challenge
+func ntlmAuthenticateMessage(creds ntlmCredentials, challenge *ntlmChallenge) ([]byte, error) {
+	var clientChallenge [8]byte
+	if _, err := rand.Read(clientChallenge[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate client challenge: %w", err)
+	}
+
+	timestamp, serverTime := ntlmTimestamp(challenge.TargetInfo)
+	if !serverTime {
+		timestamp = fileTime(time.Now())
+	}
+	lmResponse, ntResponse := ntlmV2Responses(creds, challenge.ServerChallenge, clientChallenge, timestamp, challenge.TargetInfo)
+	// With a server timestamp the LMv2 response must be zeroed (MS-NLMP 3.1.5.1.2)
+	if serverTime {
+		lmResponse = make([]byte, 24)
+	}
+
+	fields := [][]byte{
+		lmResponse,
+		ntResponse,
+		encodeUTF16(creds.Domain),
+		encodeUTF16(creds.User),
+		nil, // Workstation
+		nil, // Encrypted random session key
+	}
+	const headerSize = 64
+	msg := make([]byte, headerSize)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 3)
+
+	offset := headerSize
+	for i, field := range fields {
+		pos := 12 + i*8
+		binary.LittleEndian.PutUint16(msg[pos:], uint16(len(field)))
+		binary.LittleEndian.PutUint16(msg[pos+2:], uint16(len(field)))
+		binary.LittleEndian.PutUint32(msg[pos+4:], uint32(offset))
+		offset += len(field)
+	}
+	binary.LittleEndian.PutUint32(msg[60:], challenge.Flags&ntlmNegotiateFlags)
+	for _, field := range fields {
+		msg = append(msg, field...)
+	}
+	return msg, nil
+}
+
+// ntowfV2 derives the NTLMv2 key from the credentials
+func ntowfV2(creds ntlmCredentials) []byte {
+	hash := md4.New()
+	hash.Write(encodeUTF16(creds.Password))
+	return hmacMD5(hash.Sum(nil), encodeUTF16(strings.ToUpper(creds.User)+creds.Domain))
+}
+
+// ntlmV2Responses computes the LMv2 and NTLMv2 challenge responses
+func ntlmV2Responses(creds ntlmCredentials, serverChallenge, clientChallenge [8]byte, timestamp []byte, targetInfo []byte) (lm, nt []byte) {
+	key := ntowfV2(creds)
+
+	var blob bytes.Buffer
+	blob.Write([]byte{1, 1, 0, 0, 0, 0, 0, 0})
+	blob.Write(timestamp)
+	blob.Write(clientChallenge[:])
+	blob.Write([]byte{0, 0, 0, 0})
+	blob.Write(targetInfo)
+	blob.Write([]byte{0, 0, 0, 0})
+
+	proof := hmacMD5(key, serverChallenge[:], blob.Bytes())
+	nt = append(proof, blob.Bytes()...)
+	lm = append(hmacMD5(key, serverChallenge[:], clientChallenge[:]), clientChallenge[:]...)
+	return lm, nt
+}
+
+// ntlmTimestamp returns the server's timestamp from the target info, if it sent one
+func ntlmTimestamp(targetInfo []byte) ([]byte, bool) {
+	for rest := targetInfo; len(rest) >= 4; {
+		id := binary.LittleEndian.Uint16(rest)
+		length := int(binary.LittleEndian.Uint16(rest[2:]))
+		if id == 0 || len(rest) < 4+length {
+			break
+		}
+		if id == avTimestamp && length == 8 {
+			return rest[4:12], true
+		}
+		rest = rest[4+length:]
+	}
+	return nil, false
+}
+
+// fileTime encodes t as a Windows FILETIME (100ns intervals since 1601)
+func fileTime(t time.Time) []byte {
+	const epochOffset = 116444736000000000
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(t.UnixNano()/100+epochOffset))
+	return buf
+}
+
+// hmacMD5 returns the HMAC-MD5 of the concatenated data
+func hmacMD5(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(md5.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+// encodeUTF16 encodes s as UTF-16LE
+func encodeUTF16(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	buf := make([]byte, 2*len(units))
+	for i, unit := range units {
+		binary.LittleEndian.PutUint16(buf[2*i:], unit)
+	}
+	return buf
+}
+
+// ntlmToken finds the base64 token of scheme in the WWW-Authenticate values of a response
+func ntlmToken(values []string, scheme string) ([]byte, bool) {
+	for _, value := range values {
+		name, token, _ := strings.Cut(strings.TrimSpace(value), " ")
+		if !strings.EqualFold(name, scheme) || token == "" {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(token))
+		if err == nil {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// sendWithHandshake authenticates with NTLM (scheme "NTLM" or "Negotiate") and returns the final
+// response. The handshake must stay on one connection, so it runs on a transport limited to a
+// single connection; release closes it once the response body has been read.
+func sendWithHandshake(client *http.Client, httpReq *http.Request, scheme string, creds ntlmCredentials) (resp *http.Response, release func(), err error) {
+	base, ok := client.Transport.(*http.Transport)
+	if !ok {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+	transport.MaxConnsPerHost = 1
+	single := *client
+	single.Transport = transport
+	release = transport.CloseIdleConnections
+
+	resp, err = single.Do(withAuthorization(httpReq, scheme, ntlmNegotiateMessage()))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, release, err
+	}
+	offered := resp.Header.Values("WWW-Authenticate")
+	// The connection is only reused once the body has been consumed
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	resp.Body.Close()
+
+	token, ok := ntlmToken(offered, scheme)
+	if !ok {
+		release()
+		return nil, nil, fmt.Errorf("server did not answer the %s handshake (offered: %s)", scheme, strings.Join(offered, ", "))
+	}
+	challenge, err := parseNTLMChallenge(token)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	msg, err := ntlmAuthenticateMessage(creds, challenge)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	resp, err = single.Do(withAuthorization(httpReq, scheme, msg))
+	return resp, release, err
+}
+
+// withAuthorization clones a request (including a fresh body) with an Authorization token
+func withAuthorization(httpReq *http.Request, scheme string, token []byte) *http.Request {
+	clone := httpReq.Clone(httpReq.Context())
+	if httpReq.GetBody != nil {
+		if body, err := httpReq.GetBody(); err == nil {
+			clone.Body = body
+		}
+	}
+	clone.Header.Set("Authorization", scheme+" "+base64.StdEncoding.EncodeToString(token))
+	return clone
+}
@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"paperbox/internal/config/requests"
+)
+
+// avPair encodes one AV pair of an NTLM target info
+func avPair(id uint16, value []byte) []byte {
+	pair := make([]byte, 4, 4+len(value))
+	binary.LittleEndian.PutUint16(pair, id)
+	binary.LittleEndian.PutUint16(pair[2:], uint16(len(value)))
+	return append(pair, value...)
+}
+
+// MS-NLMP 4.2.4 NTLMv2 authentication test vectors
+func TestNTLMV2Responses(t *testing.T) {
+	creds := newNTLMCredentials(`Domain\User`, "Password", "")
+	if creds.User != "User" || creds.Domain != "Domain" {
+		t.Fatalf("newNTLMCredentials() = %+v", creds)
+	}
+	if got := hex.EncodeToString(ntowfV2(creds)); got != "0c868a403bfd7a93a3001ef22ef02e3f" {
+		t.Errorf("ntowfV2() = %s", got)
+	}
+
+	serverChallenge := [8]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	clientChallenge := [8]byte{0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa}
+	targetInfo := bytes.Join([][]byte{
+		avPair(2, encodeUTF16("Domain")),
+		avPair(1, encodeUTF16("Server")),
+		avPair(0, nil),
+	}, nil)
+
+	lm, nt := ntlmV2Responses(creds, serverChallenge, clientChallenge, make([]byte, 8), targetInfo)
+	if got := hex.EncodeToString(lm[:16]); got != "86c35097ac9cec102554764a57cccc19" {
+		t.Errorf("LMv2 = %s", got)
+	}
+	if got := hex.EncodeToString(nt[:16]); got != "68cd0ab851e51c96aabc927bebef6a1c" {
+		t.Errorf("NTProofStr = %s", got)
+	}
+}
+
+func TestSendPerformsNTLMHandshake(t *testing.T) {
+	for _, authType := range []requests.AuthType{requests.AuthTypeNTLM, requests.AuthTypeNegotiate} {
+		t.Run(string(authType), func(t *testing.T) {
+			scheme := "NTLM"
+			if authType == requests.AuthTypeNegotiate {
+				scheme = "Negotiate"
+			}
+			var negotiatedOn string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				token, ok := ntlmToken(r.Header.Values("Authorization"), scheme)
+				switch {
+				case !ok:
+					w.Header().Set("WWW-Authenticate", scheme)
+					w.WriteHeader(http.StatusUnauthorized)
+				case binary.LittleEndian.Uint32(token[8:]) == 1:
+					negotiatedOn = r.RemoteAddr
+					w.Header().Set("WWW-Authenticate", scheme+" "+base64.StdEncoding.EncodeToString(testChallenge()))
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write([]byte("challenge"))
+				case r.RemoteAddr != negotiatedOn:
+					http.Error(w, "handshake moved to another connection", http.StatusBadRequest)
+				default:
+					body := new(bytes.Buffer)
+					body.ReadFrom(r.Body)
+					if !bytes.Contains(token, encodeUTF16("alice")) || !bytes.Contains(token, encodeUTF16("CORP")) {
+						http.Error(w, "unexpected user", http.StatusForbidden)
+						return
+					}
+					w.Write([]byte("welcome " + body.String()))
+				}
+			}))
+			defer server.Close()
+
+			req := &ResolvedRequest{Method: "POST", URL: server.URL, Body: "payload"}
+			if err := applyAuth(req, &requests.Auth{Type: authType, Username: `CORP\alice`, Password: "secret"}); err != nil {
+				t.Fatalf("applyAuth() error = %v", err)
+			}
+			exec := New().Send(context.Background(), req)
+			if exec.Error != "" || exec.Status != http.StatusOK {
+				t.Fatalf("Send() = %d %q, error %q", exec.Status, exec.Body, exec.Error)
+			}
+			if exec.Body != "welcome payload" {
+				t.Errorf("Send() body = %q", exec.Body)
+			}
+		})
+	}
+}
+
+// testChallenge builds a CHALLENGE_MESSAGE with a server timestamp in its target info
+func testChallenge() []byte {
+	targetInfo := bytes.Join([][]byte{
+		avPair(2, encodeUTF16("CORP")),
+		avPair(avTimestamp, make([]byte, 8)),
+		avPair(0, nil),
+	}, nil)
+	msg := make([]byte, 48)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 2)
+	binary.LittleEndian.PutUint32(msg[20:], ntlmNegotiateFlags)
+	copy(msg[24:], "\x01\x23\x45\x67\x89\xab\xcd\xef")
+	binary.LittleEndian.PutUint16(msg[40:], uint16(len(targetInfo)))
+	binary.LittleEndian.PutUint16(msg[42:], uint16(len(targetInfo)))
+	binary.LittleEndian.PutUint32(msg[44:], 48)
+	return append(msg, targetInfo...)
+}
@@ -0,0 +1,271 @@
+package engine
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/requests"
+)
+
+// pathVarPattern matches ":name" and "{name}" placeholders inside a path
+var pathVarPattern = regexp.MustCompile(`:([A-Za-z_][A-Za-z0-9_]*)|\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ResolvedRequest is a request with its final URL, headers and body, ready to go over the wire
+type ResolvedRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers []requests.Header `json:"headers"`
+	Body    string            `json:"body,omitempty"`
+	// Unresolved lists {{variables}} that had no value and were left in place
+	Unresolved []string `json:"unresolved,omitempty"`
+	// HostOverrides maps hosts to the addresses connections are made to instead (see environments.HostOverride)
+	HostOverrides map[string]string `json:"hostOverrides,omitempty"`
+	// TLS holds the environment's certificate settings, nil for the defaults
+	TLS *environments.TLSSettings `json:"tls,omitempty"`
+	// Network holds the environment's IP version and DNS server, nil for the defaults
+	Network *environments.NetworkSettings `json:"network,omitempty"`
+	// SSHTunnel is the environment's SSH jump host, nil when connections are made directly
+	SSHTunnel *SSHTunnel `json:"sshTunnel,omitempty"`
+
+	// handshake is the NTLM/Negotiate auth performed while sending, nil for other auth types
+	handshake *requests.Auth
+	// secrets are the values Masked hides
+	secrets []string
+}
+
+// URLParts is a full URL split into the fields of a request
+type URLParts struct {
+	// BaseURL overrides the request's base URL; empty when the URL starts with the one it inherits
+	BaseURL     string           `json:"baseURL,omitempty"`
+	Path        string           `json:"path"`
+	QueryParams []requests.Param `json:"queryParams,omitempty"`
+}
+
+// SplitURL splits a full URL (e.g. pasted from a browser) into the base URL, path and query
+// parameters of a request. A URL under the base URL the request inherits keeps inheriting it, the
+// base URL being compared as written and with its variables substituted; another absolute URL
+// overrides it with its origin. The fragment is dropped and query values are unescaped.
+func SplitURL(src Sources, requestID string, rawURL string) (*URLParts, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if i := strings.Index(rawURL, "#"); i >= 0 {
+		rawURL = rawURL[:i]
+	}
+	if rawURL == "" {
+		return nil, apperrors.Invalidf("URL is empty")
+	}
+	target, query, _ := strings.Cut(rawURL, "?")
+	parts := &URLParts{Path: target, QueryParams: parseQuery(query)}
+
+	if inherited := inheritedBaseURL(src, requestID); inherited != "" {
+		substituted := src.substituter(src.VariablesFor(requestID)).Apply(inherited)
+		for _, base := range []string{inherited, substituted} {
+			if rest, ok := cutBaseURL(target, base); ok {
+				parts.Path = rest
+				return parts, nil
+			}
+		}
+	}
+	if strings.Contains(target, "://") {
+		origin, rest := splitOrigin(target)
+		if _, err := url.Parse(origin); err != nil {
+			return nil, apperrors.Invalidf("invalid URL %q", rawURL)
+		}
+		parts.BaseURL, parts.Path = origin, rest
+	}
+	if parts.Path == "" {
+		parts.Path = "/"
+	}
+	return parts, nil
+}
+
+// cutBaseURL returns the path of target below base, "/" for base itself
+func cutBaseURL(target string, base string) (string, bool) {
+	base = strings.TrimRight(base, "/")
+	rest, ok := strings.CutPrefix(target, base)
+	if !ok || base == "" || rest != "" && !strings.HasPrefix(rest, "/") {
+		return "", false
+	}
+	if rest == "" {
+		rest = "/"
+	}
+	return rest, true
+}
+
+// parseQuery splits a query string into parameters in order, keeping parts that cannot be
+// unescaped (e.g. a literal "%") as written and dropping those without a name
+func parseQuery(query string) []requests.Param {
+	var params []requests.Param
+	for _, pair := range strings.Split(query, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if unescaped, err := url.QueryUnescape(value); err == nil {
+			value = unescaped
+		}
+		if key == "" {
+			continue
+		}
+		params = append(params, requests.Param{Key: key, Value: value})
+	}
+	return params
+}
+
+// Resolve builds the final request for an item: path variables are substituted, relative paths
+// are joined onto baseURL, enabled query parameters are appended and disabled headers dropped.
+// Form fields are encoded into the body, declared as a form unless a Content-Type is set.
+func Resolve(item requests.Item, baseURL string) (*ResolvedRequest, error) {
+	if item.Type != requests.ItemTypeRequest {
+		return nil, fmt.Errorf("item is not a request")
+	}
+
+	path, err := substitutePathVars(item.Path, item.PathVars)
+	if err != nil {
+		return nil, err
+	}
+
+	fullURL, err := joinURL(baseURL, path)
+	if err != nil {
+		return nil, err
+	}
+
+	fullURL, err = appendQuery(fullURL, item.QueryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := &ResolvedRequest{
+		Method:  strings.ToUpper(item.Method),
+		URL:     fullURL,
+		Headers: []requests.Header{},
+		Body:    item.Body,
+	}
+	hasContentType := false
+	for _, h := range item.Headers {
+		if !h.Disabled {
+			resolved.Headers = append(resolved.Headers, h)
+			hasContentType = hasContentType || strings.EqualFold(h.Key, "Content-Type")
+		}
+	}
+	if len(item.FormFields) > 0 {
+		resolved.Body = requests.EncodeForm(item.FormFields)
+		if !hasContentType {
+			resolved.Headers = append(resolved.Headers, requests.Header{Key: "Content-Type", Value: requests.FormContentType})
+		}
+	}
+
+	return resolved, nil
+}
+
+// substitutePathVars replaces ":name"/"{name}" placeholders with the matching enabled path variables.
+// Placeholders inside the scheme/host part ("http://host:8080") are left untouched.
+func substitutePathVars(path string, vars []requests.Param) (string, error) {
+	values := make(map[string]string, len(vars))
+	for _, v := range vars {
+		if !v.Disabled {
+			values[v.Key] = v.Value
+		}
+	}
+
+	prefix, rest := splitOrigin(path)
+
+	var missing []string
+	resolved := pathVarPattern.ReplaceAllStringFunc(rest, func(match string) string {
+		sub := pathVarPattern.FindStringSubmatch(match)
+		name := sub[1]
+		if name == "" {
+			name = sub[2]
+		}
+		value, ok := values[name]
+		if !ok {
+			missing = append(missing, name)
+			return match
+		}
+		return url.PathEscape(value)
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing value for path variable(s): %s", strings.Join(missing, ", "))
+	}
+
+	return prefix + resolved, nil
+}
+
+// splitOrigin splits "scheme://host[:port]" off an absolute URL so only the path part is templated
+func splitOrigin(rawURL string) (string, string) {
+	schemeEnd := strings.Index(rawURL, "://")
+	if schemeEnd < 0 {
+		return "", rawURL
+	}
+	hostStart := schemeEnd + len("://")
+	pathStart := strings.IndexAny(rawURL[hostStart:], "/?#")
+	if pathStart < 0 {
+		return rawURL, ""
+	}
+	return rawURL[:hostStart+pathStart], rawURL[hostStart+pathStart:]
+}
+
+// joinURL resolves path against baseURL unless path is already absolute
+func joinURL(baseURL string, path string) (string, error) {
+	if strings.Contains(path, "://") {
+		return path, nil
+	}
+
+	if baseURL == "" {
+		if path == "" {
+			return "", fmt.Errorf("request has no URL and no base URL is configured")
+		}
+		return path, nil
+	}
+
+	if path == "" {
+		return baseURL, nil
+	}
+
+	return strings.TrimRight(baseURL, "/") + "/" + strings.TrimLeft(path, "/"), nil
+}
+
+// appendQuery adds enabled query parameters to the URL, keeping any query already present
+func appendQuery(rawURL string, params []requests.Param) (string, error) {
+	var enabled []requests.Param
+	for _, p := range params {
+		if !p.Disabled {
+			enabled = append(enabled, p)
+		}
+	}
+	if len(enabled) == 0 {
+		return rawURL, nil
+	}
+
+	fragment := ""
+	if i := strings.Index(rawURL, "#"); i >= 0 {
+		rawURL, fragment = rawURL[:i], rawURL[i:]
+	}
+
+	var b strings.Builder
+	b.WriteString(rawURL)
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+		if strings.HasSuffix(rawURL, "?") || strings.HasSuffix(rawURL, "&") {
+			separator = ""
+		}
+	}
+	for _, p := range enabled {
+		b.WriteString(separator)
+		b.WriteString(url.QueryEscape(p.Key))
+		b.WriteString("=")
+		b.WriteString(url.QueryEscape(p.Value))
+		separator = "&"
+	}
+	b.WriteString(fragment)
+
+	return b.String(), nil
+}
@@ -0,0 +1,467 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/requests"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    requests.Item
+		baseURL string
+		wantURL string
+		wantErr string
+	}{
+		{
+			name: "relative path joined onto base URL",
+			item: requests.Item{
+				Type:   requests.ItemTypeRequest,
+				Method: "get",
+				Path:   "/users/:id/posts/{postId}",
+				PathVars: []requests.Param{
+					{Key: "id", Value: "42"},
+					{Key: "postId", Value: "a b"},
+				},
+				QueryParams: []requests.Param{
+					{Key: "page", Value: "1"},
+					{Key: "debug", Value: "true", Disabled: true},
+					{Key: "q", Value: "x&y"},
+				},
+			},
+			baseURL: "https://api.example.com/v1/",
+			wantURL: "https://api.example.com/v1/users/42/posts/a%20b?page=1&q=x%26y",
+		},
+		{
+			name: "absolute path keeps port and existing query",
+			item: requests.Item{
+				Type:        requests.ItemTypeRequest,
+				Method:      "GET",
+				Path:        "http://localhost:8080/items?sort=asc",
+				QueryParams: []requests.Param{{Key: "limit", Value: "5"}},
+			},
+			baseURL: "https://ignored.example.com",
+			wantURL: "http://localhost:8080/items?sort=asc&limit=5",
+		},
+		{
+			name: "missing path variable",
+			item: requests.Item{
+				Type:     requests.ItemTypeRequest,
+				Method:   "GET",
+				Path:     "/users/:id",
+				PathVars: []requests.Param{{Key: "id", Value: "1", Disabled: true}},
+			},
+			baseURL: "https://api.example.com",
+			wantErr: "missing value for path variable(s): id",
+		},
+		{
+			name:    "no URL at all",
+			item:    requests.Item{Type: requests.ItemTypeRequest, Method: "GET"},
+			wantErr: "no base URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(tt.item, tt.baseURL)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if got.URL != tt.wantURL {
+				t.Errorf("Resolve() URL = %q, want %q", got.URL, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestResolveEncodesFormFields(t *testing.T) {
+	item := requests.Item{
+		Type:   requests.ItemTypeRequest,
+		Method: "POST",
+		Path:   "https://api.example.com/login",
+		FormFields: []requests.FormField{
+			{Key: "user", Value: "ada lovelace"},
+			{Key: "debug", Value: "1", Disabled: true},
+			{Key: "next", Value: "/a&b"},
+		},
+	}
+	got, err := Resolve(item, "")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if got.Body != "user=ada+lovelace&next=%2Fa%26b" {
+		t.Errorf("Resolve() body = %q", got.Body)
+	}
+	if len(got.Headers) != 1 || got.Headers[0].Value != requests.FormContentType {
+		t.Errorf("Resolve() headers = %+v, want the form content type", got.Headers)
+	}
+
+	item.Headers = []requests.Header{{Key: "content-type", Value: "application/x-www-form-urlencoded; charset=utf-8"}}
+	got, _ = Resolve(item, "")
+	if len(got.Headers) != 1 || got.Headers[0].Value != item.Headers[0].Value {
+		t.Errorf("Resolve() headers = %+v, want the declared content type kept", got.Headers)
+	}
+}
+
+func TestSplitURL(t *testing.T) {
+	src := Sources{
+		Requests: &requests.RequestsConfig{
+			Version: requests.CurrentVersion,
+			Values: map[string]requests.Item{
+				"api": {Type: requests.ItemTypeFolder, Name: "API", BaseURL: "{{host}}/v1", Children: []string{"get"}},
+				"get": {Type: requests.ItemTypeRequest, Name: "Get", Method: "GET", Path: "/", BaseURL: "https://old.example.com"},
+			},
+		},
+		Environment: &environments.Environment{Variables: []environments.Variable{{Key: "host", Value: "https://api.example.com"}}},
+	}
+
+	tests := []struct {
+		name   string
+		rawURL string
+		want   URLParts
+	}{
+		{
+			name:   "under the inherited base URL",
+			rawURL: "https://api.example.com/v1/users?page=2&q=a%20b&flag#top",
+			want: URLParts{Path: "/users", QueryParams: []requests.Param{
+				{Key: "page", Value: "2"}, {Key: "q", Value: "a b"}, {Key: "flag"},
+			}},
+		},
+		{
+			name:   "inherited base URL as written",
+			rawURL: "{{host}}/v1",
+			want:   URLParts{Path: "/"},
+		},
+		{
+			name:   "other origin",
+			rawURL: "http://localhost:8080/v10/users?x={{id}}",
+			want: URLParts{BaseURL: "http://localhost:8080", Path: "/v10/users", QueryParams: []requests.Param{
+				{Key: "x", Value: "{{id}}"},
+			}},
+		},
+		{
+			name:   "relative path",
+			rawURL: "/health",
+			want:   URLParts{Path: "/health"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitURL(src, "get", tt.rawURL)
+			if err != nil {
+				t.Fatalf("SplitURL() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("SplitURL() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+
+	if _, err := SplitURL(src, "get", "  "); err == nil {
+		t.Error("expected an empty URL to be rejected")
+	}
+}
+
+func TestBaseURLFor(t *testing.T) {
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"root":   {Type: requests.ItemTypeFolder, Name: "Root", BaseURL: "https://root.example.com", Children: []string{"nested"}},
+			"nested": {Type: requests.ItemTypeFolder, Name: "Nested", Children: []string{"plain", "own"}},
+			"plain":  {Type: requests.ItemTypeRequest, Name: "Plain", Method: "GET"},
+			"own":    {Type: requests.ItemTypeRequest, Name: "Own", Method: "GET", BaseURL: "https://own.example.com"},
+			"other":  {Type: requests.ItemTypeFolder, Name: "Other", Children: []string{"orphan"}},
+			"orphan": {Type: requests.ItemTypeRequest, Name: "Orphan", Method: "GET"},
+		},
+	}
+	src := Sources{
+		Requests:    cfg,
+		Environment: &environments.Environment{Name: "dev", BaseURL: "https://env.example.com"},
+		UserBaseURL: "https://user.example.com",
+	}
+
+	if got := BaseURLFor(src, "own"); got != "https://own.example.com" {
+		t.Errorf("request override = %q", got)
+	}
+	if got := BaseURLFor(src, "plain"); got != "https://root.example.com" {
+		t.Errorf("folder override = %q", got)
+	}
+	if got := BaseURLFor(src, "orphan"); got != "https://env.example.com" {
+		t.Errorf("environment fallback = %q", got)
+	}
+	src.Environment = nil
+	if got := BaseURLFor(src, "orphan"); got != "https://user.example.com" {
+		t.Errorf("user config fallback = %q", got)
+	}
+}
+
+func TestResolveItemSubstitutesVariablesAndInjectsAuth(t *testing.T) {
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"api": {
+				Type:     requests.ItemTypeFolder,
+				Name:     "API",
+				Auth:     &requests.Auth{Type: requests.AuthTypeBearer, Token: "{{token}}"},
+				Children: []string{"get", "public"},
+			},
+			"get": {
+				Type:        requests.ItemTypeRequest,
+				Name:        "Get",
+				Method:      "GET",
+				Path:        "/users/:id",
+				PathVars:    []requests.Param{{Key: "id", Value: "{{userId}}"}},
+				QueryParams: []requests.Param{{Key: "trace", Value: "{{traceId}}"}},
+			},
+			"public": {
+				Type:   requests.ItemTypeRequest,
+				Name:   "Public",
+				Method: "GET",
+				Path:   "/health",
+				Auth:   &requests.Auth{Type: requests.AuthTypeNone},
+			},
+		},
+	}
+	src := Sources{
+		Requests: cfg,
+		Environment: &environments.Environment{
+			Name:    "dev",
+			BaseURL: "{{host}}/v1",
+			Variables: []environments.Variable{
+				{Key: "host", Value: "https://dev.example.com"},
+				{Key: "userId", Value: "7"},
+				{Key: "token", Value: "secret"},
+			},
+		},
+	}
+
+	got, err := ResolveItem(src, "get")
+	if err != nil {
+		t.Fatalf("ResolveItem() error = %v", err)
+	}
+	if got.URL != "https://dev.example.com/v1/users/7?trace=%7B%7BtraceId%7D%7D" {
+		t.Errorf("ResolveItem() URL = %q", got.URL)
+	}
+	if len(got.Headers) != 1 || got.Headers[0].Value != "Bearer secret" {
+		t.Errorf("ResolveItem() headers = %+v", got.Headers)
+	}
+	if len(got.Unresolved) != 1 || got.Unresolved[0] != "traceId" {
+		t.Errorf("ResolveItem() unresolved = %v", got.Unresolved)
+	}
+
+	public, err := ResolveItem(src, "public")
+	if err != nil {
+		t.Fatalf("ResolveItem() error = %v", err)
+	}
+	if len(public.Headers) != 0 {
+		t.Errorf("auth type none should not inherit, got headers %+v", public.Headers)
+	}
+}
+
+func TestResolveItemUsesAuthProvider(t *testing.T) {
+	authType := requests.AuthType(requests.PluginAuthPrefix + "test.sign")
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"get": {
+				Type:   requests.ItemTypeRequest,
+				Name:   "Get",
+				Method: "GET",
+				Path:   "https://example.com/users",
+				Auth:   &requests.Auth{Type: authType, Params: map[string]string{"key": "{{key}}"}},
+			},
+		},
+	}
+	src := Sources{
+		Requests:    cfg,
+		Environment: &environments.Environment{Variables: []environments.Variable{{Key: "key", Value: "k1"}}},
+	}
+
+	if _, err := ResolveItem(src, "get"); err == nil {
+		t.Fatal("ResolveItem() should fail without a registered provider")
+	}
+
+	DefaultAuthProviders.Register(authType, func(req *ResolvedRequest, auth *requests.Auth) error {
+		setDefaultHeader(req, "X-Signature", auth.Params["key"])
+		return nil
+	})
+	t.Cleanup(func() { DefaultAuthProviders.Unregister(authType) })
+
+	got, err := ResolveItem(src, "get")
+	if err != nil {
+		t.Fatalf("ResolveItem() error = %v", err)
+	}
+	if len(got.Headers) != 1 || got.Headers[0].Key != "X-Signature" || got.Headers[0].Value != "k1" {
+		t.Errorf("ResolveItem() headers = %+v", got.Headers)
+	}
+}
+
+func TestEffectiveVariablesPrecedence(t *testing.T) {
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"api": {
+				Type: requests.ItemTypeFolder, Name: "API", Children: []string{"users"},
+				Variables: []requests.Param{{Key: "version", Value: "v1"}, {Key: "region", Value: "eu"}},
+			},
+			"users": {
+				Type: requests.ItemTypeFolder, Name: "Users", Children: []string{"list"},
+				Variables: []requests.Param{{Key: "version", Value: "v2"}, {Key: "off", Value: "x", Disabled: true}},
+			},
+			"list": {Type: requests.ItemTypeRequest, Name: "List", Method: "GET", Path: "https://{{host}}/{{version}}/users?region={{region}}&page={{page}}"},
+		},
+	}
+	src := Sources{
+		Requests:     cfg,
+		Globals:      []environments.Variable{{Key: "host", Value: "global.example.com"}, {Key: "region", Value: "us"}},
+		Environment:  &environments.Environment{Name: "Prod", Variables: []environments.Variable{{Key: "host", Value: "prod.example.com"}}},
+		RunVariables: map[string]string{"page": "3"},
+	}
+
+	vars := src.EffectiveVariables("list")
+	want := map[string]VariableDefinition{
+		"host":    {Scope: ScopeEnvironment, Source: "Prod", Value: "prod.example.com"},
+		"page":    {Scope: ScopeRun, Value: "3"},
+		"region":  {Scope: ScopeFolder, Source: "API", Value: "eu"},
+		"version": {Scope: ScopeFolder, Source: "Users", Value: "v2"},
+	}
+	if len(vars) != len(want) {
+		t.Fatalf("EffectiveVariables() = %+v", vars)
+	}
+	for _, v := range vars {
+		if v.VariableDefinition != want[v.Key] {
+			t.Errorf("EffectiveVariables()[%s] = %+v, want %+v", v.Key, v.VariableDefinition, want[v.Key])
+		}
+	}
+	if shadowed := vars[3].Shadowed; len(shadowed) != 1 || shadowed[0].Source != "API" || shadowed[0].Value != "v1" {
+		t.Errorf("EffectiveVariables()[version].Shadowed = %+v", shadowed)
+	}
+
+	got, err := ResolveItem(src, "list")
+	if err != nil {
+		t.Fatalf("ResolveItem() error = %v", err)
+	}
+	if got.URL != "https://prod.example.com/v2/users?region=eu&page=3" {
+		t.Errorf("ResolveItem() URL = %s", got.URL)
+	}
+}
+
+func TestResolveItemReadsProcessEnv(t *testing.T) {
+	t.Setenv("PAPERBOX_TEST_TOKEN", "t-12345")
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"get": {
+				Type: requests.ItemTypeRequest, Name: "Get", Method: "GET", Path: "https://example.com/items",
+				Headers: []requests.Header{{Key: "X-Token", Value: "{{env:PAPERBOX_TEST_TOKEN}}"}},
+			},
+		},
+	}
+
+	got, err := ResolveItem(Sources{Requests: cfg}, "get")
+	if err != nil {
+		t.Fatalf("ResolveItem() error = %v", err)
+	}
+	if got.Headers[0].Value != "{{env:PAPERBOX_TEST_TOKEN}}" || len(got.Unresolved) != 1 {
+		t.Errorf("ResolveItem() without ProcessEnv headers = %+v unresolved = %v", got.Headers, got.Unresolved)
+	}
+
+	got, err = ResolveItem(Sources{Requests: cfg, ProcessEnv: true}, "get")
+	if err != nil {
+		t.Fatalf("ResolveItem() error = %v", err)
+	}
+	if got.Headers[0].Value != "t-12345" {
+		t.Errorf("ResolveItem() headers = %+v", got.Headers)
+	}
+	if masked := got.Masked(); masked.Headers[0].Value != requests.SecretMask {
+		t.Errorf("Masked() headers = %+v, want process environment values masked", masked.Headers)
+	}
+}
+
+func TestResolveItemAppliesExample(t *testing.T) {
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"create": {
+				Type: requests.ItemTypeRequest, Name: "Create", Method: "POST", Path: "https://example.com/users",
+				Body: `{"name":"default"}`,
+				Examples: &requests.Examples{Requests: []requests.RequestExample{{
+					Name:        "named",
+					Body:        `{"name":"{{user}}"}`,
+					QueryParams: []requests.Param{{Key: "notify", Value: "1"}},
+				}}},
+			},
+			"users": {Type: requests.ItemTypeFolder, Name: "Users", Children: []string{"create"}, Variables: []requests.Param{{Key: "user", Value: "ada"}}},
+		},
+	}
+
+	got, err := ResolveItem(Sources{Requests: cfg, Example: "named"}, "create")
+	if err != nil {
+		t.Fatalf("ResolveItem() error = %v", err)
+	}
+	if got.Body != `{"name":"ada"}` || got.URL != "https://example.com/users?notify=1" {
+		t.Errorf("ResolveItem() = %s %s", got.URL, got.Body)
+	}
+	if _, err := ResolveItem(Sources{Requests: cfg, Example: "missing"}, "create"); err == nil {
+		t.Error("ResolveItem() accepted an unknown example")
+	}
+}
+
+func TestVariableAnalysis(t *testing.T) {
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"api": {
+				Type: requests.ItemTypeFolder, Name: "API", Children: []string{"get", "login"},
+				Variables: []requests.Param{{Key: "version", Value: "v1"}, {Key: "unusedFolder", Value: "x"}},
+			},
+			"get": {
+				Type: requests.ItemTypeRequest, Name: "Get", Method: "GET", Path: "{{host}}/{{version}}/items/{{itemId}}",
+				Headers: []requests.Header{
+					{Key: "X-Request-Id", Value: "{{uuid}}"},
+					{Key: "X-Token", Value: "{{token}} {{env:HOME}}"},
+					{Key: "X-Pick", Value: "{{randomInt low 10}}"},
+				},
+			},
+			"login": {
+				Type: requests.ItemTypeRequest, Name: "Login", Method: "POST", Path: "{{host}}/login",
+				Captures: []requests.CaptureRule{{Variable: "token", Expression: "$.token", Kind: requests.ExtractKindJSONPath}},
+			},
+			"other": {Type: requests.ItemTypeFolder, Name: "Other", Children: []string{"ping"}},
+			"ping":  {Type: requests.ItemTypeRequest, Name: "Ping", Method: "GET", Path: "{{host}}/{{version}}/ping"},
+		},
+	}
+	envs := &environments.EnvironmentsConfig{
+		Version: 1,
+		Values: map[string]environments.Environment{
+			"dev":  {Name: "Dev", Variables: []environments.Variable{{Key: "host", Value: "http://localhost"}, {Key: "low", Value: "1"}}},
+			"prod": {Name: "Prod", Variables: []environments.Variable{{Key: "itemId", Value: "7"}, {Key: "stale", Value: "x"}}},
+		},
+		Globals: []environments.Variable{{Key: "host", Value: "https://api.example.com"}},
+	}
+
+	undefined := FindUndefinedVariables(cfg, envs)
+	want := []UndefinedVariable{
+		{Name: "itemId", Items: []string{"get"}, Environments: []string{"dev"}},
+		{Name: "version", Items: []string{"ping"}, Environments: []string{"dev", "prod"}},
+	}
+	if !reflect.DeepEqual(undefined, want) {
+		t.Errorf("FindUndefinedVariables() = %+v, want %+v", undefined, want)
+	}
+
+	unused := FindUnusedVariables(cfg, envs)
+	wantUnused := []UnusedVariable{{Name: "stale", Environment: "prod"}, {Name: "unusedFolder", FolderID: "api"}}
+	if !reflect.DeepEqual(unused, wantUnused) {
+		t.Errorf("FindUnusedVariables() = %+v, want %+v", unused, wantUnused)
+	}
+}
@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRetryBackoff is the first delay before retrying a response without Retry-After; it doubles
+// with every retry
+const DefaultRetryBackoff = time.Second
+
+// RetryPolicy retries requests answered with 429 Too Many Requests or 503 Service Unavailable,
+// waiting as long as their Retry-After header says, or backing off exponentially without one.
+// Delays are capped at MaxDelay (no cap when zero); MaxRetries zero disables retrying.
+type RetryPolicy struct {
+	MaxRetries int
+	MaxDelay   time.Duration
+}
+
+// RetryAttempt is an attempt of a request that was answered with a status worth retrying
+type RetryAttempt struct {
+	Status     int       `json:"status"`
+	RetryAfter string    `json:"retryAfter,omitempty"` // The Retry-After header, as received
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+	DelayMs    int64     `json:"delayMs"` // How long the engine waited before the next attempt
+}
+
+// SetRetryPolicy changes how rate-limited requests are retried; the zero policy disables retries
+func (e *Engine) SetRetryPolicy(policy RetryPolicy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.retry = policy
+}
+
+// retryPolicy returns the retry policy of the engine
+func (e *Engine) retryPolicy() RetryPolicy {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.retry
+}
+
+// retryDelay returns how long to wait before retrying a response, the retry being the given one
+// (counting from 1), and whether to retry at all
+func (p RetryPolicy) retryDelay(resp *http.Response, retry int, now time.Time) (time.Duration, bool) {
+	if retry > p.MaxRetries {
+		return 0, false
+	}
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	if !ok {
+		delay = DefaultRetryBackoff << (retry - 1)
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay, true
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// wait sleeps for delay unless ctx ends first
+func wait(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
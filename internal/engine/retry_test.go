@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSendRetriesRateLimitedRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			// No Retry-After: backs off, capped by the policy
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	e := NewWithClient(server.Client())
+	e.SetRetryPolicy(RetryPolicy{MaxRetries: 3, MaxDelay: 10 * time.Millisecond})
+	exec := e.Send(context.Background(), &ResolvedRequest{Method: "POST", URL: server.URL, Body: "payload"})
+
+	if exec.Status != http.StatusOK || exec.Body != "ok" {
+		t.Fatalf("Send() = %d %q, error %q; want the retried response", exec.Status, exec.Body, exec.Error)
+	}
+	if len(exec.Attempts) != 2 {
+		t.Fatalf("Attempts = %+v, want 2", exec.Attempts)
+	}
+	if exec.Attempts[0].Status != http.StatusTooManyRequests || exec.Attempts[0].RetryAfter != "0" || exec.Attempts[0].DelayMs != 0 {
+		t.Errorf("first attempt = %+v", exec.Attempts[0])
+	}
+	if exec.Attempts[1].Status != http.StatusServiceUnavailable || exec.Attempts[1].DelayMs != 10 {
+		t.Errorf("second attempt = %+v, want the delay capped at 10ms", exec.Attempts[1])
+	}
+}
+
+func TestSendStopsRetryingAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	e := NewWithClient(server.Client())
+	exec := e.Send(context.Background(), &ResolvedRequest{Method: "GET", URL: server.URL})
+	if calls.Load() != 1 || len(exec.Attempts) != 0 {
+		t.Errorf("without a retry policy got %d calls, attempts %+v", calls.Load(), exec.Attempts)
+	}
+
+	e.SetRetryPolicy(RetryPolicy{MaxRetries: 2})
+	calls.Store(0)
+	exec = e.Send(context.Background(), &ResolvedRequest{Method: "GET", URL: server.URL})
+	if calls.Load() != 3 || len(exec.Attempts) != 2 || exec.Status != http.StatusTooManyRequests {
+		t.Errorf("got %d calls, attempts %+v, status %d; want 3 calls ending with the last 429", calls.Load(), exec.Attempts, exec.Status)
+	}
+}
+
+func TestSendKeepsResponseWhenRetryExceedsTimeout(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("maintenance"))
+	}))
+	defer server.Close()
+
+	e := NewWithClient(server.Client())
+	e.SetRetryPolicy(RetryPolicy{MaxRetries: 3})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	started := time.Now()
+	exec := e.Send(ctx, &ResolvedRequest{Method: "GET", URL: server.URL})
+
+	if exec.Error != "" || exec.Status != http.StatusServiceUnavailable || exec.Body != "maintenance" {
+		t.Errorf("Send() = %d %q, error %q; want the 503 response", exec.Status, exec.Body, exec.Error)
+	}
+	if calls.Load() != 1 || len(exec.Attempts) != 0 {
+		t.Errorf("got %d calls, attempts %+v; want no retry after the timeout", calls.Load(), exec.Attempts)
+	}
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Errorf("Send() took %v, want it not to wait for the timeout", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{"Wed, 01 May 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Wed, 01 May 2024 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/requests"
+
+	"github.com/google/uuid"
+)
+
+// RunResult is the outcome of running every request in a folder
+type RunResult struct {
+	ID         string            `json:"id"`
+	FolderID   string            `json:"folderId"`
+	SessionID  string            `json:"sessionId,omitempty"` // Set for replays of a recorded session
+	StartedAt  time.Time         `json:"startedAt"`
+	DurationMs int64             `json:"durationMs"`
+	Executions []*Execution      `json:"executions"`
+	Variables  map[string]string `json:"variables,omitempty"`
+	Passed     int               `json:"passed"`
+	Failed     int               `json:"failed"`
+	OverBudget int               `json:"overBudget"` // Executions with budget warnings, also counted as passed or failed
+	Cancelled  bool              `json:"cancelled,omitempty"`
+	// Stability compares the attempts of each request when the run repeats them
+	Stability []Stability `json:"stability,omitempty"`
+	Flaky     int         `json:"flaky"` // Requests whose attempts disagree
+}
+
+// RunOptions tunes a collection run
+type RunOptions struct {
+	// Repeat sends each request this many times in a row to detect flaky endpoints (1 or less sends it once)
+	Repeat int `json:"repeat"`
+	// Progress, if not nil, is told after each request sent how many of the total were sent
+	Progress func(done, total int, current string) `json:"-"`
+}
+
+// RunCollection sends every request under a folder in tree order. Values captured by a request are
+// stored in a run-scoped variable context that later requests in the same run resolve against;
+// the environment itself is left untouched.
+func (e *Engine) RunCollection(ctx context.Context, src Sources, folderID string) (*RunResult, error) {
+	return e.RunCollectionWithOptions(ctx, src, folderID, RunOptions{})
+}
+
+// RunCollectionWithOptions runs a collection like RunCollection. With opts.Repeat above one every
+// attempt is recorded, and the attempts of each request are compared in RunResult.Stability;
+// executions of flaky requests are marked Flaky.
+func (e *Engine) RunCollectionWithOptions(ctx context.Context, src Sources, folderID string, opts RunOptions) (*RunResult, error) {
+	folder, exists := src.Requests.Values[folderID]
+	if !exists || folder.Type != requests.ItemTypeFolder {
+		return nil, apperrors.NotFoundf("folder not found")
+	}
+	if opts.Repeat > MaxRunRepeat {
+		return nil, apperrors.Invalidf("a run can repeat each request at most %d times", MaxRunRepeat)
+	}
+	repeat := max(opts.Repeat, 1)
+
+	result := &RunResult{
+		ID:        uuid.New().String(),
+		FolderID:  folderID,
+		StartedAt: time.Now(),
+		Variables: make(map[string]string),
+	}
+
+	ids := requests.RequestsUnder(src.Requests, folderID)
+	for _, requestID := range ids {
+		if ctx.Err() != nil {
+			result.Cancelled = true
+			break
+		}
+
+		var attempts []*Execution
+		for attempt := 0; attempt < repeat && ctx.Err() == nil; attempt++ {
+			attempts = append(attempts, e.runStep(ctx, src, result, RunStep{RequestID: requestID}))
+			if opts.Progress != nil {
+				opts.Progress(len(result.Executions), len(ids)*repeat, src.Requests.Values[requestID].Name)
+			}
+		}
+
+		if repeat > 1 && len(attempts) > 1 {
+			stability := stabilityOf(requestID, attempts)
+			if stability.Flaky {
+				result.Flaky++
+				for _, exec := range attempts {
+					exec.Flaky = true
+				}
+			}
+			result.Stability = append(result.Stability, stability)
+		}
+	}
+
+	result.DurationMs = time.Since(result.StartedAt).Milliseconds()
+	return result, nil
+}
+
+// RunStep is a request sent by RunSteps, with the request example to apply (see Sources.Example)
+type RunStep struct {
+	RequestID string `json:"requestId"`
+	Example   string `json:"example,omitempty"`
+}
+
+// RunSteps sends requests in the given order, like a collection run: values captured by a step are
+// visible to the later ones. A step whose request no longer exists fails without stopping the run.
+func (e *Engine) RunSteps(ctx context.Context, src Sources, steps []RunStep) *RunResult {
+	result := &RunResult{
+		ID:        uuid.New().String(),
+		StartedAt: time.Now(),
+		Variables: make(map[string]string),
+	}
+	for _, step := range steps {
+		if ctx.Err() != nil {
+			result.Cancelled = true
+			break
+		}
+		e.runStep(ctx, src, result, step)
+	}
+	result.DurationMs = time.Since(result.StartedAt).Milliseconds()
+	return result
+}
+
+// runStep sends one request of a run against the values captured so far and records its outcome
+func (e *Engine) runStep(ctx context.Context, src Sources, result *RunResult, step RunStep) *Execution {
+	runSrc := src
+	runSrc.Example = step.Example
+	runSrc.RunVariables = mergeVariables(src.RunVariables, result.Variables)
+
+	exec, err := e.Run(ctx, runSrc, step.RequestID)
+	if err != nil {
+		exec = &Execution{ID: uuid.New().String(), RequestID: step.RequestID, StartedAt: time.Now(), Error: err.Error()}
+	}
+
+	for key, value := range exec.Captured {
+		result.Variables[key] = value
+	}
+	if executionPassed(exec) {
+		result.Passed++
+	} else {
+		result.Failed++
+	}
+	if len(exec.BudgetWarnings) > 0 {
+		result.OverBudget++
+	}
+	result.Executions = append(result.Executions, exec)
+	return exec
+}
+
+// mergeVariables returns base overlaid with overrides
+func mergeVariables(base, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(overrides))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
+// executionPassed reports whether the request was sent and all of its test results passed
+func executionPassed(exec *Execution) bool {
+	if exec.Error != "" {
+		return false
+	}
+	for _, result := range exec.Tests {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,224 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"paperbox/internal/config/requests"
+)
+
+func TestRunCollectionChainsCaptures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Write([]byte(`{"access_token": "t-42"}`))
+		case "/me":
+			if r.Header.Get("Authorization") != "Bearer t-42" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"id": 1}`))
+		}
+	}))
+	defer server.Close()
+
+	login, err := ParseCaptureRule("token = body.access_token")
+	if err != nil {
+		t.Fatalf("ParseCaptureRule() error = %v", err)
+	}
+
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"root": {Type: requests.ItemTypeFolder, Name: "Root", Children: []string{"login", "nested"}},
+			"login": {
+				Type: requests.ItemTypeRequest, Name: "Login", Method: "POST", Path: "/login",
+				Captures: []requests.CaptureRule{login},
+			},
+			"nested": {Type: requests.ItemTypeFolder, Name: "Nested", Children: []string{"me"}},
+			"me": {
+				Type: requests.ItemTypeRequest, Name: "Me", Method: "GET", Path: "/me",
+				Auth: &requests.Auth{Type: requests.AuthTypeBearer, Token: "{{token}}"},
+			},
+		},
+	}
+	src := Sources{Requests: cfg, UserBaseURL: server.URL}
+
+	result, err := NewWithClient(server.Client()).RunCollection(context.Background(), src, "root")
+	if err != nil {
+		t.Fatalf("RunCollection() error = %v", err)
+	}
+	if len(result.Executions) != 2 {
+		t.Fatalf("RunCollection() ran %d requests, want 2", len(result.Executions))
+	}
+	if status := result.Executions[1].Status; status != http.StatusOK {
+		t.Errorf("second request status = %d, want captured token to be sent", status)
+	}
+	if result.Variables["token"] != "t-42" || result.Passed != 2 || result.Failed != 0 {
+		t.Errorf("RunCollection() variables = %v passed = %d failed = %d", result.Variables, result.Passed, result.Failed)
+	}
+}
+
+func TestRunCollectionFlagsBudgetWarnings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 2000))
+	}))
+	defer server.Close()
+
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"root": {
+				Type: requests.ItemTypeFolder, Name: "Root", Children: []string{"small", "nested"},
+				Budget: &requests.Budget{MaxResponseBytes: 1000, MaxDurationMs: 60000},
+			},
+			"small": {
+				Type: requests.ItemTypeRequest, Name: "Small", Method: "GET", Path: "/",
+				Budget: &requests.Budget{MaxResponseBytes: 5000},
+			},
+			"nested": {Type: requests.ItemTypeFolder, Name: "Nested", Children: []string{"large"}},
+			"large":  {Type: requests.ItemTypeRequest, Name: "Large", Method: "POST", Path: "/", Body: "payload"},
+		},
+	}
+	if budget := EffectiveBudget(cfg, "small"); budget == nil || budget.MaxResponseBytes != 5000 || budget.MaxDurationMs != 60000 {
+		t.Errorf("EffectiveBudget() = %+v, want own size limit and inherited duration", budget)
+	}
+
+	src := Sources{Requests: cfg, UserBaseURL: server.URL}
+	result, err := NewWithClient(server.Client()).RunCollection(context.Background(), src, "root")
+	if err != nil {
+		t.Fatalf("RunCollection() error = %v", err)
+	}
+	if result.OverBudget != 1 || result.Passed != 2 {
+		t.Errorf("RunCollection() overBudget = %d passed = %d, want 1 and 2", result.OverBudget, result.Passed)
+	}
+	if warnings := result.Executions[0].BudgetWarnings; len(warnings) != 0 {
+		t.Errorf("first request warnings = %v", warnings)
+	}
+	warnings := result.Executions[1].BudgetWarnings
+	if len(warnings) != 1 || warnings[0].Kind != BudgetResponseSize || warnings[0].Message != "response is 2 kB, over the 1 kB budget" {
+		t.Errorf("second request warnings = %+v", warnings)
+	}
+}
+
+func TestRunCollectionDetectsFlakyEndpoints(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/flaky" && calls.Add(1)%2 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"root":   {Type: requests.ItemTypeFolder, Name: "Root", Children: []string{"stable", "flaky"}},
+			"stable": {Type: requests.ItemTypeRequest, Name: "Stable", Method: "GET", Path: "/stable"},
+			"flaky":  {Type: requests.ItemTypeRequest, Name: "Flaky", Method: "GET", Path: "/flaky"},
+		},
+	}
+	src := Sources{Requests: cfg, UserBaseURL: server.URL}
+	eng := NewWithClient(server.Client())
+
+	var progress []string
+	opts := RunOptions{Repeat: 4, Progress: func(done, total int, current string) {
+		progress = append(progress, fmt.Sprintf("%d/%d %s", done, total, current))
+	}}
+	result, err := eng.RunCollectionWithOptions(context.Background(), src, "root", opts)
+	if err != nil {
+		t.Fatalf("RunCollectionWithOptions() error = %v", err)
+	}
+	if len(result.Executions) != 8 || len(result.Stability) != 2 || result.Flaky != 1 {
+		t.Fatalf("RunCollectionWithOptions() executions = %d stability = %+v flaky = %d", len(result.Executions), result.Stability, result.Flaky)
+	}
+	if len(progress) != 8 || progress[0] != "1/8 Stable" || progress[7] != "8/8 Flaky" {
+		t.Errorf("progress = %v, want one report per request sent", progress)
+	}
+	if stable := result.Stability[0]; stable.Flaky || stable.Attempts != 4 || stable.Statuses["200"] != 4 || result.Executions[0].Flaky {
+		t.Errorf("stable request stability = %+v", stable)
+	}
+	flaky := result.Stability[1]
+	if !flaky.Flaky || flaky.Statuses["200"] != 2 || flaky.Statuses["503"] != 2 || len(flaky.Reasons) != 1 || !result.Executions[7].Flaky {
+		t.Errorf("flaky request stability = %+v", flaky)
+	}
+
+	if _, err := eng.RunCollectionWithOptions(context.Background(), src, "root", RunOptions{Repeat: MaxRunRepeat + 1}); err == nil {
+		t.Error("RunCollectionWithOptions() accepted a repeat count over the limit")
+	}
+}
+
+func TestParseCaptureRule(t *testing.T) {
+	tests := []struct {
+		declaration string
+		want        requests.CaptureRule
+		wantErr     bool
+	}{
+		{"token = body.access_token", requests.CaptureRule{Variable: "token", Expression: "$.access_token", Kind: requests.ExtractKindJSONPath}, false},
+		{"first=body[0].id", requests.CaptureRule{Variable: "first", Expression: "$[0].id", Kind: requests.ExtractKindJSONPath}, false},
+		{"raw = body", requests.CaptureRule{Variable: "raw", Expression: "$", Kind: requests.ExtractKindJSONPath}, false},
+		{"rid = header.X-Request-Id", requests.CaptureRule{Variable: "rid", Expression: "X-Request-Id", Kind: requests.ExtractKindHeader}, false},
+		{"name = //user/name", requests.CaptureRule{Variable: "name", Expression: "//user/name", Kind: requests.ExtractKindXPath}, false},
+		{"total = xpath: sum(//item/price)", requests.CaptureRule{Variable: "total", Expression: "sum(//item/price)", Kind: requests.ExtractKindXPath}, false},
+		{"token body.access_token", requests.CaptureRule{}, true},
+		{"1token = body.x", requests.CaptureRule{}, true},
+		{"token = status", requests.CaptureRule{}, true},
+		{"token = header.", requests.CaptureRule{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.declaration, func(t *testing.T) {
+			got, err := ParseCaptureRule(tt.declaration)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCaptureRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseCaptureRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunStepsReplaysInOrder(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/login" {
+			w.Write([]byte(`{"access_token": "t-7"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer t-7" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	login, _ := ParseCaptureRule("token = body.access_token")
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"login": {Type: requests.ItemTypeRequest, Name: "Login", Method: "POST", Path: "/login", Captures: []requests.CaptureRule{login}},
+			"me": {
+				Type: requests.ItemTypeRequest, Name: "Me", Method: "GET", Path: "/me",
+				Auth: &requests.Auth{Type: requests.AuthTypeBearer, Token: "{{token}}"},
+			},
+		},
+	}
+	src := Sources{Requests: cfg, UserBaseURL: server.URL}
+
+	steps := []RunStep{{RequestID: "me"}, {RequestID: "login"}, {RequestID: "deleted"}, {RequestID: "me"}}
+	result := NewWithClient(server.Client()).RunSteps(context.Background(), src, steps)
+	if len(result.Executions) != 4 || len(paths) != 3 || paths[0] != "/me" || paths[1] != "/login" {
+		t.Fatalf("RunSteps() sent %v, %d executions", paths, len(result.Executions))
+	}
+	if result.Executions[0].Status != http.StatusUnauthorized || result.Executions[3].Status != http.StatusOK {
+		t.Errorf("statuses = %d, %d; want the token captured in between", result.Executions[0].Status, result.Executions[3].Status)
+	}
+	if result.Executions[2].Error == "" || result.Failed != 1 {
+		t.Errorf("missing request: error %q, failed = %d", result.Executions[2].Error, result.Failed)
+	}
+}
@@ -0,0 +1,191 @@
+package engine
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"paperbox/internal/config/requests"
+
+	"github.com/ohler55/ojg/jp"
+	"github.com/ohler55/ojg/oj"
+)
+
+// maxSnapshotDiffs caps how many body differences are reported per response
+const maxSnapshotDiffs = 20
+
+// NewSnapshot records an execution's response as a snapshot, keeping the comparison settings
+// of the snapshot it replaces (nil for none)
+func NewSnapshot(exec *Execution, previous *requests.Snapshot) *requests.Snapshot {
+	snapshot := &requests.Snapshot{
+		Status:     exec.Status,
+		Body:       exec.Body,
+		RecordedAt: time.Now(),
+	}
+	if previous != nil {
+		snapshot.IgnorePaths = previous.IgnorePaths
+		snapshot.CompareHeaders = previous.CompareHeaders
+	}
+	// All headers are kept so the compared ones can be changed without recording again
+	for name, values := range exec.Headers {
+		if snapshot.Headers == nil {
+			snapshot.Headers = make(map[string]string, len(exec.Headers))
+		}
+		snapshot.Headers[http.CanonicalHeaderKey(name)] = strings.Join(values, ", ")
+	}
+	return snapshot
+}
+
+// CompareSnapshot compares a response with a recorded snapshot: the status, the selected headers
+// and the body. JSON bodies are compared structurally without the ignored paths and every
+// difference becomes a failed result named after its location; other bodies must match exactly.
+func CompareSnapshot(snapshot *requests.Snapshot, exec *Execution) []TestResult {
+	results := []TestResult{{Name: "Snapshot status", Passed: exec.Status == snapshot.Status}}
+	if exec.Status != snapshot.Status {
+		results[0].Message = fmt.Sprintf("status %d, snapshot has %d", exec.Status, snapshot.Status)
+	}
+
+	for _, name := range snapshot.CompareHeaders {
+		name = http.CanonicalHeaderKey(name)
+		want := snapshot.Headers[name]
+		got := strings.Join(http.Header(exec.Headers).Values(name), ", ")
+		result := TestResult{Name: fmt.Sprintf("Snapshot header %s", name), Passed: got == want}
+		if !result.Passed {
+			result.Message = fmt.Sprintf("%q, snapshot has %q", got, want)
+		}
+		results = append(results, result)
+	}
+
+	return append(results, compareSnapshotBody(snapshot, exec.Body)...)
+}
+
+// compareSnapshotBody compares a response body with the snapshot's
+func compareSnapshotBody(snapshot *requests.Snapshot, body string) []TestResult {
+	const name = "Snapshot body"
+
+	want, wantErr := oj.ParseString(snapshot.Body)
+	got, gotErr := oj.ParseString(body)
+	if wantErr != nil || gotErr != nil {
+		if body == snapshot.Body {
+			return []TestResult{{Name: name, Passed: true}}
+		}
+		return []TestResult{{Name: name, Message: firstDifference(snapshot.Body, body, "snapshot")}}
+	}
+
+	var results []TestResult
+	for _, expression := range snapshot.IgnorePaths {
+		// Paths matching nothing are fine, the field may be optional
+		path, err := jp.ParseString(expression)
+		if err == nil {
+			if want, err = path.Remove(want); err == nil {
+				got, err = path.Remove(got)
+			}
+		}
+		if err != nil {
+			results = append(results, TestResult{Name: "Snapshot ignore path " + expression, Message: fmt.Sprintf("invalid JSONPath: %v", err)})
+		}
+	}
+
+	diffs := 0
+	diffJSON("$", want, got, "snapshot", func(location, message string) bool {
+		results = append(results, TestResult{Name: fmt.Sprintf("%s at %s", name, location), Message: message})
+		diffs++
+		return diffs < maxSnapshotDiffs
+	})
+	if diffs == 0 {
+		results = append(results, TestResult{Name: name, Passed: true})
+	}
+	return results
+}
+
+// diffJSON reports the differences between two decoded JSON values through report, which returns
+// false to stop; messages name the side of want after reference. It returns false once stopped.
+func diffJSON(location string, want, got any, reference string, report func(location, message string) bool) bool {
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			return report(location, fmt.Sprintf("got %s, %s has an object", describeJSON(got), reference))
+		}
+		keys := make([]string, 0, len(w)+len(g))
+		for key := range w {
+			keys = append(keys, key)
+		}
+		for key := range g {
+			if _, exists := w[key]; !exists {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			wantValue, inWant := w[key]
+			gotValue, inGot := g[key]
+			child := location + "." + key
+			var more bool
+			switch {
+			case !inGot:
+				more = report(child, "missing, "+reference+" has "+describeJSON(wantValue))
+			case !inWant:
+				more = report(child, "not in the "+reference+", got "+describeJSON(gotValue))
+			default:
+				more = diffJSON(child, wantValue, gotValue, reference, report)
+			}
+			if !more {
+				return false
+			}
+		}
+		return true
+
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			return report(location, fmt.Sprintf("got %s, %s has an array", describeJSON(got), reference))
+		}
+		if len(g) != len(w) && !report(location, fmt.Sprintf("%d items, %s has %d", len(g), reference, len(w))) {
+			return false
+		}
+		for i := 0; i < len(w) && i < len(g); i++ {
+			if !diffJSON(fmt.Sprintf("%s[%d]", location, i), w[i], g[i], reference, report) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		return report(location, fmt.Sprintf("got %s, %s has %s", describeJSON(got), reference, describeJSON(want)))
+	}
+	return true
+}
+
+// describeJSON renders a value for a difference message, summarizing objects and arrays
+func describeJSON(value any) string {
+	switch v := value.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return fmt.Sprintf("an array of %d items", len(v))
+	}
+	return oj.JSON(value)
+}
+
+// firstDifference describes where two texts start to differ, by line, naming the side of want
+// after reference
+func firstDifference(want, got, reference string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		switch {
+		case i >= len(gotLines):
+			return fmt.Sprintf("body ends at line %d, %s has %d lines", len(gotLines), reference, len(wantLines))
+		case i >= len(wantLines):
+			return fmt.Sprintf("body has %d lines, %s has %d", len(gotLines), reference, len(wantLines))
+		case gotLines[i] != wantLines[i]:
+			return fmt.Sprintf("line %d differs: %q, %s has %q", i+1, gotLines[i], reference, wantLines[i])
+		}
+	}
+	return "body differs from the " + reference
+}
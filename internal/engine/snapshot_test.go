@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"testing"
+
+	"paperbox/internal/config/requests"
+)
+
+func TestCompareSnapshot(t *testing.T) {
+	recorded := &Execution{
+		Status:  200,
+		Headers: map[string][]string{"Content-Type": {"application/json"}, "Date": {"Mon, 01 Jan 2024 00:00:00 GMT"}},
+		Body:    `{"id": 7, "updatedAt": "2024-01-01", "tags": ["a", "b"], "owner": {"name": "Ada"}}`,
+	}
+	snapshot := NewSnapshot(recorded, &requests.Snapshot{IgnorePaths: []string{"$.updatedAt"}, CompareHeaders: []string{"content-type"}})
+	if snapshot.Status != 200 || snapshot.Headers["Content-Type"] != "application/json" || len(snapshot.IgnorePaths) != 1 {
+		t.Fatalf("NewSnapshot() = %+v", snapshot)
+	}
+
+	same := &Execution{
+		Status:  200,
+		Headers: map[string][]string{"Content-Type": {"application/json"}, "Date": {"Tue, 02 Jan 2024 00:00:00 GMT"}},
+		Body:    `{"owner": {"name": "Ada"}, "tags": ["a", "b"], "id": 7, "updatedAt": "2024-01-02"}`,
+	}
+	for _, result := range CompareSnapshot(snapshot, same) {
+		if !result.Passed {
+			t.Errorf("CompareSnapshot() %s failed: %s", result.Name, result.Message)
+		}
+	}
+
+	drifted := &Execution{
+		Status:  201,
+		Headers: map[string][]string{"Content-Type": {"text/plain"}},
+		Body:    `{"id": "7", "tags": ["a"], "owner": {}, "extra": true}`,
+	}
+	var failures []string
+	for _, result := range CompareSnapshot(snapshot, drifted) {
+		if !result.Passed {
+			failures = append(failures, result.Name)
+		}
+	}
+	want := []string{
+		"Snapshot status",
+		"Snapshot header Content-Type",
+		"Snapshot body at $.extra",
+		"Snapshot body at $.id",
+		"Snapshot body at $.owner.name",
+		"Snapshot body at $.tags",
+	}
+	if len(failures) != len(want) {
+		t.Fatalf("CompareSnapshot() failures = %v, want %v", failures, want)
+	}
+	for i := range want {
+		if failures[i] != want[i] {
+			t.Errorf("CompareSnapshot() failure %d = %q, want %q", i, failures[i], want[i])
+		}
+	}
+
+	text := &requests.Snapshot{Status: 200, Body: "ok\nfine"}
+	if results := CompareSnapshot(text, &Execution{Status: 200, Body: "ok\nbroken"}); results[1].Passed || results[1].Message != `line 2 differs: "broken", snapshot has "fine"` {
+		t.Errorf("CompareSnapshot() text body = %+v", results)
+	}
+}
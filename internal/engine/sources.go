@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/requests"
+)
+
+// Sources holds everything a request is resolved against
+type Sources struct {
+	Requests    *requests.RequestsConfig
+	Environment *environments.Environment // nil when no environment is active
+	Globals     []environments.Variable
+	UserBaseURL string
+	// RunVariables are run-scoped values (e.g. captured by earlier requests in a collection run)
+	// that take precedence over environment variables
+	RunVariables map[string]string
+	// ProcessEnv allows {{env:NAME}} placeholders to read the process environment at send time
+	ProcessEnv bool
+	// Example names the request example applied to the resolved request (empty for none)
+	Example string
+	// Workspace holds the cookies and connections requests are sent with (nil for the shared ones)
+	Workspace *WorkspaceContext
+}
+
+// BaseURLFor returns the base URL a request inherits. Precedence, highest first:
+// the request's own BaseURL, the nearest ancestor folder with a BaseURL, the active
+// environment's BaseURL and finally the BaseURL from the user config.
+func BaseURLFor(src Sources, requestID string) string {
+	if item, exists := src.Requests.Values[requestID]; exists && item.BaseURL != "" {
+		return item.BaseURL
+	}
+	return inheritedBaseURL(src, requestID)
+}
+
+// inheritedBaseURL returns the base URL a request gets from its folders, environment or the user
+// config, ignoring its own
+func inheritedBaseURL(src Sources, requestID string) string {
+	for _, folderID := range requests.Ancestors(src.Requests, requestID) {
+		if folder := src.Requests.Values[folderID]; folder.BaseURL != "" {
+			return folder.BaseURL
+		}
+	}
+
+	if src.Environment != nil && src.Environment.BaseURL != "" {
+		return src.Environment.BaseURL
+	}
+
+	return src.UserBaseURL
+}
+
+// Variables returns the variables that apply outside any folder: globals, the active
+// environment's variables and run variables (see EffectiveVariables)
+func (src Sources) Variables() map[string]string {
+	return src.VariablesFor("")
+}
+
+// HostOverrides returns the enabled host overrides of the active environment
+func (src Sources) HostOverrides() map[string]string {
+	return hostOverrides(src.Environment, src.substituter(src.Variables()))
+}
+
+// substituter creates a substituter over vars that reads the process environment when allowed
+func (src Sources) substituter(vars map[string]string) *Substituter {
+	sub := NewSubstituter(vars)
+	if src.ProcessEnv {
+		sub.WithProcessEnv(os.LookupEnv)
+	}
+	return sub
+}
+
+// ResolveItem fully resolves the request with the given ID against all sources: {{variables}} are
+// substituted everywhere, the inherited base URL is applied and auth is injected. Variables without
+// a value are left in place and listed in Unresolved.
+func ResolveItem(src Sources, requestID string) (*ResolvedRequest, error) {
+	call := &Call{Sources: src, RequestID: requestID}
+	if err := resolveVariables(call); err != nil {
+		return nil, err
+	}
+	if err := injectAuth(call); err != nil {
+		return nil, err
+	}
+	return call.Request, nil
+}
+
+// resolveVariables resolves the call's request without its auth, keeping the substituter for
+// injectAuth
+func resolveVariables(call *Call) error {
+	src, requestID := call.Sources, call.RequestID
+	item, exists := src.Requests.Values[requestID]
+	if !exists {
+		return apperrors.NotFoundf("request not found")
+	}
+	if item.Type != requests.ItemTypeRequest {
+		return fmt.Errorf("item is not a request")
+	}
+	if src.Example != "" {
+		var err error
+		if item, err = requests.WithExample(item, src.Example); err != nil {
+			return err
+		}
+	}
+
+	vars := src.EffectiveVariables(requestID)
+	sub := src.substituter(variableValues(vars))
+	item = substituteItem(item, sub)
+	baseURL := sub.Apply(BaseURLFor(src, requestID))
+
+	resolved, err := Resolve(item, baseURL)
+	if err != nil {
+		return err
+	}
+	resolved.secrets = secretValues(vars, nil, sub.envValues)
+	resolved.HostOverrides = hostOverrides(src.Environment, sub)
+	resolved.TLS = tlsSettings(src.Environment)
+	resolved.Network = networkSettings(src.Environment)
+	resolved.SSHTunnel = sshTunnel(src.Environment, sub, variableValues(vars))
+	resolved.Unresolved = sub.Unresolved()
+
+	call.Request, call.sub, call.vars = resolved, sub, vars
+	return nil
+}
+
+// injectAuth applies the effective auth of the call's request, resolved by resolveVariables
+func injectAuth(call *Call) error {
+	auth := EffectiveAuth(call.Sources.Requests, call.RequestID)
+	if auth == nil {
+		return nil
+	}
+	sub := call.sub
+	if sub == nil {
+		sub = call.Sources.substituter(variableValues(call.vars))
+	}
+	substituteAuth(auth, sub)
+	// Auth providers only change the request line, headers and body
+	hosts, tls, network, tunnel := call.Request.HostOverrides, call.Request.TLS, call.Request.Network, call.Request.SSHTunnel
+	if err := applyAuth(call.Request, auth); err != nil {
+		return err
+	}
+	call.Request.HostOverrides, call.Request.TLS, call.Request.Network, call.Request.SSHTunnel = hosts, tls, network, tunnel
+	call.Request.secrets = secretValues(call.vars, auth, sub.envValues)
+	call.Request.Unresolved = sub.Unresolved()
+	return nil
+}
+
+// substituteItem returns a copy of item with variables substituted in all request fields
+func substituteItem(item requests.Item, sub *Substituter) requests.Item {
+	item.Path = sub.Apply(item.Path)
+	item.Body = sub.Apply(item.Body)
+
+	params := make([]requests.Param, len(item.QueryParams))
+	for i, p := range item.QueryParams {
+		p.Key, p.Value = sub.Apply(p.Key), sub.Apply(p.Value)
+		params[i] = p
+	}
+	item.QueryParams = params
+
+	vars := make([]requests.Param, len(item.PathVars))
+	for i, v := range item.PathVars {
+		v.Value = sub.Apply(v.Value)
+		vars[i] = v
+	}
+	item.PathVars = vars
+
+	fields := make([]requests.FormField, len(item.FormFields))
+	for i, f := range item.FormFields {
+		f.Key, f.Value = sub.Apply(f.Key), sub.Apply(f.Value)
+		fields[i] = f
+	}
+	item.FormFields = fields
+
+	headers := make([]requests.Header, len(item.Headers))
+	for i, h := range item.Headers {
+		h.Key, h.Value = sub.Apply(h.Key), sub.Apply(h.Value)
+		headers[i] = h
+	}
+	item.Headers = headers
+
+	return item
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"net"
+	"strings"
+
+	"paperbox/internal/config/environments"
+	"paperbox/internal/tunnel"
+)
+
+// defaultSSHPort is used for jump hosts configured without a port
+const defaultSSHPort = "22"
+
+// SSHTunnel is the SSH jump host of a request with its variables substituted (see
+// environments.SSHTunnel). The key is never serialized.
+type SSHTunnel struct {
+	Server  string   `json:"server"`
+	User    string   `json:"user"`
+	HostKey string   `json:"hostKey,omitempty"`
+	Hosts   []string `json:"hosts,omitempty"`
+
+	key        string
+	passphrase string
+}
+
+// sshTunnel returns the enabled SSH tunnel of an environment with the key and passphrase read from
+// vars, or nil when it has none
+func sshTunnel(env *environments.Environment, sub *Substituter, vars map[string]string) *SSHTunnel {
+	if env == nil || env.Tunnel == nil || env.Tunnel.Disabled {
+		return nil
+	}
+	t := env.Tunnel
+	server := sub.Apply(t.Server)
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), defaultSSHPort)
+	}
+	resolved := &SSHTunnel{
+		Server:  server,
+		User:    sub.Apply(t.User),
+		HostKey: t.HostKey,
+		key:     vars[t.KeyVariable],
+	}
+	if t.PassphraseVariable != "" {
+		resolved.passphrase = vars[t.PassphraseVariable]
+	}
+	for _, host := range t.Hosts {
+		resolved.Hosts = append(resolved.Hosts, strings.ToLower(host))
+	}
+	return resolved
+}
+
+// SSHTunnel returns the enabled SSH tunnel of the active environment, nil when it has none
+func (src Sources) SSHTunnel() *SSHTunnel {
+	vars := src.Variables()
+	return sshTunnel(src.Environment, src.substituter(vars), vars)
+}
+
+// JumpHost returns the jump host settings of the tunnel
+func (t *SSHTunnel) JumpHost() tunnel.JumpHost {
+	return tunnel.JumpHost{Server: t.Server, User: t.User, Key: []byte(t.key), Passphrase: t.passphrase, HostKey: t.HostKey}
+}
+
+// CloseSSHTunnels closes the connections to jump hosts; the next request through one connects again
+func (e *Engine) CloseSSHTunnels() {
+	e.jumps.Close()
+}
+
+// routes reports whether connections to a dial address ("host:port") go through the tunnel: a
+// pattern matches the host, "*.example.com" its subdomains, and a pattern with a port only that port
+func (t *SSHTunnel) routes(addr string) bool {
+	if len(t.Hosts) == 0 {
+		return true
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	host = strings.ToLower(host)
+	for _, pattern := range t.Hosts {
+		name, patternPort, err := net.SplitHostPort(pattern)
+		if err != nil {
+			name, patternPort = strings.Trim(pattern, "[]"), ""
+		}
+		if patternPort != "" && patternPort != port {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(name, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == name {
+			return true
+		}
+	}
+	return false
+}
+
+// transportKey identifies the tunnel's settings among the connection settings of a request
+func (t *SSHTunnel) transportKey() string {
+	return t.JumpHost().ID() + "|" + strings.Join(t.Hosts, ",")
+}
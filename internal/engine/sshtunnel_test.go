@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/requests"
+)
+
+func TestSSHTunnelRoutes(t *testing.T) {
+	tests := []struct {
+		hosts []string
+		addr  string
+		want  bool
+	}{
+		{nil, "api.example.com:443", true},
+		{[]string{"db.internal"}, "db.internal:5432", true},
+		{[]string{"db.internal"}, "api.internal:443", false},
+		{[]string{"*.internal"}, "api.eu.internal:443", true},
+		{[]string{"*.internal"}, "internal:443", false},
+		{[]string{"10.0.1.5:8080"}, "10.0.1.5:8080", true},
+		{[]string{"10.0.1.5:8080"}, "10.0.1.5:443", false},
+		{[]string{"[fd00::1]:443"}, "[fd00::1]:443", true},
+	}
+	for _, tt := range tests {
+		tunnel := &SSHTunnel{Hosts: tt.hosts}
+		if got := tunnel.routes(tt.addr); got != tt.want {
+			t.Errorf("routes(%q) with hosts %v = %v, want %v", tt.addr, tt.hosts, got, tt.want)
+		}
+	}
+}
+
+func TestResolveItemSSHTunnel(t *testing.T) {
+	src := Sources{
+		Requests: &requests.RequestsConfig{Values: map[string]requests.Item{
+			"r": {Type: requests.ItemTypeRequest, Method: "GET", Path: "http://db.internal/status"},
+		}},
+		Environment: &environments.Environment{
+			Variables: []environments.Variable{{Key: "bastion", Value: "bastion.example.com"}, {Key: "sshKey", Value: "PEM", Secret: true}},
+			Tunnel:    &environments.SSHTunnel{Server: "{{bastion}}", User: "dev", KeyVariable: "sshKey", Hosts: []string{"DB.internal"}},
+		},
+	}
+	req, err := ResolveItem(src, "r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tunnel := req.SSHTunnel
+	if tunnel == nil || tunnel.Server != "bastion.example.com:22" || tunnel.key != "PEM" || tunnel.Hosts[0] != "db.internal" {
+		t.Fatalf("SSHTunnel = %+v", tunnel)
+	}
+	if data, _ := json.Marshal(req.Masked()); strings.Contains(string(data), "PEM") {
+		t.Errorf("serialized request reveals the key: %s", data)
+	}
+
+	src.Environment.Tunnel.Disabled = true
+	if req, _ := ResolveItem(src, "r"); req.SSHTunnel != nil {
+		t.Errorf("disabled SSHTunnel = %+v", req.SSHTunnel)
+	}
+}
+
+func TestSendThroughSSHTunnel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("direct"))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	// Nothing listens on the jump host, so only requests routed through it fail
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jumpHost := closed.Addr().String()
+	closed.Close()
+
+	e := New()
+	tunnel := &SSHTunnel{Server: jumpHost, User: "dev", key: "PEM", Hosts: []string{"*.internal"}}
+	direct := e.Send(context.Background(), &ResolvedRequest{Method: "GET", URL: server.URL, SSHTunnel: tunnel})
+	if direct.Error != "" || direct.Body != "direct" {
+		t.Errorf("request to a host outside the tunnel = %q, %q", direct.Body, direct.Error)
+	}
+	routed := e.Send(context.Background(), &ResolvedRequest{Method: "GET", URL: "http://db.internal:" + port, SSHTunnel: tunnel})
+	if !strings.Contains(routed.Error, "SSH") {
+		t.Errorf("request through the tunnel error = %q, want the jump host's", routed.Error)
+	}
+}
@@ -0,0 +1,91 @@
+package engine
+
+import "sync"
+
+const (
+	// MaxExecutions is the number of recent executions kept in memory
+	MaxExecutions = 200
+)
+
+// Store keeps the most recent executions so responses can be inspected after the fact
+type Store struct {
+	mu         sync.RWMutex
+	executions []*Execution
+}
+
+// NewStore creates an empty execution store
+func NewStore() *Store {
+	return &Store{}
+}
+
+// Add records an execution, evicting the oldest once MaxExecutions is exceeded
+func (s *Store) Add(exec *Execution) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.executions = append(s.executions, exec)
+	if len(s.executions) > MaxExecutions {
+		s.executions = s.executions[len(s.executions)-MaxExecutions:]
+	}
+}
+
+// Get returns an execution by ID
+func (s *Store) Get(id string) (*Execution, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, exec := range s.executions {
+		if exec.ID == id {
+			return exec, true
+		}
+	}
+	return nil, false
+}
+
+// List returns recorded executions, oldest first
+func (s *Store) List() []*Execution {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]*Execution, len(s.executions))
+	copy(list, s.executions)
+	return list
+}
+
+// MaxRuns is the number of recent collection runs kept in memory
+const MaxRuns = 20
+
+// RunStore keeps the most recent collection runs so reports can be written after the fact
+type RunStore struct {
+	mu   sync.RWMutex
+	runs []*RunResult
+}
+
+// NewRunStore creates an empty run store
+func NewRunStore() *RunStore {
+	return &RunStore{}
+}
+
+// Add records a run, evicting the oldest once MaxRuns is exceeded
+func (s *RunStore) Add(run *RunResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runs = append(s.runs, run)
+	if len(s.runs) > MaxRuns {
+		s.runs = s.runs[len(s.runs)-MaxRuns:]
+	}
+}
+
+// Get returns a run by ID
+func (s *RunStore) Get(id string) (*RunResult, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, run := range s.runs {
+		if run.ID == id {
+			return run, true
+		}
+	}
+	return nil, false
+}
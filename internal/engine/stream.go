@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	// EventStreamRecord is emitted with a StreamRecord for every record of a streamed response
+	EventStreamRecord = "stream:record"
+	// EventStreamEnd is emitted with a StreamEnd once a streamed response ends
+	EventStreamEnd = "stream:end"
+
+	// DefaultStreamBufferSize is how much of a streamed body is kept in the execution by default
+	DefaultStreamBufferSize = 1 << 20
+	// maxRecordSize bounds a single line or event of a stream
+	maxRecordSize = 4 << 20
+)
+
+// StreamRecord is one record of a streamed response: a line of NDJSON or chunked text, or a
+// server-sent event. Record holds it when it is JSON, Text otherwise.
+type StreamRecord struct {
+	ExecutionID string          `json:"executionId"`
+	RequestID   string          `json:"requestId,omitempty"`
+	Seq         int             `json:"seq"`
+	Event       string          `json:"event,omitempty"` // Event name of a server-sent event
+	Record      json.RawMessage `json:"record,omitempty"`
+	Text        string          `json:"text,omitempty"`
+}
+
+// StreamEnd closes the records of a streamed response
+type StreamEnd struct {
+	ExecutionID string `json:"executionId"`
+	RequestID   string `json:"requestId,omitempty"`
+	Records     int    `json:"records"`
+	Cancelled   bool   `json:"cancelled,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// SetStreamEmitter sets where the records of streamed responses are emitted; nil (the default)
+// reads streamed responses like any other body
+func (e *Engine) SetStreamEmitter(emit func(event string, payload interface{})) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.emitStream = emit
+}
+
+// SetStreamBufferSize changes how many bytes of a streamed body are kept in the execution; records
+// beyond it are still emitted. 0 keeps everything.
+func (e *Engine) SetStreamBufferSize(size int64) {
+	e.streamBuffer.Store(size)
+}
+
+// streamEmitter returns the emitter of streamed records, nil when streaming is off
+func (e *Engine) streamEmitter() func(event string, payload interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.emitStream
+}
+
+// isStream reports whether a response is read record by record: NDJSON, JSON Lines and
+// server-sent events, and plain text sent in chunks without a length (e.g. a log tail).
+// Compressed bodies are not, as their lines cannot be split before the end.
+func isStream(resp *http.Response) bool {
+	if encoding := resp.Header.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines",
+		"text/event-stream":
+		return true
+	case "text/plain":
+		return resp.ContentLength < 0 && len(resp.TransferEncoding) > 0 && resp.TransferEncoding[0] == "chunked"
+	}
+	return false
+}
+
+// readStream reads a streamed body record by record, emitting each as it arrives, until the server
+// ends it or ctx is cancelled. The first bytes up to the stream buffer size are returned as the
+// body with the number of bytes read; a cancelled stream ends without an error.
+func (e *Engine) readStream(ctx context.Context, exec *Execution, resp *http.Response, emit func(string, interface{})) ([]byte, int64, error) {
+	limit := e.streamBuffer.Load()
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	end := StreamEnd{ExecutionID: exec.ID, RequestID: exec.RequestID}
+	record := func(event string, data string) {
+		end.Records++
+		r := StreamRecord{ExecutionID: exec.ID, RequestID: exec.RequestID, Seq: end.Records, Event: event}
+		if trimmed := strings.TrimSpace(data); json.Valid([]byte(trimmed)) {
+			r.Record = json.RawMessage(trimmed)
+		} else {
+			r.Text = data
+		}
+		emit(EventStreamRecord, r)
+	}
+
+	var body bytes.Buffer
+	var size int64
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxRecordSize)
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		size += int64(len(line)) + 1
+		if limit <= 0 || int64(body.Len()+len(line)+1) <= limit {
+			body.WriteString(line)
+			body.WriteByte('\n')
+		} else {
+			exec.Truncated = true
+		}
+
+		if mediaType != "text/event-stream" {
+			if strings.TrimSpace(line) != "" {
+				record("", line)
+			}
+			continue
+		}
+		// Server-sent events end with a blank line; comments start with a colon
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "":
+			if line == "" {
+				if data != nil {
+					record(event, strings.Join(data, "\n"))
+				}
+				event, data = "", nil
+			}
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	if data != nil {
+		record(event, strings.Join(data, "\n"))
+	}
+
+	err := scanner.Err()
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		end.Cancelled, err = true, nil
+	}
+	if err != nil {
+		end.Error = err.Error()
+	}
+	emit(EventStreamEnd, end)
+	return body.Bytes(), size, err
+}
@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// streamCollector records emitted stream events
+type streamCollector struct {
+	mu      sync.Mutex
+	records []StreamRecord
+	ends    []StreamEnd
+	first   chan struct{}
+}
+
+func newStreamCollector() *streamCollector {
+	return &streamCollector{first: make(chan struct{})}
+}
+
+func (c *streamCollector) emit(event string, payload interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch event {
+	case EventStreamRecord:
+		c.records = append(c.records, payload.(StreamRecord))
+		if len(c.records) == 1 {
+			close(c.first)
+		}
+	case EventStreamEnd:
+		c.ends = append(c.ends, payload.(StreamEnd))
+	}
+}
+
+func TestSendEmitsNDJSONRecords(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte("{\"n\":1}\n\n{\"n\":2}\nnot json\n"))
+	}))
+	defer server.Close()
+
+	c := newStreamCollector()
+	e := NewWithClient(server.Client())
+	e.SetStreamEmitter(c.emit)
+	exec := e.Send(context.Background(), &ResolvedRequest{Method: "GET", URL: server.URL})
+	if exec.Error != "" {
+		t.Fatalf("Send() error = %q", exec.Error)
+	}
+
+	if len(c.records) != 3 {
+		t.Fatalf("records = %+v, want 3", c.records)
+	}
+	if string(c.records[1].Record) != `{"n":2}` || c.records[1].Seq != 2 || c.records[1].ExecutionID != exec.ID {
+		t.Errorf("second record = %+v", c.records[1])
+	}
+	if c.records[2].Record != nil || c.records[2].Text != "not json" {
+		t.Errorf("third record = %+v, want text", c.records[2])
+	}
+	if len(c.ends) != 1 || c.ends[0].Records != 3 || c.ends[0].Cancelled {
+		t.Errorf("ends = %+v", c.ends)
+	}
+	if exec.Body != "{\"n\":1}\n\n{\"n\":2}\nnot json\n" {
+		t.Errorf("Body = %q", exec.Body)
+	}
+}
+
+func TestSendEmitsServerSentEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(": keep-alive\n\nevent: delta\ndata: {\"text\":\"Hel\"}\n\ndata: line one\ndata: line two\n\n"))
+	}))
+	defer server.Close()
+
+	c := newStreamCollector()
+	e := NewWithClient(server.Client())
+	e.SetStreamEmitter(c.emit)
+	e.Send(context.Background(), &ResolvedRequest{Method: "GET", URL: server.URL})
+
+	if len(c.records) != 2 {
+		t.Fatalf("records = %+v, want 2", c.records)
+	}
+	if c.records[0].Event != "delta" || string(c.records[0].Record) != `{"text":"Hel"}` {
+		t.Errorf("first record = %+v", c.records[0])
+	}
+	if c.records[1].Text != "line one\nline two" {
+		t.Errorf("second record = %+v, want joined data lines", c.records[1])
+	}
+}
+
+func TestSendCapsStreamBuffer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/jsonl")
+		w.Write([]byte("1\n2\n3\n4\n"))
+	}))
+	defer server.Close()
+
+	c := newStreamCollector()
+	e := NewWithClient(server.Client())
+	e.SetStreamEmitter(c.emit)
+	e.SetStreamBufferSize(4)
+	exec := e.Send(context.Background(), &ResolvedRequest{Method: "GET", URL: server.URL})
+
+	if exec.Body != "1\n2\n" || !exec.Truncated || exec.Size != 8 {
+		t.Errorf("Body = %q, Truncated = %v, Size = %d; want the first 4 bytes of 8", exec.Body, exec.Truncated, exec.Size)
+	}
+	if len(c.records) != 4 {
+		t.Errorf("records = %d, want all 4 emitted", len(c.records))
+	}
+}
+
+func TestSendCancelsStream(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("started\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	c := newStreamCollector()
+	e := NewWithClient(server.Client())
+	e.SetStreamEmitter(c.emit)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-c.first:
+			cancel()
+		case <-time.After(5 * time.Second):
+		}
+	}()
+	exec := e.Send(ctx, &ResolvedRequest{Method: "GET", URL: server.URL})
+
+	if exec.Error != "" || exec.Body != "started\n" {
+		t.Errorf("Send() = %q, error %q; want the records before cancelling", exec.Body, exec.Error)
+	}
+	if len(c.ends) != 1 || !c.ends[0].Cancelled {
+		t.Errorf("ends = %+v, want a cancelled stream", c.ends)
+	}
+}
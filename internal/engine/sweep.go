@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/requests"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxSweepValues bounds how many values a sweep sends
+	MaxSweepValues = 1000
+	// MaxSweepConcurrency bounds how many requests of a sweep are in flight at once
+	MaxSweepConcurrency = 16
+)
+
+// SweepOptions select the values a sweep sends a request with
+type SweepOptions struct {
+	// Param is bound to each value as a run variable ({{param}}); query parameters and path
+	// variables of the request with that key take the value too
+	Param  string   `json:"param"`
+	Values []string `json:"values"`
+	// Concurrency is how many values are sent at once; 1 or less sends them one after another
+	Concurrency int `json:"concurrency"`
+	// Progress, if not nil, is told after each value sent how many of them were sent; it may be
+	// called from several goroutines at once
+	Progress func(done, total int, current string) `json:"-"`
+}
+
+// SweepRow is the outcome of sending a request with one value
+type SweepRow struct {
+	Value       string `json:"value"`
+	ExecutionID string `json:"executionId"`
+	Status      int    `json:"status"`
+	DurationMs  int64  `json:"durationMs"`
+	Size        int64  `json:"size"`
+	Passed      bool   `json:"passed"`
+	Error       string `json:"error,omitempty"`
+}
+
+// SweepResult tabulates a request sent once per value, in the order of the values
+type SweepResult struct {
+	ID         string       `json:"id"`
+	RequestID  string       `json:"requestId"`
+	Param      string       `json:"param"`
+	StartedAt  time.Time    `json:"startedAt"`
+	DurationMs int64        `json:"durationMs"`
+	Rows       []SweepRow   `json:"rows"`
+	Statuses   map[int]int  `json:"statuses"` // Number of rows by status; 0 counts requests that got no response
+	Executions []*Execution `json:"executions"`
+	Cancelled  bool         `json:"cancelled,omitempty"`
+}
+
+// RunSweep sends a request once per value of opts.Values, for probing pagination limits, enum
+// values or input ranges. Values are sent one after another, or up to opts.Concurrency at once;
+// rows keep the order of the values either way. Values not sent because ctx was cancelled are left
+// out.
+func (e *Engine) RunSweep(ctx context.Context, src Sources, requestID string, opts SweepOptions) (*SweepResult, error) {
+	item, exists := src.Requests.Values[requestID]
+	if !exists || item.Type != requests.ItemTypeRequest {
+		return nil, apperrors.NotFoundf("request not found")
+	}
+	switch {
+	case opts.Param == "":
+		return nil, apperrors.Invalidf("a sweep needs a parameter name")
+	case len(opts.Values) == 0:
+		return nil, apperrors.Invalidf("a sweep needs at least one value")
+	case len(opts.Values) > MaxSweepValues:
+		return nil, apperrors.Invalidf("a sweep can send at most %d values", MaxSweepValues)
+	case opts.Concurrency > MaxSweepConcurrency:
+		return nil, apperrors.Invalidf("a sweep can send at most %d values at once", MaxSweepConcurrency)
+	}
+
+	result := &SweepResult{
+		ID:        uuid.New().String(),
+		RequestID: requestID,
+		Param:     opts.Param,
+		StartedAt: time.Now(),
+		Statuses:  make(map[int]int),
+	}
+	executions := make([]*Execution, len(opts.Values))
+	slots := make(chan struct{}, max(opts.Concurrency, 1))
+	var sent atomic.Int64
+	var wg sync.WaitGroup
+	for i, value := range opts.Values {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			result.Cancelled = true
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			executions[i] = e.sweepValue(ctx, src, requestID, item, opts.Param, value)
+			if opts.Progress != nil {
+				opts.Progress(int(sent.Add(1)), len(opts.Values), value)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, exec := range executions {
+		if exec == nil {
+			continue
+		}
+		result.Rows = append(result.Rows, SweepRow{
+			Value:       opts.Values[i],
+			ExecutionID: exec.ID,
+			Status:      exec.Status,
+			DurationMs:  exec.DurationMs,
+			Size:        exec.Size,
+			Passed:      executionPassed(exec),
+			Error:       exec.Error,
+		})
+		result.Statuses[exec.Status]++
+		result.Executions = append(result.Executions, exec)
+	}
+	result.DurationMs = time.Since(result.StartedAt).Milliseconds()
+	return result, nil
+}
+
+// sweepValue sends the request of a sweep with one value
+func (e *Engine) sweepValue(ctx context.Context, src Sources, requestID string, item requests.Item, param string, value string) *Execution {
+	src.RunVariables = mergeVariables(src.RunVariables, map[string]string{param: value})
+	if swept, changed := withParam(item, param, value); changed {
+		cfg := *src.Requests
+		cfg.Values = maps.Clone(cfg.Values)
+		cfg.Values[requestID] = swept
+		src.Requests = &cfg
+	}
+	exec, err := e.Run(ctx, src, requestID)
+	if err != nil {
+		exec = &Execution{ID: uuid.New().String(), RequestID: requestID, StartedAt: time.Now(), Error: err.Error()}
+	}
+	return exec
+}
+
+// withParam returns a copy of item whose query parameters and path variables named param have
+// the value, enabled; it reports whether any had that name
+func withParam(item requests.Item, param string, value string) (requests.Item, bool) {
+	changed := false
+	set := func(params []requests.Param) []requests.Param {
+		params = append([]requests.Param{}, params...)
+		for i := range params {
+			if params[i].Key == param {
+				params[i].Value, params[i].Disabled = value, false
+				changed = true
+			}
+		}
+		return params
+	}
+	item.QueryParams = set(item.QueryParams)
+	item.PathVars = set(item.PathVars)
+	return item, changed
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"paperbox/internal/config/requests"
+)
+
+func TestRunSweep(t *testing.T) {
+	var inflight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(10 * time.Millisecond)
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit > 100 || r.Header.Get("X-Page") != r.URL.Query().Get("limit") {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"list": {
+				Type: requests.ItemTypeRequest, Name: "List", Method: "GET", Path: "/items",
+				QueryParams: []requests.Param{{Key: "limit", Value: "10", Disabled: true}},
+				Headers:     []requests.Header{{Key: "X-Page", Value: "{{limit}}"}},
+			},
+		},
+	}
+	src := Sources{Requests: cfg, UserBaseURL: server.URL}
+	e := NewWithClient(server.Client())
+
+	values := []string{"1", "100", "101", "-", "50", "1000"}
+	// Values finish in any order, but every one is counted once
+	var reported, sum atomic.Int32
+	progress := func(done, total int, current string) {
+		reported.Add(1)
+		sum.Add(int32(done))
+		if total != len(values) {
+			t.Errorf("progress total = %d, want %d", total, len(values))
+		}
+	}
+	result, err := e.RunSweep(context.Background(), src, "list", SweepOptions{Param: "limit", Values: values, Concurrency: 3, Progress: progress})
+	if err != nil {
+		t.Fatalf("RunSweep() error = %v", err)
+	}
+	want := []int{200, 200, 400, 400, 200, 400}
+	if len(result.Rows) != len(want) {
+		t.Fatalf("RunSweep() rows = %+v", result.Rows)
+	}
+	for i, row := range result.Rows {
+		if row.Value != values[i] || row.Status != want[i] {
+			t.Errorf("row %d = %s → %d, want %s → %d", i, row.Value, row.Status, values[i], want[i])
+		}
+	}
+	if reported.Load() != int32(len(values)) || sum.Load() != 21 {
+		t.Errorf("progress reported %d values summing to %d, want 1 to %d", reported.Load(), sum.Load(), len(values))
+	}
+	if result.Statuses[200] != 3 || result.Statuses[400] != 3 || len(result.Executions) != 6 {
+		t.Errorf("Statuses = %v, %d executions", result.Statuses, len(result.Executions))
+	}
+	if p := peak.Load(); p < 2 || p > 3 {
+		t.Errorf("peak concurrency = %d, want at most 3 and more than one", p)
+	}
+	if !cfg.Values["list"].QueryParams[0].Disabled {
+		t.Error("RunSweep() changed the stored request")
+	}
+
+	if _, err := e.RunSweep(context.Background(), src, "list", SweepOptions{Param: "limit"}); err == nil {
+		t.Error("RunSweep() without values should fail")
+	}
+	if _, err := e.RunSweep(context.Background(), src, "missing", SweepOptions{Param: "limit", Values: values}); err == nil {
+		t.Error("RunSweep() of a missing request should fail")
+	}
+}
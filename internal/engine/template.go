@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// templatePattern matches "{{ expression }}" placeholders
+var templatePattern = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// ProcessEnvPrefix marks a placeholder reading the process environment ({{env:API_TOKEN}})
+const ProcessEnvPrefix = "env:"
+
+// Substituter replaces {{name}} placeholders with variable values or template function results
+// ({{uuid}}, {{randomInt 1 100}}) and remembers placeholders it could not resolve
+type Substituter struct {
+	vars       map[string]string
+	funcs      *FuncRegistry
+	unresolved map[string]bool
+
+	// lookupEnv resolves {{env:NAME}} placeholders; nil leaves them unresolved
+	lookupEnv func(string) (string, bool)
+	// envValues are the process environment values substituted so far
+	envValues []string
+}
+
+// NewSubstituter creates a substituter over the given variables using the default template functions
+func NewSubstituter(vars map[string]string) *Substituter {
+	return NewSubstituterWithFuncs(vars, DefaultFuncs)
+}
+
+// NewSubstituterWithFuncs creates a substituter with a custom function registry
+func NewSubstituterWithFuncs(vars map[string]string, funcs *FuncRegistry) *Substituter {
+	return &Substituter{vars: vars, funcs: funcs, unresolved: make(map[string]bool)}
+}
+
+// WithProcessEnv lets {{env:NAME}} placeholders read the process environment through lookup
+// (os.LookupEnv outside tests)
+func (s *Substituter) WithProcessEnv(lookup func(string) (string, bool)) *Substituter {
+	s.lookupEnv = lookup
+	return s
+}
+
+// Apply substitutes all resolvable placeholders in input; the others are left as-is.
+// A variable takes precedence over a function with the same name.
+func (s *Substituter) Apply(input string) string {
+	if !strings.Contains(input, "{{") {
+		return input
+	}
+
+	return templatePattern.ReplaceAllStringFunc(input, func(match string) string {
+		expr := templatePattern.FindStringSubmatch(match)[1]
+		value, ok := s.evaluate(expr)
+		if !ok {
+			s.unresolved[expr] = true
+			return match
+		}
+		return value
+	})
+}
+
+// evaluate resolves a single placeholder expression
+func (s *Substituter) evaluate(expr string) (string, bool) {
+	if value, ok := s.vars[expr]; ok {
+		return value, true
+	}
+	if name, ok := strings.CutPrefix(expr, ProcessEnvPrefix); ok {
+		return s.processEnv(strings.TrimSpace(name))
+	}
+
+	tokens, err := tokenize(expr)
+	if err != nil || len(tokens) == 0 || tokens[0].quoted || s.funcs == nil {
+		return "", false
+	}
+
+	fn, ok := s.funcs.Lookup(tokens[0].text)
+	if !ok {
+		return "", false
+	}
+
+	// Unquoted arguments naming a variable are replaced by its value; everything else is a literal
+	args := make([]string, 0, len(tokens)-1)
+	for _, t := range tokens[1:] {
+		if value, isVar := s.vars[t.text]; isVar && !t.quoted {
+			args = append(args, value)
+		} else {
+			args = append(args, t.text)
+		}
+	}
+
+	value, err := fn(args)
+	if err != nil {
+		return "", false
+	}
+	return value, true
+}
+
+// processEnv reads a process environment variable when the substituter allows it
+func (s *Substituter) processEnv(name string) (string, bool) {
+	if s.lookupEnv == nil || name == "" {
+		return "", false
+	}
+	value, ok := s.lookupEnv(name)
+	if ok {
+		s.envValues = append(s.envValues, value)
+	}
+	return value, ok
+}
+
+// Placeholders returns the expressions of the {{...}} placeholders in text, in order
+func Placeholders(text string) []string {
+	var exprs []string
+	for _, match := range templatePattern.FindAllStringSubmatch(text, -1) {
+		exprs = append(exprs, match[1])
+	}
+	return exprs
+}
+
+// Unresolved returns the sorted names of placeholders that had no value
+func (s *Substituter) Unresolved() []string {
+	names := make([]string, 0, len(s.unresolved))
+	for name := range s.unresolved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
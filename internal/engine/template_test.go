@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestSubstituterFunctions(t *testing.T) {
+	sub := NewSubstituter(map[string]string{"secret": "key", "uuid": "fixed"})
+
+	if got := sub.Apply(`{{base64 "user:pass"}}`); got != "dXNlcjpwYXNz" {
+		t.Errorf("base64 = %q", got)
+	}
+	// Variables win over functions with the same name
+	if got := sub.Apply("{{uuid}}"); got != "fixed" {
+		t.Errorf("variable precedence = %q", got)
+	}
+	// Unquoted arguments naming a variable use its value
+	if got := sub.Apply(`{{hmacSHA256 secret "The quick brown fox jumps over the lazy dog"}}`); got != "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8" {
+		t.Errorf("hmacSHA256 = %q", got)
+	}
+
+	n, err := strconv.Atoi(sub.Apply("{{randomInt 5 7}}"))
+	if err != nil || n < 5 || n > 7 {
+		t.Errorf("randomInt = %d, %v", n, err)
+	}
+	if got := sub.Apply("{{timestamp}}"); !regexp.MustCompile(`^\d{10,}$`).MatchString(got) {
+		t.Errorf("timestamp = %q", got)
+	}
+
+	if got := sub.Apply("{{randomInt 9 1}}-{{missing}}"); got != "{{randomInt 9 1}}-{{missing}}" {
+		t.Errorf("failed placeholders should be kept, got %q", got)
+	}
+	unresolved := sub.Unresolved()
+	if len(unresolved) != 2 || unresolved[0] != "missing" || unresolved[1] != "randomInt 9 1" {
+		t.Errorf("Unresolved() = %v", unresolved)
+	}
+}
+
+func TestSubstituterProcessEnv(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		if name == "API_TOKEN" {
+			return "t-12345", true
+		}
+		return "", false
+	}
+
+	if got := NewSubstituter(nil).Apply("{{env:API_TOKEN}}"); got != "{{env:API_TOKEN}}" {
+		t.Errorf("process environment read without opting in: %q", got)
+	}
+
+	sub := NewSubstituter(map[string]string{"env:HOME": "override"}).WithProcessEnv(lookup)
+	if got := sub.Apply("Bearer {{ env:API_TOKEN }} {{env:HOME}} {{env:MISSING}}"); got != "Bearer t-12345 override {{env:MISSING}}" {
+		t.Errorf("Apply() = %q", got)
+	}
+	if unresolved := sub.Unresolved(); len(unresolved) != 1 || unresolved[0] != "env:MISSING" {
+		t.Errorf("Unresolved() = %v", unresolved)
+	}
+}
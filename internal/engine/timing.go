@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings breaks a request's duration down into phases, in milliseconds. Phases that did not
+// happen (e.g. DNS and connect on a reused connection) are zero. When redirects are followed
+// the phases describe the last hop.
+type Timings struct {
+	DNS        float64 `json:"dnsMs"`
+	Connect    float64 `json:"connectMs"`
+	TLS        float64 `json:"tlsMs"`
+	TTFB       float64 `json:"ttfbMs"` // From the request being written to the first response byte
+	Download   float64 `json:"downloadMs"`
+	Total      float64 `json:"totalMs"`
+	ReusedConn bool    `json:"reusedConn,omitempty"`
+	RemoteAddr string  `json:"remoteAddr,omitempty"`
+}
+
+// timingRecorder collects httptrace events for a single request
+type timingRecorder struct {
+	mu sync.Mutex
+
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	reused       bool
+	remoteAddr   string
+}
+
+// newTimingRecorder starts timing at the current instant
+func newTimingRecorder() *timingRecorder {
+	return &timingRecorder{start: time.Now()}
+}
+
+// trace returns the client trace hooks feeding the recorder
+func (r *timingRecorder) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { r.mark(&r.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { r.mark(&r.dnsDone) },
+		ConnectStart: func(string, string) {
+			r.mark(&r.connectStart)
+		},
+		ConnectDone: func(string, string, error) {
+			r.mark(&r.connectDone)
+		},
+		TLSHandshakeStart: func() { r.mark(&r.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { r.mark(&r.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.reused = info.Reused
+			if info.Conn != nil {
+				r.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { r.mark(&r.wroteRequest) },
+		GotFirstResponseByte: func() { r.mark(&r.firstByte) },
+	}
+}
+
+// mark records the current time into field
+func (r *timingRecorder) mark(field *time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*field = time.Now()
+}
+
+// finish computes the phase durations; end is when the body was fully read
+func (r *timingRecorder) finish(end time.Time) Timings {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return Timings{
+		DNS:        phase(r.dnsStart, r.dnsDone),
+		Connect:    phase(r.connectStart, r.connectDone),
+		TLS:        phase(r.tlsStart, r.tlsDone),
+		TTFB:       phase(r.wroteRequest, r.firstByte),
+		Download:   phase(r.firstByte, end),
+		Total:      phase(r.start, end),
+		ReusedConn: r.reused,
+		RemoteAddr: r.remoteAddr,
+	}
+}
+
+// phase returns the duration between two instants in milliseconds, or zero if either is unset
+func phase(from, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0
+	}
+	return float64(to.Sub(from).Microseconds()) / 1000
+}
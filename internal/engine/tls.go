@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/environments"
+)
+
+// tlsVersions maps the MinVersion setting to crypto/tls constants
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsSettings returns the TLS settings of an environment, or nil when it has none
+func tlsSettings(env *environments.Environment) *environments.TLSSettings {
+	if env == nil || env.TLS == nil {
+		return nil
+	}
+	settings := *env.TLS
+	return &settings
+}
+
+// tlsConfig applies TLS settings on top of a base configuration (which may be nil)
+func tlsConfig(base *tls.Config, settings *environments.TLSSettings) (*tls.Config, error) {
+	config := &tls.Config{}
+	if base != nil {
+		config = base.Clone()
+	}
+
+	config.InsecureSkipVerify = settings.InsecureSkipVerify
+	if settings.MinVersion != "" {
+		version, ok := tlsVersions[settings.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported TLS version %q", settings.MinVersion)
+		}
+		config.MinVersion = version
+	}
+
+	if len(settings.PinnedSHA256) > 0 {
+		pins := make(map[string]bool, len(settings.PinnedSHA256))
+		for _, pin := range settings.PinnedSHA256 {
+			pins[environments.NormalizeFingerprint(pin)] = true
+		}
+		// Runs after (or, when skipping verification, instead of) the normal chain checks
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			for _, cert := range state.PeerCertificates {
+				if pins[Fingerprint(cert)] {
+					return nil
+				}
+			}
+			return errors.New("no certificate in the server's chain matches a pinned fingerprint")
+		}
+	}
+	return config, nil
+}
+
+// Fingerprint returns the hex SHA-256 fingerprint of a certificate
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// CertificateInfo describes one certificate of a server's chain
+type CertificateInfo struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SerialNumber       string    `json:"serialNumber"`
+	DNSNames           []string  `json:"dnsNames,omitempty"`
+	NotBefore          time.Time `json:"notBefore"`
+	NotAfter           time.Time `json:"notAfter"`
+	IsCA               bool      `json:"isCA"`
+	SignatureAlgorithm string    `json:"signatureAlgorithm"`
+	PublicKeyAlgorithm string    `json:"publicKeyAlgorithm"`
+	SHA256             string    `json:"sha256"` // Fingerprint usable in TLSSettings.PinnedSHA256
+}
+
+// TLSInspection is the outcome of a TLS handshake with a server
+type TLSInspection struct {
+	Address     string `json:"address"`
+	ServerName  string `json:"serverName"`
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipherSuite"`
+	ALPN        string `json:"alpn,omitempty"`
+	// Verified reports whether the chain is trusted by the system and valid for ServerName
+	Verified     bool              `json:"verified"`
+	VerifyError  string            `json:"verifyError,omitempty"`
+	Certificates []CertificateInfo `json:"certificates"`
+}
+
+// InspectTLS performs a TLS handshake with the server of rawURL and describes its certificate
+// chain. The chain is returned even when it does not verify; Verified says whether it does.
+// Host overrides apply as they do when sending requests.
+func InspectTLS(ctx context.Context, rawURL string, overrides map[string]string) (*TLSInspection, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil || target.Host == "" {
+		if !strings.Contains(rawURL, "://") {
+			return InspectTLS(ctx, "https://"+rawURL, overrides)
+		}
+		return nil, apperrors.Invalidf("invalid URL %q", rawURL)
+	}
+	host, port := target.Hostname(), target.Port()
+	if port == "" {
+		port = "443"
+	}
+	addr := net.JoinHostPort(host, port)
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+
+	dialer := &tls.Dialer{Config: &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true, // Verified separately below so invalid chains can still be shown
+		NextProtos:         []string{"h2", "http/1.1"},
+	}}
+	conn, err := dialer.DialContext(ctx, "tcp", overrideAddr(overrides, addr))
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "TLS handshake failed")
+	}
+	defer conn.Close()
+	state := conn.(*tls.Conn).ConnectionState()
+
+	inspection := &TLSInspection{
+		Address:     conn.RemoteAddr().String(),
+		ServerName:  host,
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ALPN:        state.NegotiatedProtocol,
+	}
+	for _, cert := range state.PeerCertificates {
+		inspection.Certificates = append(inspection.Certificates, CertificateInfo{
+			Subject:            cert.Subject.String(),
+			Issuer:             cert.Issuer.String(),
+			SerialNumber:       cert.SerialNumber.Text(16),
+			DNSNames:           cert.DNSNames,
+			NotBefore:          cert.NotBefore,
+			NotAfter:           cert.NotAfter,
+			IsCA:               cert.IsCA,
+			SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+			PublicKeyAlgorithm: cert.PublicKeyAlgorithm.String(),
+			SHA256:             Fingerprint(cert),
+		})
+	}
+
+	if err := verifyChain(state.PeerCertificates, host); err != nil {
+		inspection.VerifyError = err.Error()
+	} else {
+		inspection.Verified = true
+	}
+	return inspection, nil
+}
+
+// verifyChain checks a presented chain against the system roots for the given host
+func verifyChain(certs []*x509.Certificate, host string) error {
+	if len(certs) == 0 {
+		return errors.New("server presented no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{DNSName: host, Intermediates: intermediates})
+	return err
+}
@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"paperbox/internal/config/environments"
+)
+
+func TestSendAppliesTLSSettings(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secure"))
+	}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+	pin := Fingerprint(server.Certificate())
+
+	tests := []struct {
+		name     string
+		settings *environments.TLSSettings
+		wantErr  bool
+	}{
+		{"untrusted certificate", nil, true},
+		{"skip verify", &environments.TLSSettings{InsecureSkipVerify: true}, false},
+		{"matching pin", &environments.TLSSettings{InsecureSkipVerify: true, PinnedSHA256: []string{strings.ToUpper(pin)}}, false},
+		{"other pin", &environments.TLSSettings{InsecureSkipVerify: true, PinnedSHA256: []string{strings.Repeat("ab", 32)}}, true},
+		{"minimum version above server", &environments.TLSSettings{InsecureSkipVerify: true, MinVersion: "1.3"}, true},
+	}
+
+	eng := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec := eng.Send(context.Background(), &ResolvedRequest{Method: "GET", URL: server.URL, TLS: tt.settings})
+			if (exec.Error != "") != tt.wantErr {
+				t.Fatalf("Send() error = %q, wantErr %v", exec.Error, tt.wantErr)
+			}
+			if !tt.wantErr && exec.Body != "secure" {
+				t.Errorf("Send() body = %q", exec.Body)
+			}
+			if exec.InsecureTLS != (tt.settings != nil && tt.settings.InsecureSkipVerify) {
+				t.Errorf("Send() InsecureTLS = %v", exec.InsecureTLS)
+			}
+		})
+	}
+}
+
+func TestInspectTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	inspection, err := InspectTLS(context.Background(), server.URL, nil)
+	if err != nil {
+		t.Fatalf("InspectTLS() error = %v", err)
+	}
+	if len(inspection.Certificates) == 0 || inspection.Certificates[0].SHA256 != Fingerprint(server.Certificate()) {
+		t.Fatalf("InspectTLS() certificates = %+v", inspection.Certificates)
+	}
+	// The test server's certificate is not trusted by the system
+	if inspection.Verified || inspection.VerifyError == "" || inspection.Version == "" {
+		t.Errorf("InspectTLS() = %+v", inspection)
+	}
+
+	if _, err := InspectTLS(context.Background(), "https://127.0.0.1:1", nil); err == nil {
+		t.Error("InspectTLS() should fail when nothing listens")
+	}
+}
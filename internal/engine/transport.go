@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TransportSettings tune the connections requests are sent over. Zero values keep the net/http
+// defaults.
+type TransportSettings struct {
+	MaxIdleConnsPerHost int           // Idle connections kept per host for reuse
+	IdleConnTimeout     time.Duration // How long an idle connection is kept
+	DisableKeepAlives   bool          // Opens a new connection for every request
+	ForceClose          bool          // Sends every request with "Connection: close"
+}
+
+// SetTransportSettings changes how connections are pooled. The engine's shared connections are
+// closed so new ones follow the settings; those of workspaces need WorkspaceContext.Close.
+func (e *Engine) SetTransportSettings(settings TransportSettings) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.transport = settings
+	if base, ok := baseTransport(e.client); ok {
+		client := *e.client
+		client.Transport = e.tune(base.Clone())
+		e.client.CloseIdleConnections()
+		e.client = &client
+	}
+	for _, client := range e.overrideClients {
+		client.CloseIdleConnections()
+	}
+	e.overrideClients = nil
+}
+
+// tune applies the transport settings to a transport of the engine (must hold the lock)
+func (e *Engine) tune(transport *http.Transport) *http.Transport {
+	settings := e.transport
+	if settings.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = settings.MaxIdleConnsPerHost
+		transport.MaxIdleConns = max(transport.MaxIdleConns, settings.MaxIdleConnsPerHost)
+	}
+	if settings.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = settings.IdleConnTimeout
+	}
+	transport.DisableKeepAlives = settings.DisableKeepAlives
+	return transport
+}
+
+// forceClose reports whether requests are sent with "Connection: close"
+func (e *Engine) forceClose() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.transport.ForceClose
+}
+
+// baseTransport returns the transport of a client, net/http's default one for a client without
+// any, and false for a custom round tripper
+func baseTransport(client *http.Client) (*http.Transport, bool) {
+	if client.Transport == nil {
+		return http.DefaultTransport.(*http.Transport), true
+	}
+	transport, ok := client.Transport.(*http.Transport)
+	return transport, ok
+}
+
+// clientFor returns the client to send a request with. Requests with host overrides, TLS, network
+// settings or an SSH tunnel get a client with its own transport, so their connections never end up in the pool
+// used for normal DNS and certificate checks. A workspace gets clients of its own, with its cookie
+// jar and one transport per set of connection settings, so no connection or cookie is shared with
+// another workspace while requests of a collection run reuse the workspace's connections.
+func (e *Engine) clientFor(req *ResolvedRequest, ws *WorkspaceContext) (*http.Client, error) {
+	e.mu.Lock()
+	shared := e.client
+	e.mu.Unlock()
+	key := transportKey(req)
+	if key == "" && ws == nil {
+		return shared, nil
+	}
+
+	mu, clients := &e.mu, &e.overrideClients
+	if ws != nil {
+		mu, clients = &ws.mu, &ws.clients
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if client, ok := (*clients)[key]; ok {
+		return client, nil
+	}
+
+	client := *shared
+	if ws != nil {
+		client.Jar = ws.jar
+	}
+	base, ok := baseTransport(shared)
+	if !ok {
+		if key == "" {
+			// A custom round tripper cannot be cloned, so only the cookies are isolated
+			(*clients)[key] = &client
+			return &client, nil
+		}
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+	dial, err := dialFunc(req.HostOverrides, req.Network, req.SSHTunnel, e.jumps)
+	if err != nil {
+		return nil, err
+	}
+	if dial != nil {
+		transport.DialContext = dial
+	}
+	if req.TLS != nil {
+		config, err := tlsConfig(transport.TLSClientConfig, req.TLS)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = config
+	}
+
+	client.Transport = transport
+	if *clients == nil {
+		*clients = make(map[string]*http.Client)
+	}
+	(*clients)[key] = &client
+	return &client, nil
+}
+
+// transportKey identifies the connection settings of a request
+func transportKey(req *ResolvedRequest) string {
+	keys := make([]string, 0, len(req.HostOverrides))
+	for host, target := range req.HostOverrides {
+		keys = append(keys, host+"="+target)
+	}
+	sort.Strings(keys)
+	key := strings.Join(keys, ";")
+
+	if tls := req.TLS; tls != nil {
+		pins := append([]string{}, tls.PinnedSHA256...)
+		sort.Strings(pins)
+		key += "|" + tls.MinVersion + "|" + strings.Join(pins, ",")
+		if tls.InsecureSkipVerify {
+			key += "|insecure"
+		}
+	}
+	if network := req.Network; network != nil {
+		key += "|ip" + network.IPVersion + "|" + network.DNSServer
+	}
+	if req.SSHTunnel != nil {
+		key += "|ssh:" + req.SSHTunnel.transportKey()
+	}
+	return key
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"sort"
+
+	"paperbox/internal/config/requests"
+)
+
+// VariableScope identifies where the value of a variable comes from
+type VariableScope string
+
+// Variable scopes, lowest precedence first
+const (
+	ScopeGlobal      VariableScope = "global"
+	ScopeEnvironment VariableScope = "environment"
+	ScopeFolder      VariableScope = "folder"
+	ScopeRun         VariableScope = "run"
+)
+
+// VariableDefinition is one value given to a variable by a scope
+type VariableDefinition struct {
+	Scope  VariableScope `json:"scope"`
+	Source string        `json:"source,omitempty"` // Environment or folder name
+	Value  string        `json:"value"`
+	Secret bool          `json:"secret,omitempty"`
+}
+
+// EffectiveVariable is the value a request sees for a variable and the definitions it overrides
+type EffectiveVariable struct {
+	Key string `json:"key"`
+	VariableDefinition
+	// Shadowed lists the overridden definitions, highest precedence first
+	Shadowed []VariableDefinition `json:"shadowed,omitempty"`
+}
+
+// EffectiveVariables returns the variables a request resolves against, sorted by key. Precedence,
+// highest first: run variables, folder variables (nearest folder first), the active environment's
+// variables and globals. An empty requestID leaves folder variables out.
+func (src Sources) EffectiveVariables(requestID string) []EffectiveVariable {
+	definitions := make(map[string][]VariableDefinition)
+	define := func(scope VariableScope, source, key, value string, secret bool) {
+		definitions[key] = append(definitions[key], VariableDefinition{Scope: scope, Source: source, Value: value, Secret: secret})
+	}
+
+	for _, v := range src.Globals {
+		if !v.Disabled {
+			define(ScopeGlobal, "", v.Key, v.Value, v.Secret)
+		}
+	}
+	if src.Environment != nil {
+		for _, v := range src.Environment.Variables {
+			if !v.Disabled {
+				define(ScopeEnvironment, src.Environment.Name, v.Key, v.Value, v.Secret)
+			}
+		}
+	}
+	if requestID != "" && src.Requests != nil {
+		folders := requests.Ancestors(src.Requests, requestID)
+		for i := len(folders) - 1; i >= 0; i-- {
+			folder := src.Requests.Values[folders[i]]
+			for _, v := range folder.Variables {
+				if !v.Disabled {
+					define(ScopeFolder, folder.Name, v.Key, v.Value, v.Secret)
+				}
+			}
+		}
+	}
+	for key, value := range src.RunVariables {
+		define(ScopeRun, "", key, value, false)
+	}
+
+	vars := make([]EffectiveVariable, 0, len(definitions))
+	for key, defs := range definitions {
+		effective := EffectiveVariable{Key: key, VariableDefinition: defs[len(defs)-1]}
+		for i := len(defs) - 2; i >= 0; i-- {
+			effective.Shadowed = append(effective.Shadowed, defs[i])
+		}
+		vars = append(vars, effective)
+	}
+	sort.Slice(vars, func(a, b int) bool { return vars[a].Key < vars[b].Key })
+	return vars
+}
+
+// VariablesFor returns the values of the variables a request resolves against
+func (src Sources) VariablesFor(requestID string) map[string]string {
+	return variableValues(src.EffectiveVariables(requestID))
+}
+
+// variableValues maps effective variables to their values
+func variableValues(effective []EffectiveVariable) map[string]string {
+	vars := make(map[string]string, len(effective))
+	for _, v := range effective {
+		vars[v.Key] = v.Value
+	}
+	return vars
+}
+
+// MaskVariables returns a copy of vars with the values of secret definitions masked
+func MaskVariables(vars []EffectiveVariable) []EffectiveVariable {
+	masked := make([]EffectiveVariable, len(vars))
+	for i, v := range vars {
+		if v.Secret {
+			v.Value = requests.SecretMask
+		}
+		shadowed := make([]VariableDefinition, len(v.Shadowed))
+		for j, d := range v.Shadowed {
+			if d.Secret {
+				d.Value = requests.SecretMask
+			}
+			shadowed[j] = d
+		}
+		if v.Shadowed != nil {
+			v.Shadowed = shadowed
+		}
+		masked[i] = v
+	}
+	return masked
+}
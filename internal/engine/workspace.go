@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+)
+
+// WorkspaceContext holds the runtime state of one workspace: its cookies, its kept-alive
+// connections and TLS sessions, the validators of its cached responses, and its execution and
+// run history. Requests sent with
+// different contexts share none of it, so switching workspaces cannot leak credentials between
+// projects. Sources.Workspace selects the context; without one the engine's shared state is used.
+type WorkspaceContext struct {
+	Name    string
+	History *Store
+	Runs    *RunStore
+
+	mu      sync.Mutex
+	jar     http.CookieJar
+	clients map[string]*http.Client // By transport key, see Engine.clientFor
+	cache   *validatorCache         // See Engine.SetConditionalRequests
+}
+
+// NewWorkspaceContext creates an empty context
+func NewWorkspaceContext(name string) *WorkspaceContext {
+	return &WorkspaceContext{
+		Name:    name,
+		History: NewStore(),
+		Runs:    NewRunStore(),
+		jar:     newCookieJar(),
+		clients: make(map[string]*http.Client),
+		cache:   newValidatorCache(),
+	}
+}
+
+// Cookies returns the cookies the workspace would send to a URL
+func (w *WorkspaceContext) Cookies(u *url.URL) []*http.Cookie {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.jar.Cookies(u)
+}
+
+// Reset drops the workspace's cookies, connections and cached responses; the history is kept
+func (w *WorkspaceContext) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closeLocked()
+	w.jar = newCookieJar()
+	w.cache.clear()
+}
+
+// ClearCache drops the responses stored for conditional requests, so the next ones are sent
+// without validators
+func (w *WorkspaceContext) ClearCache() {
+	w.cache.clear()
+}
+
+// Close closes the workspace's idle connections
+func (w *WorkspaceContext) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closeLocked()
+}
+
+// closeLocked closes and forgets the workspace's clients (must hold the lock)
+func (w *WorkspaceContext) closeLocked() {
+	for _, client := range w.clients {
+		client.CloseIdleConnections()
+	}
+	w.clients = make(map[string]*http.Client)
+}
+
+// newCookieJar creates an empty in-memory cookie jar
+func newCookieJar() http.CookieJar {
+	// cookiejar.New only fails for options it does not get
+	jar, _ := cookiejar.New(nil)
+	return jar
+}
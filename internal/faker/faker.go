@@ -0,0 +1,107 @@
+package faker
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"paperbox/internal/engine"
+)
+
+var (
+	firstNames = []string{"Olivia", "Liam", "Emma", "Noah", "Ava", "Elijah", "Sophia", "Lucas", "Mia", "Mateo", "Amelia", "Leo", "Harper", "Ezra", "Yuki", "Amara", "Ivan", "Priya"}
+	lastNames  = []string{"Smith", "Johnson", "Garcia", "Brown", "Miller", "Davis", "Martinez", "Lopez", "Wilson", "Anderson", "Tanaka", "Okafor", "Novak", "Kowalski", "Singh", "Rossi"}
+	streets    = []string{"Main St", "Oak Ave", "Maple Dr", "Cedar Ln", "Park Rd", "Elm St", "Lake View", "Hillside Ave", "River Rd", "Sunset Blvd"}
+	cities     = []string{"Springfield", "Riverside", "Franklin", "Greenville", "Bristol", "Fairview", "Salem", "Madison", "Georgetown", "Arlington"}
+	countries  = []string{"United States", "Canada", "Germany", "France", "Japan", "Brazil", "Australia", "India", "Spain", "Netherlands"}
+	companies  = []string{"Acme", "Globex", "Initech", "Umbrella", "Hooli", "Vehement", "Stark", "Wayne", "Wonka", "Cyberdyne"}
+	suffixes   = []string{"Inc", "LLC", "Group", "Labs", "Systems", "Corp"}
+	domains    = []string{"example.com", "example.org", "example.net", "mail.test"}
+	words      = []string{"alpha", "bravo", "delta", "vector", "orbit", "pixel", "quartz", "nimbus", "falcon", "ember", "lumen", "atlas", "cobalt", "harbor", "summit"}
+)
+
+// pick returns a random element of list
+func pick(list []string) string {
+	return list[rand.IntN(len(list))]
+}
+
+// FirstName returns a random first name
+func FirstName() string { return pick(firstNames) }
+
+// LastName returns a random last name
+func LastName() string { return pick(lastNames) }
+
+// Name returns a random full name
+func Name() string { return FirstName() + " " + LastName() }
+
+// Email returns a random email address
+func Email() string {
+	return fmt.Sprintf("%s.%s%d@%s", strings.ToLower(FirstName()), strings.ToLower(LastName()), rand.IntN(100), pick(domains))
+}
+
+// Phone returns a random phone number
+func Phone() string {
+	return fmt.Sprintf("+1-%03d-%03d-%04d", 200+rand.IntN(800), rand.IntN(1000), rand.IntN(10000))
+}
+
+// Street returns a random street address line
+func Street() string { return fmt.Sprintf("%d %s", 1+rand.IntN(9999), pick(streets)) }
+
+// City returns a random city name
+func City() string { return pick(cities) }
+
+// Country returns a random country name
+func Country() string { return pick(countries) }
+
+// ZipCode returns a random 5-digit postal code
+func ZipCode() string { return fmt.Sprintf("%05d", rand.IntN(100000)) }
+
+// Address returns a random single-line postal address
+func Address() string { return fmt.Sprintf("%s, %s %s, %s", Street(), City(), ZipCode(), Country()) }
+
+// Company returns a random company name
+func Company() string { return pick(companies) + " " + pick(suffixes) }
+
+// Word returns a random word
+func Word() string { return pick(words) }
+
+// Sentence returns a random sentence of 4-9 words
+func Sentence() string {
+	n := 4 + rand.IntN(6)
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = Word()
+	}
+	sentence := strings.Join(parts, " ")
+	return strings.ToUpper(sentence[:1]) + sentence[1:] + "."
+}
+
+// URL returns a random URL
+func URL() string { return fmt.Sprintf("https://%s.%s/%s", Word(), pick(domains), Word()) }
+
+// generators maps template function names (without the "faker." prefix) to generators
+var generators = map[string]func() string{
+	"name":      Name,
+	"firstName": FirstName,
+	"lastName":  LastName,
+	"email":     Email,
+	"phone":     Phone,
+	"street":    Street,
+	"city":      City,
+	"country":   Country,
+	"zipCode":   ZipCode,
+	"address":   Address,
+	"company":   Company,
+	"word":      Word,
+	"sentence":  Sentence,
+	"url":       URL,
+}
+
+// RegisterTemplateFuncs registers {{faker.*}} helpers (e.g. {{faker.email}}) into the registry
+func RegisterTemplateFuncs(registry *engine.FuncRegistry) {
+	for name, gen := range generators {
+		registry.Register("faker."+name, func(args []string) (string, error) {
+			return gen(), nil
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package faker
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxSchemaDepth stops runaway recursion through self-referencing schemas
+	maxSchemaDepth = 12
+	// defaultArrayItems is how many items are generated when the schema does not say
+	defaultArrayItems = 2
+)
+
+// GenerateSampleBody builds a realistic JSON document from a JSON Schema and returns it indented
+func GenerateSampleBody(schemaJSON string) (string, error) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(schemaJSON), &schema); err != nil {
+		return "", fmt.Errorf("failed to parse JSON schema: %w", err)
+	}
+
+	g := &schemaGenerator{root: schema}
+	value := g.generate(schema, "", 0)
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal sample body: %w", err)
+	}
+	return string(data), nil
+}
+
+// schemaGenerator walks a schema, resolving local $refs against root
+type schemaGenerator struct {
+	root map[string]interface{}
+}
+
+// generate produces a value for schema; name is the property name, used to pick realistic strings
+func (g *schemaGenerator) generate(schema map[string]interface{}, name string, depth int) interface{} {
+	if depth > maxSchemaDepth || schema == nil {
+		return nil
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		return g.generate(g.resolveRef(ref), name, depth+1)
+	}
+
+	// Explicit values always win
+	if value, ok := schema["const"]; ok {
+		return value
+	}
+	if examples, ok := schema["examples"].([]interface{}); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	if value, ok := schema["example"]; ok {
+		return value
+	}
+	if value, ok := schema["default"]; ok {
+		return value
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[rand.IntN(len(enum))]
+	}
+
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if options, ok := schema[key].([]interface{}); ok && len(options) > 0 {
+			if option, ok := options[0].(map[string]interface{}); ok {
+				return g.generate(option, name, depth+1)
+			}
+		}
+	}
+	if parts, ok := schema["allOf"].([]interface{}); ok && len(parts) > 0 {
+		return g.generate(g.mergeAllOf(parts), name, depth+1)
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		return g.generateObject(schema, depth)
+	case "array":
+		return g.generateArray(schema, name, depth)
+	case "integer":
+		min, max := numberBounds(schema, 1, 1000)
+		return int64(math.Ceil(min)) + rand.Int64N(int64(math.Floor(max)-math.Ceil(min))+1)
+	case "number":
+		min, max := numberBounds(schema, 0, 1000)
+		return math.Round((min+rand.Float64()*(max-min))*100) / 100
+	case "boolean":
+		return rand.IntN(2) == 1
+	case "null":
+		return nil
+	default:
+		return generateString(schema, name)
+	}
+}
+
+// generateObject fills every declared property
+func (g *schemaGenerator) generateObject(schema map[string]interface{}, depth int) interface{} {
+	result := make(map[string]interface{})
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	names := make([]string, 0, len(properties))
+	for propName := range properties {
+		names = append(names, propName)
+	}
+	sort.Strings(names)
+
+	for _, propName := range names {
+		if propSchema, ok := properties[propName].(map[string]interface{}); ok {
+			result[propName] = g.generate(propSchema, propName, depth+1)
+		}
+	}
+	return result
+}
+
+// generateArray produces minItems (or a default number of) items
+func (g *schemaGenerator) generateArray(schema map[string]interface{}, name string, depth int) interface{} {
+	count := defaultArrayItems
+	if minItems, ok := schema["minItems"].(float64); ok && int(minItems) > count {
+		count = int(minItems)
+	}
+	if maxItems, ok := schema["maxItems"].(float64); ok && int(maxItems) < count {
+		count = int(maxItems)
+	}
+
+	items, _ := schema["items"].(map[string]interface{})
+	result := make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		result = append(result, g.generate(items, strings.TrimSuffix(name, "s"), depth+1))
+	}
+	return result
+}
+
+// resolveRef resolves "#/definitions/x" and "#/$defs/x" style local references
+func (g *schemaGenerator) resolveRef(ref string) map[string]interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var current interface{} = g.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		node, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = node[part]
+	}
+	resolved, _ := current.(map[string]interface{})
+	return resolved
+}
+
+// mergeAllOf combines the properties of all subschemas into one object schema
+func (g *schemaGenerator) mergeAllOf(parts []interface{}) map[string]interface{} {
+	merged := map[string]interface{}{"type": "object"}
+	properties := make(map[string]interface{})
+	for _, part := range parts {
+		schema, ok := part.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if ref, ok := schema["$ref"].(string); ok {
+			schema = g.resolveRef(ref)
+		}
+		if props, ok := schema["properties"].(map[string]interface{}); ok {
+			for k, v := range props {
+				properties[k] = v
+			}
+		}
+	}
+	merged["properties"] = properties
+	return merged
+}
+
+// schemaType returns the schema type, inferring object/array from keywords when missing
+func schemaType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		// e.g. ["string", "null"]: use the first non-null type
+		for _, v := range t {
+			if s, ok := v.(string); ok && s != "null" {
+				return s
+			}
+		}
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	if _, ok := schema["items"]; ok {
+		return "array"
+	}
+	return "string"
+}
+
+// numberBounds reads minimum/maximum with the given defaults
+func numberBounds(schema map[string]interface{}, defMin, defMax float64) (float64, float64) {
+	min, max := defMin, defMax
+	if v, ok := schema["minimum"].(float64); ok {
+		min = v
+		if max < min {
+			max = min + defMax
+		}
+	}
+	if v, ok := schema["maximum"].(float64); ok {
+		max = v
+		if min > max {
+			min = max
+		}
+	}
+	return min, max
+}
+
+// generateString picks a value based on format, then on the property name, then a random word
+func generateString(schema map[string]interface{}, name string) string {
+	format, _ := schema["format"].(string)
+	switch format {
+	case "email":
+		return Email()
+	case "uuid":
+		return uuid.New().String()
+	case "date-time":
+		return time.Now().UTC().Add(-time.Duration(rand.IntN(720)) * time.Hour).Format(time.RFC3339)
+	case "date":
+		return time.Now().UTC().AddDate(0, 0, -rand.IntN(365)).Format("2006-01-02")
+	case "uri", "url":
+		return URL()
+	case "ipv4":
+		return fmt.Sprintf("192.0.2.%d", 1+rand.IntN(254))
+	}
+
+	lower := strings.ToLower(name)
+	var value string
+	switch {
+	case strings.Contains(lower, "email"):
+		value = Email()
+	case lower == "firstname" || lower == "first_name":
+		value = FirstName()
+	case lower == "lastname" || lower == "last_name":
+		value = LastName()
+	case strings.Contains(lower, "company"):
+		value = Company()
+	case strings.Contains(lower, "name"):
+		value = Name()
+	case strings.Contains(lower, "phone"):
+		value = Phone()
+	case strings.Contains(lower, "city"):
+		value = City()
+	case strings.Contains(lower, "country"):
+		value = Country()
+	case strings.Contains(lower, "zip") || strings.Contains(lower, "postal"):
+		value = ZipCode()
+	case strings.Contains(lower, "address") || strings.Contains(lower, "street"):
+		value = Street()
+	case strings.Contains(lower, "url") || strings.Contains(lower, "website"):
+		value = URL()
+	case lower == "id" || strings.HasSuffix(lower, "id"):
+		value = uuid.New().String()
+	case strings.Contains(lower, "description") || strings.Contains(lower, "comment"):
+		value = Sentence()
+	default:
+		value = Word()
+	}
+
+	if maxLength, ok := schema["maxLength"].(float64); ok && len(value) > int(maxLength) {
+		value = value[:int(maxLength)]
+	}
+	if minLength, ok := schema["minLength"].(float64); ok {
+		for len(value) < int(minLength) {
+			value += Word()
+		}
+	}
+	return value
+}
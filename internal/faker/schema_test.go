@@ -0,0 +1,45 @@
+package faker
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGenerateSampleBody(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"properties": {
+			"email": {"type": "string"},
+			"age": {"type": "integer", "minimum": 18, "maximum": 18},
+			"role": {"enum": ["admin"]},
+			"tags": {"type": "array", "items": {"type": "string"}, "minItems": 3},
+			"address": {"$ref": "#/$defs/address"}
+		},
+		"$defs": {
+			"address": {"type": "object", "properties": {"city": {"type": "string"}}}
+		}
+	}`
+
+	body, err := GenerateSampleBody(schema)
+	if err != nil {
+		t.Fatalf("GenerateSampleBody() error = %v", err)
+	}
+
+	var got struct {
+		Email   string   `json:"email"`
+		Age     int      `json:"age"`
+		Role    string   `json:"role"`
+		Tags    []string `json:"tags"`
+		Address struct {
+			City string `json:"city"`
+		} `json:"address"`
+	}
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatalf("generated body is not valid JSON: %v\n%s", err, body)
+	}
+
+	if !strings.Contains(got.Email, "@") || got.Age != 18 || got.Role != "admin" || len(got.Tags) != 3 || got.Address.City == "" {
+		t.Errorf("GenerateSampleBody() = %s", body)
+	}
+}
@@ -0,0 +1,385 @@
+// Package fuzz mutates the inputs of a request (query parameters and the leaves of a JSON body)
+// with boundary values, long strings, injections and type confusion payloads, sends the mutations
+// through the engine and reports anomalous responses: server errors, failed requests, slow
+// responses and payloads reflected in the body.
+package fuzz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/requests"
+	"paperbox/internal/engine"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxCases bounds how many mutations one fuzz run sends
+	MaxCases = 2000
+	// MaxConcurrency bounds how many mutations are in flight at once
+	MaxConcurrency = 16
+	// DefaultConcurrency is used when the options do not set one
+	DefaultConcurrency = 4
+
+	// slowRatio and slowMargin make a response slow when it takes longer than both slowRatio times
+	// the baseline and the baseline plus slowMargin
+	slowRatio  = 5
+	slowMargin = time.Second
+	// minReflected is the shortest payload looked for in response bodies
+	minReflected = 6
+	// maxShownPayload is how much of a payload a case shows
+	maxShownPayload = 80
+)
+
+// Target kinds
+const (
+	TargetQuery = "query"
+	TargetBody  = "body"
+)
+
+// Anomaly kinds
+const (
+	AnomalyServerError = "serverError"
+	AnomalyFailed      = "failed"
+	AnomalySlow        = "slow"
+	AnomalyReflected   = "reflected"
+)
+
+// Target is a field of a request to mutate: a query parameter by key, or a JSON body leaf by path
+// such as "$.user.tags[0]"
+type Target struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// Options select what a fuzz run mutates
+type Options struct {
+	Targets     []Target `json:"targets"` // Empty fuzzes every target of the request
+	Categories  []string `json:"categories,omitempty"`
+	Concurrency int      `json:"concurrency"`
+}
+
+// Case is the outcome of one mutation
+type Case struct {
+	Target      Target   `json:"target"`
+	Category    string   `json:"category"`
+	Payload     string   `json:"payload"` // Shortened for display
+	ExecutionID string   `json:"executionId"`
+	Status      int      `json:"status"`
+	DurationMs  int64    `json:"durationMs"`
+	Error       string   `json:"error,omitempty"`
+	Anomalies   []string `json:"anomalies,omitempty"`
+}
+
+// Report is the outcome of fuzzing a request. Cases follow the order of targets and payloads.
+type Report struct {
+	ID         string              `json:"id"`
+	RequestID  string              `json:"requestId"`
+	StartedAt  time.Time           `json:"startedAt"`
+	DurationMs int64               `json:"durationMs"`
+	Baseline   *engine.Execution   `json:"baseline"`
+	Cases      []Case              `json:"cases"`
+	Anomalies  int                 `json:"anomalies"` // Cases with at least one anomaly
+	Executions []*engine.Execution `json:"executions"`
+	Cancelled  bool                `json:"cancelled,omitempty"`
+}
+
+// mutation is a request item with one field replaced
+type mutation struct {
+	target  Target
+	payload Payload
+	item    requests.Item
+}
+
+// Targets lists the fields of a request that can be fuzzed: its enabled query parameters, then the
+// leaves of its body when it is a JSON object or array
+func Targets(item requests.Item) []Target {
+	targets := []Target{}
+	seen := make(map[string]bool)
+	for _, p := range item.QueryParams {
+		if !p.Disabled && !seen[p.Key] {
+			seen[p.Key] = true
+			targets = append(targets, Target{Kind: TargetQuery, Name: p.Key})
+		}
+	}
+	if body, ok := jsonBody(item); ok {
+		walkLeaves(body, "$", func(path string) {
+			targets = append(targets, Target{Kind: TargetBody, Name: path})
+		})
+	}
+	return targets
+}
+
+// Run sends the baseline request, then every mutation of the selected targets, and reports the
+// anomalous responses. A mutation is slow compared with the baseline's duration.
+func Run(ctx context.Context, e *engine.Engine, src engine.Sources, requestID string, opts Options) (*Report, error) {
+	item, exists := src.Requests.Values[requestID]
+	if !exists || item.Type != requests.ItemTypeRequest {
+		return nil, apperrors.NotFoundf("request not found")
+	}
+	if opts.Concurrency > MaxConcurrency {
+		return nil, apperrors.Invalidf("a fuzz run can send at most %d requests at once", MaxConcurrency)
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	mutations, err := mutate(item, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{ID: uuid.New().String(), RequestID: requestID, StartedAt: time.Now()}
+	baseline, err := e.Run(ctx, src, requestID)
+	if err != nil {
+		return nil, err
+	}
+	report.Baseline = baseline
+	report.Executions = append(report.Executions, baseline)
+
+	executions := make([]*engine.Execution, len(mutations))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, m := range mutations {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			report.Cancelled = true
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			executions[i] = send(ctx, e, src, requestID, m.item)
+		}()
+	}
+	wg.Wait()
+
+	for i, exec := range executions {
+		if exec == nil {
+			continue
+		}
+		m := mutations[i]
+		c := Case{
+			Target:      m.target,
+			Category:    m.payload.Category,
+			Payload:     shorten(m.payload.Value),
+			ExecutionID: exec.ID,
+			Status:      exec.Status,
+			DurationMs:  exec.DurationMs,
+			Error:       exec.Error,
+			Anomalies:   anomalies(exec, baseline, m.payload),
+		}
+		if len(c.Anomalies) > 0 {
+			report.Anomalies++
+		}
+		report.Cases = append(report.Cases, c)
+		report.Executions = append(report.Executions, exec)
+	}
+	report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+	return report, nil
+}
+
+// send runs a mutated request item in place of the stored one
+func send(ctx context.Context, e *engine.Engine, src engine.Sources, requestID string, item requests.Item) *engine.Execution {
+	cfg := *src.Requests
+	cfg.Values = maps.Clone(cfg.Values)
+	cfg.Values[requestID] = item
+	src.Requests = &cfg
+	exec, err := e.Run(ctx, src, requestID)
+	if err != nil {
+		exec = &engine.Execution{ID: uuid.New().String(), RequestID: requestID, StartedAt: time.Now(), Error: err.Error()}
+	}
+	return exec
+}
+
+// anomalies lists what is anomalous about the response to a mutation
+func anomalies(exec *engine.Execution, baseline *engine.Execution, payload Payload) []string {
+	var found []string
+	switch {
+	case exec.Status >= 500:
+		found = append(found, AnomalyServerError)
+	case exec.Status == 0:
+		found = append(found, AnomalyFailed)
+	}
+	if limit := max(baseline.DurationMs*slowRatio, baseline.DurationMs+slowMargin.Milliseconds()); exec.DurationMs > limit {
+		found = append(found, AnomalySlow)
+	}
+	if payload.Category == Injection && len(payload.Value) >= minReflected && strings.Contains(exec.Body, payload.Value) {
+		found = append(found, AnomalyReflected)
+	}
+	return found
+}
+
+// mutate builds every mutation of the selected targets with the selected payloads
+func mutate(item requests.Item, opts Options) ([]mutation, error) {
+	available := Targets(item)
+	targets := opts.Targets
+	if len(targets) == 0 {
+		targets = available
+	}
+	for _, t := range targets {
+		if !containsTarget(available, t) {
+			return nil, apperrors.Invalidf("%s %q cannot be fuzzed", t.Kind, t.Name)
+		}
+	}
+	payloads := DefaultPayloads
+	if len(opts.Categories) > 0 {
+		payloads = nil
+		for _, p := range DefaultPayloads {
+			for _, category := range opts.Categories {
+				if p.Category == category {
+					payloads = append(payloads, p)
+				}
+			}
+		}
+	}
+	if len(targets) == 0 || len(payloads) == 0 {
+		return nil, apperrors.Invalidf("nothing to fuzz: the request has no query parameters or JSON body fields")
+	}
+	if n := len(targets) * len(payloads); n > MaxCases {
+		return nil, apperrors.Invalidf("a fuzz run can send at most %d requests, %d selected", MaxCases, n)
+	}
+
+	var mutations []mutation
+	for _, target := range targets {
+		for _, payload := range payloads {
+			mutated, err := apply(item, target, payload)
+			if err != nil {
+				return nil, err
+			}
+			mutations = append(mutations, mutation{target: target, payload: payload, item: mutated})
+		}
+	}
+	return mutations, nil
+}
+
+// apply returns a copy of item with the target replaced by the payload
+func apply(item requests.Item, target Target, payload Payload) (requests.Item, error) {
+	if target.Kind == TargetQuery {
+		params := append([]requests.Param{}, item.QueryParams...)
+		for i := range params {
+			if params[i].Key == target.Name && !params[i].Disabled {
+				params[i].Value = payload.Value
+			}
+		}
+		item.QueryParams = params
+		return item, nil
+	}
+
+	body, _ := jsonBody(item)
+	var replaced bool
+	body = replaceLeaf(body, "$", target.Name, json.RawMessage(payload.JSON), &replaced)
+	if !replaced {
+		return item, apperrors.Invalidf("body field %s cannot be fuzzed", target.Name)
+	}
+	// Injections are sent as written rather than with <, > and & escaped
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(body); err != nil {
+		return item, fmt.Errorf("failed to mutate %s: %w", target.Name, err)
+	}
+	item.Body = strings.TrimSuffix(buf.String(), "\n")
+	return item, nil
+}
+
+// jsonBody decodes the body of a request when it is a JSON object or array
+func jsonBody(item requests.Item) (any, bool) {
+	trimmed := strings.TrimSpace(item.Body)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return nil, false
+	}
+	var body any
+	if err := json.Unmarshal([]byte(trimmed), &body); err != nil {
+		return nil, false
+	}
+	return body, true
+}
+
+// walkLeaves calls visit with the path of every scalar in a decoded JSON value, object keys sorted
+func walkLeaves(value any, path string, visit func(path string)) {
+	switch v := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			walkLeaves(v[key], childPath(path, key), visit)
+		}
+	case []any:
+		for i, element := range v {
+			walkLeaves(element, path+"["+strconv.Itoa(i)+"]", visit)
+		}
+	default:
+		visit(path)
+	}
+}
+
+// replaceLeaf returns value with the scalar at target replaced
+func replaceLeaf(value any, path string, target string, replacement json.RawMessage, replaced *bool) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			v[key] = replaceLeaf(child, childPath(path, key), target, replacement, replaced)
+		}
+	case []any:
+		for i, element := range v {
+			v[i] = replaceLeaf(element, path+"["+strconv.Itoa(i)+"]", target, replacement, replaced)
+		}
+	default:
+		if path == target {
+			*replaced = true
+			return replacement
+		}
+	}
+	return value
+}
+
+// childPath appends an object key to a path, bracketed when it is not a plain identifier
+func childPath(path string, key string) string {
+	plain := key != ""
+	for i, r := range key {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return path + "." + key
+	}
+	return path + "[" + strconv.Quote(key) + "]"
+}
+
+// containsTarget reports whether targets includes t
+func containsTarget(targets []Target, t Target) bool {
+	for _, candidate := range targets {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
+
+// shorten cuts a payload for display
+func shorten(value string) string {
+	if len(value) <= maxShownPayload {
+		return value
+	}
+	return fmt.Sprintf("%s… (%d bytes)", value[:maxShownPayload], len(value))
+}
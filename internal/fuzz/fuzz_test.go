@@ -0,0 +1,102 @@
+package fuzz
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"paperbox/internal/config/requests"
+	"paperbox/internal/engine"
+)
+
+func TestTargets(t *testing.T) {
+	item := requests.Item{
+		QueryParams: []requests.Param{{Key: "q"}, {Key: "page", Disabled: true}, {Key: "q"}},
+		Body:        `{"user": {"name": "ada", "tags": ["a", 1]}, "dry-run": true, "list": []}`,
+	}
+	want := []Target{
+		{TargetQuery, "q"},
+		{TargetBody, `$["dry-run"]`},
+		{TargetBody, "$.user.name"},
+		{TargetBody, "$.user.tags[0]"},
+		{TargetBody, "$.user.tags[1]"},
+	}
+	got := Targets(item)
+	if len(got) != len(want) {
+		t.Fatalf("Targets() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Targets()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	mutated, err := apply(item, Target{TargetBody, "$.user.tags[1]"}, str(Injection, "<b>"))
+	if err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+	var body map[string]any
+	if err := json.Unmarshal([]byte(mutated.Body), &body); err != nil || body["user"].(map[string]any)["tags"].([]any)[1] != "<b>" {
+		t.Errorf("apply() body = %s", mutated.Body)
+	}
+	if item.Body == mutated.Body {
+		t.Error("apply() changed the original item")
+	}
+}
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Age json.Number `json:"age"`
+		}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		if err := decoder.Decode(&body); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("results for " + r.URL.Query().Get("q")))
+	}))
+	defer server.Close()
+
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"search": {
+				Type: requests.ItemTypeRequest, Name: "Search", Method: "POST", Path: "/search",
+				QueryParams: []requests.Param{{Key: "q", Value: "books"}},
+				Body:        `{"age": 30}`,
+			},
+		},
+	}
+	src := engine.Sources{Requests: cfg, UserBaseURL: server.URL}
+	e := engine.NewWithClient(server.Client())
+
+	report, err := Run(context.Background(), e, src, "search", Options{Categories: []string{Injection, TypeConfusion}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if report.Baseline.Status != http.StatusOK || len(report.Cases) != 2*(9+6) || len(report.Executions) != len(report.Cases)+1 {
+		t.Fatalf("Run() baseline %d, %d cases", report.Baseline.Status, len(report.Cases))
+	}
+
+	kinds := make(map[string]int)
+	for _, c := range report.Cases {
+		for _, a := range c.Anomalies {
+			kinds[c.Target.Kind+":"+a]++
+		}
+	}
+	// Every injection is echoed back; a string where the body has a number breaks the server
+	if kinds["query:"+AnomalyReflected] != 9 || kinds["body:"+AnomalyServerError] == 0 || kinds["query:"+AnomalyServerError] != 0 {
+		t.Errorf("anomalies = %v", kinds)
+	}
+	if cfg.Values["search"].Body != `{"age": 30}` {
+		t.Error("Run() changed the stored request")
+	}
+
+	if _, err := Run(context.Background(), e, src, "search", Options{Targets: []Target{{TargetBody, "$.missing"}}}); err == nil {
+		t.Error("Run() with an unknown target should fail")
+	}
+}
@@ -0,0 +1,87 @@
+package fuzz
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Payload categories
+const (
+	Boundary      = "boundary"
+	LongString    = "long"
+	Injection     = "injection"
+	TypeConfusion = "type"
+)
+
+// Payload is a value a field is replaced with. JSON is how it is written into a JSON body; query
+// parameters get Value.
+type Payload struct {
+	Category string `json:"category"`
+	Value    string `json:"value"`
+	JSON     string `json:"-"`
+}
+
+// str is a payload sent as a JSON string in bodies
+func str(category, value string) Payload {
+	return Payload{Category: category, Value: value, JSON: quote(value)}
+}
+
+// raw is a payload sent as a bare JSON value in bodies
+func raw(category, value string) Payload {
+	return Payload{Category: category, Value: value, JSON: value}
+}
+
+// quote writes a JSON string without escaping HTML characters, so injections arrive as written
+func quote(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04x`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// DefaultPayloads are the values every field is fuzzed with
+var DefaultPayloads = []Payload{
+	raw(Boundary, "0"),
+	raw(Boundary, "-1"),
+	raw(Boundary, "2147483648"),
+	raw(Boundary, "9223372036854775808"),
+	raw(Boundary, "1e309"),
+	str(Boundary, ""),
+	str(Boundary, " "),
+	str(Boundary, "\x00"),
+	str(Boundary, "Ω≈ç√∫˜µ≤≥÷ 😀"),
+
+	str(LongString, strings.Repeat("A", 1024)),
+	str(LongString, strings.Repeat("A", 65536)),
+	str(LongString, strings.Repeat("%n%s", 256)),
+
+	str(Injection, "' OR '1'='1"),
+	str(Injection, `"; DROP TABLE users; --`),
+	str(Injection, "<script>alert(1337)</script>"),
+	str(Injection, `"><img src=x onerror=alert(1337)>`),
+	str(Injection, "{{7*7}}${7*7}<%= 7*7 %>"),
+	str(Injection, "../../../../etc/passwd"),
+	str(Injection, "; cat /etc/passwd"),
+	str(Injection, "${jndi:ldap://127.0.0.1/a}"),
+	str(Injection, `{"$gt": ""}`),
+
+	raw(TypeConfusion, "null"),
+	raw(TypeConfusion, "true"),
+	raw(TypeConfusion, "[]"),
+	raw(TypeConfusion, "{}"),
+	str(TypeConfusion, "123"),
+	raw(TypeConfusion, "1.5"),
+}
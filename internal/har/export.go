@@ -0,0 +1,120 @@
+package har
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Exchange is a source-agnostic request/response pair that can be exported as a HAR entry
+type Exchange struct {
+	Method          string
+	URL             string
+	Headers         map[string][]string
+	Body            string
+	Status          int
+	ResponseHeaders map[string][]string
+	ResponseBody    string
+	StartedAt       time.Time
+	Duration        time.Duration
+}
+
+// Export builds a HAR document from the given exchanges
+func Export(exchanges []Exchange, creatorVersion string) File {
+	entries := make([]Entry, 0, len(exchanges))
+	for _, ex := range exchanges {
+		entries = append(entries, exchangeToEntry(ex))
+	}
+
+	return File{
+		Log: Log{
+			Version: Version,
+			Creator: Creator{Name: "paperbox", Version: creatorVersion},
+			Entries: entries,
+		},
+	}
+}
+
+// Marshal encodes a HAR document with indentation
+func Marshal(file File) ([]byte, error) {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal HAR file: %w", err)
+	}
+	return data, nil
+}
+
+// exchangeToEntry maps one exchange onto a HAR entry
+func exchangeToEntry(ex Exchange) Entry {
+	ms := float64(ex.Duration) / float64(time.Millisecond)
+
+	entry := Entry{
+		StartedDateTime: ex.StartedAt.Format(time.RFC3339Nano),
+		Time:            ms,
+		Request: Request{
+			Method:      ex.Method,
+			URL:         ex.URL,
+			HTTPVersion: "HTTP/1.1",
+			Headers:     nameValues(ex.Headers),
+			QueryString: queryString(ex.URL),
+			Cookies:     []NameValue{},
+			HeadersSize: -1,
+			BodySize:    len(ex.Body),
+		},
+		Response: Response{
+			Status:      ex.Status,
+			StatusText:  http.StatusText(ex.Status),
+			HTTPVersion: "HTTP/1.1",
+			Headers:     nameValues(ex.ResponseHeaders),
+			Cookies:     []NameValue{},
+			Content: Content{
+				Size:     len(ex.ResponseBody),
+				MimeType: http.Header(ex.ResponseHeaders).Get("Content-Type"),
+				Text:     ex.ResponseBody,
+			},
+			HeadersSize: -1,
+			BodySize:    len(ex.ResponseBody),
+		},
+		// Only the total is known; attribute it to waiting for the server
+		Timings: Timings{Blocked: -1, DNS: -1, Connect: -1, Send: 0, Wait: ms, Receive: 0, SSL: -1},
+	}
+
+	if ex.Body != "" {
+		entry.Request.PostData = &PostData{
+			MimeType: http.Header(ex.Headers).Get("Content-Type"),
+			Text:     ex.Body,
+		}
+	}
+
+	return entry
+}
+
+// nameValues flattens a header map into sorted HAR pairs
+func nameValues(headers map[string][]string) []NameValue {
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := []NameValue{}
+	for _, key := range keys {
+		for _, value := range headers[key] {
+			result = append(result, NameValue{Name: key, Value: value})
+		}
+	}
+	return result
+}
+
+// queryString extracts query parameters from a URL
+func queryString(rawURL string) []NameValue {
+	result := []NameValue{}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return result
+	}
+	return append(result, nameValues(parsed.Query())...)
+}
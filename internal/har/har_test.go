@@ -0,0 +1,132 @@
+package har
+
+import (
+	"testing"
+	"time"
+
+	"paperbox/internal/config/requests"
+)
+
+func TestToNodesDedupesByMethodAndURL(t *testing.T) {
+	file := &File{Log: Log{Entries: []Entry{
+		{Request: Request{Method: "get", URL: "https://example.com/users"}},
+		{Request: Request{Method: "GET", URL: "https://example.com/users"}},
+		{Request: Request{Method: "POST", URL: "https://example.com/users"}},
+		{Request: Request{Method: "GET", URL: "https://example.com/users?page=2"}},
+		{Request: Request{Method: "", URL: "https://example.com/users"}},
+		{Request: Request{Method: "GET", URL: ""}},
+	}}}
+
+	nodes := ToNodes(file)
+	want := []string{"GET https://example.com/users", "POST https://example.com/users", "GET https://example.com/users?page=2"}
+	if len(nodes) != len(want) {
+		t.Fatalf("ToNodes() returned %d nodes, want %d", len(nodes), len(want))
+	}
+	for i, node := range nodes {
+		if got := node.Item.Method + " " + node.Item.Path; got != want[i] {
+			t.Errorf("node %d = %q, want %q", i, got, want[i])
+		}
+	}
+	if nodes[0].Item.Name != "GET /users" {
+		t.Errorf("Name = %q, want %q", nodes[0].Item.Name, "GET /users")
+	}
+}
+
+func TestToNodesSkipsTransportHeaders(t *testing.T) {
+	file := &File{Log: Log{Entries: []Entry{{Request: Request{
+		Method: "GET",
+		URL:    "https://example.com/",
+		Headers: []NameValue{
+			{Name: ":authority", Value: "example.com"},
+			{Name: ":path", Value: "/"},
+			{Name: "Connection", Value: "keep-alive"},
+			{Name: "Keep-Alive", Value: "timeout=5"},
+			{Name: "Transfer-Encoding", Value: "chunked"},
+			{Name: "TE", Value: "trailers"},
+			{Name: "Upgrade", Value: "h2c"},
+			{Name: "Host", Value: "example.com"},
+			{Name: "Content-Length", Value: "0"},
+			{Name: "Cookie", Value: "session=1"},
+			{Name: "Accept", Value: "application/json"},
+			{Name: "X-Trace", Value: "abc"},
+		},
+	}}}}}
+
+	nodes := ToNodes(file)
+	if len(nodes) != 1 {
+		t.Fatalf("ToNodes() returned %d nodes, want 1", len(nodes))
+	}
+	want := []requests.Header{{Key: "Accept", Value: "application/json"}, {Key: "X-Trace", Value: "abc"}}
+	headers := nodes[0].Item.Headers
+	if len(headers) != len(want) {
+		t.Fatalf("Headers = %+v, want %+v", headers, want)
+	}
+	for i := range want {
+		if headers[i] != want[i] {
+			t.Errorf("Headers[%d] = %+v, want %+v", i, headers[i], want[i])
+		}
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	exchanges := []Exchange{
+		{
+			Method:          "POST",
+			URL:             "https://example.com/login?next=%2Fhome",
+			Headers:         map[string][]string{"Content-Type": {"application/x-www-form-urlencoded"}, "X-Trace": {"abc"}},
+			Body:            "user=alice&remember=1",
+			Status:          200,
+			ResponseHeaders: map[string][]string{"Content-Type": {"application/json"}},
+			ResponseBody:    `{"ok":true}`,
+			StartedAt:       started,
+			Duration:        150 * time.Millisecond,
+		},
+		{Method: "GET", URL: "https://example.com/users", Status: 404, StartedAt: started},
+	}
+
+	data, err := Marshal(Export(exchanges, "1.2.3"))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	file, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if file.Log.Version != Version || file.Log.Creator.Version != "1.2.3" || len(file.Log.Entries) != 2 {
+		t.Fatalf("Log = %+v, want version %s by 1.2.3 with 2 entries", file.Log, Version)
+	}
+	entry := file.Log.Entries[0]
+	if entry.StartedDateTime != started.Format(time.RFC3339Nano) || entry.Time != 150 {
+		t.Errorf("entry time = %s %v, want %s 150", entry.StartedDateTime, entry.Time, started.Format(time.RFC3339Nano))
+	}
+	if len(entry.Request.QueryString) != 1 || entry.Request.QueryString[0] != (NameValue{Name: "next", Value: "/home"}) {
+		t.Errorf("QueryString = %+v, want next=/home", entry.Request.QueryString)
+	}
+	if entry.Request.PostData == nil || entry.Request.PostData.MimeType != "application/x-www-form-urlencoded" {
+		t.Errorf("PostData = %+v, want the form body", entry.Request.PostData)
+	}
+	content := entry.Response.Content
+	if entry.Response.Status != 200 || entry.Response.StatusText != "OK" || content.MimeType != "application/json" || content.Text != `{"ok":true}` {
+		t.Errorf("Response = %+v, want 200 OK with the JSON body", entry.Response)
+	}
+	if file.Log.Entries[1].Request.PostData != nil {
+		t.Errorf("PostData of a request without body = %+v, want nil", file.Log.Entries[1].Request.PostData)
+	}
+
+	nodes := ToNodes(file)
+	if len(nodes) != 2 {
+		t.Fatalf("ToNodes() returned %d nodes, want 2", len(nodes))
+	}
+	item := nodes[0].Item
+	if item.Type != requests.ItemTypeRequest || item.Method != "POST" || item.Path != exchanges[0].URL || item.Name != "POST /login" {
+		t.Errorf("item = %s %s %q, want the exported request", item.Method, item.Path, item.Name)
+	}
+	if len(item.Headers) != 2 || item.Headers[0].Key != "Content-Type" || item.Headers[1] != (requests.Header{Key: "X-Trace", Value: "abc"}) {
+		t.Errorf("Headers = %+v, want Content-Type and X-Trace", item.Headers)
+	}
+	if item.Body != "" || len(item.FormFields) != 2 || item.FormFields[0].Key != "user" || item.FormFields[0].Value != "alice" {
+		t.Errorf("Body = %q, FormFields = %+v, want the form body as fields", item.Body, item.FormFields)
+	}
+}
@@ -0,0 +1,102 @@
+package har
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/requests"
+)
+
+// skippedHeaders are browser/transport-managed headers, the hop-by-hop ones included, that are not
+// worth persisting on a request
+var skippedHeaders = map[string]bool{
+	"content-length":    true,
+	"host":              true,
+	"accept-encoding":   true,
+	"connection":        true,
+	"cookie":            true,
+	"keep-alive":        true,
+	"proxy-connection":  true,
+	"te":                true,
+	"trailer":           true,
+	"transfer-encoding": true,
+	"upgrade":           true,
+}
+
+// ParseFile reads a HAR file from disk
+func ParseFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to read HAR file")
+	}
+	return Parse(data)
+}
+
+// Parse decodes a HAR document
+func Parse(data []byte) (*File, error) {
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse HAR file: %w", err)
+	}
+	return &file, nil
+}
+
+// ToNodes converts HAR entries into request nodes, keeping only the first entry for each method+URL pair
+func ToNodes(file *File) []requests.Node {
+	seen := make(map[string]bool)
+	nodes := []requests.Node{}
+
+	for _, entry := range file.Log.Entries {
+		method := strings.ToUpper(entry.Request.Method)
+		key := method + " " + entry.Request.URL
+		if method == "" || entry.Request.URL == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		nodes = append(nodes, requests.Node{Item: entryToItem(entry)})
+	}
+
+	return nodes
+}
+
+// entryToItem maps a HAR request onto a paperbox request item
+func entryToItem(entry Entry) requests.Item {
+	req := entry.Request
+	item := requests.Item{
+		Type:   requests.ItemTypeRequest,
+		Name:   entryName(req),
+		Method: strings.ToUpper(req.Method),
+		Path:   req.URL,
+	}
+
+	for _, header := range req.Headers {
+		// HTTP/2 pseudo headers (":authority", ":path") are not real headers
+		if strings.HasPrefix(header.Name, ":") || skippedHeaders[strings.ToLower(header.Name)] {
+			continue
+		}
+		item.Headers = append(item.Headers, requests.Header{Key: header.Name, Value: header.Value})
+	}
+
+	if req.PostData != nil {
+		item.Body = req.PostData.Text
+		if fields, ok := requests.ParseFormBody(item); ok {
+			item.Body, item.FormFields = "", fields
+		}
+	}
+
+	return item
+}
+
+// entryName builds a readable default name such as "GET /api/users"
+func entryName(req Request) string {
+	name := req.URL
+	if parsed, err := url.Parse(req.URL); err == nil && parsed.Path != "" {
+		name = parsed.Path
+	}
+	return strings.ToUpper(req.Method) + " " + name
+}
@@ -0,0 +1,232 @@
+// Package health checks whether the servers of an environment are reachable. The health endpoint
+// of an environment is requested on its base URL and on every distinct folder base URL, through the
+// engine so host overrides and TLS settings apply.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"paperbox/internal/config/requests"
+	"paperbox/internal/engine"
+)
+
+const (
+	// EventHealth is emitted with a Report after every check
+	EventHealth = "env:health"
+
+	// DefaultInterval is the delay between the periodic checks of the active environment
+	DefaultInterval = time.Minute
+	// CheckTimeout bounds a single health request
+	CheckTimeout = 5 * time.Second
+)
+
+// Status of a target or a whole environment
+const (
+	StatusUp       = "up"       // Answered with a 2xx or 3xx status
+	StatusDegraded = "degraded" // Answered with another status
+	StatusDown     = "down"     // Did not answer
+)
+
+// TargetStatus is the outcome of requesting the health endpoint of one base URL
+type TargetStatus struct {
+	Name       string `json:"name"` // "Environment" or the name of the folder setting the base URL
+	URL        string `json:"url"`
+	Status     string `json:"status"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	LatencyMs  int64  `json:"latencyMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Report is the latest health of an environment; Status is that of its worst target
+type Report struct {
+	EnvironmentID string         `json:"environmentId"`
+	Status        string         `json:"status"`
+	Targets       []TargetStatus `json:"targets"`
+	CheckedAt     time.Time      `json:"checkedAt"`
+}
+
+// target is a health endpoint to request
+type target struct {
+	name string
+	url  string
+}
+
+// Monitor checks environments, keeps the latest report of each and can check the active one
+// periodically
+type Monitor struct {
+	engine *engine.Engine
+	emit   func(event string, payload interface{})
+
+	mu     sync.Mutex
+	latest map[string]*Report
+	stop   chan struct{}
+}
+
+// NewMonitor creates a monitor sending health requests with e. emit, if not nil, receives the
+// env:health events.
+func NewMonitor(e *engine.Engine, emit func(event string, payload interface{})) *Monitor {
+	return &Monitor{engine: e, emit: emit, latest: make(map[string]*Report)}
+}
+
+// Check requests the health endpoints of the environment in src, caches the report and emits it
+func (m *Monitor) Check(ctx context.Context, envID string, src engine.Sources) *Report {
+	targets := healthTargets(src)
+	report := &Report{EnvironmentID: envID, Status: StatusUp, Targets: make([]TargetStatus, len(targets)), CheckedAt: time.Now()}
+
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Targets[i] = m.ping(ctx, src, t)
+		}()
+	}
+	wg.Wait()
+	for _, t := range report.Targets {
+		report.Status = worse(report.Status, t.Status)
+	}
+	if len(targets) == 0 {
+		report.Status = StatusDown
+	}
+
+	m.mu.Lock()
+	m.latest[envID] = report
+	m.mu.Unlock()
+	if m.emit != nil {
+		m.emit(EventHealth, report)
+	}
+	return report
+}
+
+// Latest returns the cached report of an environment
+func (m *Monitor) Latest(envID string) (*Report, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	report, ok := m.latest[envID]
+	return report, ok
+}
+
+// Start checks an environment every interval until Stop. active returns the environment to check
+// and its sources; it returns false when there is nothing to check.
+func (m *Monitor) Start(interval time.Duration, active func() (string, engine.Sources, bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	m.stop = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if envID, src, ok := active(); ok {
+				m.Check(context.Background(), envID, src)
+			}
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends the periodic checks
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// ping requests one health endpoint
+func (m *Monitor) ping(ctx context.Context, src engine.Sources, t target) TargetStatus {
+	ctx, cancel := context.WithTimeout(ctx, CheckTimeout)
+	defer cancel()
+
+	req := &engine.ResolvedRequest{Method: http.MethodGet, URL: t.url, HostOverrides: src.HostOverrides()}
+	if src.Environment != nil {
+		req.TLS, req.Network = src.Environment.TLS, src.Environment.Network
+		req.SSHTunnel = src.SSHTunnel()
+	}
+	exec := m.engine.Send(ctx, req)
+	status := TargetStatus{Name: t.name, URL: t.url, StatusCode: exec.Status, LatencyMs: exec.DurationMs, Error: exec.Error}
+	switch {
+	case exec.Status == 0:
+		status.Status = StatusDown
+	case exec.Status < 400:
+		status.Status = StatusUp
+	default:
+		status.Status = StatusDegraded
+	}
+	return status
+}
+
+// healthTargets returns the health endpoint of the environment's base URL and of every distinct
+// folder base URL. An absolute health URL is the only target.
+func healthTargets(src engine.Sources) []target {
+	sub := engine.NewSubstituter(src.Variables())
+	path := ""
+	if src.Environment != nil {
+		path = sub.Apply(src.Environment.HealthPath)
+	}
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return []target{{name: "Environment", url: path}}
+	}
+
+	var targets []target
+	seen := make(map[string]bool)
+	add := func(name string, baseURL string) {
+		baseURL = sub.Apply(baseURL)
+		if baseURL == "" || seen[baseURL] {
+			return
+		}
+		seen[baseURL] = true
+		targets = append(targets, target{name: name, url: joinPath(baseURL, path)})
+	}
+
+	if src.Environment != nil && src.Environment.BaseURL != "" {
+		add("Environment", src.Environment.BaseURL)
+	} else {
+		add("Environment", src.UserBaseURL)
+	}
+	var folders []requests.Item
+	if src.Requests != nil {
+		for _, item := range src.Requests.Values {
+			if item.Type == requests.ItemTypeFolder && item.BaseURL != "" {
+				folders = append(folders, item)
+			}
+		}
+	}
+	sort.Slice(folders, func(i, j int) bool { return folders[i].Name < folders[j].Name })
+	for _, folder := range folders {
+		add(folder.Name, folder.BaseURL)
+	}
+	return targets
+}
+
+// joinPath appends a health path to a base URL
+func joinPath(baseURL string, path string) string {
+	if path == "" {
+		return baseURL
+	}
+	return strings.TrimRight(baseURL, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// worse returns the worse of two statuses
+func worse(a string, b string) string {
+	rank := map[string]int{StatusUp: 0, StatusDegraded: 1, StatusDown: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,67 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/requests"
+	"paperbox/internal/engine"
+)
+
+func TestMonitorCheck(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer healthy.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	src := engine.Sources{
+		Requests: &requests.RequestsConfig{Values: map[string]requests.Item{
+			"billing": {Type: requests.ItemTypeFolder, Name: "Billing", BaseURL: "{{billingURL}}"},
+			"same":    {Type: requests.ItemTypeFolder, Name: "Same", BaseURL: healthy.URL},
+		}},
+		Environment: &environments.Environment{
+			Name:       "staging",
+			BaseURL:    healthy.URL,
+			HealthPath: "/v1/health",
+			Variables:  []environments.Variable{{Key: "billingURL", Value: failing.URL}},
+		},
+	}
+
+	var events []interface{}
+	m := NewMonitor(engine.New(), func(event string, payload interface{}) {
+		if event == EventHealth {
+			events = append(events, payload)
+		}
+	})
+	report := m.Check(context.Background(), "env-1", src)
+	if len(report.Targets) != 2 {
+		t.Fatalf("Check() targets = %+v, want the environment and the billing folder", report.Targets)
+	}
+	if got := report.Targets[0]; got.Name != "Environment" || got.Status != StatusUp || got.URL != healthy.URL+"/v1/health" {
+		t.Errorf("environment target = %+v", got)
+	}
+	if got := report.Targets[1]; got.Name != "Billing" || got.Status != StatusDegraded || got.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("folder target = %+v", got)
+	}
+	if report.Status != StatusDegraded {
+		t.Errorf("Check() status = %s, want the worst target's", report.Status)
+	}
+	if latest, ok := m.Latest("env-1"); !ok || latest != report || len(events) != 1 {
+		t.Errorf("Latest() = %v, %v with %d events, want the cached report", latest, ok, len(events))
+	}
+
+	failing.Close()
+	src.Environment.HealthPath = failing.URL + "/health"
+	if report := m.Check(context.Background(), "env-1", src); len(report.Targets) != 1 || report.Status != StatusDown {
+		t.Errorf("Check() with an absolute health URL = %+v", report)
+	}
+}
@@ -0,0 +1,159 @@
+package importers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"paperbox/internal/config/requests"
+)
+
+var (
+	// requestLinePattern matches "METHOD URL [HTTP/x.y]"
+	requestLinePattern = regexp.MustCompile(`^([A-Za-z]+)\s+(\S+)(?:\s+HTTP/\S+)?$`)
+	// fileVariablePattern matches "@name = value" file-level variable definitions
+	fileVariablePattern = regexp.MustCompile(`^@([A-Za-z0-9_\-.]+)\s*=\s*(.*)$`)
+	// nameDirectivePattern matches "# @name value" / "// @name value"
+	nameDirectivePattern = regexp.MustCompile(`^(?:#|//)\s*@name\s+(.+)$`)
+	// headerPattern matches "Name: value"
+	headerPattern = regexp.MustCompile(`^([^:\s]+)\s*:\s*(.*)$`)
+)
+
+// ParseHTTPFile converts a VS Code REST Client (.http/.rest) file into a folder node named folderName.
+// File variables are inlined into URLs, headers and bodies; other {{variables}} are kept verbatim.
+func ParseHTTPFile(data []byte, folderName string) (requests.Node, error) {
+	blocks := splitHTTPBlocks(data)
+	variables := make(map[string]string)
+
+	folder := requests.Node{
+		Item: requests.Item{Type: requests.ItemTypeFolder, Name: nonEmpty(folderName, "HTTP file")},
+	}
+
+	for _, block := range blocks {
+		item, ok := parseHTTPBlock(block, variables)
+		if ok {
+			folder.Children = append(folder.Children, requests.Node{Item: item})
+		}
+	}
+
+	if len(folder.Children) == 0 {
+		return requests.Node{}, fmt.Errorf("no requests found in HTTP file")
+	}
+
+	return folder, nil
+}
+
+// httpBlock is the text between two "###" separators
+type httpBlock struct {
+	title string
+	lines []string
+}
+
+// splitHTTPBlocks splits the file on "###" separator lines; text after "###" is used as the request name
+func splitHTTPBlocks(data []byte) []httpBlock {
+	var blocks []httpBlock
+	current := httpBlock{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, "###") {
+			blocks = append(blocks, current)
+			current = httpBlock{title: strings.TrimSpace(strings.TrimPrefix(line, "###"))}
+			continue
+		}
+		current.lines = append(current.lines, line)
+	}
+	return append(blocks, current)
+}
+
+// parseHTTPBlock parses a single request block; variables accumulates file variables in declaration order
+func parseHTTPBlock(block httpBlock, variables map[string]string) (requests.Item, bool) {
+	item := requests.Item{Type: requests.ItemTypeRequest, Name: block.title}
+
+	i := 0
+	// Preamble: blank lines, comments, @name directives and file variables
+	for ; i < len(block.lines); i++ {
+		line := strings.TrimSpace(block.lines[i])
+		if line == "" {
+			continue
+		}
+		if m := nameDirectivePattern.FindStringSubmatch(line); m != nil {
+			item.Name = strings.TrimSpace(m[1])
+			continue
+		}
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if m := fileVariablePattern.FindStringSubmatch(line); m != nil {
+			variables[m[1]] = expandFileVariables(strings.TrimSpace(m[2]), variables)
+			continue
+		}
+		break
+	}
+	if i >= len(block.lines) {
+		return requests.Item{}, false
+	}
+
+	// Request line: either "METHOD URL [HTTP/x]" or a bare URL meaning GET
+	requestLine := strings.TrimSpace(block.lines[i])
+	if m := requestLinePattern.FindStringSubmatch(requestLine); m != nil {
+		item.Method = strings.ToUpper(m[1])
+		item.Path = m[2]
+	} else {
+		item.Method = "GET"
+		item.Path = requestLine
+	}
+	i++
+
+	// Multi-line query continuation ("?a=1" / "&b=2")
+	for ; i < len(block.lines); i++ {
+		line := strings.TrimSpace(block.lines[i])
+		if !strings.HasPrefix(line, "?") && !strings.HasPrefix(line, "&") {
+			break
+		}
+		item.Path += line
+	}
+	item.Path = expandFileVariables(item.Path, variables)
+
+	// Headers until the first blank line
+	for ; i < len(block.lines); i++ {
+		line := strings.TrimSpace(block.lines[i])
+		if line == "" {
+			i++
+			break
+		}
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if m := headerPattern.FindStringSubmatch(line); m != nil {
+			item.Headers = append(item.Headers, requests.Header{
+				Key:   m[1],
+				Value: expandFileVariables(strings.TrimSpace(m[2]), variables),
+			})
+		}
+	}
+
+	// Everything else is the body
+	if i < len(block.lines) {
+		body := strings.TrimSpace(strings.Join(block.lines[i:], "\n"))
+		item.Body = expandFileVariables(body, variables)
+	}
+
+	if item.Name == "" {
+		item.Name = item.Method + " " + item.Path
+	}
+
+	return item, true
+}
+
+// expandFileVariables replaces {{name}} references to known file variables
+func expandFileVariables(value string, variables map[string]string) string {
+	for name, replacement := range variables {
+		value = strings.ReplaceAll(value, "{{"+name+"}}", replacement)
+	}
+	return value
+}
@@ -0,0 +1,25 @@
+package importers
+
+import (
+	"strings"
+
+	"paperbox/internal/config/requests"
+)
+
+// nonEmpty returns value, or fallback when value is blank
+func nonEmpty(value string, fallback string) string {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return value
+}
+
+// withDefaultHeader appends the header unless one with the same name (case-insensitive) is already present
+func withDefaultHeader(headers []requests.Header, key string, value string) []requests.Header {
+	for _, h := range headers {
+		if strings.EqualFold(h.Key, key) {
+			return headers
+		}
+	}
+	return append(headers, requests.Header{Key: key, Value: value})
+}
@@ -0,0 +1,93 @@
+package importers
+
+import (
+	"testing"
+)
+
+func TestParseHTTPFile(t *testing.T) {
+	data := []byte(`@host = https://api.example.com
+@base = {{host}}/v1
+
+### List users
+GET {{base}}/users
+    ?page=1
+    &size=10
+Accept: application/json
+
+###
+# @name createUser
+POST {{base}}/users HTTP/1.1
+Content-Type: application/json
+Authorization: Bearer {{token}}
+
+{
+  "name": "Ann"
+}
+
+### Bare URL
+https://example.com/health
+`)
+
+	node, err := ParseHTTPFile(data, "users")
+	if err != nil {
+		t.Fatalf("ParseHTTPFile() error = %v", err)
+	}
+	if node.Item.Name != "users" || len(node.Children) != 3 {
+		t.Fatalf("ParseHTTPFile() = %+v", node)
+	}
+
+	list := node.Children[0].Item
+	if list.Name != "List users" || list.Method != "GET" || list.Path != "https://api.example.com/v1/users?page=1&size=10" {
+		t.Errorf("list request = %+v", list)
+	}
+	if len(list.Headers) != 1 || list.Headers[0].Key != "Accept" {
+		t.Errorf("list headers = %+v", list.Headers)
+	}
+
+	create := node.Children[1].Item
+	if create.Name != "createUser" || create.Method != "POST" || create.Body != "{\n  \"name\": \"Ann\"\n}" {
+		t.Errorf("create request = %+v", create)
+	}
+	if create.Headers[1].Value != "Bearer {{token}}" {
+		t.Errorf("unknown variables should be kept, got %q", create.Headers[1].Value)
+	}
+
+	health := node.Children[2].Item
+	if health.Method != "GET" || health.Path != "https://example.com/health" {
+		t.Errorf("bare URL request = %+v", health)
+	}
+}
+
+func TestParseThunderClient(t *testing.T) {
+	data := []byte(`{
+		"client": "Thunder Client",
+		"collectionName": "Shop",
+		"folders": [{"_id": "f1", "name": "Orders", "containerId": "", "sortNum": 20}],
+		"requests": [
+			{"_id": "r1", "containerId": "f1", "name": "Create order", "url": "{{url}}/orders", "method": "POST",
+			 "headers": [{"name": "X-Trace", "value": "1", "isDisabled": true}],
+			 "body": {"type": "json", "raw": "{\"id\":1}"}},
+			{"_id": "r2", "containerId": "", "name": "Ping", "url": "{{url}}/ping", "method": "get", "sortNum": 10}
+		]
+	}`)
+
+	node, err := ParseThunderClient(data)
+	if err != nil {
+		t.Fatalf("ParseThunderClient() error = %v", err)
+	}
+	if node.Item.Name != "Shop" || len(node.Children) != 2 {
+		t.Fatalf("ParseThunderClient() = %+v", node)
+	}
+	if node.Children[0].Item.Name != "Ping" || node.Children[0].Item.Method != "GET" {
+		t.Errorf("children should follow sortNum, got %+v", node.Children[0].Item)
+	}
+
+	orders := node.Children[1]
+	if orders.Item.Type != "folder" || len(orders.Children) != 1 {
+		t.Fatalf("orders folder = %+v", orders)
+	}
+	create := orders.Children[0].Item
+	if create.Body != `{"id":1}` || !create.Headers[0].Disabled || create.Headers[1].Key != "Content-Type" {
+		t.Errorf("create order = %+v", create)
+	}
+}
@@ -0,0 +1,52 @@
+package importers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"paperbox/internal/apperrors"
+)
+
+// readChunkSize is how much of a file is read between progress reports
+const readChunkSize = 256 << 10
+
+// Phases of an import job (see jobs.Handle.Report)
+const (
+	PhaseRead  = "read"  // Done and Total count bytes; Total is 0 for remote documents
+	PhaseMerge = "merge" // Done and Total count items; Current is the item's name
+)
+
+// ReadFile reads a file in chunks, passing the bytes read so far and the file size to progress
+// (which may be nil), and stops once ctx is cancelled
+func ReadFile(ctx context.Context, path string, progress func(done, total int64)) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to read file")
+	}
+	defer file.Close()
+
+	var total int64
+	if info, err := file.Stat(); err == nil {
+		total = info.Size()
+	}
+	var buf bytes.Buffer
+	buf.Grow(int(total))
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		_, err := io.CopyN(&buf, file, readChunkSize)
+		if progress != nil {
+			progress(int64(buf.Len()), max(total, int64(buf.Len())))
+		}
+		if errors.Is(err, io.EOF) {
+			return buf.Bytes(), nil
+		}
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.IOError, err, "failed to read file")
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package importers
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadFileReportsProgress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.json")
+	data := bytes.Repeat([]byte("x"), 3*readChunkSize+10)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var reports [][2]int64
+	read, err := ReadFile(context.Background(), path, func(done, total int64) {
+		reports = append(reports, [2]int64{done, total})
+	})
+	if err != nil || !bytes.Equal(read, data) {
+		t.Fatalf("ReadFile() = %d bytes, %v; want the whole file", len(read), err)
+	}
+	if len(reports) != 4 || reports[0] != [2]int64{readChunkSize, int64(len(data))} || reports[3][0] != int64(len(data)) {
+		t.Errorf("progress = %v, want one report per chunk ending with the whole file", reports)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ReadFile(ctx, path, nil); err != context.Canceled {
+		t.Errorf("ReadFile() with a cancelled context error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,157 @@
+package importers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"paperbox/internal/config/requests"
+)
+
+// thunderCollection is the export format of a Thunder Client collection (tc_col_*.json)
+type thunderCollection struct {
+	Client         string          `json:"client"`
+	CollectionName string          `json:"collectionName"`
+	Folders        []thunderFolder `json:"folders"`
+	Requests       []thunderItem   `json:"requests"`
+}
+
+type thunderFolder struct {
+	ID          string  `json:"_id"`
+	Name        string  `json:"name"`
+	ContainerID string  `json:"containerId"`
+	SortNum     float64 `json:"sortNum"`
+}
+
+type thunderItem struct {
+	ID          string          `json:"_id"`
+	ContainerID string          `json:"containerId"`
+	Name        string          `json:"name"`
+	URL         string          `json:"url"`
+	Method      string          `json:"method"`
+	SortNum     float64         `json:"sortNum"`
+	Headers     []thunderHeader `json:"headers"`
+	Body        *thunderBody    `json:"body"`
+}
+
+type thunderHeader struct {
+	Name       string `json:"name"`
+	Value      string `json:"value"`
+	IsDisabled bool   `json:"isDisabled"`
+}
+
+type thunderBody struct {
+	Type    string          `json:"type"`
+	Raw     string          `json:"raw"`
+	Form    []thunderHeader `json:"form"`
+	GraphQL *struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+}
+
+// ParseThunderClient converts a Thunder Client collection export into a single folder node
+func ParseThunderClient(data []byte) (requests.Node, error) {
+	var col thunderCollection
+	if err := json.Unmarshal(data, &col); err != nil {
+		return requests.Node{}, fmt.Errorf("failed to parse Thunder Client collection: %w", err)
+	}
+	if col.CollectionName == "" && len(col.Requests) == 0 && len(col.Folders) == 0 {
+		return requests.Node{}, fmt.Errorf("file is not a Thunder Client collection")
+	}
+
+	// Thunder Client stores a flat list where containerId points at the parent folder ("" for the collection)
+	type entry struct {
+		sortNum float64
+		node    requests.Node
+		id      string
+	}
+	byContainer := make(map[string][]entry)
+	for _, f := range col.Folders {
+		byContainer[f.ContainerID] = append(byContainer[f.ContainerID], entry{
+			sortNum: f.SortNum,
+			id:      f.ID,
+			node:    requests.Node{Item: requests.Item{Type: requests.ItemTypeFolder, Name: nonEmpty(f.Name, "Folder")}},
+		})
+	}
+	for _, r := range col.Requests {
+		byContainer[r.ContainerID] = append(byContainer[r.ContainerID], entry{
+			sortNum: r.SortNum,
+			node:    requests.Node{Item: thunderRequestToItem(r)},
+		})
+	}
+
+	// build assembles the children of a container recursively; visited guards against malformed cycles
+	visited := make(map[string]bool)
+	var build func(containerID string) []requests.Node
+	build = func(containerID string) []requests.Node {
+		entries := byContainer[containerID]
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].sortNum < entries[j].sortNum })
+
+		nodes := make([]requests.Node, 0, len(entries))
+		for _, e := range entries {
+			if e.id != "" {
+				if visited[e.id] {
+					continue
+				}
+				visited[e.id] = true
+				e.node.Children = build(e.id)
+			}
+			nodes = append(nodes, e.node)
+		}
+		return nodes
+	}
+
+	return requests.Node{
+		Item:     requests.Item{Type: requests.ItemTypeFolder, Name: nonEmpty(col.CollectionName, "Thunder Client")},
+		Children: build(""),
+	}, nil
+}
+
+// thunderRequestToItem maps a Thunder Client request onto a paperbox request item
+func thunderRequestToItem(r thunderItem) requests.Item {
+	item := requests.Item{
+		Type:   requests.ItemTypeRequest,
+		Name:   nonEmpty(r.Name, r.URL),
+		Method: strings.ToUpper(nonEmpty(r.Method, "GET")),
+		Path:   r.URL,
+	}
+
+	for _, h := range r.Headers {
+		if h.Name == "" {
+			continue
+		}
+		item.Headers = append(item.Headers, requests.Header{Key: h.Name, Value: h.Value, Disabled: h.IsDisabled})
+	}
+
+	if r.Body == nil {
+		return item
+	}
+
+	switch r.Body.Type {
+	case "formencoded":
+		for _, field := range r.Body.Form {
+			item.FormFields = append(item.FormFields, requests.FormField{Key: field.Name, Value: field.Value, Disabled: field.IsDisabled})
+		}
+		item.Headers = withDefaultHeader(item.Headers, "Content-Type", requests.FormContentType)
+	case "graphql":
+		if r.Body.GraphQL != nil {
+			payload := map[string]interface{}{"query": r.Body.GraphQL.Query}
+			if strings.TrimSpace(r.Body.GraphQL.Variables) != "" {
+				payload["variables"] = json.RawMessage(r.Body.GraphQL.Variables)
+			}
+			if data, err := json.Marshal(payload); err == nil {
+				item.Body = string(data)
+			}
+			item.Headers = withDefaultHeader(item.Headers, "Content-Type", "application/json")
+		}
+	case "json":
+		item.Body = r.Body.Raw
+		item.Headers = withDefaultHeader(item.Headers, "Content-Type", "application/json")
+	default:
+		item.Body = r.Body.Raw
+	}
+
+	return item
+}
@@ -0,0 +1,247 @@
+// Package jobs runs long operations (imports, collection runs, sweeps, team sync) in the background
+// so the bindings starting them return at once. Every job has an ID, a state and its progress, can
+// be cancelled, and announces its lifecycle with events.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"paperbox/internal/apperrors"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// EventStarted is emitted with the Job when a job starts
+	EventStarted = "job:started"
+	// EventProgress is emitted with the Job while it runs, at most every ProgressInterval per phase
+	EventProgress = "job:progress"
+	// EventFinished is emitted with the Job once it is done, failed or cancelled
+	EventFinished = "job:finished"
+
+	// ProgressInterval is how often progress is emitted at most within a phase
+	ProgressInterval = 100 * time.Millisecond
+	// KeepFinished is how many finished jobs List still returns, the latest ones
+	KeepFinished = 20
+)
+
+// Kind is the operation a job runs
+type Kind string
+
+const (
+	KindImport Kind = "import"
+	KindRun    Kind = "run"
+	KindSweep  Kind = "sweep"
+	KindSync   Kind = "sync"
+)
+
+// State is where a job is in its lifecycle
+type State string
+
+const (
+	StateRunning   State = "running"
+	StateDone      State = "done"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// Progress is how far a job got in its current phase. Total is 0 when it is not known.
+type Progress struct {
+	Phase   string `json:"phase,omitempty"`
+	Done    int64  `json:"done"`
+	Total   int64  `json:"total"`
+	Current string `json:"current,omitempty"`
+}
+
+// Job is a snapshot of a background operation
+type Job struct {
+	ID         string     `json:"id"`
+	Kind       Kind       `json:"kind"`
+	Title      string     `json:"title"`
+	State      State      `json:"state"`
+	Progress   Progress   `json:"progress"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Result     any        `json:"result,omitempty"` // What the job returned, once done or cancelled
+	Error      any        `json:"error,omitempty"`  // apperrors.Format of the failure
+}
+
+// Func is the work of a job. It should stop once ctx is cancelled, returning ctx.Err() or an error
+// wrapping it, and may report its progress through job.
+type Func func(ctx context.Context, job *Handle) (any, error)
+
+// Manager runs jobs and keeps the running and latest finished ones
+type Manager struct {
+	emit func(event string, payload interface{})
+
+	mu       sync.Mutex
+	jobs     map[string]*Handle
+	finished []string // IDs of the kept finished jobs, oldest first
+}
+
+// NewManager creates a job manager emitting the lifecycle events through emit
+func NewManager(emit func(event string, payload interface{})) *Manager {
+	return &Manager{emit: emit, jobs: make(map[string]*Handle)}
+}
+
+// Start runs fn in its own goroutine and returns the job ID. parent bounds the job's context;
+// Cancel or CancelAll cancel it.
+func (m *Manager) Start(parent context.Context, kind Kind, title string, fn Func) string {
+	ctx, cancel := context.WithCancel(parent)
+	h := &Handle{
+		manager: m,
+		cancel:  cancel,
+		job:     Job{ID: uuid.New().String(), Kind: kind, Title: title, State: StateRunning, StartedAt: time.Now()},
+	}
+	m.mu.Lock()
+	m.jobs[h.job.ID] = h
+	m.mu.Unlock()
+	m.emit(EventStarted, h.Snapshot())
+
+	go func() {
+		defer cancel()
+		result, err := fn(ctx, h)
+		h.finish(result, err, ctx.Err() != nil)
+	}()
+	return h.job.ID
+}
+
+// List returns the running jobs and the latest finished ones, newest first
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	handles := make([]*Handle, 0, len(m.jobs))
+	for _, h := range m.jobs {
+		handles = append(handles, h)
+	}
+	m.mu.Unlock()
+
+	jobs := make([]Job, 0, len(handles))
+	for _, h := range handles {
+		jobs = append(jobs, h.Snapshot())
+	}
+	sort.Slice(jobs, func(a, b int) bool {
+		if !jobs[a].StartedAt.Equal(jobs[b].StartedAt) {
+			return jobs[a].StartedAt.After(jobs[b].StartedAt)
+		}
+		return jobs[a].ID < jobs[b].ID
+	})
+	return jobs
+}
+
+// Get returns a job by ID
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	h, exists := m.jobs[id]
+	m.mu.Unlock()
+	if !exists {
+		return Job{}, apperrors.NotFoundf("job not found")
+	}
+	return h.Snapshot(), nil
+}
+
+// Cancel asks a running job to stop. It fails for a job that already finished.
+func (m *Manager) Cancel(id string) error {
+	m.mu.Lock()
+	h, exists := m.jobs[id]
+	m.mu.Unlock()
+	if !exists {
+		return apperrors.NotFoundf("job not found")
+	}
+	if h.Snapshot().State != StateRunning {
+		return apperrors.New(apperrors.Conflict, "job already finished")
+	}
+	h.cancel()
+	return nil
+}
+
+// CancelAll asks every running job to stop, e.g. on shutdown
+func (m *Manager) CancelAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, h := range m.jobs {
+		h.cancel()
+	}
+}
+
+// retire keeps a finished job among the latest KeepFinished ones
+func (m *Manager) retire(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.finished = append(m.finished, id)
+	for len(m.finished) > KeepFinished {
+		delete(m.jobs, m.finished[0])
+		m.finished = m.finished[1:]
+	}
+}
+
+// Handle is a running job as seen by its Func
+type Handle struct {
+	manager *Manager
+	cancel  context.CancelFunc
+
+	mu       sync.Mutex
+	job      Job
+	reported time.Time // When progress was last emitted
+}
+
+// ID returns the job ID
+func (h *Handle) ID() string {
+	return h.job.ID
+}
+
+// Snapshot returns a copy of the job's current state
+func (h *Handle) Snapshot() Job {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.job
+}
+
+// Report records the job's progress and emits it, unless progress of the same phase was emitted
+// less than ProgressInterval ago and the phase is not complete yet
+func (h *Handle) Report(phase string, done, total int64, current string) {
+	h.mu.Lock()
+	now := time.Now()
+	throttled := phase == h.job.Progress.Phase && now.Sub(h.reported) < ProgressInterval && (total == 0 || done < total)
+	h.job.Progress = Progress{Phase: phase, Done: done, Total: total, Current: current}
+	if throttled {
+		h.mu.Unlock()
+		return
+	}
+	h.reported = now
+	job := h.job
+	h.mu.Unlock()
+	h.manager.emit(EventProgress, job)
+}
+
+// Counter returns a function reporting a phase counted in items, for callbacks such as
+// requests.ProgressFunc
+func (h *Handle) Counter(phase string) func(done, total int, current string) {
+	return func(done, total int, current string) {
+		h.Report(phase, int64(done), int64(total), current)
+	}
+}
+
+// finish records the outcome of the job and emits it. A job that was cancelled but returned a
+// result anyway (e.g. a run with the requests sent so far) keeps it.
+func (h *Handle) finish(result any, err error, cancelled bool) {
+	h.mu.Lock()
+	now := time.Now()
+	h.job.FinishedAt = &now
+	switch {
+	case errors.Is(err, context.Canceled), err == nil && cancelled:
+		h.job.State, h.job.Result = StateCancelled, result
+	case err != nil:
+		h.job.State, h.job.Error = StateFailed, apperrors.Format(err)
+	default:
+		h.job.State, h.job.Result = StateDone, result
+	}
+	job := h.job
+	h.mu.Unlock()
+
+	h.manager.retire(job.ID)
+	h.manager.emit(EventFinished, job)
+}
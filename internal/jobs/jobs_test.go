@@ -0,0 +1,137 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"paperbox/internal/apperrors"
+)
+
+// events records what a manager emits and signals every finished job
+type events struct {
+	mu       sync.Mutex
+	names    []string
+	finished chan Job
+}
+
+func newEvents() *events {
+	return &events{finished: make(chan Job, KeepFinished+5)}
+}
+
+func (e *events) emit(event string, payload interface{}) {
+	e.mu.Lock()
+	e.names = append(e.names, event)
+	e.mu.Unlock()
+	if event == EventFinished {
+		e.finished <- payload.(Job)
+	}
+}
+
+func (e *events) wait(t *testing.T) Job {
+	t.Helper()
+	select {
+	case job := <-e.finished:
+		return job
+	case <-time.After(5 * time.Second):
+		t.Fatal("job:finished was not emitted")
+		return Job{}
+	}
+}
+
+func TestJobLifecycle(t *testing.T) {
+	ev := newEvents()
+	m := NewManager(ev.emit)
+	release := make(chan struct{})
+	id := m.Start(context.Background(), KindImport, "Import big.har", func(ctx context.Context, job *Handle) (any, error) {
+		job.Report("read", 1, 3, "")
+		job.Report("read", 2, 3, "") // Throttled
+		<-release
+		job.Report("read", 3, 3, "")
+		return "summary", nil
+	})
+
+	running, err := m.Get(id)
+	if err != nil || running.State != StateRunning || running.Kind != KindImport || running.Title != "Import big.har" {
+		t.Fatalf("Get() = %+v, %v; want the running import", running, err)
+	}
+	close(release)
+	job := ev.wait(t)
+	if job.ID != id || job.State != StateDone || job.Result != "summary" || job.FinishedAt == nil || job.Progress.Done != 3 {
+		t.Errorf("finished job = %+v, want done with its result", job)
+	}
+
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	want := []string{EventStarted, EventProgress, EventProgress, EventFinished}
+	if len(ev.names) != len(want) {
+		t.Fatalf("events = %v, want %v", ev.names, want)
+	}
+	for i := range want {
+		if ev.names[i] != want[i] {
+			t.Errorf("events = %v, want %v", ev.names, want)
+		}
+	}
+	if err := m.Cancel(id); apperrors.CodeOf(err) != apperrors.Conflict {
+		t.Errorf("Cancel() of a finished job error = %v, want CONFLICT", err)
+	}
+}
+
+func TestCancelAndFailJobs(t *testing.T) {
+	ev := newEvents()
+	m := NewManager(ev.emit)
+
+	started := make(chan struct{})
+	id := m.Start(context.Background(), KindRun, "Run API", func(ctx context.Context, job *Handle) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return "partial", nil
+	})
+	<-started
+	if err := m.Cancel(id); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if job := ev.wait(t); job.State != StateCancelled || job.Result != "partial" {
+		t.Errorf("cancelled job = %+v, want cancelled with the partial result", job)
+	}
+
+	m.Start(context.Background(), KindSync, "Sync", func(ctx context.Context, job *Handle) (any, error) {
+		return nil, apperrors.NotFoundf("workspace not found")
+	})
+	failed := ev.wait(t)
+	if appErr, ok := failed.Error.(*apperrors.Error); failed.State != StateFailed || !ok || appErr.Code != apperrors.NotFound {
+		t.Errorf("failed job = %+v, want the formatted error", failed)
+	}
+
+	if err := m.Cancel("missing"); apperrors.CodeOf(err) != apperrors.NotFound {
+		t.Errorf("Cancel() of an unknown job error = %v, want NOT_FOUND", err)
+	}
+}
+
+func TestListKeepsLatestFinishedJobs(t *testing.T) {
+	ev := newEvents()
+	m := NewManager(ev.emit)
+	for i := 0; i < KeepFinished+3; i++ {
+		m.Start(context.Background(), KindSweep, "Sweep", func(ctx context.Context, job *Handle) (any, error) {
+			return nil, errors.New("no values")
+		})
+		ev.wait(t)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	running := m.Start(context.Background(), KindRun, "Run", func(ctx context.Context, job *Handle) (any, error) {
+		<-release
+		return nil, nil
+	})
+
+	jobs := m.List()
+	if len(jobs) != KeepFinished+1 {
+		t.Fatalf("List() = %d jobs, want %d finished and the running one", len(jobs), KeepFinished)
+	}
+	if jobs[0].ID != running {
+		t.Errorf("List()[0] = %s, want the newest job first", jobs[0].ID)
+	}
+}
@@ -0,0 +1,324 @@
+// Package kube forwards local ports to Kubernetes services the way "kubectl port-forward svc/NAME"
+// does, with the user's kubeconfig, so cluster-internal services can be requested from paperbox
+// without a kubectl process running in a terminal.
+package kube
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"paperbox/internal/apperrors"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+const (
+	// EventForward is emitted with a Forward when a port-forward stops on its own, e.g. because
+	// its pod was deleted
+	EventForward = "k8s:forward"
+	// StartTimeout bounds finding the pod and opening the forward
+	StartTimeout = 30 * time.Second
+)
+
+// nonIdentifier matches what cannot be part of a {{variable}} name
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// Forward is a local port forwarded to a service port
+type Forward struct {
+	ID         string    `json:"id"`
+	Context    string    `json:"context"`
+	Namespace  string    `json:"namespace"`
+	Service    string    `json:"service"`
+	Port       int       `json:"port"`       // Service port
+	Pod        string    `json:"pod"`        // Pod the connections go to, as with kubectl
+	TargetPort int       `json:"targetPort"` // Container port on the pod
+	LocalPort  int       `json:"localPort"`
+	Variable   string    `json:"variable,omitempty"` // Variable holding LocalPort, set by the caller
+	StartedAt  time.Time `json:"startedAt"`
+	Error      string    `json:"error,omitempty"` // Why the forward stopped, in EventForward
+}
+
+// VariableName is the variable a forward to a service publishes its local port as, e.g.
+// "orders_api_port" for "orders-api"
+func VariableName(service string) string {
+	return nonIdentifier.ReplaceAllString(service, "_") + "_port"
+}
+
+// running is a forward and how to stop it
+type running struct {
+	forward Forward
+	stop    chan struct{}
+}
+
+// Forwarder keeps the running port-forwards
+type Forwarder struct {
+	mu       sync.Mutex
+	forwards map[string]*running
+	emit     func(event string, payload interface{})
+}
+
+// NewForwarder creates a forwarder; emit, if not nil, receives EventForward
+func NewForwarder(emit func(event string, payload interface{})) *Forwarder {
+	return &Forwarder{forwards: make(map[string]*running), emit: emit}
+}
+
+// Start forwards a free local port to a port of a service, through one of its running pods.
+// Empty kubeContext and namespace use those of the kubeconfig.
+func (f *Forwarder) Start(ctx context.Context, kubeContext string, namespace string, service string, port int) (*Forward, error) {
+	ctx, cancel := context.WithTimeout(ctx, StartTimeout)
+	defer cancel()
+
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{CurrentContext: kubeContext})
+	raw, err := clientConfig.RawConfig()
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to read kubeconfig")
+	}
+	if kubeContext == "" {
+		kubeContext = raw.CurrentContext
+	}
+	if namespace == "" {
+		if namespace, _, err = clientConfig.Namespace(); err != nil {
+			return nil, apperrors.Wrap(apperrors.IOError, err, "failed to read kubeconfig")
+		}
+	}
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to read kubeconfig")
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	pod, targetPort, err := target(ctx, client, namespace, service, port)
+	if err != nil {
+		return nil, err
+	}
+	dialer, err := podDialer(client, config, namespace, pod)
+	if err != nil {
+		return nil, err
+	}
+
+	stop, ready := make(chan struct{}), make(chan struct{})
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{"0:" + strconv.Itoa(targetPort)}, stop, ready, io.Discard, io.Discard)
+	if err != nil {
+		return nil, fmt.Errorf("failed to forward port: %w", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- forwarder.ForwardPorts() }()
+	select {
+	case <-ready:
+	case err := <-done:
+		return nil, apperrors.Wrap(apperrors.IOError, err, "port-forward failed")
+	case <-ctx.Done():
+		close(stop)
+		return nil, apperrors.Wrap(apperrors.IOError, ctx.Err(), "port-forward did not start")
+	}
+	ports, err := forwarder.GetPorts()
+	if err != nil || len(ports) == 0 {
+		close(stop)
+		return nil, fmt.Errorf("port-forward has no local port: %w", err)
+	}
+
+	r := &running{
+		forward: Forward{
+			ID:         uuid.New().String(),
+			Context:    kubeContext,
+			Namespace:  namespace,
+			Service:    service,
+			Port:       port,
+			Pod:        pod,
+			TargetPort: targetPort,
+			LocalPort:  int(ports[0].Local),
+			StartedAt:  time.Now(),
+		},
+		stop: stop,
+	}
+	f.mu.Lock()
+	f.forwards[r.forward.ID] = r
+	f.mu.Unlock()
+	go f.wait(r, done)
+	forward := r.forward
+	return &forward, nil
+}
+
+// wait forgets a forward once it ends and emits it when it was not stopped with Stop
+func (f *Forwarder) wait(r *running, done <-chan error) {
+	err := <-done
+	f.mu.Lock()
+	_, active := f.forwards[r.forward.ID]
+	delete(f.forwards, r.forward.ID)
+	f.mu.Unlock()
+	if !active || f.emit == nil {
+		return
+	}
+	forward := r.forward
+	forward.Error = "port-forward stopped"
+	if err != nil {
+		forward.Error = err.Error()
+	}
+	f.emit(EventForward, forward)
+}
+
+// SetVariable records the variable a forward's local port was published as
+func (f *Forwarder) SetVariable(id string, variable string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r, ok := f.forwards[id]; ok {
+		r.forward.Variable = variable
+	}
+}
+
+// Stop ends a forward
+func (f *Forwarder) Stop(id string) error {
+	f.mu.Lock()
+	r, ok := f.forwards[id]
+	delete(f.forwards, id)
+	f.mu.Unlock()
+	if !ok {
+		return apperrors.NotFoundf("port-forward not found")
+	}
+	close(r.stop)
+	return nil
+}
+
+// StopAll ends every forward
+func (f *Forwarder) StopAll() {
+	f.mu.Lock()
+	forwards := f.forwards
+	f.forwards = make(map[string]*running)
+	f.mu.Unlock()
+	for _, r := range forwards {
+		close(r.stop)
+	}
+}
+
+// List returns the running forwards, oldest first
+func (f *Forwarder) List() []Forward {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	forwards := make([]Forward, 0, len(f.forwards))
+	for _, r := range f.forwards {
+		forwards = append(forwards, r.forward)
+	}
+	sort.Slice(forwards, func(i, j int) bool { return forwards[i].StartedAt.Before(forwards[j].StartedAt) })
+	return forwards
+}
+
+// target picks the pod a service port is forwarded to, like kubectl: a running pod selected by the
+// service, ready ones first. It returns the pod and the container port the service port targets.
+func target(ctx context.Context, client kubernetes.Interface, namespace string, service string, port int) (string, int, error) {
+	svc, err := client.CoreV1().Services(namespace).Get(ctx, service, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return "", 0, apperrors.NotFoundf("service %q not found in namespace %q", service, namespace)
+	}
+	if err != nil {
+		return "", 0, apperrors.Wrap(apperrors.IOError, err, "failed to read service")
+	}
+	var servicePort *corev1.ServicePort
+	for i, p := range svc.Spec.Ports {
+		if int(p.Port) == port && p.Protocol != corev1.ProtocolUDP {
+			servicePort = &svc.Spec.Ports[i]
+		}
+	}
+	if servicePort == nil {
+		return "", 0, apperrors.NotFoundf("service %q has no TCP port %d", service, port)
+	}
+	if len(svc.Spec.Selector) == 0 {
+		return "", 0, apperrors.Invalidf("service %q selects no pods", service)
+	}
+
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String()})
+	if err != nil {
+		return "", 0, apperrors.Wrap(apperrors.IOError, err, "failed to list pods")
+	}
+	var candidates []corev1.Pod
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+			candidates = append(candidates, pod)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", 0, apperrors.NotFoundf("service %q has no running pod", service)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if a, b := podReady(candidates[i]), podReady(candidates[j]); a != b {
+			return a
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+	pod := candidates[0]
+
+	targetPort, err := containerPort(pod, servicePort)
+	if err != nil {
+		return "", 0, err
+	}
+	return pod.Name, targetPort, nil
+}
+
+// podReady reports whether a pod passes its readiness checks
+func podReady(pod corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// containerPort resolves the target port of a service port on a pod; a named port is looked up in
+// the pod's containers
+func containerPort(pod corev1.Pod, servicePort *corev1.ServicePort) (int, error) {
+	tp := servicePort.TargetPort
+	switch {
+	case tp.Type == intstr.Int && tp.IntVal != 0:
+		return int(tp.IntVal), nil
+	case tp.Type == intstr.String && tp.StrVal != "":
+		for _, c := range pod.Spec.Containers {
+			for _, p := range c.Ports {
+				if p.Name == tp.StrVal && p.Protocol != corev1.ProtocolUDP {
+					return int(p.ContainerPort), nil
+				}
+			}
+		}
+		return 0, apperrors.NotFoundf("pod %q has no port named %q", pod.Name, tp.StrVal)
+	}
+	return int(servicePort.Port), nil
+}
+
+// podDialer opens the port-forward stream of a pod over WebSockets, falling back to SPDY for API
+// servers that do not support them, as kubectl does
+func podDialer(client *kubernetes.Clientset, config *rest.Config, namespace string, pod string) (httpstream.Dialer, error) {
+	url := client.CoreV1().RESTClient().Post().Resource("pods").Namespace(namespace).Name(pod).SubResource("portforward").URL()
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create port-forward transport: %w", err)
+	}
+	spdyDialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+	websocketDialer, err := portforward.NewSPDYOverWebsocketDialer(url, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create port-forward transport: %w", err)
+	}
+	return portforward.NewFallbackDialer(websocketDialer, spdyDialer, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	}), nil
+}
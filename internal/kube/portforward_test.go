@@ -0,0 +1,88 @@
+package kube
+
+import (
+	"context"
+	"testing"
+
+	"paperbox/internal/apperrors"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// pod is a pod of the "orders" app in a phase, ready or not, with a container port named "http"
+func pod(name string, phase corev1.PodPhase, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", Labels: map[string]string{"app": "orders"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  "api",
+			Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+		}}},
+		Status: corev1.PodStatus{Phase: phase, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}},
+	}
+}
+
+func TestTarget(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "orders-api", Namespace: "shop"},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "orders"},
+			Ports: []corev1.ServicePort{
+				{Port: 80, TargetPort: intstr.FromString("http"), Protocol: corev1.ProtocolTCP},
+				{Port: 9090, TargetPort: intstr.FromInt32(9091), Protocol: corev1.ProtocolTCP},
+				{Port: 53, TargetPort: intstr.FromInt32(53), Protocol: corev1.ProtocolUDP},
+			},
+		},
+	}
+	client := fake.NewClientset(service,
+		pod("orders-a", corev1.PodPending, false),
+		pod("orders-b", corev1.PodRunning, false),
+		pod("orders-c", corev1.PodRunning, true),
+	)
+	ctx := context.Background()
+
+	name, port, err := target(ctx, client, "shop", "orders-api", 80)
+	if err != nil || name != "orders-c" || port != 8080 {
+		t.Errorf("target(80) = %q, %d, %v, want the ready pod and the named container port", name, port, err)
+	}
+	if _, port, _ := target(ctx, client, "shop", "orders-api", 9090); port != 9091 {
+		t.Errorf("target(9090) port = %d, want 9091", port)
+	}
+	if _, _, err := target(ctx, client, "shop", "orders-api", 53); apperrors.CodeOf(err) != apperrors.NotFound {
+		t.Errorf("target() of a UDP port error = %v", err)
+	}
+	if _, _, err := target(ctx, client, "shop", "payments", 80); apperrors.CodeOf(err) != apperrors.NotFound {
+		t.Errorf("target() of a missing service error = %v", err)
+	}
+
+	if err := client.CoreV1().Pods("shop").Delete(ctx, "orders-c", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if name, _, err := target(ctx, client, "shop", "orders-api", 80); err != nil || name != "orders-b" {
+		t.Errorf("target() without a ready pod = %q, %v, want the running one", name, err)
+	}
+}
+
+func TestVariableName(t *testing.T) {
+	for service, want := range map[string]string{"orders-api": "orders_api_port", "db": "db_port", "a.b--c": "a_b_c_port"} {
+		if got := VariableName(service); got != want {
+			t.Errorf("VariableName(%q) = %q, want %q", service, got, want)
+		}
+	}
+}
+
+func TestForwarderStop(t *testing.T) {
+	f := NewForwarder(nil)
+	if err := f.Stop("missing"); apperrors.CodeOf(err) != apperrors.NotFound {
+		t.Errorf("Stop() of an unknown forward error = %v", err)
+	}
+	if forwards := f.List(); len(forwards) != 0 {
+		t.Errorf("List() = %+v", forwards)
+	}
+}
@@ -0,0 +1,193 @@
+// Package leak scans data about to leave the app (workspace bundles, item links, plugin exports,
+// OpenAPI documents, HAR files and run reports) for likely credentials that secret masking missed:
+// JWTs, AWS keys, bearer tokens, private keys, well-known API token formats and high-entropy values
+// assigned to credential-like names. A Guard then refuses the export, redacts the findings or lets
+// them through, as the user chose.
+package leak
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/requests"
+)
+
+// Kinds of findings
+const (
+	KindPrivateKey   = "privateKey"
+	KindJWT          = "jwt"
+	KindAWSAccessKey = "awsAccessKey"
+	KindAWSSecretKey = "awsSecretKey"
+	KindAPIToken     = "apiToken" // GitHub, GitLab, Slack, Stripe and Google API tokens
+	KindBearer       = "bearerToken"
+	KindHighEntropy  = "highEntropy"
+)
+
+const (
+	// minEntropy is the Shannon entropy (bits per character) a value assigned to a credential-like
+	// name needs to be reported; words, paths and placeholders stay below it
+	minEntropy = 3.5
+	// shownPrefix is how many characters of a finding its preview shows
+	shownPrefix = 6
+)
+
+// Policy is what a Guard does with findings
+type Policy string
+
+const (
+	// Confirm refuses the export with a SECRETS_DETECTED error listing the findings, so the user
+	// can confirm (export again with Allow) or redact
+	Confirm Policy = ""
+	// Redact replaces the findings with requests.SecretMask
+	Redact Policy = "redact"
+	// Allow exports the data as is
+	Allow Policy = "allow"
+)
+
+// Finding is a likely secret. Preview shows only the first characters and the length.
+type Finding struct {
+	Kind    string `json:"kind"`
+	Source  string `json:"source"` // Part of the export, e.g. "requests.json"
+	Line    int    `json:"line"`
+	Preview string `json:"preview"`
+
+	start, end int
+}
+
+// detector finds one kind of secret; the secret is submatch group of pattern
+type detector struct {
+	kind    string
+	pattern *regexp.Regexp
+	group   int
+	entropy bool // Report only values of at least minEntropy
+}
+
+// detectors run in order; a match overlapping an earlier finding is skipped, so a JWT sent as a
+// bearer token is reported once, as a JWT
+var detectors = []detector{
+	{kind: KindPrivateKey, pattern: regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY-----(?:[\s\S]*?-----END (?:[A-Z0-9]+ )*PRIVATE KEY-----)?`)},
+	{kind: KindJWT, pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]*`)},
+	{kind: KindAWSAccessKey, pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA)[0-9A-Z]{16}\b`)},
+	{kind: KindAPIToken, pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,}|glpat-[A-Za-z0-9_-]{20,}|xox[abposr]-[A-Za-z0-9-]{10,}|[sr]k_(?:live|test)_[A-Za-z0-9]{24,}|AIza[0-9A-Za-z_-]{35})`)},
+	{kind: KindAWSSecretKey, group: 1, pattern: regexp.MustCompile(`(?i)aws[a-z0-9_-]{0,20}?secret[a-z0-9_-]{0,20}[\\"'\s]*[:=][\\"'\s]*([A-Za-z0-9/+]{40})(?:[^A-Za-z0-9/+=]|$)`)},
+	{kind: KindBearer, group: 1, pattern: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/-]{20,}=*)`)},
+	{kind: KindHighEntropy, group: 1, entropy: true, pattern: regexp.MustCompile(`(?i)(?:token|secret|passw(?:or)?d|api[_-]?key|access[_-]?key|private[_-]?key|credentials?)[a-z0-9_-]*[\\"'\s]*[:=][\\"'\s]*([A-Za-z0-9+/=_.~-]{16,})`)},
+}
+
+// Scan returns the likely secrets in data in the order they appear; source names data in the
+// findings
+func Scan(source string, data []byte) []Finding {
+	var findings []Finding
+	for _, d := range detectors {
+		for _, m := range d.pattern.FindAllSubmatchIndex(data, -1) {
+			start, end := m[2*d.group], m[2*d.group+1]
+			if start < 0 || overlaps(findings, start, end) {
+				continue
+			}
+			value := string(data[start:end])
+			if d.entropy && entropy(value) < minEntropy {
+				continue
+			}
+			findings = append(findings, Finding{
+				Kind:    d.kind,
+				Source:  source,
+				Line:    1 + strings.Count(string(data[:start]), "\n"),
+				Preview: preview(value),
+				start:   start,
+				end:     end,
+			})
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].start < findings[j].start })
+	return findings
+}
+
+// Mask returns a copy of data with findings returned by Scan for it replaced by requests.SecretMask
+func Mask(data []byte, findings []Finding) []byte {
+	redacted := make([]byte, 0, len(data))
+	last := 0
+	for _, f := range findings {
+		redacted = append(redacted, data[last:f.start]...)
+		redacted = append(redacted, requests.SecretMask...)
+		last = f.end
+	}
+	return append(redacted, data[last:]...)
+}
+
+// Guard applies a policy to the parts of one export
+type Guard struct {
+	policy   Policy
+	findings []Finding
+}
+
+// NewGuard creates a guard for an export with the policy the user chose
+func NewGuard(policy Policy) *Guard {
+	return &Guard{policy: policy}
+}
+
+// Check scans one part of the export and returns it redacted if the policy says so
+func (g *Guard) Check(source string, data []byte) []byte {
+	findings := Scan(source, data)
+	g.findings = append(g.findings, findings...)
+	if g.policy == Redact && len(findings) > 0 {
+		return Mask(data, findings)
+	}
+	return data
+}
+
+// Findings returns what the checked parts contained
+func (g *Guard) Findings() []Finding {
+	return g.findings
+}
+
+// Err returns a SECRETS_DETECTED error with the findings in its details when the policy is Confirm
+// and the checked parts contain likely secrets; callers must not write the export then
+func (g *Guard) Err() error {
+	switch g.policy {
+	case Confirm:
+		if len(g.findings) == 0 {
+			return nil
+		}
+		return apperrors.New(apperrors.SecretsDetected,
+			"the export looks like it contains %d secret(s); redact them or confirm to export as is", len(g.findings)).
+			WithDetail("findings", g.findings)
+	case Redact, Allow:
+		return nil
+	default:
+		return apperrors.Invalidf("unknown secrets policy %q", g.policy)
+	}
+}
+
+// overlaps reports whether [start, end) overlaps one of the findings
+func overlaps(findings []Finding, start int, end int) bool {
+	for _, f := range findings {
+		if start < f.end && f.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// entropy returns the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	n := float64(len([]rune(s)))
+	var bits float64
+	for _, count := range counts {
+		p := float64(count) / n
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
+
+// preview shows the start of a secret and its length
+func preview(value string) string {
+	return fmt.Sprintf("%s… (%d chars)", value[:min(shownPrefix, len(value)/3)], len(value))
+}
@@ -0,0 +1,191 @@
+// Package lint checks a collection for common problems: undocumented items, credentials pasted
+// into headers, plain-HTTP URLs, unused environment variables, empty folders and malformed XML
+// bodies. Findings may carry a fix, which Apply turns into the item and variable changes for the
+// caller to store.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/requests"
+)
+
+// Collection is what a lint run looks at: the folder being linted within the whole tree, and the
+// environments its requests resolve against
+type Collection struct {
+	Requests     *requests.RequestsConfig
+	Environments *environments.EnvironmentsConfig
+	FolderID     string
+}
+
+// Finding is one problem found by a rule. Variable findings name an environment instead of an item
+// ("" for the global variables).
+type Finding struct {
+	ID          string            `json:"id"` // Stable across runs, used to pick fixes to apply
+	Rule        string            `json:"rule"`
+	Severity    requests.Severity `json:"severity"`
+	ItemID      string            `json:"itemId,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Field       string            `json:"field,omitempty"`
+	Message     string            `json:"message"`
+	Fix         *Fix              `json:"fix,omitempty"`
+}
+
+// Fix is a suggested change resolving a finding. Only the description is sent to the UI; the
+// change itself is recomputed when fixes are applied.
+type Fix struct {
+	Description string `json:"description"`
+
+	item       func(item *requests.Item)                                  // Edits the finding's item
+	deleteItem bool                                                       // Deletes the finding's item
+	env        string                                                     // Environment edited by variables ("" for globals)
+	variables  func(vars []environments.Variable) []environments.Variable // Edits the variables of env
+}
+
+// Rule checks a collection
+type Rule func(c *Collection) []Finding
+
+// Registry is a concurrency-safe set of rules by name
+type Registry struct {
+	mu    sync.RWMutex
+	rules map[string]Rule
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{rules: make(map[string]Rule)}
+}
+
+// Register adds or replaces a rule
+func (r *Registry) Register(name string, rule Rule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules[name] = rule
+}
+
+// Names returns the registered rule names, sorted
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.rules))
+	for name := range r.rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lint runs every rule not listed in disabled and returns the findings ordered by rule and ID
+func (r *Registry) Lint(c *Collection, disabled []string) []Finding {
+	skip := make(map[string]bool, len(disabled))
+	for _, name := range disabled {
+		skip[name] = true
+	}
+
+	findings := []Finding{}
+	for _, name := range r.Names() {
+		if skip[name] {
+			continue
+		}
+		r.mu.RLock()
+		rule := r.rules[name]
+		r.mu.RUnlock()
+		for _, finding := range rule(c) {
+			finding.Rule = name
+			finding.ID = fmt.Sprintf("%s:%s%s:%s", name, finding.ItemID, finding.Environment, finding.Field)
+			findings = append(findings, finding)
+		}
+	}
+	sort.SliceStable(findings, func(a, b int) bool {
+		if findings[a].Rule != findings[b].Rule {
+			return findings[a].Rule < findings[b].Rule
+		}
+		return findings[a].ID < findings[b].ID
+	})
+	return findings
+}
+
+// DefaultRules holds the built-in rules
+var DefaultRules = NewRegistry()
+
+func init() {
+	DefaultRules.Register(RuleMissingDescription, missingDescription)
+	DefaultRules.Register(RuleHardcodedToken, hardcodedToken)
+	DefaultRules.Register(RuleInsecureURL, insecureURL)
+	DefaultRules.Register(RuleUnusedVariable, unusedVariable)
+	DefaultRules.Register(RuleEmptyFolder, emptyFolder)
+	DefaultRules.Register(RuleMalformedXML, malformedXML)
+}
+
+// Changes are the edits made by a set of fixes
+type Changes struct {
+	Items     map[string]requests.Item           // Edited items by ID
+	Deleted   []string                           // Items to delete
+	Variables map[string][]environments.Variable // New variables by environment ID ("" for globals)
+}
+
+// Apply combines the fixes of the given findings into one set of changes. Findings without a fix
+// are ignored; several fixes of the same item or environment are applied one after the other.
+func Apply(c *Collection, findings []Finding) *Changes {
+	changes := &Changes{Items: make(map[string]requests.Item), Variables: make(map[string][]environments.Variable)}
+	for _, finding := range findings {
+		fix := finding.Fix
+		switch {
+		case fix == nil:
+		case fix.deleteItem:
+			changes.Deleted = append(changes.Deleted, finding.ItemID)
+		case fix.item != nil:
+			item, edited := changes.Items[finding.ItemID]
+			if !edited {
+				item = c.Requests.Values[finding.ItemID]
+			}
+			fix.item(&item)
+			changes.Items[finding.ItemID] = item
+		}
+		if fix != nil && fix.variables != nil {
+			vars, edited := changes.Variables[fix.env]
+			if !edited {
+				vars = c.variables(fix.env)
+			}
+			changes.Variables[fix.env] = fix.variables(vars)
+		}
+	}
+	for _, id := range changes.Deleted {
+		delete(changes.Items, id)
+	}
+	return changes
+}
+
+// variables returns a copy of the variables of an environment ("" for globals)
+func (c *Collection) variables(env string) []environments.Variable {
+	if c.Environments == nil {
+		return nil
+	}
+	if env == "" {
+		return append([]environments.Variable{}, c.Environments.Globals...)
+	}
+	return append([]environments.Variable{}, c.Environments.Values[env].Variables...)
+}
+
+// items returns the folder and every item below it, in tree order
+func (c *Collection) items() []string {
+	var ids []string
+	visited := make(map[string]bool)
+	var walk func(id string)
+	walk = func(id string) {
+		item, exists := c.Requests.Values[id]
+		if !exists || visited[id] {
+			return
+		}
+		visited[id] = true
+		ids = append(ids, id)
+		for _, childID := range item.Children {
+			walk(childID)
+		}
+	}
+	walk(c.FolderID)
+	return ids
+}
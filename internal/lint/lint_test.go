@@ -0,0 +1,101 @@
+package lint
+
+import (
+	"testing"
+
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/requests"
+)
+
+func testCollection() *Collection {
+	return &Collection{
+		Requests: &requests.RequestsConfig{
+			Version: requests.CurrentVersion,
+			Values: map[string]requests.Item{
+				"root": {Type: requests.ItemTypeFolder, Name: "API", Description: "The API", Children: []string{"list", "create", "empty"}},
+				"list": {
+					Type: requests.ItemTypeRequest, Name: "List", Description: "Lists users", Method: "GET", Path: "http://api.example.com/users",
+					Headers: []requests.Header{{Key: "Authorization", Value: "Bearer abc123"}, {Key: "Accept", Value: "application/json"}},
+				},
+				"create": {
+					Type: requests.ItemTypeRequest, Name: "Create", Method: "POST", Path: "http://localhost:8080/users",
+					Headers: []requests.Header{{Key: "Authorization", Value: "Bearer other"}, {Key: "X-Trace", Value: "{{trace}}"}},
+					Body:    "<user><name>{{name}}</user>",
+				},
+				"empty": {Type: requests.ItemTypeFolder, Name: "Drafts", Description: "Later"},
+			},
+		},
+		Environments: &environments.EnvironmentsConfig{
+			Version: 1,
+			Active:  "dev",
+			Values: map[string]environments.Environment{
+				"dev": {Name: "Dev", Variables: []environments.Variable{{Key: "trace", Value: "1"}, {Key: "stale", Value: "x"}}},
+			},
+			Globals: []environments.Variable{{Key: "authorization", Value: "old"}},
+		},
+		FolderID: "root",
+	}
+}
+
+func TestLint(t *testing.T) {
+	findings := DefaultRules.Lint(testCollection(), nil)
+	byID := make(map[string]Finding, len(findings))
+	for _, finding := range findings {
+		byID[finding.ID] = finding
+	}
+
+	for _, id := range []string{
+		"missingDescription:create:description",
+		"hardcodedToken:list:headers.Authorization",
+		"hardcodedToken:create:headers.Authorization",
+		"insecureURL:list:path",
+		"unusedVariable:dev:stale",
+		"unusedVariable::authorization",
+		"emptyFolder:empty:",
+		"malformedXML:create:body",
+	} {
+		if _, ok := byID[id]; !ok {
+			t.Errorf("Lint() is missing %s, got %v", id, findings)
+		}
+	}
+	if _, ok := byID["insecureURL:create:path"]; ok {
+		t.Error("Lint() flagged http:// to localhost")
+	}
+	if _, ok := byID["unusedVariable:dev:trace"]; ok {
+		t.Error("Lint() flagged a referenced variable as unused")
+	}
+	if got := DefaultRules.Lint(testCollection(), []string{RuleMissingDescription, RuleHardcodedToken, RuleInsecureURL, RuleUnusedVariable, RuleMalformedXML}); len(got) != 1 || got[0].Rule != RuleEmptyFolder {
+		t.Errorf("Lint() with disabled rules = %v", got)
+	}
+}
+
+func TestApply(t *testing.T) {
+	c := testCollection()
+	findings := DefaultRules.Lint(c, []string{RuleMissingDescription})
+	changes := Apply(c, findings)
+
+	list := changes.Items["list"]
+	if list.Path != "https://api.example.com/users" || list.Headers[0].Value != "Bearer {{authorization}}" {
+		t.Errorf("Apply() list = %+v", list)
+	}
+	if got := changes.Items["create"].Headers[0].Value; got != "Bearer {{authorization_2}}" {
+		t.Errorf("Apply() create Authorization = %q, want a second variable", got)
+	}
+	if c.Requests.Values["list"].Headers[0].Value != "Bearer abc123" {
+		t.Error("Apply() modified the linted config")
+	}
+	if len(changes.Deleted) != 1 || changes.Deleted[0] != "empty" {
+		t.Errorf("Apply() deleted = %v", changes.Deleted)
+	}
+
+	vars := make(map[string]environments.Variable)
+	for _, v := range changes.Variables["dev"] {
+		vars[v.Key] = v
+	}
+	if _, ok := vars["stale"]; ok || vars["authorization"].Value != "abc123" || !vars["authorization"].Secret || vars["authorization_2"].Value != "other" {
+		t.Errorf("Apply() dev variables = %+v", changes.Variables["dev"])
+	}
+	if globals, ok := changes.Variables[""]; !ok || len(globals) != 0 {
+		t.Errorf("Apply() globals = %+v, want the unused one removed", globals)
+	}
+}
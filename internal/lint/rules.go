@@ -0,0 +1,261 @@
+package lint
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/requests"
+	"paperbox/internal/engine"
+	"paperbox/internal/response"
+)
+
+const (
+	RuleMissingDescription = "missingDescription"
+	RuleHardcodedToken     = "hardcodedToken"
+	RuleInsecureURL        = "insecureURL"
+	RuleUnusedVariable     = "unusedVariable"
+	RuleEmptyFolder        = "emptyFolder"
+	RuleMalformedXML       = "malformedXML"
+)
+
+// nonIdentifier matches the characters a header name loses when it becomes a variable name
+var nonIdentifier = regexp.MustCompile(`[^a-z0-9]+`)
+
+// missingDescription reports folders and requests without documentation
+func missingDescription(c *Collection) []Finding {
+	var findings []Finding
+	for _, id := range c.items() {
+		item := c.Requests.Values[id]
+		if strings.TrimSpace(item.Description) == "" {
+			findings = append(findings, Finding{
+				Severity: requests.SeverityWarning,
+				ItemID:   id,
+				Field:    "description",
+				Message:  fmt.Sprintf("%s '%s' has no description", item.Type, item.Name),
+			})
+		}
+	}
+	return findings
+}
+
+// hardcodedToken reports credential headers with literal values. The fix moves each value into a
+// secret variable of the active environment (the globals when none is active) and references it.
+func hardcodedToken(c *Collection) []Finding {
+	env := ""
+	if c.Environments != nil {
+		env = c.Environments.Active
+	}
+	// Names are picked across all findings so two different tokens never share a variable
+	taken := make(map[string]string)
+	for _, v := range c.variables(env) {
+		taken[v.Key] = v.Value
+	}
+
+	var findings []Finding
+	for _, id := range c.items() {
+		item := c.Requests.Values[id]
+		for _, h := range item.Headers {
+			if !requests.IsSecretHeader(h) || strings.TrimSpace(h.Value) == "" || strings.Contains(h.Value, "{{") {
+				continue
+			}
+
+			// Keep the scheme of "Bearer <token>" in the header, only the credential is secret
+			scheme, token := "", h.Value
+			if before, after, found := strings.Cut(h.Value, " "); found && !strings.ContainsAny(before, "=;,") {
+				scheme, token = before+" ", strings.TrimSpace(after)
+			}
+			name := variableName(taken, h.Key, token)
+			taken[name] = token
+
+			key, value := h.Key, h.Value
+			replacement := scheme + "{{" + name + "}}"
+			findings = append(findings, Finding{
+				Severity: requests.SeverityError,
+				ItemID:   id,
+				Field:    "headers." + h.Key,
+				Message:  fmt.Sprintf("header %s of '%s' holds a hard-coded credential", h.Key, item.Name),
+				Fix: &Fix{
+					Description: fmt.Sprintf("Move the value into the secret variable {{%s}}", name),
+					item: func(item *requests.Item) {
+						item.Headers = append([]requests.Header{}, item.Headers...)
+						for i := range item.Headers {
+							if item.Headers[i].Key == key && item.Headers[i].Value == value {
+								item.Headers[i].Value = replacement
+							}
+						}
+					},
+					env: env,
+					variables: func(vars []environments.Variable) []environments.Variable {
+						for _, v := range vars {
+							if v.Key == name {
+								return vars
+							}
+						}
+						return append(vars, environments.Variable{Key: name, Value: token, Secret: true})
+					},
+				},
+			})
+		}
+	}
+	return findings
+}
+
+// variableName derives a variable name from a header name, numbering it when the name already
+// holds a different value
+func variableName(taken map[string]string, header string, value string) string {
+	base := strings.Trim(nonIdentifier.ReplaceAllString(strings.ToLower(header), "_"), "_")
+	name := base
+	for n := 2; ; n++ {
+		existing, ok := taken[name]
+		if !ok || existing == value {
+			return name
+		}
+		name = fmt.Sprintf("%s_%d", base, n)
+	}
+}
+
+// insecureURL reports request paths and base URLs using plain HTTP to hosts other than this machine
+func insecureURL(c *Collection) []Finding {
+	var findings []Finding
+	for _, id := range c.items() {
+		item := c.Requests.Values[id]
+		for _, field := range []struct {
+			name string
+			get  func(item *requests.Item) *string
+		}{
+			{"path", func(item *requests.Item) *string { return &item.Path }},
+			{"baseURL", func(item *requests.Item) *string { return &item.BaseURL }},
+		} {
+			if !isInsecure(*field.get(&item)) {
+				continue
+			}
+			findings = append(findings, Finding{
+				Severity: requests.SeverityWarning,
+				ItemID:   id,
+				Field:    field.name,
+				Message:  fmt.Sprintf("%s of '%s' uses http://", field.name, item.Name),
+				Fix: &Fix{
+					Description: "Use https://",
+					item: func(item *requests.Item) {
+						if value := field.get(item); isInsecure(*value) {
+							*value = "https://" + (*value)[len("http://"):]
+						}
+					},
+				},
+			})
+		}
+	}
+	return findings
+}
+
+// isInsecure reports whether a URL uses plain HTTP to a host that is not the local machine
+func isInsecure(rawURL string) bool {
+	if len(rawURL) < len("http://") || !strings.EqualFold(rawURL[:len("http://")], "http://") {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil && (ip.IsLoopback() || ip.IsUnspecified()) {
+		return false
+	}
+	return true
+}
+
+// unusedVariable reports environment and global variables that nothing refers to (see
+// engine.FindUnusedVariables). Environments are shared by the whole tree, so references are looked
+// up everywhere, not only below the linted folder.
+func unusedVariable(c *Collection) []Finding {
+	if c.Environments == nil {
+		return nil
+	}
+
+	var findings []Finding
+	for _, unused := range engine.FindUnusedVariables(c.Requests, c.Environments) {
+		if unused.FolderID != "" {
+			continue
+		}
+		env, key := unused.Environment, unused.Name
+		owner := "global variables"
+		if env != "" {
+			owner = fmt.Sprintf("environment '%s'", c.Environments.Values[env].Name)
+		}
+		findings = append(findings, Finding{
+			Severity:    requests.SeverityWarning,
+			Environment: env,
+			Field:       key,
+			Message:     fmt.Sprintf("variable '%s' of %s is not used by any request", key, owner),
+			Fix: &Fix{
+				Description: fmt.Sprintf("Remove '%s'", key),
+				env:         env,
+				variables: func(vars []environments.Variable) []environments.Variable {
+					kept := vars[:0:0]
+					for _, v := range vars {
+						if v.Key != key {
+							kept = append(kept, v)
+						}
+					}
+					return kept
+				},
+			},
+		})
+	}
+	return findings
+}
+
+// emptyFolder reports folders without children
+func emptyFolder(c *Collection) []Finding {
+	var findings []Finding
+	for _, id := range c.items() {
+		item := c.Requests.Values[id]
+		if item.Type == requests.ItemTypeFolder && len(item.Children) == 0 {
+			findings = append(findings, Finding{
+				Severity: requests.SeverityWarning,
+				ItemID:   id,
+				Message:  fmt.Sprintf("folder '%s' is empty", item.Name),
+				Fix:      &Fix{Description: "Delete the folder", deleteItem: true},
+			})
+		}
+	}
+	return findings
+}
+
+// malformedXML reports XML request bodies that are not well-formed. Bodies count as XML by their
+// Content-Type header or, without one, by their first character.
+func malformedXML(c *Collection) []Finding {
+	var findings []Finding
+	for _, id := range c.items() {
+		item := c.Requests.Values[id]
+		if item.Type != requests.ItemTypeRequest || strings.TrimSpace(item.Body) == "" {
+			continue
+		}
+		contentType := ""
+		for _, h := range item.Headers {
+			if strings.EqualFold(h.Key, "Content-Type") && !h.Disabled {
+				contentType = h.Value
+			}
+		}
+		mediaType := response.BodyMediaType(contentType, item.Body)
+		if !strings.Contains(mediaType, "xml") {
+			continue
+		}
+		if err := response.Validate(mediaType, item.Body); err != nil {
+			findings = append(findings, Finding{
+				Severity: requests.SeverityError,
+				ItemID:   id,
+				Field:    "body",
+				Message:  fmt.Sprintf("XML body of '%s' is not well-formed: %v", item.Name, err),
+			})
+		}
+	}
+	return findings
+}
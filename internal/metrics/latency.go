@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// MaxLatencyPoints bounds the latency series kept per request; the oldest points are dropped first
+const MaxLatencyPoints = 5000
+
+// point is one execution of a request in its latency series
+type point struct {
+	At         time.Time `json:"at"`
+	DurationMs int64     `json:"ms"`
+	Status     int       `json:"status,omitempty"` // 0 when no response was received
+	Failed     bool      `json:"failed,omitempty"`
+}
+
+// LatencyBucket aggregates the executions of a request that started in [Start, Start+bucket size).
+// Latencies only cover executions that got a response.
+type LatencyBucket struct {
+	Start  time.Time `json:"start"`
+	Count  int       `json:"count"`
+	Failed int       `json:"failed"`
+	MinMs  int64     `json:"minMs"`
+	MaxMs  int64     `json:"maxMs"`
+	AvgMs  float64   `json:"avgMs"`
+	P50Ms  int64     `json:"p50Ms"`
+	P95Ms  int64     `json:"p95Ms"`
+}
+
+// LatencyHistory is the response time of a request over a window, in consecutive buckets (empty
+// ones included, so gaps show on a chart)
+type LatencyHistory struct {
+	RequestID string          `json:"requestId"`
+	Window    Period          `json:"window"`
+	Since     time.Time       `json:"since"`
+	BucketMs  int64           `json:"bucketMs"`
+	Buckets   []LatencyBucket `json:"buckets"`
+}
+
+// recordLatency appends a sample to the series of its request (must be called with the lock held)
+func (r *Recorder) recordLatency(s Sample) {
+	if s.RequestID == "" {
+		return
+	}
+	if r.data.Requests == nil {
+		r.data.Requests = make(map[string][]point)
+	}
+	series := append(r.data.Requests[s.RequestID], point{At: s.At.UTC(), DurationMs: s.DurationMs, Status: s.Status, Failed: s.Failed})
+	// Samples normally arrive in order; runs recorded after the fact may not
+	if n := len(series); n > 1 && series[n-1].At.Before(series[n-2].At) {
+		sort.SliceStable(series, func(i, j int) bool { return series[i].At.Before(series[j].At) })
+	}
+	if len(series) > MaxLatencyPoints {
+		series = append([]point(nil), series[len(series)-MaxLatencyPoints:]...)
+	}
+	r.data.Requests[s.RequestID] = series
+}
+
+// LatencyHistory buckets the latency series of a request over the window ending at now: hourly for
+// a day, every 6 hours for a week and daily for a month or everything kept
+func (r *Recorder) LatencyHistory(requestID string, window Period, now time.Time) (*LatencyHistory, error) {
+	since, err := periodStart(window, now)
+	if err != nil {
+		return nil, err
+	}
+	bucket := 24 * time.Hour
+	switch window {
+	case PeriodDay:
+		bucket = time.Hour
+	case PeriodWeek:
+		bucket = 6 * time.Hour
+	case PeriodAll, "":
+		since = now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -RetentionDays)
+	}
+	end := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+
+	history := &LatencyHistory{
+		RequestID: requestID,
+		Window:    window,
+		Since:     since,
+		BucketMs:  bucket.Milliseconds(),
+		Buckets:   make([]LatencyBucket, 0, int(end.Sub(since)/bucket)),
+	}
+	for start := since; start.Before(end); start = start.Add(bucket) {
+		history.Buckets = append(history.Buckets, LatencyBucket{Start: start})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	durations := make([][]int64, len(history.Buckets))
+	for _, p := range r.data.Requests[requestID] {
+		if p.At.Before(since) || !p.At.Before(end) {
+			continue
+		}
+		i := int(p.At.Sub(since) / bucket)
+		b := &history.Buckets[i]
+		b.Count++
+		if p.Failed {
+			b.Failed++
+		}
+		if p.Status > 0 {
+			durations[i] = append(durations[i], p.DurationMs)
+		}
+	}
+	for i, d := range durations {
+		summarize(&history.Buckets[i], d)
+	}
+	return history, nil
+}
+
+// summarize sets the latency aggregates of a bucket from its measured durations
+func summarize(b *LatencyBucket, durations []int64) {
+	if len(durations) == 0 {
+		return
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	var total int64
+	for _, d := range durations {
+		total += d
+	}
+	b.MinMs = durations[0]
+	b.MaxMs = durations[len(durations)-1]
+	b.AvgMs = float64(total) / float64(len(durations))
+	b.P50Ms = percentile(durations, 50)
+	b.P95Ms = percentile(durations, 95)
+}
+
+// percentile returns the nearest-rank percentile of sorted durations, 0 when there are none
+func percentile(sorted []int64, p int) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// LatencyRequestIDs returns the IDs of the requests that have a latency series, sorted
+func (r *Recorder) LatencyRequestIDs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]string, 0, len(r.data.Requests))
+	for id := range r.data.Requests {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ForgetLatency drops the latency series of the given requests, e.g. deleted ones, and schedules a save
+func (r *Recorder) ForgetLatency(requestIDs ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range requestIDs {
+		delete(r.data.Requests, id)
+	}
+	r.debounce.Schedule(func() {
+		_ = r.Flush()
+	})
+}
+
+// pruneLatency drops points older than RetentionDays (must be called with the lock held)
+func (r *Recorder) pruneLatency(now time.Time) {
+	cutoff := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -RetentionDays)
+	for id, series := range r.data.Requests {
+		i := sort.Search(len(series), func(i int) bool { return !series[i].At.Before(cutoff) })
+		switch {
+		case i == len(series):
+			delete(r.data.Requests, id)
+		case i > 0:
+			r.data.Requests[id] = append([]point(nil), series[i:]...)
+		}
+	}
+}
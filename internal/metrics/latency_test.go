@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistoryBuckets(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 30, 0, 0, time.UTC)
+	r := NewAt(filepath.Join(t.TempDir(), FileName))
+
+	r.Record(Sample{RequestID: "req1", Status: 200, DurationMs: 100, At: now.Add(-2 * time.Hour)})
+	r.Record(Sample{RequestID: "req1", Status: 200, DurationMs: 300, At: now.Add(-2*time.Hour + time.Minute)})
+	r.Record(Sample{RequestID: "req1", Status: 500, DurationMs: 200, Failed: true, At: now})
+	r.Record(Sample{RequestID: "req1", Failed: true, At: now})
+	r.Record(Sample{RequestID: "req2", Status: 200, DurationMs: 900, At: now})
+	r.Record(Sample{Status: 200, DurationMs: 900, At: now}) // ad-hoc, not part of any series
+
+	day, err := r.LatencyHistory("req1", PeriodDay, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(day.Buckets) != 24 || day.BucketMs != time.Hour.Milliseconds() {
+		t.Fatalf("day history has %d buckets of %dms, want 24 hourly buckets", len(day.Buckets), day.BucketMs)
+	}
+	b := day.Buckets[10]
+	if b.Count != 2 || b.Failed != 0 || b.MinMs != 100 || b.MaxMs != 300 || b.AvgMs != 200 {
+		t.Errorf("10:00 bucket = %+v, want 2 runs between 100ms and 300ms", b)
+	}
+	b = day.Buckets[12]
+	if b.Count != 2 || b.Failed != 2 || b.MinMs != 200 || b.MaxMs != 200 {
+		t.Errorf("12:00 bucket = %+v, want 2 failed runs with one measured 200ms latency", b)
+	}
+	if day.Buckets[11].Count != 0 {
+		t.Errorf("11:00 bucket = %+v, want empty", day.Buckets[11])
+	}
+
+	week, _ := r.LatencyHistory("req1", PeriodWeek, now)
+	if n := len(week.Buckets); n != 28 || week.Buckets[n-3].Count != 2 || week.Buckets[n-2].Count != 2 {
+		t.Errorf("week history = %+v, want 28 buckets with 2 runs in 06:00 and 2 in 12:00 today", week.Buckets)
+	}
+
+	unknown, _ := r.LatencyHistory("missing", PeriodMonth, now)
+	if len(unknown.Buckets) != 30 {
+		t.Errorf("month history for an unknown request has %d buckets, want 30 empty ones", len(unknown.Buckets))
+	}
+
+	if _, err := r.LatencyHistory("req1", "decade", now); err == nil {
+		t.Error("expected an error for an unknown window")
+	}
+}
+
+func TestLatencyHistoryTrimsAndPrunes(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	r := NewAt(filepath.Join(t.TempDir(), FileName))
+
+	old := now.AddDate(0, 0, -RetentionDays-1)
+	r.Record(Sample{RequestID: "stale", Status: 200, DurationMs: 10, At: old})
+	for i := 0; i < MaxLatencyPoints+10; i++ {
+		r.Record(Sample{RequestID: "busy", Status: 200, DurationMs: int64(i), At: now.Add(-time.Duration(MaxLatencyPoints+10-i) * time.Second)})
+	}
+
+	day, err := r.LatencyHistory("busy", PeriodDay, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	var minMs int64 = -1
+	for _, b := range day.Buckets {
+		count += b.Count
+		if b.Count > 0 && (minMs < 0 || b.MinMs < minMs) {
+			minMs = b.MinMs
+		}
+	}
+	if count != MaxLatencyPoints || minMs != 10 {
+		t.Errorf("busy history has %d runs starting at %dms, want the latest %d starting at 10ms", count, minMs, MaxLatencyPoints)
+	}
+
+	r.mu.Lock()
+	_, kept := r.data.Requests["stale"]
+	r.mu.Unlock()
+	if kept {
+		t.Error("series older than the retention period should be pruned")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	hundred := make([]int64, 100)
+	for i := range hundred {
+		hundred[i] = int64(i + 1)
+	}
+	tests := []struct {
+		name   string
+		sorted []int64
+		p      int
+		want   int64
+	}{
+		{"empty", nil, 50, 0},
+		{"single p50", []int64{42}, 50, 42},
+		{"single p95", []int64{42}, 95, 42},
+		{"single p99", []int64{42}, 99, 42},
+		{"two p50", []int64{10, 20}, 50, 10},
+		{"two p95", []int64{10, 20}, 95, 20},
+		{"hundred p95", hundred, 95, 95},
+		{"hundred p99", hundred, 99, 99},
+		{"twenty p95", hundred[:20], 95, 19},
+		{"twenty p99", hundred[:20], 99, 20},
+	}
+	for _, tt := range tests {
+		if got := percentile(tt.sorted, tt.p); got != tt.want {
+			t.Errorf("%s: percentile(%d) = %d, want %d", tt.name, tt.p, got, tt.want)
+		}
+	}
+}
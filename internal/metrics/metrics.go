@@ -0,0 +1,302 @@
+// Package metrics keeps local, never-transmitted usage statistics: how many requests were sent,
+// how many failed and how long each endpoint took, aggregated per day in a metrics file, and the
+// latency series of each saved request.
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/core"
+	"paperbox/internal/config/storage"
+
+	"github.com/adrg/xdg"
+)
+
+const (
+	// FileName is the name of the metrics file in the app data directory
+	FileName = "metrics.json"
+	// RetentionDays is how many days of statistics are kept
+	RetentionDays = 90
+
+	dayLayout = "2006-01-02"
+)
+
+// Period selects the time range of GetUsageStats
+type Period string
+
+const (
+	PeriodDay   Period = "day"
+	PeriodWeek  Period = "week"
+	PeriodMonth Period = "month"
+	PeriodAll   Period = "all"
+)
+
+// Sample is the outcome of one sent request
+type Sample struct {
+	RequestID  string // Saved request sent, empty for ad-hoc requests
+	Method     string
+	URL        string
+	Status     int
+	DurationMs int64
+	Failed     bool // transport error or an HTTP status of 400 or above
+	At         time.Time
+}
+
+// counts is the aggregate stored per day and per endpoint
+type counts struct {
+	Sent     int   `json:"sent"`
+	Failed   int   `json:"failed"`
+	TotalMs  int64 `json:"totalMs"`
+	Measured int   `json:"measured"` // samples that got a response and count towards latency
+}
+
+func (c *counts) add(s Sample) {
+	c.Sent++
+	if s.Failed {
+		c.Failed++
+	}
+	if s.Status > 0 {
+		c.TotalMs += s.DurationMs
+		c.Measured++
+	}
+}
+
+func (c *counts) merge(o counts) {
+	c.Sent += o.Sent
+	c.Failed += o.Failed
+	c.TotalMs += o.TotalMs
+	c.Measured += o.Measured
+}
+
+// day holds one day of statistics
+type day struct {
+	counts
+	Endpoints map[string]*counts `json:"endpoints"`
+}
+
+// file is the on-disk layout
+type file struct {
+	Version  int                `json:"version"`
+	Days     map[string]*day    `json:"days"`
+	Requests map[string][]point `json:"requests,omitempty"` // Latency series by request ID, oldest first
+}
+
+// EndpointStats summarizes one endpoint ("METHOD host/path")
+type EndpointStats struct {
+	Endpoint     string  `json:"endpoint"`
+	Sent         int     `json:"sent"`
+	Failed       int     `json:"failed"`
+	AvgLatencyMs float64 `json:"avgLatencyMs"`
+}
+
+// UsageStats summarizes a period
+type UsageStats struct {
+	Period       Period          `json:"period"`
+	Since        time.Time       `json:"since"`
+	Sent         int             `json:"sent"`
+	Failed       int             `json:"failed"`
+	AvgLatencyMs float64         `json:"avgLatencyMs"`
+	Endpoints    []EndpointStats `json:"endpoints"`
+}
+
+// Recorder aggregates samples in memory and writes them to the metrics file with a debounce
+type Recorder struct {
+	mu       sync.Mutex
+	path     string
+	writer   storage.Writer
+	debounce *core.Debouncer
+	data     file
+}
+
+// New creates a recorder writing to the metrics file in the app data directory
+func New() *Recorder {
+	return NewAt(path.Join(xdg.DataHome, "paperbox", FileName))
+}
+
+// NewAt creates a recorder writing to the given file
+func NewAt(filePath string) *Recorder {
+	return &Recorder{
+		path:     filePath,
+		writer:   storage.NewFileWriter(),
+		debounce: core.NewDebouncer(core.DefaultDebounceDuration),
+		data:     file{Version: 1, Days: make(map[string]*day)},
+	}
+}
+
+// Load reads previously recorded statistics; a missing file is not an error
+func (r *Recorder) Load() error {
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to read metrics")
+	}
+
+	var loaded file
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("failed to parse metrics: %w", err)
+	}
+	if loaded.Days == nil {
+		loaded.Days = make(map[string]*day)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.data = loaded
+	return nil
+}
+
+// Record adds a sample and schedules a save
+func (r *Recorder) Record(s Sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := s.At.UTC().Format(dayLayout)
+	d, ok := r.data.Days[key]
+	if !ok {
+		d = &day{Endpoints: make(map[string]*counts)}
+		r.data.Days[key] = d
+	}
+	d.add(s)
+
+	endpoint := Endpoint(s.Method, s.URL)
+	c, ok := d.Endpoints[endpoint]
+	if !ok {
+		c = &counts{}
+		d.Endpoints[endpoint] = c
+	}
+	c.add(s)
+	r.recordLatency(s)
+
+	r.prune(s.At)
+	r.debounce.Schedule(func() {
+		_ = r.Flush()
+	})
+}
+
+// Flush writes the statistics to disk immediately
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.data, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics: %w", err)
+	}
+
+	if err := storage.EnsureParentDir(r.path); err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to write metrics")
+	}
+	if err := r.writer.WriteAtomic(r.path, data, 0o644); err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to write metrics")
+	}
+	return nil
+}
+
+// Stats summarizes the period ending at now. Endpoints are ordered by number of requests sent.
+func (r *Recorder) Stats(period Period, now time.Time) (*UsageStats, error) {
+	since, err := periodStart(period, now)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var total counts
+	endpoints := make(map[string]*counts)
+	for key, d := range r.data.Days {
+		date, err := time.Parse(dayLayout, key)
+		if err != nil || date.Before(since) {
+			continue
+		}
+		total.merge(d.counts)
+		for endpoint, c := range d.Endpoints {
+			if _, ok := endpoints[endpoint]; !ok {
+				endpoints[endpoint] = &counts{}
+			}
+			endpoints[endpoint].merge(*c)
+		}
+	}
+
+	stats := &UsageStats{
+		Period:       period,
+		Since:        since,
+		Sent:         total.Sent,
+		Failed:       total.Failed,
+		AvgLatencyMs: average(total),
+		Endpoints:    make([]EndpointStats, 0, len(endpoints)),
+	}
+	for endpoint, c := range endpoints {
+		stats.Endpoints = append(stats.Endpoints, EndpointStats{
+			Endpoint:     endpoint,
+			Sent:         c.Sent,
+			Failed:       c.Failed,
+			AvgLatencyMs: average(*c),
+		})
+	}
+	sort.Slice(stats.Endpoints, func(i, j int) bool {
+		if stats.Endpoints[i].Sent != stats.Endpoints[j].Sent {
+			return stats.Endpoints[i].Sent > stats.Endpoints[j].Sent
+		}
+		return stats.Endpoints[i].Endpoint < stats.Endpoints[j].Endpoint
+	})
+	return stats, nil
+}
+
+// Endpoint identifies an endpoint as "METHOD host/path", ignoring the scheme and query
+func Endpoint(method string, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return method + " " + rawURL
+	}
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+	return method + " " + u.Host + p
+}
+
+// prune drops days older than RetentionDays (must be called with the lock held)
+func (r *Recorder) prune(now time.Time) {
+	cutoff := now.UTC().AddDate(0, 0, -RetentionDays).Format(dayLayout)
+	for key := range r.data.Days {
+		if key < cutoff {
+			delete(r.data.Days, key)
+		}
+	}
+	r.pruneLatency(now)
+}
+
+// periodStart returns the first day (UTC midnight) included in period
+func periodStart(period Period, now time.Time) (time.Time, error) {
+	today := now.UTC().Truncate(24 * time.Hour)
+	switch period {
+	case PeriodDay:
+		return today, nil
+	case PeriodWeek:
+		return today.AddDate(0, 0, -6), nil
+	case PeriodMonth:
+		return today.AddDate(0, 0, -29), nil
+	case PeriodAll, "":
+		return time.Time{}, nil
+	default:
+		return time.Time{}, apperrors.Invalidf("unknown period %q", period)
+	}
+}
+
+func average(c counts) float64 {
+	if c.Measured == 0 {
+		return 0
+	}
+	return float64(c.TotalMs) / float64(c.Measured)
+}
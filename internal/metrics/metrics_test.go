@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecorderStats(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), FileName)
+	r := NewAt(path)
+
+	r.Record(Sample{Method: "GET", URL: "https://api.test/users?page=1", Status: 200, DurationMs: 100, At: now})
+	r.Record(Sample{Method: "GET", URL: "https://api.test/users?page=2", Status: 500, DurationMs: 300, Failed: true, At: now})
+	r.Record(Sample{Method: "POST", URL: "https://api.test/login", Failed: true, At: now})
+	r.Record(Sample{Method: "GET", URL: "https://api.test/old", Status: 200, DurationMs: 50, At: now.AddDate(0, 0, -10)})
+
+	day, err := r.Stats(PeriodDay, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if day.Sent != 3 || day.Failed != 2 || day.AvgLatencyMs != 200 {
+		t.Errorf("day stats = %+v, want 3 sent, 2 failed, 200ms average", day)
+	}
+	if len(day.Endpoints) != 2 || day.Endpoints[0].Endpoint != "GET api.test/users" || day.Endpoints[0].Sent != 2 {
+		t.Errorf("day endpoints = %+v, want GET api.test/users first with 2 requests", day.Endpoints)
+	}
+
+	month, _ := r.Stats(PeriodMonth, now)
+	if month.Sent != 4 {
+		t.Errorf("month sent = %d, want 4", month.Sent)
+	}
+
+	if _, err := r.Stats("decade", now); err == nil {
+		t.Error("expected an error for an unknown period")
+	}
+
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewAt(path)
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if all, _ := reloaded.Stats(PeriodAll, now); all.Sent != 4 {
+		t.Errorf("reloaded stats sent = %d, want 4", all.Sent)
+	}
+}
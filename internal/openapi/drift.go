@@ -0,0 +1,285 @@
+package openapi
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"paperbox/internal/config/requests"
+)
+
+// DriftKind classifies a difference between a collection and its spec
+type DriftKind string
+
+const (
+	// DriftMissingOperation is a spec operation no request in the collection exercises
+	DriftMissingOperation DriftKind = "missingOperation"
+	// DriftRemovedEndpoint is a request whose method and path match no spec operation
+	DriftRemovedEndpoint DriftKind = "removedEndpoint"
+	// DriftParameterMismatch is a request whose parameters or body disagree with its operation
+	DriftParameterMismatch DriftKind = "parameterMismatch"
+)
+
+// Drift is one difference between a collection and its spec
+type Drift struct {
+	Kind      DriftKind `json:"kind"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`                // Spec path, or the request path when nothing matched
+	RequestID string    `json:"requestId,omitempty"` // Unset for missing operations
+	Parameter string    `json:"parameter,omitempty"` // For parameter mismatches, "body" for the request body
+	Message   string    `json:"message"`
+}
+
+// DriftReport compares the requests under a folder with the operations of a spec
+type DriftReport struct {
+	Spec       string  `json:"spec"`
+	Title      string  `json:"title,omitempty"`
+	Version    string  `json:"version,omitempty"`
+	Operations int     `json:"operations"`
+	Requests   int     `json:"requests"`
+	Matched    int     `json:"matched"`
+	Drifts     []Drift `json:"drifts"`
+}
+
+// segment is one part of a path: a literal, or a parameter (with its name when the request names it)
+type segment struct {
+	value string
+	param bool
+}
+
+// CheckDrift matches the requests under folderID with the operations of doc by method and path
+// and reports operations without requests, requests without operations and parameter mismatches.
+// Request paths may carry a base URL or {{variable}} prefix and the server base path of the spec.
+func CheckDrift(doc *Document, cfg *requests.RequestsConfig, folderID string) *DriftReport {
+	endpoints := doc.Endpoints()
+	report := &DriftReport{
+		Title:      doc.Info.Title,
+		Version:    doc.Info.Version,
+		Operations: len(endpoints),
+		Drifts:     []Drift{},
+	}
+
+	specSegments := make([][]segment, len(endpoints))
+	for i, endpoint := range endpoints {
+		specSegments[i] = splitPath(endpoint.Path, true)
+	}
+	basePaths := doc.BasePaths()
+
+	covered := make([]bool, len(endpoints))
+	var requestDrifts []Drift
+	for _, id := range requests.RequestsUnder(cfg, folderID) {
+		item := cfg.Values[id]
+		report.Requests++
+		method := strings.ToUpper(item.Method)
+		if method == "" {
+			method = "GET"
+		}
+
+		path := requestPath(item.Path)
+		match, segments := -1, []segment(nil)
+		for _, candidate := range withoutBasePaths(splitPath(path, false), basePaths) {
+			best, bestScore := -1, -1
+			for i, endpoint := range endpoints {
+				if endpoint.Method != method {
+					continue
+				}
+				if score := matchSegments(specSegments[i], candidate); score > bestScore {
+					best, bestScore = i, score
+				}
+			}
+			if best >= 0 {
+				match, segments = best, candidate
+				break
+			}
+		}
+
+		if match < 0 {
+			requestDrifts = append(requestDrifts, Drift{
+				Kind:      DriftRemovedEndpoint,
+				Method:    method,
+				Path:      path,
+				RequestID: id,
+				Message:   fmt.Sprintf("%q (%s %s) matches no operation in the spec", item.Name, method, path),
+			})
+			continue
+		}
+		covered[match] = true
+		report.Matched++
+		requestDrifts = append(requestDrifts, parameterDrifts(id, item, endpoints[match], specSegments[match], segments)...)
+	}
+
+	for i, endpoint := range endpoints {
+		if !covered[i] {
+			report.Drifts = append(report.Drifts, Drift{
+				Kind:    DriftMissingOperation,
+				Method:  endpoint.Method,
+				Path:    endpoint.Path,
+				Message: fmt.Sprintf("%s %s is in the spec but no request uses it", endpoint.Method, endpoint.Path),
+			})
+		}
+	}
+	report.Drifts = append(report.Drifts, requestDrifts...)
+	return report
+}
+
+// parameterDrifts compares a request with the operation it matched
+func parameterDrifts(id string, item requests.Item, endpoint Endpoint, spec, actual []segment) []Drift {
+	var drifts []Drift
+	add := func(parameter, message string) {
+		drifts = append(drifts, Drift{
+			Kind:      DriftParameterMismatch,
+			Method:    endpoint.Method,
+			Path:      endpoint.Path,
+			RequestID: id,
+			Parameter: parameter,
+			Message:   message,
+		})
+	}
+
+	// Path parameters the request names differently
+	for i, s := range spec {
+		if s.param && actual[i].param && actual[i].value != "" && actual[i].value != s.value {
+			add(s.value, fmt.Sprintf("path parameter %q is named %q in the spec", actual[i].value, s.value))
+		}
+	}
+
+	query, enabledQuery := requestQuery(item)
+	headers := make(map[string]bool)
+	for _, h := range item.Headers {
+		if !h.Disabled {
+			headers[strings.ToLower(h.Key)] = true
+		}
+	}
+
+	specQuery := make(map[string]bool)
+	for _, p := range endpoint.Parameters {
+		switch p.In {
+		case "query":
+			specQuery[p.Name] = true
+			if p.Required && !enabledQuery[p.Name] {
+				add(p.Name, fmt.Sprintf("required query parameter %q is missing", p.Name))
+			}
+		case "header":
+			if p.Required && !headers[strings.ToLower(p.Name)] {
+				add(p.Name, fmt.Sprintf("required header %q is missing", p.Name))
+			}
+		}
+	}
+	for _, name := range query {
+		if !specQuery[name] {
+			add(name, fmt.Sprintf("query parameter %q is not in the spec", name))
+		}
+	}
+
+	if body := endpoint.Operation.RequestBody; body != nil && body.Required && strings.TrimSpace(item.Body) == "" && len(item.FormFields) == 0 {
+		add("body", "the spec requires a request body")
+	}
+	return drifts
+}
+
+// requestQuery returns the query parameter names of a request in order, and which of them are enabled.
+// Parameters written into the path's query string count as enabled.
+func requestQuery(item requests.Item) ([]string, map[string]bool) {
+	var names []string
+	enabled := make(map[string]bool)
+	seen := make(map[string]bool)
+	add := func(name string, on bool) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+		enabled[name] = enabled[name] || on
+	}
+
+	if _, rawQuery, ok := strings.Cut(item.Path, "?"); ok {
+		rawQuery, _, _ = strings.Cut(rawQuery, "#")
+		for _, pair := range strings.Split(rawQuery, "&") {
+			name, _, _ := strings.Cut(pair, "=")
+			if decoded, err := url.QueryUnescape(name); err == nil && decoded != "" {
+				add(decoded, true)
+			}
+		}
+	}
+	for _, p := range item.QueryParams {
+		add(p.Key, !p.Disabled)
+	}
+	return names, enabled
+}
+
+// requestPath strips the origin (or a leading {{variable}} standing for it), query and fragment off a request path
+func requestPath(path string) string {
+	if strings.HasPrefix(path, "{{") {
+		if end := strings.Index(path, "}}"); end >= 0 {
+			path = path[end+2:]
+		}
+	} else if _, rest, ok := strings.Cut(path, "://"); ok {
+		path = ""
+		if slash := strings.IndexAny(rest, "/?#"); slash >= 0 {
+			path = rest[slash:]
+		}
+	}
+	if end := strings.IndexAny(path, "?#"); end >= 0 {
+		path = path[:end]
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// splitPath splits a path into segments. Spec paths mark parameters as {name}; request paths as
+// :name, {name} or {{variable}} (the latter unnamed, as the variable need not match the parameter).
+func splitPath(path string, spec bool) []segment {
+	var segments []segment
+	for _, part := range strings.Split(path, "/") {
+		switch {
+		case part == "":
+			continue
+		case !spec && strings.HasPrefix(part, "{{") && strings.HasSuffix(part, "}}"):
+			segments = append(segments, segment{param: true})
+		case strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}"):
+			segments = append(segments, segment{value: strings.Trim(part, "{}"), param: true})
+		case !spec && strings.HasPrefix(part, ":") && len(part) > 1:
+			segments = append(segments, segment{value: part[1:], param: true})
+		default:
+			segments = append(segments, segment{value: part})
+		}
+	}
+	return segments
+}
+
+// withoutBasePaths returns the request segments as they are, followed by the variants with a
+// matching server base path removed
+func withoutBasePaths(segments []segment, basePaths []string) [][]segment {
+	candidates := [][]segment{segments}
+	for _, base := range basePaths {
+		prefix := splitPath(base, true)
+		if len(prefix) == 0 || len(prefix) > len(segments) {
+			continue
+		}
+		if matchSegments(prefix, segments[:len(prefix)]) >= 0 {
+			candidates = append(candidates, segments[len(prefix):])
+		}
+	}
+	return candidates
+}
+
+// matchSegments reports how well a request path fits a spec path: -1 when it does not, otherwise
+// the number of literal segments in common, so /users/me wins over /users/{id}
+func matchSegments(spec, actual []segment) int {
+	if len(spec) != len(actual) {
+		return -1
+	}
+	score := 0
+	for i, s := range spec {
+		switch {
+		case s.param:
+			// Any request segment may fill a parameter
+		case actual[i].param || actual[i].value != s.value:
+			return -1
+		default:
+			score++
+		}
+	}
+	return score
+}
@@ -0,0 +1,305 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"paperbox/internal/config/requests"
+)
+
+// ExportVersion is the OpenAPI version of exported documents
+const ExportVersion = "3.1.0"
+
+// ResponseSample is a recorded response used as the example of an exported operation
+type ResponseSample struct {
+	Status   int
+	MimeType string
+	Body     string
+}
+
+// skippedHeaderParams are headers OpenAPI describes elsewhere (content negotiation, security schemes)
+var skippedHeaderParams = map[string]bool{
+	"accept":        true,
+	"content-type":  true,
+	"authorization": true,
+}
+
+// Export synthesizes an OpenAPI skeleton from the requests under a folder: one operation per method
+// and path, with its parameters, and example bodies taken from the stored request bodies, the last
+// recorded response of each request (samples by request ID) when given, and saved response examples. Schemas are inferred
+// from the examples. When several requests share a method and path, the first one in tree order wins.
+func Export(cfg *requests.RequestsConfig, folderID string, samples map[string]ResponseSample) *Document {
+	folder := cfg.Values[folderID]
+	doc := &Document{
+		OpenAPI: ExportVersion,
+		Info:    Info{Title: folder.Name, Description: folder.Description, Version: "1.0.0"},
+		Paths:   make(map[string]*PathItem),
+	}
+
+	servers := make(map[string]bool)
+	addServer := func(serverURL string) {
+		// Templated URLs would need server variables whose values only the environment knows
+		serverURL = strings.TrimRight(serverURL, "/")
+		if serverURL != "" && !strings.Contains(serverURL, "{{") && !servers[serverURL] {
+			servers[serverURL] = true
+			doc.Servers = append(doc.Servers, Server{URL: serverURL})
+		}
+	}
+	for _, id := range append([]string{folderID}, requests.Ancestors(cfg, folderID)...) {
+		if baseURL := cfg.Values[id].BaseURL; baseURL != "" {
+			addServer(baseURL)
+			break
+		}
+	}
+
+	operationIDs := make(map[string]bool)
+	for _, id := range requests.RequestsUnder(cfg, folderID) {
+		item := cfg.Values[id]
+		method := strings.ToUpper(item.Method)
+		if method == "" {
+			method = "GET"
+		}
+		addServer(item.BaseURL)
+		if origin := requestOrigin(item.Path); origin != "" {
+			addServer(origin)
+		}
+
+		path, pathParams := specPath(requestPath(item.Path), item.PathVars)
+		pathItem := doc.Paths[path]
+		if pathItem == nil {
+			pathItem = &PathItem{}
+			doc.Paths[path] = pathItem
+		}
+		if pathItem.Operation(method) != nil {
+			continue
+		}
+
+		op := &Operation{
+			OperationID: uniqueOperationID(item.Name, operationIDs),
+			Summary:     item.Name,
+			Description: item.Description,
+			Tags:        item.Tags,
+			Parameters:  append(pathParams, requestParameters(item)...),
+			Responses:   make(map[string]Response),
+		}
+		if len(item.FormFields) > 0 {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{bodyType(item): mediaType(requests.EncodeForm(item.FormFields))},
+			}
+		} else if strings.TrimSpace(item.Body) != "" {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{bodyType(item): mediaType(item.Body)},
+			}
+		}
+		if sample, ok := samples[id]; ok && sample.Status > 0 {
+			response := Response{Description: nonEmpty(http.StatusText(sample.Status), "Response")}
+			if strings.TrimSpace(sample.Body) != "" {
+				response.Content = map[string]MediaType{nonEmpty(sample.MimeType, "text/plain"): mediaType(sample.Body)}
+			}
+			op.Responses[strconv.Itoa(sample.Status)] = response
+		}
+		// Saved response examples fill in the statuses no recorded response covers
+		if item.Examples != nil {
+			for _, example := range item.Examples.Responses {
+				status := strconv.Itoa(example.Status)
+				if _, exists := op.Responses[status]; exists {
+					continue
+				}
+				response := Response{Description: example.Name}
+				if strings.TrimSpace(example.Body) != "" {
+					response.Content = map[string]MediaType{exampleType(example): mediaType(example.Body)}
+				}
+				op.Responses[status] = response
+			}
+		}
+		if len(op.Responses) == 0 {
+			op.Responses["default"] = Response{Description: "Response not recorded"}
+		}
+		pathItem.SetOperation(method, op)
+	}
+	return doc
+}
+
+// requestOrigin returns "scheme://host" of an absolute request path
+func requestOrigin(path string) string {
+	scheme, rest, ok := strings.Cut(path, "://")
+	if !ok || strings.HasPrefix(path, "{{") {
+		return ""
+	}
+	if end := strings.IndexAny(rest, "/?#"); end >= 0 {
+		rest = rest[:end]
+	}
+	return scheme + "://" + rest
+}
+
+// specPath turns a request path into a spec path template ({name} for :name, {name} and {{name}}
+// segments) and describes its path parameters, using the request's path variables as examples
+func specPath(path string, vars []requests.Param) (string, []Parameter) {
+	examples := make(map[string]string, len(vars))
+	for _, v := range vars {
+		examples[v.Key] = v.Value
+	}
+
+	var params []Parameter
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		name := ""
+		switch {
+		case strings.HasPrefix(part, "{{") && strings.HasSuffix(part, "}}"):
+			name = strings.TrimSpace(part[2 : len(part)-2])
+		case strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}"):
+			name = part[1 : len(part)-1]
+		case strings.HasPrefix(part, ":") && len(part) > 1:
+			name = part[1:]
+		default:
+			continue
+		}
+		parts[i] = "{" + name + "}"
+		param := Parameter{Name: name, In: "path", Required: true, Schema: map[string]any{"type": "string"}}
+		if example, ok := examples[name]; ok && example != "" {
+			param.Example = example
+		}
+		params = append(params, param)
+	}
+	return strings.Join(parts, "/"), params
+}
+
+// requestParameters describes the query parameters and headers of a request
+func requestParameters(item requests.Item) []Parameter {
+	var params []Parameter
+	values := make(map[string]string)
+	for _, p := range item.QueryParams {
+		values[p.Key] = p.Value
+	}
+	if _, rawQuery, ok := strings.Cut(item.Path, "?"); ok {
+		for _, pair := range strings.Split(rawQuery, "&") {
+			key, value, _ := strings.Cut(pair, "=")
+			if _, exists := values[key]; !exists {
+				values[key] = value
+			}
+		}
+	}
+	names, enabled := requestQuery(item)
+	for _, name := range names {
+		param := Parameter{Name: name, In: "query", Required: enabled[name], Schema: map[string]any{"type": "string"}}
+		if values[name] != "" {
+			param.Example = values[name]
+		}
+		params = append(params, param)
+	}
+
+	for _, h := range item.Headers {
+		if h.Disabled || skippedHeaderParams[strings.ToLower(h.Key)] {
+			continue
+		}
+		param := Parameter{Name: h.Key, In: "header", Required: true, Schema: map[string]any{"type": "string"}}
+		if h.Value != "" && !requests.IsSecretHeader(h) {
+			param.Example = h.Value
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// bodyType returns the content type of a request body, from its Content-Type header or the body itself
+func bodyType(item requests.Item) string {
+	for _, h := range item.Headers {
+		if !h.Disabled && strings.EqualFold(h.Key, "Content-Type") && h.Value != "" {
+			value, _, _ := strings.Cut(h.Value, ";")
+			return strings.TrimSpace(value)
+		}
+	}
+	if len(item.FormFields) > 0 {
+		return requests.FormContentType
+	}
+	if json.Valid([]byte(item.Body)) {
+		return "application/json"
+	}
+	return "text/plain"
+}
+
+// exampleType returns the content type of a response example, from its Content-Type header or the body itself
+func exampleType(example requests.ResponseExample) string {
+	return bodyType(requests.Item{Headers: example.Headers, Body: example.Body})
+}
+
+// mediaType uses a body as example; JSON bodies are decoded and get an inferred schema
+func mediaType(body string) MediaType {
+	var value any
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		return MediaType{Schema: map[string]any{"type": "string"}, Example: body}
+	}
+	return MediaType{Schema: inferSchema(value), Example: value}
+}
+
+// inferSchema derives a JSON Schema describing an example value
+func inferSchema(value any) map[string]any {
+	switch v := value.(type) {
+	case map[string]any:
+		properties := make(map[string]any, len(v))
+		for key, item := range v {
+			properties[key] = inferSchema(item)
+		}
+		return map[string]any{"type": "object", "properties": properties}
+	case []any:
+		schema := map[string]any{"type": "array"}
+		if len(v) > 0 {
+			schema["items"] = inferSchema(v[0])
+		}
+		return schema
+	case string:
+		return map[string]any{"type": "string"}
+	case float64:
+		if v == float64(int64(v)) {
+			return map[string]any{"type": "integer"}
+		}
+		return map[string]any{"type": "number"}
+	case bool:
+		return map[string]any{"type": "boolean"}
+	}
+	return map[string]any{"type": "null"}
+}
+
+// uniqueOperationID derives a camelCase operation ID from a request name, numbering duplicates
+func uniqueOperationID(name string, taken map[string]bool) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range name {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			upper = b.Len() > 0
+		case b.Len() == 0:
+			b.WriteRune(unicode.ToLower(r))
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	id := nonEmpty(b.String(), "operation")
+	candidate := id
+	for n := 2; taken[candidate]; n++ {
+		candidate = id + strconv.Itoa(n)
+	}
+	taken[candidate] = true
+	return candidate
+}
+
+// nonEmpty returns value, or fallback when value is blank
+func nonEmpty(value string, fallback string) string {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return value
+}
+
+// Marshal encodes a document as indented JSON
+func Marshal(doc *Document) ([]byte, error) {
+	return json.MarshalIndent(doc, "", "  ")
+}
@@ -0,0 +1,300 @@
+package openapi
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"paperbox/internal/config/requests"
+)
+
+// maxSampleDepth bounds how deep example bodies are generated from nested or recursive schemas
+const maxSampleDepth = 6
+
+// Import builds a folder with one request per operation, in subfolders named after the first tag
+// of each operation. The folder's base URL is the first server (OpenAPI 3) or the host, scheme and
+// basePath (Swagger 2.0). Path parameters keep their {name} placeholders and become path variables;
+// bodies are the examples of the spec, or generated from the schema when there is none. name is
+// used when the document has no title.
+func Import(doc *Document, name string) requests.Node {
+	baseURL, pathPrefix := doc.serverURL()
+	root := requests.Node{Item: requests.Item{
+		Type:        requests.ItemTypeFolder,
+		Name:        nonEmpty(doc.Info.Title, name),
+		Description: doc.Info.Description,
+		BaseURL:     baseURL,
+	}}
+
+	tagFolders := make(map[string]int)
+	for _, endpoint := range doc.Endpoints() {
+		node := requests.Node{Item: doc.importRequest(endpoint, pathPrefix)}
+		tags := endpoint.Operation.Tags
+		if len(tags) == 0 {
+			root.Children = append(root.Children, node)
+			continue
+		}
+		i, exists := tagFolders[tags[0]]
+		if !exists {
+			i = len(root.Children)
+			tagFolders[tags[0]] = i
+			root.Children = append(root.Children, requests.Node{Item: requests.Item{Type: requests.ItemTypeFolder, Name: tags[0]}})
+		}
+		root.Children[i].Children = append(root.Children[i].Children, node)
+	}
+	return root
+}
+
+// serverURL returns the base URL requests are sent to and, when it cannot be absolute (a Swagger
+// basePath without host), the prefix to put in front of the request paths instead
+func (d *Document) serverURL() (string, string) {
+	if d.Swagger != "" {
+		basePath := strings.TrimRight(d.BasePath, "/")
+		if d.Host == "" {
+			return "", basePath
+		}
+		scheme := "https"
+		if len(d.Schemes) > 0 && !containsString(d.Schemes, "https") {
+			scheme = d.Schemes[0]
+		}
+		return scheme + "://" + d.Host + basePath, ""
+	}
+	for _, server := range d.Servers {
+		// Relative server URLs are relative to the document, whose location is unknown here
+		if strings.Contains(server.URL, "://") {
+			return strings.TrimRight(server.URL, "/"), ""
+		}
+		return "", strings.TrimRight(server.URL, "/")
+	}
+	return "", ""
+}
+
+// importRequest converts one operation into a request
+func (d *Document) importRequest(endpoint Endpoint, pathPrefix string) requests.Item {
+	op := endpoint.Operation
+	item := requests.Item{
+		Type:        requests.ItemTypeRequest,
+		Name:        nonEmpty(op.Summary, nonEmpty(op.OperationID, endpoint.Method+" "+endpoint.Path)),
+		Description: op.Description,
+		Tags:        op.Tags,
+		Method:      endpoint.Method,
+		Path:        pathPrefix + endpoint.Path,
+	}
+
+	for _, p := range endpoint.Parameters {
+		value := d.parameterValue(p)
+		switch p.In {
+		case "path":
+			item.PathVars = append(item.PathVars, requests.Param{Key: p.Name, Value: value})
+		case "query":
+			item.QueryParams = append(item.QueryParams, requests.Param{Key: p.Name, Value: value, Disabled: !p.Required})
+		case "header":
+			item.Headers = append(item.Headers, requests.Header{Key: p.Name, Value: value, Disabled: !p.Required})
+		case "body":
+			item.Body = d.sampleBody(p.Schema, nil)
+			item.Headers = append(item.Headers, requests.Header{Key: "Content-Type", Value: d.consumes(op, "application/json")})
+		case "formData":
+			item.FormFields = append(item.FormFields, requests.FormField{Key: p.Name, Value: value, Description: p.Description})
+		}
+	}
+	if len(item.FormFields) > 0 {
+		item.Headers = append(item.Headers, requests.Header{Key: "Content-Type", Value: requests.FormContentType})
+	}
+
+	if op.RequestBody != nil && item.Body == "" && len(item.FormFields) == 0 {
+		if contentType, media, ok := preferredMedia(op.RequestBody.Content); ok {
+			item.Body = d.sampleBody(media.Schema, media.Example)
+			item.Headers = append(item.Headers, requests.Header{Key: "Content-Type", Value: contentType})
+		}
+	}
+	return item
+}
+
+// consumes returns the first content type an operation (or the document) accepts, preferring JSON
+func (d *Document) consumes(op *Operation, fallback string) string {
+	types := op.Consumes
+	if len(types) == 0 {
+		types = d.Consumes
+	}
+	for _, t := range types {
+		if strings.Contains(t, "json") {
+			return t
+		}
+	}
+	if len(types) > 0 {
+		return types[0]
+	}
+	return fallback
+}
+
+// parameterValue returns the example, default or first allowed value of a parameter. Nothing is
+// made up from the type alone, as a placeholder would address the wrong resource or filter results.
+func (d *Document) parameterValue(p Parameter) string {
+	value := p.Example
+	if value == nil {
+		value = p.Default
+	}
+	schema := p.Schema
+	if ref, ok := schema["$ref"].(string); ok {
+		schema = d.resolveSchema(ref)
+	}
+	for _, key := range []string{"example", "default"} {
+		if value == nil {
+			value = schema[key]
+		}
+	}
+	if enum, ok := schema["enum"].([]any); value == nil && ok && len(enum) > 0 {
+		value = enum[0]
+	}
+	if value == nil && len(p.Enum) > 0 {
+		value = p.Enum[0]
+	}
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// sampleBody returns an example as request body, or one generated from the schema
+func (d *Document) sampleBody(schema map[string]any, example any) string {
+	if example == nil {
+		example = d.sample(schema, 0)
+	}
+	if example == nil {
+		return ""
+	}
+	if s, ok := example.(string); ok {
+		return s
+	}
+	encoded, err := json.MarshalIndent(example, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// sample generates an example value matching a schema
+func (d *Document) sample(schema map[string]any, depth int) any {
+	if schema == nil || depth > maxSampleDepth {
+		return nil
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		return d.sample(d.resolveSchema(ref), depth+1)
+	}
+	for _, key := range []string{"example", "default"} {
+		if value, ok := schema[key]; ok {
+			return value
+		}
+	}
+	if values, ok := schema["enum"].([]any); ok && len(values) > 0 {
+		return values[0]
+	}
+	for _, key := range []string{"allOf", "oneOf", "anyOf"} {
+		if schemas, ok := schema[key].([]any); ok && len(schemas) > 0 {
+			merged := map[string]any{}
+			for _, s := range schemas {
+				sub, _ := s.(map[string]any)
+				value := d.sample(sub, depth+1)
+				object, isObject := value.(map[string]any)
+				if !isObject {
+					return value
+				}
+				for k, v := range object {
+					merged[k] = v
+				}
+				if key != "allOf" {
+					break
+				}
+			}
+			return merged
+		}
+	}
+
+	schemaType, _ := schema["type"].(string)
+	if properties, ok := schema["properties"].(map[string]any); ok && (schemaType == "" || schemaType == "object") {
+		object := make(map[string]any, len(properties))
+		for name, property := range properties {
+			schema, _ := property.(map[string]any)
+			object[name] = d.sample(schema, depth+1)
+		}
+		return object
+	}
+	switch schemaType {
+	case "object":
+		return map[string]any{}
+	case "array":
+		items, _ := schema["items"].(map[string]any)
+		if value := d.sample(items, depth+1); value != nil {
+			return []any{value}
+		}
+		return []any{}
+	case "string":
+		return sampleString(schema)
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	}
+	return nil
+}
+
+// resolveSchema follows a local schema reference (OpenAPI 3 components or Swagger 2.0 definitions)
+func (d *Document) resolveSchema(ref string) map[string]any {
+	var schema any
+	switch {
+	case strings.HasPrefix(ref, "#/components/schemas/") && d.Components != nil:
+		schema = d.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+	case strings.HasPrefix(ref, "#/definitions/"):
+		schema = d.Definitions[strings.TrimPrefix(ref, "#/definitions/")]
+	}
+	resolved, _ := schema.(map[string]any)
+	return resolved
+}
+
+// sampleString returns a placeholder string in the schema's format
+func sampleString(schema map[string]any) string {
+	switch schema["format"] {
+	case "date":
+		return "2024-01-01"
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "uri", "url":
+		return "https://example.com"
+	}
+	return "string"
+}
+
+// preferredMedia picks the JSON content of a body, or else the first content type in name order
+func preferredMedia(content map[string]MediaType) (string, MediaType, bool) {
+	types := make([]string, 0, len(content))
+	for contentType := range content {
+		types = append(types, contentType)
+	}
+	if len(types) == 0 {
+		return "", MediaType{}, false
+	}
+	sort.Strings(types)
+	for _, contentType := range types {
+		if strings.Contains(contentType, "json") {
+			return contentType, content[contentType], true
+		}
+	}
+	return types[0], content[types[0]], true
+}
+
+// containsString reports whether values includes value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
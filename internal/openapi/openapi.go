@@ -0,0 +1,343 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"paperbox/internal/apperrors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadTimeout bounds fetching a document from a URL
+const LoadTimeout = 30 * time.Second
+
+// Methods are the operation methods of a path item, in the order the spec lists them
+var Methods = []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"}
+
+// Document is the part of an OpenAPI 3.x document paperbox works with. Swagger 2.0 documents are
+// read too; their host, schemes, basePath, consumes, definitions and top-level parameters are kept
+// in the fields below that have no OpenAPI 3 counterpart.
+type Document struct {
+	OpenAPI     string               `json:"openapi,omitempty"`
+	Swagger     string               `json:"swagger,omitempty"`
+	Info        Info                 `json:"info"`
+	Servers     []Server             `json:"servers,omitempty"`
+	Host        string               `json:"host,omitempty"`
+	Schemes     []string             `json:"schemes,omitempty"`
+	BasePath    string               `json:"basePath,omitempty"`
+	Consumes    []string             `json:"consumes,omitempty"`
+	Paths       map[string]*PathItem `json:"paths"`
+	Components  *Components          `json:"components,omitempty"`
+	Parameters  map[string]Parameter `json:"parameters,omitempty"`  // Swagger 2.0 shared parameters
+	Definitions map[string]any       `json:"definitions,omitempty"` // Swagger 2.0 shared schemas
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server is a base URL the API is served from
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// Components holds the reusable schemas and parameters operations refer to
+type Components struct {
+	Schemas    map[string]any       `json:"schemas,omitempty"`
+	Parameters map[string]Parameter `json:"parameters,omitempty"`
+}
+
+// PathItem holds the operations of one path template
+type PathItem struct {
+	Parameters []Parameter `json:"parameters,omitempty"`
+	Get        *Operation  `json:"get,omitempty"`
+	Put        *Operation  `json:"put,omitempty"`
+	Post       *Operation  `json:"post,omitempty"`
+	Delete     *Operation  `json:"delete,omitempty"`
+	Options    *Operation  `json:"options,omitempty"`
+	Head       *Operation  `json:"head,omitempty"`
+	Patch      *Operation  `json:"patch,omitempty"`
+	Trace      *Operation  `json:"trace,omitempty"`
+}
+
+// Operation is one method of a path
+type Operation struct {
+	OperationID string              `json:"operationId,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Consumes    []string            `json:"consumes,omitempty"` // Swagger 2.0 request content types
+	Responses   map[string]Response `json:"responses,omitempty"`
+}
+
+// Parameter is an operation parameter; Ref is set instead of the other fields for "$ref" entries
+type Parameter struct {
+	Ref         string         `json:"$ref,omitempty"`
+	Name        string         `json:"name,omitempty"`
+	In          string         `json:"in,omitempty"` // "path", "query", "header" or "cookie" ("body"/"formData" in Swagger 2.0)
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      map[string]any `json:"schema,omitempty"`
+	Example     any            `json:"example,omitempty"`
+	// Swagger 2.0 describes parameters other than the body without a schema
+	Type    string `json:"type,omitempty"`
+	Default any    `json:"default,omitempty"`
+	Enum    []any  `json:"enum,omitempty"`
+}
+
+// RequestBody describes the body an operation accepts
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Response describes one response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema and example of a body in one content type
+type MediaType struct {
+	Schema  map[string]any `json:"schema,omitempty"`
+	Example any            `json:"example,omitempty"`
+}
+
+// Operation returns the operation of a method, or nil
+func (p *PathItem) Operation(method string) *Operation {
+	switch strings.ToUpper(method) {
+	case "GET":
+		return p.Get
+	case "PUT":
+		return p.Put
+	case "POST":
+		return p.Post
+	case "DELETE":
+		return p.Delete
+	case "OPTIONS":
+		return p.Options
+	case "HEAD":
+		return p.Head
+	case "PATCH":
+		return p.Patch
+	case "TRACE":
+		return p.Trace
+	}
+	return nil
+}
+
+// SetOperation sets the operation of a method; unknown methods are ignored
+func (p *PathItem) SetOperation(method string, op *Operation) {
+	switch strings.ToUpper(method) {
+	case "GET":
+		p.Get = op
+	case "PUT":
+		p.Put = op
+	case "POST":
+		p.Post = op
+	case "DELETE":
+		p.Delete = op
+	case "OPTIONS":
+		p.Options = op
+	case "HEAD":
+		p.Head = op
+	case "PATCH":
+		p.Patch = op
+	case "TRACE":
+		p.Trace = op
+	}
+}
+
+// Endpoint is one operation of a document with its path-level and operation parameters merged
+type Endpoint struct {
+	Method     string
+	Path       string
+	Operation  *Operation
+	Parameters []Parameter
+}
+
+// Endpoints lists the operations of the document ordered by path, then method
+func (d *Document) Endpoints() []Endpoint {
+	paths := make([]string, 0, len(d.Paths))
+	for path := range d.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var endpoints []Endpoint
+	for _, path := range paths {
+		item := d.Paths[path]
+		if item == nil {
+			continue
+		}
+		for _, method := range Methods {
+			op := item.Operation(method)
+			if op == nil {
+				continue
+			}
+			endpoints = append(endpoints, Endpoint{
+				Method:     method,
+				Path:       path,
+				Operation:  op,
+				Parameters: d.mergeParameters(item.Parameters, op.Parameters),
+			})
+		}
+	}
+	return endpoints
+}
+
+// mergeParameters resolves references and lets operation parameters override path-level ones
+// with the same name and location
+func (d *Document) mergeParameters(pathParams, opParams []Parameter) []Parameter {
+	var merged []Parameter
+	index := make(map[string]int)
+	for _, p := range append(append([]Parameter{}, pathParams...), opParams...) {
+		p = d.resolveParameter(p)
+		if p.Name == "" {
+			continue
+		}
+		key := p.In + ":" + p.Name
+		if i, exists := index[key]; exists {
+			merged[i] = p
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, p)
+	}
+	return merged
+}
+
+// resolveParameter follows a local "$ref" to a shared parameter; unresolvable references are dropped
+func (d *Document) resolveParameter(p Parameter) Parameter {
+	if p.Ref == "" {
+		return p
+	}
+	switch {
+	case strings.HasPrefix(p.Ref, "#/components/parameters/") && d.Components != nil:
+		return d.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+	case strings.HasPrefix(p.Ref, "#/parameters/"):
+		return d.Parameters[strings.TrimPrefix(p.Ref, "#/parameters/")]
+	}
+	return Parameter{}
+}
+
+// BasePaths returns the path prefixes declared by the servers (or Swagger basePath), without trailing slashes
+func (d *Document) BasePaths() []string {
+	var prefixes []string
+	add := func(path string) {
+		path = strings.TrimRight(path, "/")
+		if path != "" {
+			prefixes = append(prefixes, path)
+		}
+	}
+	add(d.BasePath)
+	for _, server := range d.Servers {
+		serverURL := server.URL
+		if _, rest, ok := strings.Cut(serverURL, "://"); ok {
+			serverURL = ""
+			if slash := strings.Index(rest, "/"); slash >= 0 {
+				serverURL = rest[slash:]
+			}
+		}
+		add(serverURL)
+	}
+	return prefixes
+}
+
+// Parse decodes an OpenAPI document in JSON or YAML
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+		}
+	} else {
+		var raw any
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+		}
+		// Round-trip through JSON so the JSON tags apply to YAML documents as well
+		encoded, err := json.Marshal(stringKeys(raw))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+		}
+		if err := json.Unmarshal(encoded, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+		}
+	}
+
+	if doc.OpenAPI == "" && doc.Swagger == "" {
+		return nil, fmt.Errorf("not an OpenAPI document: missing \"openapi\" version")
+	}
+	return &doc, nil
+}
+
+// stringKeys converts YAML maps with non-string keys (e.g. response codes) into JSON-encodable maps
+func stringKeys(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = stringKeys(item)
+		}
+		return v
+	case map[any]any:
+		converted := make(map[string]any, len(v))
+		for key, item := range v {
+			converted[fmt.Sprint(key)] = stringKeys(item)
+		}
+		return converted
+	case []any:
+		for i, item := range v {
+			v[i] = stringKeys(item)
+		}
+		return v
+	}
+	return value
+}
+
+// Load reads an OpenAPI document from a file path or an http(s) URL
+func Load(ctx context.Context, location string) (*Document, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		data, err := os.ReadFile(location)
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.IOError, err, "failed to read OpenAPI document")
+		}
+		return Parse(data)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, LoadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, apperrors.Invalidf("invalid OpenAPI document URL %q", location)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to fetch OpenAPI document")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apperrors.New(apperrors.IOError, "failed to fetch OpenAPI document: %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to fetch OpenAPI document")
+	}
+	return Parse(data)
+}
@@ -0,0 +1,270 @@
+package openapi
+
+import (
+	"testing"
+
+	"paperbox/internal/config/requests"
+)
+
+const petstore = `
+openapi: 3.0.3
+info:
+  title: Petstore
+  version: 1.0.0
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /pets:
+    get:
+      parameters:
+        - name: limit
+          in: query
+        - $ref: '#/components/parameters/Tenant'
+      responses:
+        200:
+          description: A list of pets
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+      responses:
+        201:
+          description: Created
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+    get:
+      responses:
+        200:
+          description: A pet
+    delete:
+      responses:
+        204:
+          description: Deleted
+  /pets/mine:
+    get:
+      responses:
+        200:
+          description: My pets
+components:
+  parameters:
+    Tenant:
+      name: X-Tenant
+      in: header
+      required: true
+`
+
+func TestParseYAML(t *testing.T) {
+	doc, err := Parse([]byte(petstore))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if doc.Info.Title != "Petstore" || doc.Paths["/pets"].Get.Responses["200"].Description != "A list of pets" {
+		t.Errorf("Parse() = %+v", doc)
+	}
+
+	endpoints := doc.Endpoints()
+	if len(endpoints) != 5 || endpoints[0].Method != "GET" || endpoints[0].Path != "/pets" {
+		t.Fatalf("Endpoints() = %+v", endpoints)
+	}
+	if params := endpoints[0].Parameters; len(params) != 2 || params[1].Name != "X-Tenant" || !params[1].Required {
+		t.Errorf("Endpoints() parameters = %+v, want the $ref resolved", params)
+	}
+	if bases := doc.BasePaths(); len(bases) != 1 || bases[0] != "/v1" {
+		t.Errorf("BasePaths() = %v", bases)
+	}
+
+	if _, err := Parse([]byte(`{"info": {"title": "x"}}`)); err == nil {
+		t.Error("Parse() accepted a document without an openapi version")
+	}
+}
+
+func TestCheckDrift(t *testing.T) {
+	doc, err := Parse([]byte(petstore))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"root": {Type: requests.ItemTypeFolder, Name: "Pets", Children: []string{"list", "create", "get", "mine", "old"}},
+			"list": {
+				Type: requests.ItemTypeRequest, Name: "List", Method: "GET", Path: "{{baseUrl}}/v1/pets?page=2",
+				Headers: []requests.Header{{Key: "x-tenant", Value: "acme"}},
+			},
+			"create": {Type: requests.ItemTypeRequest, Name: "Create", Method: "post", Path: "/pets"},
+			"get":    {Type: requests.ItemTypeRequest, Name: "Get", Method: "GET", Path: "https://api.example.com/v1/pets/:id"},
+			"mine":   {Type: requests.ItemTypeRequest, Name: "Mine", Method: "GET", Path: "/pets/mine"},
+			"old":    {Type: requests.ItemTypeRequest, Name: "Old", Method: "GET", Path: "/owners"},
+		},
+	}
+
+	report := CheckDrift(doc, cfg, "root")
+	if report.Operations != 5 || report.Requests != 5 || report.Matched != 4 {
+		t.Errorf("CheckDrift() operations = %d requests = %d matched = %d", report.Operations, report.Requests, report.Matched)
+	}
+
+	want := []Drift{
+		{Kind: DriftMissingOperation, Method: "DELETE", Path: "/pets/{petId}"},
+		{Kind: DriftParameterMismatch, Method: "GET", Path: "/pets", RequestID: "list", Parameter: "page"},
+		{Kind: DriftParameterMismatch, Method: "POST", Path: "/pets", RequestID: "create", Parameter: "body"},
+		{Kind: DriftParameterMismatch, Method: "GET", Path: "/pets/{petId}", RequestID: "get", Parameter: "petId"},
+		{Kind: DriftRemovedEndpoint, Method: "GET", Path: "/owners", RequestID: "old"},
+	}
+	if len(report.Drifts) != len(want) {
+		t.Fatalf("CheckDrift() drifts = %+v", report.Drifts)
+	}
+	for i, w := range want {
+		got := report.Drifts[i]
+		got.Message = ""
+		if got != w {
+			t.Errorf("drift %d = %+v, want %+v (%s)", i, got, w, report.Drifts[i].Message)
+		}
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"root": {Type: requests.ItemTypeFolder, Name: "Users API", BaseURL: "https://api.example.com", Children: []string{"get", "create", "search"}},
+			"get": {
+				Type: requests.ItemTypeRequest, Name: "Get user", Method: "GET", Path: "/users/:id",
+				PathVars: []requests.Param{{Key: "id", Value: "42"}},
+			},
+			"create": {
+				Type: requests.ItemTypeRequest, Name: "Create user", Method: "POST", Path: "{{baseUrl}}/users",
+				Headers: []requests.Header{{Key: "Content-Type", Value: "application/json"}, {Key: "X-Trace", Value: "1"}},
+				Body:    `{"name": "Ada", "admin": false}`,
+			},
+			"search": {Type: requests.ItemTypeRequest, Name: "Get user", Method: "GET", Path: "/users?q=ada"},
+		},
+	}
+	samples := map[string]ResponseSample{"get": {Status: 200, MimeType: "application/json", Body: `{"id": 42}`}}
+
+	doc := Export(cfg, "root", samples)
+	if doc.OpenAPI != ExportVersion || doc.Info.Title != "Users API" || len(doc.Servers) != 1 {
+		t.Errorf("Export() = %+v", doc)
+	}
+	get := doc.Paths["/users/{id}"].Get
+	if get == nil || get.OperationID != "getUser" || get.Parameters[0].Example != "42" {
+		t.Fatalf("Export() GET /users/{id} = %+v", get)
+	}
+	if example := get.Responses["200"].Content["application/json"].Example; example.(map[string]any)["id"] != float64(42) {
+		t.Errorf("Export() response example = %v", example)
+	}
+	create := doc.Paths["/users"].Post
+	if create == nil || create.RequestBody.Content["application/json"].Schema["properties"].(map[string]any)["admin"].(map[string]any)["type"] != "boolean" {
+		t.Errorf("Export() POST /users = %+v", create)
+	}
+	if search := doc.Paths["/users"].Get; search == nil || search.OperationID != "getUser2" {
+		t.Errorf("Export() GET /users = %+v", search)
+	}
+
+	data, err := Marshal(doc)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	parsed, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if report := CheckDrift(parsed, cfg, "root"); len(report.Drifts) != 0 {
+		t.Errorf("CheckDrift() on exported spec = %+v", report.Drifts)
+	}
+}
+
+const swagger2 = `{
+	"swagger": "2.0",
+	"info": {"title": "Legacy Orders", "version": "1.0"},
+	"host": "orders.internal:8443",
+	"schemes": ["http", "https"],
+	"basePath": "/api/v2/",
+	"consumes": ["application/json"],
+	"paths": {
+		"/orders/{orderId}": {
+			"parameters": [{"name": "orderId", "in": "path", "required": true, "type": "string", "default": "o-1"}],
+			"get": {"tags": ["orders"], "summary": "Get order", "parameters": [{"$ref": "#/parameters/Expand"}], "responses": {"200": {"description": "OK"}}}
+		},
+		"/orders": {
+			"post": {
+				"tags": ["orders"],
+				"operationId": "createOrder",
+				"parameters": [{"name": "order", "in": "body", "required": true, "schema": {"$ref": "#/definitions/Order"}}],
+				"responses": {"201": {"description": "Created"}}
+			}
+		},
+		"/login": {
+			"post": {
+				"parameters": [
+					{"name": "user", "in": "formData", "type": "string", "default": "ada"},
+					{"name": "remember", "in": "formData", "type": "boolean", "enum": [true]}
+				],
+				"responses": {"204": {"description": "Logged in"}}
+			}
+		}
+	},
+	"parameters": {"Expand": {"name": "expand", "in": "query", "type": "string", "enum": ["items"]}},
+	"definitions": {
+		"Order": {"type": "object", "properties": {"id": {"type": "string", "format": "uuid"}, "items": {"type": "array", "items": {"$ref": "#/definitions/Item"}}}},
+		"Item": {"type": "object", "properties": {"sku": {"type": "string", "example": "A-1"}, "quantity": {"type": "integer"}}}
+	}
+}`
+
+func TestImportSwagger2(t *testing.T) {
+	doc, err := Parse([]byte(swagger2))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	root := Import(doc, "orders.json")
+	if root.Item.Name != "Legacy Orders" || root.Item.BaseURL != "https://orders.internal:8443/api/v2" {
+		t.Fatalf("Import() folder = %+v", root.Item)
+	}
+	if len(root.Children) != 2 || root.Children[0].Item.Name != "POST /login" || root.Children[1].Item.Name != "orders" {
+		t.Fatalf("Import() children = %+v", root.Children)
+	}
+
+	login := root.Children[0].Item
+	if requests.EncodeForm(login.FormFields) != "user=ada&remember=true" || login.Headers[0].Value != "application/x-www-form-urlencoded" {
+		t.Errorf("form request = %+v", login)
+	}
+	orders := root.Children[1].Children
+	if len(orders) != 2 {
+		t.Fatalf("orders folder = %+v", orders)
+	}
+	create, get := orders[0].Item, orders[1].Item
+	if create.Name != "createOrder" || create.Path != "/orders" || create.Headers[0].Value != "application/json" ||
+		create.Body != "{\n  \"id\": \"00000000-0000-0000-0000-000000000000\",\n  \"items\": [\n    {\n      \"quantity\": 0,\n      \"sku\": \"A-1\"\n    }\n  ]\n}" {
+		t.Errorf("body request = %+v", create)
+	}
+	if get.Path != "/orders/{orderId}" || len(get.PathVars) != 1 || get.PathVars[0].Value != "o-1" ||
+		len(get.QueryParams) != 1 || get.QueryParams[0].Value != "items" || !get.QueryParams[0].Disabled {
+		t.Errorf("path and query request = %+v", get)
+	}
+
+	// Without a host the base path prefixes the request paths
+	doc.Host = ""
+	if root := Import(doc, "orders.json"); root.Item.BaseURL != "" || root.Children[0].Item.Path != "/api/v2/login" {
+		t.Errorf("Import() without host = %+v", root)
+	}
+}
+
+func TestImportOpenAPI3(t *testing.T) {
+	doc, err := Parse([]byte(petstore))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	root := Import(doc, "petstore")
+	if root.Item.BaseURL != "https://api.example.com/v1" || len(root.Children) != 5 {
+		t.Fatalf("Import() = %+v", root)
+	}
+	if create := root.Children[1].Item; create.Method != "POST" || create.Body != "{}" || create.Headers[0].Value != "application/json" {
+		t.Errorf("POST /pets = %+v", create)
+	}
+}
@@ -0,0 +1,387 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/requests"
+	"paperbox/internal/engine"
+)
+
+// Info describes a discovered plugin for the frontend
+type Info struct {
+	Manifest
+	Running bool   `json:"running"`
+	Error   string `json:"error,omitempty"` // Why the plugin could not be loaded or started
+}
+
+// FormatRef is an import or export format together with the plugin providing it
+type FormatRef struct {
+	Plugin string `json:"plugin"`
+	Format
+}
+
+// plugin is a discovered plugin and its process while enabled
+type plugin struct {
+	dir      string
+	manifest *Manifest
+	invalid  error // Why the plugin cannot be used at all (bad manifest, duplicate name)
+	err      error // Why the last start failed
+	proc     *process
+}
+
+// Manager discovers plugins, runs the enabled ones and registers what they provide with the engine
+type Manager struct {
+	dir        string
+	appVersion string
+	funcs      *engine.FuncRegistry
+	auth       *engine.AuthRegistry
+	middleware *engine.MiddlewareRegistry
+
+	mu      sync.Mutex
+	plugins map[string]*plugin
+}
+
+// NewManager creates a manager discovering plugins in dir. Template functions, auth schemes and
+// request hooks of enabled plugins are registered in funcs, auth and middleware.
+func NewManager(dir string, appVersion string, funcs *engine.FuncRegistry, auth *engine.AuthRegistry, middleware *engine.MiddlewareRegistry) *Manager {
+	return &Manager{
+		dir:        dir,
+		appVersion: appVersion,
+		funcs:      funcs,
+		auth:       auth,
+		middleware: middleware,
+		plugins:    make(map[string]*plugin),
+	}
+}
+
+// Discover rescans the plugins directory. Running plugins that are still installed keep running;
+// those that were removed are stopped. A missing directory means no plugins.
+func (m *Manager) Discover() error {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to read plugins directory")
+	}
+
+	found := make(map[string]*plugin)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(m.dir, entry.Name())
+		manifest, err := readManifest(dir)
+		if err != nil {
+			// Keep broken plugins listed so the user can see why they are not available
+			found[entry.Name()] = &plugin{dir: dir, manifest: &Manifest{Name: entry.Name()}, invalid: err}
+			continue
+		}
+		if other, ok := found[manifest.Name]; ok {
+			other.invalid = fmt.Errorf("plugin %s is installed twice (%s and %s)", manifest.Name, other.dir, dir)
+			continue
+		}
+		found[manifest.Name] = &plugin{dir: dir, manifest: manifest}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, old := range m.plugins {
+		if old.proc == nil {
+			continue
+		}
+		if current, ok := found[name]; ok && current.invalid == nil && current.dir == old.dir {
+			current.proc = old.proc
+			continue
+		}
+		m.stopLocked(name, old)
+	}
+	m.plugins = found
+	return nil
+}
+
+// List returns every discovered plugin, sorted by name
+func (m *Manager) List() []Info {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	infos := make([]Info, 0, len(m.plugins))
+	for _, p := range m.plugins {
+		info := Info{Manifest: *p.manifest, Running: p.proc != nil}
+		if err := errors.Join(p.invalid, p.err); err != nil {
+			info.Error = err.Error()
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Enable starts a plugin and registers its template functions, auth schemes and request hooks
+func (m *Manager) Enable(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.plugins[name]
+	if !ok {
+		return apperrors.NotFoundf("plugin %q not found", name)
+	}
+	if p.invalid != nil {
+		return apperrors.Invalidf("plugin %q cannot be loaded: %v", name, p.invalid)
+	}
+	if p.proc != nil {
+		return nil
+	}
+
+	// Built-in helpers such as {{faker.*}} share the namespace, so a plugin may not shadow them
+	for _, fn := range p.manifest.TemplateFunctions {
+		if _, taken := m.funcs.Lookup(FunctionName(name, fn)); taken {
+			return apperrors.New(apperrors.Conflict, "template function %s already exists", FunctionName(name, fn))
+		}
+	}
+
+	proc, err := startProcess(p.manifest, p.dir)
+	if err != nil {
+		p.err = err
+		return apperrors.Wrap(apperrors.IOError, err, "failed to enable plugin")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCallTimeout)
+	defer cancel()
+	if err := proc.call(ctx, "initialize", initializeParams{AppVersion: m.appVersion, ProtocolVersion: ProtocolVersion}, nil); err != nil {
+		proc.stop()
+		p.err = err
+		return apperrors.Wrap(apperrors.IOError, err, "failed to enable plugin")
+	}
+
+	p.proc, p.err = proc, nil
+	for _, fn := range p.manifest.TemplateFunctions {
+		m.funcs.Register(FunctionName(name, fn), m.templateFunc(name, fn))
+	}
+	for _, scheme := range p.manifest.AuthSchemes {
+		m.auth.Register(AuthType(name, scheme.ID), m.authProvider(name, scheme.ID))
+	}
+	if hasHook(p.manifest, HookBeforeSend) {
+		m.middleware.Register(MiddlewareName(name), engine.OrderSend, m.beforeSend(name))
+	}
+	return nil
+}
+
+// Disable unregisters a plugin's capabilities and stops it
+func (m *Manager) Disable(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.plugins[name]
+	if !ok {
+		return apperrors.NotFoundf("plugin %q not found", name)
+	}
+	m.stopLocked(name, p)
+	return nil
+}
+
+// Close stops every running plugin
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, p := range m.plugins {
+		m.stopLocked(name, p)
+	}
+}
+
+// stopLocked unregisters and stops a running plugin (must hold the lock)
+func (m *Manager) stopLocked(name string, p *plugin) {
+	if p.proc == nil {
+		return
+	}
+	for _, fn := range p.manifest.TemplateFunctions {
+		m.funcs.Unregister(FunctionName(name, fn))
+	}
+	for _, scheme := range p.manifest.AuthSchemes {
+		m.auth.Unregister(AuthType(name, scheme.ID))
+	}
+	m.middleware.Unregister(MiddlewareName(name))
+	p.proc.stop()
+	p.proc = nil
+}
+
+// Importers lists the import formats of the running plugins
+func (m *Manager) Importers() []FormatRef {
+	return m.formats(func(manifest *Manifest) []Format { return manifest.Importers })
+}
+
+// Exporters lists the export formats of the running plugins
+func (m *Manager) Exporters() []FormatRef {
+	return m.formats(func(manifest *Manifest) []Format { return manifest.Exporters })
+}
+
+// formats collects formats of running plugins, sorted by plugin and format
+func (m *Manager) formats(of func(*Manifest) []Format) []FormatRef {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var refs []FormatRef
+	for name, p := range m.plugins {
+		if p.proc == nil {
+			continue
+		}
+		for _, format := range of(p.manifest) {
+			refs = append(refs, FormatRef{Plugin: name, Format: format})
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Plugin != refs[j].Plugin {
+			return refs[i].Plugin < refs[j].Plugin
+		}
+		return refs[i].ID < refs[j].ID
+	})
+	return refs
+}
+
+// Import converts a file with a plugin's import format into request nodes
+func (m *Manager) Import(pluginName string, format string, name string, data []byte) ([]requests.Node, error) {
+	proc, err := m.running(pluginName, func(manifest *Manifest) bool { return hasFormat(manifest.Importers, format) })
+	if err != nil {
+		return nil, err
+	}
+
+	var result importResult
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCallTimeout)
+	defer cancel()
+	if err := proc.call(ctx, "import", importParams{Format: format, Name: name, Data: data}, &result); err != nil {
+		return nil, err
+	}
+	return result.Nodes, nil
+}
+
+// Export converts request nodes into a plugin's export format
+func (m *Manager) Export(pluginName string, format string, nodes []requests.Node) ([]byte, error) {
+	proc, err := m.running(pluginName, func(manifest *Manifest) bool { return hasFormat(manifest.Exporters, format) })
+	if err != nil {
+		return nil, err
+	}
+
+	var result exportResult
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCallTimeout)
+	defer cancel()
+	if err := proc.call(ctx, "export", exportParams{Format: format, Nodes: nodes}, &result); err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// running returns the process of an enabled plugin that provides the wanted capability
+func (m *Manager) running(name string, provides func(*Manifest) bool) (*process, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.plugins[name]
+	if !ok {
+		return nil, apperrors.NotFoundf("plugin %q not found", name)
+	}
+	if p.proc == nil {
+		return nil, apperrors.Invalidf("plugin %q is not enabled", name)
+	}
+	if !provides(p.manifest) {
+		return nil, apperrors.NotFoundf("plugin %q does not provide this format", name)
+	}
+	return p.proc, nil
+}
+
+// templateFunc forwards a template function call to the plugin
+func (m *Manager) templateFunc(name string, fn string) engine.TemplateFunc {
+	return func(args []string) (string, error) {
+		proc, err := m.running(name, func(*Manifest) bool { return true })
+		if err != nil {
+			return "", err
+		}
+		var result templateResult
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultCallTimeout)
+		defer cancel()
+		if err := proc.call(ctx, "template", templateParams{Function: fn, Args: args}, &result); err != nil {
+			return "", err
+		}
+		return result.Value, nil
+	}
+}
+
+// authProvider forwards credential injection to the plugin, which returns the modified request
+func (m *Manager) authProvider(name string, scheme string) engine.AuthProvider {
+	return func(req *engine.ResolvedRequest, auth *requests.Auth) error {
+		proc, err := m.running(name, func(*Manifest) bool { return true })
+		if err != nil {
+			return err
+		}
+		var result authResult
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultCallTimeout)
+		defer cancel()
+		if err := proc.call(ctx, "auth", authParams{Scheme: scheme, Params: auth.Params, Request: *req}, &result); err != nil {
+			return err
+		}
+		unresolved := req.Unresolved
+		*req = result.Request
+		req.Unresolved = unresolved
+		return nil
+	}
+}
+
+// beforeSend passes every request through the plugin before it is sent. The secrets and settings
+// that do not travel over the protocol are kept.
+func (m *Manager) beforeSend(name string) engine.Middleware {
+	return func(next engine.Handler) engine.Handler {
+		return func(ctx context.Context, call *engine.Call) (*engine.Execution, error) {
+			proc, err := m.running(name, func(manifest *Manifest) bool { return hasHook(manifest, HookBeforeSend) })
+			if err != nil {
+				return nil, err
+			}
+			var result beforeSendResult
+			callCtx, cancel := context.WithTimeout(ctx, DefaultCallTimeout)
+			defer cancel()
+			if err := proc.call(callCtx, "beforeSend", beforeSendParams{RequestID: call.RequestID, Request: *call.Request}, &result); err != nil {
+				return nil, fmt.Errorf("plugin %s: %w", name, err)
+			}
+			req := *call.Request
+			req.Method, req.URL, req.Headers, req.Body = result.Request.Method, result.Request.URL, result.Request.Headers, result.Request.Body
+			call.Request = &req
+			return next(ctx, call)
+		}
+	}
+}
+
+// FunctionName is the template function name of a plugin function ("{{plugin.fn args}}")
+func FunctionName(pluginName string, fn string) string {
+	return pluginName + "." + fn
+}
+
+// AuthType is the requests.AuthType of a plugin auth scheme
+func AuthType(pluginName string, scheme string) requests.AuthType {
+	return requests.AuthType(requests.PluginAuthPrefix + pluginName + "." + scheme)
+}
+
+// MiddlewareName is the name the request hooks of a plugin are registered under
+func MiddlewareName(pluginName string) string {
+	return "plugin:" + pluginName
+}
+
+// hasHook reports whether a plugin asks to be called at the given hook
+func hasHook(manifest *Manifest, hook string) bool {
+	for _, h := range manifest.Hooks {
+		if h == hook {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFormat reports whether formats includes the given ID
+func hasFormat(formats []Format, id string) bool {
+	for _, format := range formats {
+		if format.ID == id {
+			return true
+		}
+	}
+	return false
+}
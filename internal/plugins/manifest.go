@@ -0,0 +1,117 @@
+// Package plugins runs third-party extensions: import and export formats, auth schemes, template
+// functions and request hooks. A plugin is a directory under plugins/ holding a plugin.json manifest and
+// an executable that speaks line-delimited JSON over stdin/stdout (see protocol.go). External
+// executables are used instead of Go plugins, which only load when built with the exact same
+// toolchain and are not supported on Windows.
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"github.com/adrg/xdg"
+)
+
+// ManifestFileName is the name of the manifest in every plugin directory
+const ManifestFileName = "plugin.json"
+
+// namePattern restricts plugin and capability names so they can be used in auth types and function names
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// DefaultDir returns the directory plugins are discovered in
+func DefaultDir() string {
+	return path.Join(xdg.DataHome, "paperbox", "plugins")
+}
+
+// Manifest describes a plugin and what it provides
+type Manifest struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+	// Executable is started with Args; a relative path is resolved against the plugin directory
+	Executable string   `json:"executable"`
+	Args       []string `json:"args,omitempty"`
+
+	Importers         []Format     `json:"importers,omitempty"`
+	Exporters         []Format     `json:"exporters,omitempty"`
+	AuthSchemes       []AuthScheme `json:"authSchemes,omitempty"`
+	TemplateFunctions []string     `json:"templateFunctions,omitempty"`
+	// Hooks lists the points of request execution the plugin is called at (see HookBeforeSend)
+	Hooks []string `json:"hooks,omitempty"`
+}
+
+// HookBeforeSend calls the plugin with every request after its auth is injected; the plugin returns
+// the request to send
+const HookBeforeSend = "beforeSend"
+
+// Format is an import or export format provided by a plugin
+type Format struct {
+	ID         string   `json:"id"`
+	Label      string   `json:"label"`
+	Extensions []string `json:"extensions,omitempty"` // e.g. ".yaml", used for file dialogs
+}
+
+// AuthScheme is an auth type provided by a plugin; Fields name the params it expects in Auth.Params
+type AuthScheme struct {
+	ID     string   `json:"id"`
+	Label  string   `json:"label"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// readManifest loads and checks the manifest of a plugin directory
+func readManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ManifestFileName, err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ManifestFileName, err)
+	}
+	if err := manifest.validate(); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// validate checks the fields the app relies on
+func (m *Manifest) validate() error {
+	if !namePattern.MatchString(m.Name) {
+		return fmt.Errorf("plugin name %q must only contain letters, digits, '-' and '_'", m.Name)
+	}
+	if m.Executable == "" {
+		return fmt.Errorf("plugin %s has no executable", m.Name)
+	}
+
+	ids := make([]string, 0, len(m.AuthSchemes)+len(m.TemplateFunctions))
+	for _, scheme := range m.AuthSchemes {
+		ids = append(ids, scheme.ID)
+	}
+	ids = append(ids, m.TemplateFunctions...)
+	for _, format := range append(append([]Format{}, m.Importers...), m.Exporters...) {
+		ids = append(ids, format.ID)
+	}
+	for _, hook := range m.Hooks {
+		if hook != HookBeforeSend {
+			return fmt.Errorf("plugin %s: unknown hook %q", m.Name, hook)
+		}
+	}
+	for _, id := range ids {
+		if !namePattern.MatchString(id) {
+			return fmt.Errorf("plugin %s: %q must only contain letters, digits, '-' and '_'", m.Name, id)
+		}
+	}
+	return nil
+}
+
+// executablePath resolves the executable against the plugin directory
+func (m *Manifest) executablePath(dir string) string {
+	if filepath.IsAbs(m.Executable) {
+		return m.Executable
+	}
+	return filepath.Join(dir, m.Executable)
+}
@@ -0,0 +1,260 @@
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"paperbox/internal/config/requests"
+	"paperbox/internal/engine"
+)
+
+// helperEnv makes the test binary act as a plugin (see TestHelperPlugin)
+const helperEnv = "PAPERBOX_TEST_PLUGIN"
+
+// TestHelperPlugin is the plugin executable used by the other tests; it does nothing in a normal run
+func TestHelperPlugin(t *testing.T) {
+	if os.Getenv(helperEnv) != "1" {
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	out := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var req struct {
+			ID     int64           `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			os.Exit(2)
+		}
+
+		var result interface{} = struct{}{}
+		switch req.Method {
+		case "import":
+			var params importParams
+			_ = json.Unmarshal(req.Params, &params)
+			folder := requests.Node{Item: requests.Item{Type: requests.ItemTypeFolder, Name: params.Name}}
+			for _, line := range strings.Split(strings.TrimSpace(string(params.Data)), "\n") {
+				method, path, _ := strings.Cut(line, " ")
+				folder.Children = append(folder.Children, requests.Node{Item: requests.Item{
+					Type: requests.ItemTypeRequest, Name: path, Method: method, Path: path,
+				}})
+			}
+			result = importResult{Nodes: []requests.Node{folder}}
+		case "export":
+			var params exportParams
+			_ = json.Unmarshal(req.Params, &params)
+			result = exportResult{Data: []byte(params.Nodes[0].Item.Name)}
+		case "template":
+			var params templateParams
+			_ = json.Unmarshal(req.Params, &params)
+			result = templateResult{Value: strings.ToUpper(strings.Join(params.Args, " "))}
+		case "auth":
+			var params authParams
+			_ = json.Unmarshal(req.Params, &params)
+			params.Request.Headers = append(params.Request.Headers, requests.Header{Key: "X-Signature", Value: params.Scheme + ":" + params.Params["key"]})
+			result = authResult{Request: params.Request}
+		case "beforeSend":
+			var params beforeSendParams
+			_ = json.Unmarshal(req.Params, &params)
+			params.Request.Headers = append(params.Request.Headers, requests.Header{Key: "X-Request", Value: params.RequestID})
+			result = beforeSendResult{Request: params.Request}
+		}
+		_ = out.Encode(map[string]interface{}{"id": req.ID, "result": result})
+		if req.Method == "shutdown" {
+			os.Exit(0)
+		}
+	}
+	os.Exit(0)
+}
+
+// installHelper writes a manifest running the test binary as plugin "demo" into a plugins directory
+func installHelper(t *testing.T) string {
+	t.Helper()
+	t.Setenv(helperEnv, "1")
+
+	dir := t.TempDir()
+	manifest := Manifest{
+		Name:              "demo",
+		Version:           "1.0.0",
+		Executable:        os.Args[0],
+		Args:              []string{"-test.run=^TestHelperPlugin$"},
+		Importers:         []Format{{ID: "lines", Label: "Method and path per line"}},
+		Exporters:         []Format{{ID: "name", Label: "Folder name"}},
+		AuthSchemes:       []AuthScheme{{ID: "sign", Label: "Signature", Fields: []string{"key"}}},
+		TemplateFunctions: []string{"upper"},
+		Hooks:             []string{HookBeforeSend},
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "demo"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "demo", ManifestFileName), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestManagerLifecycle(t *testing.T) {
+	dir := installHelper(t)
+	// A directory with a broken manifest is listed with its error
+	if err := os.MkdirAll(filepath.Join(dir, "broken"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	funcs, auth, middleware := engine.NewFuncRegistry(), engine.NewAuthRegistry(), engine.NewMiddlewareRegistry()
+	m := NewManager(dir, "test", funcs, auth, middleware)
+	t.Cleanup(m.Close)
+	if err := m.Discover(); err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+
+	infos := m.List()
+	if len(infos) != 2 || infos[0].Name != "broken" || infos[0].Error == "" || infos[1].Name != "demo" || infos[1].Running {
+		t.Fatalf("List() = %+v", infos)
+	}
+	if err := m.Enable("broken"); err == nil {
+		t.Error("Enable(broken) should fail")
+	}
+	if _, err := m.Import("demo", "lines", "x", nil); err == nil {
+		t.Error("Import() before Enable should fail")
+	}
+
+	if err := m.Enable("demo"); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+	if !m.List()[1].Running {
+		t.Error("demo should be running")
+	}
+
+	fn, ok := funcs.Lookup("demo.upper")
+	if !ok {
+		t.Fatal("template function not registered")
+	}
+	if got, err := fn([]string{"a", "b"}); err != nil || got != "A B" {
+		t.Errorf("demo.upper = %q, %v", got, err)
+	}
+
+	provider, ok := auth.Lookup(AuthType("demo", "sign"))
+	if !ok {
+		t.Fatal("auth scheme not registered")
+	}
+	req := &engine.ResolvedRequest{Method: "GET", URL: "https://example.com", Headers: []requests.Header{}}
+	if err := provider(req, &requests.Auth{Type: AuthType("demo", "sign"), Params: map[string]string{"key": "k1"}}); err != nil {
+		t.Fatalf("auth provider error = %v", err)
+	}
+	if len(req.Headers) != 1 || req.Headers[0].Value != "sign:k1" || req.URL != "https://example.com" {
+		t.Errorf("auth provider request = %+v", req)
+	}
+
+	if names := middleware.Names(); len(names) != 1 || names[0] != MiddlewareName("demo") {
+		t.Fatalf("middleware = %v, want the beforeSend hook", names)
+	}
+	hooked := middleware.Then(func(ctx context.Context, call *engine.Call) (*engine.Execution, error) {
+		return &engine.Execution{Request: *call.Request}, nil
+	})
+	exec, err := hooked(context.Background(), &engine.Call{RequestID: "r1", Request: req})
+	if err != nil || len(exec.Request.Headers) != 2 || exec.Request.Headers[1].Value != "r1" {
+		t.Errorf("beforeSend hook = %+v, %v", exec, err)
+	}
+
+	nodes, err := m.Import("demo", "lines", "Imported", []byte("GET /users\nPOST /users"))
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].Item.Name != "Imported" || len(nodes[0].Children) != 2 || nodes[0].Children[1].Item.Method != "POST" {
+		t.Errorf("Import() = %+v", nodes)
+	}
+	if _, err := m.Import("demo", "missing", "x", nil); err == nil {
+		t.Error("Import() with an unknown format should fail")
+	}
+
+	data, err := m.Export("demo", "name", nodes)
+	if err != nil || string(data) != "Imported" {
+		t.Errorf("Export() = %q, %v", data, err)
+	}
+	if refs := m.Importers(); len(refs) != 1 || refs[0].Plugin != "demo" || refs[0].ID != "lines" {
+		t.Errorf("Importers() = %+v", refs)
+	}
+
+	if err := m.Disable("demo"); err != nil {
+		t.Fatalf("Disable() error = %v", err)
+	}
+	if _, ok := funcs.Lookup("demo.upper"); ok {
+		t.Error("template function still registered after Disable")
+	}
+	if _, ok := auth.Lookup(AuthType("demo", "sign")); ok {
+		t.Error("auth scheme still registered after Disable")
+	}
+	if names := middleware.Names(); len(names) != 0 {
+		t.Errorf("middleware still registered after Disable: %v", names)
+	}
+	if refs := m.Exporters(); len(refs) != 0 {
+		t.Errorf("Exporters() after Disable = %+v", refs)
+	}
+}
+
+func TestDiscoverStopsRemovedPlugins(t *testing.T) {
+	dir := installHelper(t)
+	funcs, auth, middleware := engine.NewFuncRegistry(), engine.NewAuthRegistry(), engine.NewMiddlewareRegistry()
+	m := NewManager(dir, "test", funcs, auth, middleware)
+	t.Cleanup(m.Close)
+
+	if err := m.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Enable("demo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rediscovering keeps a plugin that is still installed running
+	if err := m.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	if infos := m.List(); len(infos) != 1 || !infos[0].Running {
+		t.Fatalf("List() after rediscovery = %+v", infos)
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, "demo")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	if infos := m.List(); len(infos) != 0 {
+		t.Errorf("List() after removal = %+v", infos)
+	}
+	if _, ok := funcs.Lookup("demo.upper"); ok {
+		t.Error("template function of a removed plugin is still registered")
+	}
+}
+
+func TestManifestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest Manifest
+		wantErr  bool
+	}{
+		{"valid", Manifest{Name: "demo", Executable: "run"}, false},
+		{"no executable", Manifest{Name: "demo"}, true},
+		{"dotted name", Manifest{Name: "a.b", Executable: "run"}, true},
+		{"dotted function", Manifest{Name: "demo", Executable: "run", TemplateFunctions: []string{"a.b"}}, true},
+		{"bad format id", Manifest{Name: "demo", Executable: "run", Importers: []Format{{ID: "x y"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.manifest.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"paperbox/internal/config/requests"
+	"paperbox/internal/engine"
+)
+
+// The protocol: the app writes one JSON request per line to the plugin's stdin and the plugin
+// answers each with one JSON response line on stdout carrying the same id. Stderr is passed
+// through to the app's stderr for logging. Methods and their params/results:
+//
+//	initialize  {appVersion, protocolVersion}            -> {}
+//	import      {format, name, data (base64)}            -> {nodes: [Node]}
+//	export      {format, nodes: [Node]}                  -> {data (base64)}
+//	auth        {scheme, params, request}                -> {request}
+//	template    {function, args}                         -> {value}
+//	beforeSend  {requestId, request}                     -> {request}
+//	shutdown    {}                                       -> {} and then exit
+const ProtocolVersion = 1
+
+const (
+	// DefaultCallTimeout bounds how long a plugin may take to answer a call
+	DefaultCallTimeout = 30 * time.Second
+	// shutdownTimeout is how long a plugin gets to exit after shutdown before it is killed
+	shutdownTimeout = 2 * time.Second
+	// maxMessageSize bounds a single response line (imports can be large)
+	maxMessageSize = 64 << 20
+)
+
+type rpcRequest struct {
+	ID     int64       `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+type initializeParams struct {
+	AppVersion      string `json:"appVersion"`
+	ProtocolVersion int    `json:"protocolVersion"`
+}
+
+type importParams struct {
+	Format string `json:"format"`
+	Name   string `json:"name"`
+	Data   []byte `json:"data"`
+}
+
+type importResult struct {
+	Nodes []requests.Node `json:"nodes"`
+}
+
+type exportParams struct {
+	Format string          `json:"format"`
+	Nodes  []requests.Node `json:"nodes"`
+}
+
+type exportResult struct {
+	Data []byte `json:"data"`
+}
+
+type authParams struct {
+	Scheme  string                 `json:"scheme"`
+	Params  map[string]string      `json:"params"`
+	Request engine.ResolvedRequest `json:"request"`
+}
+
+type authResult struct {
+	Request engine.ResolvedRequest `json:"request"`
+}
+
+type beforeSendParams struct {
+	RequestID string                 `json:"requestId"`
+	Request   engine.ResolvedRequest `json:"request"`
+}
+
+type beforeSendResult struct {
+	Request engine.ResolvedRequest `json:"request"`
+}
+
+type templateParams struct {
+	Function string   `json:"function"`
+	Args     []string `json:"args"`
+}
+
+type templateResult struct {
+	Value string `json:"value"`
+}
+
+// process is a running plugin executable. Calls are serialized: one request is in flight at a time.
+type process struct {
+	name      string
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	responses chan rpcResponse
+	exited    chan struct{} // Closed once stdout is closed
+	exitErr   error         // Why reading stopped, valid after exited is closed
+
+	mu     sync.Mutex
+	nextID int64
+}
+
+// startProcess launches the plugin executable in its directory
+func startProcess(manifest *Manifest, dir string) (*process, error) {
+	cmd := exec.Command(manifest.executablePath(dir), manifest.Args...)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", manifest.Name, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", manifest.Name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", manifest.Name, err)
+	}
+
+	p := &process{
+		name:      manifest.Name,
+		cmd:       cmd,
+		stdin:     stdin,
+		responses: make(chan rpcResponse),
+		exited:    make(chan struct{}),
+	}
+	go p.read(stdout)
+	return p, nil
+}
+
+// read forwards response lines until stdout closes
+func (p *process) read(stdout io.Reader) {
+	defer close(p.exited)
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		var resp rpcResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			fmt.Fprintf(os.Stderr, "Plugin %s wrote an invalid message: %v\n", p.name, err)
+			continue
+		}
+		select {
+		case p.responses <- resp:
+		case <-time.After(DefaultCallTimeout):
+			// Nobody is waiting (the call timed out); drop the late answer
+		}
+	}
+	p.exitErr = scanner.Err()
+}
+
+// call sends a request and decodes the result into result (which may be nil)
+func (p *process) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.nextID++
+	id := p.nextID
+	line, err := json.Marshal(rpcRequest{ID: id, Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+	if _, err := p.stdin.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("plugin %s is not running: %w", p.name, err)
+	}
+
+	for {
+		select {
+		case resp := <-p.responses:
+			if resp.ID != id {
+				continue // Answer to an earlier call that timed out
+			}
+			if resp.Error != nil {
+				return fmt.Errorf("plugin %s: %s", p.name, resp.Error.Message)
+			}
+			if result == nil || len(resp.Result) == 0 {
+				return nil
+			}
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return fmt.Errorf("plugin %s returned an invalid %s result: %w", p.name, method, err)
+			}
+			return nil
+		case <-p.exited:
+			if p.exitErr != nil {
+				return fmt.Errorf("plugin %s exited: %w", p.name, p.exitErr)
+			}
+			return fmt.Errorf("plugin %s exited before answering %s", p.name, method)
+		case <-ctx.Done():
+			return fmt.Errorf("plugin %s did not answer %s: %w", p.name, method, ctx.Err())
+		}
+	}
+}
+
+// stop asks the plugin to shut down and kills it if it does not exit in time
+func (p *process) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	_ = p.call(ctx, "shutdown", struct{}{}, nil)
+	_ = p.stdin.Close()
+
+	select {
+	case <-p.exited:
+	case <-ctx.Done():
+		_ = p.cmd.Process.Kill()
+	}
+	_ = p.cmd.Wait()
+}
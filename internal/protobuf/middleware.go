@@ -0,0 +1,89 @@
+package protobuf
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strings"
+
+	"paperbox/internal/config/requests"
+	"paperbox/internal/engine"
+	"paperbox/internal/response"
+)
+
+// ContentType is set on encoded request bodies unless the request already declares a protobuf type
+const ContentType = "application/x-protobuf"
+
+// Middleware encodes the JSON body of requests declaring a protobuf request message and decodes
+// successful responses of those declaring a response message, so assertions and the response view
+// work on JSON. The execution shows the request body as the JSON it was written in. Register it at
+// engine.OrderSend so it sees the final request.
+func Middleware(reg *Registry) engine.Middleware {
+	return func(next engine.Handler) engine.Handler {
+		return func(ctx context.Context, call *engine.Call) (*engine.Execution, error) {
+			var body *requests.ProtobufBody
+			if call.Sources.Requests != nil {
+				body = call.Sources.Requests.Values[call.RequestID].Protobuf
+			}
+			if body == nil || call.Request == nil {
+				return next(ctx, call)
+			}
+
+			written := *call.Request
+			if body.RequestMessage != "" {
+				encoded, err := reg.Encode(body.RequestMessage, []byte(written.Body))
+				if err != nil {
+					return nil, err
+				}
+				req := written
+				req.Body = string(encoded)
+				req.Headers = withContentType(req.Headers)
+				call.Request = &req
+			}
+
+			exec, err := next(ctx, call)
+			if err != nil || exec == nil {
+				return exec, err
+			}
+			if body.RequestMessage != "" {
+				exec.Request.Body = written.Masked().Body
+			}
+			if body.ResponseMessage != "" && decodable(exec) {
+				decoded, err := reg.Decode(body.ResponseMessage, exec.RawBody())
+				if err != nil {
+					exec.Error = err.Error()
+					return exec, nil
+				}
+				exec.Body = string(decoded)
+				exec.FormattedBody = string(decoded)
+				exec.MimeType = "application/json"
+				exec.Binary = nil
+			}
+			return exec, nil
+		}
+	}
+}
+
+// decodable reports whether a response is a complete, successful body that was not declared as text
+func decodable(exec *engine.Execution) bool {
+	if exec.Error != "" || exec.Truncated || exec.Status < 200 || exec.Status >= 300 || len(exec.RawBody()) == 0 {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(http.Header(exec.Headers).Get("Content-Type"))
+	return !response.IsText(mediaType)
+}
+
+// withContentType replaces the Content-Type of headers with ContentType, keeping a protobuf one
+func withContentType(headers []requests.Header) []requests.Header {
+	result := make([]requests.Header, 0, len(headers)+1)
+	for _, h := range headers {
+		if strings.EqualFold(h.Key, "Content-Type") && !h.Disabled {
+			if strings.Contains(h.Value, "proto") {
+				return headers
+			}
+			continue
+		}
+		result = append(result, h)
+	}
+	return append(result, requests.Header{Key: "Content-Type", Value: ContentType})
+}
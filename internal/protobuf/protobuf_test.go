@@ -0,0 +1,163 @@
+package protobuf
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"paperbox/internal/config/requests"
+	"paperbox/internal/engine"
+)
+
+const shopProto = `syntax = "proto3";
+package shop.v1;
+
+import "common.proto";
+
+message Order {
+  string id = 1;
+  repeated Item items = 2;
+  map<string, string> labels = 3;
+
+  message Item {
+    string sku = 1;
+    int32 quantity = 2;
+  }
+}
+
+message Receipt {
+  string order_id = 1;
+  common.Money total = 2;
+}
+`
+
+const commonProto = `syntax = "proto3";
+package common;
+
+message Money {
+  string currency = 1;
+  int64 cents = 2;
+}
+`
+
+func writeProtos(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range map[string]string{"shop.proto": shopProto, "common.proto": commonProto} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, "shop.proto")
+}
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry()
+	messages, err := reg.Register(writeProtos(t))
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	want := []string{"shop.v1.Order", "shop.v1.Order.Item", "shop.v1.Receipt"}
+	if !slices.Equal(messages, want) {
+		t.Errorf("Register() = %v, want %v", messages, want)
+	}
+
+	encoded, err := reg.Encode("shop.v1.Order", []byte(`{"id": "o-1", "items": [{"sku": "A", "quantity": 2}]}`))
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	decoded, err := reg.Decode("shop.v1.Order", encoded)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	var order map[string]any
+	if err := json.Unmarshal(decoded, &order); err != nil || order["id"] != "o-1" {
+		t.Errorf("Decode() = %s, want the encoded order", decoded)
+	}
+
+	if _, err := reg.Encode("shop.v1.Order", []byte(`{"unknown": 1}`)); err == nil {
+		t.Error("Encode() with an unknown field should fail")
+	}
+	if _, err := reg.Encode("shop.v1.Missing", nil); err == nil {
+		t.Error("Encode() with an unregistered message should fail")
+	}
+}
+
+func TestRegisterInvalidProto(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.proto")
+	if err := os.WriteFile(path, []byte(`syntax = "proto3"; message {`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reg := NewRegistry()
+	if _, err := reg.Register(path); err == nil {
+		t.Fatal("Register() with a syntax error should fail")
+	}
+	if len(reg.Files()) != 0 {
+		t.Errorf("Files() = %v, want none after a failed registration", reg.Files())
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	reg := NewRegistry()
+	if _, err := reg.Register(writeProtos(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	var gotType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotType = r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		order, err := reg.Decode("shop.v1.Order", body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var decoded struct{ ID string }
+		json.Unmarshal(order, &decoded)
+		receipt, _ := reg.Encode("shop.v1.Receipt", []byte(`{"orderId": "`+decoded.ID+`", "total": {"currency": "EUR", "cents": "1250"}}`))
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Write(receipt)
+	}))
+	defer server.Close()
+
+	engine.DefaultMiddleware.Register("protobuf", engine.OrderSend, Middleware(reg))
+	defer engine.DefaultMiddleware.Unregister("protobuf")
+
+	body := `{"id": "o-7"}`
+	cfg := &requests.RequestsConfig{Values: map[string]requests.Item{
+		"create": {
+			Type: requests.ItemTypeRequest, Name: "Create", Method: "POST", Path: "/orders", Body: body,
+			Headers:  []requests.Header{{Key: "Content-Type", Value: "application/json"}},
+			Protobuf: &requests.ProtobufBody{RequestMessage: "shop.v1.Order", ResponseMessage: "shop.v1.Receipt"},
+		},
+	}}
+	exec, err := engine.NewWithClient(server.Client()).Run(context.Background(), engine.Sources{Requests: cfg, UserBaseURL: server.URL}, "create")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if exec.Status != http.StatusOK || exec.Error != "" {
+		t.Fatalf("Run() status %d, error %q", exec.Status, exec.Error)
+	}
+	if gotType != ContentType {
+		t.Errorf("server received Content-Type %q, want %q", gotType, ContentType)
+	}
+	if exec.Request.Body != body {
+		t.Errorf("execution request body = %q, want the JSON it was written in", exec.Request.Body)
+	}
+	var receipt struct {
+		OrderID string `json:"orderId"`
+		Total   struct{ Currency string }
+	}
+	if err := json.Unmarshal([]byte(exec.Body), &receipt); err != nil || receipt.OrderID != "o-7" || receipt.Total.Currency != "EUR" {
+		t.Errorf("response body = %s, want the decoded receipt", exec.Body)
+	}
+	if exec.MimeType != "application/json" || exec.Binary != nil {
+		t.Errorf("response is %s (binary %v), want decoded JSON", exec.MimeType, exec.Binary != nil)
+	}
+}
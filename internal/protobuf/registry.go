@@ -0,0 +1,157 @@
+// Package protobuf keeps the message types of registered .proto files so request bodies can be
+// written as JSON and sent as protobuf, and protobuf responses shown as JSON (see Middleware).
+package protobuf
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"paperbox/internal/apperrors"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Registry is a concurrency-safe set of compiled .proto files keyed by path
+type Registry struct {
+	mu    sync.RWMutex
+	files map[string]protoreflect.FileDescriptor
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{files: make(map[string]protoreflect.FileDescriptor)}
+}
+
+// Register compiles a .proto file, resolving its imports against its directory and the well-known
+// types, and returns the full names of the messages it defines. Registering a path again reloads it.
+func (r *Registry) Register(path string) ([]string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to resolve .proto path")
+	}
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: []string{filepath.Dir(path)}}),
+	}
+	files, err := compiler.Compile(context.Background(), filepath.Base(path))
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.ValidationFailed, err, "failed to compile "+filepath.Base(path))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.files[path] = files[0]
+	return messageNames(files[0].Messages()), nil
+}
+
+// Unregister removes a .proto file
+func (r *Registry) Unregister(path string) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.files, path)
+}
+
+// Files returns the registered .proto files, sorted
+func (r *Registry) Files() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	paths := make([]string, 0, len(r.files))
+	for path := range r.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Messages returns the full names of the messages of every registered file, sorted
+func (r *Registry) Messages() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var names []string
+	for _, file := range r.files {
+		names = append(names, messageNames(file.Messages())...)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns a registered message type by full name, e.g. "shop.v1.Order"
+func (r *Registry) Lookup(name string) (protoreflect.MessageDescriptor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, file := range r.files {
+		if desc := findMessage(file.Messages(), protoreflect.FullName(name)); desc != nil {
+			return desc, true
+		}
+	}
+	return nil, false
+}
+
+// Encode converts a JSON document (protobuf JSON mapping) into the binary encoding of a message
+func (r *Registry) Encode(message string, data []byte) ([]byte, error) {
+	desc, ok := r.Lookup(message)
+	if !ok {
+		return nil, apperrors.NotFoundf("protobuf message %s is not registered", message)
+	}
+	msg := dynamicpb.NewMessage(desc)
+	if len(data) > 0 {
+		if err := protojson.Unmarshal(data, msg); err != nil {
+			return nil, apperrors.Wrap(apperrors.ValidationFailed, err, "body does not match "+message)
+		}
+	}
+	encoded, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", message, err)
+	}
+	return encoded, nil
+}
+
+// Decode converts the binary encoding of a message into indented JSON
+func (r *Registry) Decode(message string, data []byte) ([]byte, error) {
+	desc, ok := r.Lookup(message)
+	if !ok {
+		return nil, apperrors.NotFoundf("protobuf message %s is not registered", message)
+	}
+	msg := dynamicpb.NewMessage(desc)
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("response is not a valid %s: %w", message, err)
+	}
+	return protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(msg)
+}
+
+// messageNames lists the full names of messages and their nested messages, skipping map entries
+func messageNames(messages protoreflect.MessageDescriptors) []string {
+	var names []string
+	for i := 0; i < messages.Len(); i++ {
+		desc := messages.Get(i)
+		if desc.IsMapEntry() {
+			continue
+		}
+		names = append(names, string(desc.FullName()))
+		names = append(names, messageNames(desc.Messages())...)
+	}
+	return names
+}
+
+// findMessage looks a message up by full name among messages and their nested messages
+func findMessage(messages protoreflect.MessageDescriptors, name protoreflect.FullName) protoreflect.MessageDescriptor {
+	for i := 0; i < messages.Len(); i++ {
+		desc := messages.Get(i)
+		if desc.FullName() == name {
+			return desc
+		}
+		if nested := findMessage(desc.Messages(), name); nested != nil {
+			return nested
+		}
+	}
+	return nil
+}
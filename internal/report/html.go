@@ -0,0 +1,139 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+)
+
+// htmlPage is a standalone summary page of a run
+var htmlPage = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Folder}} – run report</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 1100px; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; color: #1f2328; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1.5rem; }
+th, td { border: 1px solid #d0d7de; padding: 0.3rem 0.6rem; text-align: left; vertical-align: top; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; word-break: break-all; }
+.passed { color: #1a7f37; }
+.failed { color: #cf222e; }
+.note { color: #9a6700; }
+ul { margin: 0; padding-left: 1.2rem; }
+</style>
+</head>
+<body>
+<h1>{{.Folder}}</h1>
+<p>Started {{.StartedAt}} · {{.Result.DurationMs}} ms ·
+<span class="passed">{{.Result.Passed}} passed</span> ·
+<span class="failed">{{.Result.Failed}} failed</span>
+{{- if .Result.OverBudget}} · <span class="note">{{.Result.OverBudget}} over budget</span>{{end}}
+{{- if .Result.Flaky}} · <span class="note">{{.Result.Flaky}} flaky</span>{{end}}
+{{- if .Result.Cancelled}} · <span class="note">cancelled</span>{{end}}</p>
+<table>
+<tr><th>Request</th><th>Status</th><th>Time</th><th>Result</th></tr>
+{{- range .Rows}}
+<tr>
+<td>{{.Name}}<br><code>{{.Method}} {{.URL}}</code></td>
+<td>{{if .Status}}{{.Status}}{{end}}</td>
+<td>{{.DurationMs}} ms</td>
+<td>{{if .Passed}}<span class="passed">passed</span>{{else}}<span class="failed">failed</span>{{end}}
+{{- if .Flaky}} <span class="note">flaky</span>{{end}}
+{{- if .Notes}}<ul>{{range .Notes}}<li>{{.}}</li>{{end}}</ul>{{end}}</td>
+</tr>
+{{- end}}
+</table>
+{{- if .Stability}}
+<h2>Stability</h2>
+<table>
+<tr><th>Request</th><th>Attempts</th><th>Failures</th><th>Latency (min / median / max)</th><th>Flaky</th></tr>
+{{- range .Stability}}
+<tr>
+<td>{{.Name}}</td>
+<td>{{.Attempts}}</td>
+<td>{{.Failures}}</td>
+<td>{{.MinMs}} / {{.MedianMs}} / {{.MaxMs}} ms</td>
+<td>{{if .Flaky}}<span class="note">yes</span><ul>{{range .Reasons}}<li>{{.}}</li>{{end}}</ul>{{else}}no{{end}}</td>
+</tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// htmlRow is one execution in the HTML summary
+type htmlRow struct {
+	Name       string
+	Method     string
+	URL        string
+	Status     int
+	DurationMs int64
+	Passed     bool
+	Flaky      bool
+	Notes      []string // Errors, failed tests and budget warnings
+}
+
+// htmlStability is the stability of one request in the HTML summary
+type htmlStability struct {
+	Name                   string
+	Attempts, Failures     int
+	MinMs, MedianMs, MaxMs int64
+	Flaky                  bool
+	Reasons                []string
+}
+
+// HTML renders a run as a standalone HTML summary
+func HTML(run Run) ([]byte, error) {
+	data := struct {
+		Run
+		StartedAt string
+		Rows      []htmlRow
+		Stability []htmlStability
+	}{Run: run, StartedAt: run.Result.StartedAt.Format("2006-01-02 15:04:05 MST")}
+
+	for _, exec := range run.Result.Executions {
+		row := htmlRow{
+			Name:       run.Name(exec.RequestID),
+			Method:     exec.Request.Method,
+			URL:        exec.Request.URL,
+			Status:     exec.Status,
+			DurationMs: exec.DurationMs,
+			Passed:     passed(exec),
+			Flaky:      exec.Flaky,
+		}
+		if exec.Error != "" {
+			row.Notes = append(row.Notes, exec.Error)
+		}
+		for _, failed := range failures(exec) {
+			note := failed.Name
+			if failed.Message != "" {
+				note = fmt.Sprintf("%s: %s", failed.Name, failed.Message)
+			}
+			row.Notes = append(row.Notes, note)
+		}
+		for _, warning := range exec.BudgetWarnings {
+			row.Notes = append(row.Notes, warning.Message)
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	for _, s := range run.Result.Stability {
+		data.Stability = append(data.Stability, htmlStability{
+			Name:     run.Name(s.RequestID),
+			Attempts: s.Attempts,
+			Failures: s.Failures,
+			MinMs:    s.MinMs,
+			MedianMs: s.MedianMs,
+			MaxMs:    s.MaxMs,
+			Flaky:    s.Flaky,
+			Reasons:  s.Reasons,
+		})
+	}
+
+	var page bytes.Buffer
+	if err := htmlPage.Execute(&page, data); err != nil {
+		return nil, fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return page.Bytes(), nil
+}
@@ -0,0 +1,121 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// junitSuites is the root element of a JUnit XML report
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+// junitSuite holds the executions of one run
+type junitSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitCase     `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// junitCase is one execution; its failed test results become failures, transport errors an error
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failures  []junitResult `xml:"failure,omitempty"`
+	Error     *junitResult  `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitResult struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit renders a run as JUnit XML, one test case per execution
+func JUnit(run Run) ([]byte, error) {
+	result := run.Result
+	suite := junitSuite{
+		Name:      run.Folder,
+		Time:      seconds(result.DurationMs),
+		Timestamp: result.StartedAt.UTC().Format(time.RFC3339),
+	}
+	if result.Flaky > 0 {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "flaky", Value: fmt.Sprint(result.Flaky)})
+	}
+	if result.Cancelled {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "cancelled", Value: "true"})
+	}
+
+	// Repeated runs execute a request several times; number the attempts to keep names unique
+	attempts := make(map[string]int)
+	for _, exec := range result.Executions {
+		attempts[exec.RequestID]++
+		name := run.Name(exec.RequestID)
+		if attempts[exec.RequestID] > 1 {
+			name = fmt.Sprintf("%s (attempt %d)", name, attempts[exec.RequestID])
+		}
+
+		c := junitCase{
+			Name:      name,
+			Classname: run.Folder,
+			Time:      seconds(exec.DurationMs),
+			SystemOut: strings.TrimSpace(fmt.Sprintf("%s %s -> %d %s", exec.Request.Method, exec.Request.URL, exec.Status, exec.StatusText)),
+		}
+		if exec.Flaky {
+			c.SystemOut += "\nflaky: attempts of this request disagreed"
+		}
+		for _, warning := range exec.BudgetWarnings {
+			c.SystemOut += "\nbudget: " + warning.Message
+		}
+		if exec.Error != "" {
+			c.Error = &junitResult{Message: exec.Error, Text: exec.Error}
+			suite.Errors++
+		}
+		for _, failed := range failures(exec) {
+			c.Failures = append(c.Failures, junitResult{Message: failed.Name, Text: failed.Message})
+		}
+		if len(c.Failures) > 0 {
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	suites := junitSuites{
+		Name:     run.Folder,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Time:     suite.Time,
+		Suites:   []junitSuite{suite},
+	}
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JUnit report: %w", err)
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// seconds formats milliseconds as the decimal seconds JUnit uses
+func seconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
@@ -0,0 +1,195 @@
+// Package report renders the results of collection runs for CI systems and people: JUnit XML,
+// a machine-readable JSON report and a standalone HTML summary. Other packages may register more formats.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/requests"
+	"paperbox/internal/engine"
+)
+
+const (
+	FormatJUnit = "junit"
+	FormatJSON  = "json"
+	FormatHTML  = "html"
+)
+
+// Run is a collection run together with the names reports describe it with
+type Run struct {
+	Result *engine.RunResult
+	Folder string            // Name of the folder that was run
+	Names  map[string]string // Request names by ID
+}
+
+// NewRun looks up the names of the folder and requests of a run
+func NewRun(result *engine.RunResult, cfg *requests.RequestsConfig) Run {
+	run := Run{Result: result, Folder: cfg.Values[result.FolderID].Name, Names: make(map[string]string)}
+	for _, exec := range result.Executions {
+		if item, exists := cfg.Values[exec.RequestID]; exists {
+			run.Names[exec.RequestID] = item.Name
+		}
+	}
+	return run
+}
+
+// Name returns the name of a request in the run, falling back to its ID
+func (r Run) Name(requestID string) string {
+	if name := r.Names[requestID]; name != "" {
+		return name
+	}
+	return requestID
+}
+
+// Reporter renders a run in one format
+type Reporter func(run Run) ([]byte, error)
+
+// Registry is a concurrency-safe set of reporters by format
+type Registry struct {
+	mu        sync.RWMutex
+	reporters map[string]Reporter
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{reporters: make(map[string]Reporter)}
+}
+
+// Register adds or replaces the reporter of a format
+func (r *Registry) Register(format string, reporter Reporter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reporters[format] = reporter
+}
+
+// Formats returns the registered formats, sorted
+func (r *Registry) Formats() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	formats := make([]string, 0, len(r.reporters))
+	for format := range r.reporters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Render renders a run with the reporter of a format
+func (r *Registry) Render(format string, run Run) ([]byte, error) {
+	r.mu.RLock()
+	reporter, ok := r.reporters[format]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, apperrors.Invalidf("unsupported report format '%s'", format)
+	}
+	return reporter(run)
+}
+
+// DefaultReporters holds the built-in formats
+var DefaultReporters = NewRegistry()
+
+func init() {
+	DefaultReporters.Register(FormatJUnit, JUnit)
+	DefaultReporters.Register(FormatJSON, JSON)
+	DefaultReporters.Register(FormatHTML, HTML)
+}
+
+// jsonReport is the layout of JSON reports; it leaves out response bodies
+type jsonReport struct {
+	ID         string             `json:"id"`
+	FolderID   string             `json:"folderId"`
+	Folder     string             `json:"folder"`
+	StartedAt  time.Time          `json:"startedAt"`
+	DurationMs int64              `json:"durationMs"`
+	Passed     int                `json:"passed"`
+	Failed     int                `json:"failed"`
+	OverBudget int                `json:"overBudget"`
+	Flaky      int                `json:"flaky"`
+	Cancelled  bool               `json:"cancelled"`
+	Requests   []jsonRequest      `json:"requests"`
+	Stability  []engine.Stability `json:"stability,omitempty"`
+}
+
+// jsonRequest is one execution in a JSON report
+type jsonRequest struct {
+	RequestID      string                 `json:"requestId"`
+	Name           string                 `json:"name"`
+	Method         string                 `json:"method"`
+	URL            string                 `json:"url"`
+	Status         int                    `json:"status"`
+	DurationMs     int64                  `json:"durationMs"`
+	Passed         bool                   `json:"passed"`
+	Error          string                 `json:"error,omitempty"`
+	Tests          []engine.TestResult    `json:"tests,omitempty"`
+	BudgetWarnings []engine.BudgetWarning `json:"budgetWarnings,omitempty"`
+	Flaky          bool                   `json:"flaky,omitempty"`
+}
+
+// JSON renders a run as an indented JSON report
+func JSON(run Run) ([]byte, error) {
+	result := run.Result
+	report := jsonReport{
+		ID:         result.ID,
+		FolderID:   result.FolderID,
+		Folder:     run.Folder,
+		StartedAt:  result.StartedAt,
+		DurationMs: result.DurationMs,
+		Passed:     result.Passed,
+		Failed:     result.Failed,
+		OverBudget: result.OverBudget,
+		Flaky:      result.Flaky,
+		Cancelled:  result.Cancelled,
+		Requests:   make([]jsonRequest, 0, len(result.Executions)),
+		Stability:  result.Stability,
+	}
+	for _, exec := range result.Executions {
+		report.Requests = append(report.Requests, jsonRequest{
+			RequestID:      exec.RequestID,
+			Name:           run.Name(exec.RequestID),
+			Method:         exec.Request.Method,
+			URL:            exec.Request.URL,
+			Status:         exec.Status,
+			DurationMs:     exec.DurationMs,
+			Passed:         passed(exec),
+			Error:          exec.Error,
+			Tests:          exec.Tests,
+			BudgetWarnings: exec.BudgetWarnings,
+			Flaky:          exec.Flaky,
+		})
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON report: %w", err)
+	}
+	return data, nil
+}
+
+// passed reports whether an execution got a response and passed all of its tests
+func passed(exec *engine.Execution) bool {
+	if exec.Error != "" {
+		return false
+	}
+	for _, result := range exec.Tests {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// failures returns the failed test results of an execution
+func failures(exec *engine.Execution) []engine.TestResult {
+	var failed []engine.TestResult
+	for _, result := range exec.Tests {
+		if !result.Passed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,90 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"paperbox/internal/config/requests"
+	"paperbox/internal/engine"
+)
+
+func testRun() Run {
+	cfg := &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"root": {Type: requests.ItemTypeFolder, Name: "Users <API>", Children: []string{"list", "get"}},
+			"list": {Type: requests.ItemTypeRequest, Name: "List users", Method: "GET", Path: "/users"},
+			"get":  {Type: requests.ItemTypeRequest, Name: "Get user", Method: "GET", Path: "/users/1"},
+		},
+	}
+	result := &engine.RunResult{
+		ID:         "run-1",
+		FolderID:   "root",
+		StartedAt:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		DurationMs: 1500,
+		Passed:     1,
+		Failed:     2,
+		Executions: []*engine.Execution{
+			{RequestID: "list", Request: engine.ResolvedRequest{Method: "GET", URL: "https://api.example.com/users"}, Status: 200, DurationMs: 120,
+				Tests: []engine.TestResult{{Name: "Snapshot status", Passed: true}}},
+			{RequestID: "get", Request: engine.ResolvedRequest{Method: "GET", URL: "https://api.example.com/users/1"}, Status: 500, DurationMs: 80,
+				Tests: []engine.TestResult{{Name: "Snapshot status", Message: "status 500, snapshot has 200"}}},
+			{RequestID: "get", Request: engine.ResolvedRequest{Method: "GET", URL: "https://api.example.com/users/1"}, Error: "connection refused"},
+		},
+	}
+	return NewRun(result, cfg)
+}
+
+func TestJUnit(t *testing.T) {
+	data, err := DefaultReporters.Render(FormatJUnit, testRun())
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	var suites junitSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("report is not valid XML: %v\n%s", err, data)
+	}
+	if suites.Tests != 3 || suites.Failures != 1 || suites.Errors != 1 || suites.Time != "1.500" {
+		t.Errorf("JUnit() totals = %+v", suites)
+	}
+	cases := suites.Suites[0].Cases
+	if cases[0].Name != "List users" || cases[0].Classname != "Users <API>" || len(cases[0].Failures) != 0 {
+		t.Errorf("JUnit() first case = %+v", cases[0])
+	}
+	if len(cases[1].Failures) != 1 || cases[1].Failures[0].Text != "status 500, snapshot has 200" {
+		t.Errorf("JUnit() second case = %+v", cases[1])
+	}
+	if cases[2].Name != "Get user (attempt 2)" || cases[2].Error == nil {
+		t.Errorf("JUnit() third case = %+v", cases[2])
+	}
+}
+
+func TestJSONAndHTML(t *testing.T) {
+	data, err := DefaultReporters.Render(FormatJSON, testRun())
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	var report jsonReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	if report.Folder != "Users <API>" || len(report.Requests) != 3 || !report.Requests[0].Passed || report.Requests[1].Passed || report.Requests[2].Error == "" {
+		t.Errorf("JSON() = %+v", report)
+	}
+
+	page, err := DefaultReporters.Render(FormatHTML, testRun())
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	html := string(page)
+	if !strings.Contains(html, "<h1>Users &lt;API&gt;</h1>") || !strings.Contains(html, "status 500, snapshot has 200") {
+		t.Errorf("HTML() = %s", html)
+	}
+
+	if _, err := DefaultReporters.Render("pdf", testRun()); err == nil {
+		t.Error("Render() accepted an unknown format")
+	}
+}
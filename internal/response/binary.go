@@ -0,0 +1,100 @@
+package response
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF for DecodeConfig
+	_ "image/jpeg" // register JPEG for DecodeConfig
+	_ "image/png"  // register PNG for DecodeConfig
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// MaxPreviewSize is the largest binary body returned inline as base64; bigger bodies go to a temp file
+	MaxPreviewSize = 2 << 20
+
+	tempDirName = "paperbox-responses"
+)
+
+// Binary describes a non-text response body
+type Binary struct {
+	MimeType string `json:"mimeType"`
+	Size     int    `json:"size"`
+	Preview  string `json:"preview,omitempty"`  // base64 data for small bodies
+	TempPath string `json:"tempPath,omitempty"` // file holding the body when it is too large to preview
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+}
+
+// DescribeBinary builds the binary metadata for a processed body. Image dimensions are filled in for
+// PNG, JPEG and GIF. Bodies larger than MaxPreviewSize are written to a temp file instead of inlined.
+func DescribeBinary(body *Body) (*Binary, error) {
+	bin := &Binary{MimeType: body.MimeType, Size: len(body.Bytes)}
+
+	if strings.HasPrefix(body.MimeType, "image/") {
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(body.Bytes)); err == nil {
+			bin.Width, bin.Height = cfg.Width, cfg.Height
+		}
+	}
+
+	if len(body.Bytes) <= MaxPreviewSize {
+		bin.Preview = base64.StdEncoding.EncodeToString(body.Bytes)
+		return bin, nil
+	}
+
+	path, err := writeTempFile(body.Bytes, body.MimeType)
+	if err != nil {
+		return bin, err
+	}
+	bin.TempPath = path
+	return bin, nil
+}
+
+// writeTempFile stores data in the response temp directory with an extension matching the MIME type
+func writeTempFile(data []byte, mediaType string) (string, error) {
+	path, _, err := WriteTempBody(bytes.NewReader(data), mediaType)
+	return path, err
+}
+
+// WriteTempBody streams a body into the response temp directory, with an extension matching the
+// MIME type, and returns the file and the number of bytes written
+func WriteTempBody(r io.Reader, mediaType string) (string, int64, error) {
+	dir := TempDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", 0, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	ext := ""
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		ext = exts[0]
+	}
+
+	f, err := os.CreateTemp(dir, "response-*"+ext)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer f.Close()
+
+	size, err := io.Copy(f, r)
+	if err != nil {
+		os.Remove(f.Name())
+		return "", size, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return f.Name(), size, nil
+}
+
+// TempDir returns the directory response bodies too large to preview are written to
+func TempDir() string {
+	return filepath.Join(os.TempDir(), tempDirName)
+}
+
+// RemoveTempFiles deletes every response temp file (called on shutdown)
+func RemoveTempFiles() error {
+	return os.RemoveAll(TempDir())
+}
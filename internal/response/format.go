@@ -0,0 +1,199 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const indent = "  "
+
+// htmlVoidElements never have children or closing tags
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// htmlRawElements keep their content untouched
+var htmlRawElements = map[string]bool{
+	"pre": true, "textarea": true, "script": true, "style": true,
+}
+
+// Format pretty-prints text of a known media type. Returns false when the type is not
+// supported or the text cannot be parsed.
+func Format(mediaType string, text string) (string, bool) {
+	var (
+		formatted string
+		err       error
+	)
+	switch {
+	case isJSON(mediaType):
+		formatted, err = formatJSON(text)
+	case isXML(mediaType):
+		formatted, err = formatXML(text)
+	case mediaType == "text/html":
+		formatted, err = formatHTML(text)
+	default:
+		return "", false
+	}
+	if err != nil {
+		return "", false
+	}
+	return formatted, true
+}
+
+// Validate reports where text is not well-formed JSON or XML, for those media types; text of other
+// types always passes
+func Validate(mediaType string, text string) error {
+	switch {
+	case isJSON(mediaType):
+		var value any
+		err := json.Unmarshal([]byte(text), &value)
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line := 1 + strings.Count(text[:syntaxErr.Offset], "\n")
+			return fmt.Errorf("line %d: %s", line, syntaxErr.Error())
+		}
+		return err
+	case isXML(mediaType):
+		decoder := xml.NewDecoder(strings.NewReader(text))
+		for {
+			_, err := decoder.Token()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// BodyMediaType returns the media type of a body: that of contentType or, when it names none, XML
+// or JSON if the body looks like either
+func BodyMediaType(contentType string, body string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	trimmed := strings.TrimSpace(body)
+	switch {
+	case strings.HasPrefix(trimmed, "<") && !strings.HasPrefix(strings.ToLower(trimmed), "<!doctype html"):
+		return "application/xml"
+	case strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "["):
+		return "application/json"
+	}
+	return ""
+}
+
+// formatJSON indents a JSON document
+func formatJSON(text string) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(text), "", indent); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// formatXML re-encodes an XML document with indentation, dropping insignificant whitespace
+func formatXML(text string) (string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(text))
+	decoder.Strict = false
+
+	var buf bytes.Buffer
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", indent)
+
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if data, ok := token.(xml.CharData); ok && len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		if err := encoder.EncodeToken(xml.CopyToken(token)); err != nil {
+			return "", err
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// formatHTML puts every tag on its own line indented by nesting depth. Contents of pre, textarea,
+// script and style elements are kept verbatim.
+func formatHTML(text string) (string, error) {
+	tokenizer := html.NewTokenizer(strings.NewReader(text))
+
+	var (
+		buf   strings.Builder
+		depth int
+		raw   string
+	)
+	writeLine := func(line string) {
+		buf.WriteString(strings.Repeat(indent, depth))
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if errors.Is(tokenizer.Err(), io.EOF) {
+				break
+			}
+			return "", tokenizer.Err()
+		}
+
+		token := tokenizer.Token()
+		if raw != "" {
+			if tokenType == html.EndTagToken && token.Data == raw {
+				raw = ""
+				buf.WriteString(token.String())
+				buf.WriteByte('\n')
+				continue
+			}
+			buf.WriteString(string(tokenizer.Raw()))
+			continue
+		}
+
+		switch tokenType {
+		case html.StartTagToken:
+			if htmlRawElements[token.Data] {
+				buf.WriteString(strings.Repeat(indent, depth))
+				buf.WriteString(token.String())
+				raw = token.Data
+				continue
+			}
+			writeLine(token.String())
+			if !htmlVoidElements[token.Data] {
+				depth++
+			}
+		case html.EndTagToken:
+			if depth > 0 {
+				depth--
+			}
+			writeLine(token.String())
+		case html.TextToken:
+			if trimmed := strings.TrimSpace(token.Data); trimmed != "" {
+				writeLine(html.EscapeString(trimmed))
+			}
+		default:
+			writeLine(string(tokenizer.Raw()))
+		}
+	}
+
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
@@ -0,0 +1,154 @@
+package response
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gabriel-vasile/mimetype"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// MaxDecodedSize bounds how large a decompressed body may grow
+	MaxDecodedSize = 64 << 20
+	// MaxFormatSize is the largest body that is pretty-printed
+	MaxFormatSize = 8 << 20
+)
+
+// Body is a response body after decoding, ready for display
+type Body struct {
+	MimeType        string `json:"mimeType"`
+	Charset         string `json:"charset,omitempty"`
+	ContentEncoding string `json:"contentEncoding,omitempty"`
+	Raw             string `json:"raw"`
+	Formatted       string `json:"formatted,omitempty"`
+	Bytes           []byte `json:"-"`
+}
+
+// Process decompresses the body according to Content-Encoding, detects its MIME type and charset,
+// converts text to UTF-8 and pretty-prints JSON, XML and HTML. Decoding errors are returned
+// alongside a body holding the undecoded bytes.
+func Process(header http.Header, data []byte) (*Body, error) {
+	body := &Body{ContentEncoding: strings.ToLower(strings.TrimSpace(header.Get("Content-Encoding")))}
+
+	decoded, err := Decompress(body.ContentEncoding, data)
+	if err != nil {
+		decoded = data
+	}
+	body.Bytes = decoded
+
+	contentType := header.Get("Content-Type")
+	body.MimeType = DetectMIME(contentType, decoded)
+
+	if IsText(body.MimeType) {
+		body.Raw, body.Charset = toUTF8(decoded, contentType)
+		if len(body.Raw) <= MaxFormatSize {
+			if formatted, ok := Format(body.MimeType, body.Raw); ok {
+				body.Formatted = formatted
+			}
+		}
+	} else {
+		body.Raw = string(decoded)
+	}
+
+	return body, err
+}
+
+// Decompress undoes a Content-Encoding (gzip, deflate, br; comma-separated encodings are undone in reverse order)
+func Decompress(encoding string, data []byte) ([]byte, error) {
+	if encoding == "" || encoding == "identity" {
+		return data, nil
+	}
+
+	encodings := strings.Split(encoding, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		var reader io.Reader
+		switch enc := strings.TrimSpace(encodings[i]); enc {
+		case "", "identity":
+			continue
+		case "gzip", "x-gzip":
+			gz, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode gzip body: %w", err)
+			}
+			reader = gz
+		case "deflate":
+			reader = flate.NewReader(bytes.NewReader(data))
+		case "br":
+			reader = brotli.NewReader(bytes.NewReader(data))
+		default:
+			return nil, fmt.Errorf("unsupported content encoding '%s'", enc)
+		}
+
+		decoded, err := io.ReadAll(io.LimitReader(reader, MaxDecodedSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s body: %w", strings.TrimSpace(encodings[i]), err)
+		}
+		if len(decoded) > MaxDecodedSize {
+			return nil, fmt.Errorf("decoded body exceeds %d bytes", MaxDecodedSize)
+		}
+		data = decoded
+	}
+	return data, nil
+}
+
+// DetectMIME returns the media type from the Content-Type header, falling back to content sniffing
+// when the header is missing or generic
+func DetectMIME(contentType string, data []byte) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType != "application/octet-stream" {
+		return mediaType
+	}
+	if len(data) == 0 {
+		return "application/octet-stream"
+	}
+	mediaType, _, _ := mime.ParseMediaType(mimetype.Detect(data).String())
+	return mediaType
+}
+
+// IsText reports whether a media type holds human-readable text
+func IsText(mediaType string) bool {
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case isJSON(mediaType), isXML(mediaType):
+		return true
+	}
+	switch mediaType {
+	case "application/javascript", "application/ecmascript", "application/x-www-form-urlencoded",
+		"application/graphql", "application/yaml", "application/x-yaml", "application/toml",
+		"application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return true
+	}
+	return false
+}
+
+// toUTF8 converts text in the charset declared by contentType (or sniffed from the content) to UTF-8
+func toUTF8(data []byte, contentType string) (string, string) {
+	enc, name, _ := charset.DetermineEncoding(data, contentType)
+	if name == "utf-8" || enc == nil {
+		return string(data), "utf-8"
+	}
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil || !utf8.Valid(decoded) {
+		return string(data), name
+	}
+	return string(decoded), name
+}
+
+// isJSON reports whether the media type is JSON or a +json suffix type
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// isXML reports whether the media type is XML or a +xml suffix type
+func isXML(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
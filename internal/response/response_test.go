@@ -0,0 +1,130 @@
+package response
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestProcessDecompressesAndFormats(t *testing.T) {
+	payload := []byte(`{"a":1,"b":[true]}`)
+
+	var gz bytes.Buffer
+	gzw := gzip.NewWriter(&gz)
+	gzw.Write(payload)
+	gzw.Close()
+
+	var br bytes.Buffer
+	brw := brotli.NewWriter(&br)
+	brw.Write(payload)
+	brw.Close()
+
+	for name, encoded := range map[string][]byte{"gzip": gz.Bytes(), "br": br.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			header := http.Header{"Content-Encoding": {name}, "Content-Type": {"application/json; charset=utf-8"}}
+			body, err := Process(header, encoded)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if body.Raw != string(payload) || body.MimeType != "application/json" {
+				t.Errorf("Process() raw = %q mime = %q", body.Raw, body.MimeType)
+			}
+			if body.Formatted != "{\n  \"a\": 1,\n  \"b\": [\n    true\n  ]\n}" {
+				t.Errorf("Process() formatted = %q", body.Formatted)
+			}
+		})
+	}
+}
+
+func TestProcessConvertsCharset(t *testing.T) {
+	latin1, _ := charmap.ISO8859_1.NewEncoder().Bytes([]byte("café"))
+	body, err := Process(http.Header{"Content-Type": {"text/plain; charset=iso-8859-1"}}, latin1)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if body.Raw != "café" || body.Charset != "windows-1252" {
+		t.Errorf("Process() raw = %q charset = %q", body.Raw, body.Charset)
+	}
+}
+
+func TestDetectMIMESniffsGenericTypes(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	if got := DetectMIME("application/octet-stream", png); got != "image/png" {
+		t.Errorf("DetectMIME() = %q, want image/png", got)
+	}
+	if got := DetectMIME("", []byte("<?xml version=\"1.0\"?><a/>")); got != "text/xml" {
+		t.Errorf("DetectMIME() = %q, want text/xml", got)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		mediaType string
+		text      string
+		want      string
+		wantOK    bool
+	}{
+		{
+			name:      "XML",
+			mediaType: "application/xml",
+			text:      `<a><b x="1">t</b> <c/></a>`,
+			want:      "<a>\n  <b x=\"1\">t</b>\n  <c></c>\n</a>",
+			wantOK:    true,
+		},
+		{
+			name:      "HTML keeps pre verbatim",
+			mediaType: "text/html",
+			text:      "<html><body><p>Hi</p><pre>  a\n b</pre><br></body></html>",
+			want:      "<html>\n  <body>\n    <p>\n      Hi\n    </p>\n    <pre>  a\n b</pre>\n    <br>\n  </body>\n</html>",
+			wantOK:    true,
+		},
+		{name: "invalid JSON", mediaType: "application/json", text: `{"a":`, wantOK: false},
+		{name: "plain text", mediaType: "text/plain", text: "hello", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Format(tt.mediaType, tt.text)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Format() = %q, %v; want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		mediaType string
+		text      string
+		wantErr   string
+	}{
+		{name: "XML", mediaType: "text/xml", text: `<?xml version="1.0"?><a><b>{{id}}</b></a>`},
+		{name: "unclosed XML element", mediaType: "application/soap+xml", text: "<a>\n<b></a>", wantErr: "line 2"},
+		{name: "JSON", mediaType: "application/json", text: `{"a": [1, 2]}`},
+		{name: "JSON syntax error", mediaType: "application/json", text: "{\n  \"a\": 1,\n}", wantErr: "line 3"},
+		{name: "other types pass", mediaType: "text/plain", text: "<a>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.mediaType, tt.text)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate() error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if got := BodyMediaType("", "  <Envelope/>"); got != "application/xml" {
+		t.Errorf("BodyMediaType() = %q, want application/xml for an XML body without Content-Type", got)
+	}
+	if got := BodyMediaType("text/xml; charset=utf-8", `{"a": 1}`); got != "text/xml" {
+		t.Errorf("BodyMediaType() = %q, want the declared type", got)
+	}
+}
@@ -0,0 +1,43 @@
+// Command gen writes the JSON Schema of every paperbox config file to a directory.
+//
+//	go run ./internal/schema/gen -out build/schemas
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"paperbox/internal/config/storage"
+	"paperbox/internal/schema"
+)
+
+func main() {
+	out := flag.String("out", "build/schemas", "directory the schemas are written to")
+	flag.Parse()
+
+	if err := run(*out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(out string) error {
+	writer := storage.NewFileWriter()
+	for _, name := range schema.Names() {
+		s, err := schema.ForConfig(name)
+		if err != nil {
+			return err
+		}
+		data, err := schema.Marshal(s)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(out, name+".schema.json")
+		if err := writer.WriteAtomic(path, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return nil
+}
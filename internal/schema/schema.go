@@ -0,0 +1,303 @@
+// Package schema generates JSON Schema documents for the paperbox config files from their Go
+// structs, including the constraints of their validation tags, so editors and external tools can
+// check hand-edited files.
+package schema
+
+//go:generate go run ./gen -out ../../build/schemas
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/requests"
+	"paperbox/internal/config/user"
+)
+
+// Draft is the JSON Schema dialect of the generated documents
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document or subschema
+type Schema map[string]interface{}
+
+// configs maps each config name to a zero value of its type and a title
+var configs = map[string]struct {
+	value interface{}
+	title string
+}{
+	"requests":     {requests.RequestsConfig{}, "Paperbox requests"},
+	"user":         {user.Config{}, "Paperbox user settings"},
+	"environments": {environments.EnvironmentsConfig{}, "Paperbox environments"},
+}
+
+// Names returns the names of the configs a schema can be generated for
+func Names() []string {
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForConfig returns the schema of a config file by name (see Names)
+func ForConfig(name string) (Schema, error) {
+	config, ok := configs[name]
+	if !ok {
+		return nil, apperrors.NotFoundf("no schema for config %q", name).WithDetail("names", Names())
+	}
+	return Generate(config.value, config.title), nil
+}
+
+// Marshal encodes a schema as indented JSON
+func Marshal(s Schema) ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Generate builds the schema of v's type. Named struct types are placed under $defs.
+func Generate(v interface{}, title string) Schema {
+	g := &generator{defs: make(map[string]Schema)}
+	root := g.schemaFor(reflect.TypeOf(v), "")
+	if ref, ok := root["$ref"].(string); ok {
+		// Inline the root type rather than pointing at its own definition
+		name := strings.TrimPrefix(ref, "#/$defs/")
+		root = g.defs[name]
+		delete(g.defs, name)
+	}
+
+	s := Schema{"$schema": Draft, "title": title}
+	for key, value := range root {
+		s[key] = value
+	}
+	if len(g.defs) > 0 {
+		s["$defs"] = g.defs
+	}
+	return s
+}
+
+// generator collects definitions of named struct types while walking a type
+type generator struct {
+	defs map[string]Schema
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaFor returns the schema of t with the constraints of a validate tag applied
+func (g *generator) schemaFor(t reflect.Type, tag string) Schema {
+	if t.Kind() == reflect.Pointer {
+		s := g.schemaFor(t.Elem(), tag)
+		return Schema{"anyOf": []interface{}{s, Schema{"type": "null"}}}
+	}
+
+	fieldRules, elemRules, keyRules := splitRules(tag)
+
+	var s Schema
+	switch {
+	case t == timeType:
+		s = Schema{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct:
+		s = g.structRef(t)
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		s = Schema{"type": "array", "items": g.schemaFor(t.Elem(), strings.Join(elemRules, ","))}
+	case t.Kind() == reflect.Map:
+		s = Schema{"type": "object", "additionalProperties": g.schemaFor(t.Elem(), strings.Join(elemRules, ","))}
+		if len(keyRules) > 0 {
+			s["propertyNames"] = applyRules(Schema{"type": "string"}, reflect.TypeOf(""), keyRules)
+		}
+	case t.Kind() == reflect.String:
+		s = Schema{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		s = Schema{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		s = Schema{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		s = Schema{"type": "number"}
+	default:
+		s = Schema{}
+	}
+
+	return applyRules(s, t, fieldRules)
+}
+
+// structRef returns a $ref to the definition of a struct type, generating it on first use
+func (g *generator) structRef(t reflect.Type) Schema {
+	name := t.Name()
+	if name == "" {
+		return g.structSchema(t)
+	}
+	if _, ok := g.defs[name]; !ok {
+		g.defs[name] = Schema{} // Placeholder so recursive types terminate
+		g.defs[name] = g.structSchema(t)
+	}
+	return Schema{"$ref": "#/$defs/" + name}
+}
+
+// structSchema describes the JSON properties of a struct
+func (g *generator) structSchema(t reflect.Type) Schema {
+	properties := make(map[string]Schema)
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		tag := field.Tag.Get("validate")
+		properties[name] = g.schemaFor(field.Type, tag)
+		if rules, _, _ := splitRules(tag); slices.Contains(rules, "required") {
+			required = append(required, name)
+		}
+	}
+
+	s := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// splitRules separates a validate tag into the rules of the field itself, those after "dive"
+// (applied to elements) and those between "keys" and "endkeys" (applied to map keys)
+func splitRules(tag string) (field, elem, keys []string) {
+	if tag == "" {
+		return nil, nil, nil
+	}
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		if rule != "dive" {
+			continue
+		}
+		field, rest := rules[:i], rules[i+1:]
+		if len(rest) > 0 && rest[0] == "keys" {
+			for j, r := range rest {
+				if r == "endkeys" {
+					return field, rest[j+1:], rest[1:j]
+				}
+			}
+		}
+		return field, rest, nil
+	}
+	return rules, nil, nil
+}
+
+// applyRules adds the constraints of validation rules to s. With omitempty the constraints
+// only hold for non-zero values, so the zero value is allowed as an alternative.
+func applyRules(s Schema, t reflect.Type, rules []string) Schema {
+	constraints := Schema{}
+	omitEmpty := false
+
+	for _, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "omitempty":
+			omitEmpty = true
+		case "required":
+			if t.Kind() == reflect.String {
+				constraints["minLength"] = 1
+			}
+		case "min", "max":
+			if key := boundKeyword(t, name); key != "" {
+				constraints[key] = parseNumber(param)
+			}
+		case "oneof":
+			values := strings.Fields(param)
+			enum := make([]interface{}, len(values))
+			for i, value := range values {
+				enum[i] = value
+				if s["type"] == "integer" {
+					enum[i] = parseNumber(value)
+				}
+			}
+			constraints["enum"] = enum
+		case "url":
+			constraints["format"] = "uri"
+		case "email":
+			constraints["format"] = "email"
+		case "bcp47_language_tag":
+			constraints["pattern"] = `^[A-Za-z]{2,3}(-[A-Za-z0-9]{1,8})*$`
+		case "http_method":
+			constraints["pattern"] = caseInsensitiveEnum(requests.HTTPMethods)
+		case "auth_type":
+			constraints["pattern"] = `^(none|basic|bearer|apiKey|ntlm|negotiate|` + requests.PluginAuthPrefix + `[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+)$`
+		}
+	}
+
+	if len(constraints) == 0 {
+		return s
+	}
+	if omitEmpty && s["type"] != "array" && s["type"] != "object" {
+		s["anyOf"] = []interface{}{Schema{"const": reflect.Zero(t).Interface()}, constraints}
+		return s
+	}
+	for key, value := range constraints {
+		s[key] = value
+	}
+	return s
+}
+
+// boundKeyword returns the JSON Schema keyword for a min or max rule on t
+func boundKeyword(t reflect.Type, rule string) string {
+	bounds := map[reflect.Kind][2]string{
+		reflect.String: {"minLength", "maxLength"},
+		reflect.Slice:  {"minItems", "maxItems"},
+		reflect.Array:  {"minItems", "maxItems"},
+		reflect.Map:    {"minProperties", "maxProperties"},
+	}
+	pair, ok := bounds[t.Kind()]
+	if !ok {
+		if t.Kind() < reflect.Int || t.Kind() > reflect.Float64 {
+			return ""
+		}
+		pair = [2]string{"minimum", "maximum"}
+	}
+	if rule == "min" {
+		return pair[0]
+	}
+	return pair[1]
+}
+
+// parseNumber returns param as an int when it is one, otherwise as a float
+func parseNumber(param string) interface{} {
+	if n, err := strconv.Atoi(param); err == nil {
+		return n
+	}
+	f, _ := strconv.ParseFloat(param, 64)
+	return f
+}
+
+// caseInsensitiveEnum builds a regex pattern matching any of values regardless of case
+func caseInsensitiveEnum(values []string) string {
+	alternatives := make([]string, len(values))
+	for i, value := range values {
+		var b strings.Builder
+		for _, r := range value {
+			lower, upper := strings.ToLower(string(r)), strings.ToUpper(string(r))
+			if lower == upper {
+				b.WriteRune(r)
+			} else {
+				b.WriteString("[" + upper + lower + "]")
+			}
+		}
+		alternatives[i] = b.String()
+	}
+	return "^(" + strings.Join(alternatives, "|") + ")$"
+}
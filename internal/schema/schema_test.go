@@ -0,0 +1,138 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"paperbox/internal/config/requests"
+	"paperbox/internal/config/user"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// compile turns a generated schema into a validator
+func compile(t *testing.T, name string) *jsonschema.Schema {
+	t.Helper()
+	s, err := ForConfig(name)
+	if err != nil {
+		t.Fatalf("ForConfig(%q) error = %v", name, err)
+	}
+	data, err := Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(name+".schema.json", doc); err != nil {
+		t.Fatal(err)
+	}
+	compiled, err := compiler.Compile(name + ".schema.json")
+	if err != nil {
+		t.Fatalf("generated %s schema does not compile: %v", name, err)
+	}
+	return compiled
+}
+
+// instance encodes v the way it is written to disk
+func instance(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestUserSchema(t *testing.T) {
+	s := compile(t, "user")
+
+	cfg := user.DefaultConfig()
+	if err := s.Validate(instance(t, cfg)); err != nil {
+		t.Errorf("default user config should match its schema: %v", err)
+	}
+
+	cfg.Theme = "neon"
+	if err := s.Validate(instance(t, cfg)); err == nil {
+		t.Error("schema should reject a theme outside oneof")
+	}
+
+	cfg = user.DefaultConfig()
+	cfg.Window.Width = 100
+	if err := s.Validate(instance(t, cfg)); err == nil {
+		t.Error("schema should reject a window narrower than the minimum")
+	}
+}
+
+func TestRequestsSchema(t *testing.T) {
+	s := compile(t, "requests")
+
+	cfg := &requests.RequestsConfig{
+		Version:   requests.CurrentVersion,
+		RootOrder: []string{"folder"},
+		Values: map[string]requests.Item{
+			"folder": {Type: requests.ItemTypeFolder, Name: "Folder", Children: []string{"req"}},
+			"req": {
+				Type:    requests.ItemTypeRequest,
+				Name:    "Get",
+				Method:  "get",
+				Path:    "/users",
+				Headers: []requests.Header{{Key: "Accept", Value: "application/json"}},
+				Auth:    &requests.Auth{Type: requests.AuthTypeBearer, Token: "t"},
+			},
+		},
+	}
+	if err := s.Validate(instance(t, cfg)); err != nil {
+		t.Errorf("valid requests config should match its schema: %v", err)
+	}
+
+	item := cfg.Values["req"]
+	item.Method = "FETCH"
+	cfg.Values["req"] = item
+	if err := s.Validate(instance(t, cfg)); err == nil {
+		t.Error("schema should reject an unknown HTTP method")
+	}
+
+	item.Method = "GET"
+	item.Headers = []requests.Header{{Key: ""}}
+	cfg.Values["req"] = item
+	if err := s.Validate(instance(t, cfg)); err == nil {
+		t.Error("schema should reject a header without a key")
+	}
+}
+
+func TestForConfigUnknown(t *testing.T) {
+	if _, err := ForConfig("nope"); err == nil {
+		t.Error("ForConfig() of an unknown config should fail")
+	}
+}
+
+// TestGeneratedFilesUpToDate fails when build/schemas was not regenerated after a struct change
+func TestGeneratedFilesUpToDate(t *testing.T) {
+	for _, name := range Names() {
+		s, err := ForConfig(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join("..", "..", "build", "schemas", name+".schema.json"))
+		if err != nil {
+			t.Fatalf("missing generated schema: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("build/schemas/%s.schema.json is out of date; run go generate ./internal/schema", name)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"paperbox/internal/config/requests"
+)
+
+// DefaultLimit is how many matches Search returns when no limit is given
+const DefaultLimit = 20
+
+// Scoring follows fzf: every matched character scores, runs of consecutive characters and
+// characters at word boundaries earn bonuses, and gaps between matched characters cost points
+const (
+	scoreMatch        = 16
+	bonusBoundary     = 8
+	bonusFirstChar    = 8
+	bonusConsecutive  = 4
+	penaltyGapStart   = 3
+	penaltyGapExtend  = 1
+	bonusNameField    = 10
+	penaltyPathLength = 1 // per 8 runes, so shorter targets win ties
+)
+
+// Range is a half-open [Start, End) span of rune offsets in a matched field
+type Range struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Match is a request that matched the query; the ranges highlight the matched characters
+type Match struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Score      int     `json:"score"`
+	NameRanges []Range `json:"nameRanges,omitempty"`
+	PathRanges []Range `json:"pathRanges,omitempty"`
+}
+
+// entry is an indexed request with its fields pre-lowered for matching
+type entry struct {
+	id, name, method, path string
+	nameRunes, pathRunes   []rune
+	nameBounds, pathBounds []bool
+}
+
+// Index holds every request prepared for fuzzy matching
+type Index struct {
+	entries []entry
+}
+
+// Build indexes the requests of a config
+func Build(cfg *requests.RequestsConfig) *Index {
+	idx := &Index{entries: make([]entry, 0, len(cfg.Values))}
+	for id, item := range cfg.Values {
+		if item.Type != requests.ItemTypeRequest {
+			continue
+		}
+		nameRunes, nameBounds := prepare(item.Name)
+		pathRunes, pathBounds := prepare(item.Path)
+		idx.entries = append(idx.entries, entry{
+			id:         id,
+			name:       item.Name,
+			method:     item.Method,
+			path:       item.Path,
+			nameRunes:  nameRunes,
+			pathRunes:  pathRunes,
+			nameBounds: nameBounds,
+			pathBounds: pathBounds,
+		})
+	}
+	// Stable order so equal scores are returned deterministically
+	sort.Slice(idx.entries, func(i, j int) bool { return idx.entries[i].id < idx.entries[j].id })
+	return idx
+}
+
+// Search returns up to limit requests matching query, best first. Whitespace separates terms
+// that must all match; an empty query matches nothing.
+func (idx *Index) Search(query string, limit int) []Match {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return []Match{}
+	}
+	patterns := make([][]rune, len(terms))
+	for i, term := range terms {
+		patterns[i] = []rune(term)
+	}
+
+	matches := []Match{}
+	for _, e := range idx.entries {
+		if m, ok := e.match(patterns); ok {
+			matches = append(matches, m)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// match scores every term against the name and path, keeping the better field per term
+func (e *entry) match(patterns [][]rune) (Match, bool) {
+	m := Match{ID: e.id, Name: e.name, Method: e.method, Path: e.path}
+	for _, pattern := range patterns {
+		nameScore, nameRanges, nameOK := fuzzy(e.nameRunes, e.nameBounds, pattern)
+		pathScore, pathRanges, pathOK := fuzzy(e.pathRunes, e.pathBounds, pattern)
+		switch {
+		case nameOK && (!pathOK || nameScore+bonusNameField >= pathScore):
+			m.Score += nameScore + bonusNameField
+			m.NameRanges = mergeRanges(m.NameRanges, nameRanges)
+		case pathOK:
+			m.Score += pathScore
+			m.PathRanges = mergeRanges(m.PathRanges, pathRanges)
+		default:
+			return Match{}, false
+		}
+	}
+	m.Score -= (len(e.nameRunes) + len(e.pathRunes)) / 8 * penaltyPathLength
+	return m, true
+}
+
+// prepare lowers text and marks which runes start a word
+func prepare(text string) ([]rune, []bool) {
+	original := []rune(text)
+	runes := make([]rune, len(original))
+	bounds := make([]bool, len(original))
+	for i, r := range original {
+		runes[i] = unicode.ToLower(r)
+		if i == 0 {
+			bounds[i] = true
+			continue
+		}
+		prev := original[i-1]
+		bounds[i] = (!unicode.IsLetter(prev) && !unicode.IsDigit(prev) && (unicode.IsLetter(r) || unicode.IsDigit(r))) ||
+			(unicode.IsLower(prev) && unicode.IsUpper(r))
+	}
+	return runes, bounds
+}
+
+// fuzzy finds pattern as a subsequence of text the way fzf's v1 algorithm does: a forward
+// scan finds the first complete match, then a backward scan from its end finds the shortest
+// window, which is scored
+func fuzzy(text []rune, bounds []bool, pattern []rune) (int, []Range, bool) {
+	if len(pattern) == 0 || len(pattern) > len(text) {
+		return 0, nil, false
+	}
+
+	// Forward scan
+	pi, end := 0, -1
+	for i, r := range text {
+		if r == pattern[pi] {
+			pi++
+			if pi == len(pattern) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	// Backward scan
+	pi, start := len(pattern)-1, end
+	for i := end; i >= 0; i-- {
+		if text[i] == pattern[pi] {
+			pi--
+			if pi < 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	// Score the window, matching greedily from its start
+	score := 0
+	var ranges []Range
+	pi, prev := 0, -1
+	for i := start; i <= end && pi < len(pattern); i++ {
+		if text[i] != pattern[pi] {
+			continue
+		}
+		score += scoreMatch
+		if bounds[i] {
+			score += bonusBoundary
+			if pi == 0 {
+				score += bonusFirstChar
+			}
+		}
+		if prev >= 0 {
+			if gap := i - prev - 1; gap == 0 {
+				score += bonusConsecutive
+			} else {
+				score -= penaltyGapStart + (gap-1)*penaltyGapExtend
+			}
+		}
+		if n := len(ranges); n > 0 && ranges[n-1].End == i {
+			ranges[n-1].End++
+		} else {
+			ranges = append(ranges, Range{Start: i, End: i + 1})
+		}
+		prev = i
+		pi++
+	}
+
+	return score, ranges, true
+}
+
+// mergeRanges combines two sorted range lists into one sorted list without overlaps
+func mergeRanges(a, b []Range) []Range {
+	all := append(append([]Range{}, a...), b...)
+	if len(all) == 0 {
+		return nil
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Start < all[j].Start })
+
+	merged := all[:1]
+	for _, r := range all[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End {
+			if r.End > last.End {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// Finder keeps an Index for the latest config revision and rebuilds it only when the revision changes
+type Finder struct {
+	mu       sync.Mutex
+	revision uint64
+	index    *Index
+}
+
+// Search searches the requests config at revision. load is only called, and the index only
+// rebuilt, when the revision differs from the one the current index was built for.
+func (f *Finder) Search(revision uint64, load func() *requests.RequestsConfig, query string, limit int) []Match {
+	f.mu.Lock()
+	if f.index == nil || f.revision != revision {
+		f.index = Build(load())
+		f.revision = revision
+	}
+	index := f.index
+	f.mu.Unlock()
+
+	return index.Search(query, limit)
+}
@@ -0,0 +1,82 @@
+package search
+
+import (
+	"testing"
+
+	"paperbox/internal/config/requests"
+)
+
+func testConfig() *requests.RequestsConfig {
+	return &requests.RequestsConfig{
+		Version: requests.CurrentVersion,
+		Values: map[string]requests.Item{
+			"folder": {Type: requests.ItemTypeFolder, Name: "Users", Children: []string{"list", "get", "orders"}},
+			"list":   {Type: requests.ItemTypeRequest, Name: "List users", Method: "GET", Path: "/api/users"},
+			"get":    {Type: requests.ItemTypeRequest, Name: "Get user", Method: "GET", Path: "/api/users/{id}"},
+			"orders": {Type: requests.ItemTypeRequest, Name: "User orders", Method: "GET", Path: "/api/users/{id}/orders"},
+		},
+	}
+}
+
+func TestSearchRanksAndHighlights(t *testing.T) {
+	idx := Build(testConfig())
+
+	matches := idx.Search("gu", 0)
+	if len(matches) == 0 || matches[0].ID != "get" {
+		t.Fatalf("expected 'Get user' first for 'gu', got %+v", matches)
+	}
+	want := []Range{{Start: 0, End: 1}, {Start: 4, End: 5}}
+	if got := matches[0].NameRanges; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("NameRanges = %+v, want %+v", got, want)
+	}
+
+	matches = idx.Search("orders", 0)
+	if len(matches) != 1 || matches[0].ID != "orders" {
+		t.Errorf("expected only 'User orders' for 'orders', got %+v", matches)
+	}
+}
+
+func TestSearchMultipleTermsAndPaths(t *testing.T) {
+	idx := Build(testConfig())
+
+	matches := idx.Search("user {id}", 0)
+	if len(matches) != 2 {
+		t.Fatalf("expected two requests with {id} in the path, got %+v", matches)
+	}
+	for _, m := range matches {
+		if len(m.PathRanges) == 0 {
+			t.Errorf("expected path highlight for %s, got %+v", m.ID, m)
+		}
+	}
+
+	if matches := idx.Search("zzz", 0); len(matches) != 0 {
+		t.Errorf("expected no matches, got %+v", matches)
+	}
+	if matches := idx.Search("  ", 0); len(matches) != 0 {
+		t.Errorf("expected no matches for a blank query, got %+v", matches)
+	}
+	if matches := idx.Search("u", 1); len(matches) != 1 {
+		t.Errorf("expected limit to cap results, got %d", len(matches))
+	}
+}
+
+func TestFinderRebuildsOnRevision(t *testing.T) {
+	cfg := testConfig()
+	finder := &Finder{}
+	load := func() *requests.RequestsConfig { return cfg }
+
+	if got := finder.Search(1, load, "renamed", 0); len(got) != 0 {
+		t.Fatalf("unexpected match %+v", got)
+	}
+
+	item := cfg.Values["list"]
+	item.Name = "Renamed"
+	cfg.Values["list"] = item
+
+	if got := finder.Search(1, load, "renamed", 0); len(got) != 0 {
+		t.Errorf("same revision should reuse the index, got %+v", got)
+	}
+	if got := finder.Search(2, load, "renamed", 0); len(got) != 1 {
+		t.Errorf("new revision should rebuild the index, got %+v", got)
+	}
+}
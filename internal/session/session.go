@@ -0,0 +1,180 @@
+// Package session records the requests sent between App.StartSession and App.StopSession as a
+// named session, persisted as one JSON file per session, so a multi-step scenario can be replayed
+// in order later, against the same or another environment.
+package session
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/storage"
+	"paperbox/internal/engine"
+
+	"github.com/adrg/xdg"
+	"github.com/google/uuid"
+)
+
+// DirName is the directory sessions are stored in, in the app data directory
+const DirName = "sessions"
+
+// Session is a recorded sequence of executions
+type Session struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Environment string     `json:"environment,omitempty"` // Environment active when recording started
+	StartedAt   time.Time  `json:"startedAt"`
+	StoppedAt   *time.Time `json:"stoppedAt,omitempty"`
+	Steps       []Step     `json:"steps"`
+}
+
+// Step is one execution of a session: the request to send again when replaying, and what the
+// recorded execution looked like. URL is masked like the execution's request.
+type Step struct {
+	engine.RunStep
+	ExecutionID string    `json:"executionId"`
+	Name        string    `json:"name,omitempty"` // Request name when it was recorded
+	Method      string    `json:"method"`
+	URL         string    `json:"url"`
+	Status      int       `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	StartedAt   time.Time `json:"startedAt"`
+	DurationMs  int64     `json:"durationMs"`
+}
+
+// New starts a session
+func New(name string, environment string) (*Session, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, apperrors.Invalidf("session name is required")
+	}
+	return &Session{
+		ID:          uuid.New().String(),
+		Name:        name,
+		Environment: environment,
+		StartedAt:   time.Now(),
+		Steps:       []Step{},
+	}, nil
+}
+
+// Record adds an execution of a request to the session; name is the request's name and example the
+// request example it was sent with
+func (s *Session) Record(exec *engine.Execution, name string, example string) {
+	s.Steps = append(s.Steps, Step{
+		RunStep:     engine.RunStep{RequestID: exec.RequestID, Example: example},
+		ExecutionID: exec.ID,
+		Name:        name,
+		Method:      exec.Request.Method,
+		URL:         exec.Request.URL,
+		Status:      exec.Status,
+		Error:       exec.Error,
+		StartedAt:   exec.StartedAt,
+		DurationMs:  exec.DurationMs,
+	})
+}
+
+// RunSteps returns the steps to replay the session with engine.RunSteps
+func (s *Session) RunSteps() []engine.RunStep {
+	steps := make([]engine.RunStep, len(s.Steps))
+	for i, step := range s.Steps {
+		steps[i] = step.RunStep
+	}
+	return steps
+}
+
+// Store keeps sessions as JSON files in a directory
+type Store struct {
+	mu      sync.Mutex
+	dir     string
+	storage *storage.FileStorage
+}
+
+// NewStore creates a store of sessions in dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir, storage: storage.NewFileStorage()}
+}
+
+// DefaultDir returns the sessions directory in the app data directory
+func DefaultDir() string {
+	return path.Join(xdg.DataHome, "paperbox", DirName)
+}
+
+// file returns the path of a session's file
+func (st *Store) file(id string) string {
+	return filepath.Join(st.dir, id+".json")
+}
+
+// Save writes a session
+func (st *Store) Save(s *Session) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if err := st.storage.Save(st.file(s.ID), s); err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to save session")
+	}
+	return nil
+}
+
+// Get reads a session by ID
+func (st *Store) Get(id string) (*Session, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, apperrors.NotFoundf("session not found")
+	}
+	if _, err := os.Stat(st.file(id)); os.IsNotExist(err) {
+		return nil, apperrors.NotFoundf("session not found")
+	}
+	var s Session
+	if err := st.storage.Load(st.file(id), &s); err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to read session")
+	}
+	return &s, nil
+}
+
+// List returns the stored sessions, most recent first. Unreadable files are skipped.
+func (st *Store) List() ([]Session, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	entries, err := os.ReadDir(st.dir)
+	if os.IsNotExist(err) {
+		return []Session{}, nil
+	}
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to list sessions")
+	}
+
+	sessions := []Session{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		var s Session
+		if err := st.storage.Load(filepath.Join(st.dir, entry.Name()), &s); err != nil || s.ID == "" {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].StartedAt.After(sessions[j].StartedAt) })
+	return sessions, nil
+}
+
+// Delete removes a session
+func (st *Store) Delete(id string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, err := uuid.Parse(id); err != nil {
+		return apperrors.NotFoundf("session not found")
+	}
+	if err := os.Remove(st.file(id)); err != nil {
+		if os.IsNotExist(err) {
+			return apperrors.NotFoundf("session not found")
+		}
+		return apperrors.Wrap(apperrors.IOError, err, "failed to delete session")
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/engine"
+)
+
+func TestStore(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if list, err := store.List(); err != nil || len(list) != 0 {
+		t.Fatalf("List() of a new store = %v, %v", list, err)
+	}
+	if _, err := New("  ", ""); apperrors.CodeOf(err) != apperrors.ValidationFailed {
+		t.Errorf("New() without a name error = %v", err)
+	}
+
+	older, _ := New("login flow", "staging")
+	older.StartedAt = time.Now().Add(-time.Hour)
+	newer, _ := New("checkout", "")
+	newer.Record(&engine.Execution{
+		ID: "e1", RequestID: "r1", Status: 201,
+		Request: engine.ResolvedRequest{Method: "POST", URL: "https://api.example.com/orders"},
+	}, "Create order", "happy path")
+	for _, s := range []*Session{older, newer} {
+		if err := store.Save(s); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	got, err := store.Get(newer.ID)
+	if err != nil || len(got.Steps) != 1 || got.Steps[0].Name != "Create order" || got.Steps[0].Status != 201 {
+		t.Fatalf("Get() = %+v, %v", got, err)
+	}
+	if steps := got.RunSteps(); len(steps) != 1 || steps[0] != (engine.RunStep{RequestID: "r1", Example: "happy path"}) {
+		t.Errorf("RunSteps() = %+v", steps)
+	}
+
+	list, err := store.List()
+	if err != nil || len(list) != 2 || list[0].ID != newer.ID || list[1].Environment != "staging" {
+		t.Errorf("List() = %+v, %v; want the most recent first", list, err)
+	}
+
+	if err := store.Delete(older.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(older.ID); apperrors.CodeOf(err) != apperrors.NotFound {
+		t.Errorf("Get() of a deleted session error = %v", err)
+	}
+	if _, err := store.Get("../requests"); apperrors.CodeOf(err) != apperrors.NotFound {
+		t.Errorf("Get() of a path error = %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/version"
+)
+
+const (
+	// RequestTimeout bounds a single pull or push
+	RequestTimeout = 30 * time.Second
+
+	// maxResponseSize bounds what is read from the server
+	maxResponseSize = 64 << 20
+)
+
+// TokenSource returns the bearer token sent to the server; an empty token sends none
+type TokenSource func() (string, error)
+
+// Client speaks the sync protocol with one workspace on a server
+type Client struct {
+	server    string
+	workspace string
+	token     TokenSource
+	http      *http.Client
+}
+
+// NewClient creates a client for a workspace. server is the base URL of the sync server; token
+// may be nil for servers without authentication.
+func NewClient(server string, workspace string, token TokenSource) (*Client, error) {
+	u, err := url.Parse(server)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, apperrors.Invalidf("sync server must be an http or https URL")
+	}
+	if strings.TrimSpace(workspace) == "" {
+		return nil, apperrors.Invalidf("sync workspace is required")
+	}
+	return &Client{
+		server:    strings.TrimRight(server, "/"),
+		workspace: workspace,
+		token:     token,
+		http:      &http.Client{Timeout: RequestTimeout},
+	}, nil
+}
+
+// Pull fetches the changes to a document that since does not cover
+func (c *Client) Pull(ctx context.Context, document string, since Vector) (*PullResponse, error) {
+	var resp PullResponse
+	query := url.Values{"since": {since.String()}}
+	if err := c.do(ctx, http.MethodGet, c.documentURL(document)+"?"+query.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Push sends local changes to a document
+func (c *Client) Push(ctx context.Context, document string, req *PushRequest) (*PushResponse, error) {
+	var resp PushResponse
+	if err := c.do(ctx, http.MethodPost, c.documentURL(document), req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// documentURL returns the URL of a document in the workspace
+func (c *Client) documentURL(document string) string {
+	return fmt.Sprintf("%s/v1/workspaces/%s/documents/%s", c.server, url.PathEscape(c.workspace), url.PathEscape(document))
+}
+
+// do sends a request with an optional JSON body and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method string, target string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode sync request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create sync request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "paperbox/"+version.Version)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != nil {
+		token, err := c.token()
+		if err != nil {
+			return apperrors.Wrap(apperrors.ValidationFailed, err, "failed to read the sync token")
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "sync server is unreachable")
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to read sync response")
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return apperrors.New(apperrors.ValidationFailed, "sync server rejected the token (%s)", resp.Status)
+	case resp.StatusCode == http.StatusNotFound:
+		return apperrors.NotFoundf("sync workspace %q not found", c.workspace)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return apperrors.New(apperrors.IOError, "sync server returned %s", resp.Status)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "sync server sent an invalid response")
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// Package sync shares configs with a team through a self-hosted paperbox-sync server, or any
+// endpoint speaking the same small HTTP protocol. It plugs into storage.StorageCoordinator as the
+// cloud storage: the local file stays authoritative, saves push the items that changed and loads
+// pull what teammates changed since the last sync.
+//
+// The protocol has two calls per document (a config file, named by its base name):
+//
+//	GET  {server}/v1/workspaces/{workspace}/documents/{name}?since={vector}  -> PullResponse
+//	POST {server}/v1/workspaces/{workspace}/documents/{name}  PushRequest    -> PushResponse
+//
+// Items (the entries of the config's "values") carry a revision vector, a counter per replica
+// that changed them. A pull returns the items whose vector is not covered by since. A push is
+// accepted per item when its vector covers the server's; otherwise the server keeps its version
+// and reports it as a conflict. Requests carry the workspace token as a bearer token.
+package sync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// itemsKey is the top-level config field whose entries are synced as separate items
+const itemsKey = "values"
+
+// Vector is a revision vector: how many changes each replica made
+type Vector map[string]uint64
+
+// Covers reports whether v includes every change counted by other
+func (v Vector) Covers(other Vector) bool {
+	for replica, n := range other {
+		if v[replica] < n {
+			return false
+		}
+	}
+	return true
+}
+
+// Merge returns the vector counting the changes of both
+func (v Vector) Merge(other Vector) Vector {
+	merged := make(Vector, len(v)+len(other))
+	for replica, n := range v {
+		merged[replica] = n
+	}
+	for replica, n := range other {
+		if n > merged[replica] {
+			merged[replica] = n
+		}
+	}
+	return merged
+}
+
+// Next returns a copy of v with one more change by replica
+func (v Vector) Next(replica string) Vector {
+	next := v.Merge(nil)
+	next[replica]++
+	return next
+}
+
+// String encodes the vector as sorted "replica=count" pairs separated by commas
+func (v Vector) String() string {
+	replicas := make([]string, 0, len(v))
+	for replica := range v {
+		replicas = append(replicas, replica)
+	}
+	sort.Strings(replicas)
+	pairs := make([]string, len(replicas))
+	for i, replica := range replicas {
+		pairs[i] = replica + "=" + strconv.FormatUint(v[replica], 10)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// ParseVector parses the encoding produced by Vector.String
+func ParseVector(text string) (Vector, error) {
+	v := make(Vector)
+	if text == "" {
+		return v, nil
+	}
+	for _, pair := range strings.Split(text, ",") {
+		replica, count, ok := strings.Cut(pair, "=")
+		n, err := strconv.ParseUint(count, 10, 64)
+		if !ok || replica == "" || err != nil {
+			return nil, fmt.Errorf("invalid revision vector entry %q", pair)
+		}
+		v[replica] = n
+	}
+	return v, nil
+}
+
+// Change is the state of one item at a revision; a null Data means the item was deleted
+type Change struct {
+	ID     string          `json:"id"`
+	Data   json.RawMessage `json:"data"`
+	Vector Vector          `json:"vector"`
+}
+
+// Deleted reports whether the change deletes the item
+func (c Change) Deleted() bool {
+	return isNull(c.Data)
+}
+
+// isNull reports whether data is missing or JSON null
+func isNull(data json.RawMessage) bool {
+	return len(data) == 0 || bytes.Equal(data, []byte("null"))
+}
+
+// PullResponse holds what changed on the server since the requested vector
+type PullResponse struct {
+	Cursor   Vector          `json:"cursor"`             // Covers every change on the server, the next since
+	Document json.RawMessage `json:"document,omitempty"` // The config without its items; nil when never pushed
+	Changes  []Change        `json:"changes"`
+}
+
+// PushRequest sends the items a replica changed since it last synced
+type PushRequest struct {
+	Replica  string          `json:"replica"`
+	Document json.RawMessage `json:"document,omitempty"` // Set when the config outside its items changed
+	Changes  []Change        `json:"changes"`
+}
+
+// PushResponse reports which changes the server kept
+type PushResponse struct {
+	Accepted  []string `json:"accepted"`  // IDs of the pushed items now stored as sent
+	Conflicts []Change `json:"conflicts"` // The server's version of the items it refused
+}
+
+// Conflict is an item changed both locally and by a teammate since the last sync. Local is nil
+// when the item was deleted locally, Remote.Data when a teammate deleted it.
+type Conflict struct {
+	Document string          `json:"document"`
+	ItemID   string          `json:"itemId"`
+	Local    json.RawMessage `json:"local"`
+	Remote   Change          `json:"remote"`
+}
@@ -0,0 +1,350 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	gosync "sync"
+
+	"paperbox/internal/config/storage"
+
+	"github.com/google/uuid"
+)
+
+// errNoRemoteData is returned by Load for documents nobody pushed yet; the coordinator then keeps
+// the local config
+var errNoRemoteData = errors.New("document was never synced")
+
+// state is what a replica remembers between runs: its ID and, per document, the last synced
+// version of each item
+type state struct {
+	Replica   string                    `json:"replica"`
+	Documents map[string]*documentState `json:"documents"`
+}
+
+// documentState is the synced state of one document. Deleted items are kept with a null Data so
+// their vector survives.
+type documentState struct {
+	Cursor   Vector            `json:"cursor"`
+	Document json.RawMessage   `json:"document,omitempty"`
+	Items    map[string]Change `json:"items"`
+}
+
+// Storage is a storage.Storage syncing configs with a workspace, meant as the cloud storage of a
+// storage.StorageCoordinator with Resolve as its conflict handler. Load pulls what changed since
+// the last sync; Resolve merges it into the local config, reporting items changed on both sides as
+// conflicts; Save pushes the local changes. Conflicting items keep their local version, which
+// replaces the remote one with the next save unless the user takes the remote version first.
+type Storage struct {
+	client     *Client
+	statePath  string
+	files      *storage.FileStorage
+	onConflict func(Conflict)
+
+	mu      gosync.Mutex
+	state   *state
+	pending map[string]*PullResponse // Pulled but not merged into the state until the next save
+	loaded  string                   // Document of the last Load, the one Resolve merges
+}
+
+// NewStorage creates a syncing storage remembering its state in statePath. onConflict, if not nil,
+// is called for every conflict found while pulling or pushing.
+func NewStorage(client *Client, statePath string, onConflict func(Conflict)) *Storage {
+	return &Storage{
+		client:     client,
+		statePath:  statePath,
+		files:      storage.NewFileStorage(),
+		onConflict: onConflict,
+		pending:    make(map[string]*PullResponse),
+	}
+}
+
+// Load pulls the changes to a config and decodes the remote version into target
+func (s *Storage) Load(filePath string, target interface{}) error {
+	name := filepath.Base(filePath)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, err := s.document(name)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Pull(context.Background(), name, doc.Cursor)
+	if err != nil {
+		return err
+	}
+	s.pending[name] = resp
+	s.loaded = name
+
+	document := doc.Document
+	if !isNull(resp.Document) {
+		document = resp.Document
+	}
+	items := make(map[string]json.RawMessage)
+	for id, change := range doc.Items {
+		items[id] = change.Data
+	}
+	for _, change := range resp.Changes {
+		items[change.ID] = change.Data
+	}
+	for id, data := range items {
+		if isNull(data) {
+			delete(items, id)
+		}
+	}
+	if isNull(document) && len(items) == 0 {
+		return errNoRemoteData
+	}
+	return assemble(document, items, target)
+}
+
+// Resolve is the storage.ConflictHandler merging the changes pulled by the last Load into local:
+// remote changes to items left alone locally are taken, items changed on both sides keep their
+// local version and are reported as conflicts. The settings outside the items are taken when they
+// did not change locally or were never synced.
+func (s *Storage) Resolve(local, remote interface{}) (storage.ConflictResolution, error) {
+	conflicts, err := s.merge(local)
+	if err != nil {
+		return storage.ResolutionKeepLocal, err
+	}
+	s.report(conflicts)
+	return storage.ResolutionKeepLocal, nil
+}
+
+// merge applies the pending pull of the last loaded document to local
+func (s *Storage) merge(local interface{}) ([]Conflict, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := s.pending[s.loaded]
+	if resp == nil {
+		return nil, nil
+	}
+	doc, err := s.document(s.loaded)
+	if err != nil {
+		return nil, err
+	}
+	document, items, err := split(local)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []Conflict
+	for _, change := range resp.Changes {
+		base, synced := doc.Items[change.ID]
+		current, exists := items[change.ID]
+		switch {
+		case synced && jsonEqual(base.Data, change.Data) && base.Vector.Covers(change.Vector):
+			// Already known, e.g. the remote side of an earlier conflict
+		case synced && jsonEqual(base.Data, current), !synced && !exists:
+			if change.Deleted() {
+				delete(items, change.ID)
+			} else {
+				items[change.ID] = change.Data
+			}
+		case !jsonEqual(current, change.Data):
+			conflicts = append(conflicts, Conflict{Document: s.loaded, ItemID: change.ID, Local: current, Remote: change})
+		}
+	}
+	if !isNull(resp.Document) && (isNull(doc.Document) || jsonEqual(doc.Document, document)) {
+		document = resp.Document
+	}
+
+	if err := assemble(document, items, local); err != nil {
+		return nil, err
+	}
+	return conflicts, nil
+}
+
+// Save pushes the items that changed since the last sync
+func (s *Storage) Save(filePath string, data interface{}) error {
+	conflicts, err := s.push(filepath.Base(filePath), data)
+	s.report(conflicts)
+	return err
+}
+
+// push sends the local changes to a document and records what the server kept
+func (s *Storage) push(name string, data interface{}) ([]Conflict, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, err := s.document(name)
+	if err != nil {
+		return nil, err
+	}
+	pulled := s.pending[name]
+	if pulled != nil {
+		// The config being saved was merged with this pull when it was loaded
+		if !isNull(pulled.Document) {
+			doc.Document = pulled.Document
+		}
+		for _, change := range pulled.Changes {
+			doc.Items[change.ID] = change
+		}
+		doc.Cursor = doc.Cursor.Merge(pulled.Cursor)
+		delete(s.pending, name)
+	}
+
+	document, items, err := split(data)
+	if err != nil {
+		return nil, err
+	}
+	req := &PushRequest{Replica: s.state.Replica, Changes: []Change{}}
+	if !jsonEqual(document, doc.Document) {
+		req.Document = document
+	}
+	for id, current := range items {
+		if base, synced := doc.Items[id]; !synced || !jsonEqual(base.Data, current) {
+			req.Changes = append(req.Changes, Change{ID: id, Data: current, Vector: base.Vector.Next(s.state.Replica)})
+		}
+	}
+	for id, base := range doc.Items {
+		if _, exists := items[id]; !exists && !base.Deleted() {
+			req.Changes = append(req.Changes, Change{ID: id, Data: json.RawMessage("null"), Vector: base.Vector.Next(s.state.Replica)})
+		}
+	}
+	if len(req.Changes) == 0 && req.Document == nil {
+		if pulled != nil {
+			return nil, s.saveState()
+		}
+		return nil, nil
+	}
+	sort.Slice(req.Changes, func(a, b int) bool { return req.Changes[a].ID < req.Changes[b].ID })
+
+	resp, err := s.client.Push(context.Background(), name, req)
+	if err != nil {
+		return nil, err
+	}
+	pushed := make(map[string]Change, len(req.Changes))
+	for _, change := range req.Changes {
+		pushed[change.ID] = change
+	}
+	for _, id := range resp.Accepted {
+		if change, ok := pushed[id]; ok {
+			doc.Items[id] = change
+			doc.Cursor = doc.Cursor.Merge(change.Vector)
+		}
+	}
+	var conflicts []Conflict
+	for _, change := range resp.Conflicts {
+		// The remote version becomes the base, so the local one wins with the next push
+		doc.Items[change.ID] = change
+		conflicts = append(conflicts, Conflict{Document: name, ItemID: change.ID, Local: items[change.ID], Remote: change})
+	}
+	if req.Document != nil {
+		doc.Document = req.Document
+	}
+
+	return conflicts, s.saveState()
+}
+
+// saveState writes the state file (must hold the lock)
+func (s *Storage) saveState() error {
+	if err := s.files.Save(s.statePath, s.state); err != nil {
+		return fmt.Errorf("failed to save sync state: %w", err)
+	}
+	return nil
+}
+
+// report passes conflicts to the conflict callback
+func (s *Storage) report(conflicts []Conflict) {
+	if s.onConflict == nil {
+		return
+	}
+	for _, conflict := range conflicts {
+		s.onConflict(conflict)
+	}
+}
+
+// document returns the synced state of a document, reading the state file on first use (must
+// hold the lock)
+func (s *Storage) document(name string) (*documentState, error) {
+	if s.state == nil {
+		var st state
+		if err := s.files.Load(s.statePath, &st); err != nil {
+			return nil, fmt.Errorf("failed to load sync state: %w", err)
+		}
+		if st.Replica == "" {
+			st.Replica = uuid.New().String()
+		}
+		if st.Documents == nil {
+			st.Documents = make(map[string]*documentState)
+		}
+		s.state = &st
+	}
+	doc, ok := s.state.Documents[name]
+	if !ok {
+		doc = &documentState{Cursor: Vector{}, Items: make(map[string]Change)}
+		s.state.Documents[name] = doc
+	}
+	return doc, nil
+}
+
+// split encodes a config as its document (the fields other than the items) and its items
+func split(data interface{}) (json.RawMessage, map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	fields := make(map[string]json.RawMessage)
+	if !isNull(encoded) {
+		if err := json.Unmarshal(encoded, &fields); err != nil {
+			return nil, nil, fmt.Errorf("config must be a JSON object: %w", err)
+		}
+	}
+	items := make(map[string]json.RawMessage)
+	if raw, ok := fields[itemsKey]; ok && !isNull(raw) {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, nil, fmt.Errorf("config %s must be an object: %w", itemsKey, err)
+		}
+	}
+	delete(fields, itemsKey)
+	document, err := json.Marshal(fields)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return document, items, nil
+}
+
+// assemble decodes a document and its items into target, replacing what it held
+func assemble(document json.RawMessage, items map[string]json.RawMessage, target interface{}) error {
+	fields := make(map[string]json.RawMessage)
+	if !isNull(document) {
+		if err := json.Unmarshal(document, &fields); err != nil {
+			return fmt.Errorf("synced config must be a JSON object: %w", err)
+		}
+	}
+	values, err := json.Marshal(items)
+	if err != nil {
+		return fmt.Errorf("failed to assemble config: %w", err)
+	}
+	fields[itemsKey] = values
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("failed to assemble config: %w", err)
+	}
+
+	if v := reflect.ValueOf(target); v.Kind() == reflect.Ptr && !v.IsNil() {
+		v.Elem().Set(reflect.Zero(v.Elem().Type()))
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("failed to decode synced config: %w", err)
+	}
+	return nil
+}
+
+// jsonEqual reports whether two JSON values are equal, ignoring formatting and key order
+func jsonEqual(a, b json.RawMessage) bool {
+	if isNull(a) || isNull(b) {
+		return isNull(a) == isNull(b)
+	}
+	var x, y interface{}
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
@@ -0,0 +1,161 @@
+package sync
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"paperbox/internal/config/storage"
+)
+
+// memoryServer is a minimal in-memory sync server
+type memoryServer struct {
+	document json.RawMessage
+	items    map[string]Change
+}
+
+func (m *memoryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		since, err := ParseVector(r.URL.Query().Get("since"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		resp := PullResponse{Cursor: Vector{}, Document: m.document, Changes: []Change{}}
+		for _, change := range m.items {
+			resp.Cursor = resp.Cursor.Merge(change.Vector)
+			if !since.Covers(change.Vector) {
+				resp.Changes = append(resp.Changes, change)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	case http.MethodPost:
+		var req PushRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.Document != nil {
+			m.document = req.Document
+		}
+		resp := PushResponse{Accepted: []string{}, Conflicts: []Change{}}
+		for _, change := range req.Changes {
+			if current, ok := m.items[change.ID]; ok && !change.Vector.Covers(current.Vector) {
+				resp.Conflicts = append(resp.Conflicts, current)
+				continue
+			}
+			m.items[change.ID] = change
+			resp.Accepted = append(resp.Accepted, change.ID)
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+type testConfig struct {
+	Version int                          `json:"version"`
+	Values  map[string]map[string]string `json:"values"`
+}
+
+// replica is one installation: its own config file and sync state behind a coordinator
+type replica struct {
+	coordinator *storage.StorageCoordinator
+	path        string
+	conflicts   []Conflict
+}
+
+func newReplica(t *testing.T, server string) *replica {
+	dir := t.TempDir()
+	client, err := NewClient(server, "team", func() (string, error) { return "secret", nil })
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	r := &replica{path: filepath.Join(dir, "requests.json")}
+	store := NewStorage(client, filepath.Join(dir, "sync.json"), func(c Conflict) { r.conflicts = append(r.conflicts, c) })
+	r.coordinator = storage.NewStorageCoordinator(storage.NewFileStorage(), store, store.Resolve)
+	return r
+}
+
+func (r *replica) load(t *testing.T) *testConfig {
+	var cfg testConfig
+	if err := r.coordinator.Load(r.path, &cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return &cfg
+}
+
+func (r *replica) save(t *testing.T, cfg *testConfig) {
+	if err := r.coordinator.Save(r.path, cfg); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+}
+
+func TestStorageSyncsReplicas(t *testing.T) {
+	server := httptest.NewServer(&memoryServer{items: make(map[string]Change)})
+	defer server.Close()
+	alice, bob := newReplica(t, server.URL), newReplica(t, server.URL)
+
+	alice.load(t)
+	alice.save(t, &testConfig{Version: 1, Values: map[string]map[string]string{"users": {"name": "Users"}, "orders": {"name": "Orders"}}})
+
+	cfg := bob.load(t)
+	if cfg.Version != 1 || cfg.Values["users"]["name"] != "Users" || len(cfg.Values) != 2 {
+		t.Fatalf("bob loaded %+v, want alice's config", cfg)
+	}
+	cfg.Values["users"] = map[string]string{"name": "All users"}
+	delete(cfg.Values, "orders")
+	bob.save(t, cfg)
+
+	cfg = alice.load(t)
+	if cfg.Values["users"]["name"] != "All users" || cfg.Values["orders"] != nil {
+		t.Errorf("alice loaded %+v, want bob's edit and deletion", cfg)
+	}
+
+	// Both edit the same item; bob pushes second and is told about alice's version
+	cfg.Values["users"]["name"] = "Alice's users"
+	alice.save(t, cfg)
+	stale := &testConfig{Version: 1, Values: map[string]map[string]string{"users": {"name": "Bob's users"}}}
+	bob.save(t, stale)
+	if len(bob.conflicts) != 1 || bob.conflicts[0].ItemID != "users" || !jsonEqual(bob.conflicts[0].Remote.Data, json.RawMessage(`{"name":"Alice's users"}`)) {
+		t.Fatalf("bob conflicts = %+v, want the users item", bob.conflicts)
+	}
+
+	// The next save of bob's version replaces alice's
+	stale.Values["users"]["name"] = "Bob's users, again"
+	bob.save(t, stale)
+	if cfg := alice.load(t); cfg.Values["users"]["name"] != "Bob's users, again" || len(alice.conflicts) != 0 {
+		t.Errorf("alice loaded %+v with conflicts %+v", cfg, alice.conflicts)
+	}
+}
+
+func TestClientRejectsBadToken(t *testing.T) {
+	server := httptest.NewServer(&memoryServer{items: make(map[string]Change)})
+	defer server.Close()
+	client, err := NewClient(server.URL, "team", func() (string, error) { return "wrong", nil })
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if _, err := client.Pull(t.Context(), "requests.json", nil); err == nil {
+		t.Error("Pull() with a wrong token succeeded")
+	}
+	if _, err := NewClient("ftp://example.com", "team", nil); err == nil {
+		t.Error("NewClient() accepted a non-HTTP server")
+	}
+}
+
+func TestVector(t *testing.T) {
+	v := Vector{"a": 2, "b": 1}
+	if !v.Covers(Vector{"a": 1}) || v.Covers(Vector{"c": 1}) || !v.Next("c").Covers(Vector{"c": 1}) {
+		t.Error("Covers() gave a wrong result")
+	}
+	parsed, err := ParseVector(v.String())
+	if err != nil || !parsed.Covers(v) || !v.Covers(parsed) {
+		t.Errorf("ParseVector(%q) = %v, %v", v.String(), parsed, err)
+	}
+}
@@ -0,0 +1,172 @@
+// Package transform holds the small text conversions API work keeps needing: base64 and URL
+// encoding, hashes, HMACs and JSON string escaping. Each operation is also a template function
+// (see RegisterTemplateFuncs).
+package transform
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/engine"
+)
+
+// Operations
+const (
+	Base64Encode = "base64Encode"
+	Base64Decode = "base64Decode" // Accepts standard and URL-safe alphabets, with or without padding
+	URLEncode    = "urlEncode"    // Query encoding: spaces become "+"
+	URLDecode    = "urlDecode"
+	SHA1         = "sha1" // Hashes and HMACs are hex-encoded
+	SHA256       = "sha256"
+	MD5          = "md5"
+	HMACSHA1     = "hmacSHA1"
+	HMACSHA256   = "hmacSHA256"
+	JSONEscape   = "jsonEscape" // Escapes text for use inside a JSON string
+	JSONUnescape = "jsonUnescape"
+)
+
+// operation converts input; key is only used by HMACs
+type operation func(input string, key string) (string, error)
+
+var operations = map[string]operation{
+	Base64Encode: func(input, _ string) (string, error) {
+		return base64.StdEncoding.EncodeToString([]byte(input)), nil
+	},
+	Base64Decode: decodeBase64,
+	URLEncode: func(input, _ string) (string, error) {
+		return url.QueryEscape(input), nil
+	},
+	URLDecode: func(input, _ string) (string, error) {
+		return url.QueryUnescape(input)
+	},
+	SHA1:         digest(sha1.New),
+	SHA256:       digest(sha256.New),
+	MD5:          digest(md5.New),
+	HMACSHA1:     mac(sha1.New),
+	HMACSHA256:   mac(sha256.New),
+	JSONEscape:   escapeJSON,
+	JSONUnescape: unescapeJSON,
+}
+
+// keyed are the operations that take a key
+var keyed = map[string]bool{HMACSHA1: true, HMACSHA256: true}
+
+// Names returns the operations, sorted
+func Names() []string {
+	names := make([]string, 0, len(operations))
+	for name := range operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply runs an operation on input; key is the secret of HMAC operations and ignored by the others
+func Apply(name string, input string, key string) (string, error) {
+	op, ok := operations[name]
+	if !ok {
+		return "", apperrors.Invalidf("unknown text operation %q", name)
+	}
+	result, err := op(input, key)
+	if err != nil {
+		return "", apperrors.Wrap(apperrors.ValidationFailed, err, name+" failed")
+	}
+	return result, nil
+}
+
+// RegisterTemplateFuncs registers every operation as a template function of the same name, e.g.
+// {{sha256 body}} or {{hmacSHA1 key payload}}. Functions already registered, such as the built-in
+// {{hmacSHA256}}, are kept.
+func RegisterTemplateFuncs(registry *engine.FuncRegistry) {
+	for name, op := range operations {
+		if _, exists := registry.Lookup(name); exists {
+			continue
+		}
+		registry.Register(name, func(args []string) (string, error) {
+			if keyed[name] {
+				if len(args) != 2 {
+					return "", fmt.Errorf("%s expects 2 arguments (key, payload), got %d", name, len(args))
+				}
+				return op(args[1], args[0])
+			}
+			if len(args) != 1 {
+				return "", fmt.Errorf("%s expects 1 argument, got %d", name, len(args))
+			}
+			return op(args[0], "")
+		})
+	}
+}
+
+// decodeBase64 decodes standard or URL-safe base64, padded or not, into text
+func decodeBase64(input, _ string) (string, error) {
+	input = strings.TrimSpace(input)
+	var decoded []byte
+	var err error
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err = enc.DecodeString(input); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("input is not base64")
+	}
+	if !utf8.Valid(decoded) {
+		return "", fmt.Errorf("decoded data is binary, not text")
+	}
+	return string(decoded), nil
+}
+
+// digest returns an operation hashing its input
+func digest(newHash func() hash.Hash) operation {
+	return func(input, _ string) (string, error) {
+		h := newHash()
+		h.Write([]byte(input))
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+}
+
+// mac returns an operation computing the HMAC of its input
+func mac(newHash func() hash.Hash) operation {
+	return func(input, key string) (string, error) {
+		h := hmac.New(newHash, []byte(key))
+		h.Write([]byte(input))
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+}
+
+// escapeJSON returns input as the content of a JSON string, without the quotes
+func escapeJSON(input, _ string) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(input); err != nil {
+		return "", err
+	}
+	encoded := strings.TrimSuffix(buf.String(), "\n")
+	return encoded[1 : len(encoded)-1], nil
+}
+
+// unescapeJSON reads input as the content of a JSON string; surrounding quotes are optional
+func unescapeJSON(input, _ string) (string, error) {
+	if len(input) < 2 || input[0] != '"' || input[len(input)-1] != '"' {
+		input = `"` + input + `"`
+	}
+	var s string
+	if err := json.Unmarshal([]byte(input), &s); err != nil {
+		return "", fmt.Errorf("input is not an escaped JSON string")
+	}
+	return s, nil
+}
@@ -0,0 +1,58 @@
+package transform
+
+import (
+	"testing"
+
+	"paperbox/internal/engine"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		op, input, key string
+		want           string
+		wantErr        bool
+	}{
+		{op: Base64Encode, input: "user:pass", want: "dXNlcjpwYXNz"},
+		{op: Base64Decode, input: "dXNlcjpwYXNz", want: "user:pass"},
+		{op: Base64Decode, input: "PDw_Pz4-", want: "<<??>>"}, // URL-safe, no padding
+		{op: Base64Decode, input: "not base64!", wantErr: true},
+		{op: Base64Decode, input: "/w==", wantErr: true}, // Binary
+		{op: URLEncode, input: "a b&c=d/é", want: "a+b%26c%3Dd%2F%C3%A9"},
+		{op: URLDecode, input: "a+b%26c", want: "a b&c"},
+		{op: URLDecode, input: "%zz", wantErr: true},
+		{op: SHA1, input: "abc", want: "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{op: SHA256, input: "abc", want: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{op: MD5, input: "abc", want: "900150983cd24fb0d6963f7d28e17f72"},
+		{op: HMACSHA256, input: "The quick brown fox jumps over the lazy dog", key: "key", want: "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"},
+		{op: HMACSHA1, input: "The quick brown fox jumps over the lazy dog", key: "key", want: "de7c9b85b8b78aa6bc8a7a36f70a90701c9db4d9"},
+		{op: JSONEscape, input: "say \"hi\"\n<b>", want: `say \"hi\"\n<b>`},
+		{op: JSONUnescape, input: `say \"hi\"\n`, want: "say \"hi\"\n"},
+		{op: JSONUnescape, input: `"quoted"`, want: "quoted"},
+		{op: "rot13", input: "x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.op+" "+tt.input, func(t *testing.T) {
+			got, err := Apply(tt.op, tt.input, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterTemplateFuncs(t *testing.T) {
+	registry := engine.NewFuncRegistry()
+	registry.Register(SHA1, func(args []string) (string, error) { return "kept", nil })
+	RegisterTemplateFuncs(registry)
+
+	sub := engine.NewSubstituterWithFuncs(map[string]string{"secret": "key"}, registry)
+	got := sub.Apply(`{{sha1 "abc"}} {{md5 "abc"}} {{hmacSHA1 secret "The quick brown fox jumps over the lazy dog"}}`)
+	want := "kept 900150983cd24fb0d6963f7d28e17f72 de7c9b85b8b78aa6bc8a7a36f70a90701c9db4d9"
+	if got != want {
+		t.Errorf("Apply() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,216 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// JumpHost is an SSH server connections to private hosts are forwarded through, like "ssh -J"
+type JumpHost struct {
+	Server     string // host:port of the SSH server
+	User       string
+	Key        []byte // Private key in PEM form
+	Passphrase string // Passphrase of an encrypted key
+	HostKey    string // Expected host key fingerprint ("SHA256:..."); required to send anything
+}
+
+// errUnpinned is returned for a jump host whose host key is not pinned
+var errUnpinned = errors.New("the SSH tunnel's host key is not pinned; check the tunnel and set its hostKey to the fingerprint")
+
+// ID identifies the connection settings of a jump host without revealing the key
+func (h JumpHost) ID() string {
+	sum := sha256.Sum256(append(append([]byte{}, h.Key...), h.Passphrase...))
+	return h.User + "@" + h.Server + "|" + h.HostKey + "|" + hex.EncodeToString(sum[:])
+}
+
+// Jumps keeps one SSH connection per jump host and opens connections through it. A connection
+// that drops is forgotten and made again on the next dial.
+type Jumps struct {
+	mu      sync.Mutex
+	clients map[string]*ssh.Client
+	dialing map[string]*jumpDial // Connections being made, which concurrent dials wait for
+}
+
+// jumpDial is a connection to a jump host being made; done is closed once client or err is set
+type jumpDial struct {
+	done   chan struct{}
+	client *ssh.Client
+	err    error
+}
+
+// NewJumps creates an empty set of jump host connections
+func NewJumps() *Jumps {
+	return &Jumps{clients: make(map[string]*ssh.Client), dialing: make(map[string]*jumpDial)}
+}
+
+// Dial opens a TCP connection to addr from the jump host; the jump host resolves its name
+func (j *Jumps) Dial(ctx context.Context, host JumpHost, addr string) (net.Conn, error) {
+	client, err := j.client(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("SSH tunnel via %s could not reach %s: %w", host.Server, addr, err)
+	}
+	return conn, nil
+}
+
+// Check logs in to a jump host and returns its host key fingerprint, for pinning. An unpinned host
+// key is accepted for this login only, as nothing is sent through it. The fingerprint is also
+// returned when logging in fails after the key exchange.
+func Check(ctx context.Context, host JumpHost) (string, error) {
+	var fingerprint string
+	client, err := connect(ctx, host, func(key ssh.PublicKey) { fingerprint = ssh.FingerprintSHA256(key) })
+	if err != nil {
+		return fingerprint, err
+	}
+	client.Close()
+	return fingerprint, nil
+}
+
+// Close closes every jump host connection
+func (j *Jumps) Close() {
+	j.mu.Lock()
+	clients := j.clients
+	j.clients = make(map[string]*ssh.Client)
+	j.mu.Unlock()
+	for _, client := range clients {
+		client.Close()
+	}
+}
+
+// client returns the connection to a jump host, connecting first if there is none. The lock is
+// not held while connecting, and concurrent dials of the same jump host share one login.
+func (j *Jumps) client(ctx context.Context, host JumpHost) (*ssh.Client, error) {
+	if host.HostKey == "" {
+		return nil, errUnpinned
+	}
+	id := host.ID()
+	for {
+		j.mu.Lock()
+		if client, ok := j.clients[id]; ok {
+			j.mu.Unlock()
+			return client, nil
+		}
+		if pending, ok := j.dialing[id]; ok {
+			j.mu.Unlock()
+			select {
+			case <-pending.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			// The dial that connected was cancelled by its own caller, so this one tries again
+			if pending.err != nil && ctx.Err() == nil &&
+				(errors.Is(pending.err, context.Canceled) || errors.Is(pending.err, context.DeadlineExceeded)) {
+				continue
+			}
+			return pending.client, pending.err
+		}
+		pending := &jumpDial{done: make(chan struct{})}
+		j.dialing[id] = pending
+		j.mu.Unlock()
+
+		pending.client, pending.err = connect(ctx, host, nil)
+		j.mu.Lock()
+		delete(j.dialing, id)
+		if pending.err == nil {
+			j.clients[id] = pending.client
+		}
+		j.mu.Unlock()
+		close(pending.done)
+		if pending.err != nil {
+			return nil, pending.err
+		}
+
+		client := pending.client
+		go func() {
+			_ = client.Wait()
+			j.mu.Lock()
+			defer j.mu.Unlock()
+			if j.clients[id] == client {
+				delete(j.clients, id)
+			}
+		}()
+		return client, nil
+	}
+}
+
+// connect logs in to a jump host with its key. seen is called with the host key before it is
+// checked; when it is set, an unpinned host key is accepted.
+func connect(ctx context.Context, host JumpHost, seen func(ssh.PublicKey)) (*ssh.Client, error) {
+	config, err := jumpConfig(host)
+	if err != nil {
+		return nil, err
+	}
+	if seen != nil {
+		check := config.HostKeyCallback
+		config.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			seen(key)
+			if host.HostKey == "" {
+				return nil
+			}
+			return check(hostname, remote, key)
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: DialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", host.Server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SSH server %s: %w", host.Server, err)
+	}
+	// The handshake has no context of its own, so it is bounded by a deadline
+	deadline := time.Now().Add(DialTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+	c, chans, reqs, err := ssh.NewClientConn(conn, host.Server, config)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to log in to SSH server %s: %w", host.Server, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// jumpConfig builds the SSH client configuration of a jump host
+func jumpConfig(host JumpHost) (*ssh.ClientConfig, error) {
+	if len(host.Key) == 0 {
+		return nil, errors.New("the SSH tunnel has no private key; set its key variable")
+	}
+	var signer ssh.Signer
+	var err error
+	if host.Passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(host.Key, []byte(host.Passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(host.Key)
+	}
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return nil, errors.New("the SSH tunnel key is encrypted; set its passphrase variable")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the SSH tunnel key: %w", err)
+	}
+
+	// Unlike the public tunnel's relay, a jump host sees the requests, so its key must be pinned
+	check := func(string, net.Addr, ssh.PublicKey) error { return errUnpinned }
+	if host.HostKey != "" {
+		check = hostKeyCallback(host.HostKey)
+	}
+	return &ssh.ClientConfig{
+		User:            host.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: check,
+		Timeout:         DialTimeout,
+	}, nil
+}
@@ -0,0 +1,262 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// jumpServer is a minimal SSH jump host: it accepts one client key and forwards direct-tcpip
+// channels, resolving "private.internal" to the loopback address as a private DNS would
+type jumpServer struct {
+	addr    string
+	hostKey ssh.PublicKey
+
+	mu      sync.Mutex
+	logins  int
+	targets []string
+}
+
+func startJumpServer(t *testing.T, authorized ssh.PublicKey) *jumpServer {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &jumpServer{hostKey: signer.PublicKey()}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(authorized.Marshal()) {
+				return nil, io.EOF
+			}
+			s.mu.Lock()
+			s.logins++
+			s.mu.Unlock()
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	s.addr = listener.Addr().String()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, config)
+		}
+	}()
+	return s
+}
+
+// serve relays the direct-tcpip channels of one client connection
+func (s *jumpServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		var target struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+		if newChannel.ChannelType() != "direct-tcpip" || ssh.Unmarshal(newChannel.ExtraData(), &target) != nil {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+			continue
+		}
+		s.mu.Lock()
+		s.targets = append(s.targets, target.Host)
+		s.mu.Unlock()
+
+		host := strings.Replace(target.Host, "private.internal", "127.0.0.1", 1)
+		upstream, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(target.Port))))
+		if err != nil {
+			_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			upstream.Close()
+			continue
+		}
+		go ssh.DiscardRequests(requests)
+		go func() {
+			defer channel.Close()
+			_, _ = io.Copy(channel, upstream)
+		}()
+		go func() {
+			defer upstream.Close()
+			_, _ = io.Copy(upstream, channel)
+		}()
+	}
+}
+
+// clientKey generates a client key in PEM form, encrypted when passphrase is set
+func clientKey(t *testing.T, passphrase string) ([]byte, ssh.PublicKey) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block *pem.Block
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(private, "", []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(private, "")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPublic, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(block), sshPublic
+}
+
+func TestJumpsDialThroughJumpHost(t *testing.T) {
+	private := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "inside "+r.Host)
+	}))
+	defer private.Close()
+	port := strconv.Itoa(private.Listener.Addr().(*net.TCPAddr).Port)
+
+	key, public := clientKey(t, "")
+	server := startJumpServer(t, public)
+	host := JumpHost{Server: server.addr, User: "dev", Key: key, HostKey: ssh.FingerprintSHA256(server.hostKey)}
+
+	jumps := NewJumps()
+	defer jumps.Close()
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return jumps.Dial(ctx, host, addr)
+		},
+		DisableKeepAlives: true,
+	}}
+	for range 2 {
+		resp, err := client.Get("http://private.internal:" + port + "/")
+		if err != nil {
+			t.Fatalf("Get() through the jump host error = %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "inside private.internal:"+port {
+			t.Errorf("response = %q", body)
+		}
+	}
+	if server.logins != 1 || len(server.targets) != 2 || server.targets[0] != "private.internal" {
+		t.Errorf("logins = %d, targets = %v, want one login and names resolved by the jump host", server.logins, server.targets)
+	}
+
+	jumps.Close()
+	if _, err := client.Get("http://private.internal:" + port + "/"); err != nil {
+		t.Fatalf("Get() after Close() error = %v", err)
+	}
+	if server.logins != 2 {
+		t.Errorf("logins = %d, want a new login after Close()", server.logins)
+	}
+}
+
+func TestJumpHostChecks(t *testing.T) {
+	key, public := clientKey(t, "")
+	server := startJumpServer(t, public)
+	want := ssh.FingerprintSHA256(server.hostKey)
+
+	fingerprint, err := Check(context.Background(), JumpHost{Server: server.addr, User: "dev", Key: key})
+	if err != nil || fingerprint != want {
+		t.Errorf("Check() = %q, %v, want %q", fingerprint, err, want)
+	}
+	fingerprint, err = Check(context.Background(), JumpHost{Server: server.addr, User: "dev", Key: key, HostKey: "SHA256:other"})
+	if err == nil || fingerprint != want {
+		t.Errorf("Check() with another pinned key = %q, %v, want an error and the server's fingerprint", fingerprint, err)
+	}
+
+	other, _ := clientKey(t, "")
+	if _, err := Check(context.Background(), JumpHost{Server: server.addr, User: "dev", Key: other}); err == nil {
+		t.Error("Check() with an unknown key succeeded")
+	}
+	encrypted, encryptedPublic := clientKey(t, "s3cret")
+	encryptedServer := startJumpServer(t, encryptedPublic)
+	if _, err := Check(context.Background(), JumpHost{Server: encryptedServer.addr, User: "dev", Key: encrypted}); err == nil || !strings.Contains(err.Error(), "passphrase") {
+		t.Errorf("Check() without the passphrase error = %v", err)
+	}
+	if _, err := Check(context.Background(), JumpHost{Server: encryptedServer.addr, User: "dev", Key: encrypted, Passphrase: "s3cret"}); err != nil {
+		t.Errorf("Check() with the passphrase error = %v", err)
+	}
+	if _, err := Check(context.Background(), JumpHost{Server: server.addr, User: "dev"}); err == nil {
+		t.Error("Check() without a key succeeded")
+	}
+}
+
+func TestJumpsRefuseUnpinnedHostKey(t *testing.T) {
+	key, public := clientKey(t, "")
+	server := startJumpServer(t, public)
+
+	jumps := NewJumps()
+	defer jumps.Close()
+	if _, err := jumps.Dial(context.Background(), JumpHost{Server: server.addr, User: "dev", Key: key}, "private.internal:80"); err == nil || !strings.Contains(err.Error(), "not pinned") {
+		t.Errorf("Dial() through an unpinned jump host error = %v", err)
+	}
+	if server.logins != 0 {
+		t.Errorf("logins = %d, want none before the host key is pinned", server.logins)
+	}
+}
+
+func TestJumpsShareConcurrentLogins(t *testing.T) {
+	private := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer private.Close()
+	addr := "private.internal:" + strconv.Itoa(private.Listener.Addr().(*net.TCPAddr).Port)
+
+	key, public := clientKey(t, "")
+	server := startJumpServer(t, public)
+	host := JumpHost{Server: server.addr, User: "dev", Key: key, HostKey: ssh.FingerprintSHA256(server.hostKey)}
+
+	jumps := NewJumps()
+	defer jumps.Close()
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := jumps.Dial(context.Background(), host, addr)
+			if err != nil {
+				t.Errorf("Dial() error = %v", err)
+				return
+			}
+			conn.Close()
+		}()
+	}
+	wg.Wait()
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.logins != 1 || len(server.targets) != 8 {
+		t.Errorf("logins = %d, targets = %d, want the concurrent dials to share one login", server.logins, len(server.targets))
+	}
+}
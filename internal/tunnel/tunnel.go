@@ -0,0 +1,314 @@
+// Package tunnel exposes a local port on a public URL through an SSH reverse tunnel, the way
+// "ssh -R 80:localhost:PORT localhost.run" does, so third-party webhooks can reach the local
+// webhook listener or mock server without installing a separate agent. Jumps does the opposite:
+// it forwards connections to hosts inside a private network through an SSH jump host.
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// DialTimeout bounds connecting and authenticating to the SSH server
+	DialTimeout = 15 * time.Second
+	// URLTimeout bounds how long the server may take to announce the public URL
+	URLTimeout = 15 * time.Second
+)
+
+// errStopped is returned by a Start that Stop aborted
+var errStopped = errors.New("tunnel was stopped while opening")
+
+// urlPattern finds the public URL in the banner printed by tunnel services
+var urlPattern = regexp.MustCompile(`https://[A-Za-z0-9.-]+\.[A-Za-z]{2,}[^\s"']*`)
+
+// Options describe the SSH server to tunnel through
+type Options struct {
+	Server     string // host:port of the SSH server
+	User       string
+	KeyFile    string // Private key; empty logs in without a key, as public tunnel services allow
+	RemotePort int    // Port requested on the server
+	// PublicURL is the address the server exposes the port on. When empty, the first https URL the
+	// server prints (as tunnel services do) is used.
+	PublicURL string
+	// HostKey is the expected host key fingerprint ("SHA256:..."). When empty, the server's key is
+	// only accepted through TrustKey.
+	HostKey string
+	// TrustKey is called with the fingerprint of an unpinned server's key on the first login, to pin
+	// it; the key is accepted when it returns nil. Without it an unpinned server is refused.
+	TrustKey func(fingerprint string) error
+}
+
+// Tunnel is a single reverse tunnel; it can be restarted after Stop, or once the server dropped it
+type Tunnel struct {
+	mu       sync.Mutex
+	client   *ssh.Client
+	listener net.Listener
+	url      string
+	cancel   context.CancelFunc // Aborts the Start in progress; nil when none
+}
+
+// New creates a stopped tunnel
+func New() *Tunnel {
+	return &Tunnel{}
+}
+
+// Start connects to the server, forwards its remote port to localPort on this machine and
+// returns the public URL. The lock is not held while connecting, so Stop aborts a Start in progress.
+func (t *Tunnel) Start(opts Options, localPort int) (string, error) {
+	if localPort <= 0 || localPort > 65535 {
+		return "", fmt.Errorf("invalid port %d", localPort)
+	}
+	config, err := clientConfig(opts)
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	if t.client != nil {
+		url := t.url
+		t.mu.Unlock()
+		return "", fmt.Errorf("tunnel is already open at %s", url)
+	}
+	if t.cancel != nil {
+		t.mu.Unlock()
+		return "", errors.New("tunnel is already opening")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	t.mu.Unlock()
+
+	client, listener, url, err := open(ctx, opts, config)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cancel = nil
+	if ctx.Err() != nil {
+		if err == nil {
+			listener.Close()
+			client.Close()
+		}
+		err = errStopped
+	}
+	cancel()
+	if err != nil {
+		return "", err
+	}
+
+	local := net.JoinHostPort("127.0.0.1", strconv.Itoa(localPort))
+	go forward(listener, local)
+	go t.watch(client)
+
+	t.client, t.listener, t.url = client, listener, url
+	return url, nil
+}
+
+// open logs in to the server, asks it to forward the remote port and waits for the public URL.
+// Cancelling ctx closes the connection, which aborts whichever step is under way.
+func open(ctx context.Context, opts Options, config *ssh.ClientConfig) (*ssh.Client, net.Listener, string, error) {
+	dialer := &net.Dialer{Timeout: DialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", opts.Server)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to connect to %s: %w", opts.Server, err)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	// The handshake has no context of its own, so it is bounded by a deadline
+	_ = conn.SetDeadline(time.Now().Add(DialTimeout))
+	c, chans, reqs, err := ssh.NewClientConn(conn, opts.Server, config)
+	if err != nil {
+		conn.Close()
+		return nil, nil, "", fmt.Errorf("failed to connect to %s: %w", opts.Server, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	client := ssh.NewClient(c, chans, reqs)
+
+	listener, err := client.Listen("tcp", net.JoinHostPort("0.0.0.0", strconv.Itoa(opts.RemotePort)))
+	if err != nil {
+		client.Close()
+		return nil, nil, "", fmt.Errorf("server refused the tunnel: %w", err)
+	}
+
+	url := opts.PublicURL
+	if url == "" {
+		if url, err = announcedURL(ctx, client); err != nil {
+			listener.Close()
+			client.Close()
+			return nil, nil, "", err
+		}
+	}
+	return client, listener, url, nil
+}
+
+// watch clears the tunnel once its connection is gone, so that a tunnel the server dropped can
+// be started again
+func (t *Tunnel) watch(client *ssh.Client) {
+	_ = client.Wait()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client != client {
+		return
+	}
+	_ = t.listener.Close()
+	t.client, t.listener, t.url = nil, nil, ""
+}
+
+// Stop closes the tunnel, or aborts opening it
+func (t *Tunnel) Stop() error {
+	t.mu.Lock()
+	client, listener := t.client, t.listener
+	t.client, t.listener, t.url = nil, nil, ""
+	if t.cancel != nil {
+		t.cancel()
+	}
+	t.mu.Unlock()
+
+	if client == nil {
+		return nil
+	}
+	_ = listener.Close()
+	if err := client.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return fmt.Errorf("failed to close tunnel: %w", err)
+	}
+	return nil
+}
+
+// URL returns the public URL, or an empty string when the tunnel is closed
+func (t *Tunnel) URL() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.url
+}
+
+// clientConfig builds the SSH client configuration from the options
+func clientConfig(opts Options) (*ssh.ClientConfig, error) {
+	// Key-less services accept "none" (tried first by the client) or an empty keyboard-interactive login
+	auth := []ssh.AuthMethod{
+		ssh.KeyboardInteractive(func(_, _ string, questions []string, _ []bool) ([]string, error) {
+			return make([]string, len(questions)), nil
+		}),
+	}
+	if opts.KeyFile != "" {
+		data, err := os.ReadFile(opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key file: %w", err)
+		}
+		auth = append([]ssh.AuthMethod{ssh.PublicKeys(signer)}, auth...)
+	}
+
+	// An unpinned key is trusted on first use at most, so later logins are checked against it
+	check := func(_ string, _ net.Addr, key ssh.PublicKey) error {
+		return fmt.Errorf("the tunnel server's host key %s is not pinned; set it as hostKey in the tunnel settings", ssh.FingerprintSHA256(key))
+	}
+	switch {
+	case opts.HostKey != "":
+		check = hostKeyCallback(opts.HostKey)
+	case opts.TrustKey != nil:
+		check = func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			return opts.TrustKey(ssh.FingerprintSHA256(key))
+		}
+	}
+	return &ssh.ClientConfig{
+		User:            opts.User,
+		Auth:            auth,
+		HostKeyCallback: check,
+		Timeout:         DialTimeout,
+	}, nil
+}
+
+// hostKeyCallback checks the server's key against a pinned fingerprint ("SHA256:...")
+func hostKeyCallback(pinned string) ssh.HostKeyCallback {
+	return func(_ string, _ net.Addr, key ssh.PublicKey) error {
+		if got := ssh.FingerprintSHA256(key); got != pinned {
+			return fmt.Errorf("host key %s does not match the pinned %s", got, pinned)
+		}
+		return nil
+	}
+}
+
+// announcedURL opens a shell session and waits for the server to print the public URL, or for ctx
+// to be cancelled
+func announcedURL(ctx context.Context, client *ssh.Client) (string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to open session: %w", err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return "", fmt.Errorf("failed to open session: %w", err)
+	}
+	stderr, err := session.StderrPipe()
+	if err != nil {
+		return "", fmt.Errorf("failed to open session: %w", err)
+	}
+	if err := session.Shell(); err != nil {
+		return "", fmt.Errorf("failed to open session: %w", err)
+	}
+
+	// The session stays open for the lifetime of the client; its output is only scanned for the URL
+	found := make(chan string, 1)
+	for _, output := range []io.Reader{stdout, stderr} {
+		go func(output io.Reader) {
+			scanner := bufio.NewScanner(output)
+			for scanner.Scan() {
+				if url := urlPattern.FindString(scanner.Text()); url != "" {
+					select {
+					case found <- url:
+					default:
+					}
+				}
+			}
+		}(output)
+	}
+
+	select {
+	case url := <-found:
+		return url, nil
+	case <-time.After(URLTimeout):
+		return "", errors.New("the tunnel server did not announce a public URL; set one in the tunnel settings")
+	case <-ctx.Done():
+		return "", errStopped
+	}
+}
+
+// forward relays every connection accepted on the remote listener to the local address
+func forward(listener net.Listener, local string) {
+	for {
+		remote, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			conn, err := net.DialTimeout("tcp", local, DialTimeout)
+			if err != nil {
+				remote.Close()
+				return
+			}
+			go relay(conn, remote)
+			relay(remote, conn)
+		}()
+	}
+}
+
+// relay copies from src to dst and closes both ends when done
+func relay(dst, src net.Conn) {
+	defer dst.Close()
+	defer src.Close()
+	_, _ = io.Copy(dst, src)
+}
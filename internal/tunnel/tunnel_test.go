@@ -0,0 +1,283 @@
+package tunnel
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeBanner is what the fake server prints to shell sessions
+const fakeBanner = "Welcome!\r\nabc123.lhr.life tunneled with tls termination, https://abc123.lhr.life\r\n"
+
+// fakeServer is a minimal tunnel service: it accepts any login, honours tcpip-forward by listening
+// on a local port and prints banner to shell sessions
+type fakeServer struct {
+	addr    string
+	hostKey ssh.PublicKey
+	public  chan string          // Address of the listener opened for the forward
+	conns   chan *ssh.ServerConn // Every login
+}
+
+func startFakeServer(t *testing.T, banner string) *fakeServer {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &fakeServer{addr: listener.Addr().String(), hostKey: signer.PublicKey(), public: make(chan string, 4), conns: make(chan *ssh.ServerConn, 4)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(t, conn, config, banner)
+		}
+	}()
+	return s
+}
+
+// serve handles one login
+func (s *fakeServer) serve(t *testing.T, conn net.Conn, config *ssh.ServerConfig, banner string) {
+	serverConn, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	s.conns <- serverConn
+	go s.handleRequests(t, serverConn, requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for req := range requests {
+				_ = req.Reply(req.Type == "shell", nil)
+				if req.Type == "shell" {
+					_, _ = io.WriteString(channel, banner)
+				}
+			}
+		}()
+	}
+}
+
+// handleRequests answers tcpip-forward by relaying a local listener through forwarded-tcpip channels
+func (s *fakeServer) handleRequests(t *testing.T, conn *ssh.ServerConn, requests <-chan *ssh.Request) {
+	for req := range requests {
+		if req.Type != "tcpip-forward" {
+			_ = req.Reply(false, nil)
+			continue
+		}
+		var forward struct {
+			Addr string
+			Port uint32
+		}
+		if err := ssh.Unmarshal(req.Payload, &forward); err != nil {
+			_ = req.Reply(false, nil)
+			continue
+		}
+		public, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			_ = req.Reply(false, nil)
+			continue
+		}
+		t.Cleanup(func() { public.Close() })
+		port := uint32(public.Addr().(*net.TCPAddr).Port)
+		_ = req.Reply(true, ssh.Marshal(struct{ Port uint32 }{port}))
+		s.public <- public.Addr().String()
+
+		go func() {
+			for {
+				visitor, err := public.Accept()
+				if err != nil {
+					return
+				}
+				payload := ssh.Marshal(struct {
+					Addr       string
+					Port       uint32
+					OriginAddr string
+					OriginPort uint32
+				}{forward.Addr, port, "127.0.0.1", 1})
+				channel, requests, err := conn.OpenChannel("forwarded-tcpip", payload)
+				if err != nil {
+					visitor.Close()
+					continue
+				}
+				go ssh.DiscardRequests(requests)
+				go func() {
+					defer channel.Close()
+					_, _ = io.Copy(channel, visitor)
+				}()
+				go func() {
+					defer visitor.Close()
+					_, _ = io.Copy(visitor, channel)
+				}()
+			}
+		}()
+	}
+}
+
+func TestTunnelForwardsToLocalPort(t *testing.T) {
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "hello from "+r.URL.Path)
+	}))
+	defer local.Close()
+	localPort := local.Listener.Addr().(*net.TCPAddr).Port
+
+	server := startFakeServer(t, fakeBanner)
+	tunnel := New()
+	publicURL, err := tunnel.Start(Options{
+		Server:  server.addr,
+		User:    "nokey",
+		HostKey: ssh.FingerprintSHA256(server.hostKey),
+	}, localPort)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer tunnel.Stop()
+
+	if publicURL != "https://abc123.lhr.life" || tunnel.URL() != publicURL {
+		t.Errorf("Start() URL = %q", publicURL)
+	}
+	if _, err := tunnel.Start(Options{Server: server.addr}, localPort); err == nil {
+		t.Error("second Start() should fail while open")
+	}
+
+	resp, err := http.Get("http://" + <-server.public + "/hooks")
+	if err != nil {
+		t.Fatalf("Get() through tunnel error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "hello from /hooks" {
+		t.Errorf("response through tunnel = %q", body)
+	}
+
+	if err := tunnel.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if tunnel.URL() != "" {
+		t.Error("URL() should be empty after Stop")
+	}
+}
+
+func TestTunnelRejectsUnpinnedHostKey(t *testing.T) {
+	server := startFakeServer(t, fakeBanner)
+	tunnel := New()
+	_, err := tunnel.Start(Options{Server: server.addr, User: "nokey", HostKey: "SHA256:other"}, 8080)
+	if err == nil {
+		tunnel.Stop()
+		t.Fatal("Start() should fail when the host key does not match")
+	}
+}
+
+func TestTunnelTrustsHostKeyOnFirstUse(t *testing.T) {
+	server := startFakeServer(t, fakeBanner)
+	fingerprint := ssh.FingerprintSHA256(server.hostKey)
+
+	tunnel := New()
+	_, err := tunnel.Start(Options{Server: server.addr, User: "nokey"}, 8080)
+	if err == nil {
+		tunnel.Stop()
+		t.Fatal("Start() should fail without a pinned host key")
+	}
+	if !strings.Contains(err.Error(), fingerprint) {
+		t.Errorf("Start() error = %v, want it to name the fingerprint", err)
+	}
+
+	var trusted string
+	_, err = tunnel.Start(Options{Server: server.addr, User: "nokey", TrustKey: func(fp string) error {
+		trusted = fp
+		return nil
+	}}, 8080)
+	if err != nil {
+		t.Fatalf("Start() with TrustKey error = %v", err)
+	}
+	defer tunnel.Stop()
+	if trusted != fingerprint {
+		t.Errorf("TrustKey() got %q, want %q", trusted, fingerprint)
+	}
+}
+
+func TestTunnelRestartsAfterServerDrops(t *testing.T) {
+	server := startFakeServer(t, fakeBanner)
+	opts := Options{Server: server.addr, User: "nokey", HostKey: ssh.FingerprintSHA256(server.hostKey)}
+	tunnel := New()
+	if _, err := tunnel.Start(opts, 8080); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer tunnel.Stop()
+
+	(<-server.conns).Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for tunnel.URL() != "" {
+		if time.Now().After(deadline) {
+			t.Fatal("URL() still set after the server dropped the tunnel")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := tunnel.Start(opts, 8080); err != nil {
+		t.Fatalf("Start() after the server dropped the tunnel error = %v", err)
+	}
+}
+
+func TestTunnelStopAbortsStart(t *testing.T) {
+	// The server never announces a URL, so Start waits until it is stopped
+	server := startFakeServer(t, "Welcome!\r\n")
+	tunnel := New()
+	errs := make(chan error, 1)
+	go func() {
+		_, err := tunnel.Start(Options{Server: server.addr, User: "nokey", HostKey: ssh.FingerprintSHA256(server.hostKey)}, 8080)
+		errs <- err
+	}()
+	<-server.conns
+
+	stopped := make(chan struct{})
+	go func() {
+		_ = tunnel.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop() blocked on the Start in progress")
+	}
+	select {
+	case err := <-errs:
+		if !errors.Is(err, errStopped) {
+			t.Errorf("Start() error = %v, want it stopped", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start() did not return after Stop")
+	}
+	if tunnel.URL() != "" {
+		t.Errorf("URL() = %q after an aborted Start", tunnel.URL())
+	}
+}
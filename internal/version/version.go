@@ -0,0 +1,5 @@
+package version
+
+// Version is the application version written into exported files.
+// Release builds override it with -ldflags "-X paperbox/internal/version.Version=x.y.z".
+var Version = "dev"
@@ -0,0 +1,346 @@
+package workspace
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/requests"
+	"paperbox/internal/config/storage"
+	"paperbox/internal/config/user"
+	"paperbox/internal/leak"
+)
+
+const (
+	// FormatName identifies paperbox workspace bundles
+	FormatName = "paperbox-workspace"
+	// FormatVersion is the current version of the bundle layout
+	FormatVersion = 1
+
+	manifestFile     = "manifest.json"
+	requestsFile     = "requests.json"
+	environmentsFile = "environments.json"
+	settingsFile     = "settings.json"
+
+	// maxEntrySize bounds how much is read from a single zip entry
+	maxEntrySize = 256 << 20
+
+	// redactedValue replaces stripped secret values
+	redactedValue = ""
+)
+
+// Manifest describes the bundle contents and the versions they were written with
+type Manifest struct {
+	Format              string    `json:"format"`
+	FormatVersion       int       `json:"formatVersion"`
+	AppVersion          string    `json:"appVersion"`
+	CreatedAt           time.Time `json:"createdAt"`
+	RequestsVersion     int       `json:"requestsVersion"`
+	EnvironmentsVersion int       `json:"environmentsVersion,omitempty"`
+	SettingsVersion     int       `json:"settingsVersion,omitempty"`
+}
+
+// Settings is the portable subset of the user config (no machine-specific fields such as window state).
+// Pointer fields distinguish "not set" from a meaningful zero (unlimited depth, no timeout).
+type Settings struct {
+	Version            int    `json:"version"`
+	Theme              string `json:"theme,omitempty"`
+	FontSize           int    `json:"fontSize,omitempty"`
+	BaseURL            string `json:"baseURL,omitempty"`
+	MaxFolderDepth     *int   `json:"maxFolderDepth,omitempty"`
+	Locale             string `json:"locale,omitempty"`
+	AutosaveIntervalMs int    `json:"autosaveIntervalMs,omitempty"`
+	RequestTimeoutMs   *int   `json:"requestTimeoutMs,omitempty"`
+}
+
+// Bundle is the in-memory representation of a workspace bundle
+type Bundle struct {
+	Manifest     Manifest
+	Requests     *requests.RequestsConfig
+	Environments *environments.EnvironmentsConfig
+	Settings     *Settings
+}
+
+// SettingsFromConfig extracts the portable settings from the user config
+func SettingsFromConfig(cfg *user.Config) *Settings {
+	maxFolderDepth, requestTimeoutMs := cfg.MaxFolderDepth, cfg.RequestTimeoutMs
+	return &Settings{
+		Version:            cfg.Version,
+		Theme:              cfg.Theme,
+		FontSize:           cfg.FontSize,
+		BaseURL:            cfg.BaseURL,
+		MaxFolderDepth:     &maxFolderDepth,
+		Locale:             cfg.Locale,
+		AutosaveIntervalMs: cfg.AutosaveIntervalMs,
+		RequestTimeoutMs:   &requestTimeoutMs,
+	}
+}
+
+// Patch returns the settings as a user config patch
+func (s *Settings) Patch() map[string]interface{} {
+	patch := make(map[string]interface{})
+	if s.Theme != "" {
+		patch["theme"] = s.Theme
+	}
+	if s.FontSize > 0 {
+		patch["fontSize"] = s.FontSize
+	}
+	if s.BaseURL != "" {
+		patch["baseURL"] = s.BaseURL
+	}
+	if s.MaxFolderDepth != nil {
+		patch["maxFolderDepth"] = *s.MaxFolderDepth
+	}
+	if s.Locale != "" {
+		patch["locale"] = s.Locale
+	}
+	if s.AutosaveIntervalMs > 0 {
+		patch["autosaveIntervalMs"] = s.AutosaveIntervalMs
+	}
+	if s.RequestTimeoutMs != nil {
+		patch["requestTimeoutMs"] = *s.RequestTimeoutMs
+	}
+	return patch
+}
+
+// Export writes the bundle to path as a zip archive. Secret header and variable values are stripped;
+// other likely secrets are handled as policy says. envs and settings may be nil to leave them out
+// of the bundle.
+func Export(path string, appVersion string, cfg *requests.RequestsConfig, envs *environments.EnvironmentsConfig, settings *Settings, policy leak.Policy) error {
+	cfg = stripSecrets(cfg)
+
+	manifest := Manifest{
+		Format:          FormatName,
+		FormatVersion:   FormatVersion,
+		AppVersion:      appVersion,
+		CreatedAt:       time.Now().UTC(),
+		RequestsVersion: cfg.Version,
+	}
+	if envs != nil {
+		envs = stripVariableSecrets(envs)
+		manifest.EnvironmentsVersion = envs.Version
+	}
+	if settings != nil {
+		manifest.SettingsVersion = settings.Version
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	guard := leak.NewGuard(policy)
+
+	if err := writeJSONEntry(zw, guard, manifestFile, manifest); err != nil {
+		return err
+	}
+	if err := writeJSONEntry(zw, guard, requestsFile, cfg); err != nil {
+		return err
+	}
+	if envs != nil {
+		if err := writeJSONEntry(zw, guard, environmentsFile, envs); err != nil {
+			return err
+		}
+	}
+	if settings != nil {
+		if err := writeJSONEntry(zw, guard, settingsFile, settings); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize bundle: %w", err)
+	}
+	if err := guard.Err(); err != nil {
+		return err
+	}
+
+	if err := storage.NewFileWriter().WriteAtomic(path, buf.Bytes(), 0o644); err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to write bundle")
+	}
+	return nil
+}
+
+// Import reads a bundle from path. The requests config is run through the migration chain and validated.
+func Import(path string) (*Bundle, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to open bundle")
+	}
+	defer zr.Close()
+
+	entries := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+
+	bundle := &Bundle{}
+
+	manifestData, err := readEntry(entries, manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(manifestData, &bundle.Manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle manifest: %w", err)
+	}
+	if bundle.Manifest.Format != FormatName {
+		return nil, fmt.Errorf("file is not a paperbox workspace bundle")
+	}
+	if bundle.Manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("bundle format version %d is newer than supported version %d", bundle.Manifest.FormatVersion, FormatVersion)
+	}
+
+	requestsData, err := readEntry(entries, requestsFile)
+	if err != nil {
+		return nil, err
+	}
+	bundle.Requests, err = requests.Decode(requestsData)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := entries[environmentsFile]; ok {
+		envData, err := readEntry(entries, environmentsFile)
+		if err != nil {
+			return nil, err
+		}
+		var envs environments.EnvironmentsConfig
+		if err := json.Unmarshal(envData, &envs); err != nil {
+			return nil, fmt.Errorf("failed to parse bundle environments: %w", err)
+		}
+		if err := environments.Validate(&envs); err != nil {
+			return nil, fmt.Errorf("bundle environments are invalid: %w", err)
+		}
+		bundle.Environments = &envs
+	}
+
+	if _, ok := entries[settingsFile]; ok {
+		settingsData, err := readEntry(entries, settingsFile)
+		if err != nil {
+			return nil, err
+		}
+		var settings Settings
+		if err := json.Unmarshal(settingsData, &settings); err != nil {
+			return nil, fmt.Errorf("failed to parse bundle settings: %w", err)
+		}
+		bundle.Settings = &settings
+	}
+
+	return bundle, nil
+}
+
+// writeJSONEntry adds an indented JSON file to the archive, checked by guard
+func writeJSONEntry(zw *zip.Writer, guard *leak.Guard, name string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+	data = guard.Check(name, data)
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to bundle: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to bundle: %w", name, err)
+	}
+	return nil
+}
+
+// readEntry reads a required file from the archive
+func readEntry(entries map[string]*zip.File, name string) ([]byte, error) {
+	f, ok := entries[name]
+	if !ok {
+		return nil, fmt.Errorf("bundle is missing %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxEntrySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(data) > maxEntrySize {
+		return nil, fmt.Errorf("%s exceeds the maximum bundle entry size", name)
+	}
+	return data, nil
+}
+
+// stripSecrets returns a copy of cfg with sensitive header values (also in examples and snapshots)
+// and auth credentials cleared
+func stripSecrets(cfg *requests.RequestsConfig) *requests.RequestsConfig {
+	stripped := *cfg
+	stripped.Values = make(map[string]requests.Item, len(cfg.Values))
+	for id, item := range cfg.Values {
+		item = requests.MaskHeaders(item, redactedValue)
+		if len(item.Variables) > 0 {
+			variables := make([]requests.Param, len(item.Variables))
+			copy(variables, item.Variables)
+			for i := range variables {
+				if variables[i].Secret || isSensitiveName(variables[i].Key) {
+					variables[i].Value = redactedValue
+				}
+			}
+			item.Variables = variables
+		}
+		if item.Auth != nil {
+			auth := *item.Auth
+			auth.Password = redactedValue
+			auth.Token = redactedValue
+			auth.Value = redactedValue
+			if len(auth.Params) > 0 {
+				params := make(map[string]string, len(auth.Params))
+				for key := range auth.Params {
+					params[key] = redactedValue
+				}
+				auth.Params = params
+			}
+			item.Auth = &auth
+		}
+		stripped.Values[id] = item
+	}
+	return &stripped
+}
+
+// stripVariableSecrets returns a copy of envs with values of secret and credential-like variables cleared
+func stripVariableSecrets(envs *environments.EnvironmentsConfig) *environments.EnvironmentsConfig {
+	stripped := *envs
+	stripped.Globals = stripVariables(envs.Globals)
+	stripped.Values = make(map[string]environments.Environment, len(envs.Values))
+	for id, env := range envs.Values {
+		env.Variables = stripVariables(env.Variables)
+		stripped.Values[id] = env
+	}
+	return &stripped
+}
+
+// stripVariables returns a copy of vars with values of secret and credential-like variables cleared
+func stripVariables(vars []environments.Variable) []environments.Variable {
+	if len(vars) == 0 {
+		return vars
+	}
+	stripped := make([]environments.Variable, len(vars))
+	copy(stripped, vars)
+	for i := range stripped {
+		if stripped[i].Secret || isSensitiveName(stripped[i].Key) {
+			stripped[i].Value = redactedValue
+		}
+	}
+	return stripped
+}
+
+// isSensitiveName reports whether a variable name suggests it holds a credential
+func isSensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, marker := range []string{"token", "secret", "password", "apikey", "api_key"} {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
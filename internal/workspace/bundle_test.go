@@ -0,0 +1,263 @@
+package workspace
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"paperbox/internal/config/environments"
+	"paperbox/internal/config/requests"
+	"paperbox/internal/leak"
+)
+
+// writeBundle writes a zip archive with the given files for Import to read
+func writeBundle(t *testing.T, files map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bundle.zip")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(out)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func bundleConfig() *requests.RequestsConfig {
+	now := time.Now().UTC()
+	return &requests.RequestsConfig{
+		Version:   requests.CurrentVersion,
+		RootOrder: []string{"root"},
+		Values: map[string]requests.Item{
+			"root": {
+				Type: requests.ItemTypeFolder, Name: "API", Children: []string{"login"}, CreatedAt: now, UpdatedAt: now,
+				Variables: []requests.Param{{Key: "region", Value: "eu"}, {Key: "apiToken", Value: "folder-token"}, {Key: "pin", Value: "pin-secret", Secret: true}},
+			},
+			"login": {
+				Type: requests.ItemTypeRequest, Name: "Login", Method: "POST", Path: "/login", CreatedAt: now, UpdatedAt: now,
+				Headers: []requests.Header{{Key: "Authorization", Value: "Bearer header-token"}, {Key: "Accept", Value: "application/json"}},
+				Auth:    &requests.Auth{Type: "basic", Username: "alice", Password: "hunter2"},
+				Examples: &requests.Examples{
+					Requests:  []requests.RequestExample{{Name: "admin", Headers: []requests.Header{{Key: "X-Api-Key", Value: "example-key"}, {Key: "X-Role", Value: "admin"}}}},
+					Responses: []requests.ResponseExample{{Name: "ok", Status: 200, Headers: []requests.Header{{Key: "Set-Cookie", Value: "session=example-cookie"}}}},
+				},
+				Snapshot: &requests.Snapshot{
+					Status:     200,
+					Headers:    map[string]string{"Set-Cookie": "session=snapshot-cookie", "Content-Type": "application/json"},
+					Body:       `{"ok":true}`,
+					RecordedAt: now,
+				},
+			},
+		},
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	cfg := bundleConfig()
+	envs := &environments.EnvironmentsConfig{
+		Version: 1,
+		Active:  "dev",
+		Values: map[string]environments.Environment{
+			"dev": {Name: "Dev", Variables: []environments.Variable{{Key: "host", Value: "localhost"}, {Key: "password", Value: "env-password"}, {Key: "key", Value: "env-key", Secret: true}}},
+		},
+		Globals: []environments.Variable{{Key: "team", Value: "core"}, {Key: "SECRET_VALUE", Value: "global-secret"}},
+	}
+	depth := 0
+	settings := &Settings{Version: 1, Theme: "dark", MaxFolderDepth: &depth}
+
+	path := filepath.Join(t.TempDir(), "workspace.zip")
+	if err := Export(path, "1.2.3", cfg, envs, settings, leak.Allow); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	bundle, err := Import(path)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if bundle.Manifest.Format != FormatName || bundle.Manifest.FormatVersion != FormatVersion || bundle.Manifest.AppVersion != "1.2.3" {
+		t.Errorf("Manifest = %+v", bundle.Manifest)
+	}
+	if bundle.Manifest.RequestsVersion != requests.CurrentVersion || bundle.Manifest.EnvironmentsVersion != 1 || bundle.Manifest.SettingsVersion != 1 {
+		t.Errorf("Manifest versions = %+v", bundle.Manifest)
+	}
+
+	if len(bundle.Requests.Values) != 2 || len(bundle.Requests.RootOrder) != 1 || bundle.Requests.RootOrder[0] != "root" {
+		t.Fatalf("Requests = %+v, want the exported tree", bundle.Requests)
+	}
+	login := bundle.Requests.Values["login"]
+	if login.Method != "POST" || login.Path != "/login" || login.Snapshot == nil || login.Snapshot.Body != `{"ok":true}` {
+		t.Errorf("login = %+v, want the exported request", login)
+	}
+	if bundle.Environments == nil || bundle.Environments.Active != "dev" || bundle.Environments.Values["dev"].Variables[0].Value != "localhost" {
+		t.Errorf("Environments = %+v, want the exported environments", bundle.Environments)
+	}
+	if bundle.Settings == nil || bundle.Settings.Theme != "dark" || bundle.Settings.MaxFolderDepth == nil || *bundle.Settings.MaxFolderDepth != 0 {
+		t.Errorf("Settings = %+v, want the exported settings with an unlimited depth", bundle.Settings)
+	}
+	if patch := bundle.Settings.Patch(); patch["theme"] != "dark" || patch["maxFolderDepth"] != 0 {
+		t.Errorf("Patch() = %v", patch)
+	}
+
+	// Leaving the environments and settings out
+	path = filepath.Join(t.TempDir(), "requests-only.zip")
+	if err := Export(path, "1.2.3", cfg, nil, nil, leak.Allow); err != nil {
+		t.Fatalf("Export() without environments error = %v", err)
+	}
+	if bundle, err := Import(path); err != nil || bundle.Environments != nil || bundle.Settings != nil {
+		t.Errorf("Import() = %+v, %v, want only the requests", bundle, err)
+	}
+}
+
+func TestExportStripsSecrets(t *testing.T) {
+	cfg := bundleConfig()
+	envs := &environments.EnvironmentsConfig{
+		Version: 1,
+		Values: map[string]environments.Environment{
+			"dev": {Name: "Dev", Variables: []environments.Variable{{Key: "host", Value: "localhost"}, {Key: "dbPassword", Value: "env-password"}, {Key: "key", Value: "env-key", Secret: true}}},
+		},
+		Globals: []environments.Variable{{Key: "team", Value: "core"}, {Key: "SECRET_VALUE", Value: "global-secret"}},
+	}
+
+	path := filepath.Join(t.TempDir(), "workspace.zip")
+	if err := Export(path, "1.2.3", cfg, envs, nil, leak.Allow); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := Import(path)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	// The archive is compressed, so look at what was imported as well as at the raw bytes
+	for _, secret := range []string{"header-token", "hunter2", "example-key", "example-cookie", "snapshot-cookie", "folder-token", "pin-secret", "env-password", "env-key", "global-secret"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("bundle contains %q", secret)
+		}
+	}
+
+	login := bundle.Requests.Values["login"]
+	if login.Headers[0].Value != redactedValue || login.Headers[1].Value != "application/json" {
+		t.Errorf("Headers = %+v, want only Authorization cleared", login.Headers)
+	}
+	if login.Auth == nil || login.Auth.Username != "alice" || login.Auth.Password != redactedValue {
+		t.Errorf("Auth = %+v, want the password cleared", login.Auth)
+	}
+	example := login.Examples.Requests[0]
+	if example.Headers[0].Value != redactedValue || example.Headers[1].Value != "admin" {
+		t.Errorf("request example headers = %+v, want only X-Api-Key cleared", example.Headers)
+	}
+	if value := login.Examples.Responses[0].Headers[0].Value; value != redactedValue {
+		t.Errorf("response example Set-Cookie = %q, want it cleared", value)
+	}
+	if headers := login.Snapshot.Headers; headers["Set-Cookie"] != redactedValue || headers["Content-Type"] != "application/json" {
+		t.Errorf("snapshot headers = %v, want only Set-Cookie cleared", headers)
+	}
+	variables := bundle.Requests.Values["root"].Variables
+	if variables[0].Value != "eu" || variables[1].Value != redactedValue || variables[2].Value != redactedValue {
+		t.Errorf("folder variables = %+v, want apiToken and the secret pin cleared", variables)
+	}
+	devVars := bundle.Environments.Values["dev"].Variables
+	if devVars[0].Value != "localhost" || devVars[1].Value != redactedValue || devVars[2].Value != redactedValue {
+		t.Errorf("environment variables = %+v, want dbPassword and the secret key cleared", devVars)
+	}
+	if globals := bundle.Environments.Globals; globals[0].Value != "core" || globals[1].Value != redactedValue {
+		t.Errorf("globals = %+v, want SECRET_VALUE cleared", globals)
+	}
+
+	// The config being exported is left alone
+	original := cfg.Values["login"]
+	if original.Headers[0].Value != "Bearer header-token" || original.Auth.Password != "hunter2" ||
+		original.Examples.Responses[0].Headers[0].Value != "session=example-cookie" || original.Snapshot.Headers["Set-Cookie"] != "session=snapshot-cookie" {
+		t.Errorf("Export() changed the config: %+v", original)
+	}
+	if envs.Globals[1].Value != "global-secret" || envs.Values["dev"].Variables[1].Value != "env-password" {
+		t.Errorf("Export() changed the environments: %+v", envs)
+	}
+}
+
+func TestImportRejectsNewerFormat(t *testing.T) {
+	path := writeBundle(t, map[string]string{
+		manifestFile: `{"format": "paperbox-workspace", "formatVersion": 99}`,
+		requestsFile: `{"version": 1, "values": {}}`,
+	})
+	_, err := Import(path)
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Import() error = %v, want the format version refused", err)
+	}
+
+	path = writeBundle(t, map[string]string{
+		manifestFile: `{"format": "something-else", "formatVersion": 1}`,
+		requestsFile: `{"version": 1, "values": {}}`,
+	})
+	if _, err := Import(path); err == nil {
+		t.Error("Import() of a foreign archive succeeded")
+	}
+
+	path = writeBundle(t, map[string]string{
+		manifestFile: `{"format": "paperbox-workspace", "formatVersion": 1}`,
+		requestsFile: `{"version": 999, "values": {}}`,
+	})
+	if _, err := Import(path); err == nil {
+		t.Error("Import() of requests written by a newer version succeeded")
+	}
+}
+
+func TestImportMigratesOldRequests(t *testing.T) {
+	// Version 1 had no root order and kept form bodies as text
+	path := writeBundle(t, map[string]string{
+		manifestFile: `{"format": "paperbox-workspace", "formatVersion": 1, "requestsVersion": 1}`,
+		requestsFile: `{
+			"version": 1,
+			"values": {
+				"root": {"type": "folder", "name": "API", "children": ["form"]},
+				"form": {
+					"type": "request", "name": "Submit", "method": "POST", "path": "/submit",
+					"headers": [{"key": "Content-Type", "value": "application/x-www-form-urlencoded"}],
+					"body": "name=alice&age=30"
+				}
+			}
+		}`,
+	})
+
+	bundle, err := Import(path)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	cfg := bundle.Requests
+	if cfg.Version != requests.CurrentVersion {
+		t.Errorf("Version = %d, want %d", cfg.Version, requests.CurrentVersion)
+	}
+	if len(cfg.RootOrder) != 1 || cfg.RootOrder[0] != "root" {
+		t.Errorf("RootOrder = %v, want [root]", cfg.RootOrder)
+	}
+	form := cfg.Values["form"]
+	if form.Body != "" || len(form.FormFields) != 2 || form.FormFields[0].Key != "name" || form.FormFields[1].Value != "30" {
+		t.Errorf("form = body %q, fields %+v, want the body split into fields", form.Body, form.FormFields)
+	}
+	if form.CreatedAt.IsZero() {
+		t.Error("CreatedAt is zero, want the items stamped by the migration")
+	}
+	if bundle.Environments != nil || bundle.Settings != nil {
+		t.Errorf("Import() = %+v, want no environments or settings", bundle)
+	}
+}
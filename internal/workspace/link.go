@@ -0,0 +1,128 @@
+package workspace
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/requests"
+	"paperbox/internal/leak"
+)
+
+const (
+	// LinkPrefix starts every item link, so pasted text is recognised and the encoding can change later
+	LinkPrefix = "paperbox1."
+	// LinkFileExtension is the extension of files holding an item link
+	LinkFileExtension = ".paperbox"
+
+	// maxLinkSize bounds the decompressed size of a link
+	maxLinkSize = 4 << 20
+)
+
+// Link is a single request or folder shared as text, with the values of the variables it uses
+type Link struct {
+	RequestsVersion int              `json:"requestsVersion"`
+	Item            requests.Node    `json:"item"`
+	Variables       []requests.Param `json:"variables,omitempty"`
+}
+
+// EncodeLink encodes an item and its descendants as a compact, URL-safe link. Secret header values
+// and credentials are stripped like in bundles, secret and credential-like variables are left out,
+// and notes, snapshots and bookkeeping fields are dropped. Other likely secrets are handled as policy
+// says.
+func EncodeLink(cfg *requests.RequestsConfig, itemID string, vars []requests.Param, policy leak.Policy) (string, error) {
+	node, ok := requests.ToNode(stripSecrets(cfg), itemID)
+	if !ok {
+		return "", apperrors.NotFoundf("item not found")
+	}
+	link := Link{RequestsVersion: cfg.Version, Item: trimNode(node)}
+	for _, v := range vars {
+		if !v.Secret && !isSensitiveName(v.Key) {
+			link.Variables = append(link.Variables, v)
+		}
+	}
+
+	data, err := json.Marshal(link)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode link: %w", err)
+	}
+	guard := leak.NewGuard(policy)
+	data = guard.Check("link", data)
+	if err := guard.Err(); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	zw, _ := flate.NewWriter(&buf, flate.BestCompression)
+	if _, err := zw.Write(data); err != nil {
+		return "", fmt.Errorf("failed to compress link: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress link: %w", err)
+	}
+	return LinkPrefix + base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// DecodeLink parses a link produced by EncodeLink; surrounding whitespace is ignored
+func DecodeLink(text string) (*Link, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(text), LinkPrefix)
+	if !ok {
+		return nil, apperrors.Invalidf("text is not a paperbox link")
+	}
+	compressed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, apperrors.Invalidf("link is damaged: %v", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(compressed)), maxLinkSize+1))
+	if err != nil {
+		return nil, apperrors.Invalidf("link is damaged: %v", err)
+	}
+	if len(data) > maxLinkSize {
+		return nil, apperrors.Invalidf("link exceeds the maximum size")
+	}
+
+	var link Link
+	if err := json.Unmarshal(data, &link); err != nil {
+		return nil, apperrors.Invalidf("link is damaged: %v", err)
+	}
+	if link.RequestsVersion > requests.CurrentVersion {
+		return nil, apperrors.Invalidf("link was made by a newer version of paperbox")
+	}
+	if link.Item.Item.Type != requests.ItemTypeRequest && link.Item.Item.Type != requests.ItemTypeFolder {
+		return nil, apperrors.Invalidf("link holds no request or folder")
+	}
+	return &link, nil
+}
+
+// WithVariables returns vars followed by the link variables whose keys vars does not have
+func (l *Link) WithVariables(vars []requests.Param) []requests.Param {
+	merged := append([]requests.Param{}, vars...)
+	defined := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		defined[v.Key] = true
+	}
+	for _, v := range l.Variables {
+		if !defined[v.Key] {
+			merged = append(merged, v)
+		}
+	}
+	return merged
+}
+
+// trimNode drops what is personal or recorded rather than part of the request itself
+func trimNode(node requests.Node) requests.Node {
+	item := &node.Item
+	item.Notes, item.Snapshot, item.Favorite = nil, nil, false
+	item.CreatedAt, item.UpdatedAt, item.LastUsedAt = time.Time{}, time.Time{}, nil
+	children := make([]requests.Node, len(node.Children))
+	for i, child := range node.Children {
+		children[i] = trimNode(child)
+	}
+	node.Children = children
+	return node
+}
@@ -0,0 +1,79 @@
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"paperbox/internal/apperrors"
+	"paperbox/internal/config/storage"
+)
+
+const (
+	// SettingsFormatName identifies paperbox settings profiles
+	SettingsFormatName = "paperbox-settings"
+	// SettingsFormatVersion is the current version of the profile layout
+	SettingsFormatVersion = 1
+
+	// maxProfileSize bounds how much of a profile file is read
+	maxProfileSize = 1 << 20
+)
+
+// SettingsProfile is a standalone settings file used to copy a setup to another machine
+type SettingsProfile struct {
+	Format        string    `json:"format"`
+	FormatVersion int       `json:"formatVersion"`
+	AppVersion    string    `json:"appVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+	Settings      Settings  `json:"settings"`
+}
+
+// ExportSettings writes the portable settings to path as a JSON profile
+func ExportSettings(path string, appVersion string, settings *Settings) error {
+	profile := SettingsProfile{
+		Format:        SettingsFormatName,
+		FormatVersion: SettingsFormatVersion,
+		AppVersion:    appVersion,
+		CreatedAt:     time.Now().UTC(),
+		Settings:      *settings,
+	}
+
+	data, err := json.MarshalIndent(profile, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	if err := storage.NewFileWriter().WriteAtomic(path, data, 0o644); err != nil {
+		return apperrors.Wrap(apperrors.IOError, err, "failed to write settings")
+	}
+	return nil
+}
+
+// ImportSettings reads a settings profile written by ExportSettings
+func ImportSettings(path string) (*Settings, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to open settings")
+	}
+	if info.Size() > maxProfileSize {
+		return nil, apperrors.Invalidf("settings file is too large")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to read settings")
+	}
+
+	var profile SettingsProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, apperrors.Wrap(apperrors.ValidationFailed, err, "failed to parse settings")
+	}
+	if profile.Format != SettingsFormatName {
+		return nil, apperrors.Invalidf("file is not a paperbox settings profile")
+	}
+	if profile.FormatVersion > SettingsFormatVersion {
+		return nil, apperrors.Invalidf("settings format version %d is newer than supported version %d", profile.FormatVersion, SettingsFormatVersion)
+	}
+
+	return &profile.Settings, nil
+}
@@ -0,0 +1,51 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"paperbox/internal/config/user"
+)
+
+func TestSettingsProfileRoundTrip(t *testing.T) {
+	cfg := user.DefaultConfig()
+	cfg.Theme = "dark"
+	cfg.MaxFolderDepth = 0
+	cfg.RequestTimeoutMs = 0
+	cfg.Window = user.WindowState{Width: 1280, Height: 800, X: 10, Y: 20}
+
+	path := filepath.Join(t.TempDir(), "settings.json")
+	if err := ExportSettings(path, "test", SettingsFromConfig(cfg)); err != nil {
+		t.Fatalf("ExportSettings() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	for _, machineSpecific := range []string{"window", "maximized"} {
+		if strings.Contains(string(data), machineSpecific) {
+			t.Errorf("exported profile should not contain %q: %s", machineSpecific, data)
+		}
+	}
+
+	settings, err := ImportSettings(path)
+	if err != nil {
+		t.Fatalf("ImportSettings() error = %v", err)
+	}
+	patch := settings.Patch()
+	if patch["theme"] != "dark" || patch["maxFolderDepth"] != 0 || patch["requestTimeoutMs"] != 0 {
+		t.Errorf("Patch() = %v, want theme and explicit zero depth/timeout", patch)
+	}
+	if _, ok := patch["window"]; ok {
+		t.Errorf("Patch() must not touch window state: %v", patch)
+	}
+}
+
+func TestImportSettingsRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "other.json")
+	os.WriteFile(path, []byte(`{"format":"something-else"}`), 0o644)
+
+	if _, err := ImportSettings(path); err == nil {
+		t.Error("expected an error for a file that is not a settings profile")
+	}
+}
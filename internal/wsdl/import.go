@@ -0,0 +1,172 @@
+package wsdl
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"paperbox/internal/config/requests"
+)
+
+// Envelope namespaces by SOAP version
+var envelopeNamespaces = map[string]string{
+	SOAP11: "http://schemas.xmlsoap.org/soap/envelope/",
+	SOAP12: "http://www.w3.org/2003/05/soap-envelope",
+}
+
+// Import builds a folder with one POST request per operation, in a subfolder per port when the
+// document has several. A port folder's base URL is the origin of its address, so the endpoint
+// can be pointed elsewhere for an environment. Requests carry the SOAPAction (SOAP 1.1) or the
+// action parameter of the Content-Type (SOAP 1.2) and an envelope whose simple values are
+// Placeholder. ids selects operations by Operation.ID, all when empty; name is used when the
+// document has none.
+func Import(defs *Definitions, name string, ids []string) requests.Node {
+	selected := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		selected[id] = true
+	}
+	var ops []Operation
+	for _, op := range defs.Operations() {
+		if len(ids) == 0 || selected[op.ID] {
+			ops = append(ops, op)
+		}
+	}
+
+	root := requests.Node{Item: requests.Item{
+		Type:        requests.ItemTypeFolder,
+		Name:        nonEmpty(defs.Name, name),
+		Description: strings.TrimSpace(defs.Documentation),
+	}}
+	ports := make(map[string]int)
+	for _, op := range ops {
+		key := op.Service + "/" + op.Port
+		if _, exists := ports[key]; !exists {
+			ports[key] = len(root.Children)
+			baseURL, _ := splitAddress(op.Address)
+			root.Children = append(root.Children, requests.Node{Item: requests.Item{
+				Type:    requests.ItemTypeFolder,
+				Name:    op.Port,
+				BaseURL: baseURL,
+			}})
+		}
+		folder := &root.Children[ports[key]]
+		folder.Children = append(folder.Children, requests.Node{Item: importRequest(defs, op)})
+	}
+	if len(root.Children) == 1 {
+		// A single port needs no subfolder
+		root.Item.BaseURL = root.Children[0].Item.BaseURL
+		root.Children = root.Children[0].Children
+	}
+	return root
+}
+
+// importRequest converts one operation into a request
+func importRequest(defs *Definitions, op Operation) requests.Item {
+	_, path := splitAddress(op.Address)
+	item := requests.Item{
+		Type:        requests.ItemTypeRequest,
+		Name:        op.Name,
+		Description: op.Documentation,
+		Method:      "POST",
+		Path:        path,
+		Body:        Envelope(defs, op),
+	}
+	if op.Version == SOAP12 {
+		contentType := "application/soap+xml; charset=utf-8"
+		if op.SOAPAction != "" {
+			contentType += fmt.Sprintf("; action=%q", op.SOAPAction)
+		}
+		item.Headers = []requests.Header{{Key: "Content-Type", Value: contentType}}
+	} else {
+		item.Headers = []requests.Header{
+			{Key: "Content-Type", Value: "text/xml; charset=utf-8"},
+			{Key: "SOAPAction", Value: fmt.Sprintf("%q", op.SOAPAction)},
+		}
+	}
+	return item
+}
+
+// Envelope generates the request envelope of an operation. Document-style bodies hold the
+// elements of the input parts; rpc-style bodies wrap the parts in an element named after the
+// operation.
+func Envelope(defs *Definitions, op Operation) string {
+	w := &writer{defs: defs, buf: &strings.Builder{}, prefixes: make(map[string]string), open: make(map[*ComplexType]bool)}
+	depth := 2
+	if op.Style == "rpc" {
+		namespace := op.namespace
+		if op.body != nil && op.body.Namespace != "" {
+			namespace = op.body.Namespace
+		}
+		wrapper := w.prefix(namespace) + ":" + op.Name
+		w.line(depth, "<"+wrapper+">")
+		for _, part := range bodyParts(op) {
+			w.part(part, depth+1)
+		}
+		w.line(depth, "</"+wrapper+">")
+	} else {
+		for _, part := range bodyParts(op) {
+			w.part(part, depth)
+		}
+	}
+
+	envelope := envelopeNamespaces[op.Version]
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("<soapenv:Envelope xmlns:soapenv=%q", envelope))
+	for _, namespace := range w.order {
+		b.WriteString(fmt.Sprintf(" xmlns:%s=%q", w.prefixes[namespace], namespace))
+	}
+	b.WriteString(">\n  <soapenv:Header/>\n  <soapenv:Body>\n")
+	b.WriteString(w.buf.String())
+	b.WriteString("  </soapenv:Body>\n</soapenv:Envelope>")
+	return b.String()
+}
+
+// part writes a message part: its element, or an unqualified element of its type named after it
+func (w *writer) part(part Part, depth int) {
+	if part.Element != "" {
+		w.globalElement(part.Element, depth)
+		return
+	}
+	w.element(Element{Name: part.Name, Type: part.Type}, &Schema{}, "", depth)
+}
+
+// bodyParts returns the input parts that go into the body, as listed by soap:body
+func bodyParts(op Operation) []Part {
+	if op.input == nil {
+		return nil
+	}
+	if op.body == nil || strings.TrimSpace(op.body.Parts) == "" {
+		return op.input.Parts
+	}
+	var parts []Part
+	for _, name := range strings.Fields(op.body.Parts) {
+		for _, part := range op.input.Parts {
+			if part.Name == name {
+				parts = append(parts, part)
+			}
+		}
+	}
+	return parts
+}
+
+// splitAddress splits a port address into its origin and the rest ("/" when empty)
+func splitAddress(address string) (string, string) {
+	u, err := url.Parse(address)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", address
+	}
+	origin := u.Scheme + "://" + u.Host
+	rest := strings.TrimPrefix(address, origin)
+	if rest == "" {
+		rest = "/"
+	}
+	return origin, rest
+}
+
+// nonEmpty returns value, or fallback when value is empty
+func nonEmpty(value string, fallback string) string {
+	if strings.TrimSpace(value) != "" {
+		return value
+	}
+	return fallback
+}
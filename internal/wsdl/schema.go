@@ -0,0 +1,245 @@
+package wsdl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxElementDepth bounds how deep envelopes are generated from nested or recursive types
+const maxElementDepth = 8
+
+// Placeholder is the value of simple elements in generated envelopes
+const Placeholder = "?"
+
+// Schema is the part of an XML Schema envelopes are generated from
+type Schema struct {
+	TargetNamespace    string        `xml:"targetNamespace,attr"`
+	ElementFormDefault string        `xml:"elementFormDefault,attr"`
+	Elements           []Element     `xml:"element"`
+	ComplexTypes       []ComplexType `xml:"complexType"`
+	SimpleTypes        []SimpleType  `xml:"simpleType"`
+}
+
+// Element declares an element, with a named or an inline type
+type Element struct {
+	Name        string       `xml:"name,attr"`
+	Type        string       `xml:"type,attr"`
+	Ref         string       `xml:"ref,attr"`
+	MinOccurs   string       `xml:"minOccurs,attr"`
+	MaxOccurs   string       `xml:"maxOccurs,attr"`
+	ComplexType *ComplexType `xml:"complexType"`
+	SimpleType  *SimpleType  `xml:"simpleType"`
+}
+
+// ComplexType is a type with child elements
+type ComplexType struct {
+	Name           string `xml:"name,attr"`
+	Particle              // Content declared directly
+	ComplexContent *struct {
+		Extension *struct {
+			Base string `xml:"base,attr"`
+			Particle
+		} `xml:"extension"`
+	} `xml:"complexContent"`
+}
+
+// Particle is the content of a complex type or a group within it
+type Particle struct {
+	Sequence *Group `xml:"sequence"`
+	All      *Group `xml:"all"`
+	Choice   *Group `xml:"choice"`
+}
+
+// Group lists elements and nested groups; only the first branch of a choice is generated
+type Group struct {
+	Elements  []Element `xml:"element"`
+	Sequences []Group   `xml:"sequence"`
+	Choices   []Group   `xml:"choice"`
+}
+
+// SimpleType restricts a built-in type, e.g. to a list of values
+type SimpleType struct {
+	Name        string `xml:"name,attr"`
+	Restriction struct {
+		Enumerations []struct {
+			Value string `xml:"value,attr"`
+		} `xml:"enumeration"`
+	} `xml:"restriction"`
+}
+
+// writer generates the XML of elements from the schemas of a document
+type writer struct {
+	defs     *Definitions
+	buf      *strings.Builder
+	prefixes map[string]string     // Namespace prefixes declared on the envelope, by namespace
+	order    []string              // Namespaces in the order they got their prefix
+	open     map[*ComplexType]bool // Types being written, so recursive types stop after one level
+}
+
+// prefix returns the prefix of a namespace, assigning "tns", "ns2", "ns3"... on first use
+func (w *writer) prefix(namespace string) string {
+	if p, ok := w.prefixes[namespace]; ok {
+		return p
+	}
+	p := "tns"
+	if len(w.order) > 0 {
+		p = fmt.Sprintf("ns%d", len(w.order)+1)
+	}
+	w.prefixes[namespace] = p
+	w.order = append(w.order, namespace)
+	return p
+}
+
+// line writes an indented line
+func (w *writer) line(depth int, text string) {
+	w.buf.WriteString(strings.Repeat("  ", depth))
+	w.buf.WriteString(text)
+	w.buf.WriteByte('\n')
+}
+
+// globalElement writes a top-level element by (prefixed) name
+func (w *writer) globalElement(qname string, depth int) {
+	elem, schema := w.findElement(localName(qname))
+	if elem == nil {
+		w.line(depth, fmt.Sprintf("<%s>%s</%s>", localName(qname), Placeholder, localName(qname)))
+		return
+	}
+	w.element(*elem, schema, w.prefix(schema.TargetNamespace)+":", depth)
+}
+
+// element writes an element of a schema; name is prefixed with qualifier
+func (w *writer) element(elem Element, schema *Schema, qualifier string, depth int) {
+	if elem.Ref != "" {
+		if ref, refSchema := w.findElement(localName(elem.Ref)); ref != nil {
+			referenced := *ref
+			referenced.MinOccurs, referenced.MaxOccurs = elem.MinOccurs, elem.MaxOccurs
+			w.element(referenced, refSchema, w.prefix(refSchema.TargetNamespace)+":", depth)
+			return
+		}
+		elem.Name = localName(elem.Ref)
+	}
+	name := qualifier + elem.Name
+
+	switch {
+	case elem.MinOccurs == "0":
+		w.line(depth, "<!--Optional:-->")
+	case elem.MaxOccurs == "unbounded" || elem.MaxOccurs != "" && elem.MaxOccurs != "1":
+		w.line(depth, "<!--1 or more repetitions:-->")
+	}
+
+	complexType, typeSchema := elem.ComplexType, schema
+	simpleType := elem.SimpleType
+	if complexType == nil && simpleType == nil && elem.Type != "" {
+		complexType, typeSchema = w.findComplexType(localName(elem.Type))
+		if complexType == nil {
+			simpleType = w.findSimpleType(localName(elem.Type))
+		}
+	}
+	if complexType == nil || w.open[complexType] || depth > maxElementDepth {
+		w.line(depth, fmt.Sprintf("<%s>%s</%s>", name, simpleValue(simpleType), name))
+		return
+	}
+
+	// Children go into their own buffer so types without any yield an empty element
+	outer := w.buf
+	w.buf = &strings.Builder{}
+	w.open[complexType] = true
+	w.complexContent(*complexType, typeSchema, depth+1, 0)
+	delete(w.open, complexType)
+	children := w.buf.String()
+	w.buf = outer
+	if children == "" {
+		w.line(depth, fmt.Sprintf("<%s/>", name))
+		return
+	}
+	w.line(depth, fmt.Sprintf("<%s>", name))
+	w.buf.WriteString(children)
+	w.line(depth, fmt.Sprintf("</%s>", name))
+}
+
+// complexContent writes the child elements of a complex type, those of its base type first
+func (w *writer) complexContent(t ComplexType, schema *Schema, depth int, extensions int) {
+	particle := t.Particle
+	if t.ComplexContent != nil && t.ComplexContent.Extension != nil {
+		ext := t.ComplexContent.Extension
+		if base, baseSchema := w.findComplexType(localName(ext.Base)); base != nil && extensions < maxElementDepth {
+			w.complexContent(*base, baseSchema, depth, extensions+1)
+		}
+		particle = ext.Particle
+	}
+	for _, group := range []*Group{particle.Sequence, particle.All} {
+		if group != nil {
+			w.group(*group, schema, depth, false)
+		}
+	}
+	if particle.Choice != nil {
+		w.group(*particle.Choice, schema, depth, true)
+	}
+}
+
+// group writes the elements of a group; a choice only gets its first branch
+func (w *writer) group(g Group, schema *Schema, depth int, choice bool) {
+	if choice && len(g.Elements) > 0 {
+		w.line(depth, "<!--You have a CHOICE of the next items:-->")
+		g = Group{Elements: g.Elements[:1]}
+	}
+	qualifier := ""
+	if schema.ElementFormDefault == "qualified" {
+		qualifier = w.prefix(schema.TargetNamespace) + ":"
+	}
+	for _, elem := range g.Elements {
+		w.element(elem, schema, qualifier, depth)
+	}
+	for _, nested := range g.Sequences {
+		w.group(nested, schema, depth, false)
+	}
+	for _, nested := range g.Choices {
+		w.group(nested, schema, depth, true)
+	}
+}
+
+// findElement looks a top-level element up in all schemas
+func (w *writer) findElement(name string) (*Element, *Schema) {
+	for i := range w.defs.Schemas {
+		schema := &w.defs.Schemas[i]
+		for j := range schema.Elements {
+			if schema.Elements[j].Name == name {
+				return &schema.Elements[j], schema
+			}
+		}
+	}
+	return nil, nil
+}
+
+// findComplexType looks a named complex type up in all schemas
+func (w *writer) findComplexType(name string) (*ComplexType, *Schema) {
+	for i := range w.defs.Schemas {
+		schema := &w.defs.Schemas[i]
+		for j := range schema.ComplexTypes {
+			if schema.ComplexTypes[j].Name == name {
+				return &schema.ComplexTypes[j], schema
+			}
+		}
+	}
+	return nil, nil
+}
+
+// findSimpleType looks a named simple type up in all schemas
+func (w *writer) findSimpleType(name string) *SimpleType {
+	for i := range w.defs.Schemas {
+		for j := range w.defs.Schemas[i].SimpleTypes {
+			if w.defs.Schemas[i].SimpleTypes[j].Name == name {
+				return &w.defs.Schemas[i].SimpleTypes[j]
+			}
+		}
+	}
+	return nil
+}
+
+// simpleValue returns the first allowed value of a restricted type, or the placeholder
+func simpleValue(t *SimpleType) string {
+	if t != nil && len(t.Restriction.Enumerations) > 0 {
+		return t.Restriction.Enumerations[0].Value
+	}
+	return Placeholder
+}
@@ -0,0 +1,288 @@
+// Package wsdl reads WSDL 1.1 service descriptions and turns their SOAP operations into requests
+// with a ready-to-fill envelope (see Import). Schemas are read from the types section of the
+// document; schemas it imports from elsewhere are not fetched, their elements get a placeholder.
+package wsdl
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"paperbox/internal/apperrors"
+)
+
+// LoadTimeout bounds fetching a document from a URL
+const LoadTimeout = 30 * time.Second
+
+// SOAP versions of a binding
+const (
+	SOAP11 = "1.1"
+	SOAP12 = "1.2"
+)
+
+const (
+	nsWSDL   = "http://schemas.xmlsoap.org/wsdl/"
+	nsSOAP11 = "http://schemas.xmlsoap.org/wsdl/soap/"
+	nsSOAP12 = "http://schemas.xmlsoap.org/wsdl/soap12/"
+)
+
+// Definitions is the part of a WSDL 1.1 document paperbox works with
+type Definitions struct {
+	XMLName         xml.Name   `xml:"definitions"`
+	Name            string     `xml:"name,attr"`
+	TargetNamespace string     `xml:"targetNamespace,attr"`
+	Documentation   string     `xml:"documentation"`
+	Schemas         []Schema   `xml:"types>schema"`
+	Messages        []Message  `xml:"message"`
+	PortTypes       []PortType `xml:"portType"`
+	Bindings        []Binding  `xml:"binding"`
+	Services        []Service  `xml:"service"`
+}
+
+// Message is the input or output of an operation, made of parts
+type Message struct {
+	Name  string `xml:"name,attr"`
+	Parts []Part `xml:"part"`
+}
+
+// Part is a message part: a schema element (document style) or a value of a schema type (rpc style)
+type Part struct {
+	Name    string `xml:"name,attr"`
+	Element string `xml:"element,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+// PortType is the abstract interface of a service
+type PortType struct {
+	Name       string              `xml:"name,attr"`
+	Operations []PortTypeOperation `xml:"operation"`
+}
+
+// PortTypeOperation names the input message of an operation
+type PortTypeOperation struct {
+	Name          string `xml:"name,attr"`
+	Documentation string `xml:"documentation"`
+	Input         struct {
+		Message string `xml:"message,attr"`
+	} `xml:"input"`
+}
+
+// Binding maps a port type onto SOAP 1.1 or 1.2
+type Binding struct {
+	Name       string             `xml:"name,attr"`
+	Type       string             `xml:"type,attr"`
+	SOAP11     *soapBinding       `xml:"http://schemas.xmlsoap.org/wsdl/soap/ binding"`
+	SOAP12     *soapBinding       `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ binding"`
+	Operations []BindingOperation `xml:"http://schemas.xmlsoap.org/wsdl/ operation"`
+}
+
+// soapBinding is the soap:binding of a binding; its style is the default of the operations
+type soapBinding struct {
+	Style string `xml:"style,attr"`
+}
+
+// BindingOperation holds the SOAP action and body encoding of an operation
+type BindingOperation struct {
+	Name   string         `xml:"name,attr"`
+	SOAP11 *soapOperation `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
+	SOAP12 *soapOperation `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ operation"`
+	Input  struct {
+		SOAP11 *soapBody `xml:"http://schemas.xmlsoap.org/wsdl/soap/ body"`
+		SOAP12 *soapBody `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ body"`
+	} `xml:"http://schemas.xmlsoap.org/wsdl/ input"`
+}
+
+// soapOperation is the soap:operation of a binding operation
+type soapOperation struct {
+	Action string `xml:"soapAction,attr"`
+	Style  string `xml:"style,attr"`
+}
+
+// soapBody is the soap:body of an operation input
+type soapBody struct {
+	Namespace string `xml:"namespace,attr"` // Namespace of the rpc wrapper element
+	Parts     string `xml:"parts,attr"`     // Space-separated parts in the body; empty for all
+}
+
+// Service is a set of ports, each exposing a binding at an address
+type Service struct {
+	Name          string `xml:"name,attr"`
+	Documentation string `xml:"documentation"`
+	Ports         []Port `xml:"port"`
+}
+
+// Port is a binding served at an address
+type Port struct {
+	Name    string   `xml:"name,attr"`
+	Binding string   `xml:"binding,attr"`
+	SOAP11  *address `xml:"http://schemas.xmlsoap.org/wsdl/soap/ address"`
+	SOAP12  *address `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ address"`
+}
+
+// address is the soap:address of a port
+type address struct {
+	Location string `xml:"location,attr"`
+}
+
+// Operation is a SOAP operation offered at one port of a service
+type Operation struct {
+	ID            string `json:"id"` // "service/port/operation"
+	Service       string `json:"service"`
+	Port          string `json:"port"`
+	Name          string `json:"name"`
+	Documentation string `json:"documentation,omitempty"`
+	SOAPAction    string `json:"soapAction,omitempty"`
+	Version       string `json:"version"` // SOAP11 or SOAP12
+	Style         string `json:"style"`   // "document" or "rpc"
+	Address       string `json:"address"`
+
+	input     *Message
+	body      *soapBody
+	namespace string // Target namespace of the definitions, for rpc wrappers without one
+}
+
+// Parse reads a WSDL 1.1 document
+func Parse(data []byte) (*Definitions, error) {
+	var defs Definitions
+	if err := xml.Unmarshal(data, &defs); err != nil {
+		return nil, apperrors.Wrap(apperrors.ValidationFailed, err, "invalid WSDL document")
+	}
+	if defs.XMLName.Space != nsWSDL {
+		return nil, apperrors.Invalidf("the document is not a WSDL 1.1 description")
+	}
+	return &defs, nil
+}
+
+// Load reads a WSDL document from a file path or an http(s) URL
+func Load(ctx context.Context, location string) (*Definitions, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		data, err := os.ReadFile(location)
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.IOError, err, "failed to read WSDL document")
+		}
+		return Parse(data)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, LoadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, apperrors.Invalidf("invalid WSDL document URL %q", location)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to fetch WSDL document")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apperrors.New(apperrors.IOError, "failed to fetch WSDL document: %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.IOError, err, "failed to fetch WSDL document")
+	}
+	return Parse(data)
+}
+
+// Operations lists the operations of every SOAP port, in document order. Ports without a SOAP
+// address (e.g. HTTP bindings) are skipped.
+func (d *Definitions) Operations() []Operation {
+	var ops []Operation
+	for _, service := range d.Services {
+		for _, port := range service.Ports {
+			binding := d.binding(port.Binding)
+			if binding == nil {
+				continue
+			}
+			version, addr, defaultStyle := SOAP11, port.SOAP11, binding.SOAP11
+			if port.SOAP12 != nil {
+				version, addr, defaultStyle = SOAP12, port.SOAP12, binding.SOAP12
+			}
+			if addr == nil {
+				continue
+			}
+			portType := d.portType(binding.Type)
+
+			for _, bop := range binding.Operations {
+				op := Operation{
+					ID:        service.Name + "/" + port.Name + "/" + bop.Name,
+					Service:   service.Name,
+					Port:      port.Name,
+					Name:      bop.Name,
+					Version:   version,
+					Style:     "document",
+					Address:   addr.Location,
+					body:      bop.Input.SOAP11,
+					namespace: d.TargetNamespace,
+				}
+				soapOp := bop.SOAP11
+				if version == SOAP12 {
+					soapOp, op.body = bop.SOAP12, bop.Input.SOAP12
+				}
+				if defaultStyle != nil && defaultStyle.Style != "" {
+					op.Style = defaultStyle.Style
+				}
+				if soapOp != nil {
+					op.SOAPAction = soapOp.Action
+					if soapOp.Style != "" {
+						op.Style = soapOp.Style
+					}
+				}
+				if portType != nil {
+					for _, pop := range portType.Operations {
+						if pop.Name == bop.Name {
+							op.Documentation = strings.TrimSpace(pop.Documentation)
+							op.input = d.message(pop.Input.Message)
+							break
+						}
+					}
+				}
+				ops = append(ops, op)
+			}
+		}
+	}
+	return ops
+}
+
+// binding looks a binding up by (prefixed) name
+func (d *Definitions) binding(qname string) *Binding {
+	for i := range d.Bindings {
+		if d.Bindings[i].Name == localName(qname) {
+			return &d.Bindings[i]
+		}
+	}
+	return nil
+}
+
+// portType looks a port type up by (prefixed) name
+func (d *Definitions) portType(qname string) *PortType {
+	for i := range d.PortTypes {
+		if d.PortTypes[i].Name == localName(qname) {
+			return &d.PortTypes[i]
+		}
+	}
+	return nil
+}
+
+// message looks a message up by (prefixed) name
+func (d *Definitions) message(qname string) *Message {
+	for i := range d.Messages {
+		if d.Messages[i].Name == localName(qname) {
+			return &d.Messages[i]
+		}
+	}
+	return nil
+}
+
+// localName strips the namespace prefix of a qualified name. Names are matched by local name only,
+// which is ambiguous only for documents reusing a name across namespaces.
+func localName(qname string) string {
+	if i := strings.LastIndexByte(qname, ':'); i >= 0 {
+		return qname[i+1:]
+	}
+	return qname
+}
@@ -0,0 +1,117 @@
+package wsdl
+
+import (
+	"encoding/xml"
+	"os"
+	"strings"
+	"testing"
+
+	"paperbox/internal/config/requests"
+)
+
+func loadOrders(t *testing.T) *Definitions {
+	t.Helper()
+	data, err := os.ReadFile("testdata/orders.wsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return defs
+}
+
+func TestOperations(t *testing.T) {
+	ops := loadOrders(t).Operations()
+	want := []struct {
+		id, action, version, style string
+	}{
+		{"OrderService/OrderSoap/GetOrder", "http://example.com/orders/GetOrder", SOAP11, "document"},
+		{"OrderService/OrderSoap/PlaceOrder", "http://example.com/orders/PlaceOrder", SOAP11, "document"},
+		{"OrderService/OrderSoap/Ping", "", SOAP11, "rpc"},
+		{"OrderService/OrderSoap12/GetOrder", "http://example.com/orders/GetOrder", SOAP12, "document"},
+	}
+	if len(ops) != len(want) {
+		t.Fatalf("Operations() returned %d operations, want %d", len(ops), len(want))
+	}
+	for i, w := range want {
+		op := ops[i]
+		if op.ID != w.id || op.SOAPAction != w.action || op.Version != w.version || op.Style != w.style {
+			t.Errorf("Operations()[%d] = %s %q %s %s, want %s %q %s %s", i, op.ID, op.SOAPAction, op.Version, op.Style, w.id, w.action, w.version, w.style)
+		}
+	}
+	if ops[0].Documentation != "Returns one order" || ops[0].Address != "https://api.example.com/OrderService.asmx" {
+		t.Errorf("Operations()[0] = %+v", ops[0])
+	}
+}
+
+func TestImport(t *testing.T) {
+	defs := loadOrders(t)
+	root := Import(defs, "orders", nil)
+	if root.Item.Name != "OrderService" || root.Item.Description != "Order management" || len(root.Children) != 2 {
+		t.Fatalf("Import() = %q with %d children, want a folder per port", root.Item.Name, len(root.Children))
+	}
+	soap11 := root.Children[0]
+	if soap11.Item.BaseURL != "https://api.example.com" || len(soap11.Children) != 3 {
+		t.Fatalf("port folder = %+v", soap11.Item)
+	}
+
+	get := soap11.Children[0].Item
+	if get.Method != "POST" || get.Path != "/OrderService.asmx" {
+		t.Errorf("request = %s %s", get.Method, get.Path)
+	}
+	wantHeaders := []requests.Header{{Key: "Content-Type", Value: "text/xml; charset=utf-8"}, {Key: "SOAPAction", Value: `"http://example.com/orders/GetOrder"`}}
+	if len(get.Headers) != 2 || get.Headers[0] != wantHeaders[0] || get.Headers[1] != wantHeaders[1] {
+		t.Errorf("headers = %v, want %v", get.Headers, wantHeaders)
+	}
+	soap12 := root.Children[1].Children[0].Item
+	if soap12.Headers[0].Value != `application/soap+xml; charset=utf-8; action="http://example.com/orders/GetOrder"` {
+		t.Errorf("SOAP 1.2 Content-Type = %q", soap12.Headers[0].Value)
+	}
+
+	single := Import(defs, "orders", []string{"OrderService/OrderSoap/PlaceOrder"})
+	if len(single.Children) != 1 || single.Children[0].Item.Type != requests.ItemTypeRequest || single.Item.BaseURL != "https://api.example.com" {
+		t.Errorf("Import() of one operation should put the request directly in the folder, got %+v", single)
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	defs := loadOrders(t)
+	ops := defs.Operations()
+
+	place := Envelope(defs, ops[1])
+	for _, want := range []string{
+		`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:tns="http://example.com/orders">`,
+		"<tns:Id>?</tns:Id>",            // From the extended base type
+		"<tns:Status>Open</tns:Status>", // First enumeration value
+		"<tns:Sku>?</tns:Sku>",          // Named complex type
+		"<tns:Parent>?</tns:Parent>",    // Recursion stops
+		"<!--1 or more repetitions:-->", // maxOccurs
+	} {
+		if !strings.Contains(place, want) {
+			t.Errorf("Envelope() is missing %s:\n%s", want, place)
+		}
+	}
+
+	ping := Envelope(defs, ops[2])
+	if !strings.Contains(ping, `xmlns:tns="http://example.com/ping"`) || !strings.Contains(ping, "<tns:Ping>\n      <message>?</message>") {
+		t.Errorf("rpc Envelope() = %s", ping)
+	}
+	if soap12 := Envelope(defs, ops[3]); !strings.Contains(soap12, "http://www.w3.org/2003/05/soap-envelope") {
+		t.Errorf("SOAP 1.2 Envelope() = %s", soap12)
+	}
+
+	for _, op := range ops {
+		var doc struct{}
+		if err := xml.Unmarshal([]byte(Envelope(defs, op)), &doc); err != nil {
+			t.Errorf("Envelope(%s) is not well-formed: %v", op.ID, err)
+		}
+	}
+}
+
+func TestParseRejectsOtherDocuments(t *testing.T) {
+	if _, err := Parse([]byte(`<definitions xmlns="http://example.com/other"/>`)); err == nil {
+		t.Error("Parse() accepted a document that is not WSDL")
+	}
+}
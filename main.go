@@ -2,6 +2,9 @@ package main
 
 import (
 	"embed"
+	"os"
+
+	"paperbox/internal/apperrors"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -12,6 +15,11 @@ import (
 var assets embed.FS
 
 func main() {
+	// "paperbox doctor" checks the app data directory without starting the window
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(runDoctor(os.Args[2:]))
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
 
@@ -25,6 +33,10 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 255, G: 255, B: 255, A: 1},
 		OnStartup:        app.startup,
+		OnDomReady:       app.domReady,
+		OnBeforeClose:    app.beforeClose,
+		OnShutdown:       app.shutdown,
+		ErrorFormatter:   apperrors.Format,
 		Bind: []interface{}{
 			app,
 		},
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+
 	"paperbox/internal/config/requests"
 )
 
@@ -11,6 +13,9 @@ type Item = requests.Item
 type Requests struct {
 	Values    map[string]Item `json:"values"`
 	RootOrder []string        `json:"rootOrder,omitempty"`
+	Revision  uint64          `json:"revision"`
+	// Unloaded lists the root folders whose contents were not read yet (see App.LoadFolder)
+	Unloaded []string `json:"unloaded,omitempty"`
 }
 
 // NewRequests creates a new empty Requests structure
@@ -22,16 +27,18 @@ func NewRequests() Requests {
 
 // MarshalJSON implements json.Marshaler interface
 func (r Requests) MarshalJSON() ([]byte, error) {
-	return requests.MarshalRequests(r.Values)
+	type alias Requests
+	return json.Marshal(alias(r))
 }
 
 // UnmarshalJSON implements json.Unmarshaler interface
 func (r *Requests) UnmarshalJSON(data []byte) error {
-	values, err := requests.UnmarshalRequests(data)
-	if err != nil {
+	type alias Requests
+	var decoded alias
+	if err := json.Unmarshal(data, &decoded); err != nil {
 		return err
 	}
-	r.Values = values
+	*r = Requests(decoded)
 	if r.Values == nil {
 		r.Values = make(map[string]Item)
 	}
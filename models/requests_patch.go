@@ -3,7 +3,9 @@ package models
 import "paperbox/internal/config/requests"
 
 // RequestsPatch represents a partial update to the requests configuration
-// All fields are optional - only provided fields will be updated
+// All fields are optional - only provided fields will be updated.
+// Revision is the last revision the caller has seen; 0 applies the patch unconditionally.
 type RequestsPatch struct {
-	Values map[string]requests.Item `json:"values,omitempty"`
+	Values   map[string]requests.Item `json:"values,omitempty"`
+	Revision uint64                   `json:"revision,omitempty"`
 }